@@ -107,6 +107,9 @@ func TestDefaults(t *testing.T) {
 		AdmissionWebhookCertPath: "/admission-webhook/tls.crt",
 		AdmissionWebhookKeyPath:  "/admission-webhook/tls.key",
 
+		AdmissionWebhookMaxPluginConfigSize: 1 << 20,
+		AdmissionFailPolicy:                 "closed",
+
 		KongAdminURL:           "http://localhost:8001",
 		KongAdminConcurrency:   10,
 		KongWorkspace:          "",
@@ -115,6 +118,14 @@ func TestDefaults(t *testing.T) {
 		KongAdminTLSSkipVerify: false,
 		KongAdminTLSServerName: "",
 		KongAdminCACertPath:    "",
+		CredentialTypeKey:      "kongCredType",
+
+		DefaultServiceConnectTimeout: 60 * time.Second,
+		DefaultServiceWriteTimeout:   60 * time.Second,
+		DefaultServiceReadTimeout:    60 * time.Second,
+
+		DefaultRouteStripPath:    false,
+		DefaultRoutePreserveHost: true,
 
 		WatchNamespace: "",
 		IngressClass:   "kong",
@@ -155,6 +166,8 @@ func TestOverrideViaCLIFlags(t *testing.T) {
 		"--admission-webhook-listen", ":8081",
 		"--admission-webhook-cert-file", "/cert-file",
 		"--admission-webhook-key-file", "/key-file",
+		"--admission-webhook-max-plugin-config-size", "4096",
+		"--admission-fail-policy", "open",
 
 		"--kong-admin-url", "https://kong.example.com",
 		"--kong-admin-concurrency", "1",
@@ -167,6 +180,14 @@ func TestOverrideViaCLIFlags(t *testing.T) {
 		"--kong-admin-ca-cert-file", "/path/to/ca-cert",
 
 		"--kong-custom-entities-secret", "foons/foosecretname",
+		"--credential-type-key", "credType",
+
+		"--default-service-connect-timeout", "5s",
+		"--default-service-write-timeout", "90s",
+		"--default-service-read-timeout", "2m",
+
+		"--default-route-strip-path",
+		"--default-route-preserve-host=false",
 
 		"--watch-namespace", "foons",
 		"--ingress-class", "kong-internal",
@@ -179,6 +200,7 @@ func TestOverrideViaCLIFlags(t *testing.T) {
 
 		"--sync-period", "10s",
 		"--sync-rate-limit", "0.9",
+		"--sync-coalesce-threshold", "100",
 
 		"--apiserver-host", "kube-apiserver.internal",
 		"--kubeconfig", "/path/to/kubeconfig",
@@ -200,6 +222,9 @@ func TestOverrideViaCLIFlags(t *testing.T) {
 		AdmissionWebhookCertPath: "/cert-file",
 		AdmissionWebhookKeyPath:  "/key-file",
 
+		AdmissionWebhookMaxPluginConfigSize: 4096,
+		AdmissionFailPolicy:                 "open",
+
 		KongAdminURL:           "https://kong.example.com",
 		KongAdminConcurrency:   1,
 		KongWorkspace:          "yolo",
@@ -210,6 +235,14 @@ func TestOverrideViaCLIFlags(t *testing.T) {
 		KongAdminCACertPath:    "/path/to/ca-cert",
 
 		KongCustomEntitiesSecret: "foons/foosecretname",
+		CredentialTypeKey:        "credType",
+
+		DefaultServiceConnectTimeout: 5 * time.Second,
+		DefaultServiceWriteTimeout:   90 * time.Second,
+		DefaultServiceReadTimeout:    2 * time.Minute,
+
+		DefaultRouteStripPath:    true,
+		DefaultRoutePreserveHost: false,
 
 		WatchNamespace: "foons",
 		IngressClass:   "kong-internal",
@@ -220,8 +253,9 @@ func TestOverrideViaCLIFlags(t *testing.T) {
 		UpdateStatus:           false,
 		UpdateStatusOnShutdown: false,
 
-		SyncPeriod:    10 * time.Second,
-		SyncRateLimit: 0.9,
+		SyncPeriod:            10 * time.Second,
+		SyncRateLimit:         0.9,
+		SyncCoalesceThreshold: 100,
 
 		APIServerHost:      "kube-apiserver.internal",
 		KubeConfigFilePath: "/path/to/kubeconfig",
@@ -277,6 +311,9 @@ func TestOverrideViaEnvVars(t *testing.T) {
 		AdmissionWebhookCertPath: "/new-cert-path",
 		AdmissionWebhookKeyPath:  "/new-key-path",
 
+		AdmissionWebhookMaxPluginConfigSize: 1 << 20,
+		AdmissionFailPolicy:                 "closed",
+
 		KongAdminFilterTags:    []string{"managed-by-ingress-controller"},
 		KongAdminURL:           "http://localhost:8001",
 		KongAdminConcurrency:   100,
@@ -287,6 +324,14 @@ func TestOverrideViaEnvVars(t *testing.T) {
 		KongAdminCACertPath:    "",
 
 		KongCustomEntitiesSecret: "foons/barsecretname",
+		CredentialTypeKey:        "kongCredType",
+
+		DefaultServiceConnectTimeout: 60 * time.Second,
+		DefaultServiceWriteTimeout:   60 * time.Second,
+		DefaultServiceReadTimeout:    60 * time.Second,
+
+		DefaultRouteStripPath:    false,
+		DefaultRoutePreserveHost: true,
 
 		WatchNamespace: "",
 		IngressClass:   "kong",
@@ -431,8 +476,11 @@ func TestEnvironmentCertificates(t *testing.T) {
 		AdmissionWebhookListen:   ":9001",
 		AdmissionWebhookCertPath: "/admission-webhook/tls.crt",
 		AdmissionWebhookKeyPath:  "/admission-webhook/tls.key",
-		AdmissionWebhookCert:     tlsPairs[0].Cert,
-		AdmissionWebhookKey:      tlsPairs[0].Key,
+
+		AdmissionWebhookMaxPluginConfigSize: 1 << 20,
+		AdmissionFailPolicy:                 "closed",
+		AdmissionWebhookCert:                tlsPairs[0].Cert,
+		AdmissionWebhookKey:                 tlsPairs[0].Key,
 
 		KongAdminCACert: tlsPairs[0].Cert,
 	}
@@ -442,6 +490,73 @@ func TestEnvironmentCertificates(t *testing.T) {
 	assert.Equal(expected.KongAdminCACert, conf.KongAdminCACert)
 }
 
+func TestAnonymousReportsEnvVar(t *testing.T) {
+	oldArgs := os.Args
+	defer func() { os.Args = oldArgs }()
+
+	tests := []struct {
+		name    string
+		args    []string
+		envs    map[string]string
+		want    bool
+		wantErr bool
+	}{
+		{
+			name: "default",
+			want: true,
+		},
+		{
+			name: "disabled by KONG_ANONYMOUS_REPORTS",
+			envs: map[string]string{"KONG_ANONYMOUS_REPORTS": "off"},
+			want: false,
+		},
+		{
+			name: "disabled by KONG_ANONYMOUS_REPORTS as a boolean",
+			envs: map[string]string{"KONG_ANONYMOUS_REPORTS": "false"},
+			want: false,
+		},
+		{
+			name: "enabled by KONG_ANONYMOUS_REPORTS",
+			envs: map[string]string{"KONG_ANONYMOUS_REPORTS": "on"},
+			want: true,
+		},
+		{
+			name: "explicit flag takes precedence",
+			args: []string{"--anonymous-reports=true"},
+			envs: map[string]string{"KONG_ANONYMOUS_REPORTS": "off"},
+			want: true,
+		},
+		{
+			name: "CONTROLLER_ANONYMOUS_REPORTS takes precedence",
+			envs: map[string]string{"CONTROLLER_ANONYMOUS_REPORTS": "true", "KONG_ANONYMOUS_REPORTS": "off"},
+			want: true,
+		},
+		{
+			name:    "invalid KONG_ANONYMOUS_REPORTS",
+			envs:    map[string]string{"KONG_ANONYMOUS_REPORTS": "maybe"},
+			wantErr: true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			resetForTesting(func() { t.Fatal("bad parse") })
+			os.Args = append([]string{"cmd"}, test.args...)
+			for k, v := range test.envs {
+				os.Setenv(k, v)
+				defer os.Unsetenv(k)
+			}
+
+			conf, err := parseFlags()
+			if test.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, test.want, conf.AnonymousReports)
+		})
+	}
+}
+
 func TestDumpConfig(t *testing.T) {
 	oldArgs := os.Args
 	defer func() { os.Args = oldArgs }()
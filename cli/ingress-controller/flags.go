@@ -20,6 +20,7 @@ import (
 	"flag"
 	"fmt"
 	"os"
+	"strconv"
 	"strings"
 	"time"
 
@@ -28,6 +29,7 @@ import (
 
 	apiv1 "k8s.io/api/core/v1"
 
+	"github.com/kong/kubernetes-ingress-controller/internal/admission"
 	"github.com/kong/kubernetes-ingress-controller/pkg/annotations"
 	"github.com/kong/kubernetes-ingress-controller/pkg/util"
 )
@@ -37,6 +39,9 @@ const (
 	defaultKongFilterTag            = "managed-by-ingress-controller"
 	defaultAdmissionWebhookCertPath = "/admission-webhook/tls.crt"
 	defaultAdmissionWebhookKeyPath  = "/admission-webhook/tls.key"
+
+	// defaultAdmissionWebhookMaxPluginConfigSize is 1 MiB.
+	defaultAdmissionWebhookMaxPluginConfigSize = 1 << 20
 )
 
 type cliConfig struct {
@@ -47,6 +52,9 @@ type cliConfig struct {
 	AdmissionWebhookCert     string
 	AdmissionWebhookKey      string
 
+	AdmissionWebhookMaxPluginConfigSize int
+	AdmissionFailPolicy                 string
+
 	// Kong connection details
 	KongAdminURL             string
 	KongWorkspace            string
@@ -58,6 +66,16 @@ type cliConfig struct {
 	KongAdminCACertPath      string
 	KongAdminCACert          string
 	KongCustomEntitiesSecret string
+	CredentialTypeKey        string
+
+	// Kong service defaults
+	DefaultServiceConnectTimeout time.Duration
+	DefaultServiceWriteTimeout   time.Duration
+	DefaultServiceReadTimeout    time.Duration
+
+	// Kong route defaults
+	DefaultRouteStripPath    bool
+	DefaultRoutePreserveHost bool
 
 	// Resource filtering
 	WatchNamespace                 string
@@ -74,9 +92,10 @@ type cliConfig struct {
 	UpdateStatusOnShutdown bool
 
 	// Runtime behavior
-	SyncPeriod        time.Duration
-	SyncRateLimit     float32
-	EnableReverseSync bool
+	SyncPeriod            time.Duration
+	SyncRateLimit         float32
+	EnableReverseSync     bool
+	SyncCoalesceThreshold int
 
 	// Logging
 	LogLevel  string
@@ -119,6 +138,15 @@ TLS handshake`)
 		`PEM-encoded certificate for TLS handshake`)
 	flags.String("admission-webhook-key", "",
 		`PEM-encoded private key for TLS handshake`)
+	flags.Int("admission-webhook-max-plugin-config-size", defaultAdmissionWebhookMaxPluginConfigSize,
+		`Maximum size in bytes of the JSON-encoded configuration of a KongPlugin;
+larger configurations are rejected before they are submitted to Kong for
+validation.`)
+	flags.String("admission-fail-policy", string(admission.FailPolicyClosed),
+		`What the admission controller answers when Kong's Admin API is unreachable
+or failing, so that objects can't be validated: 'closed' rejects them, blocking
+changes for the duration of the outage; 'open' admits them with a warning,
+letting through objects Kong may reject once the controller syncs them.`)
 
 	// Kong connection details
 	flags.String("kong-admin-url", defaultKongAdminURL,
@@ -133,7 +161,8 @@ format of protocol://address:port`)
 
 	flags.StringSlice("kong-admin-filter-tag", []string{defaultKongFilterTag},
 		`The tag used to manage and filter entities in Kong
-This flag can be specified multiple times to specify multiple tags.`)
+This flag can be specified multiple times to specify multiple tags;
+only entities carrying all of the tags are managed.`)
 
 	flags.StringSlice("kong-admin-header", nil,
 		`add a header (key:value) to every Admin API call,
@@ -160,6 +189,32 @@ Kong's Admin SSL certificate.`)
 		`Secret containing custom entities that should be populated in DB-less
 mode of Kong. Takes the form of namespace/name.`)
 
+	flags.String("credential-type-key", util.DefaultCredentialTypeKey,
+		`Data field of Secrets holding the type of the Kong credential they contain.
+The type can also be set with the `+util.CredentialTypeLabel+` label, which must
+then agree with the data field if both are present.`)
+
+	// Kong service defaults
+	flags.Duration("default-service-connect-timeout", 60*time.Second,
+		`Connect timeout of the Kong services generated for Kubernetes services,
+unless overridden by a KongIngress.`)
+	flags.Duration("default-service-write-timeout", 60*time.Second,
+		`Write timeout of the Kong services generated for Kubernetes services,
+unless overridden by a KongIngress.`)
+	flags.Duration("default-service-read-timeout", 60*time.Second,
+		`Read timeout of the Kong services generated for Kubernetes services,
+unless overridden by a KongIngress.`)
+
+	// Kong route defaults
+	flags.Bool("default-route-strip-path", false,
+		`Strip the matched path from requests on the Kong routes generated for HTTP,
+unless overridden by the konghq.com/strip-path annotation or a KongIngress.
+For Ingress paths of type Prefix the prefix is stripped; for type Exact the
+whole path is, so the upstream receives /.`)
+	flags.Bool("default-route-preserve-host", true,
+		`Send the Host header of requests to upstreams on the Kong routes generated for HTTP,
+unless overridden by the konghq.com/preserve-host annotation or a KongIngress.`)
+
 	// Resource filtering
 	flags.String("watch-namespace", apiv1.NamespaceAll,
 		`Namespace to watch for Ingress. Default is to watch all namespaces`)
@@ -194,6 +249,10 @@ IP/hostname when the controller is being stopped.`)
 		`Relist and confirm cloud resources this often.`)
 	flags.Float32("sync-rate-limit", 0.3,
 		`Define the sync frequency upper limit`)
+	flags.Int("sync-coalesce-threshold", 0,
+		`Number of queued updates beyond which the controller stops syncing for each of them in
+turn and instead waits a few seconds to sync once for all of them and any that follow,
+relieving Kong and the controller under heavy churn. 0 disables coalescing.`)
 	flag.Bool("enable-reverse-sync", false, `Enable reverse checks from Kong to Kubernetes`)
 
 	// Logging
@@ -234,7 +293,12 @@ Ingress API with Kubernetes.`)
 	flags.Bool("version", false,
 		`Shows release information about the Kong Ingress controller`)
 	flags.Bool("anonymous-reports", true,
-		`Send anonymized usage data to help improve Kong`)
+		`Send anonymized usage data to help improve Kong: the versions of the controller, Kubernetes and Kong,
+Kong's database, the hostname, the kinds of resources watched, and the number of managed Ingresses
+and of KongPlugins by plugin name. Names and namespaces of objects are never sent.
+A random ID identifying the installation is persisted in the kong-ingress-controller-reports
+ConfigMap of the controller's namespace. Unless this flag is set, KONG_ANONYMOUS_REPORTS=off also
+disables reports.`)
 
 	return flags
 }
@@ -272,6 +336,9 @@ func parseFlags() (cliConfig, error) {
 		viper.GetString("admission-webhook-cert")
 	config.AdmissionWebhookKey =
 		viper.GetString("admission-webhook-key")
+	config.AdmissionWebhookMaxPluginConfigSize =
+		viper.GetInt("admission-webhook-max-plugin-config-size")
+	config.AdmissionFailPolicy = viper.GetString("admission-fail-policy")
 
 	// Kong connection details
 	config.KongAdminURL = viper.GetString("kong-admin-url")
@@ -297,6 +364,16 @@ func parseFlags() (cliConfig, error) {
 
 	config.KongCustomEntitiesSecret = viper.GetString(
 		"kong-custom-entities-secret")
+	config.CredentialTypeKey = viper.GetString("credential-type-key")
+
+	// Kong service defaults
+	config.DefaultServiceConnectTimeout = viper.GetDuration("default-service-connect-timeout")
+	config.DefaultServiceWriteTimeout = viper.GetDuration("default-service-write-timeout")
+	config.DefaultServiceReadTimeout = viper.GetDuration("default-service-read-timeout")
+
+	// Kong route defaults
+	config.DefaultRouteStripPath = viper.GetBool("default-route-strip-path")
+	config.DefaultRoutePreserveHost = viper.GetBool("default-route-preserve-host")
 
 	// Resource filtering
 	config.WatchNamespace = viper.GetString("watch-namespace")
@@ -316,6 +393,7 @@ func parseFlags() (cliConfig, error) {
 	config.SyncPeriod = viper.GetDuration("sync-period")
 	config.SyncRateLimit = (float32)(viper.GetFloat64("sync-rate-limit"))
 	config.EnableReverseSync = viper.GetBool("enable-reverse-sync")
+	config.SyncCoalesceThreshold = viper.GetInt("sync-coalesce-threshold")
 
 	// Logging
 	config.LogLevel = viper.GetString("log-level")
@@ -339,6 +417,34 @@ func parseFlags() (cliConfig, error) {
 	// Misc
 	config.EnableProfiling = viper.GetBool("profiling")
 	config.ShowVersion = viper.GetBool("version")
-	config.AnonymousReports = viper.GetBool("anonymous-reports")
+	if config.AnonymousReports, err = anonymousReports(flagSet); err != nil {
+		return cliConfig{}, err
+	}
 	return config, nil
 }
+
+// anonymousReportsEnv disables anonymous reports when set to a false value, as deployment tooling may set
+// Kong's own anonymous_reports through the environment of both Kong and the controller.
+const anonymousReportsEnv = "KONG_ANONYMOUS_REPORTS"
+
+// anonymousReports resolves whether anonymous reports are sent. The --anonymous-reports flag, when set
+// explicitly or through CONTROLLER_ANONYMOUS_REPORTS, takes precedence over anonymousReportsEnv.
+func anonymousReports(flags *pflag.FlagSet) (bool, error) {
+	_, controllerEnvSet := os.LookupEnv("CONTROLLER_ANONYMOUS_REPORTS")
+	value, kongEnvSet := os.LookupEnv(anonymousReportsEnv)
+	if flags.Changed("anonymous-reports") || controllerEnvSet || !kongEnvSet {
+		return viper.GetBool("anonymous-reports"), nil
+	}
+	// Kong spells its boolean settings on and off
+	switch strings.ToLower(value) {
+	case "on":
+		return true, nil
+	case "off":
+		return false, nil
+	}
+	enabled, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, fmt.Errorf("could not parse %s (%q): must be a boolean, on or off", anonymousReportsEnv, value)
+	}
+	return enabled, nil
+}
@@ -35,19 +35,25 @@ import (
 
 	"github.com/eapache/channels"
 	"github.com/fatih/color"
-	"github.com/hashicorp/go-uuid"
 	"github.com/kong/go-kong/kong"
 	"github.com/kong/kubernetes-ingress-controller/internal/admission"
 	"github.com/kong/kubernetes-ingress-controller/internal/ingress/controller"
+	"github.com/kong/kubernetes-ingress-controller/internal/ingress/task"
+	"github.com/kong/kubernetes-ingress-controller/pkg/annotations"
 	configuration "github.com/kong/kubernetes-ingress-controller/pkg/apis/configuration/v1"
 	configclientv1 "github.com/kong/kubernetes-ingress-controller/pkg/client/configuration/clientset/versioned"
 	configinformer "github.com/kong/kubernetes-ingress-controller/pkg/client/configuration/informers/externalversions"
+	"github.com/kong/kubernetes-ingress-controller/pkg/kongstate"
+	"github.com/kong/kubernetes-ingress-controller/pkg/parser"
 	"github.com/kong/kubernetes-ingress-controller/pkg/sendconfig"
 	"github.com/kong/kubernetes-ingress-controller/pkg/store"
 	"github.com/kong/kubernetes-ingress-controller/pkg/util"
+	"github.com/kong/kubernetes-ingress-controller/railgun/apis/configuration/v1alpha1"
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/viper"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/util/runtime"
@@ -89,11 +95,24 @@ func controllerConfigFromCLIConfig(cliConfig cliConfig) controller.Configuration
 			Concurrency: cliConfig.KongAdminConcurrency,
 		},
 		KongCustomEntitiesSecret: cliConfig.KongCustomEntitiesSecret,
+		CredentialTypeKey:        cliConfig.CredentialTypeKey,
+
+		ServiceDefaults: parser.ServiceDefaults{
+			ConnectTimeout: int(cliConfig.DefaultServiceConnectTimeout / time.Millisecond),
+			WriteTimeout:   int(cliConfig.DefaultServiceWriteTimeout / time.Millisecond),
+			ReadTimeout:    int(cliConfig.DefaultServiceReadTimeout / time.Millisecond),
+		},
+		RouteDefaults: parser.RouteDefaults{
+			StripPath:    kong.Bool(cliConfig.DefaultRouteStripPath),
+			PreserveHost: kong.Bool(cliConfig.DefaultRoutePreserveHost),
+		},
 
 		ResyncPeriod:      cliConfig.SyncPeriod,
 		SyncRateLimit:     cliConfig.SyncRateLimit,
 		EnableReverseSync: cliConfig.EnableReverseSync,
 
+		SyncCoalesceThreshold: cliConfig.SyncCoalesceThreshold,
+
 		Namespace: cliConfig.WatchNamespace,
 
 		IngressClass: cliConfig.IngressClass,
@@ -156,10 +175,46 @@ func main() {
 		log.Fatalf(invalidConfErrPrefix+"resync period (%vs) is too low", cliConfig.SyncPeriod.Seconds())
 	}
 
+	if cliConfig.SyncCoalesceThreshold < 0 {
+		log.Fatalf(invalidConfErrPrefix+"sync-coalesce-threshold (%d) cannot be negative", cliConfig.SyncCoalesceThreshold)
+	}
+
 	if cliConfig.KongAdminConcurrency < 1 {
 		log.Fatalf(invalidConfErrPrefix+"kong-admin-concurrency (%v) cannot be less than 1", cliConfig.KongAdminConcurrency)
 	}
 
+	if err := sendconfig.ValidateFilterTags(cliConfig.KongAdminFilterTags); err != nil {
+		log.Fatalf(invalidConfErrPrefix+"kong-admin-filter-tag: %v", err)
+	}
+	if cliConfig.KongWorkspace != "" {
+		if err := sendconfig.ValidateWorkspaceName(cliConfig.KongWorkspace); err != nil {
+			log.Fatalf(invalidConfErrPrefix+"kong-workspace: %v", err)
+		}
+	}
+
+	if cliConfig.CredentialTypeKey == "" {
+		log.Fatalf(invalidConfErrPrefix + "credential-type-key cannot be empty")
+	}
+
+	if cliConfig.AdmissionWebhookMaxPluginConfigSize < 1 {
+		log.Fatalf(invalidConfErrPrefix+"admission-webhook-max-plugin-config-size (%d) cannot be less than 1",
+			cliConfig.AdmissionWebhookMaxPluginConfigSize)
+	}
+	admissionFailPolicy, err := admission.ParseFailPolicy(cliConfig.AdmissionFailPolicy)
+	if err != nil {
+		log.Fatalf(invalidConfErrPrefix+"admission-fail-policy: %v", err)
+	}
+
+	for name, timeout := range map[string]time.Duration{
+		"default-service-connect-timeout": cliConfig.DefaultServiceConnectTimeout,
+		"default-service-write-timeout":   cliConfig.DefaultServiceWriteTimeout,
+		"default-service-read-timeout":    cliConfig.DefaultServiceReadTimeout,
+	} {
+		if timeout < time.Millisecond {
+			log.Fatalf(invalidConfErrPrefix+"%s (%v) cannot be less than 1ms", name, timeout)
+		}
+	}
+
 	kubeCfg, kubeClient, err := createApiserverClient(cliConfig.APIServerHost,
 		cliConfig.KubeConfigFilePath, log)
 	if err != nil {
@@ -241,6 +296,8 @@ func main() {
 		headers: cliConfig.KongAdminHeaders,
 		rt:      defaultTransport,
 	}
+	c = sendconfig.LimitConcurrency(c, cliConfig.KongAdminConcurrency)
+	c = sendconfig.KeepErrorBodies(c)
 
 	kongClient, err := kong.NewClient(kong.String(cliConfig.KongAdminURL), c)
 	if err != nil {
@@ -266,14 +323,15 @@ func main() {
 		log.Infof("retry %d to fetch metadata from kong: %v", retryCount, err)
 		continue
 	}
-	v, err := getSemVerVer(root["version"].(string))
-	if err != nil {
-		log.Fatalf("failed to determine version of kong: %v", err)
+	reportedVersion, _ := root["version"].(string)
+	if err := controllerConfig.Kong.SetVersion(reportedVersion); err != nil {
+		log.Warnf("failed to determine version of kong, version-gated features are disabled: %v", err)
+	} else {
+		log.WithField("kong_version", controllerConfig.Kong.Version).
+			Infof("kong version: %s", controllerConfig.Kong.Version)
 	}
-	log.WithField("kong_version", v).Infof("kong version: %s", v)
-	controllerConfig.Kong.Version = v
 
-	if strings.Contains(root["version"].(string), "enterprise") {
+	if strings.Contains(reportedVersion, "enterprise") {
 		log.Debug("enterprise version of kong detected")
 		controllerConfig.Kong.Enterprise = true
 	}
@@ -300,7 +358,7 @@ func main() {
 	// setup workspace in Kong Enterprise
 	if cliConfig.KongWorkspace != "" {
 		// ensure the workspace exists or try creating it
-		err := ensureWorkspace(ctx, kongClient, cliConfig.KongWorkspace)
+		err := sendconfig.EnsureWorkspace(ctx, kongClient, cliConfig.KongWorkspace)
 		if err != nil {
 			log.Fatalf("failed to ensure workspace in kong: %v", err)
 		}
@@ -415,6 +473,22 @@ func main() {
 	cacheStores.TCPIngress = tcpIngressInformer.GetStore()
 	informers = append(informers, tcpIngressInformer)
 
+	hasUDPIngress, err := util.ServerHasGVK(kubeClient.Discovery(), v1alpha1.GroupVersion.String(), "UDPIngress")
+	if err != nil && !apierrors.IsNotFound(err) {
+		log.Fatalf("failed to retrieve UDPIngress availability: %s", err)
+	}
+	if hasUDPIngress {
+		udpIngressInformer, err := newUDPIngressInformer(kubeCfg, cliConfig.WatchNamespace, cliConfig.SyncPeriod)
+		if err != nil {
+			log.Fatalf("failed to create UDPIngress informer: %v", err)
+		}
+		udpIngressInformer.AddEventHandler(reh)
+		cacheStores.UDPIngress = udpIngressInformer.GetStore()
+		informers = append(informers, udpIngressInformer)
+	} else {
+		log.Warn("UDPIngress CRD not detected. Disabling UDPIngress functionality.")
+	}
+
 	kongIngressInformer := kongInformerFactory.Configuration().V1().KongIngresses().Informer()
 	kongIngressInformer.AddEventHandler(reh)
 	cacheStores.Configuration = kongIngressInformer.GetStore()
@@ -471,6 +545,17 @@ func main() {
 		log.Fatalf("failed to create a controller: %v", err)
 	}
 
+	// served on /metrics from the default registry
+	if err := task.RegisterMetrics(prometheus.DefaultRegisterer); err != nil {
+		log.Fatalf("failed to register the sync queue metrics: %v", err)
+	}
+	if err := sendconfig.RegisterMetrics(prometheus.DefaultRegisterer); err != nil {
+		log.Fatalf("failed to register the configuration push metrics: %v", err)
+	}
+	if err := kongstate.RegisterMetrics(prometheus.DefaultRegisterer); err != nil {
+		log.Fatalf("failed to register the translation metrics: %v", err)
+	}
+
 	exitCh := make(chan int, 1)
 	var wg sync.WaitGroup
 	mux := http.NewServeMux()
@@ -485,42 +570,71 @@ func main() {
 
 	if cliConfig.AnonymousReports {
 		logger := log.WithField("component", "reporter")
-		hostname, err := os.Hostname()
-		if err != nil {
-			logger.Warnf("failed to fetch hostname: %v", err)
-		}
-		uuid, err := uuid.GenerateUUID()
-		if err != nil {
-			logger.Warnf("failed to generate a random uuid: %v", err)
-		}
-		k8sVersion, err := kubeClient.Discovery().ServerVersion()
-		if err != nil {
-			logger.Warnf("failed to fetch k8s api-server version: %v", err)
-		}
-		info := util.Info{
-			KongVersion:       root["version"].(string),
-			KICVersion:        RELEASE,
-			KubernetesVersion: k8sVersion.String(),
-			Hostname:          hostname,
-			ID:                uuid,
-			KongDB:            kongDB,
-		}
-		reporter := util.Reporter{
-			Info:   info,
-			Logger: logger,
+		// root was fetched with rootWithTimeout, so only the Kubernetes
+		// version is left to look up, in the reporter with a bounded context
+		if info, err := reportInfo(root, logger); err != nil {
+			logger.WithError(err).Warn("not sending anonymous reports")
+		} else {
+			if id, err := installationID(ctx, kubeClient); err != nil {
+				logger.WithError(err).Warn("failed to persist the installation ID, reporting a random one")
+			} else {
+				info.ID = id
+			}
+			info.Controllers = []string{"ingress", "tcpingress", "kongingress", "kongplugin", "kongconsumer"}
+			if hasKongClusterPlugin {
+				info.Controllers = append(info.Controllers, "kongclusterplugin")
+			}
+			if controllerConfig.EnableKnativeIngressSupport {
+				info.Controllers = append(info.Controllers, "knativeingress")
+			}
+			reporter := util.Reporter{
+				Info: info,
+				// counts only: names and namespaces of objects are never reported
+				Usage: func() util.Usage {
+					usage := util.Usage{
+						Ingresses:   len(store.ListIngressesV1()) + len(store.ListIngressesV1beta1()),
+						KongPlugins: map[string]int{},
+					}
+					for _, obj := range cacheStores.Plugin.List() {
+						if plugin, ok := obj.(*configuration.KongPlugin); ok {
+							usage.KongPlugins[plugin.PluginName]++
+						}
+					}
+					return usage
+				},
+				KubernetesVersion: func(ctx context.Context) (string, error) {
+					k8sVersion, err := util.ServerVersion(ctx, kubeClient.Discovery())
+					if err != nil {
+						return "", err
+					}
+					return k8sVersion.String(), nil
+				},
+				Logger: logger,
+			}
+			reporter.Logger = logger
+			go reporter.Run(stopCh)
 		}
-		reporter.Logger = logger
-		go reporter.Run(stopCh)
 	}
 	if cliConfig.AdmissionWebhookListen != "off" {
 		logger := log.WithField("component", "admission-server")
+		availablePlugins := admission.NewAvailablePluginStore(kongClient, availablePluginsTTL)
 		admissionServer := admission.Server{
 			Validator: admission.KongHTTPValidator{
 				Client: kongClient,
 				Logger: logger,
 				Store:  store,
+				CredentialSchemas: admission.NewCredentialSchemaStore(kongClient,
+					availablePlugins, credentialSchemaTTL),
+				CredentialTypeKey:   cliConfig.CredentialTypeKey,
+				AvailablePlugins:    availablePlugins,
+				MaxPluginConfigSize: cliConfig.AdmissionWebhookMaxPluginConfigSize,
+				KongVersion:         controllerConfig.Kong.Version,
 			},
-			Logger: logger,
+			FailPolicy:                  admissionFailPolicy,
+			IngressClass:                cliConfig.IngressClass,
+			IngressV1Beta1ClassMatching: classMatching(cliConfig.ProcessClasslessIngressV1Beta1),
+			IngressV1ClassMatching:      classMatching(cliConfig.ProcessClasslessIngressV1),
+			Logger:                      logger,
 		}
 		var cert tls.Certificate
 		if cliConfig.AdmissionWebhookCertPath != defaultAdmissionWebhookCertPath && cliConfig.AdmissionWebhookCert != "" {
@@ -566,6 +680,15 @@ func main() {
 	os.Exit(<-exitCh)
 }
 
+// classMatching returns how the class of objects is matched, accepting the objects without class if
+// processClassless is set, as the store does.
+func classMatching(processClassless bool) annotations.ClassMatching {
+	if processClassless {
+		return annotations.ExactOrEmptyClassMatch
+	}
+	return annotations.ExactClassMatch
+}
+
 func handleSigterm(kong *controller.KongController,
 	stopCh chan<- struct{},
 	exitCh chan<- int,
@@ -654,6 +777,17 @@ func createApiserverClient(apiserverHost string, kubeConfig string,
 	return cfg, client, nil
 }
 
+// installationID returns the ID of the installation persisted in the namespace of the controller.
+func installationID(ctx context.Context, kubeClient kubernetes.Interface) (string, error) {
+	namespace := os.Getenv("POD_NAMESPACE")
+	if namespace == "" {
+		return "", fmt.Errorf("POD_NAMESPACE is not set")
+	}
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	return util.InstallationID(ctx, kubeClient, namespace, reportsConfigMapName)
+}
+
 func rootWithTimeout(ctx context.Context, kc *kong.Client) (map[string]interface{}, error) {
 	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
 	defer cancel()
@@ -661,6 +795,18 @@ func rootWithTimeout(ctx context.Context, kc *kong.Client) (map[string]interface
 }
 
 const (
+	// credentialSchemaTTL is how long the credential schemas fetched
+	// from Kong are cached by the admission webhook.
+	credentialSchemaTTL = 5 * time.Minute
+
+	// availablePluginsTTL is how long the plugins available on Kong
+	// are cached by the admission webhook.
+	availablePluginsTTL = 5 * time.Minute
+
+	// reportsConfigMapName is the ConfigMap, in the namespace of the controller,
+	// persisting the ID of the installation sent in anonymous reports.
+	reportsConfigMapName = "kong-ingress-controller-reports"
+
 	// High enough QPS to fit all expected use cases. QPS=0 is not set here, because
 	// client code is overriding it.
 	defaultQPS = 1e6
@@ -1,65 +1,83 @@
 package main
 
 import (
-	"context"
 	"errors"
 	"fmt"
-	"regexp"
-	"strings"
+	"os"
+	"time"
 
-	"github.com/blang/semver"
-	"github.com/kong/go-kong/kong"
+	"github.com/hashicorp/go-uuid"
+	"github.com/kong/kubernetes-ingress-controller/pkg/util"
+	"github.com/kong/kubernetes-ingress-controller/railgun/apis/configuration/v1alpha1"
+	"github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/serializer"
+	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/cache"
 )
 
-func getSemVerVer(v string) (semver.Version, error) {
-	// fix enterprise edition semver adding patch number
-	// fix enterprise edition version with dash
-	// fix bad version formats like 0.13.0preview1
-	re := regexp.MustCompile(`(\d+\.\d+)(?:[\.-](\d+))?(?:\-?(.+)$|$)`)
-	m := re.FindStringSubmatch(v)
-	if len(m) != 4 {
-		return semver.Version{}, fmt.Errorf("Unknown Kong version : '%v'", v)
+func newEmptyStore() cache.Store {
+	return cache.NewStore(func(interface{}) (string, error) { return "", errors.New("this store cannot add elements") })
+}
+
+// newUDPIngressInformer returns an informer of the UDPIngresses in namespace, or in all namespaces if it is
+// empty. The generated clientset doesn't cover the v1alpha1 API, so it lists and watches them with a REST
+// client of its own.
+func newUDPIngressInformer(kubeCfg *rest.Config, namespace string,
+	resync time.Duration) (cache.SharedIndexInformer, error) {
+	scheme := runtime.NewScheme()
+	if err := v1alpha1.AddToScheme(scheme); err != nil {
+		return nil, err
 	}
-	if m[2] == "" {
-		m[2] = "0"
+	cfg := rest.CopyConfig(kubeCfg)
+	cfg.APIPath = "/apis"
+	cfg.GroupVersion = &v1alpha1.GroupVersion
+	cfg.NegotiatedSerializer = serializer.NewCodecFactory(scheme).WithoutConversion()
+	if cfg.UserAgent == "" {
+		cfg.UserAgent = rest.DefaultKubernetesUserAgent()
 	}
-	if m[3] != "" {
-		m[3] = "-" + strings.Replace(m[3], "enterprise-edition", "enterprise", 1)
-		m[3] = strings.Replace(m[3], ".", "", -1)
+	client, err := rest.RESTClientFor(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("creating UDPIngress client: %w", err)
 	}
-	v = fmt.Sprintf("%s.%s%s", m[1], m[2], m[3])
-	return semver.Make(v)
+	lw := cache.NewListWatchFromClient(client, "udpingresses", namespace, fields.Everything())
+	return cache.NewSharedIndexInformer(lw, &v1alpha1.UDPIngress{}, resync,
+		cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc}), nil
 }
 
-func ensureWorkspace(ctx context.Context, client *kong.Client, workspace string) error {
-	req, err := client.NewRequest("GET", "/workspaces/"+workspace, nil, nil)
-	if err != nil {
-		return err
+// reportInfo gathers the metadata of anonymous reports, using root, the
+// payload of the root endpoint of Kong's Admin API. It fails if the version
+// or the database of Kong can't be found in root, in which case no report
+// should be sent. Fields which are only nice to have are left empty, with a
+// warning logged, when they can't be gathered.
+func reportInfo(root map[string]interface{}, logger logrus.FieldLogger) (util.Info, error) {
+	kongVersion, ok := root["version"].(string)
+	if !ok || kongVersion == "" {
+		return util.Info{}, fmt.Errorf("malformed Kong version: %v", root["version"])
 	}
-	_, err = client.Do(ctx, req, nil)
-	if err != nil {
-		if kong.IsNotFoundErr(err) {
-			if err := createWorkspace(ctx, client, workspace); err != nil {
-				return fmt.Errorf("creating workspace '%v': %w", workspace, err)
-			}
-			return nil
-		}
-		return fmt.Errorf("looking up workspace '%v': %w", workspace, err)
+	kongConfiguration, ok := root["configuration"].(map[string]interface{})
+	if !ok {
+		return util.Info{}, fmt.Errorf("malformed Kong configuration: %v", root["configuration"])
+	}
+	kongDB, ok := kongConfiguration["database"].(string)
+	if !ok || kongDB == "" {
+		return util.Info{}, fmt.Errorf("malformed Kong database: %v", kongConfiguration["database"])
 	}
-	return nil
-}
 
-func createWorkspace(ctx context.Context, client *kong.Client, workspace string) error {
-	body := map[string]string{"name": workspace}
-	req, err := client.NewRequest("POST", "/workspaces", nil, body)
+	hostname, err := os.Hostname()
 	if err != nil {
-		return err
+		logger.WithField("field", "hostname").WithError(err).Warn("failed to gather report field")
 	}
-	_, err = client.Do(ctx, req, nil)
-	return err
-}
-
-func newEmptyStore() cache.Store {
-	return cache.NewStore(func(interface{}) (string, error) { return "", errors.New("this store cannot add elements") })
+	id, err := uuid.GenerateUUID()
+	if err != nil {
+		logger.WithField("field", "id").WithError(err).Warn("failed to gather report field")
+	}
+	return util.Info{
+		KongVersion: kongVersion,
+		KICVersion:  RELEASE,
+		Hostname:    hostname,
+		ID:          id,
+		KongDB:      kongDB,
+	}, nil
 }
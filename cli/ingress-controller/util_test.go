@@ -1,38 +1,122 @@
 package main
 
 import (
+	"net/http"
+	"net/http/httptest"
 	"testing"
+
+	"github.com/kong/kubernetes-ingress-controller/railgun/apis/configuration/v1alpha1"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/cache"
 )
 
-func TestFixVersion(t *testing.T) {
-	validVersions := map[string]string{
-		"0.14.1":                          "0.14.1",
-		"0.14.2rc":                        "0.14.2-rc",
-		"0.14.2rc1":                       "0.14.2-rc1",
-		"0.14.2preview":                   "0.14.2-preview",
-		"0.14.2preview1":                  "0.14.2-preview1",
-		"0.33-enterprise-edition":         "0.33.0-enterprise",
-		"0.33-1-enterprise-edition":       "0.33.1-enterprise",
-		"1.3.0.0-enterprise-edition-lite": "1.3.0-0-enterprise-lite",
-		"1.3.0.0-enterprise-lite":         "1.3.0-0-enterprise-lite",
-	}
-	for inputVersion, expectedVersion := range validVersions {
-		v, err := getSemVerVer(inputVersion)
-		if err != nil {
-			t.Errorf("error converting %s: %v", inputVersion, err)
-		} else if v.String() != expectedVersion {
-			t.Errorf("converting %s, expecting %s, getting %s", inputVersion, expectedVersion, v.String())
+func TestNewUDPIngressInformer(t *testing.T) {
+	stopCh := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/apis/configuration.konghq.com/v1alpha1/namespaces/default/udpingresses" {
+			w.WriteHeader(http.StatusNotFound)
+			return
 		}
-	}
+		w.Header().Set("content-type", "application/json")
+		if r.URL.Query().Get("watch") == "true" {
+			// no change until the informer stops
+			select {
+			case <-stopCh:
+			case <-r.Context().Done():
+			}
+			return
+		}
+		_, _ = w.Write([]byte(`{"apiVersion":"configuration.konghq.com/v1alpha1","kind":"UDPIngressList",` +
+			`"metadata":{"resourceVersion":"1"},"items":[{"apiVersion":"configuration.konghq.com/v1alpha1",` +
+			`"kind":"UDPIngress","metadata":{"namespace":"default","name":"dns"},` +
+			`"spec":{"listenPort":53,"targetPort":53,"host":"coredns.kube-system"}}]}`))
+	}))
+	defer server.Close()
 
-	invalidVersions := []string{
-		"",
-		"0-1-1",
+	informer, err := newUDPIngressInformer(&rest.Config{Host: server.URL}, "default", 0)
+	require.NoError(t, err)
+	defer close(stopCh)
+	go informer.Run(stopCh)
+	require.True(t, cache.WaitForCacheSync(stopCh, informer.HasSynced))
+
+	obj, exists, err := informer.GetStore().GetByKey("default/dns")
+	require.NoError(t, err)
+	require.True(t, exists)
+	udpIngress, ok := obj.(*v1alpha1.UDPIngress)
+	require.True(t, ok)
+	assert.Equal(t, 53, udpIngress.Spec.ListenPort)
+}
+
+func TestReportInfo(t *testing.T) {
+	tests := []struct {
+		name       string
+		root       map[string]interface{}
+		wantErr    bool
+		wantKong   string
+		wantKongDB string
+	}{
+		{
+			name: "valid payload",
+			root: map[string]interface{}{
+				"version":       "2.3.3",
+				"configuration": map[string]interface{}{"database": "off"},
+			},
+			wantKong:   "2.3.3",
+			wantKongDB: "off",
+		},
+		{
+			name:    "empty payload",
+			root:    map[string]interface{}{},
+			wantErr: true,
+		},
+		{
+			name: "version is not a string",
+			root: map[string]interface{}{
+				"version":       2.3,
+				"configuration": map[string]interface{}{"database": "off"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "empty version",
+			root: map[string]interface{}{
+				"version":       "",
+				"configuration": map[string]interface{}{"database": "off"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "configuration is not an object",
+			root: map[string]interface{}{
+				"version":       "2.3.3",
+				"configuration": "off",
+			},
+			wantErr: true,
+		},
+		{
+			name: "missing database",
+			root: map[string]interface{}{
+				"version":       "2.3.3",
+				"configuration": map[string]interface{}{},
+			},
+			wantErr: true,
+		},
 	}
-	for _, inputVersion := range invalidVersions {
-		_, err := getSemVerVer(inputVersion)
-		if err == nil {
-			t.Errorf("expecting error converting %s, getting no errors", inputVersion)
-		}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			info, err := reportInfo(tt.root, logrus.New())
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantKong, info.KongVersion)
+			assert.Equal(t, tt.wantKongDB, info.KongDB)
+			assert.Equal(t, RELEASE, info.KICVersion)
+			assert.NotEmpty(t, info.ID)
+		})
 	}
 }
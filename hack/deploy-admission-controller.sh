@@ -35,6 +35,9 @@ webhooks:
     resources:
     - kongconsumers
     - kongplugins
+    - kongingresses
+    - tcpingresses
+    - udpingresses
   - apiGroups:
     - ''
     apiVersions:
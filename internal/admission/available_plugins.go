@@ -0,0 +1,89 @@
+package admission
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/kong/go-kong/kong"
+)
+
+// availablePluginsFetchTimeout bounds the wait for Kong to list its available
+// plugins, so that a slow Kong doesn't hold the admission request until the
+// API server gives up on it.
+const availablePluginsFetchTimeout = 3 * time.Second
+
+// AvailablePluginStore tells which plugins are available on the running Kong,
+// as listed by the plugins.available_on_server field of its root endpoint.
+// The list is cached for TTL; it is fetched again on the next lookup when
+// Kong could not be reached.
+type AvailablePluginStore struct {
+	client *kong.Client
+	ttl    time.Duration
+	// timeout bounds every fetch of the available plugins.
+	timeout time.Duration
+
+	lock      sync.Mutex
+	plugins   map[string]bool
+	fetchedAt time.Time
+}
+
+// NewAvailablePluginStore creates an AvailablePluginStore.
+func NewAvailablePluginStore(client *kong.Client, ttl time.Duration) *AvailablePluginStore {
+	return &AvailablePluginStore{
+		client:  client,
+		ttl:     ttl,
+		timeout: availablePluginsFetchTimeout,
+	}
+}
+
+// IsAvailable reports whether the plugin name is available on Kong.
+// The second boolean is false if the available plugins could not be fetched,
+// in which case the first one is meaningless.
+func (s *AvailablePluginStore) IsAvailable(ctx context.Context, name string) (bool, bool) {
+	s.lock.Lock()
+	plugins, fetchedAt := s.plugins, s.fetchedAt
+	s.lock.Unlock()
+
+	if plugins == nil || time.Since(fetchedAt) >= s.ttl {
+		ctx, cancel := context.WithTimeout(ctx, s.timeout)
+		defer cancel()
+		var err error
+		plugins, err = s.fetchAvailablePlugins(ctx)
+		if err != nil {
+			return false, false
+		}
+		s.lock.Lock()
+		s.plugins, s.fetchedAt = plugins, time.Now()
+		s.lock.Unlock()
+	}
+	return plugins[name], true
+}
+
+// fetchAvailablePlugins returns the set of plugins available on Kong.
+func (s *AvailablePluginStore) fetchAvailablePlugins(ctx context.Context) (map[string]bool, error) {
+	req, err := s.client.NewRequest("GET", "/", nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	var root struct {
+		Plugins struct {
+			// the values describe the plugin and differ across versions of Kong
+			AvailableOnServer map[string]interface{} `json:"available_on_server"`
+		} `json:"plugins"`
+	}
+	_, err = s.client.Do(ctx, req, &root)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(root.Plugins.AvailableOnServer) == 0 {
+		return nil, errors.New("Kong lists no available plugins")
+	}
+	plugins := make(map[string]bool, len(root.Plugins.AvailableOnServer))
+	for name := range root.Plugins.AvailableOnServer {
+		plugins[name] = true
+	}
+	return plugins, nil
+}
@@ -0,0 +1,75 @@
+package admission
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/kong/go-kong/kong"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAvailablePluginStore_IsAvailable(t *testing.T) {
+	requests := 0
+	rootStatus := http.StatusOK
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if r.URL.Path == "/" {
+			w.WriteHeader(rootStatus)
+			_, _ = w.Write([]byte(`{"plugins":{"available_on_server":{"key-auth":true,"cors":true}}}`))
+			return
+		}
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+	client, err := kong.NewClient(kong.String(server.URL), server.Client())
+	assert.NoError(t, err)
+
+	t.Run("plugins are looked up in the cached list", func(t *testing.T) {
+		requests, rootStatus = 0, http.StatusOK
+		store := NewAvailablePluginStore(client, time.Hour)
+		available, ok := store.IsAvailable(context.Background(), "key-auth")
+		assert.True(t, ok)
+		assert.True(t, available)
+		available, ok = store.IsAvailable(context.Background(), "custom-plugin")
+		assert.True(t, ok)
+		assert.False(t, available)
+		assert.Equal(t, 1, requests)
+	})
+
+	t.Run("expired list is fetched again", func(t *testing.T) {
+		requests, rootStatus = 0, http.StatusOK
+		store := NewAvailablePluginStore(client, 0)
+		store.IsAvailable(context.Background(), "key-auth")
+		store.IsAvailable(context.Background(), "cors")
+		assert.Equal(t, 2, requests)
+	})
+
+	t.Run("unknown when the list can't be fetched", func(t *testing.T) {
+		requests, rootStatus = 0, http.StatusInternalServerError
+		store := NewAvailablePluginStore(client, time.Hour)
+		_, ok := store.IsAvailable(context.Background(), "key-auth")
+		assert.False(t, ok)
+		_, ok = store.IsAvailable(context.Background(), "key-auth")
+		assert.False(t, ok)
+		assert.Equal(t, 2, requests, "failures are not cached")
+	})
+
+	t.Run("unknown when Kong is too slow to answer", func(t *testing.T) {
+		release := make(chan struct{})
+		slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			<-release
+		}))
+		defer slow.Close()
+		defer close(release)
+		slowClient, err := kong.NewClient(kong.String(slow.URL), slow.Client())
+		assert.NoError(t, err)
+
+		store := NewAvailablePluginStore(slowClient, time.Hour)
+		store.timeout = 10 * time.Millisecond
+		_, ok := store.IsAvailable(context.Background(), "key-auth")
+		assert.False(t, ok)
+	})
+}
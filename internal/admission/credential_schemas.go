@@ -0,0 +1,131 @@
+package admission
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/kong/go-kong/kong"
+)
+
+var (
+	// credTypeToSchemaEntity maps credential types to the name of the
+	// entity describing them in Kong's /schemas endpoint.
+	credTypeToSchemaEntity = map[string]string{
+		"key-auth":             "keyauth_credentials",
+		"keyauth_credential":   "keyauth_credentials",
+		"basic-auth":           "basicauth_credentials",
+		"basicauth_credential": "basicauth_credentials",
+		"hmac-auth":            "hmacauth_credentials",
+		"hmacauth_credential":  "hmacauth_credentials",
+		"jwt":                  "jwt_secrets",
+		"jwt_secret":           "jwt_secrets",
+		"oauth2":               "oauth2_credentials",
+		"acl":                  "acls",
+		"mtls-auth":            "mtls_auth_credentials",
+	}
+)
+
+// CredentialSchemaStore derives the fields required by each credential type
+// from the credential schemas of the running Kong.
+// Schemas are cached for TTL and the static credTypeToFields map is used,
+// without being cached, whenever the schema endpoint of Kong is not available.
+type CredentialSchemaStore struct {
+	client *kong.Client
+	// plugins tells whether the plugins providing credential types are
+	// available on Kong; if nil, every credential type is assumed to be.
+	plugins *AvailablePluginStore
+	ttl     time.Duration
+
+	lock    sync.Mutex
+	entries map[string]credentialSchemaEntry
+}
+
+type credentialSchemaEntry struct {
+	fields    []string
+	fetchedAt time.Time
+}
+
+// NewCredentialSchemaStore creates a CredentialSchemaStore.
+func NewCredentialSchemaStore(client *kong.Client, plugins *AvailablePluginStore,
+	ttl time.Duration) *CredentialSchemaStore {
+	return &CredentialSchemaStore{
+		client:  client,
+		plugins: plugins,
+		ttl:     ttl,
+		entries: make(map[string]credentialSchemaEntry),
+	}
+}
+
+// RequiredFields returns the fields a credential of credType must set.
+// The boolean is false if credType is not a known credential type.
+func (s *CredentialSchemaStore) RequiredFields(ctx context.Context,
+	credType string) ([]string, bool) {
+	entity, ok := credTypeToSchemaEntity[credType]
+	if !ok {
+		return nil, false
+	}
+	if entity == "mtls_auth_credentials" && s.plugins != nil {
+		if available, ok := s.plugins.IsAvailable(ctx, "mtls-auth"); ok && !available {
+			// this build of Kong lacks the plugin, nothing can be demanded
+			return nil, true
+		}
+	}
+
+	// lookup in cache
+	s.lock.Lock()
+	entry, ok := s.entries[entity]
+	s.lock.Unlock()
+	if ok && time.Since(entry.fetchedAt) < s.ttl {
+		return entry.fields, true
+	}
+
+	// not present in cache or expired, lookup
+	fields, err := s.fetchRequiredFields(ctx, entity)
+	if err != nil {
+		// older versions of Kong lack the schemas endpoint
+		return credTypeToFields[credType], true
+	}
+	s.lock.Lock()
+	s.entries[entity] = credentialSchemaEntry{
+		fields:    fields,
+		fetchedAt: time.Now(),
+	}
+	s.lock.Unlock()
+	return fields, true
+}
+
+// fetchRequiredFields returns the fields of the entity's schema which must be
+// supplied by the user: required fields without a default which Kong does not
+// generate itself, leaving out the reference to the consumer.
+func (s *CredentialSchemaStore) fetchRequiredFields(ctx context.Context,
+	entity string) ([]string, error) {
+	req, err := s.client.NewRequest("GET", "/schemas/"+entity, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	var schema struct {
+		Fields []map[string]struct {
+			Type     string      `json:"type"`
+			Required bool        `json:"required"`
+			Auto     bool        `json:"auto"`
+			Default  interface{} `json:"default"`
+		} `json:"fields"`
+	}
+	_, err = s.client.Do(ctx, req, &schema)
+	if err != nil {
+		return nil, err
+	}
+
+	var fields []string
+	for _, field := range schema.Fields {
+		for name, attributes := range field {
+			if !attributes.Required || attributes.Auto || attributes.Default != nil ||
+				attributes.Type == "foreign" {
+				continue
+			}
+			fields = append(fields, name)
+		}
+	}
+	return fields, nil
+}
@@ -0,0 +1,87 @@
+package admission
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/kong/go-kong/kong"
+	"github.com/stretchr/testify/assert"
+)
+
+const basicAuthSchema = `{"fields":[
+	{"id":{"type":"string","auto":true,"uuid":true}},
+	{"created_at":{"type":"integer","auto":true,"timestamp":true}},
+	{"consumer":{"type":"foreign","required":true,"reference":"consumers"}},
+	{"username":{"type":"string","required":true,"unique":true}},
+	{"password":{"type":"string","required":true}},
+	{"tags":{"type":"set","required":false}}
+]}`
+
+func TestCredentialSchemaStore_RequiredFields(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		switch r.URL.Path {
+		case "/schemas/basicauth_credentials":
+			_, _ = w.Write([]byte(basicAuthSchema))
+			return
+		case "/":
+			_, _ = w.Write([]byte(`{"plugins":{"available_on_server":{"key-auth":true,"basic-auth":true}}}`))
+			return
+		}
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+	client, err := kong.NewClient(kong.String(server.URL), server.Client())
+	assert.NoError(t, err)
+
+	t.Run("fields are derived from the schema and cached", func(t *testing.T) {
+		requests = 0
+		store := NewCredentialSchemaStore(client, nil, time.Hour)
+		for i := 0; i < 3; i++ {
+			fields, ok := store.RequiredFields(context.Background(), "basic-auth")
+			assert.True(t, ok)
+			sort.Strings(fields)
+			assert.Equal(t, []string{"password", "username"}, fields)
+		}
+		assert.Equal(t, 1, requests)
+	})
+
+	t.Run("expired entries are fetched again", func(t *testing.T) {
+		requests = 0
+		store := NewCredentialSchemaStore(client, nil, 0)
+		store.RequiredFields(context.Background(), "basic-auth")
+		store.RequiredFields(context.Background(), "basicauth_credential")
+		assert.Equal(t, 2, requests)
+	})
+
+	t.Run("static fields are used, but not cached, when the schema is unavailable", func(t *testing.T) {
+		requests = 0
+		store := NewCredentialSchemaStore(client, nil, time.Hour)
+		for i := 0; i < 2; i++ {
+			fields, ok := store.RequiredFields(context.Background(), "key-auth")
+			assert.True(t, ok)
+			assert.Equal(t, []string{"key"}, fields)
+		}
+		assert.Equal(t, 2, requests)
+	})
+
+	t.Run("unknown credential types are rejected", func(t *testing.T) {
+		store := NewCredentialSchemaStore(client, nil, time.Hour)
+		_, ok := store.RequiredFields(context.Background(), "foo")
+		assert.False(t, ok)
+	})
+
+	t.Run("mtls-auth fields are not demanded on Kong lacking the plugin", func(t *testing.T) {
+		requests = 0
+		store := NewCredentialSchemaStore(client, NewAvailablePluginStore(client, time.Hour), time.Hour)
+		fields, ok := store.RequiredFields(context.Background(), "mtls-auth")
+		assert.True(t, ok)
+		assert.Empty(t, fields)
+		assert.Equal(t, 1, requests, "only the available plugins are fetched")
+	})
+}
@@ -0,0 +1,46 @@
+package admission
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+
+	"github.com/kong/go-kong/kong"
+)
+
+// FailPolicy decides what the admission webhook answers when an object can't
+// be validated because Kong's Admin API is unreachable or failing.
+//
+// Failing open keeps changes flowing while Kong restarts or is down, at the
+// cost of admitting objects Kong may reject later, when the controller syncs
+// them. Failing closed never admits an object Kong hasn't validated, at the
+// cost of blocking every change to the validated resources during an outage.
+type FailPolicy string
+
+const (
+	// FailPolicyOpen admits the objects which can't be validated, with a warning.
+	FailPolicyOpen FailPolicy = "open"
+	// FailPolicyClosed rejects the objects which can't be validated.
+	FailPolicyClosed FailPolicy = "closed"
+)
+
+// ParseFailPolicy returns the FailPolicy named policy.
+func ParseFailPolicy(policy string) (FailPolicy, error) {
+	switch FailPolicy(policy) {
+	case FailPolicyOpen, FailPolicyClosed:
+		return FailPolicy(policy), nil
+	}
+	return "", fmt.Errorf("invalid fail policy '%s': must be %s or %s", policy, FailPolicyOpen, FailPolicyClosed)
+}
+
+// isKongUnavailable tells whether err means Kong's Admin API could not be
+// reached or failed to answer, rather than answered the object is invalid:
+// a connection error or a 5xx status. A 404 is a genuine answer.
+func isKongUnavailable(err error) bool {
+	var apiErr *kong.APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.Code() >= 500
+	}
+	var urlErr *url.Error
+	return errors.As(err, &urlErr)
+}
@@ -7,10 +7,15 @@ import (
 	"io/ioutil"
 	"net/http"
 
+	"github.com/kong/kubernetes-ingress-controller/pkg/annotations"
 	configuration "github.com/kong/kubernetes-ingress-controller/pkg/apis/configuration/v1"
+	configurationv1beta1 "github.com/kong/kubernetes-ingress-controller/pkg/apis/configuration/v1beta1"
+	"github.com/kong/kubernetes-ingress-controller/pkg/kongstate"
+	"github.com/kong/kubernetes-ingress-controller/railgun/apis/configuration/v1alpha1"
 	"github.com/sirupsen/logrus"
 	admission "k8s.io/api/admission/v1"
 	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
 	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/serializer"
@@ -28,6 +33,18 @@ type Server struct {
 	// it the server to validate.
 	Validator KongValidator
 
+	// FailPolicy decides the response for the objects which can't be
+	// validated because Kong is unavailable. It defaults to FailPolicyClosed.
+	FailPolicy FailPolicy
+
+	// IngressClass is the class of the Ingresses handled by the controller. The paths of the
+	// Ingresses of other classes are left to their own controller.
+	IngressClass string
+	// IngressV1Beta1ClassMatching and IngressV1ClassMatching tell how the class of v1beta1 and v1
+	// Ingresses is matched against IngressClass. The zero value validates the paths of every Ingress.
+	IngressV1Beta1ClassMatching annotations.ClassMatching
+	IngressV1ClassMatching      annotations.ClassMatching
+
 	Logger logrus.FieldLogger
 }
 
@@ -53,6 +70,9 @@ func (a Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	response, err := a.handleValidation(r.Context(), *review.Request)
+	if err != nil && isKongUnavailable(err) {
+		response, err = a.kongUnavailable(*review.Request, err), nil
+	}
 	if err != nil {
 		a.Logger.Errorf("failed to run validation: %v", err)
 		http.Error(w, err.Error(), http.StatusInternalServerError)
@@ -82,12 +102,104 @@ var (
 		Group:    configuration.SchemeGroupVersion.Group,
 		Version:  configuration.SchemeGroupVersion.Version,
 		Resource: "kongplugins"}
+	kongIngressGVResource = meta.GroupVersionResource{
+		Group:    configuration.SchemeGroupVersion.Group,
+		Version:  configuration.SchemeGroupVersion.Version,
+		Resource: "kongingresses"}
+	tcpIngressGVResource = meta.GroupVersionResource{
+		Group:    configurationv1beta1.SchemeGroupVersion.Group,
+		Version:  configurationv1beta1.SchemeGroupVersion.Version,
+		Resource: "tcpingresses"}
+	udpIngressGVResource = meta.GroupVersionResource{
+		Group:    v1alpha1.GroupVersion.Group,
+		Version:  v1alpha1.GroupVersion.Version,
+		Resource: "udpingresses"}
 	secretGVResource = meta.GroupVersionResource{
 		Group:    corev1.SchemeGroupVersion.Group,
 		Version:  corev1.SchemeGroupVersion.Version,
 		Resource: "secrets"}
+	serviceGVResource = meta.GroupVersionResource{
+		Group:    corev1.SchemeGroupVersion.Group,
+		Version:  corev1.SchemeGroupVersion.Version,
+		Resource: "services"}
+	// ingressGVResources are the versions of Ingresses which may reference KongPlugins.
+	ingressGVResources = []meta.GroupVersionResource{
+		{Group: "networking.k8s.io", Version: "v1", Resource: "ingresses"},
+		{Group: "networking.k8s.io", Version: "v1beta1", Resource: "ingresses"},
+		{Group: "extensions", Version: "v1beta1", Resource: "ingresses"},
+	}
 )
 
+// isIngressGVResource tells whether resource is one of ingressGVResources.
+func isIngressGVResource(resource meta.GroupVersionResource) bool {
+	for _, ingress := range ingressGVResources {
+		if resource == ingress {
+			return true
+		}
+	}
+	return false
+}
+
+// isKongIngress tells whether the Ingress raw, of the version resource, is of the class of the server,
+// matched as the store of the controller does.
+func (a Server) isKongIngress(resource meta.GroupVersionResource, obj *meta.PartialObjectMetadata,
+	raw []byte) (bool, error) {
+	if resource.Group != "networking.k8s.io" || resource.Version != "v1" {
+		return annotations.IngressClassValidatorFuncFromObjectMeta(a.IngressClass)(
+			&obj.ObjectMeta, a.IngressV1Beta1ClassMatching), nil
+	}
+	if obj.GetAnnotations()[annotations.IngressClassKey] != "" {
+		return annotations.IngressClassValidatorFuncFromObjectMeta(a.IngressClass)(
+			&obj.ObjectMeta, a.IngressV1ClassMatching), nil
+	}
+	var ingress networkingv1.Ingress
+	if err := json.Unmarshal(raw, &ingress); err != nil {
+		return false, err
+	}
+	return annotations.IngressClassValidatorFuncFromV1Ingress(a.IngressClass)(
+		&ingress, a.IngressV1ClassMatching), nil
+}
+
+// ingressRegexPaths returns the paths of the Ingress raw, of the version resource, which Kong compiles as
+// regular expressions. These are the paths passed to Kong as is: all of the paths of v1beta1 Ingresses, and
+// the ImplementationSpecific ones of v1 Ingresses, whose Exact and Prefix paths are escaped.
+func ingressRegexPaths(resource meta.GroupVersionResource, raw []byte) ([]string, error) {
+	// the rules of all versions of Ingresses share this structure
+	var ingress struct {
+		Spec struct {
+			Rules []struct {
+				HTTP *struct {
+					Paths []struct {
+						Path     string  `json:"path"`
+						PathType *string `json:"pathType"`
+					} `json:"paths"`
+				} `json:"http"`
+			} `json:"rules"`
+		} `json:"spec"`
+	}
+	if err := json.Unmarshal(raw, &ingress); err != nil {
+		return nil, err
+	}
+	var paths []string
+	seen := map[string]bool{}
+	for _, rule := range ingress.Spec.Rules {
+		if rule.HTTP == nil {
+			continue
+		}
+		for _, path := range rule.HTTP.Paths {
+			if resource.Group == "networking.k8s.io" && resource.Version == "v1" && path.PathType != nil &&
+				*path.PathType != string(networkingv1.PathTypeImplementationSpecific) {
+				continue
+			}
+			if kongstate.IsRegexPath(path.Path) && !seen[path.Path] {
+				seen[path.Path] = true
+				paths = append(paths, path.Path)
+			}
+		}
+	}
+	return paths, nil
+}
+
 func (a Server) handleValidation(ctx context.Context, request admission.AdmissionRequest) (
 	*admission.AdmissionResponse, error) {
 	var response admission.AdmissionResponse
@@ -95,9 +207,38 @@ func (a Server) handleValidation(ctx context.Context, request admission.Admissio
 	var ok bool
 	var message string
 	var err error
+	// pluginReferrer is the object being validated if it may reference KongPlugins
+	var pluginReferrer meta.Object
 
-	switch request.Resource {
-	case consumerGVResource:
+	switch {
+	case request.Resource == serviceGVResource || isIngressGVResource(request.Resource):
+		// only the plugins referenced by these objects are validated
+		var obj meta.PartialObjectMetadata
+		if err := json.Unmarshal(request.Object.Raw, &obj); err != nil {
+			return nil, err
+		}
+		ok, pluginReferrer = true, &obj
+		var paths []string
+		if isIngressGVResource(request.Resource) {
+			kong, err := a.isKongIngress(request.Resource, &obj, request.Object.Raw)
+			if err != nil {
+				return nil, err
+			}
+			// the paths of the Ingresses of other classes are never sent to Kong
+			if kong {
+				paths, err = ingressRegexPaths(request.Resource, request.Object.Raw)
+				if err != nil {
+					return nil, err
+				}
+			}
+		}
+		if len(paths) > 0 {
+			ok, message, err = a.Validator.ValidateIngressPaths(ctx, paths)
+			if err != nil {
+				return nil, err
+			}
+		}
+	case request.Resource == consumerGVResource:
 		consumer := configuration.KongConsumer{}
 		deserializer := codecs.UniversalDeserializer()
 		_, _, err = deserializer.Decode(request.Object.Raw,
@@ -105,6 +246,7 @@ func (a Server) handleValidation(ctx context.Context, request admission.Admissio
 		if err != nil {
 			return nil, err
 		}
+		pluginReferrer = &consumer
 		switch request.Operation {
 		case admission.Create:
 			ok, message, err = a.Validator.ValidateConsumer(ctx, consumer)
@@ -118,20 +260,36 @@ func (a Server) handleValidation(ctx context.Context, request admission.Admissio
 			if err != nil {
 				return nil, err
 			}
-			// validate only if the username is being changed
-			if consumer.Username != oldConsumer.Username {
+			// only the identifiers being changed can conflict with other consumers
+			changed := consumer
+			if changed.Username == oldConsumer.Username {
+				changed.Username = ""
+			}
+			if changed.CustomID == oldConsumer.CustomID {
+				changed.CustomID = ""
+			}
+			switch {
+			case consumer.Username == "" && consumer.CustomID == "":
 				ok, message, err = a.Validator.ValidateConsumer(ctx, consumer)
-				if err != nil {
-					return nil, err
-				}
-			} else {
+			case changed.Username != "" || changed.CustomID != "":
+				ok, message, err = a.Validator.ValidateConsumer(ctx, changed)
+			default:
 				ok = true
 			}
+			if err != nil {
+				return nil, err
+			}
 		default:
 			return nil, fmt.Errorf("unknown operation '%v'", string(request.Operation))
 		}
+		if ok {
+			ok, message, err = a.Validator.ValidateConsumerCredentials(ctx, consumer)
+			if err != nil {
+				return nil, err
+			}
+		}
 
-	case pluginGVResource:
+	case request.Resource == pluginGVResource:
 		plugin := configuration.KongPlugin{}
 		deserializer := codecs.UniversalDeserializer()
 		_, _, err = deserializer.Decode(request.Object.Raw,
@@ -140,25 +298,60 @@ func (a Server) handleValidation(ctx context.Context, request admission.Admissio
 			return nil, err
 		}
 
-		ok, message, err = a.Validator.ValidatePlugin(plugin)
+		ok, message, err = a.Validator.ValidatePlugin(ctx, plugin)
 		if err != nil {
 			return nil, err
 		}
-	case secretGVResource:
-		secret := corev1.Secret{}
+	case request.Resource == kongIngressGVResource:
+		kongIngress := configuration.KongIngress{}
 		deserializer := codecs.UniversalDeserializer()
 		_, _, err = deserializer.Decode(request.Object.Raw,
-			nil, &secret)
+			nil, &kongIngress)
+		if err != nil {
+			return nil, err
+		}
+
+		ok, message, err = a.Validator.ValidateKongIngress(ctx, kongIngress)
+		if err != nil {
+			return nil, err
+		}
+	case request.Resource == tcpIngressGVResource:
+		tcpIngress := configurationv1beta1.TCPIngress{}
+		deserializer := codecs.UniversalDeserializer()
+		_, _, err = deserializer.Decode(request.Object.Raw,
+			nil, &tcpIngress)
+		if err != nil {
+			return nil, err
+		}
+
+		ok, message, err = a.Validator.ValidateTCPIngress(tcpIngress)
 		if err != nil {
 			return nil, err
 		}
-		if _, ok = secret.Data["kongCredType"]; !ok {
-			// secret does not look like a credential resource in Kong
-			ok = true
-			break
+		pluginReferrer = &tcpIngress
+	case request.Resource == udpIngressGVResource:
+		udpIngress := v1alpha1.UDPIngress{}
+		deserializer := codecs.UniversalDeserializer()
+		_, _, err = deserializer.Decode(request.Object.Raw,
+			nil, &udpIngress)
+		if err != nil {
+			return nil, err
 		}
 
-		ok, message, err = a.Validator.ValidateCredential(secret)
+		ok, message, err = a.Validator.ValidateUDPIngress(udpIngress)
+		if err != nil {
+			return nil, err
+		}
+		pluginReferrer = &udpIngress
+	case request.Resource == secretGVResource:
+		secret := corev1.Secret{}
+		deserializer := codecs.UniversalDeserializer()
+		_, _, err = deserializer.Decode(request.Object.Raw,
+			nil, &secret)
+		if err != nil {
+			return nil, err
+		}
+		ok, message, err = a.Validator.ValidateCredential(ctx, secret)
 		if err != nil {
 			return nil, err
 		}
@@ -167,6 +360,9 @@ func (a Server) handleValidation(ctx context.Context, request admission.Admissio
 			request.Resource.Group, request.Resource.Version,
 			request.Resource.Resource)
 	}
+	if ok && pluginReferrer != nil {
+		ok, message, err = a.Validator.ValidatePluginReferences(pluginReferrer)
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -180,3 +376,23 @@ func (a Server) handleValidation(ctx context.Context, request admission.Admissio
 	}
 	return &response, nil
 }
+
+// kongUnavailable answers request, whose object could not be validated because
+// of err from Kong, as decided by the fail policy of the server.
+func (a Server) kongUnavailable(request admission.AdmissionRequest, err error) *admission.AdmissionResponse {
+	response := &admission.AdmissionResponse{UID: request.UID}
+	if a.FailPolicy == FailPolicyOpen {
+		a.Logger.Warnf("admitting %s %s/%s without validation, kong is unavailable: %v",
+			request.Kind.Kind, request.Namespace, request.Name, err)
+		response.Allowed = true
+		response.Warnings = []string{fmt.Sprintf("admitted without validation, kong is unavailable: %v", err)}
+		return response
+	}
+	a.Logger.Errorf("rejecting %s %s/%s, kong is unavailable to validate it: %v",
+		request.Kind.Kind, request.Namespace, request.Name, err)
+	response.Result = &meta.Status{
+		Code:    http.StatusServiceUnavailable,
+		Message: fmt.Sprintf("kong is unavailable to validate the object: %v", err),
+	}
+	return response
+}
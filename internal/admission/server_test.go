@@ -9,13 +9,18 @@ import (
 	"net/http/httptest"
 	"testing"
 
+	"github.com/kong/go-kong/kong"
+	"github.com/kong/kubernetes-ingress-controller/pkg/annotations"
 	configuration "github.com/kong/kubernetes-ingress-controller/pkg/apis/configuration/v1"
+	configurationv1beta1 "github.com/kong/kubernetes-ingress-controller/pkg/apis/configuration/v1beta1"
+	"github.com/kong/kubernetes-ingress-controller/railgun/apis/configuration/v1alpha1"
 	"github.com/lithammer/dedent"
 	"github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/assert"
 	admission "k8s.io/api/admission/v1"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
 )
 
 var decoder = codecs.UniversalDeserializer()
@@ -31,16 +36,45 @@ func (v KongFakeValidator) ValidateConsumer(_ context.Context,
 	return v.Result, v.Message, v.Error
 }
 
-func (v KongFakeValidator) ValidatePlugin(
+func (v KongFakeValidator) ValidateConsumerCredentials(_ context.Context,
+	consumer configuration.KongConsumer) (bool, string, error) {
+	return v.Result, v.Message, v.Error
+}
+
+func (v KongFakeValidator) ValidatePlugin(_ context.Context,
 	k8sPlugin configuration.KongPlugin) (bool, string, error) {
 	return v.Result, v.Message, v.Error
 }
 
-func (v KongFakeValidator) ValidateCredential(
+func (v KongFakeValidator) ValidateCredential(_ context.Context,
 	secret corev1.Secret) (bool, string, error) {
 	return v.Result, v.Message, v.Error
 }
 
+func (v KongFakeValidator) ValidateKongIngress(_ context.Context,
+	kongIngress configuration.KongIngress) (bool, string, error) {
+	return v.Result, v.Message, v.Error
+}
+
+func (v KongFakeValidator) ValidateIngressPaths(_ context.Context,
+	paths []string) (bool, string, error) {
+	return v.Result, v.Message, v.Error
+}
+
+func (v KongFakeValidator) ValidateTCPIngress(
+	tcpIngress configurationv1beta1.TCPIngress) (bool, string, error) {
+	return v.Result, v.Message, v.Error
+}
+
+func (v KongFakeValidator) ValidateUDPIngress(
+	udpIngress v1alpha1.UDPIngress) (bool, string, error) {
+	return v.Result, v.Message, v.Error
+}
+
+func (v KongFakeValidator) ValidatePluginReferences(obj metav1.Object) (bool, string, error) {
+	return v.Result, v.Message, v.Error
+}
+
 func TestServeHTTPBasic(t *testing.T) {
 	assert := assert.New(t)
 	res := httptest.NewRecorder()
@@ -259,6 +293,105 @@ func TestValidationWebhook(t *testing.T) {
 				wantRespCode:       http.StatusInternalServerError,
 				wantFailureMessage: "error making API call to kong\n",
 			},
+			{
+				name: "kong consumer validator error on custom_id change",
+				reqBody: dedent.Dedent(`
+					{
+						"kind": "AdmissionReview",
+						"apiVersion": "` + apiVersion + `",
+						"request": {
+							"uid": "b2df61dd-ab5b-4cb4-9be0-878533c83892",
+							"resource": {
+								"group": "configuration.konghq.com",
+								"version": "v1",
+								"resource": "kongconsumers"
+							},
+							"object": {
+								"apiVersion": "configuration.konghq.com/v1",
+								"kind": "KongConsumer",
+							"username":"foo",
+							"custom_id":"foo-id"
+							},
+							"oldObject": {
+								"apiVersion": "configuration.konghq.com/v1",
+								"kind": "KongConsumer",
+							"username":"foo"
+							},
+						"operation": "UPDATE"
+						}
+					}`),
+				validator:          KongFakeValidator{Error: errors.New("error making API call to kong")},
+				wantRespCode:       http.StatusInternalServerError,
+				wantFailureMessage: "error making API call to kong\n",
+			},
+			{
+				name: "validate plugin references of an ingress",
+				reqBody: dedent.Dedent(`
+					{
+						"kind": "AdmissionReview",
+						"apiVersion": "` + apiVersion + `",
+						"request": {
+							"uid": "b2df61dd-ab5b-4cb4-9be0-878533c83892",
+							"resource": {
+								"group": "networking.k8s.io",
+								"version": "v1",
+								"resource": "ingresses"
+							},
+							"object": {
+								"apiVersion": "networking.k8s.io/v1",
+								"kind": "Ingress",
+								"metadata": {
+									"name": "foo",
+									"annotations": {"konghq.com/plugins": "limit-1,limit-2"}
+								}
+							},
+						"operation": "CREATE"
+						}
+					}`),
+				validator:    KongFakeValidator{Result: false, Message: "duplicate plugins"},
+				wantRespCode: http.StatusOK,
+				wantSuccessResponse: admission.AdmissionResponse{
+					UID:     "b2df61dd-ab5b-4cb4-9be0-878533c83892",
+					Allowed: false,
+					Result: &metav1.Status{
+						Code:    http.StatusBadRequest,
+						Message: "duplicate plugins",
+					},
+				},
+			},
+			{
+				name: "validate regex paths of an ingress",
+				reqBody: dedent.Dedent(`
+					{
+						"kind": "AdmissionReview",
+						"apiVersion": "` + apiVersion + `",
+						"request": {
+							"uid": "b2df61dd-ab5b-4cb4-9be0-878533c83892",
+							"resource": {
+								"group": "networking.k8s.io",
+								"version": "v1beta1",
+								"resource": "ingresses"
+							},
+							"object": {
+								"apiVersion": "networking.k8s.io/v1beta1",
+								"kind": "Ingress",
+								"metadata": {"name": "foo"},
+								"spec": {"rules": [{"http": {"paths": [{"path": "/foo("}]}}]}
+							},
+						"operation": "CREATE"
+						}
+					}`),
+				validator:    KongFakeValidator{Result: false, Message: `invalid path "/foo("`},
+				wantRespCode: http.StatusOK,
+				wantSuccessResponse: admission.AdmissionResponse{
+					UID:     "b2df61dd-ab5b-4cb4-9be0-878533c83892",
+					Allowed: false,
+					Result: &metav1.Status{
+						Code:    http.StatusBadRequest,
+						Message: `invalid path "/foo("`,
+					},
+				},
+			},
 			{
 				name: "unknown resource",
 				reqBody: dedent.Dedent(`
@@ -310,6 +443,37 @@ func TestValidationWebhook(t *testing.T) {
 					Result:  &metav1.Status{},
 				},
 			},
+			{
+				name: "validate kong ingress invalid",
+				reqBody: dedent.Dedent(`
+					{
+						"kind": "AdmissionReview",
+						"apiVersion": "` + apiVersion + `",
+						"request": {
+							"uid": "b2df61dd-ab5b-4cb4-9be0-878533c83892",
+							"resource": {
+								"group": "configuration.konghq.com",
+								"version": "v1",
+								"resource": "kongingresses"
+							},
+							"object": {
+								"apiVersion": "configuration.konghq.com/v1",
+								"kind": "KongIngress",
+								"upstream": {"hash_on": "nonsense"}
+							}
+						}
+					}`),
+				validator:    KongFakeValidator{Result: false, Message: "invalid upstream"},
+				wantRespCode: http.StatusOK,
+				wantSuccessResponse: admission.AdmissionResponse{
+					UID:     "b2df61dd-ab5b-4cb4-9be0-878533c83892",
+					Allowed: false,
+					Result: &metav1.Status{
+						Code:    400,
+						Message: "invalid upstream",
+					},
+				},
+			},
 		} {
 			t.Run(fmt.Sprintf("%s/%s", apiVersion, tt.name), func(t *testing.T) {
 				// arrange
@@ -340,3 +504,188 @@ func TestValidationWebhook(t *testing.T) {
 		}
 	}
 }
+
+func TestIngressRegexPaths(t *testing.T) {
+	v1 := metav1.GroupVersionResource{Group: "networking.k8s.io", Version: "v1", Resource: "ingresses"}
+	v1beta1 := metav1.GroupVersionResource{Group: "extensions", Version: "v1beta1", Resource: "ingresses"}
+	raw := []byte(`{"spec": {"rules": [
+		{"http": {"paths": [
+			{"path": "/plain", "pathType": "ImplementationSpecific"},
+			{"path": "/foo(", "pathType": "ImplementationSpecific"},
+			{"path": "/bar/[0-9]+"},
+			{"path": "/exact(", "pathType": "Exact"},
+			{"path": "/prefix(", "pathType": "Prefix"}
+		]}},
+		{"host": "example.com"},
+		{"http": {"paths": [{"path": "/foo(", "pathType": "ImplementationSpecific"}]}}
+	]}}`)
+
+	paths, err := ingressRegexPaths(v1, raw)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"/foo(", "/bar/[0-9]+"}, paths, "exact and prefix paths are escaped")
+
+	paths, err = ingressRegexPaths(v1beta1, raw)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"/foo(", "/bar/[0-9]+", "/exact(", "/prefix("}, paths, "v1beta1 paths are passed as is")
+}
+
+// pathsValidator rejects every regex path, and accepts everything else.
+type pathsValidator struct {
+	KongFakeValidator
+}
+
+func (v pathsValidator) ValidateIngressPaths(_ context.Context, paths []string) (bool, string, error) {
+	return false, fmt.Sprintf("invalid paths %v", paths), nil
+}
+
+func TestHandleValidationIngressClass(t *testing.T) {
+	v1 := metav1.GroupVersionResource{Group: "networking.k8s.io", Version: "v1", Resource: "ingresses"}
+	v1beta1 := metav1.GroupVersionResource{Group: "networking.k8s.io", Version: "v1beta1", Resource: "ingresses"}
+	ingress := func(metadata string, spec string) []byte {
+		return []byte(`{"metadata": ` + metadata + `, "spec": {` + spec +
+			`"rules": [{"http": {"paths": [{"path": "/foo(", "pathType": "ImplementationSpecific"}]}}]}}`)
+	}
+	kongAnnotation := `{"name": "foo", "annotations": {"kubernetes.io/ingress.class": "kong"}}`
+	otherAnnotation := `{"name": "foo", "annotations": {"kubernetes.io/ingress.class": "nginx"}}`
+	classless := `{"name": "foo"}`
+
+	for _, tt := range []struct {
+		name          string
+		resource      metav1.GroupVersionResource
+		object        []byte
+		classless     bool
+		wantValidated bool
+	}{
+		{name: "v1 annotated kong", resource: v1, object: ingress(kongAnnotation, ""), wantValidated: true},
+		{name: "v1 annotated other class", resource: v1, object: ingress(otherAnnotation, "")},
+		{name: "v1 class name kong", resource: v1, object: ingress(classless, `"ingressClassName": "kong",`),
+			wantValidated: true},
+		{name: "v1 class name other class", resource: v1, object: ingress(classless, `"ingressClassName": "nginx",`)},
+		{name: "v1 classless", resource: v1, object: ingress(classless, "")},
+		{name: "v1 classless, processed", resource: v1, object: ingress(classless, ""), classless: true,
+			wantValidated: true},
+		{name: "v1beta1 annotated kong", resource: v1beta1, object: ingress(kongAnnotation, ""), wantValidated: true},
+		{name: "v1beta1 annotated other class", resource: v1beta1, object: ingress(otherAnnotation, "")},
+		{name: "v1beta1 classless", resource: v1beta1, object: ingress(classless, "")},
+		{name: "v1beta1 classless, processed", resource: v1beta1, object: ingress(classless, ""), classless: true,
+			wantValidated: true},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			matching := annotations.ExactClassMatch
+			if tt.classless {
+				matching = annotations.ExactOrEmptyClassMatch
+			}
+			server := Server{
+				Validator:                   pathsValidator{KongFakeValidator{Result: true}},
+				IngressClass:                annotations.DefaultIngressClass,
+				IngressV1Beta1ClassMatching: matching,
+				IngressV1ClassMatching:      matching,
+				Logger:                      logrus.New(),
+			}
+			response, err := server.handleValidation(context.Background(), admission.AdmissionRequest{
+				Resource:  tt.resource,
+				Operation: admission.Create,
+				Object:    runtime.RawExtension{Raw: tt.object},
+			})
+			assert.NoError(t, err)
+			assert.Equal(t, !tt.wantValidated, response.Allowed)
+		})
+	}
+}
+
+func TestServeHTTPKongUnavailable(t *testing.T) {
+	consumerReview := []byte(`{
+		"kind": "AdmissionReview",
+		"apiVersion": "admission.k8s.io/v1",
+		"request": {
+			"uid": "b2df61dd-ab5b-4cb4-9be0-878533c83892",
+			"kind": {"group": "configuration.konghq.com", "version": "v1", "kind": "KongConsumer"},
+			"resource": {"group": "configuration.konghq.com", "version": "v1", "resource": "kongconsumers"},
+			"namespace": "default",
+			"name": "foo",
+			"object": {"apiVersion": "configuration.konghq.com/v1", "kind": "KongConsumer", "username": "foo"},
+			"operation": "CREATE"
+		}
+	}`)
+	pluginReview := []byte(`{
+		"kind": "AdmissionReview",
+		"apiVersion": "admission.k8s.io/v1",
+		"request": {
+			"uid": "b2df61dd-ab5b-4cb4-9be0-878533c83892",
+			"kind": {"group": "configuration.konghq.com", "version": "v1", "kind": "KongPlugin"},
+			"resource": {"group": "configuration.konghq.com", "version": "v1", "resource": "kongplugins"},
+			"namespace": "default",
+			"name": "foo",
+			"object": {"apiVersion": "configuration.konghq.com/v1", "kind": "KongPlugin", "plugin": "key-auth"},
+			"operation": "CREATE"
+		}
+	}`)
+
+	notFound := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte(`{"message":"Not found"}`))
+	}))
+	defer notFound.Close()
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		_, _ = w.Write([]byte(`{"message":"failure to get a peer from the ring-balancer"}`))
+	}))
+	defer failing.Close()
+	down := httptest.NewServer(http.NotFoundHandler())
+	down.Close()
+
+	for _, tt := range []struct {
+		name        string
+		review      []byte
+		kongURL     string
+		policy      FailPolicy
+		wantAllowed bool
+		wantCode    int32
+		wantWarning bool
+	}{
+		{name: "consumer not found", review: consumerReview, kongURL: notFound.URL, policy: FailPolicyClosed,
+			wantAllowed: true},
+		{name: "kong failing, fail closed", review: consumerReview, kongURL: failing.URL, policy: FailPolicyClosed,
+			wantCode: http.StatusServiceUnavailable},
+		{name: "kong failing, fail open", review: consumerReview, kongURL: failing.URL, policy: FailPolicyOpen,
+			wantAllowed: true, wantWarning: true},
+		{name: "kong down, fail closed", review: consumerReview, kongURL: down.URL, policy: FailPolicyClosed,
+			wantCode: http.StatusServiceUnavailable},
+		{name: "kong down, fail open", review: consumerReview, kongURL: down.URL, policy: FailPolicyOpen,
+			wantAllowed: true, wantWarning: true},
+		{name: "plugin, kong failing, fail closed", review: pluginReview, kongURL: failing.URL,
+			policy: FailPolicyClosed, wantCode: http.StatusServiceUnavailable},
+		{name: "plugin, kong failing, fail open", review: pluginReview, kongURL: failing.URL,
+			policy: FailPolicyOpen, wantAllowed: true, wantWarning: true},
+		{name: "plugin, kong down, fail closed", review: pluginReview, kongURL: down.URL,
+			policy: FailPolicyClosed, wantCode: http.StatusServiceUnavailable},
+		{name: "plugin, kong down, fail open", review: pluginReview, kongURL: down.URL,
+			policy: FailPolicyOpen, wantAllowed: true, wantWarning: true},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			client, err := kong.NewClient(kong.String(tt.kongURL), http.DefaultClient)
+			assert.NoError(t, err)
+			server := Server{
+				Validator:  KongHTTPValidator{Client: client, Logger: logrus.New()},
+				FailPolicy: tt.policy,
+				Logger:     logrus.New(),
+			}
+
+			res := httptest.NewRecorder()
+			req, err := http.NewRequest("POST", "", bytes.NewBuffer(tt.review))
+			assert.NoError(t, err)
+			server.ServeHTTP(res, req)
+
+			assert.Equal(t, http.StatusOK, res.Code)
+			var review admission.AdmissionReview
+			_, _, err = decoder.Decode(res.Body.Bytes(), nil, &review)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantAllowed, review.Response.Allowed)
+			if tt.wantCode != 0 {
+				assert.Equal(t, tt.wantCode, review.Response.Result.Code)
+				assert.Contains(t, review.Response.Result.Message, "kong is unavailable to validate the object")
+			}
+			assert.Equal(t, tt.wantWarning, len(review.Response.Warnings) == 1)
+		})
+	}
+}
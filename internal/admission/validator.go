@@ -2,22 +2,40 @@ package admission
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
 	"strings"
 
+	"github.com/blang/semver"
 	"github.com/kong/go-kong/kong"
+	"github.com/kong/kubernetes-ingress-controller/pkg/annotations"
 	configurationv1 "github.com/kong/kubernetes-ingress-controller/pkg/apis/configuration/v1"
+	configurationv1beta1 "github.com/kong/kubernetes-ingress-controller/pkg/apis/configuration/v1beta1"
 	"github.com/kong/kubernetes-ingress-controller/pkg/kongstate"
+	"github.com/kong/kubernetes-ingress-controller/pkg/sendconfig"
 	"github.com/kong/kubernetes-ingress-controller/pkg/store"
+	"github.com/kong/kubernetes-ingress-controller/pkg/util"
+	"github.com/kong/kubernetes-ingress-controller/railgun/apis/configuration/v1alpha1"
 	"github.com/sirupsen/logrus"
 	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
 // KongValidator validates Kong entities.
 type KongValidator interface {
 	ValidateConsumer(ctx context.Context, consumer configurationv1.KongConsumer) (bool, string, error)
-	ValidatePlugin(consumer configurationv1.KongPlugin) (bool, string, error)
-	ValidateCredential(secret corev1.Secret) (bool, string, error)
+	ValidateConsumerCredentials(ctx context.Context, consumer configurationv1.KongConsumer) (bool, string, error)
+	ValidatePlugin(ctx context.Context, plugin configurationv1.KongPlugin) (bool, string, error)
+	ValidateCredential(ctx context.Context, secret corev1.Secret) (bool, string, error)
+	ValidateKongIngress(ctx context.Context, kongIngress configurationv1.KongIngress) (bool, string, error)
+	ValidateIngressPaths(ctx context.Context, paths []string) (bool, string, error)
+	ValidateTCPIngress(tcpIngress configurationv1beta1.TCPIngress) (bool, string, error)
+	ValidateUDPIngress(udpIngress v1alpha1.UDPIngress) (bool, string, error)
+	ValidatePluginReferences(obj metav1.Object) (bool, string, error)
 }
 
 // KongHTTPValidator implements KongValidator interface to validate Kong
@@ -26,42 +44,109 @@ type KongHTTPValidator struct {
 	Client *kong.Client
 	Logger logrus.FieldLogger
 	Store  store.Storer
+
+	// CredentialSchemas provides the fields required by each credential
+	// type. If nil, the fields known to the controller are used.
+	CredentialSchemas *CredentialSchemaStore
+
+	// CredentialTypeKey is the data field of Secrets holding the type of their
+	// credential. If empty, util.DefaultCredentialTypeKey is used.
+	CredentialTypeKey string
+
+	// AvailablePlugins tells which plugins are available on Kong. If nil, or
+	// if they can't be fetched, plugins are only checked against their schema.
+	AvailablePlugins *AvailablePluginStore
+
+	// MaxPluginConfigSize is the maximum size in bytes of the JSON-encoded
+	// configuration of a plugin submitted to Kong for validation. 0 means no limit.
+	MaxPluginConfigSize int
+
+	// KongVersion is the version of Kong, which gates the features plugins
+	// can use. With the zero version, none is used.
+	KongVersion semver.Version
 }
 
-// ValidateConsumer checks if consumer has a Username and a consumer with
-// the same username doesn't exist in Kong.
+// ValidateConsumer checks if consumer has a Username or a CustomID, and that
+// no consumer with the same username or custom_id exists in Kong.
 // If an error occurs during validation, it is returned as the last argument.
 // The first boolean communicates if the consumer is valid or not and string
 // holds a message if the entity is not valid.
 func (validator KongHTTPValidator) ValidateConsumer(ctx context.Context,
 	consumer configurationv1.KongConsumer) (bool, string, error) {
-	if consumer.Username == "" {
-		return false, "username cannot be empty", nil
+	if consumer.Username == "" && consumer.CustomID == "" {
+		return false, "username or custom_id must be set", nil
 	}
-	c, err := validator.Client.Consumers.Get(ctx, &consumer.Username)
-	if err != nil {
-		if kong.IsNotFoundErr(err) {
-			return true, "", nil
+	if consumer.Username != "" {
+		c, err := validator.Client.Consumers.Get(ctx, &consumer.Username)
+		if err != nil && !kong.IsNotFoundErr(err) {
+			validator.Logger.Errorf("failed to fetch consumer from kong: %v", err)
+			return false, "", fmt.Errorf("fetching consumer from Kong: %w", err)
+		}
+		if c != nil {
+			return false, fmt.Sprintf("consumer with username %q already exists", consumer.Username), nil
 		}
-		validator.Logger.Errorf("failed to fetch consumer from kong: %v", err)
-		return false, "", fmt.Errorf("fetching consumer from Kong: %w", err)
 	}
-	if c != nil {
-		return false, "consumer already exists", nil
+	if consumer.CustomID != "" {
+		c, err := validator.Client.Consumers.GetByCustomID(ctx, &consumer.CustomID)
+		if err != nil && !kong.IsNotFoundErr(err) {
+			validator.Logger.Errorf("failed to fetch consumer from kong: %v", err)
+			return false, "", fmt.Errorf("fetching consumer from Kong: %w", err)
+		}
+		if c != nil {
+			return false, fmt.Sprintf("consumer with custom_id %q already exists", consumer.CustomID), nil
+		}
+	}
+	return true, "", nil
+}
+
+// ValidateConsumerCredentials checks that the Secrets referenced as credentials
+// by consumer exist and hold valid credentials, as checked by ValidateCredential.
+// Referenced Secrets which don't hold a Kong credential are left to the controller,
+// as for ValidateCredential. The problems of all credentials are reported at once.
+func (validator KongHTTPValidator) ValidateConsumerCredentials(ctx context.Context,
+	consumer configurationv1.KongConsumer) (bool, string, error) {
+	var problems []string
+	for _, name := range consumer.Credentials {
+		secret, err := validator.Store.GetSecret(consumer.Namespace, name)
+		if err != nil {
+			if errors.As(err, &store.ErrNotFound{}) {
+				problems = append(problems, fmt.Sprintf("credential %s: secret not found", name))
+				continue
+			}
+			return false, "", fmt.Errorf("fetching credential secret %s/%s: %w", consumer.Namespace, name, err)
+		}
+		ok, message, err := validator.ValidateCredential(ctx, *secret)
+		if err != nil {
+			return false, "", err
+		}
+		if !ok {
+			problems = append(problems, fmt.Sprintf("credential %s: %s", name, message))
+		}
+	}
+	if len(problems) > 0 {
+		return false, strings.Join(problems, "; "), nil
 	}
 	return true, "", nil
 }
 
 // ValidatePlugin checks if k8sPlugin is valid. It does so by performing
-// an HTTP request to Kong's Admin API entity validation endpoints.
-// If an error occurs during validation, it is returned as the last argument.
-// The first boolean communicates if k8sPluign is valid or not and string
+// an HTTP request to Kong's Admin API entity validation endpoints, after
+// checking that the plugin is available on Kong if AvailablePlugins is set.
+// Only a 400 response of Kong means the plugin is invalid; any other error,
+// e.g. Kong being unreachable, is returned as the last argument.
+// The first boolean communicates if k8sPlugin is valid or not and string
 // holds a message if the entity is not valid.
-func (validator KongHTTPValidator) ValidatePlugin(
+func (validator KongHTTPValidator) ValidatePlugin(ctx context.Context,
 	k8sPlugin configurationv1.KongPlugin) (bool, string, error) {
 	if k8sPlugin.PluginName == "" {
 		return false, "plugin name cannot be empty", nil
 	}
+	if validator.AvailablePlugins != nil {
+		available, ok := validator.AvailablePlugins.IsAvailable(ctx, k8sPlugin.PluginName)
+		if ok && !available {
+			return false, fmt.Sprintf("plugin %q is not enabled on the Kong server", k8sPlugin.PluginName), nil
+		}
+	}
 	var plugin kong.Plugin
 	plugin.Name = kong.String(k8sPlugin.PluginName)
 	var err error
@@ -81,6 +166,28 @@ func (validator KongHTTPValidator) ValidatePlugin(
 		plugin.Config = config
 
 	}
+	// validate the configuration Kong actually receives, with the fields
+	// sourced from secrets set
+	plugin.Config, err = kongstate.ApplyConfigPatches(validator.Store,
+		plugin.Config, k8sPlugin.ConfigPatches, k8sPlugin.Namespace)
+	if err != nil {
+		return false, err.Error(), nil
+	}
+	// vault references are resolved by Kong, but are checked to be well-formed and supported as Kong
+	// may accept them as plain values
+	if err := kongstate.ValidateVaultReferences(plugin.Config, validator.KongVersion); err != nil {
+		return false, err.Error(), nil
+	}
+	if validator.MaxPluginConfigSize > 0 {
+		config, err := json.Marshal(plugin.Config)
+		if err != nil {
+			return false, "could not encode plugin configuration", err
+		}
+		if len(config) > validator.MaxPluginConfigSize {
+			return false, fmt.Sprintf("plugin configuration is too large: %d bytes exceed the limit of %d bytes",
+				len(config), validator.MaxPluginConfigSize), nil
+		}
+	}
 	if k8sPlugin.RunOn != "" {
 		plugin.RunOn = kong.String(k8sPlugin.RunOn)
 	}
@@ -92,19 +199,381 @@ func (validator KongHTTPValidator) ValidatePlugin(
 	if err != nil {
 		return false, "", err
 	}
-	resp, err := validator.Client.Do(context.Background(), req, nil)
+	_, err = validator.Client.Do(ctx, req, nil)
 	if err != nil {
-		return false, err.Error(), nil
+		var apiErr *kong.APIError
+		if errors.As(err, &apiErr) && apiErr.Code() == http.StatusBadRequest {
+			// Kong rejected the plugin, its message details the invalid fields
+			return false, apiErrorMessage(apiErr), nil
+		}
+		return false, "", fmt.Errorf("validating plugin with Kong: %w", err)
 	}
-	if resp.StatusCode == 201 {
-		return true, "", nil
+	return true, "", nil
+}
+
+// apiErrorMessage returns the message of the response body of Kong held by apiErr,
+// which only exposes it as part of its Error() string.
+func apiErrorMessage(apiErr *kong.APIError) string {
+	description := apiErr.Error()
+	prefix := fmt.Sprintf("HTTP status %d (message: ", apiErr.Code())
+	if strings.HasPrefix(description, prefix) && strings.HasSuffix(description, ")") {
+		message, err := strconv.Unquote(strings.TrimSuffix(strings.TrimPrefix(description, prefix), ")"))
+		if err == nil && message != "" {
+			return message
+		}
+	}
+	return description
+}
+
+// ValidateKongIngress checks if the Upstream, Service (proxy) and Route
+// embedded in kongIngress are valid. It does so by submitting each of them to
+// the schema validation endpoint of its entity in Kong. As KongIngress only
+// holds overrides, the fields required by Kong which the controller fills in
+// are set to placeholders first.
+// All invalid entities are reported in the message.
+func (validator KongHTTPValidator) ValidateKongIngress(ctx context.Context,
+	kongIngress configurationv1.KongIngress) (bool, string, error) {
+	type embedded struct {
+		field    string
+		endpoint string
+		entity   interface{}
+		base     map[string]interface{}
+	}
+	var entities []embedded
+	if kongIngress.Upstream != nil {
+		entities = append(entities, embedded{
+			field:    "upstream",
+			endpoint: "/schemas/upstreams/validate",
+			entity:   kongIngress.Upstream,
+			base:     map[string]interface{}{"name": "kongingress-validation"},
+		})
 	}
+	if kongIngress.Proxy != nil {
+		entities = append(entities, embedded{
+			field:    "proxy",
+			endpoint: "/schemas/services/validate",
+			entity:   kongIngress.Proxy,
+			base:     map[string]interface{}{"name": "kongingress-validation", "host": "kongingress-validation"},
+		})
+	}
+	if kongIngress.Route != nil {
+		entities = append(entities, embedded{
+			field:    "route",
+			endpoint: "/schemas/routes/validate",
+			entity:   kongIngress.Route,
+			base:     routeValidationBase(kongIngress.Route),
+		})
+	}
+
+	var invalid []string
+	for _, e := range entities {
+		document, err := overlayJSON(e.base, e.entity)
+		if err != nil {
+			return false, "", fmt.Errorf("preparing %s for validation: %w", e.field, err)
+		}
+		req, err := validator.Client.NewRequest("POST", e.endpoint, nil, document)
+		if err != nil {
+			return false, "", err
+		}
+		_, err = validator.Client.Do(ctx, req, nil)
+		if err != nil {
+			var apiErr *kong.APIError
+			if errors.As(err, &apiErr) && apiErr.Code() == http.StatusBadRequest {
+				invalid = append(invalid, fmt.Sprintf("invalid %s: %v", e.field, err))
+				continue
+			}
+			return false, "", fmt.Errorf("validating %s with Kong: %w", e.field, err)
+		}
+	}
+	if len(invalid) > 0 {
+		return false, strings.Join(invalid, "; "), nil
+	}
+	return true, "", nil
+}
+
+// ValidateIngressPaths checks with the route schema of Kong that the paths of
+// an Ingress, which Kong compiles as regular expressions, are valid, so that
+// an invalid path is rejected with the Ingress rather than failing the whole
+// configuration when the controller syncs it.
+// If an error occurs during validation, it is returned as the last argument.
+// The first boolean communicates if the paths are valid or not and string
+// holds a message naming the invalid paths.
+func (validator KongHTTPValidator) ValidateIngressPaths(ctx context.Context,
+	paths []string) (bool, string, error) {
+	var invalid []string
+	for _, path := range paths {
+		ok, message, err := sendconfig.ValidateRoutePath(ctx, validator.Client, path)
+		if err != nil {
+			return false, "", err
+		}
+		if !ok {
+			invalid = append(invalid, fmt.Sprintf("invalid path %q: %s", path, message))
+		}
+	}
+	if len(invalid) > 0 {
+		return false, strings.Join(invalid, "; "), nil
+	}
+	return true, "", nil
+}
+
+// routeValidationBase returns the placeholder matching criteria for a route
+// with the protocols of route, as Kong requires routes to match on something.
+func routeValidationBase(route *kong.Route) map[string]interface{} {
+	for _, protocol := range route.Protocols {
+		switch *protocol {
+		case "http", "https", "grpc", "grpcs":
+			return map[string]interface{}{"paths": []string{"/"}}
+		}
+	}
+	if len(route.Protocols) == 0 {
+		return map[string]interface{}{"paths": []string{"/"}}
+	}
+	// stream routes can't match on paths
+	return map[string]interface{}{"destinations": []map[string]interface{}{{"port": 80}}}
+}
+
+// overlayJSON returns base with the fields set in entity, as serialized to JSON, set on top.
+func overlayJSON(base map[string]interface{}, entity interface{}) (map[string]interface{}, error) {
+	raw, err := json.Marshal(entity)
 	if err != nil {
-		return false, "", err
+		return nil, err
+	}
+	var fields map[string]interface{}
+	if err := json.Unmarshal(raw, &fields); err != nil {
+		return nil, err
+	}
+	document := make(map[string]interface{}, len(base)+len(fields))
+	for k, v := range base {
+		document[k] = v
+	}
+	for k, v := range fields {
+		document[k] = v
+	}
+	return document, nil
+}
+
+// ValidateTCPIngress checks if the rules of tcpIngress have a valid port and
+// backend, and that no other TCPIngress already routes the same port and SNI.
+// A port routes TLS streams either by SNI or regardless of it, so rules with and
+// without a host cannot share it.
+// If an error occurs during validation, it is returned as the last argument.
+// The first boolean communicates if tcpIngress is valid or not and string
+// holds a message if the entity is not valid.
+func (validator KongHTTPValidator) ValidateTCPIngress(
+	tcpIngress configurationv1beta1.TCPIngress) (bool, string, error) {
+	for i, rule := range tcpIngress.Spec.Rules {
+		if !isValidPort(rule.Port) {
+			return false, fmt.Sprintf("rules[%d]: invalid port %d", i, rule.Port), nil
+		}
+		if rule.Backend.ServiceName == "" {
+			return false, fmt.Sprintf("rules[%d]: backend serviceName cannot be empty", i), nil
+		}
+		if !isValidPort(rule.Backend.ServicePort) {
+			return false, fmt.Sprintf("rules[%d]: invalid backend servicePort %d",
+				i, rule.Backend.ServicePort), nil
+		}
+	}
+
+	existing, err := validator.Store.ListTCPIngresses()
+	if err != nil {
+		return false, "", fmt.Errorf("listing TCPIngresses: %w", err)
+	}
+	// rules with different SNIs can share a port, so a rule is identified by both
+	type tcpRuleKey struct {
+		port int
+		host string
+	}
+	// and a port is either routed by SNI or not
+	type tcpListenerKey struct {
+		port int
+		sni  bool
+	}
+	owners := map[tcpRuleKey]string{}
+	listenerOwners := map[tcpListenerKey]string{}
+	for _, other := range existing {
+		if other.Namespace == tcpIngress.Namespace && other.Name == tcpIngress.Name {
+			continue
+		}
+		for _, rule := range other.Spec.Rules {
+			owners[tcpRuleKey{rule.Port, strings.ToLower(rule.Host)}] = other.Namespace + "/" + other.Name
+			listenerOwners[tcpListenerKey{rule.Port, rule.Host != ""}] = other.Namespace + "/" + other.Name
+		}
+	}
+	seen := map[tcpRuleKey]bool{}
+	seenListeners := map[tcpListenerKey]bool{}
+	for i, rule := range tcpIngress.Spec.Rules {
+		key := tcpRuleKey{rule.Port, strings.ToLower(rule.Host)}
+		if owner, ok := owners[key]; ok {
+			return false, fmt.Sprintf("rules[%d]: port %d%s is already in use by TCPIngress %s",
+				i, rule.Port, describeSNI(rule.Host), owner), nil
+		}
+		if seen[key] {
+			return false, fmt.Sprintf("rules[%d]: port %d%s is used by more than one rule",
+				i, rule.Port, describeSNI(rule.Host)), nil
+		}
+		seen[key] = true
+
+		// the other kind of rule on the same port
+		mixed := tcpListenerKey{rule.Port, rule.Host == ""}
+		if owner, ok := listenerOwners[mixed]; ok {
+			return false, fmt.Sprintf("rules[%d]: port %d is already routed %s by TCPIngress %s",
+				i, rule.Port, describeSNIRouting(mixed.sni), owner), nil
+		}
+		if seenListeners[mixed] {
+			return false, fmt.Sprintf("rules[%d]: port %d is routed both with and without SNI",
+				i, rule.Port), nil
+		}
+		seenListeners[tcpListenerKey{rule.Port, rule.Host != ""}] = true
 	}
 	return true, "", nil
 }
 
+// describeSNIRouting describes how a port routes TLS streams.
+func describeSNIRouting(sni bool) string {
+	if sni {
+		return "by SNI"
+	}
+	return "without SNI"
+}
+
+// ValidateUDPIngress checks if udpIngress has a valid listen port and
+// backend, and that no other UDPIngress already listens on the same port.
+// If an error occurs during validation, it is returned as the last argument.
+// The first boolean communicates if udpIngress is valid or not and string
+// holds a message if the entity is not valid.
+func (validator KongHTTPValidator) ValidateUDPIngress(
+	udpIngress v1alpha1.UDPIngress) (bool, string, error) {
+	if !isValidPort(udpIngress.Spec.ListenPort) {
+		return false, fmt.Sprintf("invalid listenPort %d", udpIngress.Spec.ListenPort), nil
+	}
+	if udpIngress.Spec.Host == "" {
+		return false, "host cannot be empty", nil
+	}
+	if !isValidPort(udpIngress.Spec.TargetPort) {
+		return false, fmt.Sprintf("invalid targetPort %d", udpIngress.Spec.TargetPort), nil
+	}
+
+	existing, err := validator.Store.ListUDPIngresses()
+	if err != nil {
+		return false, "", fmt.Errorf("listing UDPIngresses: %w", err)
+	}
+	for _, other := range existing {
+		if other.Namespace == udpIngress.Namespace && other.Name == udpIngress.Name {
+			continue
+		}
+		if other.Spec.ListenPort == udpIngress.Spec.ListenPort {
+			return false, fmt.Sprintf("listenPort %d is already in use by UDPIngress %s/%s",
+				udpIngress.Spec.ListenPort, other.Namespace, other.Name), nil
+		}
+	}
+	return true, "", nil
+}
+
+// ValidatePluginReferences checks that the KongPlugins and KongClusterPlugins referenced by the
+// konghq.com/plugins annotation of obj can be applied together: they must configure distinct plugins,
+// as Kong rejects two instances of the same plugin on one entity, and each must load its configuration
+// from the secret of its configFrom, which a KongPlugin reads from its own namespace and a
+// KongClusterPlugin from the namespace it names. References to plugins, or to secrets, which don't
+// exist yet are not checked.
+func (validator KongHTTPValidator) ValidatePluginReferences(obj metav1.Object) (bool, string, error) {
+	referencedBy := map[string]referencedPlugin{}
+	for _, name := range annotations.ExtractKongPluginsFromAnnotations(obj.GetAnnotations()) {
+		plugin, ok, err := validator.referencedPlugin(obj.GetNamespace(), name)
+		if err != nil {
+			return false, "", err
+		}
+		if !ok {
+			continue
+		}
+		if plugin.configErr != nil {
+			return false, fmt.Sprintf("%s '%s' cannot be attached to the object: %v",
+				plugin.kind, name, plugin.configErr), nil
+		}
+		if other, ok := referencedBy[plugin.pluginName]; ok {
+			return false, fmt.Sprintf("%s '%s' and %s '%s' both configure plugin '%s', "+
+				"which can only be attached once to an object",
+				other.kind, other.name, plugin.kind, name, plugin.pluginName), nil
+		}
+		referencedBy[plugin.pluginName] = plugin
+	}
+	return true, "", nil
+}
+
+// referencedPlugin is a KongPlugin or KongClusterPlugin referenced by the konghq.com/plugins annotation
+// of an object.
+type referencedPlugin struct {
+	kind       string
+	name       string
+	pluginName string
+	// configErr tells why the configuration of the plugin can't be loaded from the secret of its configFrom.
+	configErr error
+}
+
+// referencedPlugin returns the KongPlugin referenced as name from namespace, or the KongClusterPlugin of
+// that name. The boolean is false if there is neither, or if the secret of its configFrom doesn't exist:
+// like the plugin, it may be created after the object.
+func (validator KongHTTPValidator) referencedPlugin(namespace, name string) (referencedPlugin, bool, error) {
+	plugin, err := validator.Store.GetKongPlugin(namespace, name)
+	if err == nil {
+		ref := referencedPlugin{kind: "KongPlugin", name: name, pluginName: plugin.PluginName}
+		if secretValue := plugin.ConfigFrom.SecretValue; secretValue != (configurationv1.SecretValueFromSource{}) {
+			exists, err := validator.secretExists(secretValue.Secret)
+			if err != nil || !exists {
+				return referencedPlugin{}, false, err
+			}
+			_, ref.configErr = kongstate.SecretToConfiguration(validator.Store, secretValue, plugin.Namespace)
+		}
+		return ref, true, nil
+	}
+	if !errors.As(err, &store.ErrNotFound{}) {
+		return referencedPlugin{}, false, err
+	}
+	clusterPlugin, err := validator.Store.GetKongClusterPlugin(name)
+	if err == nil {
+		ref := referencedPlugin{kind: "KongClusterPlugin", name: name, pluginName: clusterPlugin.PluginName}
+		if secretValue := clusterPlugin.ConfigFrom.SecretValue; secretValue !=
+			(configurationv1.NamespacedSecretValueFromSource{}) {
+			if secretValue.Namespace == "" {
+				ref.configErr = fmt.Errorf("configFrom does not set the namespace of secret '%s'", secretValue.Secret)
+				return ref, true, nil
+			}
+			exists, err := validator.secretExists(secretValue.Secret)
+			if err != nil || !exists {
+				return referencedPlugin{}, false, err
+			}
+			_, ref.configErr = kongstate.SecretToConfiguration(validator.Store,
+				configurationv1.SecretValueFromSource{Secret: secretValue.Secret, Key: secretValue.Key},
+				secretValue.Namespace)
+		}
+		return ref, true, nil
+	}
+	if !errors.As(err, &store.ErrNotFound{}) {
+		return referencedPlugin{}, false, err
+	}
+	return referencedPlugin{}, false, nil
+}
+
+// secretExists tells whether a Secret of the given name exists in any namespace. A plugin reading its
+// configuration from a Secret which only exists in other namespaces reads it from the wrong one.
+func (validator KongHTTPValidator) secretExists(name string) (bool, error) {
+	secrets, err := validator.Store.ListSecretsNamed(name)
+	if err != nil {
+		return false, err
+	}
+	return len(secrets) > 0, nil
+}
+
+func isValidPort(port int) bool {
+	return port > 0 && port <= 65535
+}
+
+func describeSNI(host string) string {
+	if host == "" {
+		return ""
+	}
+	return fmt.Sprintf(" with SNI '%s'", host)
+}
+
 var (
 	keyAuthFields   = []string{"key"}
 	basicAuthFields = []string{"username", "password"}
@@ -112,7 +581,8 @@ var (
 	jwtAuthFields   = []string{"algorithm", "rsa_public_key", "key", "secret"}
 	mtlsAuthFields  = []string{"subject_name"}
 
-	// TODO dynamically fetch these from Kong
+	// credTypeToFields is used when the required fields can't be
+	// fetched from Kong
 	credTypeToFields = map[string][]string{
 		"key-auth":             keyAuthFields,
 		"keyauth_credential":   keyAuthFields,
@@ -126,24 +596,55 @@ var (
 		"acl":                  {"group"},
 		"mtls-auth":            mtlsAuthFields,
 	}
+
+	keyAuthUniqueField   = uniqueField{collection: "key-auths", field: "key"}
+	basicAuthUniqueField = uniqueField{collection: "basic-auths", field: "username"}
+	hmacAuthUniqueField  = uniqueField{collection: "hmac-auths", field: "username"}
+	jwtAuthUniqueField   = uniqueField{collection: "jwts", field: "key"}
+
+	// credTypeToUniqueField maps credential types to the field Kong
+	// enforces uniqueness on. Credential types without an entry here
+	// (acl, mtls-auth) have no globally unique field.
+	credTypeToUniqueField = map[string]uniqueField{
+		"key-auth":             keyAuthUniqueField,
+		"keyauth_credential":   keyAuthUniqueField,
+		"basic-auth":           basicAuthUniqueField,
+		"basicauth_credential": basicAuthUniqueField,
+		"hmac-auth":            hmacAuthUniqueField,
+		"hmacauth_credential":  hmacAuthUniqueField,
+		"jwt":                  jwtAuthUniqueField,
+		"jwt_secret":           jwtAuthUniqueField,
+		"oauth2":               {collection: "oauth2", field: "client_id"},
+	}
 )
 
+// uniqueField is a credential field whose value must be unique across
+// all credentials of its type in Kong.
+type uniqueField struct {
+	// collection is the Admin API collection of the credential type,
+	// which accepts the unique field as the lookup key.
+	collection string
+	field      string
+}
+
 // ValidateCredential checks if the secret contains a credential meant to
 // be installed in Kong. If so, then it verifies if all the required fields
 // are present in it or not. If valid, it returns true with an empty string,
 // else it returns false with the error messsage. If an error happens during
 // validation, error is returned.
-func (validator KongHTTPValidator) ValidateCredential(
+func (validator KongHTTPValidator) ValidateCredential(ctx context.Context,
 	secret corev1.Secret) (bool, string, error) {
 
-	credTypeBytes, ok := secret.Data["kongCredType"]
+	credType, ok, err := util.CredentialType(&secret, validator.CredentialTypeKey)
+	if err != nil {
+		return false, err.Error(), nil
+	}
 	if !ok {
 		// doesn't look like a credential resource
 		return true, "", nil
 	}
-	credType := string(credTypeBytes)
 
-	fields, ok := credTypeToFields[credType]
+	fields, ok := validator.requiredFields(ctx, credType)
 	if !ok {
 		return false, "invalid credential type: " + credType, nil
 	}
@@ -159,11 +660,103 @@ func (validator KongHTTPValidator) ValidateCredential(
 			strings.Join(missingFields, ", "), nil
 	}
 
-	// TODO add unique key violation detection
-	// For each credential, there is a unique column, like key for key-auth,
-	// username for basic-auth; make an API call to Kong's Admin API
-	// and verify if there will be a violation, similar to how it's done
-	// for KongConsumer; return error if the resource is already present in
-	// Kong.
-	return true, "", nil
+	unique, ok := credTypeToUniqueField[credType]
+	if !ok {
+		return true, "", nil
+	}
+	value := string(secret.Data[unique.field])
+	consumerID, err := validator.credentialConsumerID(ctx, unique, value)
+	if err != nil {
+		validator.Logger.Errorf("failed to fetch credential from kong: %v", err)
+		return false, "", fmt.Errorf("fetching credential from Kong: %w", err)
+	}
+	if consumerID == "" {
+		return true, "", nil
+	}
+	owned, err := validator.isOwnedBy(ctx, secret, consumerID)
+	if err != nil {
+		validator.Logger.Errorf("failed to fetch consumer from kong: %v", err)
+		return false, "", fmt.Errorf("fetching consumer from Kong: %w", err)
+	}
+	if owned {
+		// the credential is being updated in place
+		return true, "", nil
+	}
+	return false, fmt.Sprintf("%s credential with %s '%s' already exists",
+		credType, unique.field, value), nil
+}
+
+// requiredFields returns the fields a credential of credType must set.
+// The boolean is false if credType is not a known credential type.
+func (validator KongHTTPValidator) requiredFields(ctx context.Context,
+	credType string) ([]string, bool) {
+	if validator.CredentialSchemas == nil {
+		fields, ok := credTypeToFields[credType]
+		return fields, ok
+	}
+	return validator.CredentialSchemas.RequiredFields(ctx, credType)
+}
+
+// credentialConsumerID looks up the credential with the given value of its
+// unique field in Kong and returns the ID of the consumer it belongs to.
+// An empty ID is returned if no such credential exists.
+func (validator KongHTTPValidator) credentialConsumerID(ctx context.Context,
+	unique uniqueField, value string) (string, error) {
+	endpoint := "/" + unique.collection + "/" + url.PathEscape(value)
+	req, err := validator.Client.NewRequest("GET", endpoint, nil, nil)
+	if err != nil {
+		return "", err
+	}
+	var credential struct {
+		Consumer *kong.Consumer `json:"consumer,omitempty"`
+	}
+	_, err = validator.Client.Do(ctx, req, &credential)
+	if err != nil {
+		if kong.IsNotFoundErr(err) {
+			return "", nil
+		}
+		return "", err
+	}
+	if credential.Consumer == nil || credential.Consumer.ID == nil {
+		return "", nil
+	}
+	return *credential.Consumer.ID, nil
+}
+
+// isOwnedBy checks whether the Kong consumer with consumerID corresponds to
+// a KongConsumer which references secret as one of its credentials.
+func (validator KongHTTPValidator) isOwnedBy(ctx context.Context,
+	secret corev1.Secret, consumerID string) (bool, error) {
+	var owners []*configurationv1.KongConsumer
+	for _, consumer := range validator.Store.ListKongConsumers() {
+		if consumer.Namespace != secret.Namespace {
+			continue
+		}
+		for _, credential := range consumer.Credentials {
+			if credential == secret.Name {
+				owners = append(owners, consumer)
+				break
+			}
+		}
+	}
+	if len(owners) == 0 {
+		return false, nil
+	}
+
+	c, err := validator.Client.Consumers.Get(ctx, &consumerID)
+	if err != nil {
+		if kong.IsNotFoundErr(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	for _, owner := range owners {
+		if owner.Username != "" && c.Username != nil && *c.Username == owner.Username {
+			return true, nil
+		}
+		if owner.CustomID != "" && c.CustomID != nil && *c.CustomID == owner.CustomID {
+			return true, nil
+		}
+	}
+	return false, nil
 }
@@ -1,12 +1,26 @@
 package admission
 
 import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/blang/semver"
+	"github.com/kong/go-kong/kong"
+	"github.com/kong/kubernetes-ingress-controller/pkg/annotations"
 	configurationv1 "github.com/kong/kubernetes-ingress-controller/pkg/apis/configuration/v1"
+	configurationv1beta1 "github.com/kong/kubernetes-ingress-controller/pkg/apis/configuration/v1beta1"
 	"github.com/kong/kubernetes-ingress-controller/pkg/store"
+	"github.com/kong/kubernetes-ingress-controller/railgun/apis/configuration/v1alpha1"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
 	corev1 "k8s.io/api/core/v1"
 	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
 func TestKongHTTPValidator_ValidateCredential(t *testing.T) {
@@ -16,6 +30,7 @@ func TestKongHTTPValidator_ValidateCredential(t *testing.T) {
 	tests := []struct {
 		name        string
 		args        args
+		credTypeKey string
 		wantOK      bool
 		wantMessage string
 		wantErr     bool
@@ -148,11 +163,113 @@ func TestKongHTTPValidator_ValidateCredential(t *testing.T) {
 			wantMessage: "",
 			wantErr:     false,
 		},
+		{
+			name: "valid key-auth credential typed by label",
+			args: args{
+				secret: corev1.Secret{
+					ObjectMeta: metav1.ObjectMeta{
+						Labels: map[string]string{"konghq.com/credential": "key-auth"},
+					},
+					Data: map[string][]byte{
+						"key": []byte("foo"),
+					},
+				},
+			},
+			wantOK:      true,
+			wantMessage: "",
+			wantErr:     false,
+		},
+		{
+			name: "invalid key-auth credential typed by label",
+			args: args{
+				secret: corev1.Secret{
+					ObjectMeta: metav1.ObjectMeta{
+						Labels: map[string]string{"konghq.com/credential": "key-auth"},
+					},
+					Data: map[string][]byte{
+						"key-wrong": []byte("foo"),
+					},
+				},
+			},
+			wantOK:      false,
+			wantMessage: "missing required field(s): key",
+			wantErr:     false,
+		},
+		{
+			name: "label and field agreeing on the credential type",
+			args: args{
+				secret: corev1.Secret{
+					ObjectMeta: metav1.ObjectMeta{
+						Labels: map[string]string{"konghq.com/credential": "key-auth"},
+					},
+					Data: map[string][]byte{
+						"key":          []byte("foo"),
+						"kongCredType": []byte("key-auth"),
+					},
+				},
+			},
+			wantOK:      true,
+			wantMessage: "",
+			wantErr:     false,
+		},
+		{
+			name: "label and field disagreeing on the credential type",
+			args: args{
+				secret: corev1.Secret{
+					ObjectMeta: metav1.ObjectMeta{
+						Labels: map[string]string{"konghq.com/credential": "key-auth"},
+					},
+					Data: map[string][]byte{
+						"key":          []byte("foo"),
+						"kongCredType": []byte("basic-auth"),
+					},
+				},
+			},
+			wantOK: false,
+			wantMessage: `credential type "key-auth" of label konghq.com/credential conflicts ` +
+				`with credential type "basic-auth" of field kongCredType`,
+			wantErr: false,
+		},
+		{
+			name: "valid key-auth credential typed by a custom key",
+			args: args{
+				secret: corev1.Secret{
+					Data: map[string][]byte{
+						"key":  []byte("foo"),
+						"type": []byte("key-auth"),
+					},
+				},
+			},
+			credTypeKey: "type",
+			wantOK:      true,
+			wantMessage: "",
+			wantErr:     false,
+		},
+		{
+			name: "default key is ignored when a custom key is configured",
+			args: args{
+				secret: corev1.Secret{
+					Data: map[string][]byte{
+						"kongCredType": []byte("foo"),
+					},
+				},
+			},
+			credTypeKey: "type",
+			wantOK:      true,
+			wantMessage: "",
+			wantErr:     false,
+		},
 	}
+	kongClient, closeAdminAPI := newFakeAdminAPI(t, nil)
+	defer closeAdminAPI()
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			validator := KongHTTPValidator{}
-			got, got1, err := validator.ValidateCredential(tt.args.secret)
+			validator := KongHTTPValidator{
+				Client:            kongClient,
+				Logger:            logrus.New(),
+				CredentialTypeKey: tt.credTypeKey,
+			}
+			got, got1, err := validator.ValidateCredential(context.Background(), tt.args.secret)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("KongHTTPValidator.ValidateCredential() error = %v, wantErr %v", err, tt.wantErr)
 				return
@@ -167,6 +284,289 @@ func TestKongHTTPValidator_ValidateCredential(t *testing.T) {
 	}
 }
 
+func TestKongHTTPValidator_ValidateConsumer(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/consumers/alice":
+			_, _ = w.Write([]byte(`{"id":"consumer-1","username":"alice"}`))
+		case r.URL.Path == "/consumers" && r.URL.Query().Get("custom_id") == "bob-id":
+			_, _ = w.Write([]byte(`{"data":[{"id":"consumer-2","custom_id":"bob-id"}]}`))
+		case r.URL.Path == "/consumers":
+			_, _ = w.Write([]byte(`{"data":[]}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"message":"Not found"}`))
+		}
+	}))
+	defer server.Close()
+	client, err := kong.NewClient(kong.String(server.URL), server.Client())
+	assert.NoError(t, err)
+	validator := KongHTTPValidator{Client: client, Logger: logrus.New()}
+
+	tests := []struct {
+		name        string
+		consumer    configurationv1.KongConsumer
+		wantOK      bool
+		wantMessage string
+	}{
+		{
+			name:        "no identifier",
+			consumer:    configurationv1.KongConsumer{},
+			wantMessage: "username or custom_id must be set",
+		},
+		{
+			name:     "new username",
+			consumer: configurationv1.KongConsumer{Username: "carol"},
+			wantOK:   true,
+		},
+		{
+			name:        "existing username",
+			consumer:    configurationv1.KongConsumer{Username: "alice"},
+			wantMessage: `consumer with username "alice" already exists`,
+		},
+		{
+			name:     "new custom_id",
+			consumer: configurationv1.KongConsumer{CustomID: "carol-id"},
+			wantOK:   true,
+		},
+		{
+			name:        "existing custom_id",
+			consumer:    configurationv1.KongConsumer{CustomID: "bob-id"},
+			wantMessage: `consumer with custom_id "bob-id" already exists`,
+		},
+		{
+			name:        "new username with existing custom_id",
+			consumer:    configurationv1.KongConsumer{Username: "carol", CustomID: "bob-id"},
+			wantMessage: `consumer with custom_id "bob-id" already exists`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ok, message, err := validator.ValidateConsumer(context.Background(), tt.consumer)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantOK, ok)
+			assert.Equal(t, tt.wantMessage, message)
+		})
+	}
+}
+
+func TestKongHTTPValidator_ValidateConsumerCredentials(t *testing.T) {
+	kongClient, closeAdminAPI := newFakeAdminAPI(t, map[string]string{
+		"/key-auths/taken":      `{"id":"c1","key":"taken","consumer":{"id":"consumer-1"}}`,
+		"/consumers/consumer-1": `{"id":"consumer-1","username":"alice"}`,
+	})
+	defer closeAdminAPI()
+	secret := func(name string, data map[string]string) *corev1.Secret {
+		s := &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+			Data:       map[string][]byte{},
+		}
+		for k, v := range data {
+			s.Data[k] = []byte(v)
+		}
+		return s
+	}
+	store, _ := store.NewFakeStore(store.FakeObjects{
+		Secrets: []*corev1.Secret{
+			secret("valid", map[string]string{"kongCredType": "key-auth", "key": "free"}),
+			secret("missing-field", map[string]string{"kongCredType": "basic-auth", "username": "foo"}),
+			secret("taken", map[string]string{"kongCredType": "key-auth", "key": "taken"}),
+			secret("unrelated", map[string]string{"token": "foo"}),
+		},
+	})
+	validator := KongHTTPValidator{
+		Client: kongClient,
+		Logger: logrus.New(),
+		Store:  store,
+	}
+
+	for _, tt := range []struct {
+		name        string
+		credentials []string
+		wantOK      bool
+		wantMessage string
+	}{
+		{
+			name:   "no credentials",
+			wantOK: true,
+		},
+		{
+			name:        "valid credentials",
+			credentials: []string{"valid"},
+			wantOK:      true,
+		},
+		{
+			name:        "secrets without credentials are skipped",
+			credentials: []string{"valid", "unrelated"},
+			wantOK:      true,
+		},
+		{
+			name:        "missing secret",
+			credentials: []string{"valid", "missing"},
+			wantOK:      false,
+			wantMessage: "credential missing: secret not found",
+		},
+		{
+			name:        "problems of all credentials are reported",
+			credentials: []string{"missing-field", "valid", "missing", "taken"},
+			wantOK:      false,
+			wantMessage: "credential missing-field: missing required field(s): password; " +
+				"credential missing: secret not found; " +
+				"credential taken: key-auth credential with key 'taken' already exists",
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			ok, message, err := validator.ValidateConsumerCredentials(context.Background(),
+				configurationv1.KongConsumer{
+					ObjectMeta:  metav1.ObjectMeta{Name: "consumer", Namespace: "default"},
+					Username:    "consumer",
+					Credentials: tt.credentials,
+				})
+			assert.Nil(t, err)
+			assert.Equal(t, tt.wantOK, ok)
+			assert.Equal(t, tt.wantMessage, message)
+		})
+	}
+}
+
+func TestKongHTTPValidator_ValidateCredentialUniqueness(t *testing.T) {
+	kongClient, closeAdminAPI := newFakeAdminAPI(t, map[string]string{
+		"/key-auths/taken":      `{"id":"c1","key":"taken","consumer":{"id":"consumer-1"}}`,
+		"/basic-auths/taken":    `{"id":"c2","username":"taken","consumer":{"id":"consumer-2"}}`,
+		"/consumers/consumer-1": `{"id":"consumer-1","username":"alice"}`,
+		"/consumers/consumer-2": `{"id":"consumer-2","custom_id":"bob-id"}`,
+	})
+	defer closeAdminAPI()
+	store, _ := store.NewFakeStore(store.FakeObjects{
+		KongConsumers: []*configurationv1.KongConsumer{
+			{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "alice",
+					Namespace: "default",
+					Annotations: map[string]string{
+						annotations.IngressClassKey: annotations.DefaultIngressClass,
+					},
+				},
+				Username:    "alice",
+				Credentials: []string{"alice-key"},
+			},
+			{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "bob",
+					Namespace: "default",
+					Annotations: map[string]string{
+						annotations.IngressClassKey: annotations.DefaultIngressClass,
+					},
+				},
+				CustomID:    "bob-id",
+				Credentials: []string{"bob-basic"},
+			},
+		},
+	})
+	tests := []struct {
+		name        string
+		secret      corev1.Secret
+		wantOK      bool
+		wantMessage string
+	}{
+		{
+			name: "key-auth key not present in kong",
+			secret: corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Name: "new-key", Namespace: "default"},
+				Data: map[string][]byte{
+					"kongCredType": []byte("key-auth"),
+					"key":          []byte("free"),
+				},
+			},
+			wantOK: true,
+		},
+		{
+			name: "key-auth key already used by another consumer",
+			secret: corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Name: "new-key", Namespace: "default"},
+				Data: map[string][]byte{
+					"kongCredType": []byte("key-auth"),
+					"key":          []byte("taken"),
+				},
+			},
+			wantOK:      false,
+			wantMessage: "key-auth credential with key 'taken' already exists",
+		},
+		{
+			name: "key-auth key updated in place by its owner",
+			secret: corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Name: "alice-key", Namespace: "default"},
+				Data: map[string][]byte{
+					"kongCredType": []byte("key-auth"),
+					"key":          []byte("taken"),
+				},
+			},
+			wantOK: true,
+		},
+		{
+			name: "key-auth key referenced by a consumer in another namespace",
+			secret: corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Name: "alice-key", Namespace: "other"},
+				Data: map[string][]byte{
+					"kongCredType": []byte("key-auth"),
+					"key":          []byte("taken"),
+				},
+			},
+			wantOK:      false,
+			wantMessage: "key-auth credential with key 'taken' already exists",
+		},
+		{
+			name: "basic-auth username updated in place by owner identified by custom_id",
+			secret: corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Name: "bob-basic", Namespace: "default"},
+				Data: map[string][]byte{
+					"kongCredType": []byte("basic-auth"),
+					"username":     []byte("taken"),
+					"password":     []byte("pass"),
+				},
+			},
+			wantOK: true,
+		},
+		{
+			name: "basic-auth username already used by another consumer",
+			secret: corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Name: "alice-key", Namespace: "default"},
+				Data: map[string][]byte{
+					"kongCredType": []byte("basic-auth"),
+					"username":     []byte("taken"),
+					"password":     []byte("pass"),
+				},
+			},
+			wantOK:      false,
+			wantMessage: "basic-auth credential with username 'taken' already exists",
+		},
+		{
+			name: "acl credentials have no unique field",
+			secret: corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Name: "acl", Namespace: "default"},
+				Data: map[string][]byte{
+					"kongCredType": []byte("acl"),
+					"group":        []byte("taken"),
+				},
+			},
+			wantOK: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			validator := KongHTTPValidator{
+				Client: kongClient,
+				Logger: logrus.New(),
+				Store:  store,
+			}
+			got, message, err := validator.ValidateCredential(context.Background(), tt.secret)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantOK, got)
+			assert.Equal(t, tt.wantMessage, message)
+		})
+	}
+}
+
 func TestKongHTTPValidator_ValidatePlugin(t *testing.T) {
 	store, _ := store.NewFakeStore(store.FakeObjects{})
 	type args struct {
@@ -245,7 +645,7 @@ func TestKongHTTPValidator_ValidatePlugin(t *testing.T) {
 			validator := KongHTTPValidator{
 				Store: store,
 			}
-			got, got1, err := validator.ValidatePlugin(tt.args.plugin)
+			got, got1, err := validator.ValidatePlugin(context.Background(), tt.args.plugin)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("KongHTTPValidator.ValidatePlugin() error = %v, wantErr %v", err, tt.wantErr)
 				return
@@ -259,3 +659,923 @@ func TestKongHTTPValidator_ValidatePlugin(t *testing.T) {
 		})
 	}
 }
+
+func TestKongHTTPValidator_ValidatePluginAvailability(t *testing.T) {
+	rootStatus := http.StatusOK
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/":
+			w.WriteHeader(rootStatus)
+			_, _ = w.Write([]byte(`{"plugins":{"available_on_server":{"key-auth":true}}}`))
+		case "/schemas/plugins/validate":
+			w.WriteHeader(http.StatusCreated)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+	client, err := kong.NewClient(kong.String(server.URL), server.Client())
+	assert.NoError(t, err)
+	store, _ := store.NewFakeStore(store.FakeObjects{})
+
+	tests := []struct {
+		name        string
+		pluginName  string
+		rootStatus  int
+		wantOK      bool
+		wantMessage string
+	}{
+		{
+			name:       "plugin available on Kong",
+			pluginName: "key-auth",
+			rootStatus: http.StatusOK,
+			wantOK:     true,
+		},
+		{
+			name:        "plugin not available on Kong",
+			pluginName:  "custom-plugin",
+			rootStatus:  http.StatusOK,
+			wantOK:      false,
+			wantMessage: `plugin "custom-plugin" is not enabled on the Kong server`,
+		},
+		{
+			name:       "schema validation only when the available plugins can't be fetched",
+			pluginName: "custom-plugin",
+			rootStatus: http.StatusInternalServerError,
+			wantOK:     true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rootStatus = tt.rootStatus
+			validator := KongHTTPValidator{
+				Client:           client,
+				Store:            store,
+				AvailablePlugins: NewAvailablePluginStore(client, time.Hour),
+			}
+			ok, message, err := validator.ValidatePlugin(context.Background(), configurationv1.KongPlugin{PluginName: tt.pluginName})
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantOK, ok)
+			assert.Equal(t, tt.wantMessage, message)
+		})
+	}
+}
+
+func TestKongHTTPValidator_ValidatePluginKongResponse(t *testing.T) {
+	tests := []struct {
+		name        string
+		status      int
+		body        string
+		wantOK      bool
+		wantMessage string
+		wantErr     bool
+	}{
+		{
+			name:   "valid plugin",
+			status: http.StatusOK,
+			body:   `{"message":"schema validation successful"}`,
+			wantOK: true,
+		},
+		{
+			name:   "invalid plugin",
+			status: http.StatusBadRequest,
+			body: `{"code":2,"name":"schema violation","fields":{"config":{"key_names":"expected an array"}},` +
+				`"message":"schema violation (config.key_names: expected an array)"}`,
+			wantOK:      false,
+			wantMessage: "schema violation (config.key_names: expected an array)",
+		},
+		{
+			name:    "kong failing",
+			status:  http.StatusInternalServerError,
+			body:    `{}`,
+			wantErr: true,
+		},
+		{
+			name:    "endpoint not found",
+			status:  http.StatusNotFound,
+			body:    `{"message":"Not found"}`,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+				_, _ = w.Write([]byte(tt.body))
+			}))
+			defer server.Close()
+			client, err := kong.NewClient(kong.String(server.URL), server.Client())
+			assert.NoError(t, err)
+			store, _ := store.NewFakeStore(store.FakeObjects{})
+			validator := KongHTTPValidator{Client: client, Store: store}
+
+			ok, message, err := validator.ValidatePlugin(context.Background(), configurationv1.KongPlugin{PluginName: "key-auth"})
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, tt.wantOK, ok)
+			assert.Equal(t, tt.wantMessage, message)
+		})
+	}
+
+	t.Run("transport error", func(t *testing.T) {
+		server := httptest.NewServer(http.NotFoundHandler())
+		client, err := kong.NewClient(kong.String(server.URL), server.Client())
+		assert.NoError(t, err)
+		server.Close()
+		store, _ := store.NewFakeStore(store.FakeObjects{})
+		validator := KongHTTPValidator{Client: client, Store: store}
+
+		ok, _, err := validator.ValidatePlugin(context.Background(), configurationv1.KongPlugin{PluginName: "key-auth"})
+		assert.Error(t, err)
+		assert.True(t, isKongUnavailable(err), "a transport error means kong is unavailable")
+		assert.False(t, ok)
+	})
+}
+
+func TestKongHTTPValidator_ValidatePluginConfigSize(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/schemas/plugins/validate" {
+			w.WriteHeader(http.StatusCreated)
+			return
+		}
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+	client, err := kong.NewClient(kong.String(server.URL), server.Client())
+	assert.NoError(t, err)
+	store, _ := store.NewFakeStore(store.FakeObjects{})
+	validator := KongHTTPValidator{Client: client, Store: store, MaxPluginConfigSize: 64}
+
+	ok, message, err := validator.ValidatePlugin(context.Background(), configurationv1.KongPlugin{
+		PluginName: "key-auth",
+		Config:     apiextensionsv1.JSON{Raw: []byte(`{"key_names":["apikey"]}`)},
+	})
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.Empty(t, message)
+
+	ok, message, err = validator.ValidatePlugin(context.Background(), configurationv1.KongPlugin{
+		PluginName: "key-auth",
+		Config:     apiextensionsv1.JSON{Raw: []byte(`{"key_names":["` + strings.Repeat("k", 100) + `"]}`)},
+	})
+	assert.NoError(t, err)
+	assert.False(t, ok)
+	assert.Equal(t, "plugin configuration is too large: 118 bytes exceed the limit of 64 bytes", message)
+}
+
+func TestKongHTTPValidator_ValidatePluginConfigPatches(t *testing.T) {
+	store, _ := store.NewFakeStore(store.FakeObjects{
+		Secrets: []*corev1.Secret{
+			{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "redis"},
+				Data: map[string][]byte{
+					"password": []byte("s3cr3t"),
+					"port":     []byte("6380"),
+				},
+			},
+			{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "conf-secret"},
+				Data: map[string][]byte{
+					"rate-limiting-config": []byte(`{"minute": 10, "policy": "redis"}`),
+				},
+			},
+		},
+	})
+	patch := func(path, secret, key string) configurationv1.ConfigPatch {
+		return configurationv1.ConfigPatch{
+			Path: path,
+			ValueFrom: configurationv1.ConfigSource{
+				SecretValue: configurationv1.SecretValueFromSource{Secret: secret, Key: key},
+			},
+		}
+	}
+	tests := []struct {
+		name        string
+		plugin      configurationv1.KongPlugin
+		wantOK      bool
+		wantMessage string
+		wantConfig  map[string]interface{}
+	}{
+		{
+			name: "inline config merged with secret-sourced fields",
+			plugin: configurationv1.KongPlugin{
+				PluginName: "rate-limiting",
+				Config: apiextensionsv1.JSON{
+					Raw: []byte(`{"minute": 10, "policy": "redis", "redis_password": "placeholder"}`),
+				},
+				ConfigPatches: []configurationv1.ConfigPatch{
+					patch("/redis_password", "redis", "password"),
+					patch("/redis_port", "redis", "port"),
+				},
+			},
+			wantOK: true,
+			wantConfig: map[string]interface{}{
+				"minute":         float64(10),
+				"policy":         "redis",
+				"redis_password": "s3cr3t",
+				"redis_port":     float64(6380),
+			},
+		},
+		{
+			name: "ConfigFrom merged with secret-sourced fields",
+			plugin: configurationv1.KongPlugin{
+				PluginName: "rate-limiting",
+				ConfigFrom: configurationv1.ConfigSource{
+					SecretValue: configurationv1.SecretValueFromSource{Secret: "conf-secret", Key: "rate-limiting-config"},
+				},
+				ConfigPatches: []configurationv1.ConfigPatch{
+					patch("/redis/password", "redis", "password"),
+				},
+			},
+			wantOK: true,
+			wantConfig: map[string]interface{}{
+				"minute": float64(10),
+				"policy": "redis",
+				"redis":  map[string]interface{}{"password": "s3cr3t"},
+			},
+		},
+		{
+			name: "referenced secret key is absent",
+			plugin: configurationv1.KongPlugin{
+				PluginName: "rate-limiting",
+				ConfigPatches: []configurationv1.ConfigPatch{
+					patch("/redis_password", "redis", "passwd"),
+				},
+			},
+			wantMessage: "no key 'passwd' in secret 'default/redis' for config field '/redis_password'",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var submitted kong.Plugin
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, "/schemas/plugins/validate", r.URL.Path)
+				assert.NoError(t, json.NewDecoder(r.Body).Decode(&submitted))
+				w.WriteHeader(http.StatusCreated)
+			}))
+			defer server.Close()
+			client, err := kong.NewClient(kong.String(server.URL), server.Client())
+			assert.NoError(t, err)
+
+			tt.plugin.Namespace = "default"
+			validator := KongHTTPValidator{Client: client, Store: store}
+			ok, message, err := validator.ValidatePlugin(context.Background(), tt.plugin)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantOK, ok)
+			assert.Equal(t, tt.wantMessage, message)
+			if tt.wantConfig != nil {
+				assert.Equal(t, kong.Configuration(tt.wantConfig), submitted.Config)
+			}
+		})
+	}
+}
+
+func TestKongHTTPValidator_ValidatePluginVaultReferences(t *testing.T) {
+	store, _ := store.NewFakeStore(store.FakeObjects{
+		Secrets: []*corev1.Secret{
+			{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "redis"},
+				Data:       map[string][]byte{"password": []byte("{vault://hcv/redis}")},
+			},
+		},
+	})
+	supported := semver.MustParse("2.8.0")
+	tests := []struct {
+		name          string
+		version       semver.Version
+		plugin        configurationv1.KongPlugin
+		wantOK        bool
+		wantMessage   string
+		wantSubmitted map[string]interface{}
+	}{
+		{
+			name:    "well-formed references are submitted untouched",
+			version: supported,
+			plugin: configurationv1.KongPlugin{
+				PluginName: "rate-limiting",
+				Config: apiextensionsv1.JSON{
+					Raw: []byte(`{"policy": "redis", "redis": {"password": "{vault://env/redis-password}"}}`),
+				},
+				ConfigPatches: []configurationv1.ConfigPatch{{
+					Path: "/redis/username",
+					ValueFrom: configurationv1.ConfigSource{
+						SecretValue: configurationv1.SecretValueFromSource{Secret: "redis", Key: "password"},
+					},
+				}},
+			},
+			wantOK: true,
+			wantSubmitted: map[string]interface{}{
+				"policy": "redis",
+				"redis": map[string]interface{}{
+					"password": "{vault://env/redis-password}",
+					"username": "{vault://hcv/redis}",
+				},
+			},
+		},
+		{
+			name:    "malformed reference",
+			version: supported,
+			plugin: configurationv1.KongPlugin{
+				PluginName: "rate-limiting",
+				Config: apiextensionsv1.JSON{
+					Raw: []byte(`{"policy": "redis", "redis_password": "{vault://ENV/redis-password}"}`),
+				},
+			},
+			wantMessage: "invalid vault reference '{vault://ENV/redis-password}' in config field '/redis_password': " +
+				"invalid vault 'ENV': must consist of lower case letters, digits, '-' and '_', starting with a letter",
+		},
+		{
+			name:    "unterminated reference",
+			version: supported,
+			plugin: configurationv1.KongPlugin{
+				PluginName: "rate-limiting",
+				Config: apiextensionsv1.JSON{
+					Raw: []byte(`{"policy": "redis", "redis_password": "{vault://env/redis-password"}`),
+				},
+			},
+			wantMessage: "invalid vault reference '{vault://env/redis-password' in config field '/redis_password': " +
+				"must end with }",
+		},
+		{
+			name:    "reference on a Kong without vaults",
+			version: semver.MustParse("2.7.1"),
+			plugin: configurationv1.KongPlugin{
+				PluginName: "rate-limiting",
+				Config: apiextensionsv1.JSON{
+					Raw: []byte(`{"policy": "redis", "redis_password": "{vault://env/redis-password}"}`),
+				},
+			},
+			wantMessage: "vault reference '{vault://env/redis-password}' in config field '/redis_password' " +
+				"requires Kong 2.8.0 or later",
+		},
+		{
+			name: "reference on a Kong of unknown version",
+			plugin: configurationv1.KongPlugin{
+				PluginName: "rate-limiting",
+				Config: apiextensionsv1.JSON{
+					Raw: []byte(`{"policy": "redis", "redis_password": "{vault://env/redis-password}"}`),
+				},
+			},
+			wantMessage: "vault reference '{vault://env/redis-password}' in config field '/redis_password' " +
+				"requires Kong 2.8.0 or later",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var submitted *kong.Plugin
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				submitted = &kong.Plugin{}
+				assert.NoError(t, json.NewDecoder(r.Body).Decode(submitted))
+				w.WriteHeader(http.StatusCreated)
+			}))
+			defer server.Close()
+			client, err := kong.NewClient(kong.String(server.URL), server.Client())
+			assert.NoError(t, err)
+
+			tt.plugin.Namespace = "default"
+			validator := KongHTTPValidator{Client: client, Store: store, KongVersion: tt.version}
+			ok, message, err := validator.ValidatePlugin(context.Background(), tt.plugin)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantOK, ok)
+			assert.Equal(t, tt.wantMessage, message)
+			if tt.wantSubmitted != nil {
+				if assert.NotNil(t, submitted) {
+					assert.Equal(t, kong.Configuration(tt.wantSubmitted), submitted.Config)
+				}
+			} else {
+				assert.Nil(t, submitted, "rejected references must not be submitted to kong")
+			}
+		})
+	}
+}
+
+func TestKongHTTPValidator_ValidateKongIngress(t *testing.T) {
+	tests := []struct {
+		name        string
+		kongIngress configurationv1.KongIngress
+		wantOK      bool
+		wantMessage string
+		wantEntity  map[string]map[string]interface{}
+	}{
+		{
+			name:   "empty KongIngress",
+			wantOK: true,
+		},
+		{
+			name: "valid overrides are merged onto placeholders",
+			kongIngress: configurationv1.KongIngress{
+				Proxy: &kong.Service{Retries: kong.Int(3)},
+				Route: &kong.Route{StripPath: kong.Bool(false)},
+			},
+			wantOK: true,
+			wantEntity: map[string]map[string]interface{}{
+				"services": {"name": "kongingress-validation", "host": "kongingress-validation", "retries": float64(3)},
+				"routes":   {"paths": []interface{}{"/"}, "strip_path": false},
+			},
+		},
+		{
+			name: "stream route gets stream placeholders",
+			kongIngress: configurationv1.KongIngress{
+				Route: &kong.Route{Protocols: kong.StringSlice("tcp")},
+			},
+			wantOK: true,
+			wantEntity: map[string]map[string]interface{}{
+				"routes": {
+					"destinations": []interface{}{map[string]interface{}{"port": float64(80)}},
+					"protocols":    []interface{}{"tcp"},
+				},
+			},
+		},
+		{
+			name: "every invalid entity is reported",
+			kongIngress: configurationv1.KongIngress{
+				Upstream: &kong.Upstream{HashOn: kong.String("nonsense")},
+				Proxy:    &kong.Service{Retries: kong.Int(3)},
+				Route:    &kong.Route{RegexPriority: kong.Int(-1)},
+			},
+			wantOK: false,
+			wantMessage: `invalid upstream: HTTP status 400 (message: "schema violation (hash_on: expected one of: none, consumer, ip, header, cookie)"); ` +
+				`invalid route: HTTP status 400 (message: "schema violation (regex_priority: invalid)")`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			submitted := map[string]map[string]interface{}{}
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				var entity map[string]interface{}
+				assert.NoError(t, json.NewDecoder(r.Body).Decode(&entity))
+				kind := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/schemas/"), "/validate")
+				submitted[kind] = entity
+				switch {
+				case entity["hash_on"] == "nonsense":
+					w.WriteHeader(http.StatusBadRequest)
+					_, _ = w.Write([]byte(`{"message": "schema violation (hash_on: expected one of: none, consumer, ip, header, cookie)"}`))
+				case entity["regex_priority"] == float64(-1):
+					w.WriteHeader(http.StatusBadRequest)
+					_, _ = w.Write([]byte(`{"message": "schema violation (regex_priority: invalid)"}`))
+				default:
+					w.WriteHeader(http.StatusOK)
+				}
+			}))
+			defer server.Close()
+			client, err := kong.NewClient(kong.String(server.URL), server.Client())
+			assert.NoError(t, err)
+
+			validator := KongHTTPValidator{Client: client}
+			ok, message, err := validator.ValidateKongIngress(context.Background(), tt.kongIngress)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantOK, ok)
+			assert.Equal(t, tt.wantMessage, message)
+			for kind, entity := range tt.wantEntity {
+				assert.Equal(t, entity, submitted[kind])
+			}
+		})
+	}
+}
+
+func TestKongHTTPValidator_ValidateIngressPaths(t *testing.T) {
+	var submitted []interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/schemas/routes/validate", r.URL.Path)
+		var route map[string]interface{}
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&route))
+		submitted = append(submitted, route["paths"])
+		paths, _ := route["paths"].([]interface{})
+		if len(paths) == 1 && strings.HasSuffix(paths[0].(string), "(") {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"message": "schema violation (paths.1: invalid regex: '/foo(')"}`))
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+	client, err := kong.NewClient(kong.String(server.URL), server.Client())
+	assert.NoError(t, err)
+	validator := KongHTTPValidator{Client: client}
+
+	ok, message, err := validator.ValidateIngressPaths(context.Background(), []string{"/bar/[0-9]+"})
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.Empty(t, message)
+
+	submitted = nil
+	ok, message, err = validator.ValidateIngressPaths(context.Background(), []string{"/foo(", "/bar/[0-9]+"})
+	assert.NoError(t, err)
+	assert.False(t, ok)
+	assert.Equal(t, `invalid path "/foo(": HTTP status 400 (message: "schema violation (paths.1: invalid regex: '/foo(')")`,
+		message)
+	assert.Equal(t, []interface{}{[]interface{}{"/foo("}, []interface{}{"/bar/[0-9]+"}}, submitted,
+		"every path is validated on its own")
+
+	server.Close()
+	_, _, err = validator.ValidateIngressPaths(context.Background(), []string{"/foo("})
+	assert.True(t, isKongUnavailable(err))
+}
+
+func TestKongHTTPValidator_ValidateTCPIngress(t *testing.T) {
+	tcpIngress := func(namespace, name string, rules ...configurationv1beta1.IngressRule) *configurationv1beta1.TCPIngress {
+		return &configurationv1beta1.TCPIngress{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: namespace,
+				Name:      name,
+				Annotations: map[string]string{
+					annotations.IngressClassKey: annotations.DefaultIngressClass,
+				},
+			},
+			Spec: configurationv1beta1.IngressSpec{Rules: rules},
+		}
+	}
+	rule := func(host string, port int) configurationv1beta1.IngressRule {
+		return configurationv1beta1.IngressRule{
+			Host:    host,
+			Port:    port,
+			Backend: configurationv1beta1.IngressBackend{ServiceName: "echo", ServicePort: 1025},
+		}
+	}
+	store, _ := store.NewFakeStore(store.FakeObjects{
+		TCPIngresses: []*configurationv1beta1.TCPIngress{
+			tcpIngress("default", "plain", rule("", 9000)),
+			tcpIngress("default", "tls", rule("example.com", 9443)),
+		},
+	})
+	tests := []struct {
+		name        string
+		tcpIngress  *configurationv1beta1.TCPIngress
+		wantOK      bool
+		wantMessage string
+	}{
+		{
+			name:       "unused port",
+			tcpIngress: tcpIngress("default", "new", rule("", 9001)),
+			wantOK:     true,
+		},
+		{
+			name:        "port used in the same namespace",
+			tcpIngress:  tcpIngress("default", "new", rule("", 9000)),
+			wantMessage: "rules[0]: port 9000 is already in use by TCPIngress default/plain",
+		},
+		{
+			name:        "port used in another namespace",
+			tcpIngress:  tcpIngress("other", "new", rule("", 9001), rule("", 9000)),
+			wantMessage: "rules[1]: port 9000 is already in use by TCPIngress default/plain",
+		},
+		{
+			name:        "port and SNI used in another namespace",
+			tcpIngress:  tcpIngress("other", "new", rule("Example.com", 9443)),
+			wantMessage: "rules[0]: port 9443 with SNI 'Example.com' is already in use by TCPIngress default/tls",
+		},
+		{
+			name:       "port used with another SNI",
+			tcpIngress: tcpIngress("other", "new", rule("example.net", 9443)),
+			wantOK:     true,
+		},
+		{
+			name:       "updating the existing object",
+			tcpIngress: tcpIngress("default", "plain", rule("", 9000)),
+			wantOK:     true,
+		},
+		{
+			name:        "SNI rule on a port used without SNI",
+			tcpIngress:  tcpIngress("other", "new", rule("example.com", 9000)),
+			wantMessage: "rules[0]: port 9000 is already routed without SNI by TCPIngress default/plain",
+		},
+		{
+			name:        "rule without SNI on a port used by SNI",
+			tcpIngress:  tcpIngress("other", "new", rule("", 9443)),
+			wantMessage: "rules[0]: port 9443 is already routed by SNI by TCPIngress default/tls",
+		},
+		{
+			name:        "rules with and without SNI within the object",
+			tcpIngress:  tcpIngress("default", "new", rule("example.com", 9001), rule("", 9001)),
+			wantMessage: "rules[1]: port 9001 is routed both with and without SNI",
+		},
+		{
+			name:       "SNI rules sharing a port within the object",
+			tcpIngress: tcpIngress("default", "new", rule("foo.example.com", 9001), rule("bar.example.com", 9001)),
+			wantOK:     true,
+		},
+		{
+			name:        "port repeated within the object",
+			tcpIngress:  tcpIngress("default", "new", rule("", 9001), rule("", 9001)),
+			wantMessage: "rules[1]: port 9001 is used by more than one rule",
+		},
+		{
+			name:        "invalid port",
+			tcpIngress:  tcpIngress("default", "new", rule("", 0)),
+			wantMessage: "rules[0]: invalid port 0",
+		},
+		{
+			name: "missing backend service",
+			tcpIngress: tcpIngress("default", "new", configurationv1beta1.IngressRule{
+				Port: 9001, Backend: configurationv1beta1.IngressBackend{ServicePort: 80},
+			}),
+			wantMessage: "rules[0]: backend serviceName cannot be empty",
+		},
+		{
+			name: "invalid backend port",
+			tcpIngress: tcpIngress("default", "new", configurationv1beta1.IngressRule{
+				Port: 9001, Backend: configurationv1beta1.IngressBackend{ServiceName: "echo", ServicePort: 70000},
+			}),
+			wantMessage: "rules[0]: invalid backend servicePort 70000",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			validator := KongHTTPValidator{Store: store}
+			ok, message, err := validator.ValidateTCPIngress(*tt.tcpIngress)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantOK, ok)
+			assert.Equal(t, tt.wantMessage, message)
+		})
+	}
+}
+
+func TestKongHTTPValidator_ValidateUDPIngress(t *testing.T) {
+	udpIngress := func(namespace, name string, listenPort int) *v1alpha1.UDPIngress {
+		return &v1alpha1.UDPIngress{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: namespace,
+				Name:      name,
+				Annotations: map[string]string{
+					annotations.IngressClassKey: annotations.DefaultIngressClass,
+				},
+			},
+			Spec: v1alpha1.UDPIngressSpec{Host: "dns.default.svc", ListenPort: listenPort, TargetPort: 53},
+		}
+	}
+	store, _ := store.NewFakeStore(store.FakeObjects{
+		UDPIngresses: []*v1alpha1.UDPIngress{udpIngress("default", "dns", 9053)},
+	})
+	tests := []struct {
+		name        string
+		udpIngress  *v1alpha1.UDPIngress
+		wantOK      bool
+		wantMessage string
+	}{
+		{
+			name:       "unused port",
+			udpIngress: udpIngress("default", "new", 9054),
+			wantOK:     true,
+		},
+		{
+			name:        "port used in another namespace",
+			udpIngress:  udpIngress("other", "new", 9053),
+			wantMessage: "listenPort 9053 is already in use by UDPIngress default/dns",
+		},
+		{
+			name:       "updating the existing object",
+			udpIngress: udpIngress("default", "dns", 9053),
+			wantOK:     true,
+		},
+		{
+			name:        "invalid port",
+			udpIngress:  udpIngress("default", "new", 65536),
+			wantMessage: "invalid listenPort 65536",
+		},
+		{
+			name: "missing backend host",
+			udpIngress: &v1alpha1.UDPIngress{
+				Spec: v1alpha1.UDPIngressSpec{ListenPort: 9054, TargetPort: 53},
+			},
+			wantMessage: "host cannot be empty",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			validator := KongHTTPValidator{Store: store}
+			ok, message, err := validator.ValidateUDPIngress(*tt.udpIngress)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantOK, ok)
+			assert.Equal(t, tt.wantMessage, message)
+		})
+	}
+}
+
+func TestKongHTTPValidator_ValidatePluginReferences(t *testing.T) {
+	store, _ := store.NewFakeStore(store.FakeObjects{
+		Secrets: []*corev1.Secret{
+			{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "cors-config"},
+				Data:       map[string][]byte{"config": []byte(`{"origins":["example.com"]}`)},
+			},
+			{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "kong", Name: "acl-config"},
+				Data:       map[string][]byte{"config": []byte(`{"allow":["admins"]}`)},
+			},
+		},
+		KongPlugins: []*configurationv1.KongPlugin{
+			{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "limit-1"},
+				PluginName: "rate-limiting",
+			},
+			{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "limit-2"},
+				PluginName: "rate-limiting",
+			},
+			{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "auth"},
+				PluginName: "key-auth",
+			},
+			{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "cors"},
+				PluginName: "cors",
+				ConfigFrom: configurationv1.ConfigSource{
+					SecretValue: configurationv1.SecretValueFromSource{Secret: "cors-config", Key: "config"},
+				},
+			},
+			{
+				// the secret lives in another namespace, where a KongPlugin can't read it from
+				ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "acl"},
+				PluginName: "acl",
+				ConfigFrom: configurationv1.ConfigSource{
+					SecretValue: configurationv1.SecretValueFromSource{Secret: "acl-config", Key: "config"},
+				},
+			},
+			{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "cors-missing-secret"},
+				PluginName: "cors",
+				ConfigFrom: configurationv1.ConfigSource{
+					SecretValue: configurationv1.SecretValueFromSource{Secret: "missing", Key: "config"},
+				},
+			},
+			{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "cors-missing-key"},
+				PluginName: "cors",
+				ConfigFrom: configurationv1.ConfigSource{
+					SecretValue: configurationv1.SecretValueFromSource{Secret: "cors-config", Key: "missing"},
+				},
+			},
+		},
+		KongClusterPlugins: []*configurationv1.KongClusterPlugin{
+			{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "cluster-limit",
+					Annotations: map[string]string{
+						annotations.IngressClassKey: annotations.DefaultIngressClass,
+					},
+				},
+				PluginName: "rate-limiting",
+			},
+			{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "cluster-acl",
+					Annotations: map[string]string{
+						annotations.IngressClassKey: annotations.DefaultIngressClass,
+					},
+				},
+				PluginName: "acl",
+				ConfigFrom: configurationv1.NamespacedConfigSource{
+					SecretValue: configurationv1.NamespacedSecretValueFromSource{
+						Namespace: "kong", Secret: "acl-config", Key: "config",
+					},
+				},
+			},
+			{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "cluster-acl-wrong-namespace",
+					Annotations: map[string]string{
+						annotations.IngressClassKey: annotations.DefaultIngressClass,
+					},
+				},
+				PluginName: "acl",
+				ConfigFrom: configurationv1.NamespacedConfigSource{
+					SecretValue: configurationv1.NamespacedSecretValueFromSource{
+						Namespace: "default", Secret: "acl-config", Key: "config",
+					},
+				},
+			},
+			{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "cluster-acl-no-namespace",
+					Annotations: map[string]string{
+						annotations.IngressClassKey: annotations.DefaultIngressClass,
+					},
+				},
+				PluginName: "acl",
+				ConfigFrom: configurationv1.NamespacedConfigSource{
+					SecretValue: configurationv1.NamespacedSecretValueFromSource{Secret: "acl-config", Key: "config"},
+				},
+			},
+			{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "cluster-acl-missing-secret",
+					Annotations: map[string]string{
+						annotations.IngressClassKey: annotations.DefaultIngressClass,
+					},
+				},
+				PluginName: "acl",
+				ConfigFrom: configurationv1.NamespacedConfigSource{
+					SecretValue: configurationv1.NamespacedSecretValueFromSource{
+						Namespace: "kong", Secret: "missing", Key: "config",
+					},
+				},
+			},
+		},
+	})
+	tests := []struct {
+		name        string
+		plugins     string
+		wantOK      bool
+		wantMessage string
+	}{
+		{
+			name:   "no plugins",
+			wantOK: true,
+		},
+		{
+			name:    "distinct plugins",
+			plugins: "limit-1, auth",
+			wantOK:  true,
+		},
+		{
+			name:    "missing plugin",
+			plugins: "limit-1, missing",
+			wantOK:  true,
+		},
+		{
+			name:        "duplicate plugins",
+			plugins:     "limit-1,auth,limit-2",
+			wantMessage: "KongPlugin 'limit-1' and KongPlugin 'limit-2' both configure plugin 'rate-limiting', which can only be attached once to an object",
+		},
+		{
+			name:        "duplicate plugin from a KongClusterPlugin",
+			plugins:     "cluster-limit,limit-2",
+			wantMessage: "KongClusterPlugin 'cluster-limit' and KongPlugin 'limit-2' both configure plugin 'rate-limiting', which can only be attached once to an object",
+		},
+		{
+			name:    "plugins with their configuration in secrets",
+			plugins: "cors,cluster-acl,limit-1",
+			wantOK:  true,
+		},
+		{
+			name:    "KongClusterPlugin reading its secret from the wrong namespace",
+			plugins: "cors,cluster-acl,cluster-acl-wrong-namespace",
+			wantMessage: "KongClusterPlugin 'cluster-acl-wrong-namespace' cannot be attached to the object: " +
+				"error fetching plugin configuration secret 'default/acl-config': Secret default/acl-config not found",
+		},
+		{
+			name:    "KongPlugin reading a secret of another namespace",
+			plugins: "limit-1,acl",
+			wantMessage: "KongPlugin 'acl' cannot be attached to the object: " +
+				"error fetching plugin configuration secret 'default/acl-config': Secret default/acl-config not found",
+		},
+		{
+			name:    "KongClusterPlugin without the namespace of its secret",
+			plugins: "cors,cluster-acl-no-namespace",
+			wantMessage: "KongClusterPlugin 'cluster-acl-no-namespace' cannot be attached to the object: " +
+				"configFrom does not set the namespace of secret 'acl-config'",
+		},
+		{
+			name:        "valid KongClusterPlugin conflicting with a KongPlugin",
+			plugins:     "cluster-acl,cors,limit-1,limit-2",
+			wantMessage: "KongPlugin 'limit-1' and KongPlugin 'limit-2' both configure plugin 'rate-limiting', which can only be attached once to an object",
+		},
+		{
+			name:    "plugins whose secrets don't exist yet",
+			plugins: "cors-missing-secret,cluster-acl-missing-secret,limit-1",
+			wantOK:  true,
+		},
+		{
+			name:    "KongPlugin reading a missing key of its secret",
+			plugins: "cors-missing-key",
+			wantMessage: "KongPlugin 'cors-missing-key' cannot be attached to the object: " +
+				"no key 'missing' in secret 'default/cors-config'",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			obj := &metav1.ObjectMeta{Namespace: "default", Name: "foo"}
+			if tt.plugins != "" {
+				obj.Annotations = map[string]string{"konghq.com/plugins": tt.plugins}
+			}
+			validator := KongHTTPValidator{Store: store}
+			ok, message, err := validator.ValidatePluginReferences(obj)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantOK, ok)
+			assert.Equal(t, tt.wantMessage, message)
+		})
+	}
+}
+
+// newFakeAdminAPI returns a Kong client for an Admin API stub which serves
+// the given JSON bodies keyed by request path and 404 for any other path.
+func newFakeAdminAPI(t *testing.T, responses map[string]string) (*kong.Client, func()) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, ok := responses[r.URL.Path]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"message":"Not found"}`))
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(body))
+	}))
+	client, err := kong.NewClient(kong.String(server.URL), server.Client())
+	if err != nil {
+		t.Fatalf("creating kong client: %v", err)
+	}
+	return client, server.Close
+}
@@ -43,12 +43,22 @@ import (
 	knativeClientSet "knative.dev/networking/pkg/client/clientset/versioned"
 )
 
+// syncCoalesceWindow is how long syncs wait for further updates once the sync queue is backed up.
+const syncCoalesceWindow = 5 * time.Second
+
 // Configuration contains all the settings required by an Ingress controller
 type Configuration struct {
 	sendconfig.Kong
 
 	KongCustomEntitiesSecret string
 
+	// ServiceDefaults are applied to every generated Kong service unless overridden by a KongIngress.
+	ServiceDefaults parser.ServiceDefaults
+	// RouteDefaults are applied to every generated Kong route unless overridden by an annotation or a KongIngress.
+	RouteDefaults parser.RouteDefaults
+	// CredentialTypeKey is the data field of Secrets holding the type of their credential.
+	CredentialTypeKey string
+
 	KubeClient       clientset.Interface
 	KongConfigClient configClientSet.Interface
 	KnativeClient    knativeClientSet.Interface
@@ -56,6 +66,9 @@ type Configuration struct {
 	ResyncPeriod      time.Duration
 	SyncRateLimit     float32
 	EnableReverseSync bool
+	// SyncCoalesceThreshold is the number of queued updates beyond which they are coalesced into a single
+	// sync. Disabled if zero.
+	SyncCoalesceThreshold int
 
 	Namespace string
 
@@ -100,8 +113,13 @@ func (n *KongController) syncIngress(interface{}) error {
 	}
 
 	n.Logger.Infof("syncing configuration")
-	state, err := parser.Build(n.Logger.WithField("component", "store"), n.store)
-	state.Version = n.cfg.Kong.Version
+	state, err := parser.BuildWithOptions(n.Logger.WithField("component", "store"), n.store, parser.Options{
+		ServiceDefaults:   n.cfg.ServiceDefaults,
+		RouteDefaults:     n.cfg.RouteDefaults,
+		CredentialTypeKey: n.cfg.CredentialTypeKey,
+		ValidatePath:      n.pathValidator.Validate,
+		KongVersion:       n.cfg.Kong.Version,
+	})
 	if err != nil {
 		return fmt.Errorf("error building kong state: %w", err)
 	}
@@ -133,6 +151,7 @@ func NewKongController(ctx context.Context,
 
 		stopLock:          &sync.Mutex{},
 		PluginSchemaStore: *util.NewPluginSchemaStore(config.Kong.Client),
+		pathValidator:     sendconfig.NewRoutePathValidator(config.Kong.Client),
 
 		Logger: config.Logger,
 	}
@@ -140,6 +159,9 @@ func NewKongController(ctx context.Context,
 	n.store = store
 	n.syncQueue = task.NewTaskQueue(n.syncIngress,
 		config.Logger.WithField("component", "sync-queue"))
+	n.syncQueue.Name = "sync"
+	n.syncQueue.CoalesceThreshold = config.SyncCoalesceThreshold
+	n.syncQueue.CoalesceWindow = syncCoalesceWindow
 
 	electionID := config.ElectionID + "-" + config.IngressClass
 
@@ -219,6 +241,9 @@ type KongController struct {
 
 	PluginSchemaStore util.PluginSchemaStore
 
+	// pathValidator validates the regular expression paths of routes with Kong before they are synced.
+	pathValidator *sendconfig.RoutePathValidator
+
 	Logger logrus.FieldLogger
 }
 
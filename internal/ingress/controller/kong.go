@@ -28,6 +28,7 @@ import (
 	"github.com/kong/kubernetes-ingress-controller/pkg/kongstate"
 	"github.com/kong/kubernetes-ingress-controller/pkg/sendconfig"
 	"github.com/kong/kubernetes-ingress-controller/pkg/util"
+	"github.com/sirupsen/logrus"
 )
 
 // OnUpdate is called periodically by syncQueue to keep the configuration in sync.
@@ -56,6 +57,13 @@ func (n *KongController) OnUpdate(ctx context.Context, state *kongstate.KongStat
 		customEntities,
 		n.runningConfigHash,
 	)
+	for _, entity := range sendconfig.RejectedEntities(err) {
+		logger := n.Logger.WithFields(logrus.Fields{"entity": entity.Path, "name": entity.Name, "errors": entity.Errors})
+		if source, ok := state.EntitySource(entity.Type, entity.Name); ok {
+			logger = logger.WithFields(logrus.Fields{"kind": source.Kind, "namespace": source.Namespace, "object": source.Name})
+		}
+		logger.Error("kong rejected an entity of the configuration")
+	}
 
 	if n.cfg.DumpConfig != util.ConfigDumpModeOff {
 		if n.cfg.DumpConfig == util.ConfigDumpModeEnabled {
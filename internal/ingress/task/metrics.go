@@ -0,0 +1,60 @@
+package task
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	metricsNamespace = "kong_ingress_controller"
+	metricsSubsystem = "task_queue"
+
+	queueLabel = "queue"
+)
+
+var (
+	queueDepth = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Subsystem: metricsSubsystem,
+		Name:      "depth",
+		Help:      "Number of items waiting in a task queue.",
+	}, []string{queueLabel})
+
+	coalescedItems = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Subsystem: metricsSubsystem,
+		Name:      "coalesced_total",
+		Help:      "Number of items of a task queue handled by the sync of another item as the queue was backed up.",
+	}, []string{queueLabel})
+)
+
+// RegisterMetrics registers the collectors of the task queue metrics with
+// registerer.
+func RegisterMetrics(registerer prometheus.Registerer) error {
+	for _, collector := range []prometheus.Collector{
+		queueDepth,
+		coalescedItems,
+	} {
+		if err := registerer.Register(collector); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// observeDepth records the number of items waiting in the queue, if it is
+// named.
+func (t *Queue) observeDepth() {
+	if t.Name == "" {
+		return
+	}
+	queueDepth.WithLabelValues(t.Name).Set(float64(t.queue.Len()))
+}
+
+// observeCoalesced records the number of items coalesced into the sync of
+// another, if the queue is named.
+func (t *Queue) observeCoalesced(count int) {
+	if t.Name == "" {
+		return
+	}
+	coalescedItems.WithLabelValues(t.Name).Add(float64(count))
+}
@@ -47,6 +47,16 @@ type Queue struct {
 
 	lastSync int64
 
+	// Name labels the metrics of the queue. The metrics of unnamed queues
+	// are not reported.
+	Name string
+	// CoalesceThreshold, when positive, is the number of items waiting in
+	// the queue beyond which the worker drains them all and waits for
+	// CoalesceWindow before syncing once, for them and for all the items
+	// queued in the meantime.
+	CoalesceThreshold int
+	CoalesceWindow    time.Duration
+
 	Logger logrus.FieldLogger
 }
 
@@ -79,6 +89,7 @@ func (t *Queue) Enqueue(obj interface{}) {
 		Key:       key,
 		Timestamp: ts,
 	})
+	t.observeDepth()
 }
 
 func (t *Queue) defaultKeyFunc(obj interface{}) (interface{}, error) {
@@ -94,14 +105,13 @@ func (t *Queue) defaultKeyFunc(obj interface{}) (interface{}, error) {
 func (t *Queue) worker() {
 	for {
 		key, quit := t.queue.Get()
+		t.observeDepth()
 		if quit {
 			if !isClosed(t.workerDone) {
 				close(t.workerDone)
 			}
 			return
 		}
-		ts := time.Now().UnixNano()
-
 		item := key.(Element)
 		if t.lastSync > item.Timestamp {
 			t.Logger.Debugf("skipping sync for '%v': timestamp too old (%v > %v)", item.Key, t.lastSync, item.Timestamp)
@@ -110,6 +120,15 @@ func (t *Queue) worker() {
 			continue
 		}
 
+		if count := t.coalesce(); count > 0 {
+			t.Logger.Warnf("%d items are queued, more than %d: syncing them along with '%v' in %v",
+				count, t.CoalesceThreshold, item.Key, t.CoalesceWindow)
+			t.observeCoalesced(count)
+			time.Sleep(t.CoalesceWindow)
+		}
+		// the items queued before the sync starts are skipped once it succeeds
+		ts := time.Now().UnixNano()
+
 		t.Logger.Debugf("syncing item '%v'", item.Key)
 		if err := t.sync(key); err != nil {
 			t.Logger.Errorf("failed to sync: %v", err)
@@ -127,6 +146,29 @@ func (t *Queue) worker() {
 	}
 }
 
+// coalesce drains the queue if more than CoalesceThreshold items wait in it,
+// so that the sync about to run handles them all, and returns the number of
+// items drained. As every sync handles the state as a whole, the drained
+// items need no sync of their own; should the sync fail, the item it is run
+// for is requeued and the next sync handles them.
+func (t *Queue) coalesce() int {
+	if t.CoalesceThreshold <= 0 || t.queue.Len() <= t.CoalesceThreshold {
+		return 0
+	}
+	count := 0
+	for t.queue.Len() > 0 {
+		key, quit := t.queue.Get()
+		if quit {
+			break
+		}
+		t.queue.Forget(key)
+		t.queue.Done(key)
+		count++
+	}
+	t.observeDepth()
+	return count
+}
+
 func isClosed(ch <-chan bool) bool {
 	select {
 	case <-ch:
@@ -22,6 +22,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/sirupsen/logrus"
 )
 
@@ -160,6 +161,110 @@ func TestSkipEnqueue(t *testing.T) {
 	q.Shutdown()
 }
 
+func echoKeyFn(obj interface{}) (interface{}, error) {
+	return obj, nil
+}
+
+func TestQueueDepthMetric(t *testing.T) {
+	q := NewCustomTaskQueue(mockSynFn, echoKeyFn, logrus.New())
+	q.Name = "test-depth"
+	depth := queueDepth.WithLabelValues(q.Name)
+
+	for i := 0; i < 3; i++ {
+		q.Enqueue(fmt.Sprintf("item-%d", i))
+	}
+	if got := testutil.ToFloat64(depth); got != 3 {
+		t.Errorf("depth should be 3, but is %v", got)
+	}
+
+	// the worker empties the queue
+	stopCh := make(chan struct{})
+	go q.Run(time.Second, stopCh)
+	if !waitFor(func() bool { return testutil.ToFloat64(depth) == 0 }) {
+		t.Errorf("depth should be 0, but is %v", testutil.ToFloat64(depth))
+	}
+	q.Shutdown()
+}
+
+func TestCoalesce(t *testing.T) {
+	for _, tt := range []struct {
+		name          string
+		threshold     int
+		wantSyncs     uint32
+		wantCoalesced float64
+	}{
+		// the items queued during the first sync make for a second one, and
+		// those queued during the second one for a third
+		{name: "disabled", wantSyncs: 3},
+		// the items queued during the first sync are coalesced, and those
+		// queued in the coalesce window are handled by the same second sync
+		{name: "backed up", threshold: 2, wantSyncs: 2, wantCoalesced: 4},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			var syncs uint32
+			release := make(chan struct{})
+			syncFn := func(interface{}) error {
+				if atomic.AddUint32(&syncs, 1) == 1 {
+					<-release
+				}
+				return nil
+			}
+			q := NewCustomTaskQueue(syncFn, echoKeyFn, logrus.New())
+			q.Name = "test-coalesce-" + tt.name
+			q.CoalesceThreshold = tt.threshold
+			q.CoalesceWindow = 200 * time.Millisecond
+			depth := queueDepth.WithLabelValues(q.Name)
+			coalesced := coalescedItems.WithLabelValues(q.Name)
+			coalescedBefore := testutil.ToFloat64(coalesced)
+
+			stopCh := make(chan struct{})
+			go q.Run(time.Second, stopCh)
+			q.Enqueue("first")
+			if !waitFor(func() bool { return atomic.LoadUint32(&syncs) == 1 }) {
+				t.Fatalf("the first sync should have started")
+			}
+
+			// queued while the first sync runs
+			for i := 0; i < 5; i++ {
+				q.Enqueue(fmt.Sprintf("item-%d", i))
+			}
+			if got := testutil.ToFloat64(depth); got != 5 {
+				t.Errorf("depth should be 5, but is %v", got)
+			}
+			close(release)
+
+			// queued once the worker is done with the items above
+			if !waitFor(func() bool { return testutil.ToFloat64(depth) == 0 }) {
+				t.Fatalf("depth should be 0, but is %v", testutil.ToFloat64(depth))
+			}
+			for i := 0; i < 3; i++ {
+				q.Enqueue(fmt.Sprintf("late-item-%d", i))
+			}
+
+			time.Sleep(q.CoalesceWindow + 100*time.Millisecond)
+			q.Shutdown()
+			if got := atomic.LoadUint32(&syncs); got != tt.wantSyncs {
+				t.Errorf("syncs should be %d, but are %d", tt.wantSyncs, got)
+			}
+			if got := testutil.ToFloat64(coalesced) - coalescedBefore; got != tt.wantCoalesced {
+				t.Errorf("coalesced items should be %v, but are %v", tt.wantCoalesced, got)
+			}
+		})
+	}
+}
+
+// waitFor waits for condition to be true, checking it every 10 ms for up to
+// 5 seconds, and returns whether it became true.
+func waitFor(condition func() bool) bool {
+	for i := 0; i < 500; i++ {
+		if condition() {
+			return true
+		}
+		time.Sleep(time.Millisecond * 10)
+	}
+	return false
+}
+
 // checkSR waits for the value to match expected.
 // It loops and checks every 10 ms till 5 seconds.
 // This should usually succeed in the first attempt if plenty of CPU
@@ -52,6 +52,11 @@ const (
 	SNIsKey              = "/snis"
 	RequestBuffering     = "/request-buffering"
 	ResponseBuffering    = "/response-buffering"
+	WeightedBackendsKey  = "/weighted-backends"
+	HashOnHeaderKey      = "/hash-on-header"
+	HashOnCookieKey      = "/hash-on-cookie"
+	HashOnCookiePathKey  = "/hash-on-cookie-path"
+	TagsKey              = "/tags"
 
 	// DefaultIngressClass defines the default class used
 	// by Kong's ingress controller.
@@ -231,3 +236,44 @@ func ExtractResponseBuffering(anns map[string]string) (string, bool) {
 	s, ok := anns[AnnotationPrefix+ResponseBuffering]
 	return s, ok
 }
+
+// ExtractWeightedBackends extracts the weighted-backends annotation value, listing the
+// Services the upstream of a Service distributes traffic to, with their weights.
+func ExtractWeightedBackends(anns map[string]string) (string, bool) {
+	s, ok := anns[AnnotationPrefix+WeightedBackendsKey]
+	return s, ok
+}
+
+// ExtractHashOnHeader extracts the name of the header the upstream of a Service
+// hashes requests on.
+func ExtractHashOnHeader(anns map[string]string) string {
+	return anns[AnnotationPrefix+HashOnHeaderKey]
+}
+
+// ExtractHashOnCookie extracts the name of the cookie the upstream of a Service
+// hashes requests on.
+func ExtractHashOnCookie(anns map[string]string) string {
+	return anns[AnnotationPrefix+HashOnCookieKey]
+}
+
+// ExtractHashOnCookiePath extracts the path of the cookie the upstream of a
+// Service hashes requests on.
+func ExtractHashOnCookiePath(anns map[string]string) string {
+	return anns[AnnotationPrefix+HashOnCookiePathKey]
+}
+
+// ExtractTags extracts the tags annotation value, listing the tags set on the
+// Kong entities generated from an object.
+func ExtractTags(anns map[string]string) []string {
+	val := anns[AnnotationPrefix+TagsKey]
+	if val == "" {
+		return nil
+	}
+	var tags []string
+	for _, tag := range strings.Split(val, ",") {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
@@ -645,3 +645,56 @@ func TestExtractResponseBuffering(t *testing.T) {
 		})
 	}
 }
+
+func TestExtractWeightedBackends(t *testing.T) {
+	type args struct {
+		anns map[string]string
+	}
+	tests := []struct {
+		name string
+		args args
+		want string
+	}{
+		{
+			name: "empty",
+			want: "",
+		},
+		{
+			name: "non-empty",
+			args: args{
+				anns: map[string]string{
+					"konghq.com/weighted-backends": "foo=90,foo-canary=10",
+				},
+			},
+			want: "foo=90,foo-canary=10",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := ExtractWeightedBackends(tt.args.anns)
+			if tt.want == "" {
+				assert.False(t, ok)
+			} else {
+				assert.True(t, ok)
+			}
+			if got != tt.want {
+				t.Errorf("ExtractWeightedBackends() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExtractHashOn(t *testing.T) {
+	anns := map[string]string{
+		"konghq.com/hash-on-header":      "x-user-id",
+		"konghq.com/hash-on-cookie":      "session",
+		"konghq.com/hash-on-cookie-path": "/app",
+	}
+	assert.Equal(t, "x-user-id", ExtractHashOnHeader(anns))
+	assert.Equal(t, "session", ExtractHashOnCookie(anns))
+	assert.Equal(t, "/app", ExtractHashOnCookiePath(anns))
+
+	assert.Empty(t, ExtractHashOnHeader(nil))
+	assert.Empty(t, ExtractHashOnCookie(nil))
+	assert.Empty(t, ExtractHashOnCookiePath(nil))
+}
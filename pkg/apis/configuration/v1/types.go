@@ -101,6 +101,10 @@ type KongPlugin struct {
 	// ConfigFrom references a secret containing the plugin configuration.
 	ConfigFrom ConfigSource `json:"configFrom,omitempty"`
 
+	// ConfigPatches set individual fields of the plugin configuration to
+	// values read from secrets, on top of Config or ConfigFrom.
+	ConfigPatches []ConfigPatch `json:"configPatches,omitempty"`
+
 	// PluginName is the name of the plugin to which to apply the config
 	PluginName string `json:"plugin,omitempty"`
 
@@ -120,6 +124,16 @@ type ConfigSource struct {
 	SecretValue     SecretValueFromSource `json:"secretKeyRef,omitempty"`
 }
 
+// ConfigPatch sets a single field of a plugin configuration to a value read
+// from a secret.
+type ConfigPatch struct {
+	// Path is the JSON Pointer to the field to set, e.g. /redis_password
+	Path string `json:"path"`
+	// ValueFrom is the secret key holding the value of the field. The value
+	// is used as JSON if it parses as such, and as a string otherwise.
+	ValueFrom ConfigSource `json:"valueFrom"`
+}
+
 // NamespacedConfigSource is a wrapper around NamespacedSecretValueFromSource
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
 type NamespacedConfigSource struct {
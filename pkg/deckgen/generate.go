@@ -27,6 +27,7 @@ func ToDeckContent(
 
 	for _, s := range k8sState.Services {
 		service := file.FService{Service: s.Service}
+		service.Tags = withSelectorTags(service.Tags, selectorTags)
 		for _, p := range s.Plugins {
 			plugin := file.FPlugin{
 				Plugin: *p.DeepCopy(),
@@ -35,6 +36,7 @@ func ToDeckContent(
 			if err != nil {
 				log.Errorf("failed to fill-in defaults for plugin: %s", *plugin.Name)
 			}
+			plugin.Tags = withSelectorTags(plugin.Tags, selectorTags)
 			service.Plugins = append(service.Plugins, &plugin)
 			sort.SliceStable(service.Plugins, func(i, j int) bool {
 				return strings.Compare(*service.Plugins[i].Name, *service.Plugins[j].Name) > 0
@@ -44,6 +46,7 @@ func ToDeckContent(
 		for _, r := range s.Routes {
 			route := file.FRoute{Route: r.Route}
 			fillRoute(&route.Route)
+			route.Tags = withSelectorTags(route.Tags, selectorTags)
 
 			for _, p := range r.Plugins {
 				plugin := file.FPlugin{
@@ -53,6 +56,7 @@ func ToDeckContent(
 				if err != nil {
 					log.Errorf("failed to fill-in defaults for plugin: %s", *plugin.Name)
 				}
+				plugin.Tags = withSelectorTags(plugin.Tags, selectorTags)
 				route.Plugins = append(route.Plugins, &plugin)
 				sort.SliceStable(route.Plugins, func(i, j int) bool {
 					return strings.Compare(*route.Plugins[i].Name, *route.Plugins[j].Name) > 0
@@ -77,6 +81,7 @@ func ToDeckContent(
 		if err != nil {
 			log.Errorf("failed to fill-in defaults for plugin: %s", *plugin.Name)
 		}
+		plugin.Tags = withSelectorTags(plugin.Tags, selectorTags)
 		content.Plugins = append(content.Plugins, plugin)
 	}
 	sort.SliceStable(content.Plugins, func(i, j int) bool {
@@ -87,6 +92,7 @@ func ToDeckContent(
 	for _, u := range k8sState.Upstreams {
 		fillUpstream(&u.Upstream)
 		upstream := file.FUpstream{Upstream: u.Upstream}
+		upstream.Tags = withSelectorTags(upstream.Tags, selectorTags)
 		for _, t := range u.Targets {
 			target := file.FTarget{Target: t.Target}
 			upstream.Targets = append(upstream.Targets, &target)
@@ -118,8 +124,11 @@ func ToDeckContent(
 
 	for _, c := range k8sState.Consumers {
 		consumer := file.FConsumer{Consumer: c.Consumer}
+		consumer.Tags = withSelectorTags(consumer.Tags, selectorTags)
 		for _, p := range c.Plugins {
-			consumer.Plugins = append(consumer.Plugins, &file.FPlugin{Plugin: p})
+			plugin := file.FPlugin{Plugin: p}
+			plugin.Tags = withSelectorTags(plugin.Tags, selectorTags)
+			consumer.Plugins = append(consumer.Plugins, &plugin)
 		}
 
 		for _, v := range c.KeyAuths {
@@ -146,7 +155,7 @@ func ToDeckContent(
 		content.Consumers = append(content.Consumers, consumer)
 	}
 	sort.SliceStable(content.Consumers, func(i, j int) bool {
-		return strings.Compare(*content.Consumers[i].Username, *content.Consumers[j].Username) > 0
+		return strings.Compare(consumerString(content.Consumers[i]), consumerString(content.Consumers[j])) > 0
 	})
 	if len(selectorTags) > 0 {
 		content.Info = &file.Info{
@@ -157,6 +166,27 @@ func ToDeckContent(
 	return &content
 }
 
+// withSelectorTags returns the tags set on an entity from the annotations of its object along with
+// selectorTags, the tags marking the entities owned by the controller, which are first. Entities without
+// tags of their own are left as is, as decK sets the selector tags on every entity it syncs to a database.
+func withSelectorTags(tags []*string, selectorTags []string) []*string {
+	if len(tags) == 0 {
+		return tags
+	}
+	merged := kong.StringSlice(selectorTags...)
+	seen := make(map[string]bool, len(selectorTags)+len(tags))
+	for _, tag := range selectorTags {
+		seen[tag] = true
+	}
+	for _, tag := range tags {
+		if tag != nil && !seen[*tag] {
+			seen[*tag] = true
+			merged = append(merged, kong.String(*tag))
+		}
+	}
+	return merged
+}
+
 func fillRoute(route *kong.Route) {
 	if route.HTTPSRedirectStatusCode == nil {
 		route.HTTPSRedirectStatusCode = kong.Int(426)
@@ -204,3 +234,16 @@ func fillPlugin(ctx context.Context, plugin *file.FPlugin, schemas *util.PluginS
 	plugin.RunOn = nil
 	return nil
 }
+
+// consumerString returns a string representation of a FConsumer suitable as a sorting key, which doesn't
+// require its username, as consumers identified by their custom_id only have none.
+func consumerString(consumer file.FConsumer) string {
+	result := ""
+	if consumer.Username != nil {
+		result = *consumer.Username
+	}
+	if consumer.CustomID != nil {
+		result += "/" + *consumer.CustomID
+	}
+	return result
+}
@@ -0,0 +1,116 @@
+package deckgen
+
+import (
+	"context"
+	"testing"
+
+	"github.com/kong/go-kong/kong"
+	"github.com/kong/kubernetes-ingress-controller/pkg/kongstate"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWithSelectorTags(t *testing.T) {
+	selectorTags := []string{"managed-by-ingress-controller"}
+	for _, tt := range []struct {
+		name string
+		tags []*string
+		want []*string
+	}{
+		{
+			name: "no tags",
+			tags: nil,
+			want: nil,
+		},
+		{
+			name: "tags follow the selector tags",
+			tags: kong.StringSlice("team-a", "env-prod"),
+			want: kong.StringSlice("managed-by-ingress-controller", "team-a", "env-prod"),
+		},
+		{
+			name: "selector tags are not repeated",
+			tags: kong.StringSlice("managed-by-ingress-controller", "team-a"),
+			want: kong.StringSlice("managed-by-ingress-controller", "team-a"),
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, withSelectorTags(tt.tags, selectorTags))
+		})
+	}
+}
+
+func TestToDeckContentTags(t *testing.T) {
+	assert := assert.New(t)
+	selectorTags := []string{"managed-by-ingress-controller"}
+	state := &kongstate.KongState{
+		Services: []kongstate.Service{
+			{
+				Service: kong.Service{
+					Name: kong.String("default.foo.80"),
+					Tags: kong.StringSlice("team-a"),
+				},
+				Routes: []kongstate.Route{
+					{
+						Route: kong.Route{
+							Name:  kong.String("default.foo.00"),
+							Paths: kong.StringSlice("/"),
+							Tags:  kong.StringSlice("team-a", "env-prod"),
+						},
+					},
+					{
+						Route: kong.Route{
+							Name:  kong.String("default.foo.01"),
+							Paths: kong.StringSlice("/bar"),
+						},
+					},
+				},
+			},
+		},
+		Upstreams: []kongstate.Upstream{
+			{
+				Upstream: kong.Upstream{
+					Name: kong.String("foo.default.80.svc"),
+					Tags: kong.StringSlice("team-a"),
+				},
+			},
+		},
+		Consumers: []kongstate.Consumer{
+			{
+				Consumer: kong.Consumer{
+					Username: kong.String("alice"),
+					Tags:     kong.StringSlice("team-b"),
+				},
+			},
+		},
+	}
+
+	content := ToDeckContent(context.Background(), logrus.New(), state, nil, selectorTags)
+
+	assert.Len(content.Services, 1)
+	assert.Equal(kong.StringSlice("managed-by-ingress-controller", "team-a"), content.Services[0].Tags)
+	assert.Len(content.Services[0].Routes, 2)
+	assert.Equal(kong.StringSlice("managed-by-ingress-controller", "team-a", "env-prod"),
+		content.Services[0].Routes[1].Tags)
+	assert.Nil(content.Services[0].Routes[0].Tags)
+	assert.Len(content.Upstreams, 1)
+	assert.Equal(kong.StringSlice("managed-by-ingress-controller", "team-a"), content.Upstreams[0].Tags)
+	assert.Len(content.Consumers, 1)
+	assert.Equal(kong.StringSlice("managed-by-ingress-controller", "team-b"), content.Consumers[0].Tags)
+}
+
+func TestToDeckContentConsumersWithoutUsername(t *testing.T) {
+	state := &kongstate.KongState{
+		Consumers: []kongstate.Consumer{
+			{Consumer: kong.Consumer{CustomID: kong.String("bar-id")}},
+			{Consumer: kong.Consumer{Username: kong.String("alice")}},
+			{Consumer: kong.Consumer{CustomID: kong.String("foo-id")}},
+		},
+	}
+
+	content := ToDeckContent(context.Background(), logrus.New(), state, nil, nil)
+
+	assert.Len(t, content.Consumers, 3)
+	assert.Equal(t, "alice", *content.Consumers[0].Username)
+	assert.Equal(t, "foo-id", *content.Consumers[1].CustomID)
+	assert.Equal(t, "bar-id", *content.Consumers[2].CustomID)
+}
@@ -6,6 +6,7 @@ import (
 	"github.com/blang/semver"
 	"github.com/kong/go-kong/kong"
 	configurationv1 "github.com/kong/kubernetes-ingress-controller/pkg/apis/configuration/v1"
+	"github.com/kong/kubernetes-ingress-controller/pkg/util"
 )
 
 var (
@@ -108,7 +109,7 @@ func (c *Consumer) SetCredential(credType string, credConfig interface{}, versio
 		}
 		c.ACLGroups = append(c.ACLGroups, cred)
 	case "mtls-auth":
-		if version.LT(minMTLSCredentialVersion) {
+		if !util.KongVersionAtLeast(version, minMTLSCredentialVersion) {
 			return fmt.Errorf("controller cannot support mtls-auth below version %v", minMTLSCredentialVersion)
 		}
 		cred, err := NewMTLSAuth(credConfig)
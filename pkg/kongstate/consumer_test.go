@@ -74,6 +74,7 @@ func TestConsumer_SetCredential(t *testing.T) {
 	username := "example"
 	standardVersion := semver.MustParse("2.3.2")
 	mtlsUnsupportedVersion := semver.MustParse("1.3.2")
+	enterpriseVersion := semver.MustParse("2.3.2-0-enterprise")
 	type args struct {
 		credType   string
 		consumer   *Consumer
@@ -479,6 +480,34 @@ func TestConsumer_SetCredential(t *testing.T) {
 			result:  &Consumer{Consumer: kong.Consumer{Username: &username}},
 			wantErr: true,
 		},
+		{
+			name: "mtls-auth on an enterprise build of a supported version",
+			args: args{
+				credType:   "mtls-auth",
+				consumer:   &Consumer{Consumer: kong.Consumer{Username: &username}},
+				credConfig: map[string]string{"subject_name": "foo@example.com"},
+				version:    enterpriseVersion,
+			},
+			result: &Consumer{
+				Consumer: kong.Consumer{Username: &username},
+				MTLSAuths: []*MTLSAuth{
+					{kong.MTLSAuth{
+						SubjectName: kong.String("foo@example.com"),
+					}},
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "mtls-auth on an unknown version",
+			args: args{
+				credType:   "mtls-auth",
+				consumer:   &Consumer{Consumer: kong.Consumer{Username: &username}},
+				credConfig: map[string]string{"subject_name": "foo@example.com"},
+			},
+			result:  &Consumer{Consumer: kong.Consumer{Username: &username}},
+			wantErr: true,
+		},
 		{
 			name: "mtls-auth with invalid subject_name type",
 			args: args{
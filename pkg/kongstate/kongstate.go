@@ -1,10 +1,12 @@
 package kongstate
 
 import (
+	"errors"
 	"fmt"
 	"strings"
 
 	"github.com/blang/semver"
+	"github.com/google/uuid"
 	"github.com/kong/go-kong/kong"
 	"github.com/kong/kubernetes-ingress-controller/pkg/annotations"
 	configurationv1 "github.com/kong/kubernetes-ingress-controller/pkg/apis/configuration/v1"
@@ -47,7 +49,15 @@ func (ks *KongState) SanitizedCopy() *KongState {
 	}
 }
 
-func (ks *KongState) FillConsumersAndCredentials(log logrus.FieldLogger, s store.Storer) {
+// customIDConsumerNamespace is the namespace of the IDs of the consumers identified by their custom_id only.
+var customIDConsumerNamespace = uuid.NewSHA1(uuid.NameSpaceDNS, []byte("consumers.konghq.com"))
+
+// FillConsumersAndCredentials adds the KongConsumers and the credentials of their Secrets
+// to the state, reading the type of each credential as util.CredentialType does with credTypeKey.
+func (ks *KongState) FillConsumersAndCredentials(log logrus.FieldLogger, s store.Storer, credTypeKey string) {
+	if credTypeKey == "" {
+		credTypeKey = util.DefaultCredentialTypeKey
+	}
 	consumerIndex := make(map[string]Consumer)
 
 	// build consumer index
@@ -62,12 +72,19 @@ func (ks *KongState) FillConsumersAndCredentials(log logrus.FieldLogger, s store
 		if kConsumer.CustomID != "" {
 			c.CustomID = kong.String(kConsumer.CustomID)
 		}
+		if c.Username == nil {
+			// Kong and decK look consumers up by username or ID only: the ID of a consumer without
+			// username is derived from its custom_id, so that its plugins can reference it and it
+			// keeps the same ID across syncs
+			c.ID = kong.String(uuid.NewSHA1(customIDConsumerNamespace, []byte(kConsumer.CustomID)).String())
+		}
 		c.K8sKongConsumer = *kConsumer
 
 		log = log.WithFields(logrus.Fields{
 			"kongconsumer_name":      kConsumer.Name,
 			"kongconsumer_namespace": kConsumer.Namespace,
 		})
+		c.Tags = tagsFromAnnotations(log, "KongConsumer", kConsumer.Annotations)
 		for _, cred := range kConsumer.Credentials {
 			log = log.WithFields(logrus.Fields{
 				"secret_name":      cred,
@@ -76,10 +93,25 @@ func (ks *KongState) FillConsumersAndCredentials(log logrus.FieldLogger, s store
 			secret, err := s.GetSecret(kConsumer.Namespace, cred)
 			if err != nil {
 				log.Errorf("failed to fetch secret: %v", err)
+				ObserveTranslationFailure("KongConsumer", TranslationFailureMissingReference)
+				continue
+			}
+			credType, _, err := util.CredentialType(secret, credTypeKey)
+			if err != nil {
+				log.Errorf("failed to provision credential: %v", err)
+				ObserveTranslationFailure("KongConsumer", TranslationFailureInvalidCredential)
+				continue
+			}
+			if !supportedCreds.Has(credType) {
+				log.Errorf("failed to provision credential: invalid credType: %v", credType)
+				ObserveTranslationFailure("KongConsumer", TranslationFailureInvalidCredential)
 				continue
 			}
 			credConfig := map[string]interface{}{}
 			for k, v := range secret.Data {
+				if k == credTypeKey {
+					continue
+				}
 				// TODO populate these based on schema from Kong
 				// and remove this workaround
 				if k == "redirect_uris" {
@@ -88,21 +120,15 @@ func (ks *KongState) FillConsumersAndCredentials(log logrus.FieldLogger, s store
 				}
 				credConfig[k] = string(v)
 			}
-			credType, ok := credConfig["kongCredType"].(string)
-			if !ok {
-				log.Errorf("failed to provision credential: invalid credType: %v", credType)
-			}
-			if !supportedCreds.Has(credType) {
-				log.Errorf("failed to provision credential: invalid credType: %v", credType)
-				continue
-			}
-			if len(credConfig) <= 1 { // 1 key of credType itself
+			if len(credConfig) == 0 {
 				log.Errorf("failed to provision credential: empty secret")
+				ObserveTranslationFailure("KongConsumer", TranslationFailureInvalidCredential)
 				continue
 			}
 			err = c.SetCredential(credType, credConfig, ks.Version)
 			if err != nil {
 				log.Errorf("failed to provision credential: %v", err)
+				ObserveTranslationFailure("KongConsumer", TranslationFailureInvalidCredential)
 				continue
 			}
 		}
@@ -120,14 +146,15 @@ func (ks *KongState) FillOverrides(log logrus.FieldLogger, s store.Storer) {
 	for i := 0; i < len(ks.Services); i++ {
 		// Services
 		anns := ks.Services[i].K8sService.Annotations
-		kongIngress, err := getKongIngressForService(s, ks.Services[i].K8sService)
+		kongIngress, err := getKongIngressForKongService(s, ks.Services[i])
 		if err != nil {
 			log.WithFields(logrus.Fields{
 				"service_name":      ks.Services[i].K8sService.Name,
 				"service_namespace": ks.Services[i].K8sService.Namespace,
 			}).Errorf("failed to fetch KongIngress resource for Service: %v", err)
+			ObserveTranslationFailure("Service", TranslationFailureMissingReference)
 		}
-		ks.Services[i].override(kongIngress, anns)
+		ks.Services[i].override(log, kongIngress, anns)
 
 		// Routes
 		for j := 0; j < len(ks.Services[i].Routes); j++ {
@@ -139,25 +166,40 @@ func (ks *KongState) FillOverrides(log logrus.FieldLogger, s store.Storer) {
 					"resource_name":      ks.Services[i].Routes[j].Ingress.Name,
 					"resource_namespace": ks.Services[i].Routes[j].Ingress.Namespace,
 				}).Errorf("failed to fetch KongIngress resource: %v", err)
+				ObserveTranslationFailure(ks.Services[i].Routes[j].Ingress.Kind, TranslationFailureMissingReference)
 			}
 
 			ks.Services[i].Routes[j].override(log, kongIngress)
+			if ks.Services[i].Protocol != nil {
+				ks.Services[i].Routes[j].useServiceProtocol(log, *ks.Services[i].Protocol, kongIngress)
+			}
 		}
 	}
 
 	// Upstreams
 	for i := 0; i < len(ks.Upstreams); i++ {
-		kongIngress, err := getKongIngressForService(s,
-			ks.Upstreams[i].Service.K8sService)
+		kongIngress, err := getKongIngressForKongService(s, ks.Upstreams[i].Service)
 		anns := ks.Upstreams[i].Service.K8sService.Annotations
 		if err != nil {
 			log.WithFields(logrus.Fields{
 				"service_name":      ks.Upstreams[i].Service.K8sService.Name,
 				"service_namespace": ks.Upstreams[i].Service.K8sService.Namespace,
 			}).Errorf("failed to fetch KongIngress resource for Service: %v", err)
+			ObserveTranslationFailure("Service", TranslationFailureMissingReference)
 			continue
 		}
-		ks.Upstreams[i].override(kongIngress, anns)
+		if ks.Upstreams[i].Service.isUDP() && kongIngress != nil && kongIngress.Upstream != nil {
+			if err := validateStreamHealthchecks(kongIngress.Upstream.Healthchecks); err != nil {
+				log.WithFields(logrus.Fields{
+					"kongingress_name":      kongIngress.Name,
+					"kongingress_namespace": kongIngress.Namespace,
+				}).Errorf("ignoring upstream settings of KongIngress for UDP service %s: %v",
+					*ks.Upstreams[i].Service.Name, err)
+				ObserveTranslationFailure("KongIngress", TranslationFailureInvalidKongIngress)
+				kongIngress = nil
+			}
+		}
+		ks.Upstreams[i].override(log, kongIngress, anns)
 	}
 }
 
@@ -212,9 +254,15 @@ func (ks *KongState) getPluginRelations() map[string]util.ForeignRelations {
 	}
 	// consumer
 	for _, c := range ks.Consumers {
+		// plugins reference consumers by username, or by the ID of the consumers
+		// identified by their custom_id only
+		identifier := c.Username
+		if identifier == nil {
+			identifier = c.ID
+		}
 		pluginList := annotations.ExtractKongPluginsFromAnnotations(c.K8sKongConsumer.GetAnnotations())
 		for _, pluginName := range pluginList {
-			addConsumerRelation(c.K8sKongConsumer.Namespace, pluginName, *c.Username)
+			addConsumerRelation(c.K8sKongConsumer.Namespace, pluginName, *identifier)
 		}
 	}
 	return pluginRels
@@ -226,12 +274,18 @@ func buildPlugins(log logrus.FieldLogger, s store.Storer, pluginRels map[string]
 	for pluginIdentifier, relations := range pluginRels {
 		identifier := strings.Split(pluginIdentifier, ":")
 		namespace, kongPluginName := identifier[0], identifier[1]
-		plugin, err := getPlugin(s, namespace, kongPluginName)
+		pluginLog := log.WithFields(logrus.Fields{
+			"kongplugin_name":      kongPluginName,
+			"kongplugin_namespace": namespace,
+		})
+		plugin, err := getPlugin(pluginLog, s, namespace, kongPluginName)
 		if err != nil {
-			log.WithFields(logrus.Fields{
-				"kongplugin_name":      kongPluginName,
-				"kongplugin_namespace": namespace,
-			}).Errorf("failed to fetch KongPlugin: %v", err)
+			pluginLog.Errorf("failed to fetch KongPlugin: %v", err)
+			if errors.Is(err, errPluginNotFound) {
+				ObserveTranslationFailure("KongPlugin", TranslationFailureMissingReference)
+			} else {
+				ObserveTranslationFailure("KongPlugin", TranslationFailureInvalidPluginConfig)
+			}
 			continue
 		}
 
@@ -293,16 +347,21 @@ func globalPlugins(log logrus.FieldLogger, s store.Storer) ([]Plugin, error) {
 			log.WithFields(logrus.Fields{
 				"kongclusterplugin_name": k8sPlugin.Name,
 			}).Errorf("invalid KongClusterPlugin: empty plugin property")
+			ObserveTranslationFailure("KongClusterPlugin", TranslationFailureInvalidPluginConfig)
 			continue
 		}
 		if _, ok := res[pluginName]; ok {
 			log.Error("multiple KongPlugin definitions found with"+
 				" 'global' label for '", pluginName,
 				"', the plugin will not be applied")
+			ObserveTranslationFailure("KongClusterPlugin", TranslationFailureConflict)
 			duplicates = append(duplicates, pluginName)
 			continue
 		}
-		if plugin, err := kongPluginFromK8SClusterPlugin(s, k8sPlugin); err == nil {
+		pluginLog := log.WithFields(logrus.Fields{
+			"kongclusterplugin_name": k8sPlugin.Name,
+		})
+		if plugin, err := kongPluginFromK8SClusterPlugin(pluginLog, s, k8sPlugin); err == nil {
 			res[pluginName] = Plugin{
 				Plugin: plugin,
 			}
@@ -310,6 +369,7 @@ func globalPlugins(log logrus.FieldLogger, s store.Storer) ([]Plugin, error) {
 			log.WithFields(logrus.Fields{
 				"kongclusterplugin_name": k8sPlugin.Name,
 			}).Errorf("failed to generate configuration from KongClusterPlugin: %v ", err)
+			ObserveTranslationFailure("KongClusterPlugin", TranslationFailureInvalidPluginConfig)
 		}
 	}
 	for _, plugin := range duplicates {
@@ -332,5 +392,6 @@ var supportedCreds = sets.NewString(
 	"hmac-auth",
 	"jwt",
 	"key-auth",
+	"mtls-auth",
 	"oauth2",
 )
@@ -12,6 +12,7 @@ import (
 	"github.com/kong/kubernetes-ingress-controller/pkg/util"
 	"github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
@@ -66,6 +67,32 @@ func Test_getPluginRelations(t *testing.T) {
 			name: "empty state",
 			want: map[string]util.ForeignRelations{},
 		},
+		{
+			name: "consumer identified by custom_id only",
+			args: args{
+				state: KongState{
+					Consumers: []Consumer{
+						{
+							Consumer: kong.Consumer{
+								ID:       kong.String("1a2f4f5c-3a35-5d3b-9f2b-38c6e5b5a6f1"),
+								CustomID: kong.String("foo-id"),
+							},
+							K8sKongConsumer: configurationv1.KongConsumer{
+								ObjectMeta: metav1.ObjectMeta{
+									Namespace: "ns1",
+									Annotations: map[string]string{
+										annotations.AnnotationPrefix + annotations.PluginsKey: "foo",
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+			want: map[string]util.ForeignRelations{
+				"ns1:foo": {Consumer: []string{"1a2f4f5c-3a35-5d3b-9f2b-38c6e5b5a6f1"}},
+			},
+		},
 		{
 			name: "single consumer annotation",
 			args: args{
@@ -351,9 +378,159 @@ func Test_FillConsumersAndCredentials(t *testing.T) {
 		state := KongState{
 			Version: semver.MustParse("2.3.2"),
 		}
-		state.FillConsumersAndCredentials(logrus.New(), store)
+		state.FillConsumersAndCredentials(logrus.New(), store, "")
 		assert.Equal(t, want.Consumers[0].Consumer.Username, state.Consumers[0].Consumer.Username)
 		assert.Equal(t, want.Consumers[0].Consumer.CustomID, state.Consumers[0].Consumer.CustomID)
 		assert.Equal(t, want.Consumers[0].KeyAuths[0].Key, state.Consumers[0].KeyAuths[0].Key)
 	})
 }
+
+func Test_FillConsumersAndCredentialsMTLSAuth(t *testing.T) {
+	store, _ := store.NewFakeStore(store.FakeObjects{
+		Secrets: []*corev1.Secret{{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "foo-mtls",
+				Namespace: "default",
+			},
+			Data: map[string][]byte{
+				"kongCredType": []byte("mtls-auth"),
+				"subject_name": []byte("foo@example.com"),
+			},
+		}},
+		KongConsumers: []*configurationv1.KongConsumer{{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "foo",
+				Namespace: "default",
+				Annotations: map[string]string{
+					"kubernetes.io/ingress.class": annotations.DefaultIngressClass,
+				},
+			},
+			Username:    "foo",
+			Credentials: []string{"foo-mtls"},
+		}},
+	})
+
+	for _, tt := range []struct {
+		name    string
+		version semver.Version
+		want    []*MTLSAuth
+	}{
+		{
+			name:    "supported version",
+			version: semver.MustParse("2.3.2"),
+			want:    []*MTLSAuth{{kong.MTLSAuth{SubjectName: kong.String("foo@example.com")}}},
+		},
+		{
+			name:    "enterprise build of a supported version",
+			version: semver.MustParse("2.3.2-0-enterprise"),
+			want:    []*MTLSAuth{{kong.MTLSAuth{SubjectName: kong.String("foo@example.com")}}},
+		},
+		{
+			name:    "unsupported version",
+			version: semver.MustParse("2.2.0"),
+		},
+		{
+			name: "unknown version",
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			state := KongState{Version: tt.version}
+			state.FillConsumersAndCredentials(logrus.New(), store, "")
+			require.Len(t, state.Consumers, 1, "the consumer is kept when its credential is skipped")
+			assert.Equal(t, tt.want, state.Consumers[0].MTLSAuths)
+		})
+	}
+}
+
+func Test_FillConsumersAndCredentialsWithoutUsername(t *testing.T) {
+	store, _ := store.NewFakeStore(store.FakeObjects{
+		KongConsumers: []*configurationv1.KongConsumer{{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "bar",
+				Namespace: "default",
+				Annotations: map[string]string{
+					"kubernetes.io/ingress.class": annotations.DefaultIngressClass,
+				},
+			},
+			CustomID: "bar-id",
+		}},
+	})
+
+	var state, again KongState
+	state.FillConsumersAndCredentials(logrus.New(), store, "")
+	again.FillConsumersAndCredentials(logrus.New(), store, "")
+	require.Len(t, state.Consumers, 1)
+	assert.Nil(t, state.Consumers[0].Username)
+	require.NotNil(t, state.Consumers[0].ID, "consumers without username are identified by an ID")
+	assert.Equal(t, state.Consumers[0].ID, again.Consumers[0].ID, "the ID is stable across syncs")
+
+	source, ok := state.EntitySource("consumers", *state.Consumers[0].ID)
+	assert.True(t, ok)
+	assert.Equal(t, "bar", source.Name)
+}
+
+func Test_FillConsumersAndCredentialsTypedByLabel(t *testing.T) {
+	secrets := []*corev1.Secret{
+		{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "labeled",
+				Namespace: "default",
+				Labels:    map[string]string{"konghq.com/credential": "key-auth"},
+			},
+			Data: map[string][]byte{
+				"key": []byte("by-label"),
+			},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "custom-key",
+				Namespace: "default",
+			},
+			Data: map[string][]byte{
+				"type": []byte("key-auth"),
+				"key":  []byte("by-custom-key"),
+			},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "conflicting",
+				Namespace: "default",
+				Labels:    map[string]string{"konghq.com/credential": "key-auth"},
+			},
+			Data: map[string][]byte{
+				"type":     []byte("basic-auth"),
+				"username": []byte("conflicting"),
+				"password": []byte("conflicting"),
+			},
+		},
+	}
+	consumers := []*configurationv1.KongConsumer{
+		{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "foo",
+				Namespace: "default",
+				Annotations: map[string]string{
+					"kubernetes.io/ingress.class": annotations.DefaultIngressClass,
+				},
+			},
+			Username:    "foo",
+			Credentials: []string{"labeled", "custom-key", "conflicting"},
+		},
+	}
+	store, _ := store.NewFakeStore(store.FakeObjects{
+		Secrets:       secrets,
+		KongConsumers: consumers,
+	})
+
+	state := KongState{
+		Version: semver.MustParse("2.3.2"),
+	}
+	state.FillConsumersAndCredentials(logrus.New(), store, "type")
+	assert.Len(t, state.Consumers, 1)
+	var keys []string
+	for _, keyAuth := range state.Consumers[0].KeyAuths {
+		keys = append(keys, *keyAuth.Key)
+	}
+	assert.Equal(t, []string{"by-label", "by-custom-key"}, keys)
+	assert.Empty(t, state.Consumers[0].BasicAuths, "credentials with conflicting types must be skipped")
+}
@@ -0,0 +1,64 @@
+package kongstate
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	metricsNamespace = "kong_ingress_controller"
+	metricsSubsystem = "translation"
+
+	kindLabel   = "kind"
+	reasonLabel = "reason"
+
+	// unknownKind is the kind label of the failures of objects whose kind isn't recorded.
+	unknownKind = "unknown"
+)
+
+// TranslationFailureReason is why an object, or part of it, could not be translated into the configuration
+// of Kong. The reasons are a fixed set, so that they can label metrics.
+type TranslationFailureReason string
+
+const (
+	// TranslationFailureInvalidAnnotation is the reason of an annotation whose value is invalid.
+	TranslationFailureInvalidAnnotation TranslationFailureReason = "invalid_annotation"
+	// TranslationFailureInvalidPluginConfig is the reason of a KongPlugin or KongClusterPlugin which is invalid
+	// or whose configuration could not be generated.
+	TranslationFailureInvalidPluginConfig TranslationFailureReason = "invalid_plugin_config"
+	// TranslationFailureInvalidCredential is the reason of a credential of a KongConsumer which could not be
+	// provisioned.
+	TranslationFailureInvalidCredential TranslationFailureReason = "invalid_credential"
+	// TranslationFailureInvalidCertificate is the reason of a Secret which does not hold a valid certificate.
+	TranslationFailureInvalidCertificate TranslationFailureReason = "invalid_certificate"
+	// TranslationFailureInvalidRule is the reason of a rule of an Ingress-like object which is invalid.
+	TranslationFailureInvalidRule TranslationFailureReason = "invalid_rule"
+	// TranslationFailureInvalidKongIngress is the reason of the settings of a KongIngress which are invalid
+	// for the object they apply to.
+	TranslationFailureInvalidKongIngress TranslationFailureReason = "invalid_kongingress"
+	// TranslationFailureMissingReference is the reason of an object referencing another one which does not
+	// exist, e.g. a Secret, a KongPlugin or a backend Service.
+	TranslationFailureMissingReference TranslationFailureReason = "missing_reference"
+	// TranslationFailureConflict is the reason of an object conflicting with another one.
+	TranslationFailureConflict TranslationFailureReason = "conflict"
+)
+
+var translationFailures = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Namespace: metricsNamespace,
+	Subsystem: metricsSubsystem,
+	Name:      "failures_total",
+	Help:      "Number of objects, or parts of them, which could not be translated into the configuration of Kong.",
+}, []string{kindLabel, reasonLabel})
+
+// RegisterMetrics registers the collectors of the translation metrics with registerer.
+func RegisterMetrics(registerer prometheus.Registerer) error {
+	return registerer.Register(translationFailures)
+}
+
+// ObserveTranslationFailure records that an object of kind, or part of it, could not be translated for reason.
+// The failures are counted every time a configuration is built, as they are logged.
+func ObserveTranslationFailure(kind string, reason TranslationFailureReason) {
+	if kind == "" {
+		kind = unknownKind
+	}
+	translationFailures.WithLabelValues(kind, string(reason)).Inc()
+}
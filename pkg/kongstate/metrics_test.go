@@ -0,0 +1,83 @@
+package kongstate
+
+import (
+	"testing"
+
+	"github.com/blang/semver"
+	"github.com/kong/kubernetes-ingress-controller/pkg/annotations"
+	configurationv1 "github.com/kong/kubernetes-ingress-controller/pkg/apis/configuration/v1"
+	"github.com/kong/kubernetes-ingress-controller/pkg/store"
+	"github.com/kong/kubernetes-ingress-controller/pkg/util"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func translationFailureCount(kind string, reason TranslationFailureReason) float64 {
+	return testutil.ToFloat64(translationFailures.WithLabelValues(kind, string(reason)))
+}
+
+func TestTranslationFailureMetrics(t *testing.T) {
+	assert.NoError(t, RegisterMetrics(prometheus.NewRegistry()))
+
+	s, err := store.NewFakeStore(store.FakeObjects{
+		Secrets: []*corev1.Secret{{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "bad-cred"},
+			Data:       map[string][]byte{"kongCredType": []byte("not-a-credential"), "key": []byte("k")},
+		}},
+		KongConsumers: []*configurationv1.KongConsumer{{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace:   "default",
+				Name:        "foo",
+				Annotations: map[string]string{annotations.IngressClassKey: annotations.DefaultIngressClass},
+			},
+			Username:    "foo",
+			Credentials: []string{"missing-cred", "bad-cred"},
+		}},
+	})
+	assert.NoError(t, err)
+
+	t.Run("consumer credentials", func(t *testing.T) {
+		missing := translationFailureCount("KongConsumer", TranslationFailureMissingReference)
+		invalid := translationFailureCount("KongConsumer", TranslationFailureInvalidCredential)
+
+		state := KongState{Version: semver.MustParse("2.3.2")}
+		state.FillConsumersAndCredentials(logrus.New(), s, "")
+
+		assert.Equal(t, missing+1, translationFailureCount("KongConsumer", TranslationFailureMissingReference))
+		assert.Equal(t, invalid+1, translationFailureCount("KongConsumer", TranslationFailureInvalidCredential))
+	})
+
+	t.Run("missing plugin", func(t *testing.T) {
+		missing := translationFailureCount("KongPlugin", TranslationFailureMissingReference)
+
+		buildPlugins(logrus.New(), s, map[string]util.ForeignRelations{
+			"default:missing": {Service: []string{"default.foo.80"}},
+		})
+
+		assert.Equal(t, missing+1, translationFailureCount("KongPlugin", TranslationFailureMissingReference))
+	})
+
+	t.Run("route annotation", func(t *testing.T) {
+		invalid := translationFailureCount("Ingress", TranslationFailureInvalidAnnotation)
+
+		route := Route{Ingress: util.K8sObjectInfo{Kind: "Ingress"}}
+		route.overrideMethods(logrus.New(), map[string]string{
+			annotations.AnnotationPrefix + annotations.MethodsKey: "GET,G3T",
+		})
+
+		assert.Nil(t, route.Methods)
+		assert.Equal(t, invalid+1, translationFailureCount("Ingress", TranslationFailureInvalidAnnotation))
+	})
+
+	t.Run("unknown kind", func(t *testing.T) {
+		invalid := translationFailureCount(unknownKind, TranslationFailureInvalidAnnotation)
+
+		ObserveTranslationFailure("", TranslationFailureInvalidAnnotation)
+
+		assert.Equal(t, invalid+1, translationFailureCount(unknownKind, TranslationFailureInvalidAnnotation))
+	})
+}
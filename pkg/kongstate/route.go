@@ -27,6 +27,15 @@ var validMethods = regexp.MustCompile(`\A[A-Z]+$`)
 // TODO if the Kong core adds support for wildcard SNI route match criteria, this should change
 var validSNIs = regexp.MustCompile(`^([a-zA-Z0-9]+(-[a-zA-Z0-9]+)*)+(\.([a-zA-Z0-9]+(-[a-zA-Z0-9]+)*))*$`)
 
+// plainPath matches the paths the router of Kong treats as plain prefixes rather than regular expressions.
+var plainPath = regexp.MustCompile(`^[a-zA-Z0-9.\-_~/%]*$`)
+
+// IsRegexPath tells whether the router of Kong compiles path as a regular expression, which is the case
+// of any path containing a character not allowed in a plain URI path.
+func IsRegexPath(path string) bool {
+	return !plainPath.MatchString(path)
+}
+
 // normalizeProtocols prevents users from mismatching grpc/http
 func (r *Route) normalizeProtocols() {
 	protocols := r.Protocols
@@ -181,6 +190,7 @@ func (r *Route) overrideMethods(log logrus.FieldLogger, anns map[string]string)
 			// if any method is invalid (not an uppercase alpha string),
 			// discard everything
 			log.WithField("kongroute", r.Name).Errorf("invalid method: %v", method)
+			ObserveTranslationFailure(r.Ingress.Kind, TranslationFailureInvalidAnnotation)
 			return
 		}
 	}
@@ -205,6 +215,7 @@ func (r *Route) overrideSNIs(log logrus.FieldLogger, anns map[string]string) {
 		} else {
 			// SNI is not a valid hostname
 			log.WithField("kongroute", r.Name).Errorf("invalid SNI: %v", sni)
+			ObserveTranslationFailure(r.Ingress.Kind, TranslationFailureInvalidAnnotation)
 			return
 		}
 	}
@@ -223,6 +234,7 @@ func (r *Route) overrideByAnnotation(log logrus.FieldLogger) {
 	r.overrideSNIs(log, r.Ingress.Annotations)
 	r.overrideRequestBuffering(log, r.Ingress.Annotations)
 	r.overrideResponseBuffering(log, r.Ingress.Annotations)
+	r.Tags = tagsFromAnnotations(log, r.Ingress.Kind, r.Ingress.Annotations)
 }
 
 // override sets Route fields by KongIngress first, then by annotation
@@ -242,6 +254,54 @@ func (r *Route) override(log logrus.FieldLogger, kongIngress *configurationv1.Ko
 	}
 }
 
+// useServiceProtocol makes the route of a grpc or grpcs service accept gRPC requests: unless its protocols
+// were set by the konghq.com/protocols annotation or a KongIngress, which may pair HTTP routes with a gRPC
+// service on purpose, e.g. for the grpc-web plugin, its default HTTP protocols are replaced by their gRPC
+// counterparts. As gRPC routes don't accept strip_path, a strip path set by the annotation or the
+// KongIngress is ignored with a warning.
+func (r *Route) useServiceProtocol(log logrus.FieldLogger, serviceProtocol string,
+	kongIngress *configurationv1.KongIngress) {
+	if r == nil || (serviceProtocol != "grpc" && serviceProtocol != "grpcs") {
+		return
+	}
+	var ir *kong.Route
+	if kongIngress != nil {
+		ir = kongIngress.Route
+	}
+
+	explicit := r.Ingress.Annotations[annotations.AnnotationPrefix+annotations.ProtocolsKey] != "" ||
+		(ir != nil && len(ir.Protocols) != 0)
+	if !explicit {
+		var prots []*string
+		for _, val := range r.Protocols {
+			switch *val {
+			case "http":
+				prots = append(prots, kong.String("grpc"))
+			case "https":
+				prots = append(prots, kong.String("grpcs"))
+			default:
+				prots = append(prots, val)
+			}
+		}
+		r.Protocols = prots
+	}
+
+	for _, val := range r.Protocols {
+		if *val != "grpc" && *val != "grpcs" {
+			continue
+		}
+		if strings.EqualFold(annotations.ExtractStripPath(r.Ingress.Annotations), "true") ||
+			(ir != nil && ir.StripPath != nil && *ir.StripPath) {
+			log.WithFields(logrus.Fields{
+				"ingress_namespace": r.Ingress.Namespace,
+				"ingress_name":      r.Ingress.Name,
+			}).Warnf("ignoring strip_path on route to %s service: gRPC routes don't support it", serviceProtocol)
+		}
+		r.StripPath = nil
+		return
+	}
+}
+
 // overrideByKongIngress sets Route fields by KongIngress
 func (r *Route) overrideByKongIngress(log logrus.FieldLogger, kongIngress *configurationv1.KongIngress) {
 	if kongIngress == nil || kongIngress.Route == nil {
@@ -263,6 +323,7 @@ func (r *Route) overrideByKongIngress(log logrus.FieldLogger, kongIngress *confi
 					"ingress_namespace": r.Ingress.Namespace,
 					"ingress_name":      r.Ingress.Name,
 				}).Errorf("ingress contains invalid method: '%v'", *method)
+				ObserveTranslationFailure("KongIngress", TranslationFailureInvalidKongIngress)
 				invalid = true
 			}
 		}
@@ -300,6 +361,7 @@ func (r *Route) overrideByKongIngress(log logrus.FieldLogger, kongIngress *confi
 			} else {
 				// SNI is not a valid hostname
 				log.WithField("kongroute", ir.Name).Errorf("invalid SNI: %v", unsanitizedSNI)
+				ObserveTranslationFailure("KongIngress", TranslationFailureInvalidKongIngress)
 				return
 			}
 		}
@@ -325,6 +387,7 @@ func (r *Route) overrideRequestBuffering(log logrus.FieldLogger, anns map[string
 	if err != nil {
 		// the value provided is not a parseable boolean, quit
 		log.WithField("kongroute", r.Name).Errorf("invalid request_buffering value: %s", err)
+		ObserveTranslationFailure(r.Ingress.Kind, TranslationFailureInvalidAnnotation)
 		return
 	}
 
@@ -343,6 +406,7 @@ func (r *Route) overrideResponseBuffering(log logrus.FieldLogger, anns map[strin
 	if err != nil {
 		// the value provided is not a parseable boolean, quit
 		log.WithField("kongroute", r.Name).Errorf("invalid response_buffering value: %s", err)
+		ObserveTranslationFailure(r.Ingress.Kind, TranslationFailureInvalidAnnotation)
 		return
 	}
 
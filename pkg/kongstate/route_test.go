@@ -305,6 +305,20 @@ func TestOverrideRouteByAnnotation(t *testing.T) {
 	})
 }
 
+func TestIsRegexPath(t *testing.T) {
+	for path, want := range map[string]bool{
+		"/":                false,
+		"/foo/bar-baz_1.2": false,
+		"/~user/%20":       false,
+		"/foo$":            true,
+		"/foo/[0-9]+":      true,
+		"/foo(":            true,
+		"/foo/.*":          true,
+	} {
+		assert.Equal(t, want, IsRegexPath(path), path)
+	}
+}
+
 func TestNormalizeProtocols(t *testing.T) {
 	assert := assert.New(t)
 	testTable := []struct {
@@ -356,7 +370,66 @@ func TestNormalizeProtocols(t *testing.T) {
 
 	assert.NotPanics(func() {
 		var nilUpstream *Upstream
-		nilUpstream.override(nil, make(map[string]string))
+		nilUpstream.override(logrus.New(), nil, make(map[string]string))
+	})
+}
+
+func TestUseServiceProtocol(t *testing.T) {
+	tests := []struct {
+		name            string
+		serviceProtocol string
+		protocols       []*string
+		anns            map[string]string
+		kongIngress     *configurationv1.KongIngress
+		wantProtocols   []*string
+		wantStripPath   *bool
+	}{
+		{
+			name:            "routes of http services are left alone",
+			serviceProtocol: "http",
+			protocols:       kong.StringSlice("http", "https"),
+			wantProtocols:   kong.StringSlice("http", "https"),
+			wantStripPath:   kong.Bool(true),
+		},
+		{
+			name:            "default protocols of routes of grpc services become grpc",
+			serviceProtocol: "grpc",
+			protocols:       kong.StringSlice("http", "https"),
+			wantProtocols:   kong.StringSlice("grpc", "grpcs"),
+		},
+		{
+			name:            "protocols set by annotation are kept",
+			serviceProtocol: "grpcs",
+			protocols:       kong.StringSlice("http"),
+			anns:            map[string]string{"konghq.com/protocols": "http"},
+			wantProtocols:   kong.StringSlice("http"),
+			wantStripPath:   kong.Bool(true),
+		},
+		{
+			name:            "protocols set by KongIngress are kept",
+			serviceProtocol: "grpc",
+			protocols:       kong.StringSlice("grpcs"),
+			kongIngress: &configurationv1.KongIngress{
+				Route: &kong.Route{Protocols: kong.StringSlice("grpcs"), StripPath: kong.Bool(true)},
+			},
+			wantProtocols: kong.StringSlice("grpcs"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			route := Route{
+				Route:   kong.Route{Protocols: tt.protocols, StripPath: kong.Bool(true)},
+				Ingress: util.K8sObjectInfo{Annotations: tt.anns},
+			}
+			route.useServiceProtocol(logrus.New(), tt.serviceProtocol, tt.kongIngress)
+			assert.Equal(t, tt.wantProtocols, route.Protocols)
+			assert.Equal(t, tt.wantStripPath, route.StripPath)
+		})
+	}
+
+	assert.NotPanics(t, func() {
+		var nilRoute *Route
+		nilRoute.useServiceProtocol(logrus.New(), "grpc", nil)
 	})
 }
 
@@ -7,6 +7,7 @@ import (
 	"github.com/kong/kubernetes-ingress-controller/pkg/annotations"
 	configurationv1 "github.com/kong/kubernetes-ingress-controller/pkg/apis/configuration/v1"
 	"github.com/kong/kubernetes-ingress-controller/pkg/util"
+	"github.com/sirupsen/logrus"
 	corev1 "k8s.io/api/core/v1"
 )
 
@@ -21,6 +22,11 @@ type Service struct {
 	K8sService corev1.Service
 }
 
+// isUDP tells whether s is the service of a UDPIngress, which is not backed by a Kubernetes Service.
+func (s *Service) isUDP() bool {
+	return s.Protocol != nil && *s.Protocol == "udp"
+}
+
 // overrideByKongIngress sets Service fields by KongIngress
 func (s *Service) overrideByKongIngress(kongIngress *configurationv1.KongIngress) {
 	if kongIngress == nil || kongIngress.Proxy == nil {
@@ -75,23 +81,24 @@ func (s *Service) overrideProtocol(anns map[string]string) {
 
 // overrideByAnnotation modifies the Kong service based on annotations
 // on the Kubernetes service.
-func (s *Service) overrideByAnnotation(anns map[string]string) {
+func (s *Service) overrideByAnnotation(log logrus.FieldLogger, anns map[string]string) {
 	if s == nil {
 		return
 	}
 	s.overrideProtocol(anns)
 	s.overridePath(anns)
+	s.Tags = tagsFromAnnotations(log, "Service", anns)
 }
 
 // override sets Service fields by KongIngress first, then by annotation
-func (s *Service) override(kongIngress *configurationv1.KongIngress,
+func (s *Service) override(log logrus.FieldLogger, kongIngress *configurationv1.KongIngress,
 	anns map[string]string) {
 	if s == nil {
 		return
 	}
 
 	s.overrideByKongIngress(kongIngress)
-	s.overrideByAnnotation(anns)
+	s.overrideByAnnotation(log, anns)
 
 	if *s.Protocol == "grpc" || *s.Protocol == "grpcs" {
 		// grpc(s) doesn't accept a path
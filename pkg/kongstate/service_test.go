@@ -6,6 +6,7 @@ import (
 
 	"github.com/kong/go-kong/kong"
 	configurationv1 "github.com/kong/kubernetes-ingress-controller/pkg/apis/configuration/v1"
+	"github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -360,13 +361,13 @@ func TestOverrideService(t *testing.T) {
 	}
 
 	for _, testcase := range testTable {
-		testcase.inService.override(&testcase.inKongIngresss, testcase.inAnnotation)
+		testcase.inService.override(logrus.New(), &testcase.inKongIngresss, testcase.inAnnotation)
 		assert.Equal(testcase.inService, testcase.outService)
 	}
 
 	assert.NotPanics(func() {
 		var nilService *Service
-		nilService.override(nil, nil)
+		nilService.override(logrus.New(), nil, nil)
 	})
 }
 
@@ -0,0 +1,66 @@
+package kongstate
+
+import (
+	"github.com/kong/kubernetes-ingress-controller/pkg/util"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// EntitySource is the Kubernetes object a Kong entity was generated from.
+type EntitySource struct {
+	util.K8sObjectInfo
+	// Kind is the kind of the object, empty for the objects of routes whose
+	// kind isn't recorded.
+	Kind string
+	// Object is the object itself, nil for the objects of routes.
+	Object metav1.Object
+}
+
+// EntitySource returns the Kubernetes object the Kong entity with the given
+// name was generated from. entityType is the collection of the entity in the
+// configuration of Kong: services, routes, upstreams or consumers.
+func (ks *KongState) EntitySource(entityType, name string) (EntitySource, bool) {
+	switch entityType {
+	case "services":
+		for _, service := range ks.Services {
+			if service.Name != nil && *service.Name == name {
+				return serviceSource(service), true
+			}
+		}
+	case "routes":
+		for _, service := range ks.Services {
+			for _, route := range service.Routes {
+				if route.Name != nil && *route.Name == name {
+					return EntitySource{K8sObjectInfo: route.Ingress, Kind: route.Ingress.Kind}, true
+				}
+			}
+		}
+	case "upstreams":
+		for _, upstream := range ks.Upstreams {
+			if upstream.Name != nil && *upstream.Name == name {
+				return serviceSource(upstream.Service), true
+			}
+		}
+	case "consumers":
+		for _, consumer := range ks.Consumers {
+			if (consumer.Username != nil && *consumer.Username == name) ||
+				(consumer.ID != nil && *consumer.ID == name) {
+				kongConsumer := consumer.K8sKongConsumer
+				return EntitySource{
+					K8sObjectInfo: util.FromK8sObject(&kongConsumer),
+					Kind:          "KongConsumer",
+					Object:        &kongConsumer,
+				}, true
+			}
+		}
+	}
+	return EntitySource{}, false
+}
+
+func serviceSource(service Service) EntitySource {
+	k8sService := service.K8sService
+	return EntitySource{
+		K8sObjectInfo: util.FromK8sObject(&k8sService),
+		Kind:          "Service",
+		Object:        &k8sService,
+	}
+}
@@ -0,0 +1,57 @@
+package kongstate
+
+import (
+	"testing"
+
+	"github.com/kong/go-kong/kong"
+	configurationv1 "github.com/kong/kubernetes-ingress-controller/pkg/apis/configuration/v1"
+	"github.com/kong/kubernetes-ingress-controller/pkg/util"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestEntitySource(t *testing.T) {
+	service := Service{
+		Service: kong.Service{Name: kong.String("default.foo.80")},
+		Routes: []Route{{
+			Route:   kong.Route{Name: kong.String("default.foo.00")},
+			Ingress: util.K8sObjectInfo{Namespace: "default", Name: "foo-ingress", Kind: "Ingress"},
+		}},
+		K8sService: corev1.Service{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "foo"}},
+	}
+	state := &KongState{
+		Services:  []Service{service},
+		Upstreams: []Upstream{{Upstream: kong.Upstream{Name: kong.String("foo.default.80.svc")}, Service: service}},
+		Consumers: []Consumer{{
+			Consumer:        kong.Consumer{Username: kong.String("alice")},
+			K8sKongConsumer: configurationv1.KongConsumer{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "alice"}},
+		}},
+	}
+
+	for _, tt := range []struct {
+		entityType, name string
+		wantKind         string
+		wantName         string
+		wantObject       bool
+	}{
+		{entityType: "services", name: "default.foo.80", wantKind: "Service", wantName: "foo", wantObject: true},
+		{entityType: "routes", name: "default.foo.00", wantKind: "Ingress", wantName: "foo-ingress"},
+		{entityType: "upstreams", name: "foo.default.80.svc", wantKind: "Service", wantName: "foo", wantObject: true},
+		{entityType: "consumers", name: "alice", wantKind: "KongConsumer", wantName: "alice", wantObject: true},
+	} {
+		t.Run(tt.entityType, func(t *testing.T) {
+			source, ok := state.EntitySource(tt.entityType, tt.name)
+			assert.True(t, ok)
+			assert.Equal(t, tt.wantKind, source.Kind)
+			assert.Equal(t, "default", source.Namespace)
+			assert.Equal(t, tt.wantName, source.Name)
+			assert.Equal(t, tt.wantObject, source.Object != nil)
+		})
+	}
+
+	_, ok := state.EntitySource("routes", "default.bar.00")
+	assert.False(t, ok)
+	_, ok = state.EntitySource("plugins", "key-auth")
+	assert.False(t, ok)
+}
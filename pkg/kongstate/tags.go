@@ -0,0 +1,47 @@
+package kongstate
+
+import (
+	"strings"
+	"unicode/utf8"
+
+	"github.com/kong/go-kong/kong"
+	"github.com/kong/kubernetes-ingress-controller/pkg/annotations"
+	"github.com/sirupsen/logrus"
+)
+
+// validTag tells whether Kong accepts tag: a non-empty UTF-8 string of printable characters, without spaces,
+// commas or slashes.
+func validTag(tag string) bool {
+	if tag == "" || !utf8.ValidString(tag) {
+		return false
+	}
+	for _, r := range tag {
+		if r < utf8.RuneSelf && (r <= ' ' || r > '~' || r == ',' || r == '/') {
+			return false
+		}
+	}
+	return true
+}
+
+// tagsFromAnnotations returns the tags set by the tags annotation of an object of kind, which are set on
+// the Kong entities generated from it in addition to the tags marking the entities owned by the controller.
+// Tags Kong would reject are ignored.
+func tagsFromAnnotations(log logrus.FieldLogger, kind string, anns map[string]string) []*string {
+	var tags, invalid []string
+	for _, tag := range annotations.ExtractTags(anns) {
+		if validTag(tag) {
+			tags = append(tags, tag)
+		} else {
+			invalid = append(invalid, tag)
+		}
+	}
+	if len(invalid) > 0 {
+		log.Errorf("ignoring invalid tags %q of the %s annotation: tags must be printable and cannot contain "+
+			"spaces, commas or slashes", strings.Join(invalid, ","), annotations.AnnotationPrefix+annotations.TagsKey)
+		ObserveTranslationFailure(kind, TranslationFailureInvalidAnnotation)
+	}
+	if len(tags) == 0 {
+		return nil
+	}
+	return kong.StringSlice(tags...)
+}
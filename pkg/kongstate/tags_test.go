@@ -0,0 +1,42 @@
+package kongstate
+
+import (
+	"testing"
+
+	"github.com/kong/go-kong/kong"
+	"github.com/kong/kubernetes-ingress-controller/pkg/annotations"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidTag(t *testing.T) {
+	for tag, want := range map[string]bool{
+		"team-a":          true,
+		"env:prod":        true,
+		"équipe":          true,
+		"v1.2_3~x@y":      true,
+		"":                false,
+		"team a":          false,
+		"team/a":          false,
+		"team,a":          false,
+		"team\ta":         false,
+		"invalid\xffutf8": false,
+	} {
+		assert.Equal(t, want, validTag(tag), tag)
+	}
+}
+
+func TestTagsFromAnnotations(t *testing.T) {
+	key := annotations.AnnotationPrefix + annotations.TagsKey
+
+	assert.Nil(t, tagsFromAnnotations(logrus.New(), "Ingress", nil))
+
+	invalid := translationFailureCount("Ingress", TranslationFailureInvalidAnnotation)
+	tags := tagsFromAnnotations(logrus.New(), "Ingress", map[string]string{key: "team-a, env:prod,,"})
+	assert.Equal(t, kong.StringSlice("team-a", "env:prod"), tags)
+	assert.Equal(t, invalid, translationFailureCount("Ingress", TranslationFailureInvalidAnnotation))
+
+	tags = tagsFromAnnotations(logrus.New(), "Ingress", map[string]string{key: "team-a,team/b"})
+	assert.Equal(t, kong.StringSlice("team-a"), tags, "invalid tags are ignored")
+	assert.Equal(t, invalid+1, translationFailureCount("Ingress", TranslationFailureInvalidAnnotation))
+}
@@ -1,9 +1,14 @@
 package kongstate
 
 import (
+	"fmt"
+	"regexp"
+	"strings"
+
 	"github.com/kong/go-kong/kong"
 	"github.com/kong/kubernetes-ingress-controller/pkg/annotations"
 	configurationv1 "github.com/kong/kubernetes-ingress-controller/pkg/apis/configuration/v1"
+	"github.com/sirupsen/logrus"
 )
 
 // Upstream is a wrapper around Upstream object in Kong.
@@ -25,13 +30,100 @@ func (u *Upstream) overrideHostHeader(anns map[string]string) {
 	u.HostHeader = kong.String(host)
 }
 
+// validHashHeaderName and validHashCookieName match the header and cookie names
+// accepted by the upstream schema of Kong.
+var (
+	validHashHeaderName = regexp.MustCompile(`^[a-zA-Z0-9_-]+$`)
+	validHashCookieName = regexp.MustCompile("^[a-zA-Z0-9!#$%&'*+.^_`|~-]+$")
+)
+
+// hashingFromAnnotations returns the consistent hashing set by the hash-on-header,
+// hash-on-cookie and hash-on-cookie-path annotations, or nil if none is set. It
+// returns an error if they set hashing Kong rejects, as checked against its
+// upstream schema.
+func hashingFromAnnotations(anns map[string]string) (*kong.Upstream, error) {
+	header := annotations.ExtractHashOnHeader(anns)
+	cookie := annotations.ExtractHashOnCookie(anns)
+	cookiePath := annotations.ExtractHashOnCookiePath(anns)
+	switch {
+	case header == "" && cookie == "" && cookiePath == "":
+		return nil, nil
+	case header != "" && cookie != "":
+		return nil, fmt.Errorf("%s and %s are mutually exclusive",
+			annotations.AnnotationPrefix+annotations.HashOnHeaderKey,
+			annotations.AnnotationPrefix+annotations.HashOnCookieKey)
+	case header != "":
+		if cookiePath != "" {
+			return nil, fmt.Errorf("%s only applies to hashing on a cookie",
+				annotations.AnnotationPrefix+annotations.HashOnCookiePathKey)
+		}
+		if !validHashHeaderName.MatchString(header) {
+			return nil, fmt.Errorf("invalid header name %q", header)
+		}
+		return &kong.Upstream{HashOn: kong.String("header"), HashOnHeader: kong.String(header)}, nil
+	case cookie == "":
+		return nil, fmt.Errorf("%s requires %s",
+			annotations.AnnotationPrefix+annotations.HashOnCookiePathKey,
+			annotations.AnnotationPrefix+annotations.HashOnCookieKey)
+	}
+	if !validHashCookieName.MatchString(cookie) {
+		return nil, fmt.Errorf("invalid cookie name %q", cookie)
+	}
+	hashing := &kong.Upstream{HashOn: kong.String("cookie"), HashOnCookie: kong.String(cookie)}
+	if cookiePath != "" {
+		if !strings.HasPrefix(cookiePath, "/") {
+			return nil, fmt.Errorf("cookie path %q must start with /", cookiePath)
+		}
+		hashing.HashOnCookiePath = kong.String(cookiePath)
+	}
+	return hashing, nil
+}
+
+// hasHashing tells whether upstream sets any of the consistent hashing fields.
+func hasHashing(upstream *kong.Upstream) bool {
+	return upstream.HashOn != nil || upstream.HashOnHeader != nil || upstream.HashOnCookie != nil ||
+		upstream.HashOnCookiePath != nil || upstream.HashFallback != nil || upstream.HashFallbackHeader != nil
+}
+
+// overrideHashing sets the consistent hashing of the upstream from the annotations
+// of its service. A KongIngress setting any hashing field takes precedence over
+// them, as both can't be merged into a hashing Kong accepts.
+func (u *Upstream) overrideHashing(log logrus.FieldLogger, kongIngress *configurationv1.KongIngress,
+	anns map[string]string) {
+	hashing, err := hashingFromAnnotations(anns)
+	if hashing == nil && err == nil {
+		return
+	}
+	log = log.WithFields(logrus.Fields{
+		"service_name":      u.Service.K8sService.Name,
+		"service_namespace": u.Service.K8sService.Namespace,
+	})
+	if kongIngress != nil && kongIngress.Upstream != nil && hasHashing(kongIngress.Upstream) {
+		log.Warnf("ignoring hashing annotations: the hashing set by KongIngress %s takes precedence",
+			kongIngress.Name)
+		return
+	}
+	if err != nil {
+		log.Errorf("ignoring hashing annotations: %v", err)
+		ObserveTranslationFailure("Service", TranslationFailureInvalidAnnotation)
+		return
+	}
+	u.HashOn = hashing.HashOn
+	u.HashOnHeader = hashing.HashOnHeader
+	u.HashOnCookie = hashing.HashOnCookie
+	u.HashOnCookiePath = hashing.HashOnCookiePath
+}
+
 // overrideByAnnotation modifies the Kong upstream based on annotations
 // on the Kubernetes service.
-func (u *Upstream) overrideByAnnotation(anns map[string]string) {
+func (u *Upstream) overrideByAnnotation(log logrus.FieldLogger, kongIngress *configurationv1.KongIngress,
+	anns map[string]string) {
 	if u == nil {
 		return
 	}
 	u.overrideHostHeader(anns)
+	u.overrideHashing(log, kongIngress, anns)
+	u.Tags = tagsFromAnnotations(log, "Service", anns)
 }
 
 // overrideByKongIngress modifies the Kong upstream based on KongIngresses
@@ -54,11 +146,101 @@ func (u *Upstream) overrideByKongIngress(kongIngress *configurationv1.KongIngres
 }
 
 // override sets Upstream fields by KongIngress first, then by annotation
-func (u *Upstream) override(kongIngress *configurationv1.KongIngress, anns map[string]string) {
+func (u *Upstream) override(log logrus.FieldLogger, kongIngress *configurationv1.KongIngress,
+	anns map[string]string) {
 	if u == nil {
 		return
 	}
 
 	u.overrideByKongIngress(kongIngress)
-	u.overrideByAnnotation(anns)
+	u.overrideByAnnotation(log, kongIngress, anns)
+}
+
+// validateStreamHealthchecks returns an error if healthchecks can't configure the upstream of a stream
+// service, such as the service of a UDPIngress, as checked against the upstream schema of Kong: its health
+// checks are of type tcp, without the settings of HTTP health checks, and their counters and intervals
+// are within the bounds of Kong.
+func validateStreamHealthchecks(healthchecks *kong.Healthcheck) error {
+	if healthchecks == nil {
+		return nil
+	}
+	if t := healthchecks.Threshold; t != nil && (*t < 0 || *t > 100) {
+		return fmt.Errorf("healthchecks.threshold (%v) must be between 0 and 100", *t)
+	}
+	if active := healthchecks.Active; active != nil {
+		if err := validateStreamHealthcheckType("healthchecks.active.type", active.Type); err != nil {
+			return err
+		}
+		if active.HTTPPath != nil || active.HTTPSSni != nil || active.HTTPSVerifyCertificate != nil {
+			return fmt.Errorf("healthchecks.active: http_path, https_sni and https_verify_certificate " +
+				"only apply to HTTP health checks")
+		}
+		if active.Timeout != nil && (*active.Timeout < 0 || *active.Timeout > 65535) {
+			return fmt.Errorf("healthchecks.active.timeout (%d) must be between 0 and 65535", *active.Timeout)
+		}
+		if active.Concurrency != nil && *active.Concurrency < 1 {
+			return fmt.Errorf("healthchecks.active.concurrency (%d) must be at least 1", *active.Concurrency)
+		}
+		if err := validateStreamHealthy("healthchecks.active.healthy", active.Healthy); err != nil {
+			return err
+		}
+		if err := validateStreamUnhealthy("healthchecks.active.unhealthy", active.Unhealthy); err != nil {
+			return err
+		}
+	}
+	if passive := healthchecks.Passive; passive != nil {
+		if err := validateStreamHealthcheckType("healthchecks.passive.type", passive.Type); err != nil {
+			return err
+		}
+		if err := validateStreamHealthy("healthchecks.passive.healthy", passive.Healthy); err != nil {
+			return err
+		}
+		if err := validateStreamUnhealthy("healthchecks.passive.unhealthy", passive.Unhealthy); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func validateStreamHealthcheckType(field string, t *string) error {
+	if t != nil && *t != "tcp" {
+		return fmt.Errorf("%s (%s) must be tcp: health checks of streams are of type tcp", field, *t)
+	}
+	return nil
+}
+
+func validateStreamHealthy(field string, healthy *kong.Healthy) error {
+	if healthy == nil {
+		return nil
+	}
+	if len(healthy.HTTPStatuses) > 0 {
+		return fmt.Errorf("%s.http_statuses only applies to HTTP health checks", field)
+	}
+	if err := validateHealthcheckBound(field+".interval", healthy.Interval, 65535); err != nil {
+		return err
+	}
+	return validateHealthcheckBound(field+".successes", healthy.Successes, 255)
+}
+
+func validateStreamUnhealthy(field string, unhealthy *kong.Unhealthy) error {
+	if unhealthy == nil {
+		return nil
+	}
+	if len(unhealthy.HTTPStatuses) > 0 || unhealthy.HTTPFailures != nil {
+		return fmt.Errorf("%s: http_statuses and http_failures only apply to HTTP health checks", field)
+	}
+	if err := validateHealthcheckBound(field+".interval", unhealthy.Interval, 65535); err != nil {
+		return err
+	}
+	if err := validateHealthcheckBound(field+".tcp_failures", unhealthy.TCPFailures, 255); err != nil {
+		return err
+	}
+	return validateHealthcheckBound(field+".timeouts", unhealthy.Timeouts, 255)
+}
+
+func validateHealthcheckBound(field string, value *int, max int) error {
+	if value != nil && (*value < 0 || *value > max) {
+		return fmt.Errorf("%s (%d) must be between 0 and %d", field, *value, max)
+	}
+	return nil
 }
@@ -4,7 +4,9 @@ import (
 	"testing"
 
 	"github.com/kong/go-kong/kong"
+	"github.com/kong/kubernetes-ingress-controller/pkg/annotations"
 	configurationv1 "github.com/kong/kubernetes-ingress-controller/pkg/apis/configuration/v1"
+	"github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -65,12 +67,204 @@ func TestOverrideUpstream(t *testing.T) {
 	}
 
 	for _, testcase := range testTable {
-		testcase.inUpstream.override(&testcase.inKongIngresss, make(map[string]string))
+		testcase.inUpstream.override(logrus.New(), &testcase.inKongIngresss, make(map[string]string))
 		assert.Equal(testcase.inUpstream, testcase.outUpstream)
 	}
 
 	assert.NotPanics(func() {
 		var nilUpstream *Upstream
-		nilUpstream.override(nil, make(map[string]string))
+		nilUpstream.override(logrus.New(), nil, make(map[string]string))
 	})
 }
+
+func TestOverrideUpstreamHashing(t *testing.T) {
+	const (
+		header     = annotations.AnnotationPrefix + annotations.HashOnHeaderKey
+		cookie     = annotations.AnnotationPrefix + annotations.HashOnCookieKey
+		cookiePath = annotations.AnnotationPrefix + annotations.HashOnCookiePathKey
+	)
+	for _, tt := range []struct {
+		name        string
+		anns        map[string]string
+		kongIngress *configurationv1.KongIngress
+		want        kong.Upstream
+		wantInvalid bool
+	}{
+		{
+			name: "no hashing",
+			anns: map[string]string{},
+			want: kong.Upstream{Name: kong.String("foo.com")},
+		},
+		{
+			name: "header",
+			anns: map[string]string{header: "x-user-id"},
+			want: kong.Upstream{
+				Name:         kong.String("foo.com"),
+				HashOn:       kong.String("header"),
+				HashOnHeader: kong.String("x-user-id"),
+			},
+		},
+		{
+			name: "cookie",
+			anns: map[string]string{cookie: "session", cookiePath: "/app"},
+			want: kong.Upstream{
+				Name:             kong.String("foo.com"),
+				HashOn:           kong.String("cookie"),
+				HashOnCookie:     kong.String("session"),
+				HashOnCookiePath: kong.String("/app"),
+			},
+		},
+		{
+			name: "other upstream settings of a KongIngress are kept",
+			anns: map[string]string{cookie: "session"},
+			kongIngress: &configurationv1.KongIngress{
+				Upstream: &kong.Upstream{Slots: kong.Int(100)},
+			},
+			want: kong.Upstream{
+				Name:         kong.String("foo.com"),
+				Slots:        kong.Int(100),
+				HashOn:       kong.String("cookie"),
+				HashOnCookie: kong.String("session"),
+			},
+		},
+		{
+			name: "the hashing of a KongIngress takes precedence",
+			anns: map[string]string{header: "x-user-id"},
+			kongIngress: &configurationv1.KongIngress{
+				Upstream: &kong.Upstream{HashOn: kong.String("ip")},
+			},
+			want: kong.Upstream{Name: kong.String("foo.com"), HashOn: kong.String("ip")},
+		},
+		{
+			name:        "header and cookie",
+			anns:        map[string]string{header: "x-user-id", cookie: "session"},
+			want:        kong.Upstream{Name: kong.String("foo.com")},
+			wantInvalid: true,
+		},
+		{
+			name:        "invalid header name",
+			anns:        map[string]string{header: "x user"},
+			want:        kong.Upstream{Name: kong.String("foo.com")},
+			wantInvalid: true,
+		},
+		{
+			name:        "invalid cookie name",
+			anns:        map[string]string{cookie: "session;"},
+			want:        kong.Upstream{Name: kong.String("foo.com")},
+			wantInvalid: true,
+		},
+		{
+			name:        "relative cookie path",
+			anns:        map[string]string{cookie: "session", cookiePath: "app"},
+			want:        kong.Upstream{Name: kong.String("foo.com")},
+			wantInvalid: true,
+		},
+		{
+			name:        "cookie path without cookie",
+			anns:        map[string]string{cookiePath: "/app"},
+			want:        kong.Upstream{Name: kong.String("foo.com")},
+			wantInvalid: true,
+		},
+		{
+			name:        "cookie path with header",
+			anns:        map[string]string{header: "x-user-id", cookiePath: "/app"},
+			want:        kong.Upstream{Name: kong.String("foo.com")},
+			wantInvalid: true,
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			invalid := translationFailureCount("Service", TranslationFailureInvalidAnnotation)
+
+			upstream := Upstream{Upstream: kong.Upstream{Name: kong.String("foo.com")}}
+			upstream.override(logrus.New(), tt.kongIngress, tt.anns)
+
+			assert.Equal(t, tt.want, upstream.Upstream)
+			wantCount := invalid
+			if tt.wantInvalid {
+				wantCount++
+			}
+			assert.Equal(t, wantCount, translationFailureCount("Service", TranslationFailureInvalidAnnotation))
+		})
+	}
+}
+
+func TestValidateStreamHealthchecks(t *testing.T) {
+	tests := []struct {
+		name         string
+		healthchecks *kong.Healthcheck
+		wantErr      string
+	}{
+		{
+			name: "no health checks",
+		},
+		{
+			name: "tcp health checks",
+			healthchecks: &kong.Healthcheck{
+				Active: &kong.ActiveHealthcheck{
+					Type:        kong.String("tcp"),
+					Concurrency: kong.Int(10),
+					Timeout:     kong.Int(1),
+					Healthy:     &kong.Healthy{Interval: kong.Int(10), Successes: kong.Int(3)},
+					Unhealthy:   &kong.Unhealthy{Interval: kong.Int(10), TCPFailures: kong.Int(3), Timeouts: kong.Int(2)},
+				},
+				Passive: &kong.PassiveHealthcheck{
+					Type:      kong.String("tcp"),
+					Unhealthy: &kong.Unhealthy{TCPFailures: kong.Int(5)},
+				},
+				Threshold: float64Ptr(50),
+			},
+		},
+		{
+			name: "http active health checks",
+			healthchecks: &kong.Healthcheck{
+				Active: &kong.ActiveHealthcheck{Type: kong.String("http")},
+			},
+			wantErr: "healthchecks.active.type (http) must be tcp: health checks of streams are of type tcp",
+		},
+		{
+			name: "http settings",
+			healthchecks: &kong.Healthcheck{
+				Active: &kong.ActiveHealthcheck{HTTPPath: kong.String("/status")},
+			},
+			wantErr: "healthchecks.active: http_path, https_sni and https_verify_certificate only apply to HTTP health checks",
+		},
+		{
+			name: "http failures",
+			healthchecks: &kong.Healthcheck{
+				Passive: &kong.PassiveHealthcheck{Unhealthy: &kong.Unhealthy{HTTPFailures: kong.Int(1)}},
+			},
+			wantErr: "healthchecks.passive.unhealthy: http_statuses and http_failures only apply to HTTP health checks",
+		},
+		{
+			name: "counter out of bounds",
+			healthchecks: &kong.Healthcheck{
+				Passive: &kong.PassiveHealthcheck{Unhealthy: &kong.Unhealthy{TCPFailures: kong.Int(256)}},
+			},
+			wantErr: "healthchecks.passive.unhealthy.tcp_failures (256) must be between 0 and 255",
+		},
+		{
+			name: "negative interval",
+			healthchecks: &kong.Healthcheck{
+				Active: &kong.ActiveHealthcheck{Healthy: &kong.Healthy{Interval: kong.Int(-1)}},
+			},
+			wantErr: "healthchecks.active.healthy.interval (-1) must be between 0 and 65535",
+		},
+		{
+			name:         "threshold out of bounds",
+			healthchecks: &kong.Healthcheck{Threshold: float64Ptr(101)},
+			wantErr:      "healthchecks.threshold (101) must be between 0 and 100",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateStreamHealthchecks(tt.healthchecks)
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+				return
+			}
+			assert.EqualError(t, err, tt.wantErr)
+		})
+	}
+}
+
+func float64Ptr(f float64) *float64 { return &f }
@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"strings"
 
 	"github.com/ghodss/yaml"
 	"github.com/kong/go-kong/kong"
@@ -11,6 +12,7 @@ import (
 	configurationv1 "github.com/kong/kubernetes-ingress-controller/pkg/apis/configuration/v1"
 	"github.com/kong/kubernetes-ingress-controller/pkg/store"
 	"github.com/kong/kubernetes-ingress-controller/pkg/util"
+	"github.com/sirupsen/logrus"
 	corev1 "k8s.io/api/core/v1"
 	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
 )
@@ -24,6 +26,15 @@ func getKongIngressForService(s store.Storer, service corev1.Service) (
 	return s.GetKongIngress(service.Namespace, confName)
 }
 
+// getKongIngressForKongService returns the KongIngress overriding service: the one of its Kubernetes
+// Service or, for the services of UDPIngresses, which aren't backed by one, the one of their UDPIngress.
+func getKongIngressForKongService(s store.Storer, service Service) (*configurationv1.KongIngress, error) {
+	if service.isUDP() && len(service.Routes) > 0 {
+		return getKongIngressFromObjectMeta(s, &service.Routes[0].Ingress)
+	}
+	return getKongIngressForService(s, service.K8sService)
+}
+
 func getKongIngressFromObjectMeta(s store.Storer, obj *util.K8sObjectInfo) (
 	*configurationv1.KongIngress, error) {
 	return getKongIngressFromIngressAnnotations(s, obj.Namespace, obj.Name, obj.Annotations)
@@ -47,8 +58,11 @@ func getKongIngressFromIngressAnnotations(s store.Storer, namespace, name string
 	return nil, nil
 }
 
+// errPluginNotFound is returned by getPlugin when no plugin has the name.
+var errPluginNotFound = errors.New("no KongPlugin or KongClusterPlugin was found")
+
 // getPlugin constructs a plugins from a KongPlugin resource.
-func getPlugin(s store.Storer, namespace, name string) (kong.Plugin, error) {
+func getPlugin(log logrus.FieldLogger, s store.Storer, namespace, name string) (kong.Plugin, error) {
 	var plugin kong.Plugin
 	k8sPlugin, err := s.GetKongPlugin(namespace, name)
 	if err != nil {
@@ -58,8 +72,7 @@ func getPlugin(s store.Storer, namespace, name string) (kong.Plugin, error) {
 			clusterPlugin, err := s.GetKongClusterPlugin(name)
 			// not found
 			if errors.As(err, &store.ErrNotFound{}) {
-				return plugin, errors.New(
-					"no KongPlugin or KongClusterPlugin was found")
+				return plugin, errPluginNotFound
 			}
 			if err != nil {
 				return plugin, err
@@ -67,7 +80,7 @@ func getPlugin(s store.Storer, namespace, name string) (kong.Plugin, error) {
 			if clusterPlugin.PluginName == "" {
 				return plugin, fmt.Errorf("invalid empty 'plugin' property")
 			}
-			plugin, err = kongPluginFromK8SClusterPlugin(s, *clusterPlugin)
+			plugin, err = kongPluginFromK8SClusterPlugin(log, s, *clusterPlugin)
 			return plugin, err
 		}
 	}
@@ -76,11 +89,12 @@ func getPlugin(s store.Storer, namespace, name string) (kong.Plugin, error) {
 		return plugin, fmt.Errorf("invalid empty 'plugin' property")
 	}
 
-	plugin, err = kongPluginFromK8SPlugin(s, *k8sPlugin)
+	plugin, err = kongPluginFromK8SPlugin(log, s, *k8sPlugin)
 	return plugin, err
 }
 
 func kongPluginFromK8SClusterPlugin(
+	log logrus.FieldLogger,
 	s store.Storer,
 	k8sPlugin configurationv1.KongClusterPlugin) (kong.Plugin, error) {
 	var config kong.Configuration
@@ -116,6 +130,7 @@ func kongPluginFromK8SClusterPlugin(
 		Disabled:  k8sPlugin.Disabled,
 		Protocols: k8sPlugin.Protocols,
 	}.toKongPlugin()
+	kongPlugin.Tags = tagsFromAnnotations(log, "KongClusterPlugin", k8sPlugin.Annotations)
 	return kongPlugin, nil
 }
 
@@ -125,6 +140,7 @@ func cloneStringPointerSlice(array ...*string) (res []*string) {
 }
 
 func kongPluginFromK8SPlugin(
+	log logrus.FieldLogger,
 	s store.Storer,
 	k8sPlugin configurationv1.KongPlugin) (kong.Plugin, error) {
 	var config kong.Configuration
@@ -152,6 +168,12 @@ func kongPluginFromK8SPlugin(
 					k8sPlugin.Name, k8sPlugin.Namespace, err)
 		}
 	}
+	config, err = ApplyConfigPatches(s, config, k8sPlugin.ConfigPatches, k8sPlugin.Namespace)
+	if err != nil {
+		return kong.Plugin{},
+			fmt.Errorf("error patching config for KongPlugin '%v/%v': %w",
+				k8sPlugin.Namespace, k8sPlugin.Name, err)
+	}
 	kongPlugin := plugin{
 		Name:   k8sPlugin.PluginName,
 		Config: config,
@@ -160,6 +182,7 @@ func kongPluginFromK8SPlugin(
 		Disabled:  k8sPlugin.Disabled,
 		Protocols: k8sPlugin.Protocols,
 	}.toKongPlugin()
+	kongPlugin.Tags = tagsFromAnnotations(log, "KongPlugin", k8sPlugin.Annotations)
 	return kongPlugin, nil
 }
 
@@ -213,6 +236,74 @@ func SecretToConfiguration(
 	return config, nil
 }
 
+// ApplyConfigPatches sets the fields of config referenced by patches to the
+// values of their secret keys, resulting in the configuration sent to Kong.
+// config is left untouched.
+func ApplyConfigPatches(
+	s store.Storer,
+	config kong.Configuration,
+	patches []configurationv1.ConfigPatch, namespace string) (
+	kong.Configuration, error) {
+	if len(patches) == 0 {
+		return config, nil
+	}
+	patched := config.DeepCopy()
+	if patched == nil {
+		patched = kong.Configuration{}
+	}
+	for _, patch := range patches {
+		reference := patch.ValueFrom.SecretValue
+		secret, err := s.GetSecret(namespace, reference.Secret)
+		if err != nil {
+			return kong.Configuration{}, fmt.Errorf(
+				"error fetching secret '%v/%v' for config field '%v': %v",
+				namespace, reference.Secret, patch.Path, err)
+		}
+		secretVal, ok := secret.Data[reference.Key]
+		if !ok {
+			return kong.Configuration{},
+				fmt.Errorf("no key '%v' in secret '%v/%v' for config field '%v'",
+					reference.Key, namespace, reference.Secret, patch.Path)
+		}
+		var value interface{}
+		if err := json.Unmarshal(secretVal, &value); err != nil {
+			value = string(secretVal)
+		}
+		if err := setConfigField(patched, patch.Path, value); err != nil {
+			return kong.Configuration{}, err
+		}
+	}
+	return patched, nil
+}
+
+// setConfigField sets the field of config at the JSON Pointer path to value,
+// creating the objects along the path as needed.
+func setConfigField(config kong.Configuration, path string, value interface{}) error {
+	if !strings.HasPrefix(path, "/") || len(path) == 1 {
+		return fmt.Errorf("invalid config field '%v': must be a JSON Pointer to a field", path)
+	}
+	tokens := strings.Split(path[1:], "/")
+	for i, token := range tokens {
+		tokens[i] = strings.NewReplacer("~1", "/", "~0", "~").Replace(token)
+	}
+
+	object := map[string]interface{}(config)
+	for _, token := range tokens[:len(tokens)-1] {
+		child, ok := object[token]
+		if !ok || child == nil {
+			child = map[string]interface{}{}
+			object[token] = child
+		}
+		childObject, ok := child.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("invalid config field '%v': '%v' is not an object", path, token)
+		}
+		object = childObject
+	}
+	object[tokens[len(tokens)-1]] = value
+	return nil
+}
+
 // plugin is a intermediate type to hold plugin related configuration
 type plugin struct {
 	Name   string
@@ -6,6 +6,7 @@ import (
 	"github.com/kong/go-kong/kong"
 	configurationv1 "github.com/kong/kubernetes-ingress-controller/pkg/apis/configuration/v1"
 	"github.com/kong/kubernetes-ingress-controller/pkg/store"
+	"github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/assert"
 	corev1 "k8s.io/api/core/v1"
 	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
@@ -136,7 +137,7 @@ func TestKongPluginFromK8SClusterPlugin(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got, err := kongPluginFromK8SClusterPlugin(store, tt.args.plugin)
+			got, err := kongPluginFromK8SClusterPlugin(logrus.New(), store, tt.args.plugin)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("kongPluginFromK8SClusterPlugin error = %v, wantErr %v", err, tt.wantErr)
 				return
@@ -157,6 +158,7 @@ func TestKongPluginFromK8SPlugin(t *testing.T) {
 				},
 				Data: map[string][]byte{
 					"correlation-id-config": []byte(`{"header_name": "foo"}`),
+					"header-name":           []byte("bar"),
 				},
 			},
 		},
@@ -238,6 +240,68 @@ func TestKongPluginFromK8SPlugin(t *testing.T) {
 			want:    kong.Plugin{},
 			wantErr: true,
 		},
+		{
+			name: "configuration patched from secret",
+			args: args{
+				plugin: configurationv1.KongPlugin{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "foo",
+						Namespace: "default",
+					},
+					Protocols:  []string{"http"},
+					PluginName: "correlation-id",
+					Config: apiextensionsv1.JSON{
+						Raw: []byte(`{"header_name": "foo", "echo_downstream": true}`),
+					},
+					ConfigPatches: []configurationv1.ConfigPatch{
+						{
+							Path: "/header_name",
+							ValueFrom: configurationv1.ConfigSource{
+								SecretValue: configurationv1.SecretValueFromSource{
+									Key:    "header-name",
+									Secret: "conf-secret",
+								},
+							},
+						},
+					},
+				},
+			},
+			want: kong.Plugin{
+				Name: kong.String("correlation-id"),
+				Config: kong.Configuration{
+					"header_name":     "bar",
+					"echo_downstream": true,
+				},
+				Protocols: kong.StringSlice("http"),
+			},
+			wantErr: false,
+		},
+		{
+			name: "configuration patched from missing secret key",
+			args: args{
+				plugin: configurationv1.KongPlugin{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "foo",
+						Namespace: "default",
+					},
+					Protocols:  []string{"http"},
+					PluginName: "correlation-id",
+					ConfigPatches: []configurationv1.ConfigPatch{
+						{
+							Path: "/header_name",
+							ValueFrom: configurationv1.ConfigSource{
+								SecretValue: configurationv1.SecretValueFromSource{
+									Key:    "missing",
+									Secret: "conf-secret",
+								},
+							},
+						},
+					},
+				},
+			},
+			want:    kong.Plugin{},
+			wantErr: true,
+		},
 		{
 			name: "non-JSON configuration",
 			args: args{
@@ -275,7 +339,7 @@ func TestKongPluginFromK8SPlugin(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got, err := kongPluginFromK8SPlugin(store, tt.args.plugin)
+			got, err := kongPluginFromK8SPlugin(logrus.New(), store, tt.args.plugin)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("kongPluginFromK8SPlugin error = %v, wantErr %v", err, tt.wantErr)
 				return
@@ -0,0 +1,136 @@
+package kongstate
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/blang/semver"
+	"github.com/kong/go-kong/kong"
+	"github.com/kong/kubernetes-ingress-controller/pkg/util"
+)
+
+const (
+	// vaultReferenceStart starts the values of plugin configurations which reference a secret held in a
+	// vault of Kong Enterprise, e.g. {vault://env/redis-password}. Kong resolves the references itself, so
+	// they are passed to it untouched.
+	vaultReferenceStart = "{vault://"
+	// vaultReferenceIntent is the start of the values which are meant as vault references, well-formed or not.
+	vaultReferenceIntent = "{vault:"
+)
+
+// minVaultReferenceVersion is the first version of Kong resolving vault references. Older versions
+// would use them as plain values.
+var minVaultReferenceVersion = semver.MustParse("2.8.0")
+
+// IsVaultReference tells whether value is meant as a reference to a secret held in a vault of Kong:
+// it is then to be well-formed, as checked by ValidateVaultReference.
+func IsVaultReference(value string) bool {
+	return strings.HasPrefix(value, vaultReferenceIntent)
+}
+
+// ValidateVaultReference returns an error detailing how reference is not a well-formed vault reference:
+// {vault://<vault>/<secret>[/<key>][?<query>][#<version>]}, where vault is the prefix of a vault, made of
+// lower case letters, digits, '-' and '_' and starting with a letter, and the path to the secret is made
+// of non-empty segments.
+func ValidateVaultReference(reference string) error {
+	if !strings.HasPrefix(reference, vaultReferenceStart) {
+		return fmt.Errorf("must start with %s", vaultReferenceStart)
+	}
+	if !strings.HasSuffix(reference, "}") {
+		return fmt.Errorf("must end with }")
+	}
+	body := strings.TrimSuffix(strings.TrimPrefix(reference, vaultReferenceStart), "}")
+	if strings.IndexFunc(body, unicode.IsSpace) >= 0 {
+		return fmt.Errorf("must not contain whitespace")
+	}
+	if strings.ContainsAny(body, "{}") {
+		return fmt.Errorf("must not contain nested braces")
+	}
+
+	// the query and version are interpreted by the vault
+	path := body
+	if i := strings.IndexAny(path, "?#"); i >= 0 {
+		if i == len(path)-1 {
+			return fmt.Errorf("the %c must be followed by the query or version of the secret", path[i])
+		}
+		path = path[:i]
+	}
+
+	segments := strings.Split(path, "/")
+	vault := segments[0]
+	if vault == "" {
+		return fmt.Errorf("the vault is missing")
+	}
+	if !isVaultPrefix(vault) {
+		return fmt.Errorf("invalid vault '%s': must consist of lower case letters, digits, '-' and '_', "+
+			"starting with a letter", vault)
+	}
+	if len(segments) == 1 || (len(segments) == 2 && segments[1] == "") {
+		return fmt.Errorf("the secret is missing, e.g. {vault://%s/<secret>}", vault)
+	}
+	for _, segment := range segments[1:] {
+		if segment == "" {
+			return fmt.Errorf("the path to the secret must not have empty segments")
+		}
+	}
+	return nil
+}
+
+// isVaultPrefix tells whether prefix may be the prefix of a vault.
+func isVaultPrefix(prefix string) bool {
+	for i, r := range prefix {
+		switch {
+		case r >= 'a' && r <= 'z':
+		case i > 0 && (r >= '0' && r <= '9' || r == '-' || r == '_'):
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// ValidateVaultReferences checks that the values of config meant as vault references, at any depth, are
+// well-formed and supported by a Kong of the given version, and returns an error naming the first field,
+// as a JSON Pointer, whose value is not. With the zero version, no vault reference is supported.
+func ValidateVaultReferences(config kong.Configuration, version semver.Version) error {
+	return validateVaultReferences("", map[string]interface{}(config), version)
+}
+
+func validateVaultReferences(path string, value interface{}, version semver.Version) error {
+	switch value := value.(type) {
+	case string:
+		if !IsVaultReference(value) {
+			return nil
+		}
+		if err := ValidateVaultReference(value); err != nil {
+			return fmt.Errorf("invalid vault reference '%s' in config field '%s': %w", value, path, err)
+		}
+		if !util.KongVersionAtLeast(version, minVaultReferenceVersion) {
+			return fmt.Errorf("vault reference '%s' in config field '%s' requires Kong %v or later",
+				value, path, minVaultReferenceVersion)
+		}
+	case kong.Configuration:
+		return validateVaultReferences(path, map[string]interface{}(value), version)
+	case map[string]interface{}:
+		keys := make([]string, 0, len(value))
+		for key := range value {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			token := strings.NewReplacer("~", "~0", "/", "~1").Replace(key)
+			if err := validateVaultReferences(path+"/"+token, value[key], version); err != nil {
+				return err
+			}
+		}
+	case []interface{}:
+		for i, item := range value {
+			if err := validateVaultReferences(fmt.Sprintf("%s/%d", path, i), item, version); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,70 @@
+package kongstate
+
+import (
+	"testing"
+
+	"github.com/blang/semver"
+	"github.com/kong/go-kong/kong"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateVaultReference(t *testing.T) {
+	for _, tt := range []struct {
+		reference string
+		wantErr   string
+	}{
+		{reference: "{vault://env/redis-password}"},
+		{reference: "{vault://aws/prod/redis/password}"},
+		{reference: "{vault://hcv/redis?namespace=kong}"},
+		{reference: "{vault://aws/redis/password#2}"},
+		{reference: "{vault://my_vault-2/redis}"},
+		{reference: "{vault:env/redis}", wantErr: "must start with {vault://"},
+		{reference: "{vault://env/redis", wantErr: "must end with }"},
+		{reference: "{vault://env/redis password}", wantErr: "must not contain whitespace"},
+		{reference: "{vault://env/{vault://env/redis}}", wantErr: "must not contain nested braces"},
+		{reference: "{vault://env/redis?}", wantErr: "the ? must be followed by the query or version of the secret"},
+		{reference: "{vault:///redis}", wantErr: "the vault is missing"},
+		{reference: "{vault://Env/redis}",
+			wantErr: "invalid vault 'Env': must consist of lower case letters, digits, '-' and '_', starting with a letter"},
+		{reference: "{vault://2fa/redis}",
+			wantErr: "invalid vault '2fa': must consist of lower case letters, digits, '-' and '_', starting with a letter"},
+		{reference: "{vault://env}", wantErr: "the secret is missing, e.g. {vault://env/<secret>}"},
+		{reference: "{vault://env/}", wantErr: "the secret is missing, e.g. {vault://env/<secret>}"},
+		{reference: "{vault://aws/redis//password}", wantErr: "the path to the secret must not have empty segments"},
+		{reference: "{vault://aws/redis/}", wantErr: "the path to the secret must not have empty segments"},
+	} {
+		t.Run(tt.reference, func(t *testing.T) {
+			assert.True(t, IsVaultReference(tt.reference))
+			err := ValidateVaultReference(tt.reference)
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateVaultReferences(t *testing.T) {
+	version := semver.MustParse("2.8.0")
+	config := kong.Configuration{
+		"policy":          "redis",
+		"redis_password":  "{vault://env/redis-password}",
+		"headers":         []interface{}{"x-api-key:{vault://env/api-key}", "{vault://aws/api/key}"},
+		"not_a_reference": "vault://env/redis-password",
+	}
+	assert.NoError(t, ValidateVaultReferences(config, version))
+	assert.NoError(t, ValidateVaultReferences(config, semver.MustParse("2.8.1-0-enterprise")))
+	assert.EqualError(t, ValidateVaultReferences(config, semver.MustParse("2.7.1")), "vault reference "+
+		"'{vault://aws/api/key}' in config field '/headers/1' requires Kong 2.8.0 or later")
+	assert.Error(t, ValidateVaultReferences(config, semver.Version{}), "the version of Kong is unknown")
+	assert.NoError(t, ValidateVaultReferences(kong.Configuration{"policy": "redis"}, semver.Version{}))
+
+	config["nested"] = map[string]interface{}{
+		"a/b": []interface{}{"ok", "{vault://env}"},
+	}
+	assert.EqualError(t, ValidateVaultReferences(config, version), "invalid vault reference '{vault://env}' "+
+		"in config field '/nested/a~1b/1': the secret is missing, e.g. {vault://env/<secret>}")
+
+	assert.NoError(t, ValidateVaultReferences(nil, version))
+}
@@ -36,18 +36,29 @@ func mergeIngressRules(objs ...ingressRules) ingressRules {
 	return result
 }
 
-func (ir *ingressRules) populateServices(log logrus.FieldLogger, s store.Storer) {
+// populateServices fills in the Kubernetes Service of every service. Services
+// whose Kubernetes Service or port can't be resolved are dropped along with
+// their routes, rather than generating an upstream without targets, and are
+// reported to onUnresolved, if set, once per object they were generated from.
+func (ir *ingressRules) populateServices(log logrus.FieldLogger, s store.Storer,
+	onUnresolved func(UnresolvedBackend)) {
 	// populate Kubernetes Service
 	for key, service := range ir.ServiceNameToServices {
 		k8sSvc, err := s.GetService(service.Namespace, service.Backend.Name)
-		if err != nil {
+		if err == nil {
+			service.K8sService = *k8sSvc
+			_, err = findPort(k8sSvc, service.Backend.Port)
+		}
+		// UDP services are not resolved from Kubernetes Services yet
+		if err != nil && (service.Protocol == nil || *service.Protocol != "udp") {
 			log.WithFields(logrus.Fields{
 				"service_name":      service.Backend.Name,
 				"service_namespace": service.Namespace,
-			}).Errorf("failed to fetch service: %v", err)
-		}
-		if k8sSvc != nil {
-			service.K8sService = *k8sSvc
+				"service_port":      service.Backend.Port.CanonicalString(),
+			}).Warnf("skipping service - failed to resolve backend: %v", err)
+			delete(ir.ServiceNameToServices, key)
+			reportUnresolvedBackend(service, err, onUnresolved)
+			continue
 		}
 		secretName := annotations.ExtractClientCertificate(
 			service.K8sService.GetAnnotations())
@@ -68,6 +79,112 @@ func (ir *ingressRules) populateServices(log logrus.FieldLogger, s store.Storer)
 					"secret_name":      secretName,
 					"secret_namespace": service.K8sService.Namespace,
 				}).Errorf("failed to fetch secret: %v", err)
+				kongstate.ObserveTranslationFailure("Service", kongstate.TranslationFailureMissingReference)
+			}
+		}
+		ir.ServiceNameToServices[key] = service
+	}
+}
+
+// reportUnresolvedBackend reports the unresolved backend of service to onUnresolved, if set, and as a
+// translation failure, once per object its routes were generated from.
+func reportUnresolvedBackend(service kongstate.Service, err error, onUnresolved func(UnresolvedBackend)) {
+	reported := map[string]bool{}
+	for _, route := range service.Routes {
+		source := route.Ingress.Kind + "/" + route.Ingress.Namespace + "/" + route.Ingress.Name
+		if reported[source] {
+			continue
+		}
+		reported[source] = true
+		kongstate.ObserveTranslationFailure(route.Ingress.Kind, kongstate.TranslationFailureMissingReference)
+		if onUnresolved == nil {
+			continue
+		}
+		onUnresolved(UnresolvedBackend{
+			Source:           route.Ingress,
+			ServiceNamespace: service.Namespace,
+			ServiceName:      service.Backend.Name,
+			ServicePort:      service.Backend.Port.CanonicalString(),
+			Err:              err,
+		})
+	}
+}
+
+// applyServiceDefaults sets the non-zero defaults on all services. It must run
+// before KongIngress overrides are filled in, for them to take precedence.
+func (ir *ingressRules) applyServiceDefaults(defaults ServiceDefaults) {
+	for key, service := range ir.ServiceNameToServices {
+		if defaults.ConnectTimeout > 0 {
+			service.ConnectTimeout = kong.Int(defaults.ConnectTimeout)
+		}
+		if defaults.ReadTimeout > 0 {
+			service.ReadTimeout = kong.Int(defaults.ReadTimeout)
+		}
+		if defaults.WriteTimeout > 0 {
+			service.WriteTimeout = kong.Int(defaults.WriteTimeout)
+		}
+		ir.ServiceNameToServices[key] = service
+	}
+}
+
+// validatePaths drops the regular expression paths of routes which validate rejects, and the routes left
+// without paths. Nothing is dropped if validate is nil.
+func (ir *ingressRules) validatePaths(log logrus.FieldLogger, validate func(path string) error) {
+	if validate == nil {
+		return
+	}
+	for key, service := range ir.ServiceNameToServices {
+		routes := service.Routes[:0]
+		for _, route := range service.Routes {
+			if len(route.Paths) == 0 {
+				routes = append(routes, route)
+				continue
+			}
+			var paths []*string
+			for _, path := range route.Paths {
+				if path != nil && kongstate.IsRegexPath(*path) {
+					if err := validate(*path); err != nil {
+						log.WithFields(logrus.Fields{
+							"object_kind":      route.Ingress.Kind,
+							"object_namespace": route.Ingress.Namespace,
+							"object_name":      route.Ingress.Name,
+						}).Errorf("path skipped: %v", err)
+						kongstate.ObserveTranslationFailure(route.Ingress.Kind, kongstate.TranslationFailureInvalidRule)
+						continue
+					}
+				}
+				paths = append(paths, path)
+			}
+			if len(paths) == 0 {
+				continue
+			}
+			route.Paths = paths
+			routes = append(routes, route)
+		}
+		if len(routes) == 0 {
+			delete(ir.ServiceNameToServices, key)
+			continue
+		}
+		service.Routes = routes
+		ir.ServiceNameToServices[key] = service
+	}
+}
+
+// applyRouteDefaults sets the non-nil defaults on all HTTP routes, which are the
+// routes having a strip_path. It must run before KongIngress and annotation
+// overrides are filled in, for them to take precedence.
+func (ir *ingressRules) applyRouteDefaults(defaults RouteDefaults) {
+	for key, service := range ir.ServiceNameToServices {
+		for i := range service.Routes {
+			route := &service.Routes[i]
+			if route.StripPath == nil {
+				continue
+			}
+			if defaults.StripPath != nil {
+				route.StripPath = kong.Bool(*defaults.StripPath)
+			}
+			if defaults.PreserveHost != nil {
+				route.PreserveHost = kong.Bool(*defaults.PreserveHost)
 			}
 		}
 		ir.ServiceNameToServices[key] = service
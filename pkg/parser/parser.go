@@ -6,9 +6,13 @@ import (
 	"crypto/x509"
 	"encoding/pem"
 	"fmt"
+	"net"
 	"reflect"
+	"sort"
+	"strconv"
 	"strings"
 
+	"github.com/blang/semver"
 	"github.com/kong/go-kong/kong"
 	"github.com/kong/kubernetes-ingress-controller/pkg/annotations"
 	configurationv1beta1 "github.com/kong/kubernetes-ingress-controller/pkg/apis/configuration/v1beta1"
@@ -18,7 +22,6 @@ import (
 	"github.com/sirupsen/logrus"
 	corev1 "k8s.io/api/core/v1"
 	networking "k8s.io/api/networking/v1beta1"
-	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/intstr"
 	knative "knative.dev/networking/pkg/apis/networking/v1alpha1"
 )
@@ -45,36 +48,121 @@ func parseAll(log logrus.FieldLogger, s store.Storer) ingressRules {
 	}
 	parsedKnative := fromKnativeIngress(log, knativeIngresses)
 
-	return mergeIngressRules(parsedIngressV1beta1, parsedIngressV1, parsedTCPIngress, parsedUDPIngresses, parsedKnative)
+	httpRoutes, err := s.ListHTTPRoutes()
+	if err != nil {
+		log.Errorf("failed to list HTTPRoutes: %v", err)
+	}
+	parsedHTTPRoutes := fromHTTPRouteV1Alpha1(log, httpRoutes)
+
+	return mergeIngressRules(parsedIngressV1beta1, parsedIngressV1, parsedTCPIngress, parsedUDPIngresses, parsedKnative,
+		parsedHTTPRoutes)
+}
+
+// ServiceDefaults holds the values set on every Kong service generated from
+// Kubernetes resources, unless a KongIngress overrides them.
+// Timeouts are in milliseconds; 0 leaves the value the parser picks otherwise.
+type ServiceDefaults struct {
+	ConnectTimeout int
+	ReadTimeout    int
+	WriteTimeout   int
+}
+
+// RouteDefaults holds the values set on every Kong route generated for HTTP
+// traffic, unless an annotation or a KongIngress overrides them. Nil fields
+// leave the value the parser picks otherwise: no strip_path, and preserve_host.
+//
+// With StripPath, the part of the request path matched by a route is removed
+// before proxying. For an Ingress path of type Prefix, that is the prefix; for
+// type Exact, that is the whole path, so the upstream receives "/". Paths of
+// type ImplementationSpecific are regular expressions to Kong, which strips
+// whatever they match.
+type RouteDefaults struct {
+	StripPath    *bool
+	PreserveHost *bool
+}
+
+// Options tune how Kubernetes resources are translated into a Kong configuration.
+type Options struct {
+	// ServiceDefaults are applied to every generated service.
+	ServiceDefaults ServiceDefaults
+	// RouteDefaults are applied to every generated HTTP route.
+	RouteDefaults RouteDefaults
+	// CredentialTypeKey is the data field of Secrets holding the type of their
+	// credential. If empty, util.DefaultCredentialTypeKey is used.
+	CredentialTypeKey string
+	// OnUnresolvedBackend, if set, is called for every object referencing a
+	// backend Service, or a port of it, which does not exist. No configuration
+	// is generated for the rules using such backends.
+	OnUnresolvedBackend func(UnresolvedBackend)
+	// ValidatePath, if set, is called for every path of a route which Kong compiles as a regular
+	// expression, and returns an error if Kong rejects it. Rejected paths are dropped from their route,
+	// and routes left without paths are dropped, so that they don't fail the whole configuration.
+	ValidatePath func(path string) error
+	// KongVersion is the version of Kong the configuration is generated for, which gates the
+	// features it can use. With the zero version, none is used.
+	KongVersion semver.Version
+}
+
+// UnresolvedBackend is a backend Service, referenced by an object, which
+// could not be resolved.
+type UnresolvedBackend struct {
+	// Source is the object referencing the backend.
+	Source util.K8sObjectInfo
+	// ServiceNamespace and ServiceName name the backend Service.
+	ServiceNamespace string
+	ServiceName      string
+	// ServicePort is the name or number of the port of the backend Service,
+	// or kongstate.ImplicitPort if the object does not name one.
+	ServicePort string
+	// Err tells why the backend could not be resolved.
+	Err error
 }
 
 // Build creates a Kong configuration from Ingress and Custom resources
 // defined in Kuberentes.
 // It throws an error if there is an error returned from client-go.
 func Build(log logrus.FieldLogger, s store.Storer) (*kongstate.KongState, error) {
+	return BuildWithOptions(log, s, Options{})
+}
+
+// BuildWithOptions creates a Kong configuration like Build, with the given options.
+func BuildWithOptions(log logrus.FieldLogger, s store.Storer, opts Options) (*kongstate.KongState, error) {
 	parsedAll := parseAll(log, s)
-	parsedAll.populateServices(log, s)
+	parsedAll.populateServices(log, s, opts.OnUnresolvedBackend)
+	parsedAll.validatePaths(log, opts.ValidatePath)
+	parsedAll.applyServiceDefaults(opts.ServiceDefaults)
+	parsedAll.applyRouteDefaults(opts.RouteDefaults)
+
+	result := kongstate.KongState{Version: opts.KongVersion}
+	// generate Upstreams and Targets from service defs, which may point services to their upstream
+	result.Upstreams = getUpstreams(log, s, parsedAll.ServiceNameToServices)
 
-	var result kongstate.KongState
 	// add the routes and services to the state
 	for _, service := range parsedAll.ServiceNameToServices {
 		result.Services = append(result.Services, service)
 	}
 
-	// generate Upstreams and Targets from service defs
-	result.Upstreams = getUpstreams(log, s, parsedAll.ServiceNameToServices)
-
 	// merge KongIngress with Routes, Services and Upstream
 	result.FillOverrides(log, s)
 
 	// generate consumers and credentials
-	result.FillConsumersAndCredentials(log, s)
+	result.FillConsumersAndCredentials(log, s, opts.CredentialTypeKey)
 
 	// process annotation plugins
 	result.FillPlugins(log, s)
 
 	// generate Certificates and SNIs
-	result.Certificates = getCerts(log, s, parsedAll.SecretNameToSNIs)
+	var certIDs map[string]string
+	result.Certificates, certIDs = getCerts(log, s, parsedAll.SecretNameToSNIs)
+	// client certificates must reference the certificate their secret was merged into
+	for i, service := range result.Services {
+		if service.ClientCertificate == nil || service.ClientCertificate.ID == nil {
+			continue
+		}
+		if id, ok := certIDs[*service.ClientCertificate.ID]; ok {
+			result.Services[i].ClientCertificate = &kong.Certificate{ID: kong.String(id)}
+		}
+	}
 
 	// populate CA certificates in Kong
 	var err error
@@ -99,27 +187,32 @@ func toCACerts(log logrus.FieldLogger, caCertSecrets []*corev1.Secret) []kong.CA
 		})
 		if !idExists {
 			log.Errorf("invalid CA certificate: missing 'id' field in data")
+			kongstate.ObserveTranslationFailure("Secret", kongstate.TranslationFailureInvalidCertificate)
 			continue
 		}
 
 		caCertbytes, certExists := certSecret.Data["cert"]
 		if !certExists {
 			log.Errorf("invalid CA certificate: missing 'cert' field in data")
+			kongstate.ObserveTranslationFailure("Secret", kongstate.TranslationFailureInvalidCertificate)
 			continue
 		}
 
 		pemBlock, _ := pem.Decode(caCertbytes)
 		if pemBlock == nil {
 			log.Errorf("invalid CA certificate: invalid PEM block")
+			kongstate.ObserveTranslationFailure("Secret", kongstate.TranslationFailureInvalidCertificate)
 			continue
 		}
 		x509Cert, err := x509.ParseCertificate(pemBlock.Bytes)
 		if err != nil {
 			log.Errorf("invalid CA certificate: failed to parse certificate: %v", err)
+			kongstate.ObserveTranslationFailure("Secret", kongstate.TranslationFailureInvalidCertificate)
 			continue
 		}
 		if !x509Cert.IsCA {
 			log.Errorf("invalid CA certificate: certificate is missing the 'CA' basic constraint: %v", err)
+			kongstate.ObserveTranslationFailure("Secret", kongstate.TranslationFailureInvalidCertificate)
 			continue
 		}
 
@@ -206,20 +299,19 @@ func findPort(svc *corev1.Service, wantPort kongstate.PortDef) (*corev1.ServiceP
 func getUpstreams(
 	log logrus.FieldLogger, s store.Storer, serviceMap map[string]kongstate.Service) []kongstate.Upstream {
 	var upstreams []kongstate.Upstream
-	for _, service := range serviceMap {
+	for key, service := range serviceMap {
 		// TODO: for v1alpha1 of UDPIngress we don't support automated Kubernetes service resolution,
 		// See the following issue for follow-up: https://github.com/Kong/kubernetes-ingress-controller/issues/1080
 		if service.Protocol != nil && *service.Protocol == "udp" {
-			return nil
+			upstream := getUDPUpstream(service)
+			service.Host = upstream.Name
+			serviceMap[key] = service
+			upstream.Service = service
+			upstreams = append(upstreams, upstream)
+			continue
 		}
 
-		var targets []kongstate.Target
-		port, err := findPort(&service.K8sService, service.Backend.Port)
-		if err == nil {
-			targets = getServiceEndpoints(log, s, service.K8sService, port)
-		} else {
-			log.WithField("service_name", *service.Name).Warnf("skipping service - getServiceEndpoints failed: %v", err)
-		}
+		targets := serviceTargets(log, s, service)
 
 		upstream := kongstate.Upstream{
 			Upstream: kong.Upstream{
@@ -234,6 +326,21 @@ func getUpstreams(
 	return upstreams
 }
 
+// getUDPUpstream returns the upstream of the service of a UDPIngress, targeting the host and port of
+// the UDPIngress, for the service to be pointed to. The upstream holds the settings of its KongIngress,
+// such as health checks, and is otherwise left to the defaults of Kong.
+func getUDPUpstream(service kongstate.Service) kongstate.Upstream {
+	target := net.JoinHostPort(*service.Host, strconv.Itoa(*service.Port))
+	return kongstate.Upstream{
+		Upstream: kong.Upstream{
+			Name: kong.String(fmt.Sprintf("%s.udp.svc", *service.Name)),
+		},
+		Targets: []kongstate.Target{
+			{Target: kong.Target{Target: kong.String(target)}},
+		},
+	}
+}
+
 func getCertFromSecret(secret *corev1.Secret) (string, string, error) {
 	certData, okcert := secret.Data[corev1.TLSCertKey]
 	keyData, okkey := secret.Data[corev1.TLSPrivateKeyKey]
@@ -255,15 +362,19 @@ func getCertFromSecret(secret *corev1.Secret) (string, string, error) {
 	return cert, key, nil
 }
 
-func getCerts(log logrus.FieldLogger, s store.Storer, secretsToSNIs map[string][]string) []kongstate.Certificate {
-	snisAdded := make(map[string]bool)
-	// map of cert public key + private key to certificate
-	type certWrapper struct {
-		cert              kong.Certificate
-		CreationTimestamp metav1.Time
+// getCerts creates one Kong certificate per unique key-pair found in the secrets of secretsToSNIs,
+// which can be shared by several secrets and Ingresses. The certificate takes the UID of the oldest
+// secret holding it as ID, and an SNI is attached to a single certificate only: when secrets with
+// different key-pairs claim it, the oldest one wins. Besides the certificates, it returns the ID of
+// the certificate each secret UID was merged into.
+func getCerts(log logrus.FieldLogger, s store.Storer,
+	secretsToSNIs map[string][]string) ([]kongstate.Certificate, map[string]string) {
+	type tlsSecret struct {
+		secret    *corev1.Secret
+		cert, key string
+		SNIs      []string
 	}
-	certs := make(map[string]certWrapper)
-
+	var tlsSecrets []tlsSecret
 	for secretKey, SNIs := range secretsToSNIs {
 		namespaceName := strings.Split(secretKey, "/")
 		secret, err := s.GetSecret(namespaceName[0], namespaceName[1])
@@ -272,6 +383,7 @@ func getCerts(log logrus.FieldLogger, s store.Storer, secretsToSNIs map[string][
 				"secret_name":      namespaceName[1],
 				"secret_namespace": namespaceName[0],
 			}).Logger.Errorf("failed to fetch secret: %v", err)
+			kongstate.ObserveTranslationFailure("Secret", kongstate.TranslationFailureMissingReference)
 			continue
 		}
 		cert, key, err := getCertFromSecret(secret)
@@ -280,38 +392,52 @@ func getCerts(log logrus.FieldLogger, s store.Storer, secretsToSNIs map[string][
 				"secret_name":      namespaceName[1],
 				"secret_namespace": namespaceName[0],
 			}).Logger.Errorf("failed to construct certificate from secret: %v", err)
+			kongstate.ObserveTranslationFailure("Secret", kongstate.TranslationFailureInvalidCertificate)
 			continue
 		}
-		kongCert, ok := certs[cert+key]
+		tlsSecrets = append(tlsSecrets, tlsSecret{secret: secret, cert: cert, key: key, SNIs: SNIs})
+	}
+	// process the oldest secrets first, for the output not to depend on map iteration order
+	sort.Slice(tlsSecrets, func(i, j int) bool {
+		a, b := tlsSecrets[i].secret, tlsSecrets[j].secret
+		if !a.CreationTimestamp.Equal(&b.CreationTimestamp) {
+			return a.CreationTimestamp.Before(&b.CreationTimestamp)
+		}
+		if a.Namespace != b.Namespace {
+			return a.Namespace < b.Namespace
+		}
+		return a.Name < b.Name
+	})
+
+	snisAdded := make(map[string]bool)
+	certIDs := make(map[string]string)
+	// map of cert public key + private key to the index of its certificate in res
+	certs := make(map[string]int)
+	var res []kongstate.Certificate
+	for _, tlsSecret := range tlsSecrets {
+		i, ok := certs[tlsSecret.cert+tlsSecret.key]
 		if !ok {
-			kongCert = certWrapper{
-				cert: kong.Certificate{
-					ID:   kong.String(string(secret.UID)),
-					Cert: kong.String(cert),
-					Key:  kong.String(key),
-				},
-				CreationTimestamp: secret.CreationTimestamp,
-			}
-		} else {
-			if kongCert.CreationTimestamp.After(secret.CreationTimestamp.Time) {
-				kongCert.cert.ID = kong.String(string(secret.UID))
-				kongCert.CreationTimestamp = secret.CreationTimestamp
-			}
+			i = len(res)
+			certs[tlsSecret.cert+tlsSecret.key] = i
+			res = append(res, kongstate.Certificate{Certificate: kong.Certificate{
+				ID:   kong.String(string(tlsSecret.secret.UID)),
+				Cert: kong.String(tlsSecret.cert),
+				Key:  kong.String(tlsSecret.key),
+			}})
 		}
+		kongCert := &res[i].Certificate
+		certIDs[string(tlsSecret.secret.UID)] = *kongCert.ID
 
+		SNIs := append([]string(nil), tlsSecret.SNIs...)
+		sort.Strings(SNIs)
 		for _, sni := range SNIs {
 			if !snisAdded[sni] {
 				snisAdded[sni] = true
-				kongCert.cert.SNIs = append(kongCert.cert.SNIs, kong.String(sni))
+				kongCert.SNIs = append(kongCert.SNIs, kong.String(sni))
 			}
 		}
-		certs[cert+key] = kongCert
 	}
-	var res []kongstate.Certificate
-	for _, cert := range certs {
-		res = append(res, kongstate.Certificate{Certificate: cert.cert})
-	}
-	return res
+	return res, certIDs
 }
 
 func getServiceEndpoints(log logrus.FieldLogger, s store.Storer, svc corev1.Service,
@@ -433,6 +559,28 @@ func getEndpoints(
 		}
 	}
 
+	// sort the endpoints so that the targets generated from them don't
+	// change order, and so diff, from one sync to the next
+	sort.SliceStable(upsServers, func(i, j int) bool {
+		return endpointLess(upsServers[i], upsServers[j])
+	})
 	log.Debugf("found endpoints: %v", upsServers)
 	return upsServers
 }
+
+// endpointLess orders endpoints by IP address, numerically, then by port.
+func endpointLess(a, b util.Endpoint) bool {
+	if a.Address != b.Address {
+		ipA, ipB := net.ParseIP(a.Address), net.ParseIP(b.Address)
+		if ipA == nil || ipB == nil {
+			return a.Address < b.Address
+		}
+		return bytes.Compare(ipA.To16(), ipB.To16()) < 0
+	}
+	portA, errA := strconv.Atoi(a.Port)
+	portB, errB := strconv.Atoi(b.Port)
+	if errA != nil || errB != nil {
+		return a.Port < b.Port
+	}
+	return portA < portB
+}
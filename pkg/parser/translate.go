@@ -2,6 +2,7 @@ package parser
 
 import (
 	"fmt"
+	"regexp"
 	"sort"
 	"strconv"
 	"strings"
@@ -16,6 +17,7 @@ import (
 	networkingv1beta1 "k8s.io/api/networking/v1beta1"
 	"k8s.io/apimachinery/pkg/util/intstr"
 	knative "knative.dev/networking/pkg/apis/networking/v1alpha1"
+	gatewayv1alpha1 "sigs.k8s.io/gateway-api/apis/v1alpha1"
 )
 
 func fromIngressV1beta1(log logrus.FieldLogger, ingressList []*networkingv1beta1.Ingress) ingressRules {
@@ -50,13 +52,14 @@ func fromIngressV1beta1(log logrus.FieldLogger, ingressList []*networkingv1beta1
 
 				if strings.Contains(path, "//") {
 					log.Errorf("rule skipped: invalid path: '%v'", path)
+					kongstate.ObserveTranslationFailure("Ingress", kongstate.TranslationFailureInvalidRule)
 					continue
 				}
 				if path == "" {
 					path = "/"
 				}
 				r := kongstate.Route{
-					Ingress: util.FromK8sObject(ingress),
+					Ingress: util.FromK8sObjectOfKind(ingress, "Ingress"),
 					Route: kong.Route{
 						// TODO (#834) Figure out a way to name the routes
 						// This is not a stable scheme
@@ -145,7 +148,7 @@ func fromIngressV1beta1(log logrus.FieldLogger, ingressList []*networkingv1beta1
 			}
 		}
 		r := kongstate.Route{
-			Ingress: util.FromK8sObject(&ingress),
+			Ingress: util.FromK8sObjectOfKind(&ingress, "Ingress"),
 			Route: kong.Route{
 				Name:          kong.String(ingress.Namespace + "." + ingress.Name),
 				Paths:         kong.StringSlice("/"),
@@ -191,6 +194,7 @@ func fromIngressV1(log logrus.FieldLogger, ingressList []*networkingv1.Ingress)
 			for j, rulePath := range rule.HTTP.Paths {
 				if strings.Contains(rulePath.Path, "//") {
 					log.Errorf("rule skipped: invalid path: '%v'", rulePath.Path)
+					kongstate.ObserveTranslationFailure("Ingress", kongstate.TranslationFailureInvalidRule)
 					continue
 				}
 
@@ -202,11 +206,12 @@ func fromIngressV1(log logrus.FieldLogger, ingressList []*networkingv1.Ingress)
 				paths, err := pathsFromK8s(rulePath.Path, pathType)
 				if err != nil {
 					log.Errorf("rule skipped: pathsFromK8s: %v", err)
+					kongstate.ObserveTranslationFailure("Ingress", kongstate.TranslationFailureInvalidRule)
 					continue
 				}
 
 				r := kongstate.Route{
-					Ingress: util.FromK8sObject(ingress),
+					Ingress: util.FromK8sObjectOfKind(ingress, "Ingress"),
 					Route: kong.Route{
 						// TODO (#834) Figure out a way to name the routes
 						// This is not a stable scheme
@@ -292,7 +297,7 @@ func fromIngressV1(log logrus.FieldLogger, ingressList []*networkingv1.Ingress)
 			}
 		}
 		r := kongstate.Route{
-			Ingress: util.FromK8sObject(&ingress),
+			Ingress: util.FromK8sObjectOfKind(&ingress, "Ingress"),
 			Route: kong.Route{
 				Name:          kong.String(ingress.Namespace + "." + ingress.Name),
 				Paths:         kong.StringSlice("/"),
@@ -309,6 +314,13 @@ func fromIngressV1(log logrus.FieldLogger, ingressList []*networkingv1.Ingress)
 	return result
 }
 
+// tcpListener is the port a TCPIngress rule listens on, and whether it routes
+// TLS streams by SNI on it.
+type tcpListener struct {
+	port int
+	sni  bool
+}
+
 func fromTCPIngressV1beta1(log logrus.FieldLogger, tcpIngressList []*configurationv1beta1.TCPIngress) ingressRules {
 	result := newIngressRules()
 
@@ -317,6 +329,9 @@ func fromTCPIngressV1beta1(log logrus.FieldLogger, tcpIngressList []*configurati
 			&tcpIngressList[j].CreationTimestamp)
 	})
 
+	// a port routes either by SNI or plainly, as decided by the oldest rule using it
+	listeners := map[tcpListener]string{}
+
 	for _, ingress := range tcpIngressList {
 		ingressSpec := ingress.Spec
 
@@ -331,10 +346,12 @@ func fromTCPIngressV1beta1(log logrus.FieldLogger, tcpIngressList []*configurati
 
 			if rule.Port <= 0 {
 				log.Errorf("invalid TCPIngress: invalid port: %v", rule.Port)
+				kongstate.ObserveTranslationFailure("TCPIngress", kongstate.TranslationFailureInvalidRule)
 				continue
 			}
+			sni := rule.Host != ""
 			r := kongstate.Route{
-				Ingress: util.FromK8sObject(ingress),
+				Ingress: util.FromK8sObjectOfKind(ingress, "TCPIngress"),
 				Route: kong.Route{
 					// TODO (#834) Figure out a way to name the routes
 					// This is not a stable scheme
@@ -353,18 +370,34 @@ func fromTCPIngressV1beta1(log logrus.FieldLogger, tcpIngressList []*configurati
 					},
 				},
 			}
-			host := rule.Host
-			if host != "" {
-				r.SNIs = kong.StringSlice(host)
+			// TLS streams are routed by their SNI, Kong terminating TLS with the
+			// certificate of the host
+			if sni {
+				r.Protocols = kong.StringSlice("tls")
+				r.SNIs = kong.StringSlice(rule.Host)
 			}
 			if rule.Backend.ServiceName == "" {
 				log.Errorf("invalid TCPIngress: empty serviceName")
+				kongstate.ObserveTranslationFailure("TCPIngress", kongstate.TranslationFailureInvalidRule)
 				continue
 			}
 			if rule.Backend.ServicePort <= 0 {
 				log.Errorf("invalid TCPIngress: invalid servicePort: %v", rule.Backend.ServicePort)
+				kongstate.ObserveTranslationFailure("TCPIngress", kongstate.TranslationFailureInvalidRule)
+				continue
+			}
+			if owner, ok := listeners[tcpListener{port: rule.Port, sni: !sni}]; ok {
+				if sni {
+					log.Errorf("invalid TCPIngress: port %d is already routed without SNI by TCPIngress %s", rule.Port, owner)
+				} else {
+					log.Errorf("invalid TCPIngress: port %d is already routed by SNI by TCPIngress %s", rule.Port, owner)
+				}
+				kongstate.ObserveTranslationFailure("TCPIngress", kongstate.TranslationFailureConflict)
 				continue
 			}
+			if _, ok := listeners[tcpListener{port: rule.Port, sni: sni}]; !ok {
+				listeners[tcpListener{port: rule.Port, sni: sni}] = ingress.Namespace + "/" + ingress.Name
+			}
 
 			serviceName := fmt.Sprintf("%s.%s.%d", ingress.Namespace, rule.Backend.ServiceName, rule.Backend.ServicePort)
 			service, ok := result.ServiceNameToServices[serviceName]
@@ -418,8 +451,10 @@ func fromUDPIngressV1Alpha1(log logrus.FieldLogger, ingressList []*v1alpha1.UDPI
 				Host:     kong.String(ingressSpec.Host),
 				Port:     kong.Int(ingress.Spec.TargetPort),
 			},
+			Namespace: ingress.Namespace,
 			Routes: []kongstate.Route{
 				{
+					Ingress: util.FromK8sObjectOfKind(ingress, "UDPIngress"),
 					Route: kong.Route{
 						Protocols:    []*string{kong.String("udp")},
 						Destinations: []*kong.CIDRPort{{Port: kong.Int(ingressSpec.ListenPort)}},
@@ -464,7 +499,7 @@ func fromKnativeIngress(log logrus.FieldLogger, ingressList []*knative.Ingress)
 					path = "/"
 				}
 				r := kongstate.Route{
-					Ingress: util.FromK8sObject(ingress),
+					Ingress: util.FromK8sObjectOfKind(ingress, "KnativeIngress"),
 					Route: kong.Route{
 						// TODO (#834) Figure out a way to name the routes
 						// This is not a stable scheme
@@ -559,6 +594,162 @@ func knativeSelectSplit(splits []knative.IngressBackendSplit) knative.IngressBac
 	return res
 }
 
+func fromHTTPRouteV1Alpha1(log logrus.FieldLogger, routeList []*gatewayv1alpha1.HTTPRoute) ingressRules {
+	result := newIngressRules()
+
+	sort.SliceStable(routeList, func(i, j int) bool {
+		return routeList[i].CreationTimestamp.Before(&routeList[j].CreationTimestamp)
+	})
+
+	for _, httpRoute := range routeList {
+		log = log.WithFields(logrus.Fields{
+			"httproute_namespace": httpRoute.Namespace,
+			"httproute_name":      httpRoute.Name,
+		})
+
+		var hosts []*string
+		for _, hostname := range httpRoute.Spec.Hostnames {
+			// the wildcard hostname matches all requests, as does a route without hosts
+			if hostname == "*" {
+				hosts = nil
+				break
+			}
+			hosts = append(hosts, kong.String(string(hostname)))
+		}
+
+		for i, rule := range httpRoute.Spec.Rules {
+			forwardTo, ok := httpRouteSelectForwardTo(rule.ForwardTo)
+			if !ok {
+				log.Errorf("rule skipped: no forwardTo references a service with a port")
+				kongstate.ObserveTranslationFailure("HTTPRoute", kongstate.TranslationFailureInvalidRule)
+				continue
+			}
+			if len(rule.ForwardTo) > 1 {
+				log.Warnf("rule %d forwards to several backends, only service %s with the highest weight is used",
+					i, *forwardTo.ServiceName)
+			}
+			if len(rule.Filters) > 0 {
+				log.Warnf("rule %d has filters, which are not supported and ignored", i)
+			}
+
+			matches := rule.Matches
+			if len(matches) == 0 {
+				matches = []gatewayv1alpha1.HTTPRouteMatch{{}}
+			}
+			for j, match := range matches {
+				paths, pathType, err := pathsFromHTTPPathMatch(match.Path)
+				if err != nil {
+					log.Errorf("match skipped: %v", err)
+					kongstate.ObserveTranslationFailure("HTTPRoute", kongstate.TranslationFailureInvalidRule)
+					continue
+				}
+				r := kongstate.Route{
+					Ingress: util.FromK8sObjectOfKind(httpRoute, "HTTPRoute"),
+					Route: kong.Route{
+						Name:          kong.String(fmt.Sprintf("%s.%s.%d%d", httpRoute.Namespace, httpRoute.Name, i, j)),
+						Hosts:         hosts,
+						Paths:         paths,
+						StripPath:     kong.Bool(false),
+						PreserveHost:  kong.Bool(true),
+						Protocols:     kong.StringSlice("http", "https"),
+						RegexPriority: kong.Int(priorityForPath[pathType]),
+					},
+				}
+				if match.Headers != nil {
+					if match.Headers.Type != "" && match.Headers.Type != gatewayv1alpha1.HeaderMatchExact {
+						log.Errorf("match skipped: unsupported header match type %q", match.Headers.Type)
+						kongstate.ObserveTranslationFailure("HTTPRoute", kongstate.TranslationFailureInvalidRule)
+						continue
+					}
+					r.Headers = make(map[string][]string, len(match.Headers.Values))
+					for name, value := range match.Headers.Values {
+						r.Headers[name] = []string{value}
+					}
+				}
+
+				port := kongstate.PortDef{Mode: kongstate.PortModeByNumber, Number: int32(*forwardTo.Port)}
+				serviceName := fmt.Sprintf("%s.%s.%d", httpRoute.Namespace, *forwardTo.ServiceName, *forwardTo.Port)
+				service, ok := result.ServiceNameToServices[serviceName]
+				if !ok {
+					service = kongstate.Service{
+						Service: kong.Service{
+							Name: kong.String(serviceName),
+							Host: kong.String(fmt.Sprintf("%s.%s.%s.svc", *forwardTo.ServiceName, httpRoute.Namespace,
+								port.CanonicalString())),
+							Port:           kong.Int(80),
+							Protocol:       kong.String("http"),
+							Path:           kong.String("/"),
+							ConnectTimeout: kong.Int(60000),
+							ReadTimeout:    kong.Int(60000),
+							WriteTimeout:   kong.Int(60000),
+							Retries:        kong.Int(5),
+						},
+						Namespace: httpRoute.Namespace,
+						Backend: kongstate.ServiceBackend{
+							Name: *forwardTo.ServiceName,
+							Port: port,
+						},
+					}
+				}
+				service.Routes = append(service.Routes, r)
+				result.ServiceNameToServices[serviceName] = service
+			}
+		}
+	}
+
+	return result
+}
+
+// httpRouteSelectForwardTo picks the backend of an HTTPRoute rule: as a Kong route has a single
+// service, the service with the highest weight is selected among those with a port set.
+func httpRouteSelectForwardTo(forwardTo []gatewayv1alpha1.HTTPRouteForwardTo) (gatewayv1alpha1.HTTPRouteForwardTo, bool) {
+	var res gatewayv1alpha1.HTTPRouteForwardTo
+	found := false
+	for _, candidate := range forwardTo {
+		if candidate.ServiceName == nil || *candidate.ServiceName == "" || candidate.Port == nil {
+			continue
+		}
+		if !found || candidate.Weight > res.Weight {
+			res, found = candidate, true
+		}
+	}
+	return res, found
+}
+
+// pathsFromHTTPPathMatch translates the path match of an HTTPRoute into Kong paths, along with the
+// Ingress path type of equivalent semantics. An unset match type or value defaults to a prefix of /.
+func pathsFromHTTPPathMatch(match gatewayv1alpha1.HTTPPathMatch) ([]*string, networkingv1.PathType, error) {
+	var pathType networkingv1.PathType
+	switch match.Type {
+	case "", gatewayv1alpha1.PathMatchPrefix:
+		pathType = networkingv1.PathTypePrefix
+	case gatewayv1alpha1.PathMatchExact:
+		pathType = networkingv1.PathTypeExact
+	case gatewayv1alpha1.PathMatchRegularExpression, gatewayv1alpha1.PathMatchImplementationSpecific:
+		// Kong treats paths holding regex characters as regular expressions
+		pathType = networkingv1.PathTypeImplementationSpecific
+	default:
+		return nil, "", fmt.Errorf("unknown path match type %q", match.Type)
+	}
+	value := match.Value
+	if value == "" {
+		value = "/"
+	}
+	if strings.Contains(value, "//") {
+		return nil, "", fmt.Errorf("invalid path: '%v'", value)
+	}
+	paths, err := pathsFromK8s(value, pathType)
+	return paths, pathType, err
+}
+
+// pathsFromK8s returns the paths of a Kong route matching path as pathType specifies.
+// Kong matches paths as prefixes of the request path, and as regular expressions
+// anchored at its start when they contain special characters, so:
+//   - Exact matches the escaped path, anchored at its end: /foo gives /foo$.
+//   - Prefix matches the escaped path element-wise: /foo gives /foo$ and /foo/,
+//     which match /foo and /foo/bar but not /foobar.
+//   - ImplementationSpecific passes the path to Kong as is, so that it is a prefix,
+//     or a regular expression if it contains special characters. An empty path is /.
 func pathsFromK8s(path string, pathType networkingv1.PathType) ([]*string, error) {
 	switch pathType {
 	case networkingv1.PathTypePrefix:
@@ -566,12 +757,13 @@ func pathsFromK8s(path string, pathType networkingv1.PathType) ([]*string, error
 		if base == "" {
 			return kong.StringSlice("/"), nil
 		}
+		base = regexp.QuoteMeta(base)
 		return kong.StringSlice(
 			"/"+base+"$",
 			"/"+base+"/",
 		), nil
 	case networkingv1.PathTypeExact:
-		relative := strings.TrimLeft(path, "/")
+		relative := regexp.QuoteMeta(strings.TrimLeft(path, "/"))
 		return kong.StringSlice("/" + relative + "$"), nil
 	case networkingv1.PathTypeImplementationSpecific:
 		if path == "" {
@@ -1,7 +1,9 @@
 package parser
 
 import (
+	"regexp"
 	"testing"
+	"time"
 
 	"github.com/kong/go-kong/kong"
 	"github.com/kong/kubernetes-ingress-controller/pkg/annotations"
@@ -15,6 +17,7 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/intstr"
 	knative "knative.dev/networking/pkg/apis/networking/v1alpha1"
+	gatewayv1alpha1 "sigs.k8s.io/gateway-api/apis/v1alpha1"
 )
 
 func TestFromIngressV1beta1(t *testing.T) {
@@ -776,7 +779,7 @@ func TestFromTCPIngressV1beta1(t *testing.T) {
 		route := svc.Routes[0]
 		assert.Equal(kong.Route{
 			Name:      kong.String("default.foo.0"),
-			Protocols: kong.StringSlice("tcp", "tls"),
+			Protocols: kong.StringSlice("tls"),
 			SNIs:      kong.StringSlice("example.com"),
 			Destinations: []*kong.CIDRPort{
 				{
@@ -791,6 +794,90 @@ func TestFromTCPIngressV1beta1(t *testing.T) {
 		assert.Equal(2, len(parsedInfo.SecretNameToSNIs["default/sooper-secret"]))
 		assert.Equal(2, len(parsedInfo.SecretNameToSNIs["default/sooper-secret2"]))
 	})
+	t.Run("TCPIngress rules with SNIs share a port", func(t *testing.T) {
+		tcpIngress := &configurationv1beta1.TCPIngress{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "sni",
+				Namespace: "default",
+			},
+			Spec: configurationv1beta1.IngressSpec{
+				Rules: []configurationv1beta1.IngressRule{
+					{
+						Host:    "foo.example.com",
+						Port:    9443,
+						Backend: configurationv1beta1.IngressBackend{ServiceName: "foo-svc", ServicePort: 80},
+					},
+					{
+						Host:    "bar.example.com",
+						Port:    9443,
+						Backend: configurationv1beta1.IngressBackend{ServiceName: "bar-svc", ServicePort: 80},
+					},
+				},
+				TLS: []configurationv1beta1.IngressTLS{
+					{
+						Hosts:      []string{"foo.example.com", "bar.example.com"},
+						SecretName: "sni-secret",
+					},
+				},
+			},
+		}
+		parsedInfo := fromTCPIngressV1beta1(logrus.New(), []*configurationv1beta1.TCPIngress{tcpIngress})
+		assert.Equal(2, len(parsedInfo.ServiceNameToServices))
+		assert.Equal([]string{"foo.example.com", "bar.example.com"}, parsedInfo.SecretNameToSNIs["default/sni-secret"])
+		for name, host := range map[string]string{
+			"default.foo-svc.80": "foo.example.com",
+			"default.bar-svc.80": "bar.example.com",
+		} {
+			svc := parsedInfo.ServiceNameToServices[name]
+			assert.Equal(1, len(svc.Routes))
+			assert.Equal(kong.StringSlice("tls"), svc.Routes[0].Protocols)
+			assert.Equal(kong.StringSlice(host), svc.Routes[0].SNIs)
+			assert.Equal([]*kong.CIDRPort{{Port: kong.Int(9443)}}, svc.Routes[0].Destinations)
+		}
+	})
+	t.Run("TCPIngress rules with and without SNI don't share a port", func(t *testing.T) {
+		rule := func(host string, port int, service string) configurationv1beta1.IngressRule {
+			return configurationv1beta1.IngressRule{
+				Host:    host,
+				Port:    port,
+				Backend: configurationv1beta1.IngressBackend{ServiceName: service, ServicePort: 80},
+			}
+		}
+		older := &configurationv1beta1.TCPIngress{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:              "older",
+				Namespace:         "default",
+				CreationTimestamp: metav1.NewTime(time.Now().Add(-time.Hour)),
+			},
+			Spec: configurationv1beta1.IngressSpec{
+				Rules: []configurationv1beta1.IngressRule{
+					rule("foo.example.com", 9443, "foo-svc"),
+					rule("", 9000, "foo-svc"),
+				},
+			},
+		}
+		newer := &configurationv1beta1.TCPIngress{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:              "newer",
+				Namespace:         "default",
+				CreationTimestamp: metav1.NewTime(time.Now()),
+			},
+			Spec: configurationv1beta1.IngressSpec{
+				Rules: []configurationv1beta1.IngressRule{
+					rule("", 9443, "bar-svc"),
+					rule("bar.example.com", 9000, "bar-svc"),
+					rule("bar.example.com", 9444, "bar-svc"),
+				},
+			},
+		}
+		parsedInfo := fromTCPIngressV1beta1(logrus.New(), []*configurationv1beta1.TCPIngress{newer, older})
+		assert.Equal(2, len(parsedInfo.ServiceNameToServices))
+		assert.Equal(2, len(parsedInfo.ServiceNameToServices["default.foo-svc.80"].Routes))
+		// only the rule of the newer TCPIngress on a port of its own is kept
+		routes := parsedInfo.ServiceNameToServices["default.bar-svc.80"].Routes
+		assert.Equal(1, len(routes))
+		assert.Equal("default.newer.2", *routes[0].Name)
+	})
 }
 
 func TestFromKnativeIngress(t *testing.T) {
@@ -1033,6 +1120,129 @@ func TestFromKnativeIngress(t *testing.T) {
 	})
 }
 
+func TestFromHTTPRouteV1Alpha1(t *testing.T) {
+	assert := assert.New(t)
+	port := func(p gatewayv1alpha1.PortNumber) *gatewayv1alpha1.PortNumber { return &p }
+
+	t.Run("no route returns empty info", func(t *testing.T) {
+		parsedInfo := fromHTTPRouteV1Alpha1(logrus.New(), nil)
+		assert.Equal(newIngressRules(), parsedInfo)
+	})
+	t.Run("basic route is translated", func(t *testing.T) {
+		route := &gatewayv1alpha1.HTTPRoute{
+			ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "foo-namespace"},
+			Spec: gatewayv1alpha1.HTTPRouteSpec{
+				Hostnames: []gatewayv1alpha1.Hostname{"example.com", "*.example.net"},
+				Rules: []gatewayv1alpha1.HTTPRouteRule{{
+					Matches: []gatewayv1alpha1.HTTPRouteMatch{
+						{Path: gatewayv1alpha1.HTTPPathMatch{Type: gatewayv1alpha1.PathMatchPrefix, Value: "/api"}},
+						{
+							Path: gatewayv1alpha1.HTTPPathMatch{Type: gatewayv1alpha1.PathMatchExact, Value: "/status"},
+							Headers: &gatewayv1alpha1.HTTPHeaderMatch{
+								Type:   gatewayv1alpha1.HeaderMatchExact,
+								Values: map[string]string{"x-debug": "1"},
+							},
+						},
+					},
+					ForwardTo: []gatewayv1alpha1.HTTPRouteForwardTo{{ServiceName: kong.String("foo-svc"), Port: port(8080)}},
+				}},
+			},
+		}
+		parsedInfo := fromHTTPRouteV1Alpha1(logrus.New(), []*gatewayv1alpha1.HTTPRoute{route})
+
+		assert.Equal(1, len(parsedInfo.ServiceNameToServices))
+		svc := parsedInfo.ServiceNameToServices["foo-namespace.foo-svc.8080"]
+		assert.Equal("foo-svc.foo-namespace.8080.svc", *svc.Host)
+		assert.Equal(kongstate.ServiceBackend{
+			Name: "foo-svc",
+			Port: kongstate.PortDef{Mode: kongstate.PortModeByNumber, Number: 8080},
+		}, svc.Backend)
+
+		assert.Equal(2, len(svc.Routes))
+		assert.Equal(kong.Route{
+			Name:          kong.String("foo-namespace.foo.00"),
+			Hosts:         kong.StringSlice("example.com", "*.example.net"),
+			Paths:         kong.StringSlice("/api$", "/api/"),
+			StripPath:     kong.Bool(false),
+			PreserveHost:  kong.Bool(true),
+			Protocols:     kong.StringSlice("http", "https"),
+			RegexPriority: kong.Int(200),
+		}, svc.Routes[0].Route)
+		assert.Equal(kong.Route{
+			Name:          kong.String("foo-namespace.foo.01"),
+			Hosts:         kong.StringSlice("example.com", "*.example.net"),
+			Paths:         kong.StringSlice("/status$"),
+			Headers:       map[string][]string{"x-debug": {"1"}},
+			StripPath:     kong.Bool(false),
+			PreserveHost:  kong.Bool(true),
+			Protocols:     kong.StringSlice("http", "https"),
+			RegexPriority: kong.Int(300),
+		}, svc.Routes[1].Route)
+		assert.Equal("foo", svc.Routes[0].Ingress.Name)
+	})
+	t.Run("defaults match all requests", func(t *testing.T) {
+		route := &gatewayv1alpha1.HTTPRoute{
+			ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "foo-namespace"},
+			Spec: gatewayv1alpha1.HTTPRouteSpec{
+				Hostnames: []gatewayv1alpha1.Hostname{"*"},
+				Rules: []gatewayv1alpha1.HTTPRouteRule{{
+					ForwardTo: []gatewayv1alpha1.HTTPRouteForwardTo{{ServiceName: kong.String("foo-svc"), Port: port(80)}},
+				}},
+			},
+		}
+		parsedInfo := fromHTTPRouteV1Alpha1(logrus.New(), []*gatewayv1alpha1.HTTPRoute{route})
+		routes := parsedInfo.ServiceNameToServices["foo-namespace.foo-svc.80"].Routes
+		assert.Equal(1, len(routes))
+		assert.Nil(routes[0].Hosts)
+		assert.Equal(kong.StringSlice("/"), routes[0].Paths)
+	})
+	t.Run("the backend with the highest weight is used", func(t *testing.T) {
+		route := &gatewayv1alpha1.HTTPRoute{
+			ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "foo-namespace"},
+			Spec: gatewayv1alpha1.HTTPRouteSpec{
+				Rules: []gatewayv1alpha1.HTTPRouteRule{{
+					ForwardTo: []gatewayv1alpha1.HTTPRouteForwardTo{
+						{ServiceName: kong.String("light-svc"), Port: port(80), Weight: 1},
+						{ServiceName: kong.String("heavy-svc"), Port: port(80), Weight: 9},
+						{ServiceName: kong.String("portless-svc"), Weight: 10},
+					},
+				}},
+			},
+		}
+		parsedInfo := fromHTTPRouteV1Alpha1(logrus.New(), []*gatewayv1alpha1.HTTPRoute{route})
+		assert.Equal(1, len(parsedInfo.ServiceNameToServices))
+		assert.Contains(parsedInfo.ServiceNameToServices, "foo-namespace.heavy-svc.80")
+	})
+	t.Run("unsupported matches and rules are skipped", func(t *testing.T) {
+		route := &gatewayv1alpha1.HTTPRoute{
+			ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "foo-namespace"},
+			Spec: gatewayv1alpha1.HTTPRouteSpec{
+				Rules: []gatewayv1alpha1.HTTPRouteRule{
+					{
+						Matches: []gatewayv1alpha1.HTTPRouteMatch{
+							{Headers: &gatewayv1alpha1.HTTPHeaderMatch{
+								Type:   gatewayv1alpha1.HeaderMatchRegularExpression,
+								Values: map[string]string{"x-debug": ".*"},
+							}},
+							{Path: gatewayv1alpha1.HTTPPathMatch{Value: "/ok"}},
+						},
+						ForwardTo: []gatewayv1alpha1.HTTPRouteForwardTo{{ServiceName: kong.String("foo-svc"), Port: port(80)}},
+					},
+					{
+						ForwardTo: []gatewayv1alpha1.HTTPRouteForwardTo{{ServiceName: kong.String("bar-svc")}},
+					},
+				},
+			},
+		}
+		parsedInfo := fromHTTPRouteV1Alpha1(logrus.New(), []*gatewayv1alpha1.HTTPRoute{route})
+		assert.Equal(1, len(parsedInfo.ServiceNameToServices))
+		routes := parsedInfo.ServiceNameToServices["foo-namespace.foo-svc.80"].Routes
+		assert.Equal(1, len(routes))
+		assert.Equal("foo-namespace.foo.01", *routes[0].Name)
+		assert.Equal(kong.StringSlice("/ok$", "/ok/"), routes[0].Paths)
+	})
+}
+
 func TestPathsFromK8s(t *testing.T) {
 	for _, tt := range []struct {
 		name         string
@@ -1082,6 +1292,13 @@ func TestPathsFromK8s(t *testing.T) {
 			wantExact:    kong.StringSlice("/foo/bar/$"),
 			wantImplSpec: kong.StringSlice("/foo/bar/"),
 		},
+		{
+			name:         "special characters",
+			path:         "/foo.bar/v1+",
+			wantPrefix:   kong.StringSlice(`/foo\.bar/v1\+$`, `/foo\.bar/v1\+/`),
+			wantExact:    kong.StringSlice(`/foo\.bar/v1\+$`),
+			wantImplSpec: kong.StringSlice("/foo.bar/v1+"),
+		},
 	} {
 		t.Run(tt.name, func(t *testing.T) {
 			{
@@ -1102,3 +1319,77 @@ func TestPathsFromK8s(t *testing.T) {
 		})
 	}
 }
+
+func TestPathsFromK8sMatching(t *testing.T) {
+	// matches tells whether Kong routes a request for requestPath to one of paths,
+	// all of which are regular expressions anchored at the start of the request path
+	matches := func(paths []*string, requestPath string) bool {
+		for _, path := range paths {
+			if regexp.MustCompile("^" + *path).MatchString(requestPath) {
+				return true
+			}
+		}
+		return false
+	}
+
+	for _, tt := range []struct {
+		name     string
+		path     string
+		pathType networkingv1.PathType
+		match    []string
+		noMatch  []string
+	}{
+		{
+			name:     "exact",
+			path:     "/foo",
+			pathType: networkingv1.PathTypeExact,
+			match:    []string{"/foo"},
+			noMatch:  []string{"/foo/", "/foo/bar", "/foobar", "/"},
+		},
+		{
+			name:     "prefix",
+			path:     "/foo",
+			pathType: networkingv1.PathTypePrefix,
+			match:    []string{"/foo", "/foo/", "/foo/bar"},
+			noMatch:  []string{"/foobar", "/fo", "/"},
+		},
+		{
+			name:     "prefix with trailing slash",
+			path:     "/foo/",
+			pathType: networkingv1.PathTypePrefix,
+			match:    []string{"/foo", "/foo/", "/foo/bar"},
+			noMatch:  []string{"/foobar"},
+		},
+		{
+			name:     "prefix with special characters",
+			path:     "/v1.0",
+			pathType: networkingv1.PathTypePrefix,
+			match:    []string{"/v1.0", "/v1.0/users"},
+			noMatch:  []string{"/v1x0", "/v1.00"},
+		},
+		{
+			name:     "root prefix",
+			path:     "/",
+			pathType: networkingv1.PathTypePrefix,
+			match:    []string{"/", "/foo", "/foo/bar"},
+		},
+		{
+			name:     "implementation specific",
+			path:     "/foo",
+			pathType: networkingv1.PathTypeImplementationSpecific,
+			match:    []string{"/foo", "/foo/bar", "/foobar"},
+			noMatch:  []string{"/fo"},
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			paths, err := pathsFromK8s(tt.path, tt.pathType)
+			require.NoError(t, err)
+			for _, requestPath := range tt.match {
+				assert.True(t, matches(paths, requestPath), "%s must match", requestPath)
+			}
+			for _, requestPath := range tt.noMatch {
+				assert.False(t, matches(paths, requestPath), "%s must not match", requestPath)
+			}
+		})
+	}
+}
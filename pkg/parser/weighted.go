@@ -0,0 +1,134 @@
+package parser
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/kong/go-kong/kong"
+	"github.com/kong/kubernetes-ingress-controller/pkg/annotations"
+	"github.com/kong/kubernetes-ingress-controller/pkg/kongstate"
+	"github.com/kong/kubernetes-ingress-controller/pkg/store"
+	"github.com/sirupsen/logrus"
+)
+
+// weightedTargetsTotal is the total weight the targets of an upstream with weighted backends share.
+const weightedTargetsTotal = 10000
+
+// weightedBackend is a Service an upstream distributes traffic to, along with its share of the traffic.
+type weightedBackend struct {
+	name   string
+	weight int
+}
+
+// parseWeightedBackends parses the value of the konghq.com/weighted-backends annotation:
+// a comma-separated list of name=weight pairs, naming Services with a non-negative weight.
+func parseWeightedBackends(value string) ([]weightedBackend, error) {
+	var backends []weightedBackend
+	seen := map[string]bool{}
+	for _, pair := range strings.Split(value, ",") {
+		parts := strings.SplitN(pair, "=", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("invalid backend %q: must be <service>=<weight>", strings.TrimSpace(pair))
+		}
+		name := strings.TrimSpace(parts[0])
+		if name == "" {
+			return nil, fmt.Errorf("invalid backend %q: the service name is empty", strings.TrimSpace(pair))
+		}
+		if seen[name] {
+			return nil, fmt.Errorf("service %s is listed more than once", name)
+		}
+		seen[name] = true
+		weight, err := strconv.Atoi(strings.TrimSpace(parts[1]))
+		if err != nil || weight < 0 {
+			return nil, fmt.Errorf("invalid weight of service %s: must be a non-negative integer", name)
+		}
+		backends = append(backends, weightedBackend{name: name, weight: weight})
+	}
+	return backends, nil
+}
+
+// getWeightedTargets returns the targets of the upstream of service, whose Kubernetes Service distributes
+// traffic across the Services listed in its konghq.com/weighted-backends annotation, value. Each of them
+// is reached on the port the rule of service uses, and gets the share of the traffic of its weight among
+// the Services which have endpoints, split evenly across its endpoints. Services weighing 0 get no
+// traffic at all.
+func getWeightedTargets(log logrus.FieldLogger, s store.Storer, service kongstate.Service,
+	value string) ([]kongstate.Target, error) {
+	backends, err := parseWeightedBackends(value)
+	if err != nil {
+		return nil, err
+	}
+
+	type backendTargets struct {
+		weight  int
+		targets []kongstate.Target
+	}
+	var weighted []backendTargets
+	total := 0
+	for _, backend := range backends {
+		if backend.weight == 0 {
+			continue
+		}
+		log := log.WithField("backend_service_name", backend.name)
+		k8sService := &service.K8sService
+		if backend.name != k8sService.Name {
+			if k8sService, err = s.GetService(service.Namespace, backend.name); err != nil {
+				log.Warnf("skipping weighted backend - failed to fetch service: %v", err)
+				continue
+			}
+		}
+		port, err := findPort(k8sService, service.Backend.Port)
+		if err != nil {
+			log.Warnf("skipping weighted backend - getServiceEndpoints failed: %v", err)
+			continue
+		}
+		targets := getServiceEndpoints(log, s, *k8sService, port)
+		if len(targets) == 0 {
+			continue
+		}
+		weighted = append(weighted, backendTargets{weight: backend.weight, targets: targets})
+		total += backend.weight
+	}
+
+	// the weights of the targets of a Service add up to its share of weightedTargetsTotal; targets
+	// shared by several Services add up their weights
+	var targets []kongstate.Target
+	index := map[string]int{}
+	for _, backend := range weighted {
+		share := float64(weightedTargetsTotal) * float64(backend.weight) / float64(total)
+		weight := int(math.Max(1, math.Round(share/float64(len(backend.targets)))))
+		for _, target := range backend.targets {
+			if i, ok := index[*target.Target.Target]; ok {
+				*targets[i].Weight += weight
+				continue
+			}
+			index[*target.Target.Target] = len(targets)
+			target.Weight = kong.Int(weight)
+			targets = append(targets, target)
+		}
+	}
+	return targets, nil
+}
+
+// serviceTargets returns the targets of the upstream of service: the endpoints of its Kubernetes
+// Service, or of the Services it distributes traffic to if it is annotated with konghq.com/weighted-backends.
+func serviceTargets(log logrus.FieldLogger, s store.Storer, service kongstate.Service) []kongstate.Target {
+	log = log.WithField("service_name", *service.Name)
+	if value, ok := annotations.ExtractWeightedBackends(service.K8sService.Annotations); ok {
+		targets, err := getWeightedTargets(log, s, service, value)
+		if err == nil {
+			return targets
+		}
+		log.Errorf("ignoring invalid weighted backends annotation: %v", err)
+		kongstate.ObserveTranslationFailure("Service", kongstate.TranslationFailureInvalidAnnotation)
+	}
+
+	port, err := findPort(&service.K8sService, service.Backend.Port)
+	if err != nil {
+		log.Warnf("skipping service - getServiceEndpoints failed: %v", err)
+		return nil
+	}
+	return getServiceEndpoints(log, s, service.K8sService, port)
+}
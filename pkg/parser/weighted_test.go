@@ -0,0 +1,158 @@
+package parser
+
+import (
+	"testing"
+
+	"github.com/kong/kubernetes-ingress-controller/pkg/annotations"
+	"github.com/kong/kubernetes-ingress-controller/pkg/store"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestParseWeightedBackends(t *testing.T) {
+	for _, tt := range []struct {
+		value   string
+		want    []weightedBackend
+		wantErr string
+	}{
+		{
+			value: "foo=90,foo-canary=10",
+			want:  []weightedBackend{{name: "foo", weight: 90}, {name: "foo-canary", weight: 10}},
+		},
+		{
+			value: " foo = 1 , foo-old=0",
+			want:  []weightedBackend{{name: "foo", weight: 1}, {name: "foo-old", weight: 0}},
+		},
+		{value: "foo", wantErr: `invalid backend "foo": must be <service>=<weight>`},
+		{value: "=10", wantErr: `invalid backend "=10": the service name is empty`},
+		{value: "foo=-1", wantErr: "invalid weight of service foo: must be a non-negative integer"},
+		{value: "foo=ten", wantErr: "invalid weight of service foo: must be a non-negative integer"},
+		{value: "foo=1,foo=2", wantErr: "service foo is listed more than once"},
+	} {
+		t.Run(tt.value, func(t *testing.T) {
+			backends, err := parseWeightedBackends(tt.value)
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, backends)
+		})
+	}
+}
+
+func TestWeightedBackends(t *testing.T) {
+	service := func(name string, anns map[string]string) *corev1.Service {
+		return &corev1.Service{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default", Annotations: anns},
+			Spec:       corev1.ServiceSpec{Ports: []corev1.ServicePort{{Name: "http", Port: 80}}},
+		}
+	}
+	endpoints := func(name string, ips ...string) *corev1.Endpoints {
+		var addresses []corev1.EndpointAddress
+		for _, ip := range ips {
+			addresses = append(addresses, corev1.EndpointAddress{IP: ip})
+		}
+		return &corev1.Endpoints{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+			Subsets: []corev1.EndpointSubset{{
+				Addresses: addresses,
+				Ports:     []corev1.EndpointPort{{Name: "http", Port: 8080, Protocol: corev1.ProtocolTCP}},
+			}},
+		}
+	}
+	ingress := &networkingv1.Ingress{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "foo",
+			Namespace:   "default",
+			Annotations: map[string]string{annotations.IngressClassKey: annotations.DefaultIngressClass},
+		},
+		Spec: networkingv1.IngressSpec{
+			Rules: []networkingv1.IngressRule{{
+				Host: "example.com",
+				IngressRuleValue: networkingv1.IngressRuleValue{HTTP: &networkingv1.HTTPIngressRuleValue{
+					Paths: []networkingv1.HTTPIngressPath{{
+						Path: "/",
+						Backend: networkingv1.IngressBackend{Service: &networkingv1.IngressServiceBackend{
+							Name: "foo",
+							Port: networkingv1.ServiceBackendPort{Number: 80},
+						}},
+					}},
+				}},
+			}},
+		},
+	}
+
+	for _, tt := range []struct {
+		name        string
+		annotation  string
+		wantTargets map[string]int
+	}{
+		{
+			name:       "weights are split across the endpoints of each service",
+			annotation: "foo=3,foo-canary=1,foo-old=0",
+			wantTargets: map[string]int{
+				"10.0.0.1:8080": 7500,
+				"10.0.1.1:8080": 1250,
+				"10.0.1.2:8080": 1250,
+			},
+		},
+		{
+			name:       "services without endpoints are left out",
+			annotation: "foo=50,foo-canary=25,foo-idle=25",
+			wantTargets: map[string]int{
+				"10.0.0.1:8080": 6667,
+				"10.0.1.1:8080": 1667,
+				"10.0.1.2:8080": 1667,
+			},
+		},
+		{
+			name:       "the annotated service can be left out",
+			annotation: "foo-canary=1",
+			wantTargets: map[string]int{
+				"10.0.1.1:8080": 5000,
+				"10.0.1.2:8080": 5000,
+			},
+		},
+		{
+			name:        "invalid annotations are ignored",
+			annotation:  "foo-canary",
+			wantTargets: map[string]int{"10.0.0.1:8080": 0},
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := store.NewFakeStore(store.FakeObjects{
+				IngressesV1: []*networkingv1.Ingress{ingress},
+				Services: []*corev1.Service{
+					service("foo", map[string]string{"konghq.com/weighted-backends": tt.annotation}),
+					service("foo-canary", nil),
+					service("foo-old", nil),
+					service("foo-idle", nil),
+				},
+				Endpoints: []*corev1.Endpoints{
+					endpoints("foo", "10.0.0.1"),
+					endpoints("foo-canary", "10.0.1.1", "10.0.1.2"),
+					endpoints("foo-old", "10.0.2.1"),
+				},
+			})
+			require.NoError(t, err)
+			state, err := Build(logrus.New(), s)
+			require.NoError(t, err)
+			require.Len(t, state.Upstreams, 1)
+
+			targets := map[string]int{}
+			for _, target := range state.Upstreams[0].Targets {
+				weight := 0
+				if target.Weight != nil {
+					weight = *target.Weight
+				}
+				targets[*target.Target.Target] = weight
+			}
+			assert.Equal(t, tt.wantTargets, targets)
+		})
+	}
+}
@@ -0,0 +1,73 @@
+package sendconfig
+
+import (
+	"io"
+	"net/http"
+	"sync"
+)
+
+// LimitConcurrency returns a copy of client which has at most n requests in
+// flight at any time, across every Kong client built from it. A request is in
+// flight until its response body is read to the end or closed; requests beyond
+// the limit wait for a slot, or for their context to be done, rather than failing.
+func LimitConcurrency(client *http.Client, n int) *http.Client {
+	if n < 1 {
+		n = 1
+	}
+	rt := client.Transport
+	if rt == nil {
+		rt = http.DefaultTransport
+	}
+	limited := *client
+	limited.Transport = &limitedRoundTripper{
+		slots: make(chan struct{}, n),
+		rt:    rt,
+	}
+	return &limited
+}
+
+// limitedRoundTripper bounds the number of concurrent requests made via rt.
+type limitedRoundTripper struct {
+	slots chan struct{}
+	rt    http.RoundTripper
+}
+
+// RoundTrip satisfies the RoundTripper interface.
+func (l *limitedRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	select {
+	case l.slots <- struct{}{}:
+	case <-req.Context().Done():
+		return nil, req.Context().Err()
+	}
+	release := func() { <-l.slots }
+
+	resp, err := l.rt.RoundTrip(req)
+	if err != nil {
+		release()
+		return nil, err
+	}
+	resp.Body = &releasingBody{ReadCloser: resp.Body, release: release}
+	return resp, nil
+}
+
+// releasingBody frees the slot of its request once the body is read to the
+// end or closed; go-kong doesn't close the bodies of error responses.
+type releasingBody struct {
+	io.ReadCloser
+	release func()
+	once    sync.Once
+}
+
+func (b *releasingBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if err == io.EOF {
+		b.once.Do(b.release)
+	}
+	return n, err
+}
+
+func (b *releasingBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.once.Do(b.release)
+	return err
+}
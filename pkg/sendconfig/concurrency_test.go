@@ -0,0 +1,91 @@
+package sendconfig
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/kong/go-kong/kong"
+	"github.com/stretchr/testify/assert"
+)
+
+// concurrencyRecorder is a stub Admin API holding every request for a while
+// and recording how many of them were in flight at once.
+type concurrencyRecorder struct {
+	lock        sync.Mutex
+	inFlight    int
+	maxInFlight int
+	creates     int
+}
+
+func (c *concurrencyRecorder) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	c.lock.Lock()
+	c.inFlight++
+	if c.inFlight > c.maxInFlight {
+		c.maxInFlight = c.inFlight
+	}
+	c.lock.Unlock()
+	defer func() {
+		c.lock.Lock()
+		c.inFlight--
+		c.lock.Unlock()
+	}()
+
+	time.Sleep(10 * time.Millisecond)
+	w.Header().Set("content-type", "application/json")
+	switch r.Method {
+	case http.MethodGet:
+		_, _ = w.Write([]byte(`{"data":[],"next":null}`))
+	case http.MethodDelete:
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		var entity map[string]interface{}
+		_ = json.NewDecoder(r.Body).Decode(&entity)
+		c.lock.Lock()
+		c.creates++
+		c.lock.Unlock()
+		w.WriteHeader(http.StatusCreated)
+		_ = json.NewEncoder(w).Encode(entity)
+	}
+}
+
+func TestLimitConcurrency(t *testing.T) {
+	recorder := &concurrencyRecorder{}
+	server := httptest.NewServer(recorder)
+	defer server.Close()
+
+	// Kong clients built from the limited client share its limit
+	limited := LimitConcurrency(server.Client(), 2)
+	var clients []*kong.Client
+	for i := 0; i < 2; i++ {
+		client, err := kong.NewClient(kong.String(server.URL), limited)
+		assert.NoError(t, err)
+		clients = append(clients, client)
+	}
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 10)
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, err := clients[i%2].Services.Create(context.Background(), &kong.Service{
+				Name: kong.String(fmt.Sprintf("service-%d", i)),
+				Host: kong.String("example.com"),
+			})
+			errs <- err
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		assert.NoError(t, err)
+	}
+	assert.Equal(t, 10, recorder.creates)
+	assert.LessOrEqual(t, recorder.maxInFlight, 2)
+}
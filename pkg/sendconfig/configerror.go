@@ -0,0 +1,252 @@
+package sendconfig
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	deckutils "github.com/kong/deck/utils"
+)
+
+// maxErrorBodySize caps the size of the error responses kept by KeepErrorBodies.
+const maxErrorBodySize = 1 << 20
+
+// ConfigRejectedError is returned when Kong rejects a configuration posted to
+// its DB-less /config endpoint as a whole, because some of its entities are
+// invalid.
+type ConfigRejectedError struct {
+	// Err is the error returned by the Admin API client.
+	Err error
+	// Entities are the entities of the configuration Kong reported as invalid.
+	// It is empty if the response of Kong couldn't be read.
+	Entities []RejectedEntity
+}
+
+func (e *ConfigRejectedError) Error() string {
+	if len(e.Entities) == 0 {
+		return e.Err.Error()
+	}
+	paths := make([]string, 0, len(e.Entities))
+	for _, entity := range e.Entities {
+		paths = append(paths, entity.Path)
+	}
+	return fmt.Sprintf("%v (invalid entities: %s)", e.Err, strings.Join(paths, ", "))
+}
+
+func (e *ConfigRejectedError) Unwrap() error {
+	return e.Err
+}
+
+// RejectedEntity is an entity of the configuration Kong reported as invalid.
+type RejectedEntity struct {
+	// Type is the collection holding the entity in the configuration, e.g. "routes".
+	Type string
+	// Path locates the entity in the configuration, e.g. "services[2].routes[0]".
+	Path string
+	// Name is the name of the entity, or the username of a consumer.
+	Name string
+	// Errors is a description of the invalid fields of the entity, as reported by Kong.
+	Errors string
+}
+
+// RejectedEntities returns the entities Kong reported as invalid in the
+// configuration rejected with err, which may hold the failures of several
+// Admin APIs.
+func RejectedEntities(err error) []RejectedEntity {
+	var errArray deckutils.ErrArray
+	if errors.As(err, &errArray) {
+		var entities []RejectedEntity
+		for _, err := range errArray.Errors {
+			entities = append(entities, RejectedEntities(err)...)
+		}
+		return entities
+	}
+	var rejected *ConfigRejectedError
+	if errors.As(err, &rejected) {
+		return rejected.Entities
+	}
+	return nil
+}
+
+// configErrorResponse is the body of the responses of Kong rejecting a
+// declarative configuration.
+type configErrorResponse struct {
+	Name    string                 `json:"name"`
+	Message string                 `json:"message"`
+	Fields  map[string]interface{} `json:"fields"`
+}
+
+// rejectedEntities maps the invalid fields reported by Kong in body to the
+// entities of config they belong to.
+func rejectedEntities(config, body []byte) ([]RejectedEntity, error) {
+	var response configErrorResponse
+	if err := json.Unmarshal(body, &response); err != nil {
+		return nil, fmt.Errorf("decoding the error response of kong: %w", err)
+	}
+	var content map[string]interface{}
+	if err := json.Unmarshal(config, &content); err != nil {
+		return nil, fmt.Errorf("decoding the configuration: %w", err)
+	}
+	var entities []RejectedEntity
+	collectRejectedEntities(content, response.Fields, "", &entities)
+	return entities, nil
+}
+
+// collectRejectedEntities walks the invalid fields of the entity at path
+// alongside the entity, appending the invalid entities it holds to entities.
+// Kong reports the invalid fields nested like the configuration, its entities
+// indexed from 1 by their position in their collection.
+func collectRejectedEntities(entity, fields map[string]interface{}, path string, entities *[]RejectedEntity) {
+	for _, collection := range sortedKeys(fields) {
+		items, ok := entity[collection].([]interface{})
+		if !ok || !isEntityList(items) {
+			continue
+		}
+		for _, index := range fieldIndexes(fields[collection]) {
+			if index < 1 || index > len(items) {
+				continue
+			}
+			item := items[index-1].(map[string]interface{})
+			itemFields, ok := fieldAt(fields[collection], index).(map[string]interface{})
+			if !ok {
+				continue
+			}
+			itemPath := fmt.Sprintf("%s[%d]", collection, index-1)
+			if path != "" {
+				itemPath = path + "." + itemPath
+			}
+
+			own := map[string]interface{}{}
+			for field, value := range itemFields {
+				if nested, ok := item[field].([]interface{}); !ok || !isEntityList(nested) {
+					own[field] = value
+				}
+			}
+			if len(own) > 0 {
+				description, _ := json.Marshal(own)
+				name, ok := item["name"].(string)
+				if !ok {
+					// consumers are named by their username
+					name, _ = item["username"].(string)
+				}
+				*entities = append(*entities, RejectedEntity{
+					Type:   collection,
+					Path:   itemPath,
+					Name:   name,
+					Errors: string(description),
+				})
+			}
+			collectRejectedEntities(item, itemFields, itemPath, entities)
+		}
+	}
+}
+
+// isEntityList tells whether items is a non-empty list of entities.
+func isEntityList(items []interface{}) bool {
+	for _, item := range items {
+		if _, ok := item.(map[string]interface{}); !ok {
+			return false
+		}
+	}
+	return len(items) > 0
+}
+
+// fieldIndexes returns the 1-based indexes of the entities reported in the
+// invalid fields of a collection, which Kong encodes either as an array or,
+// when sparse, as an object with numeric keys.
+func fieldIndexes(fields interface{}) []int {
+	var indexes []int
+	switch fields := fields.(type) {
+	case []interface{}:
+		for i, field := range fields {
+			if field != nil {
+				indexes = append(indexes, i+1)
+			}
+		}
+	case map[string]interface{}:
+		for key := range fields {
+			if index, err := strconv.Atoi(key); err == nil {
+				indexes = append(indexes, index)
+			}
+		}
+		sort.Ints(indexes)
+	}
+	return indexes
+}
+
+// fieldAt returns the invalid fields of the entity at the 1-based index of a collection.
+func fieldAt(fields interface{}, index int) interface{} {
+	switch fields := fields.(type) {
+	case []interface{}:
+		return fields[index-1]
+	case map[string]interface{}:
+		return fields[strconv.Itoa(index)]
+	}
+	return nil
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// errorBodyKey is the context key of the buffer receiving the body of an error response.
+type errorBodyKey struct{}
+
+// withErrorBody returns a copy of ctx whose requests made by a client built by
+// KeepErrorBodies copy the body of their error response to body.
+func withErrorBody(ctx context.Context, body *bytes.Buffer) context.Context {
+	return context.WithValue(ctx, errorBodyKey{}, body)
+}
+
+// KeepErrorBodies returns a copy of client which hands the body of the error
+// responses of Kong over to sendconfig, as go-kong only keeps their message.
+// It allows the entities of a configuration rejected by Kong to be reported.
+func KeepErrorBodies(client *http.Client) *http.Client {
+	rt := client.Transport
+	if rt == nil {
+		rt = http.DefaultTransport
+	}
+	keeping := *client
+	keeping.Transport = &errorBodyRoundTripper{rt: rt}
+	return &keeping
+}
+
+// errorBodyRoundTripper copies the body of the error responses of the
+// requests made via rt to the buffer in their context, if any.
+type errorBodyRoundTripper struct {
+	rt http.RoundTripper
+}
+
+// RoundTrip satisfies the RoundTripper interface.
+func (e *errorBodyRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := e.rt.RoundTrip(req)
+	if err != nil || resp.StatusCode < http.StatusBadRequest {
+		return resp, err
+	}
+	buffer, ok := req.Context().Value(errorBodyKey{}).(*bytes.Buffer)
+	if !ok {
+		return resp, nil
+	}
+	body, err := ioutil.ReadAll(io.LimitReader(resp.Body, maxErrorBodySize))
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	buffer.Reset()
+	buffer.Write(body)
+	resp.Body = ioutil.NopCloser(bytes.NewReader(body))
+	return resp, nil
+}
@@ -0,0 +1,125 @@
+package sendconfig
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/kong/deck/file"
+	"github.com/kong/go-kong/kong"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRejectedEntities(t *testing.T) {
+	config := []byte(`{
+		"services": [
+			{"name": "default.foo.80", "routes": [{"name": "default.foo.00"}]},
+			{"name": "default.bar.80", "routes": [{"name": "default.bar.00"}, {"name": "default.bar.01", "paths": ["bar"]}]}
+		],
+		"consumers": [{"username": "alice"}],
+		"plugins": [{"name": "key-auth", "config": {"key_names": ["apikey"]}}]
+	}`)
+
+	for _, tt := range []struct {
+		name string
+		body string
+		want []RejectedEntity
+	}{
+		{
+			name: "nested route",
+			body: `{"code":14,"name":"invalid declarative configuration","message":"declarative config is invalid",
+				"fields":{"services":[null,{"routes":[null,{"paths":["should start with: /"]}]}]}}`,
+			want: []RejectedEntity{{
+				Type:   "routes",
+				Path:   "services[1].routes[1]",
+				Name:   "default.bar.01",
+				Errors: `{"paths":["should start with: /"]}`,
+			}},
+		},
+		{
+			name: "sparse indexes",
+			body: `{"fields":{"services":{"2":{"port":"expected an integer","routes":{"1":{"name":"invalid"}}}}}}`,
+			want: []RejectedEntity{
+				{Type: "services", Path: "services[1]", Name: "default.bar.80", Errors: `{"port":"expected an integer"}`},
+				{Type: "routes", Path: "services[1].routes[0]", Name: "default.bar.00", Errors: `{"name":"invalid"}`},
+			},
+		},
+		{
+			name: "consumer and plugin",
+			body: `{"fields":{"consumers":[{"username":"duplicate"}],"plugins":[{"config":{"key_names":"unknown field"}}]}}`,
+			want: []RejectedEntity{
+				{Type: "consumers", Path: "consumers[0]", Name: "alice", Errors: `{"username":"duplicate"}`},
+				{Type: "plugins", Path: "plugins[0]", Name: "key-auth", Errors: `{"config":{"key_names":"unknown field"}}`},
+			},
+		},
+		{
+			name: "index out of range",
+			body: `{"fields":{"consumers":{"5":{"username":"duplicate"}}}}`,
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			entities, err := rejectedEntities(config, []byte(tt.body))
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, entities)
+		})
+	}
+}
+
+func TestPerformUpdateConfigRejected(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte(`{"code":14,"name":"invalid declarative configuration",` +
+			`"message":"declarative config is invalid: {routes={[1]={paths={\"should start with: /\"}}}}",` +
+			`"fields":{"services":[{"routes":[{"paths":["should start with: /"]}]}]}}`))
+	}))
+	defer server.Close()
+
+	content := &file.Content{
+		FormatVersion: "1.1",
+		Services: []file.FService{{
+			Service: kong.Service{Name: kong.String("default.foo.80")},
+			Routes: []*file.FRoute{{
+				Route: kong.Route{Name: kong.String("default.foo.00"), Paths: kong.StringSlice("foo")},
+			}},
+		}},
+	}
+
+	for _, tt := range []struct {
+		name string
+		keep bool
+		want []RejectedEntity
+	}{
+		{
+			name: "error bodies kept",
+			keep: true,
+			want: []RejectedEntity{{
+				Type:   "routes",
+				Path:   "services[0].routes[0]",
+				Name:   "default.foo.00",
+				Errors: `{"paths":["should start with: /"]}`,
+			}},
+		},
+		{
+			name: "error bodies dropped",
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			httpClient := server.Client()
+			if tt.keep {
+				httpClient = KeepErrorBodies(httpClient)
+			}
+			client, err := kong.NewClient(kong.String(server.URL), httpClient)
+			require.NoError(t, err)
+
+			_, err = PerformUpdate(context.Background(), logrus.New(), &Kong{URL: server.URL, Client: client}, true, false,
+				content, nil, nil, nil)
+			var rejected *ConfigRejectedError
+			require.ErrorAs(t, err, &rejected)
+			assert.Equal(t, tt.want, RejectedEntities(err))
+		})
+	}
+}
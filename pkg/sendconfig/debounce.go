@@ -0,0 +1,43 @@
+package sendconfig
+
+import (
+	"sync"
+	"time"
+)
+
+// Debouncer spaces configuration pushes at least a period apart. The first
+// push after a quiet period goes through right away; pushes requested within
+// the period are held back until it elapses, so that a burst of changes is
+// coalesced into a single trailing push of the latest configuration.
+// A nil *Debouncer never holds pushes back.
+type Debouncer struct {
+	period time.Duration
+	now    func() time.Time
+
+	lock sync.Mutex
+	last time.Time
+}
+
+// NewDebouncer returns a Debouncer allowing one push per period.
+// A period of 0 or less disables debouncing.
+func NewDebouncer(period time.Duration) *Debouncer {
+	return &Debouncer{period: period, now: time.Now}
+}
+
+// Reserve returns how long the caller has to wait before pushing. When it
+// returns 0 the push may proceed immediately and the period starts over;
+// otherwise the caller should ask again once the returned delay has passed.
+func (d *Debouncer) Reserve() time.Duration {
+	if d == nil || d.period <= 0 {
+		return 0
+	}
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	now := d.now()
+	if wait := d.last.Add(d.period).Sub(now); wait > 0 {
+		return wait
+	}
+	d.last = now
+	return 0
+}
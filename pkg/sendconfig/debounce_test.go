@@ -0,0 +1,39 @@
+package sendconfig
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDebouncer(t *testing.T) {
+	now := time.Unix(0, 0)
+	d := NewDebouncer(3 * time.Second)
+	d.now = func() time.Time { return now }
+
+	// leading edge: the first push goes through right away
+	assert.Equal(t, time.Duration(0), d.Reserve())
+
+	// pushes within the period wait for its end
+	now = now.Add(time.Second)
+	assert.Equal(t, 2*time.Second, d.Reserve())
+	now = now.Add(time.Second)
+	assert.Equal(t, time.Second, d.Reserve())
+
+	// trailing edge: once the period is over, one push goes through and
+	// starts a new period
+	now = now.Add(time.Second)
+	assert.Equal(t, time.Duration(0), d.Reserve())
+	assert.Equal(t, 3*time.Second, d.Reserve())
+}
+
+func TestDebouncerDisabled(t *testing.T) {
+	var nilDebouncer *Debouncer
+	assert.Equal(t, time.Duration(0), nilDebouncer.Reserve())
+
+	d := NewDebouncer(0)
+	for i := 0; i < 3; i++ {
+		assert.Equal(t, time.Duration(0), d.Reserve())
+	}
+}
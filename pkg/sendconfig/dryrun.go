@@ -0,0 +1,82 @@
+package sendconfig
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/kong/deck/crud"
+	"github.com/kong/deck/diff"
+	"github.com/kong/deck/file"
+	"github.com/kong/deck/state"
+	deckutils "github.com/kong/deck/utils"
+	"github.com/sirupsen/logrus"
+)
+
+// configDiff is the set of entities a sync would add to, remove from or
+// change in Kong.
+type configDiff struct {
+	Added   []string
+	Removed []string
+	Changed []string
+}
+
+// onUpdateDryRun logs the changes an update with targetContent would make to
+// every Admin API of kongConfig, without issuing any mutating call.
+func onUpdateDryRun(
+	log logrus.FieldLogger,
+	kongConfig *Kong,
+	targetContent *file.Content,
+	selectorTags []string,
+) error {
+	// building the target state alters it, diff a copy for every endpoint
+	config, err := json.Marshal(targetContent)
+	if err != nil {
+		return err
+	}
+
+	for _, endpoint := range kongConfig.endpoints() {
+		var content file.Content
+		if err := json.Unmarshal(config, &content); err != nil {
+			return fmt.Errorf("copying target configuration: %w", err)
+		}
+		changes, err := diffConfig(&content, kongConfig.forEndpoint(endpoint), selectorTags)
+		if err != nil {
+			return fmt.Errorf("computing configuration diff for %s: %w", endpoint.URL, err)
+		}
+		log.WithFields(logrus.Fields{
+			"kong_url": endpoint.URL,
+			"added":    changes.Added,
+			"removed":  changes.Removed,
+			"changed":  changes.Changed,
+		}).Info("dry run: configuration changes that would be applied to kong")
+	}
+	return nil
+}
+
+// diffConfig computes the changes syncing targetContent would make to Kong.
+// Kong is only read from.
+func diffConfig(targetContent *file.Content, kongConfig *Kong, selectorTags []string) (configDiff, error) {
+	syncer, _, err := newSyncer(targetContent, kongConfig, selectorTags)
+	if err != nil {
+		return configDiff{}, err
+	}
+
+	var changes configDiff
+	errs := syncer.Run(nil, 1, func(e diff.Event) (crud.Arg, error) {
+		entity := fmt.Sprintf("%s %s", e.Kind, e.Obj.(state.ConsoleString).Console())
+		switch e.Op {
+		case crud.Create:
+			changes.Added = append(changes.Added, entity)
+		case crud.Update:
+			changes.Changed = append(changes.Changed, entity)
+		case crud.Delete:
+			changes.Removed = append(changes.Removed, entity)
+		}
+		// hand the entity back as is, as if Kong accepted it
+		return e.Obj, nil
+	})
+	if errs != nil {
+		return configDiff{}, deckutils.ErrArray{Errors: errs}
+	}
+	return changes, nil
+}
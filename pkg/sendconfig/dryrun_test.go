@@ -0,0 +1,62 @@
+package sendconfig
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/kong/deck/file"
+	"github.com/kong/go-kong/kong"
+	"github.com/sirupsen/logrus"
+	logrustest "github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPerformUpdateDryRun(t *testing.T) {
+	var lock sync.Mutex
+	var mutatingCalls []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "GET" {
+			lock.Lock()
+			mutatingCalls = append(mutatingCalls, r.Method+" "+r.URL.Path)
+			lock.Unlock()
+		}
+		switch r.URL.Path {
+		case "/services":
+			_, _ = w.Write([]byte(`{"data":[{"id":"8b4d0e9c-6a1d-4fd5-9a9c-2bfc2b9b0d4e","name":"stale",` +
+				`"host":"example.com","tags":["managed-by-ingress-controller"]}],"next":null}`))
+		default:
+			_, _ = w.Write([]byte(`{"data":[],"next":null}`))
+		}
+	}))
+	defer server.Close()
+	client, err := kong.NewClient(kong.String(server.URL), server.Client())
+	assert.NoError(t, err)
+
+	log, hook := logrustest.NewNullLogger()
+	content := &file.Content{
+		FormatVersion: "1.1",
+		Services: []file.FService{
+			{Service: kong.Service{Name: kong.String("new"), Host: kong.String("example.com")}},
+		},
+	}
+	kongConfig := &Kong{URL: server.URL, Client: client, DryRun: true}
+
+	for _, inMemory := range []bool{true, false} {
+		hook.Reset()
+		_, err = PerformUpdate(context.Background(), log, kongConfig, inMemory, false, content,
+			[]string{"managed-by-ingress-controller"}, nil, nil)
+		assert.NoError(t, err)
+
+		assert.Empty(t, mutatingCalls)
+		entry := hook.LastEntry()
+		if assert.NotNil(t, entry) {
+			assert.Equal(t, logrus.InfoLevel, entry.Level)
+			assert.Equal(t, []string{"service new"}, entry.Data["added"])
+			assert.Equal(t, []string{"service stale"}, entry.Data["removed"])
+			assert.Empty(t, entry.Data["changed"])
+		}
+	}
+}
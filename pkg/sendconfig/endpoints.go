@@ -0,0 +1,83 @@
+package sendconfig
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	"github.com/kong/deck/file"
+	deckutils "github.com/kong/deck/utils"
+	"github.com/sirupsen/logrus"
+)
+
+// onUpdateAllEndpoints pushes the target configuration to every Admin API of
+// kongConfig concurrently, with at most kongConfig.Concurrency pushes in flight.
+// The update fails if it fails against any endpoint, so that the SHA of the
+// configuration isn't recorded as pushed and the next sync pushes it again;
+// failures on individual endpoints are logged along with their address.
+func onUpdateAllEndpoints(ctx context.Context,
+	log logrus.FieldLogger,
+	kongConfig *Kong,
+	inMemory bool,
+	targetContent *file.Content,
+	selectorTags []string,
+	customEntities []byte,
+) error {
+	// every endpoint gets its own copy of the target state, as building
+	// the state in DB mode is not safe for concurrent use
+	var config []byte
+	var err error
+	if inMemory {
+		config, err = renderInMemoryConfig(log, targetContent, customEntities)
+	} else {
+		config, err = json.Marshal(targetContent)
+	}
+	if err != nil {
+		return err
+	}
+
+	concurrency := kongConfig.Concurrency
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	sem := make(chan struct{}, concurrency)
+
+	endpoints := kongConfig.endpoints()
+	errs := make([]error, len(endpoints))
+	var wg sync.WaitGroup
+	for i, endpoint := range endpoints {
+		wg.Add(1)
+		go func(i int, endpoint Endpoint) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			endpointConfig := kongConfig.forEndpoint(endpoint)
+			if inMemory {
+				errs[i] = postInMemoryConfig(ctx, config, endpointConfig)
+				return
+			}
+			var content file.Content
+			if err := json.Unmarshal(config, &content); err != nil {
+				errs[i] = fmt.Errorf("copying target configuration: %w", err)
+				return
+			}
+			errs[i] = onUpdateDBMode(log, &content, endpointConfig, selectorTags)
+		}(i, endpoint)
+	}
+	wg.Wait()
+
+	var failed []error
+	for i, err := range errs {
+		observePush(endpoints[i].URL, err)
+		if err != nil {
+			log.WithField("kong_url", endpoints[i].URL).Errorf("failed to sync configuration: %v", err)
+			failed = append(failed, fmt.Errorf("%s: %w", endpoints[i].URL, err))
+		}
+	}
+	if len(failed) > 0 {
+		return deckutils.ErrArray{Errors: failed}
+	}
+	return nil
+}
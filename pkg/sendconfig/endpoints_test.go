@@ -0,0 +1,97 @@
+package sendconfig
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/kong/deck/file"
+	"github.com/kong/go-kong/kong"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func newStubAdminAPI(t *testing.T, status int, calls *int32) (*httptest.Server, Endpoint) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(calls, 1)
+		w.WriteHeader(status)
+	}))
+	client, err := kong.NewClient(kong.String(server.URL), server.Client())
+	assert.NoError(t, err)
+	return server, Endpoint{URL: server.URL, Client: client}
+}
+
+func TestOnUpdateAllEndpoints(t *testing.T) {
+	tests := []struct {
+		name     string
+		statuses []int
+		wantErr  bool
+	}{
+		{
+			name:     "all endpoints succeed",
+			statuses: []int{http.StatusCreated, http.StatusCreated, http.StatusCreated},
+		},
+		{
+			name:     "one endpoint returns 500 while others succeed",
+			statuses: []int{http.StatusCreated, http.StatusInternalServerError, http.StatusCreated},
+			wantErr:  true,
+		},
+		{
+			name:     "every endpoint returns 500",
+			statuses: []int{http.StatusInternalServerError, http.StatusInternalServerError},
+			wantErr:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls int32
+			var endpoints []Endpoint
+			for _, status := range tt.statuses {
+				server, endpoint := newStubAdminAPI(t, status, &calls)
+				defer server.Close()
+				endpoints = append(endpoints, endpoint)
+			}
+			kongConfig := &Kong{
+				URL:                 endpoints[0].URL,
+				Client:              endpoints[0].Client,
+				AdditionalEndpoints: endpoints[1:],
+				Concurrency:         2,
+			}
+			content := &file.Content{FormatVersion: "1.1"}
+
+			err := onUpdateAllEndpoints(context.Background(), logrus.New(), kongConfig, true, content, nil, nil)
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, int32(len(tt.statuses)), atomic.LoadInt32(&calls))
+		})
+	}
+}
+
+func TestPerformUpdatePartialFailureIsRetried(t *testing.T) {
+	var okCalls, failingCalls int32
+	ok, okEndpoint := newStubAdminAPI(t, http.StatusCreated, &okCalls)
+	defer ok.Close()
+	failing, failingEndpoint := newStubAdminAPI(t, http.StatusInternalServerError, &failingCalls)
+	defer failing.Close()
+	kongConfig := &Kong{
+		URL:                 okEndpoint.URL,
+		Client:              okEndpoint.Client,
+		AdditionalEndpoints: []Endpoint{failingEndpoint},
+		Concurrency:         2,
+	}
+	content := &file.Content{FormatVersion: "1.1"}
+
+	sha, err := PerformUpdate(context.Background(), logrus.New(), kongConfig, true, false, content, nil, nil, nil)
+	assert.Error(t, err)
+	assert.Nil(t, sha, "the configuration must not be recorded as pushed")
+
+	// the next sync of the same configuration reaches the endpoint which failed
+	_, err = PerformUpdate(context.Background(), logrus.New(), kongConfig, true, false, content, nil, nil, sha)
+	assert.Error(t, err)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&failingCalls))
+}
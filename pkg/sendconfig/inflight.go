@@ -0,0 +1,82 @@
+package sendconfig
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// InFlight tracks the configuration pushes to Kong which are in progress,
+// so that a shutting down process can wait for them to finish instead of
+// leaving Kong partially updated.
+type InFlight struct {
+	lock  sync.Mutex
+	count int
+	// idle is closed once the last push in progress finishes.
+	idle chan struct{}
+}
+
+// start records the start of a push. The returned function must be called
+// once the push finishes.
+func (f *InFlight) start() func() {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	if f.count == 0 {
+		f.idle = make(chan struct{})
+	}
+	f.count++
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			f.lock.Lock()
+			defer f.lock.Unlock()
+
+			f.count--
+			if f.count == 0 {
+				close(f.idle)
+			}
+		})
+	}
+}
+
+// Drain blocks until every push in progress finished or ctx is done.
+func (f *InFlight) Drain(ctx context.Context) error {
+	f.lock.Lock()
+	if f.count == 0 {
+		f.lock.Unlock()
+		return nil
+	}
+	idle := f.idle
+	f.lock.Unlock()
+
+	select {
+	case <-idle:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("waiting for in-flight configuration pushes: %w", ctx.Err())
+	}
+}
+
+// detachedContext carries the values of its parent but is never cancelled
+// along with it.
+type detachedContext struct {
+	parent context.Context
+}
+
+func (detachedContext) Deadline() (time.Time, bool)         { return time.Time{}, false }
+func (detachedContext) Done() <-chan struct{}               { return nil }
+func (detachedContext) Err() error                          { return nil }
+func (c detachedContext) Value(key interface{}) interface{} { return c.parent.Value(key) }
+
+// detach returns a context which is not cancelled when ctx is, but still
+// expires at the deadline of ctx, if any.
+func detach(ctx context.Context) (context.Context, context.CancelFunc) {
+	detached := context.Context(detachedContext{parent: ctx})
+	if deadline, ok := ctx.Deadline(); ok {
+		return context.WithDeadline(detached, deadline)
+	}
+	return context.WithCancel(detached)
+}
@@ -0,0 +1,66 @@
+package sendconfig
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/kong/deck/file"
+	"github.com/kong/go-kong/kong"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInFlight_DrainWaitsForSlowPush(t *testing.T) {
+	received := make(chan struct{})
+	var responded int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(received)
+		time.Sleep(200 * time.Millisecond)
+		atomic.StoreInt32(&responded, 1)
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer server.Close()
+	client, err := kong.NewClient(kong.String(server.URL), server.Client())
+	assert.NoError(t, err)
+
+	kongConfig := &Kong{
+		URL:      server.URL,
+		Client:   client,
+		InFlight: &InFlight{},
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	pushErr := make(chan error, 1)
+	go func() {
+		_, err := PerformUpdate(ctx, logrus.New(), kongConfig, true, false,
+			&file.Content{FormatVersion: "1.1"}, nil, nil, nil)
+		pushErr <- err
+	}()
+
+	// shut down while Kong is still processing the push
+	<-received
+	cancel()
+
+	drainCtx, drainCancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer drainCancel()
+	assert.NoError(t, kongConfig.InFlight.Drain(drainCtx))
+	assert.Equal(t, int32(1), atomic.LoadInt32(&responded), "drain returned before Kong responded to the push")
+	assert.NoError(t, <-pushErr)
+}
+
+func TestInFlight_DrainTimesOut(t *testing.T) {
+	var inFlight InFlight
+	assert.NoError(t, inFlight.Drain(context.Background()))
+
+	done := inFlight.start()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	assert.Error(t, inFlight.Drain(ctx))
+
+	done()
+	assert.NoError(t, inFlight.Drain(context.Background()))
+}
@@ -0,0 +1,34 @@
+package sendconfig
+
+import (
+	"math/rand"
+	"time"
+)
+
+// RetryJitter spreads the retries of failed pushes over time. When Kong is
+// unavailable every pending push fails at about the same time; delaying each
+// retry by the base delay plus a random jitter keeps them from all hitting
+// the Admin API at once when it recovers.
+// A nil *RetryJitter returns no delay.
+type RetryJitter struct {
+	base      time.Duration
+	maxJitter time.Duration
+}
+
+// NewRetryJitter returns a RetryJitter delaying retries by base plus up to maxJitter.
+func NewRetryJitter(base, maxJitter time.Duration) *RetryJitter {
+	return &RetryJitter{base: base, maxJitter: maxJitter}
+}
+
+// Delay returns how long to wait before retrying a failed push, between the
+// base delay and the base delay plus the maximum jitter. It returns 0 when
+// both are 0, in which case the caller should retry with its usual backoff.
+func (j *RetryJitter) Delay() time.Duration {
+	if j == nil {
+		return 0
+	}
+	if j.maxJitter <= 0 {
+		return j.base
+	}
+	return j.base + time.Duration(rand.Int63n(int64(j.maxJitter)+1)) //nolint:gosec
+}
@@ -0,0 +1,27 @@
+package sendconfig
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRetryJitter(t *testing.T) {
+	j := NewRetryJitter(2*time.Second, 3*time.Second)
+	delays := make(map[time.Duration]bool)
+	for i := 0; i < 50; i++ {
+		delay := j.Delay()
+		assert.GreaterOrEqual(t, int64(delay), int64(2*time.Second))
+		assert.LessOrEqual(t, int64(delay), int64(5*time.Second))
+		delays[delay] = true
+	}
+	assert.Greater(t, len(delays), 1, "retries failing together must not all be delayed alike")
+}
+
+func TestRetryJitterWithoutJitter(t *testing.T) {
+	var nilJitter *RetryJitter
+	assert.Equal(t, time.Duration(0), nilJitter.Delay())
+	assert.Equal(t, time.Duration(0), NewRetryJitter(0, 0).Delay())
+	assert.Equal(t, time.Second, NewRetryJitter(time.Second, 0).Delay())
+}
@@ -1,23 +1,101 @@
 package sendconfig
 
 import (
+	"context"
+	"fmt"
+	"net/http"
+
 	"github.com/blang/semver"
 	"github.com/kong/go-kong/kong"
 )
 
+// AdminAPIClient is the part of a Kong Admin API client the controller uses
+// to push configuration and check on Kong. *kong.Client satisfies it, and
+// sendconfigtest.FakeAdminAPI fakes a DB-less Kong in memory.
+//
+// Syncing a Kong backed by a database, or a dry run, goes through deck, which
+// requires the client to be a *kong.Client.
+type AdminAPIClient interface {
+	// NewRequest creates a request to endpoint, relative to the Admin API URL,
+	// with the query string qs and the JSON-encoded body.
+	NewRequest(method, endpoint string, qs interface{}, body interface{}) (*http.Request, error)
+	// Do sends req, decoding the JSON response into v if it isn't nil.
+	// Responses with an error status are returned along with an error.
+	Do(ctx context.Context, req *http.Request, v interface{}) (*kong.Response, error)
+	// Root returns the information Kong serves at the root of the Admin API.
+	Root(ctx context.Context) (map[string]interface{}, error)
+}
+
 // Kong Represents a Kong client and connection information
 type Kong struct {
 	URL        string
 	FilterTags []string
 	// Headers are injected into every request to Kong's Admin API
 	// to help with authorization/authentication.
-	Client *kong.Client
+	Client AdminAPIClient
+
+	// AdditionalEndpoints are the Admin APIs of further Kong instances which
+	// must receive the same configuration as the one at URL.
+	AdditionalEndpoints []Endpoint
+
+	// DryRun makes updates only log the changes they would make to Kong,
+	// without issuing any mutating Admin API call.
+	DryRun bool
+
+	// NoDelete tells updates of a Kong backed by a database are not to delete
+	// the entities which no longer correspond to a Kubernetes object.
+	NoDelete bool
+
+	// ManagedEntityTypes, if not empty, are the only types of entities, among
+	// EntityTypes, updates of a Kong backed by a database create, update and
+	// delete. The entities of other types are left untouched.
+	ManagedEntityTypes []string
 
 	InMemory      bool
 	HasTagSupport bool
 	Enterprise    bool
 
-	Version semver.Version
+	// Version is the version of Kong, which gates the features the configuration
+	// can use. It is the zero version, with which none is used, if Kong reported
+	// a version which can't be parsed. ReportedVersion is the version as reported.
+	Version         semver.Version
+	ReportedVersion string
 
 	Concurrency int
+
+	// InFlight, when set, tracks the pushes in progress so they can be
+	// drained on shutdown.
+	InFlight *InFlight
+}
+
+// Endpoint is a single Kong Admin API a configuration can be pushed to.
+type Endpoint struct {
+	URL    string
+	Client AdminAPIClient
+}
+
+// endpoints returns every Admin API the configuration is pushed to,
+// starting with the one at URL.
+func (k *Kong) endpoints() []Endpoint {
+	return append([]Endpoint{{URL: k.URL, Client: k.Client}}, k.AdditionalEndpoints...)
+}
+
+// forEndpoint returns a copy of the Kong configuration targeting only the
+// given endpoint.
+func (k *Kong) forEndpoint(endpoint Endpoint) *Kong {
+	copied := *k
+	copied.URL = endpoint.URL
+	copied.Client = endpoint.Client
+	copied.AdditionalEndpoints = nil
+	return &copied
+}
+
+// deckClient returns the client of k, for deck to read the state of Kong and
+// sync it.
+func (k *Kong) deckClient() (*kong.Client, error) {
+	client, ok := k.Client.(*kong.Client)
+	if !ok {
+		return nil, fmt.Errorf("syncing a kong backed by a database requires a *kong.Client, got %T", k.Client)
+	}
+	return client, nil
 }
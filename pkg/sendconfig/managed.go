@@ -0,0 +1,196 @@
+package sendconfig
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/kong/deck/file"
+	deckutils "github.com/kong/deck/utils"
+	"github.com/kong/go-kong/kong"
+)
+
+// The types of Kong entities the controller can be restricted to manage. The
+// entities attached to an entity, e.g. the targets of an upstream, are managed
+// along with it, except for plugins.
+const (
+	EntityTypeServices       = "services"
+	EntityTypeRoutes         = "routes"
+	EntityTypePlugins        = "plugins"
+	EntityTypeUpstreams      = "upstreams"
+	EntityTypeCertificates   = "certificates"
+	EntityTypeCACertificates = "ca_certificates"
+	EntityTypeConsumers      = "consumers"
+)
+
+// EntityTypes are the types of Kong entities the controller generates.
+var EntityTypes = []string{
+	EntityTypeServices,
+	EntityTypeRoutes,
+	EntityTypePlugins,
+	EntityTypeUpstreams,
+	EntityTypeCertificates,
+	EntityTypeCACertificates,
+	EntityTypeConsumers,
+}
+
+// ValidateManagedEntityTypes checks types, the types of Kong entities a controller
+// is restricted to manage. Routes are generated as part of their service, so
+// they can't be managed without services.
+func ValidateManagedEntityTypes(types []string) error {
+	if len(types) == 0 {
+		return fmt.Errorf("at least one entity type is required")
+	}
+	managed := make(map[string]bool, len(types))
+	for _, t := range types {
+		known := false
+		for _, entityType := range EntityTypes {
+			known = known || t == entityType
+		}
+		if !known {
+			return fmt.Errorf("unknown entity type %q, must be one of %s", t, strings.Join(EntityTypes, ", "))
+		}
+		managed[t] = true
+	}
+	if managed[EntityTypeRoutes] && !managed[EntityTypeServices] {
+		return fmt.Errorf("entity type %q requires %q", EntityTypeRoutes, EntityTypeServices)
+	}
+	return nil
+}
+
+// managedEntityTypes is the set of entity types a controller manages, every one
+// of them if it's nil.
+type managedEntityTypes map[string]bool
+
+// newManagedEntityTypes returns the set of types, or nil if types is empty.
+func newManagedEntityTypes(types []string) managedEntityTypes {
+	if len(types) == 0 {
+		return nil
+	}
+	managed := make(managedEntityTypes, len(types))
+	for _, t := range types {
+		managed[t] = true
+	}
+	return managed
+}
+
+func (m managedEntityTypes) has(entityType string) bool {
+	return m == nil || m[entityType]
+}
+
+// pluginManaged tells whether plugin, attached to the entities it references, is managed.
+func (m managedEntityTypes) pluginManaged(plugin *kong.Plugin) bool {
+	return m.has(EntityTypePlugins) &&
+		(plugin.Service == nil || m.has(EntityTypeServices)) &&
+		(plugin.Route == nil || m.has(EntityTypeRoutes)) &&
+		(plugin.Consumer == nil || m.has(EntityTypeConsumers))
+}
+
+// filterManagedEntities drops from rawState, the current state of Kong, every entity
+// whose type is not managed, so that syncing leaves it untouched.
+func filterManagedEntities(rawState *deckutils.KongRawState, managed managedEntityTypes) {
+	if managed == nil {
+		return
+	}
+	if !managed.has(EntityTypeServices) {
+		rawState.Services = nil
+	}
+	if !managed.has(EntityTypeRoutes) {
+		rawState.Routes = nil
+	}
+	if !managed.has(EntityTypeUpstreams) {
+		rawState.Upstreams = nil
+		rawState.Targets = nil
+	}
+	if !managed.has(EntityTypeCertificates) {
+		rawState.Certificates = nil
+		rawState.SNIs = nil
+	}
+	if !managed.has(EntityTypeCACertificates) {
+		rawState.CACertificates = nil
+	}
+	if !managed.has(EntityTypeConsumers) {
+		rawState.Consumers = nil
+		rawState.KeyAuths = nil
+		rawState.HMACAuths = nil
+		rawState.JWTAuths = nil
+		rawState.BasicAuths = nil
+		rawState.ACLGroups = nil
+		rawState.Oauth2Creds = nil
+		rawState.MTLSAuths = nil
+	}
+	var plugins []*kong.Plugin
+	for _, plugin := range rawState.Plugins {
+		if managed.pluginManaged(plugin) {
+			plugins = append(plugins, plugin)
+		}
+	}
+	rawState.Plugins = plugins
+}
+
+// managedContent returns a copy of content, the target configuration, without the entities whose
+// type is not managed. content itself is left as is.
+func managedContent(content *file.Content, managed managedEntityTypes) *file.Content {
+	if managed == nil {
+		return content
+	}
+	filtered := *content
+	filtered.Services = nil
+	if managed.has(EntityTypeServices) {
+		for _, s := range content.Services {
+			service := s
+			service.Routes = nil
+			if managed.has(EntityTypeRoutes) {
+				for _, r := range s.Routes {
+					route := *r
+					route.Plugins = managedPlugins(r.Plugins, managed)
+					service.Routes = append(service.Routes, &route)
+				}
+			}
+			service.Plugins = managedPlugins(s.Plugins, managed)
+			filtered.Services = append(filtered.Services, service)
+		}
+	}
+	filtered.Routes = nil
+	if managed.has(EntityTypeRoutes) {
+		for _, r := range content.Routes {
+			route := r
+			route.Plugins = managedPlugins(r.Plugins, managed)
+			filtered.Routes = append(filtered.Routes, route)
+		}
+	}
+	filtered.Consumers = nil
+	if managed.has(EntityTypeConsumers) {
+		for _, c := range content.Consumers {
+			consumer := c
+			consumer.Plugins = managedPlugins(c.Plugins, managed)
+			filtered.Consumers = append(filtered.Consumers, consumer)
+		}
+	}
+	filtered.Plugins = nil
+	for _, plugin := range content.Plugins {
+		if managed.pluginManaged(&plugin.Plugin) {
+			filtered.Plugins = append(filtered.Plugins, plugin)
+		}
+	}
+	if !managed.has(EntityTypeUpstreams) {
+		filtered.Upstreams = nil
+	}
+	if !managed.has(EntityTypeCertificates) {
+		filtered.Certificates = nil
+	}
+	if !managed.has(EntityTypeCACertificates) {
+		filtered.CACertificates = nil
+	}
+	return &filtered
+}
+
+// managedPlugins returns the managed plugins among plugins, those of an entity whose type is managed.
+func managedPlugins(plugins []*file.FPlugin, managed managedEntityTypes) []*file.FPlugin {
+	var filtered []*file.FPlugin
+	for _, plugin := range plugins {
+		if managed.pluginManaged(&plugin.Plugin) {
+			filtered = append(filtered, plugin)
+		}
+	}
+	return filtered
+}
@@ -0,0 +1,159 @@
+package sendconfig
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/kong/deck/file"
+	"github.com/kong/go-kong/kong"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateManagedEntityTypes(t *testing.T) {
+	for _, tt := range []struct {
+		name    string
+		types   []string
+		wantErr bool
+	}{
+		{name: "every type", types: EntityTypes},
+		{name: "services and routes", types: []string{"services", "routes"}},
+		{name: "upstreams only", types: []string{"upstreams"}},
+		{name: "none", wantErr: true},
+		{name: "unknown type", types: []string{"services", "workspaces"}, wantErr: true},
+		{name: "routes without services", types: []string{"routes", "plugins"}, wantErr: true},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateManagedEntityTypes(tt.types)
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestManagedContent(t *testing.T) {
+	content := &file.Content{
+		FormatVersion: "1.1",
+		Services: []file.FService{{
+			Service: kong.Service{Name: kong.String("foo")},
+			Routes: []*file.FRoute{{
+				Route: kong.Route{Name: kong.String("foo")},
+				Plugins: []*file.FPlugin{
+					{Plugin: kong.Plugin{Name: kong.String("cors")}},
+				},
+			}},
+			Plugins: []*file.FPlugin{
+				{Plugin: kong.Plugin{Name: kong.String("acl")}},
+				{Plugin: kong.Plugin{Name: kong.String("rate-limiting"), Consumer: &kong.Consumer{ID: kong.String("c")}}},
+			},
+		}},
+		Upstreams: []file.FUpstream{{Upstream: kong.Upstream{Name: kong.String("foo.default.80.svc")}}},
+		Consumers: []file.FConsumer{{Consumer: kong.Consumer{Username: kong.String("alice")}}},
+		Plugins: []file.FPlugin{
+			{Plugin: kong.Plugin{Name: kong.String("prometheus")}},
+			{Plugin: kong.Plugin{Name: kong.String("key-auth"), Route: &kong.Route{ID: kong.String("r")}}},
+		},
+	}
+
+	t.Run("every type is managed by default", func(t *testing.T) {
+		assert.Equal(t, content, managedContent(content, newManagedEntityTypes(nil)))
+	})
+
+	t.Run("unmanaged types are dropped", func(t *testing.T) {
+		filtered := managedContent(content, newManagedEntityTypes([]string{"services", "plugins"}))
+		if assert.Len(t, filtered.Services, 1) {
+			assert.Empty(t, filtered.Services[0].Routes)
+			if assert.Len(t, filtered.Services[0].Plugins, 1, "plugins of consumers are not managed") {
+				assert.Equal(t, "acl", *filtered.Services[0].Plugins[0].Name)
+			}
+		}
+		assert.Empty(t, filtered.Upstreams)
+		assert.Empty(t, filtered.Consumers)
+		if assert.Len(t, filtered.Plugins, 1, "plugins of routes are not managed") {
+			assert.Equal(t, "prometheus", *filtered.Plugins[0].Name)
+		}
+
+		// the original content is left as is
+		assert.Len(t, content.Services[0].Routes, 1)
+		assert.Len(t, content.Services[0].Plugins, 2)
+		assert.Len(t, content.Upstreams, 1)
+		assert.Len(t, content.Plugins, 2)
+	})
+}
+
+func TestPerformUpdateManagedEntityTypes(t *testing.T) {
+	var lock sync.Mutex
+	var mutatingCalls []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "GET" {
+			lock.Lock()
+			mutatingCalls = append(mutatingCalls, r.Method+" "+r.URL.Path)
+			lock.Unlock()
+			// created and updated entities are echoed back
+			body, _ := ioutil.ReadAll(r.Body)
+			w.WriteHeader(http.StatusCreated)
+			_, _ = w.Write(body)
+			return
+		}
+		switch r.URL.Path {
+		case "/upstreams":
+			// an upstream managed out of band, carrying the tags of the controller
+			_, _ = w.Write([]byte(`{"data":[` +
+				`{"id":"0f8c2f4e-2b86-4a53-9a3e-6f0d9c1b7a21","name":"foo.default.80.svc",` +
+				`"algorithm":"least-connections","tags":["managed-by-ingress-controller"]}` +
+				`],"next":null}`))
+		default:
+			_, _ = w.Write([]byte(`{"data":[],"next":null}`))
+		}
+	}))
+	defer server.Close()
+	client, err := kong.NewClient(kong.String(server.URL), server.Client())
+	require.NoError(t, err)
+
+	content := &file.Content{
+		FormatVersion: "1.1",
+		Services: []file.FService{{
+			Service: kong.Service{Name: kong.String("default.foo.80"), Host: kong.String("foo.default.80.svc")},
+			Routes: []*file.FRoute{{
+				Route: kong.Route{Name: kong.String("default.foo.00"), Paths: kong.StringSlice("/foo")},
+			}},
+		}},
+		Upstreams: []file.FUpstream{{
+			Upstream: kong.Upstream{Name: kong.String("foo.default.80.svc"), Algorithm: kong.String("round-robin")},
+			Targets:  []*file.FTarget{{Target: kong.Target{Target: kong.String("10.0.0.1:80")}}},
+		}},
+	}
+	kongConfig := &Kong{
+		URL:                server.URL,
+		Client:             client,
+		ManagedEntityTypes: []string{"services", "routes", "plugins"},
+		Concurrency:        1,
+	}
+
+	_, err = PerformUpdate(context.Background(), logrus.New(), kongConfig, false, false, content,
+		[]string{"managed-by-ingress-controller"}, nil, nil)
+	require.NoError(t, err)
+
+	var services, routes int
+	for _, call := range mutatingCalls {
+		assert.NotContains(t, call, "/upstreams", "upstreams are left untouched")
+		assert.NotContains(t, call, "/targets", "targets are left untouched")
+		if strings.Contains(call, "/services") {
+			services++
+		}
+		if strings.Contains(call, "/routes") {
+			routes++
+		}
+	}
+	assert.Equal(t, 1, services, "the service is created")
+	assert.Equal(t, 1, routes, "the route is created")
+}
@@ -0,0 +1,119 @@
+package sendconfig
+
+import (
+	"sync/atomic"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	metricsNamespace = "kong_ingress_controller"
+	metricsSubsystem = "configuration_push"
+
+	kongURLLabel = "kong_url"
+
+	reportedVersionLabel = "reported_version"
+	versionLabel         = "version"
+)
+
+var (
+	pushDuration = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace: metricsNamespace,
+		Subsystem: metricsSubsystem,
+		Name:      "duration_seconds",
+		Help:      "Time taken to push a configuration to Kong's Admin API(s).",
+		Buckets:   prometheus.ExponentialBuckets(0.01, 2, 12),
+	})
+
+	pushSuccessCount = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Subsystem: metricsSubsystem,
+		Name:      "success_total",
+		Help:      "Number of configuration pushes accepted by a Kong Admin API.",
+	}, []string{kongURLLabel})
+
+	pushFailureCount = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Subsystem: metricsSubsystem,
+		Name:      "failure_total",
+		Help:      "Number of configuration pushes which failed against a Kong Admin API.",
+	}, []string{kongURLLabel})
+
+	lastConfigSize = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Subsystem: metricsSubsystem,
+		Name:      "last_config_size_bytes",
+		Help:      "Size of the last configuration posted to the /config endpoint of Kong.",
+	})
+
+	// lastSyncSuccess is the UnixNano time of the last configuration pushed successfully to every
+	// Admin API, or of the start of the controller until one is.
+	lastSyncSuccess = time.Now().UnixNano()
+
+	lastSyncSuccessAge = prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Subsystem: metricsSubsystem,
+		Name:      "seconds_since_last_success",
+		Help: "Time elapsed since a configuration was last pushed successfully to Kong, " +
+			"or since the controller started if none was yet.",
+	}, func() float64 {
+		return secondsSinceLastSyncSuccess(time.Now())
+	})
+
+	kongVersionInfo = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "kong_version_info",
+		Help: "Version of Kong, as reported by its Admin API and as used to gate features, " +
+			"which is unknown if the reported version can't be parsed.",
+	}, []string{reportedVersionLabel, versionLabel})
+)
+
+// RegisterMetrics registers the collectors of the configuration push metrics
+// with registerer.
+func RegisterMetrics(registerer prometheus.Registerer) error {
+	for _, collector := range []prometheus.Collector{
+		pushDuration,
+		pushSuccessCount,
+		pushFailureCount,
+		lastConfigSize,
+		lastSyncSuccessAge,
+		kongVersionInfo,
+	} {
+		if err := registerer.Register(collector); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// observePush records the outcome of a push to the Admin API at kongURL.
+func observePush(kongURL string, err error) {
+	if err != nil {
+		pushFailureCount.WithLabelValues(kongURL).Inc()
+		return
+	}
+	pushSuccessCount.WithLabelValues(kongURL).Inc()
+}
+
+// observeKongVersion records the version of Kong, replacing the one previously recorded.
+func observeKongVersion(reported, version string) {
+	kongVersionInfo.Reset()
+	kongVersionInfo.WithLabelValues(reported, version).Set(1)
+}
+
+// observePushDuration records the time elapsed since start as the duration
+// of a push.
+func observePushDuration(start time.Time) {
+	pushDuration.Observe(time.Since(start).Seconds())
+}
+
+// observeSyncSuccess records now as the time a configuration was last pushed successfully to Kong.
+func observeSyncSuccess(now time.Time) {
+	atomic.StoreInt64(&lastSyncSuccess, now.UnixNano())
+}
+
+// secondsSinceLastSyncSuccess returns the seconds elapsed from the last successful push to now.
+func secondsSinceLastSyncSuccess(now time.Time) float64 {
+	return now.Sub(time.Unix(0, atomic.LoadInt64(&lastSyncSuccess))).Seconds()
+}
@@ -0,0 +1,77 @@
+package sendconfig
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/kong/deck/file"
+	"github.com/kong/go-kong/kong"
+	"github.com/kong/kubernetes-ingress-controller/pkg/deckgen"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func pushDurationSampleCount(t *testing.T) uint64 {
+	var metric dto.Metric
+	assert.NoError(t, pushDuration.Write(&metric))
+	return metric.GetHistogram().GetSampleCount()
+}
+
+func TestPerformUpdateMetrics(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer server.Close()
+	client, err := kong.NewClient(kong.String(server.URL), server.Client())
+	assert.NoError(t, err)
+
+	assert.NoError(t, RegisterMetrics(prometheus.NewRegistry()))
+
+	samples := pushDurationSampleCount(t)
+	successes := testutil.ToFloat64(pushSuccessCount.WithLabelValues(server.URL))
+
+	_, err = PerformUpdate(context.Background(), logrus.New(), &Kong{URL: server.URL, Client: client}, true, false,
+		&file.Content{FormatVersion: "1.1"}, nil, nil, nil)
+	assert.NoError(t, err)
+
+	assert.Equal(t, samples+1, pushDurationSampleCount(t))
+	assert.Equal(t, successes+1, testutil.ToFloat64(pushSuccessCount.WithLabelValues(server.URL)))
+	assert.Equal(t, float64(0), testutil.ToFloat64(pushFailureCount.WithLabelValues(server.URL)))
+	assert.Greater(t, testutil.ToFloat64(lastConfigSize), float64(0))
+	assert.Less(t, testutil.ToFloat64(lastSyncSuccessAge), float64(1))
+}
+
+func TestSecondsSinceLastSyncSuccess(t *testing.T) {
+	now := time.Now()
+	observeSyncSuccess(now.Add(-90 * time.Second))
+	assert.Equal(t, float64(90), secondsSinceLastSyncSuccess(now))
+}
+
+func TestPerformUpdateUnchangedRecordsSuccess(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("unexpected call to kong: %s %s", r.Method, r.URL.Path)
+	}))
+	defer server.Close()
+	client, err := kong.NewClient(kong.String(server.URL), server.Client())
+	assert.NoError(t, err)
+
+	content := &file.Content{FormatVersion: "1.1"}
+	sha, err := deckgen.GenerateSHA(content, nil)
+	assert.NoError(t, err)
+	observeSyncSuccess(time.Now().Add(-time.Hour))
+	samples := pushDurationSampleCount(t)
+
+	got, err := PerformUpdate(context.Background(), logrus.New(), &Kong{URL: server.URL, Client: client}, true, false,
+		content, nil, nil, sha)
+	assert.NoError(t, err)
+	assert.Equal(t, sha, got)
+
+	assert.Equal(t, samples, pushDurationSampleCount(t), "nothing is pushed")
+	assert.Less(t, secondsSinceLastSyncSuccess(time.Now()), float64(1), "kong is known to be up to date")
+}
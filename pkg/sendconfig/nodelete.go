@@ -0,0 +1,199 @@
+package sendconfig
+
+import (
+	deckutils "github.com/kong/deck/utils"
+)
+
+// filterStaleEntities drops from current, the current state of Kong, every entity
+// which is not in target, the target state rendered against it, so that syncing
+// leaves it in place instead of deleting it. Syncs then only create and update
+// entities; this is meant for Kong backed by a database, as a DB-less Kong
+// replaces its configuration as a whole. It returns the number of entities dropped.
+//
+// The target state reuses the IDs of the current entities it matches, which are
+// the IDs decK looks the current entities up by to tell which ones to delete.
+func filterStaleEntities(current, target *deckutils.KongRawState) int {
+	ids := targetIDs(target)
+	stale := 0
+	keep := func(id *string) bool {
+		if id != nil && ids[*id] {
+			return true
+		}
+		stale++
+		return false
+	}
+
+	services := current.Services[:0]
+	for _, e := range current.Services {
+		if keep(e.ID) {
+			services = append(services, e)
+		}
+	}
+	current.Services = services
+	routes := current.Routes[:0]
+	for _, e := range current.Routes {
+		if keep(e.ID) {
+			routes = append(routes, e)
+		}
+	}
+	current.Routes = routes
+	plugins := current.Plugins[:0]
+	for _, e := range current.Plugins {
+		if keep(e.ID) {
+			plugins = append(plugins, e)
+		}
+	}
+	current.Plugins = plugins
+	upstreams := current.Upstreams[:0]
+	for _, e := range current.Upstreams {
+		if keep(e.ID) {
+			upstreams = append(upstreams, e)
+		}
+	}
+	current.Upstreams = upstreams
+	targets := current.Targets[:0]
+	for _, e := range current.Targets {
+		if keep(e.ID) {
+			targets = append(targets, e)
+		}
+	}
+	current.Targets = targets
+	certificates := current.Certificates[:0]
+	for _, e := range current.Certificates {
+		if keep(e.ID) {
+			certificates = append(certificates, e)
+		}
+	}
+	current.Certificates = certificates
+	snis := current.SNIs[:0]
+	for _, e := range current.SNIs {
+		if keep(e.ID) {
+			snis = append(snis, e)
+		}
+	}
+	current.SNIs = snis
+	caCertificates := current.CACertificates[:0]
+	for _, e := range current.CACertificates {
+		if keep(e.ID) {
+			caCertificates = append(caCertificates, e)
+		}
+	}
+	current.CACertificates = caCertificates
+	consumers := current.Consumers[:0]
+	for _, e := range current.Consumers {
+		if keep(e.ID) {
+			consumers = append(consumers, e)
+		}
+	}
+	current.Consumers = consumers
+	keyAuths := current.KeyAuths[:0]
+	for _, e := range current.KeyAuths {
+		if keep(e.ID) {
+			keyAuths = append(keyAuths, e)
+		}
+	}
+	current.KeyAuths = keyAuths
+	hmacAuths := current.HMACAuths[:0]
+	for _, e := range current.HMACAuths {
+		if keep(e.ID) {
+			hmacAuths = append(hmacAuths, e)
+		}
+	}
+	current.HMACAuths = hmacAuths
+	jwtAuths := current.JWTAuths[:0]
+	for _, e := range current.JWTAuths {
+		if keep(e.ID) {
+			jwtAuths = append(jwtAuths, e)
+		}
+	}
+	current.JWTAuths = jwtAuths
+	basicAuths := current.BasicAuths[:0]
+	for _, e := range current.BasicAuths {
+		if keep(e.ID) {
+			basicAuths = append(basicAuths, e)
+		}
+	}
+	current.BasicAuths = basicAuths
+	aclGroups := current.ACLGroups[:0]
+	for _, e := range current.ACLGroups {
+		if keep(e.ID) {
+			aclGroups = append(aclGroups, e)
+		}
+	}
+	current.ACLGroups = aclGroups
+	oauth2Creds := current.Oauth2Creds[:0]
+	for _, e := range current.Oauth2Creds {
+		if keep(e.ID) {
+			oauth2Creds = append(oauth2Creds, e)
+		}
+	}
+	current.Oauth2Creds = oauth2Creds
+	mtlsAuths := current.MTLSAuths[:0]
+	for _, e := range current.MTLSAuths {
+		if keep(e.ID) {
+			mtlsAuths = append(mtlsAuths, e)
+		}
+	}
+	current.MTLSAuths = mtlsAuths
+	return stale
+}
+
+// targetIDs returns the set of the IDs of the entities of target. The IDs are
+// UUIDs, so the entities of every type share the set.
+func targetIDs(target *deckutils.KongRawState) map[string]bool {
+	ids := map[string]bool{}
+	add := func(id *string) {
+		if id != nil {
+			ids[*id] = true
+		}
+	}
+	for _, e := range target.Services {
+		add(e.ID)
+	}
+	for _, e := range target.Routes {
+		add(e.ID)
+	}
+	for _, e := range target.Plugins {
+		add(e.ID)
+	}
+	for _, e := range target.Upstreams {
+		add(e.ID)
+	}
+	for _, e := range target.Targets {
+		add(e.ID)
+	}
+	for _, e := range target.Certificates {
+		add(e.ID)
+	}
+	for _, e := range target.SNIs {
+		add(e.ID)
+	}
+	for _, e := range target.CACertificates {
+		add(e.ID)
+	}
+	for _, e := range target.Consumers {
+		add(e.ID)
+	}
+	for _, e := range target.KeyAuths {
+		add(e.ID)
+	}
+	for _, e := range target.HMACAuths {
+		add(e.ID)
+	}
+	for _, e := range target.JWTAuths {
+		add(e.ID)
+	}
+	for _, e := range target.BasicAuths {
+		add(e.ID)
+	}
+	for _, e := range target.ACLGroups {
+		add(e.ID)
+	}
+	for _, e := range target.Oauth2Creds {
+		add(e.ID)
+	}
+	for _, e := range target.MTLSAuths {
+		add(e.ID)
+	}
+	return ids
+}
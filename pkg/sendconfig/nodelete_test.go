@@ -0,0 +1,117 @@
+package sendconfig
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/kong/deck/file"
+	deckutils "github.com/kong/deck/utils"
+	"github.com/kong/go-kong/kong"
+	logrustest "github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPerformUpdateNoDelete(t *testing.T) {
+	var lock sync.Mutex
+	var mutatingCalls []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "GET" {
+			lock.Lock()
+			mutatingCalls = append(mutatingCalls, r.Method+" "+r.URL.Path)
+			lock.Unlock()
+			// created and updated entities are echoed back
+			body, _ := ioutil.ReadAll(r.Body)
+			_, _ = w.Write(body)
+			return
+		}
+		switch r.URL.Path {
+		case "/services":
+			_, _ = w.Write([]byte(`{"data":[` +
+				`{"id":"8b4d0e9c-6a1d-4fd5-9a9c-2bfc2b9b0d4e","name":"stale","host":"example.com",` +
+				`"tags":["managed-by-ingress-controller"]},` +
+				`{"id":"1f2a6e63-4a4b-4c7f-8d2c-3c9a0d0b5e11","name":"changed","host":"example.com",` +
+				`"tags":["managed-by-ingress-controller"]}` +
+				`],"next":null}`))
+		default:
+			_, _ = w.Write([]byte(`{"data":[],"next":null}`))
+		}
+	}))
+	defer server.Close()
+	client, err := kong.NewClient(kong.String(server.URL), server.Client())
+	require.NoError(t, err)
+
+	log, hook := logrustest.NewNullLogger()
+	content := &file.Content{
+		FormatVersion: "1.1",
+		Services: []file.FService{
+			{Service: kong.Service{Name: kong.String("new"), Host: kong.String("example.com")}},
+			{Service: kong.Service{Name: kong.String("changed"), Host: kong.String("example.org")}},
+		},
+	}
+	kongConfig := &Kong{URL: server.URL, Client: client, NoDelete: true, Concurrency: 1}
+
+	_, err = PerformUpdate(context.Background(), log, kongConfig, false, false, content,
+		[]string{"managed-by-ingress-controller"}, nil, nil)
+	require.NoError(t, err)
+
+	// the new service is created and the changed one updated, the stale one is kept
+	assert.Len(t, mutatingCalls, 2)
+	assert.Contains(t, mutatingCalls, "PUT /services/1f2a6e63-4a4b-4c7f-8d2c-3c9a0d0b5e11")
+	assert.NotContains(t, mutatingCalls, "DELETE /services/8b4d0e9c-6a1d-4fd5-9a9c-2bfc2b9b0d4e")
+	var logged bool
+	for _, entry := range hook.AllEntries() {
+		if entry.Message == "1 stale entities would have been deleted from kong, keeping them as deletes are disabled" {
+			logged = true
+		}
+	}
+	assert.True(t, logged, "the skipped deletes are logged")
+
+	// a dry run doesn't report the stale service as removed either
+	kongConfig.DryRun = true
+	hook.Reset()
+	_, err = PerformUpdate(context.Background(), log, kongConfig, false, false, content,
+		[]string{"managed-by-ingress-controller"}, nil, nil)
+	require.NoError(t, err)
+	entry := hook.LastEntry()
+	require.NotNil(t, entry)
+	assert.Equal(t, []string{"service new"}, entry.Data["added"])
+	assert.Empty(t, entry.Data["removed"])
+}
+
+func TestFilterStaleEntities(t *testing.T) {
+	current := &deckutils.KongRawState{
+		Services: []*kong.Service{{ID: kong.String("kept-service")}, {ID: kong.String("stale-service")}},
+		Routes:   []*kong.Route{{ID: kong.String("stale-route")}},
+		Plugins:  []*kong.Plugin{{ID: kong.String("kept-plugin")}},
+		Targets:  []*kong.Target{{ID: kong.String("stale-target")}},
+		Consumers: []*kong.Consumer{
+			{ID: kong.String("kept-consumer")},
+		},
+		KeyAuths: []*kong.KeyAuth{{ID: kong.String("kept-key")}, {ID: kong.String("stale-key")}},
+		ACLGroups: []*kong.ACLGroup{
+			{ID: kong.String("stale-group")},
+		},
+	}
+	target := &deckutils.KongRawState{
+		Services:  []*kong.Service{{ID: kong.String("kept-service")}, {ID: kong.String("new-service")}},
+		Plugins:   []*kong.Plugin{{ID: kong.String("kept-plugin")}},
+		Consumers: []*kong.Consumer{{ID: kong.String("kept-consumer")}},
+		KeyAuths:  []*kong.KeyAuth{{ID: kong.String("kept-key")}},
+	}
+
+	assert.Equal(t, 5, filterStaleEntities(current, target))
+	assert.Equal(t, &deckutils.KongRawState{
+		Services:  []*kong.Service{{ID: kong.String("kept-service")}},
+		Routes:    []*kong.Route{},
+		Plugins:   []*kong.Plugin{{ID: kong.String("kept-plugin")}},
+		Targets:   []*kong.Target{},
+		Consumers: []*kong.Consumer{{ID: kong.String("kept-consumer")}},
+		KeyAuths:  []*kong.KeyAuth{{ID: kong.String("kept-key")}},
+		ACLGroups: []*kong.ACLGroup{},
+	}, current)
+}
@@ -0,0 +1,73 @@
+package sendconfig
+
+import (
+	"errors"
+	"reflect"
+
+	deckutils "github.com/kong/deck/utils"
+)
+
+// ValidateFilterTags checks the tags marking the entities owned by a controller. Without
+// any tag, the controller would consider every entity in Kong its own, and an empty tag
+// matches nothing Kong can store.
+func ValidateFilterTags(tags []string) error {
+	if len(tags) == 0 {
+		return errors.New("at least one tag is required")
+	}
+	for _, tag := range tags {
+		if tag == "" {
+			return errors.New("tags cannot be empty")
+		}
+	}
+	return nil
+}
+
+// filterOwnedEntities drops from rawState every taggable entity which does not
+// carry all of selectorTags, so that entities owned by other controllers sharing
+// the same Kong are left untouched during reconciliation.
+// Kong already filters on tags when listing entities; this guards against
+// entities leaking through, as acting on them would make controllers fight.
+func filterOwnedEntities(rawState *deckutils.KongRawState, selectorTags []string) {
+	if len(selectorTags) == 0 {
+		return
+	}
+	state := reflect.ValueOf(rawState).Elem()
+	for i := 0; i < state.NumField(); i++ {
+		entities := state.Field(i)
+		// every field is a slice of pointers to entities; only entities
+		// with tags can be owned
+		entityType := entities.Type().Elem().Elem()
+		if entityType.Kind() != reflect.Struct {
+			continue
+		}
+		if _, taggable := entityType.FieldByName("Tags"); !taggable {
+			continue
+		}
+
+		owned := reflect.MakeSlice(entities.Type(), 0, entities.Len())
+		for j := 0; j < entities.Len(); j++ {
+			entity := entities.Index(j)
+			tags := entity.Elem().FieldByName("Tags").Interface().([]*string)
+			if hasAllTags(tags, selectorTags) {
+				owned = reflect.Append(owned, entity)
+			}
+		}
+		entities.Set(owned)
+	}
+}
+
+// hasAllTags returns true if every one of required is present in tags.
+func hasAllTags(tags []*string, required []string) bool {
+	present := make(map[string]bool, len(tags))
+	for _, tag := range tags {
+		if tag != nil {
+			present[*tag] = true
+		}
+	}
+	for _, tag := range required {
+		if !present[tag] {
+			return false
+		}
+	}
+	return true
+}
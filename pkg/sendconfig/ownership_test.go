@@ -0,0 +1,55 @@
+package sendconfig
+
+import (
+	"testing"
+
+	deckutils "github.com/kong/deck/utils"
+	"github.com/kong/go-kong/kong"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFilterOwnedEntities(t *testing.T) {
+	rawState := &deckutils.KongRawState{
+		Services: []*kong.Service{
+			{Name: kong.String("all-tags"), Tags: kong.StringSlice("team-a", "managed-by-ingress-controller")},
+			{Name: kong.String("extra-tags"), Tags: kong.StringSlice("foo", "managed-by-ingress-controller", "team-a")},
+			{Name: kong.String("subset"), Tags: kong.StringSlice("managed-by-ingress-controller")},
+			{Name: kong.String("other-team"), Tags: kong.StringSlice("team-b", "managed-by-ingress-controller")},
+			{Name: kong.String("untagged")},
+		},
+		Consumers: []*kong.Consumer{
+			{Username: kong.String("subset"), Tags: kong.StringSlice("team-a")},
+		},
+		RBACRoles: []*kong.RBACRole{
+			{Name: kong.String("not-taggable")},
+		},
+	}
+
+	filterOwnedEntities(rawState, []string{"managed-by-ingress-controller", "team-a"})
+
+	var services []string
+	for _, service := range rawState.Services {
+		services = append(services, *service.Name)
+	}
+	assert.Equal(t, []string{"all-tags", "extra-tags"}, services)
+	assert.Empty(t, rawState.Consumers)
+	assert.Len(t, rawState.RBACRoles, 1)
+}
+
+func TestFilterOwnedEntitiesWithoutSelectorTags(t *testing.T) {
+	rawState := &deckutils.KongRawState{
+		Services: []*kong.Service{
+			{Name: kong.String("untagged")},
+		},
+	}
+	filterOwnedEntities(rawState, nil)
+	assert.Len(t, rawState.Services, 1)
+}
+
+func TestValidateFilterTags(t *testing.T) {
+	assert.NoError(t, ValidateFilterTags([]string{"managed-by-ingress-controller"}))
+	assert.NoError(t, ValidateFilterTags([]string{"managed-by-ingress-controller", "team-a"}))
+	assert.EqualError(t, ValidateFilterTags(nil), "at least one tag is required")
+	assert.EqualError(t, ValidateFilterTags([]string{}), "at least one tag is required")
+	assert.EqualError(t, ValidateFilterTags([]string{"team-a", ""}), "tags cannot be empty")
+}
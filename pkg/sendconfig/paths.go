@@ -0,0 +1,105 @@
+package sendconfig
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/kong/go-kong/kong"
+)
+
+const (
+	// maxValidatedPaths caps the number of results kept by RoutePathValidator.
+	maxValidatedPaths = 1000
+	// defaultRoutePathValidationTimeout bounds the time RoutePathValidator waits for Kong to validate a path.
+	defaultRoutePathValidationTimeout = 5 * time.Second
+	// defaultRoutePathValidationBackoff is the time RoutePathValidator stops validating paths for after
+	// a path couldn't be validated.
+	defaultRoutePathValidationBackoff = 30 * time.Second
+)
+
+// ValidateRoutePath checks with the route schema of Kong that path can be the path of a route, which
+// catches the regular expressions its router can't compile. If an error occurs during validation, it is
+// returned as the last argument. The first boolean communicates if path is valid or not and string holds
+// the reason of Kong if it is not.
+func ValidateRoutePath(ctx context.Context, client AdminAPIClient, path string) (bool, string, error) {
+	req, err := client.NewRequest("POST", "/schemas/routes/validate", nil,
+		map[string]interface{}{"paths": []string{path}})
+	if err != nil {
+		return false, "", err
+	}
+	_, err = client.Do(ctx, req, nil)
+	if err != nil {
+		var apiErr *kong.APIError
+		if errors.As(err, &apiErr) && apiErr.Code() == http.StatusBadRequest {
+			return false, apiErr.Error(), nil
+		}
+		return false, "", fmt.Errorf("validating path %q with Kong: %w", path, err)
+	}
+	return true, "", nil
+}
+
+// RoutePathValidator validates the paths of routes with ValidateRoutePath before they are part of a
+// configuration, so that a path rejected by Kong only fails the rule it comes from, rather than the whole
+// configuration. The result for every path is remembered, as the same paths are validated on every sync.
+// Once a path can't be validated, e.g. because Kong is unreachable, no path is validated for a while, so
+// that a sync doesn't wait for Kong to time out on every path it hasn't validated yet.
+type RoutePathValidator struct {
+	client  AdminAPIClient
+	timeout time.Duration
+	backoff time.Duration
+
+	lock    sync.Mutex
+	results map[string]error
+	// skipUntil is the time until which paths aren't validated, after a path couldn't be.
+	skipUntil time.Time
+}
+
+// NewRoutePathValidator returns a RoutePathValidator validating paths with the Admin API of client.
+func NewRoutePathValidator(client AdminAPIClient) *RoutePathValidator {
+	return &RoutePathValidator{
+		client:  client,
+		timeout: defaultRoutePathValidationTimeout,
+		backoff: defaultRoutePathValidationBackoff,
+		results: map[string]error{},
+	}
+}
+
+// Validate returns an error telling why Kong rejects path, if it does. Paths which can't be validated,
+// e.g. because Kong is unreachable, are considered valid, leaving them to the push of the configuration.
+func (v *RoutePathValidator) Validate(path string) error {
+	v.lock.Lock()
+	err, ok := v.results[path]
+	skip := time.Now().Before(v.skipUntil)
+	v.lock.Unlock()
+	if ok {
+		return err
+	}
+	if skip {
+		return nil
+	}
+
+	// the lock isn't held while Kong is called, so that a slow Kong doesn't hold up the other callers
+	ctx, cancel := context.WithTimeout(context.Background(), v.timeout)
+	defer cancel()
+	ok, message, err := ValidateRoutePath(ctx, v.client, path)
+
+	v.lock.Lock()
+	defer v.lock.Unlock()
+	if err != nil {
+		v.skipUntil = time.Now().Add(v.backoff)
+		return nil
+	}
+	if len(v.results) >= maxValidatedPaths {
+		v.results = map[string]error{}
+	}
+	if ok {
+		v.results[path] = nil
+		return nil
+	}
+	v.results[path] = fmt.Errorf("path %q rejected by Kong: %s", path, message)
+	return v.results[path]
+}
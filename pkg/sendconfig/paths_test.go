@@ -0,0 +1,124 @@
+package sendconfig
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/kong/go-kong/kong"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// routeSchemaServer serves a route schema validation endpoint rejecting the paths Go can't compile, and
+// counts the validations it performs.
+func routeSchemaServer(t *testing.T, calls *int) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" || r.URL.Path != "/schemas/routes/validate" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		*calls++
+		var route struct {
+			Paths []string `json:"paths"`
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&route))
+		require.Len(t, route.Paths, 1)
+		w.Header().Set("Content-Type", "application/json")
+		if _, err := regexp.Compile(route.Paths[0]); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"code":2,"name":"schema violation",` +
+				`"message":"schema violation (paths.1: invalid regex: '/foo(')","fields":{"paths":["invalid regex"]}}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"message":"schema validation successful"}`))
+	}))
+}
+
+func TestValidateRoutePath(t *testing.T) {
+	var calls int
+	server := routeSchemaServer(t, &calls)
+	defer server.Close()
+	client, err := kong.NewClient(kong.String(server.URL), server.Client())
+	require.NoError(t, err)
+
+	ok, message, err := ValidateRoutePath(context.Background(), client, "/foo/[0-9]+")
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.Empty(t, message)
+
+	ok, message, err = ValidateRoutePath(context.Background(), client, "/foo(")
+	assert.NoError(t, err)
+	assert.False(t, ok)
+	assert.Contains(t, message, "invalid regex")
+
+	server.Close()
+	_, _, err = ValidateRoutePath(context.Background(), client, "/foo(")
+	assert.Error(t, err)
+}
+
+func TestRoutePathValidator(t *testing.T) {
+	var calls int
+	server := routeSchemaServer(t, &calls)
+	defer server.Close()
+	client, err := kong.NewClient(kong.String(server.URL), server.Client())
+	require.NoError(t, err)
+	validator := NewRoutePathValidator(client)
+
+	assert.NoError(t, validator.Validate("/foo/[0-9]+"))
+	err = validator.Validate("/foo(")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `path "/foo(" rejected by Kong`)
+	assert.Equal(t, 2, calls)
+
+	// the results are remembered
+	assert.NoError(t, validator.Validate("/foo/[0-9]+"))
+	assert.Error(t, validator.Validate("/foo("))
+	assert.Equal(t, 2, calls)
+
+	// paths which can't be validated are left to the push of the configuration
+	server.Close()
+	assert.NoError(t, validator.Validate("/bar("))
+}
+
+func TestRoutePathValidatorTimeout(t *testing.T) {
+	var calls int32
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		<-release
+	}))
+	defer server.Close()
+	defer close(release)
+	client, err := kong.NewClient(kong.String(server.URL), server.Client())
+	require.NoError(t, err)
+	validator := NewRoutePathValidator(client)
+	validator.timeout = 10 * time.Millisecond
+
+	// a Kong not answering doesn't block the sync, the path is left to the push of the configuration
+	done := make(chan error)
+	go func() { done <- validator.Validate("/foo(") }()
+	select {
+	case err := <-done:
+		assert.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("the validation of the path did not time out")
+	}
+
+	// the other paths aren't validated for a while, rather than each waiting for Kong to time out
+	assert.NoError(t, validator.Validate("/bar("))
+	assert.NoError(t, validator.Validate("/baz("))
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+
+	// after which Kong is asked again
+	validator.lock.Lock()
+	validator.skipUntil = time.Time{}
+	validator.lock.Unlock()
+	assert.NoError(t, validator.Validate("/bar("))
+	assert.Equal(t, int32(2), atomic.LoadInt32(&calls))
+}
@@ -0,0 +1,223 @@
+package sendconfig
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	deckutils "github.com/kong/deck/utils"
+)
+
+// retryBaseDelay is the delay before the first retry of an Admin API call;
+// it doubles with every further attempt.
+const retryBaseDelay = 100 * time.Millisecond
+
+// RetryOpts configures the retries of failed Admin API calls.
+type RetryOpts struct {
+	// MaxRetries is how many times a call is retried at most; zero disables retries.
+	MaxRetries int
+	// MaxDelay caps the delay between two attempts.
+	MaxDelay time.Duration
+}
+
+// RetryRequests returns a copy of client which retries idempotent requests
+// failing with a 5xx or 429 status or a connection error, with exponential
+// backoff and jitter between attempts. Other failures, other 4xx statuses
+// included, are returned immediately, as are the failures of requests whose
+// context is done.
+//
+// A 429 or 503 response with a valid Retry-After header is waited for the delay
+// Kong asks for instead of the backoff, if it is within MaxDelay. Otherwise, or
+// once the retries are exhausted, the call fails with a *RetryAfterError for
+// the caller to wait at least that long before trying again, see RetryAfter.
+func RetryRequests(client *http.Client, opts RetryOpts) *http.Client {
+	rt := client.Transport
+	if rt == nil {
+		rt = http.DefaultTransport
+	}
+	retrying := *client
+	retrying.Transport = &retryRoundTripper{
+		maxRetries: opts.MaxRetries,
+		baseDelay:  retryBaseDelay,
+		maxDelay:   opts.MaxDelay,
+		rt:         rt,
+	}
+	return &retrying
+}
+
+// retryRoundTripper retries the requests made via rt which may succeed on a
+// further attempt.
+type retryRoundTripper struct {
+	maxRetries int
+	baseDelay  time.Duration
+	maxDelay   time.Duration
+	rt         http.RoundTripper
+}
+
+// RoundTrip satisfies the RoundTripper interface.
+func (r *retryRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	for attempt := 0; ; attempt++ {
+		resp, err := r.rt.RoundTrip(req)
+		retryAfter, hasRetryAfter := parseRetryAfter(resp, time.Now())
+		if attempt >= r.maxRetries || !isRetryable(req, resp, err) ||
+			(hasRetryAfter && retryAfter > r.maxDelay) {
+			if hasRetryAfter && req.Context().Err() == nil {
+				return nil, newRetryAfterError(resp, retryAfter)
+			}
+			return resp, err
+		}
+		delay := r.delay(attempt)
+		if hasRetryAfter {
+			delay = retryAfter
+		}
+		if resp != nil {
+			// free the connection before trying again
+			_, _ = io.Copy(ioutil.Discard, resp.Body)
+			resp.Body.Close()
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+		case <-req.Context().Done():
+			timer.Stop()
+			return nil, req.Context().Err()
+		}
+
+		if req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			req = req.Clone(req.Context())
+			req.Body = body
+		}
+	}
+}
+
+// delay returns a random delay, in full jitter fashion, of at most
+// baseDelay*2^attempt, capped by maxDelay.
+func (r *retryRoundTripper) delay(attempt int) time.Duration {
+	ceiling := r.maxDelay
+	if attempt < 32 {
+		if exp := r.baseDelay << uint(attempt); exp > 0 && exp < ceiling {
+			ceiling = exp
+		}
+	}
+	if ceiling <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int63n(int64(ceiling))) + 1
+}
+
+// isRetryable tells whether the outcome of req is a transient failure and req
+// can safely be sent again.
+func isRetryable(req *http.Request, resp *http.Response, err error) bool {
+	if req.Context().Err() != nil {
+		return false
+	}
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+	default:
+		return false
+	}
+	if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+		return false
+	}
+	if err != nil {
+		return true
+	}
+	return resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= http.StatusInternalServerError
+}
+
+// parseRetryAfter returns the delay the Retry-After header of resp, a 429 or
+// 503 response, asks to wait before retrying, as of now. The header holds
+// either a number of seconds or an HTTP date; it is ignored if it holds
+// neither, and so are the headers of other responses.
+func parseRetryAfter(resp *http.Response, now time.Time) (time.Duration, bool) {
+	if resp == nil ||
+		(resp.StatusCode != http.StatusTooManyRequests && resp.StatusCode != http.StatusServiceUnavailable) {
+		return 0, false
+	}
+	value := strings.TrimSpace(resp.Header.Get("Retry-After"))
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.ParseInt(value, 10, 32); err == nil {
+		if seconds < 0 {
+			return 0, false
+		}
+		return time.Duration(seconds) * time.Second, true
+	}
+	date, err := http.ParseTime(value)
+	if err != nil {
+		return 0, false
+	}
+	if delay := date.Sub(now); delay > 0 {
+		return delay, true
+	}
+	return 0, true
+}
+
+// RetryAfterError is returned by the clients of RetryRequests when Kong asks,
+// with the Retry-After header of a 429 or 503 response, to wait longer before
+// retrying than they wait themselves.
+type RetryAfterError struct {
+	// StatusCode is the status of the response of Kong.
+	StatusCode int
+	// Delay is how long Kong asks to wait before retrying.
+	Delay time.Duration
+	// Message is the body of the response of Kong, if any.
+	Message string
+}
+
+func (e *RetryAfterError) Error() string {
+	msg := fmt.Sprintf("kong responded with %d %s, asking to retry after %s",
+		e.StatusCode, http.StatusText(e.StatusCode), e.Delay)
+	if e.Message != "" {
+		msg += ": " + e.Message
+	}
+	return msg
+}
+
+// newRetryAfterError returns the RetryAfterError of resp, consuming its body.
+func newRetryAfterError(resp *http.Response, delay time.Duration) *RetryAfterError {
+	body, _ := ioutil.ReadAll(io.LimitReader(resp.Body, maxErrorBodySize))
+	resp.Body.Close()
+	return &RetryAfterError{
+		StatusCode: resp.StatusCode,
+		Delay:      delay,
+		Message:    strings.TrimSpace(string(body)),
+	}
+}
+
+// RetryAfter returns how long Kong asked to wait before retrying the calls
+// which failed with err, which may hold the failures of several Admin APIs:
+// the longest of the delays they asked for, if any did.
+func RetryAfter(err error) (time.Duration, bool) {
+	var errArray deckutils.ErrArray
+	if errors.As(err, &errArray) {
+		var longest time.Duration
+		found := false
+		for _, err := range errArray.Errors {
+			if delay, ok := RetryAfter(err); ok {
+				found = true
+				if delay > longest {
+					longest = delay
+				}
+			}
+		}
+		return longest, found
+	}
+	var retryAfter *RetryAfterError
+	if errors.As(err, &retryAfter) {
+		return retryAfter.Delay, true
+	}
+	return 0, false
+}
@@ -0,0 +1,292 @@
+package sendconfig
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	deckutils "github.com/kong/deck/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRetryRequests(t *testing.T) {
+	tests := []struct {
+		name       string
+		method     string
+		statuses   []int
+		maxRetries int
+		wantStatus int
+		wantCalls  int32
+	}{
+		{
+			name:       "5xx errors are retried until success",
+			method:     http.MethodGet,
+			statuses:   []int{http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusOK},
+			maxRetries: 3,
+			wantStatus: http.StatusOK,
+			wantCalls:  3,
+		},
+		{
+			name:       "retries are capped",
+			method:     http.MethodGet,
+			statuses:   []int{http.StatusBadGateway},
+			maxRetries: 2,
+			wantStatus: http.StatusBadGateway,
+			wantCalls:  3,
+		},
+		{
+			name:       "4xx errors fail immediately",
+			method:     http.MethodGet,
+			statuses:   []int{http.StatusBadRequest, http.StatusOK},
+			maxRetries: 3,
+			wantStatus: http.StatusBadRequest,
+			wantCalls:  1,
+		},
+		{
+			name:       "bodies of idempotent requests are sent again",
+			method:     http.MethodPut,
+			statuses:   []int{http.StatusBadGateway, http.StatusOK},
+			maxRetries: 3,
+			wantStatus: http.StatusOK,
+			wantCalls:  2,
+		},
+		{
+			name:       "429 errors are retried",
+			method:     http.MethodGet,
+			statuses:   []int{http.StatusTooManyRequests, http.StatusOK},
+			maxRetries: 3,
+			wantStatus: http.StatusOK,
+			wantCalls:  2,
+		},
+		{
+			name:       "non-idempotent requests are not retried",
+			method:     http.MethodPost,
+			statuses:   []int{http.StatusBadGateway, http.StatusCreated},
+			maxRetries: 3,
+			wantStatus: http.StatusBadGateway,
+			wantCalls:  1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls int32
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				call := int(atomic.AddInt32(&calls, 1)) - 1
+				body, _ := ioutil.ReadAll(r.Body)
+				assert.Equal(t, "{}", string(body))
+				if call >= len(tt.statuses) {
+					call = len(tt.statuses) - 1
+				}
+				w.WriteHeader(tt.statuses[call])
+			}))
+			defer server.Close()
+
+			client := RetryRequests(server.Client(), RetryOpts{MaxRetries: tt.maxRetries, MaxDelay: time.Millisecond})
+			req, err := http.NewRequest(tt.method, server.URL, strings.NewReader("{}"))
+			assert.NoError(t, err)
+			resp, err := client.Do(req)
+			assert.NoError(t, err)
+			resp.Body.Close()
+			assert.Equal(t, tt.wantStatus, resp.StatusCode)
+			assert.Equal(t, tt.wantCalls, atomic.LoadInt32(&calls))
+		})
+	}
+}
+
+func TestRetryRequestsConnectionErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	url := server.URL
+	server.Close()
+
+	var attempts int32
+	client := RetryRequests(&http.Client{Transport: roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		atomic.AddInt32(&attempts, 1)
+		return http.DefaultTransport.RoundTrip(req)
+	})}, RetryOpts{MaxRetries: 2, MaxDelay: time.Millisecond})
+
+	_, err := client.Get(url)
+	assert.Error(t, err)
+	assert.Equal(t, int32(3), atomic.LoadInt32(&attempts))
+}
+
+func TestRetryRequestsStopsOnContextCancellation(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer server.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	client := RetryRequests(server.Client(), RetryOpts{MaxRetries: 100, MaxDelay: time.Hour})
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, server.URL, nil)
+	assert.NoError(t, err)
+
+	start := time.Now()
+	_, err = client.Do(req)
+	assert.Error(t, err)
+	assert.Less(t, time.Since(start).Seconds(), 5.0)
+	assert.Less(t, atomic.LoadInt32(&calls), int32(100))
+}
+
+func TestRetryRequestsHonorsRetryAfter(t *testing.T) {
+	tests := []struct {
+		name       string
+		method     string
+		retryAfter string
+		maxDelay   time.Duration
+		wantCalls  int32
+		wantDelay  time.Duration
+		wantErr    bool
+		wantMinGap time.Duration
+	}{
+		{
+			name:       "a delay within the maximum is waited before retrying",
+			method:     http.MethodGet,
+			retryAfter: "1",
+			maxDelay:   5 * time.Second,
+			wantCalls:  2,
+			wantMinGap: time.Second,
+		},
+		{
+			name:       "a delay beyond the maximum is returned to the caller",
+			method:     http.MethodGet,
+			retryAfter: "120",
+			maxDelay:   5 * time.Second,
+			wantCalls:  1,
+			wantDelay:  2 * time.Minute,
+			wantErr:    true,
+		},
+		{
+			name:       "the delay of non-retryable requests is returned to the caller",
+			method:     http.MethodPost,
+			retryAfter: "30",
+			maxDelay:   time.Hour,
+			wantCalls:  1,
+			wantDelay:  30 * time.Second,
+			wantErr:    true,
+		},
+		{
+			name:       "a malformed header falls back to the backoff",
+			method:     http.MethodGet,
+			retryAfter: "soon",
+			maxDelay:   time.Millisecond,
+			wantCalls:  2,
+		},
+		{
+			name:      "an absent header falls back to the backoff",
+			method:    http.MethodGet,
+			maxDelay:  time.Millisecond,
+			wantCalls: 2,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls int32
+			var lock sync.Mutex
+			var times []time.Time
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				lock.Lock()
+				times = append(times, time.Now())
+				lock.Unlock()
+				if atomic.AddInt32(&calls, 1) > 1 {
+					w.WriteHeader(http.StatusOK)
+					return
+				}
+				if tt.retryAfter != "" {
+					w.Header().Set("Retry-After", tt.retryAfter)
+				}
+				w.WriteHeader(http.StatusTooManyRequests)
+				_, _ = w.Write([]byte(`{"message":"API rate limit exceeded"}`))
+			}))
+			defer server.Close()
+
+			client := RetryRequests(server.Client(), RetryOpts{MaxRetries: 3, MaxDelay: tt.maxDelay})
+			req, err := http.NewRequest(tt.method, server.URL, nil)
+			assert.NoError(t, err)
+			resp, err := client.Do(req)
+			assert.Equal(t, tt.wantCalls, atomic.LoadInt32(&calls))
+			if tt.wantErr {
+				assert.Error(t, err)
+				assert.Contains(t, err.Error(), "API rate limit exceeded")
+				delay, ok := RetryAfter(err)
+				assert.True(t, ok)
+				assert.Equal(t, tt.wantDelay, delay)
+				return
+			}
+			assert.NoError(t, err)
+			resp.Body.Close()
+			assert.Equal(t, http.StatusOK, resp.StatusCode)
+			if tt.wantMinGap > 0 {
+				assert.GreaterOrEqual(t, int64(times[1].Sub(times[0])), int64(tt.wantMinGap))
+			}
+		})
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name      string
+		status    int
+		value     string
+		wantDelay time.Duration
+		wantOK    bool
+	}{
+		{name: "seconds", status: http.StatusTooManyRequests, value: "30", wantDelay: 30 * time.Second, wantOK: true},
+		{name: "zero", status: http.StatusTooManyRequests, value: "0", wantOK: true},
+		{
+			name:      "http date",
+			status:    http.StatusServiceUnavailable,
+			value:     now.Add(time.Minute).Format(http.TimeFormat),
+			wantDelay: time.Minute,
+			wantOK:    true,
+		},
+		{name: "past http date", status: http.StatusServiceUnavailable, value: now.Add(-time.Minute).Format(http.TimeFormat), wantOK: true},
+		{name: "absent", status: http.StatusTooManyRequests},
+		{name: "negative", status: http.StatusTooManyRequests, value: "-5"},
+		{name: "malformed", status: http.StatusTooManyRequests, value: "in a minute"},
+		{name: "other status", status: http.StatusBadGateway, value: "30"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := &http.Response{StatusCode: tt.status, Header: http.Header{}}
+			if tt.value != "" {
+				resp.Header.Set("Retry-After", tt.value)
+			}
+			delay, ok := parseRetryAfter(resp, now)
+			assert.Equal(t, tt.wantOK, ok)
+			assert.Equal(t, tt.wantDelay, delay)
+		})
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	short := &url.Error{Op: "Get", URL: "http://kong-1:8001", Err: &RetryAfterError{StatusCode: 429, Delay: time.Second}}
+	long := &url.Error{Op: "Get", URL: "http://kong-2:8001", Err: &RetryAfterError{StatusCode: 503, Delay: time.Minute}}
+
+	delay, ok := RetryAfter(fmt.Errorf("pushing: %w", short))
+	assert.True(t, ok)
+	assert.Equal(t, time.Second, delay)
+
+	delay, ok = RetryAfter(deckutils.ErrArray{Errors: []error{short, errors.New("boom"), long}})
+	assert.True(t, ok)
+	assert.Equal(t, time.Minute, delay)
+
+	_, ok = RetryAfter(deckutils.ErrArray{Errors: []error{errors.New("boom")}})
+	assert.False(t, ok)
+}
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) { return f(req) }
@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"net/http"
 	"reflect"
+	"time"
 
 	"github.com/kong/deck/diff"
 	"github.com/kong/deck/dump"
@@ -41,19 +42,42 @@ func PerformUpdate(ctx context.Context,
 	// disable optimization if reverse sync is enabled
 	if !reverseSync {
 		if equalSHA(oldSHA, newSHA) {
+			// oldSHA is only kept once pushed successfully, so Kong is known to be up to date
+			observeSyncSuccess(time.Now())
 			log.Info("no configuration change, skipping sync to kong")
 			return oldSHA, nil
 		}
 	}
 
-	if inMemory {
-		err = onUpdateInMemoryMode(ctx, log, targetContent, customEntities, kongConfig)
+	if kongConfig.DryRun {
+		return oldSHA, onUpdateDryRun(log, kongConfig, targetContent, selectorTags)
+	}
+
+	// a push which started is completed even if ctx gets cancelled,
+	// so that Kong is never left partially updated
+	if kongConfig.InFlight != nil {
+		defer kongConfig.InFlight.start()()
+		var cancel context.CancelFunc
+		ctx, cancel = detach(ctx)
+		defer cancel()
+	}
+
+	start := time.Now()
+	if len(kongConfig.AdditionalEndpoints) > 0 {
+		err = onUpdateAllEndpoints(ctx, log, kongConfig, inMemory, targetContent, selectorTags, customEntities)
 	} else {
-		err = onUpdateDBMode(targetContent, kongConfig, selectorTags)
+		if inMemory {
+			err = onUpdateInMemoryMode(ctx, log, targetContent, customEntities, kongConfig)
+		} else {
+			err = onUpdateDBMode(log, targetContent, kongConfig, selectorTags)
+		}
+		observePush(kongConfig.URL, err)
 	}
+	observePushDuration(start)
 	if err != nil {
 		return nil, err
 	}
+	observeSyncSuccess(time.Now())
 	log.Info("successfully synced configuration to kong")
 	return newSHA, nil
 }
@@ -114,6 +138,16 @@ func onUpdateInMemoryMode(ctx context.Context,
 	customEntities []byte,
 	kongConfig *Kong,
 ) error {
+	config, err := renderInMemoryConfig(log, state, customEntities)
+	if err != nil {
+		return err
+	}
+	return postInMemoryConfig(ctx, config, kongConfig)
+}
+
+// renderInMemoryConfig prepares state for the /config endpoint of Kong and
+// renders it together with customEntities.
+func renderInMemoryConfig(log logrus.FieldLogger, state *file.Content, customEntities []byte) ([]byte, error) {
 	// Kong will error out if this is set
 	state.Info = nil
 	// Kong errors out if `null`s are present in `config` of plugins
@@ -121,9 +155,12 @@ func onUpdateInMemoryMode(ctx context.Context,
 
 	config, err := renderConfigWithCustomEntities(log, state, customEntities)
 	if err != nil {
-		return fmt.Errorf("constructing kong configuration: %w", err)
+		return nil, fmt.Errorf("constructing kong configuration: %w", err)
 	}
+	return config, nil
+}
 
+func postInMemoryConfig(ctx context.Context, config []byte, kongConfig *Kong) error {
 	req, err := http.NewRequest("POST", kongConfig.URL+"/config",
 		bytes.NewReader(config))
 	if err != nil {
@@ -136,52 +173,109 @@ func onUpdateInMemoryMode(ctx context.Context,
 
 	req.URL.RawQuery = queryString.Encode()
 
-	_, err = kongConfig.Client.Do(ctx, req, nil)
+	lastConfigSize.Set(float64(len(config)))
+	var errorBody bytes.Buffer
+	resp, err := kongConfig.Client.Do(withErrorBody(ctx, &errorBody), req, nil)
 	if err != nil {
+		if resp != nil && resp.StatusCode == http.StatusBadRequest {
+			err = configRejected(config, errorBody.Bytes(), err)
+		}
 		return fmt.Errorf("posting new config to /config: %w", err)
 	}
 
 	return err
 }
 
+// configRejected returns the error of Kong rejecting config with the
+// response body, along with the entities it reported as invalid.
+func configRejected(config, body []byte, err error) error {
+	rejected := &ConfigRejectedError{Err: err}
+	if len(body) > 0 {
+		rejected.Entities, _ = rejectedEntities(config, body)
+	}
+	return rejected
+}
+
 func onUpdateDBMode(
+	log logrus.FieldLogger,
 	targetContent *file.Content,
 	kongConfig *Kong,
 	selectorTags []string,
 ) error {
+	client, err := kongConfig.deckClient()
+	if err != nil {
+		return err
+	}
+	syncer, stale, err := newSyncer(targetContent, kongConfig, selectorTags)
+	if err != nil {
+		return err
+	}
+	if stale > 0 {
+		log.WithField("kong_url", kongConfig.URL).Infof(
+			"%d stale entities would have been deleted from kong, keeping them as deletes are disabled",
+			stale)
+	}
+	_, errs := solver.Solve(nil, syncer, client, nil, kongConfig.Concurrency, false)
+	if errs != nil {
+		return deckutils.ErrArray{Errors: errs}
+	}
+	return nil
+}
+
+// newSyncer creates a syncer diffing the entities owned by the controller in
+// the current state of Kong against targetContent. With kongConfig.NoDelete,
+// the syncer leaves the stale entities in place, and their number is returned.
+func newSyncer(
+	targetContent *file.Content,
+	kongConfig *Kong,
+	selectorTags []string,
+) (*diff.Syncer, int, error) {
+	client, err := kongConfig.deckClient()
+	if err != nil {
+		return nil, 0, err
+	}
 	// read the current state
-	rawState, err := dump.Get(kongConfig.Client, dump.Config{
+	currentRawState, err := dump.Get(client, dump.Config{
 		SelectorTags: selectorTags,
 	})
 	if err != nil {
-		return fmt.Errorf("loading configuration from kong: %w", err)
+		return nil, 0, fmt.Errorf("loading configuration from kong: %w", err)
 	}
-	currentState, err := state.Get(rawState)
+	filterOwnedEntities(currentRawState, selectorTags)
+	managed := newManagedEntityTypes(kongConfig.ManagedEntityTypes)
+	filterManagedEntities(currentRawState, managed)
+	currentState, err := state.Get(currentRawState)
 	if err != nil {
-		return err
+		return nil, 0, err
 	}
+	targetContent = managedContent(targetContent, managed)
 
 	// read the target state
-	rawState, err = file.Get(targetContent, file.RenderConfig{
+	targetRawState, err := file.Get(targetContent, file.RenderConfig{
 		CurrentState: currentState,
-		KongVersion:  kongConfig.Version,
+		KongVersion:  kongConfig.deckVersion(),
 	})
 	if err != nil {
-		return err
+		return nil, 0, err
 	}
-	targetState, err := state.Get(rawState)
+	targetState, err := state.Get(targetRawState)
 	if err != nil {
-		return err
+		return nil, 0, err
+	}
+
+	var stale int
+	if kongConfig.NoDelete {
+		stale = filterStaleEntities(currentRawState, targetRawState)
+		currentState, err = state.Get(currentRawState)
+		if err != nil {
+			return nil, 0, err
+		}
 	}
 
 	syncer, err := diff.NewSyncer(currentState, targetState)
 	if err != nil {
-		return fmt.Errorf("creating a new syncer: %w", err)
+		return nil, 0, fmt.Errorf("creating a new syncer: %w", err)
 	}
 	syncer.SilenceWarnings = true
-	_, errs := solver.Solve(nil, syncer, kongConfig.Client, nil, kongConfig.Concurrency, false)
-	if errs != nil {
-		return deckutils.ErrArray{Errors: errs}
-	}
-	return nil
+	return syncer, stale, nil
 }
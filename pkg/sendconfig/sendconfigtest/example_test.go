@@ -0,0 +1,45 @@
+package sendconfigtest_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+
+	"github.com/kong/deck/file"
+	"github.com/kong/go-kong/kong"
+	"github.com/kong/kubernetes-ingress-controller/pkg/sendconfig"
+	"github.com/kong/kubernetes-ingress-controller/pkg/sendconfig/sendconfigtest"
+	"github.com/sirupsen/logrus"
+)
+
+func ExampleFakeAdminAPI() {
+	fake := sendconfigtest.NewFakeAdminAPI("2.4.1")
+	kongConfig := &sendconfig.Kong{URL: "http://kong.fake", Client: fake, InMemory: true}
+	content := &file.Content{
+		FormatVersion: "1.1",
+		Services: []file.FService{{
+			Service: kong.Service{Name: kong.String("default.foo.80"), Host: kong.String("foo.default.80.svc")},
+		}},
+	}
+
+	log := logrus.New()
+	log.Out = ioutil.Discard
+	_, err := sendconfig.PerformUpdate(context.Background(), log, kongConfig, kongConfig.InMemory, false,
+		content, nil, nil, nil)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	var pushed file.Content
+	if err := json.Unmarshal(fake.Configs()[0], &pushed); err != nil {
+		fmt.Println(err)
+		return
+	}
+	fmt.Println(fake.Requests())
+	fmt.Println(*pushed.Services[0].Name)
+	// Output:
+	// [POST /config]
+	// default.foo.80
+}
@@ -0,0 +1,150 @@
+// Package sendconfigtest provides an in-memory fake of Kong's Admin API, to
+// test the code pushing configuration to Kong without running Kong.
+package sendconfigtest
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/kong/go-kong/kong"
+	"github.com/kong/kubernetes-ingress-controller/pkg/sendconfig"
+)
+
+// fakeURL is the Admin API URL of the requests created by FakeAdminAPI.
+const fakeURL = "http://kong.fake"
+
+var _ sendconfig.AdminAPIClient = &FakeAdminAPI{}
+
+// FakeAdminAPI is an in-memory sendconfig.AdminAPIClient faking a DB-less
+// Kong: it records the configurations posted to /config and serves its root
+// information. Other requests fail. It is safe for concurrent use.
+type FakeAdminAPI struct {
+	lock sync.Mutex
+
+	// RootInfo is served at the root of the Admin API.
+	RootInfo map[string]interface{}
+	// ConfigStatus is the status of the responses to configuration pushes,
+	// http.StatusCreated if zero. A status of 400 or more fails the pushes.
+	ConfigStatus int
+	// Unavailable, if set, fails every request as if Kong couldn't be reached.
+	Unavailable error
+
+	requests []string
+	configs  [][]byte
+}
+
+// NewFakeAdminAPI returns a FakeAdminAPI faking a DB-less Kong of the given version.
+func NewFakeAdminAPI(version string) *FakeAdminAPI {
+	return &FakeAdminAPI{
+		RootInfo: map[string]interface{}{
+			"version":       version,
+			"configuration": map[string]interface{}{"database": "off"},
+		},
+	}
+}
+
+// NewRequest creates a request to endpoint with the query string qs and the
+// JSON-encoded body.
+func (f *FakeAdminAPI) NewRequest(method, endpoint string, qs interface{},
+	body interface{}) (*http.Request, error) {
+	var buf bytes.Buffer
+	if body != nil {
+		if err := json.NewEncoder(&buf).Encode(body); err != nil {
+			return nil, err
+		}
+	}
+	if qs != nil {
+		return nil, fmt.Errorf("query strings are not supported by the fake")
+	}
+	return http.NewRequest(method, fakeURL+endpoint, &buf)
+}
+
+// Do records req and answers it: the configurations posted to /config are
+// kept, and the root information is served at /.
+func (f *FakeAdminAPI) Do(ctx context.Context, req *http.Request, v interface{}) (*kong.Response, error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	f.requests = append(f.requests, req.Method+" "+req.URL.Path)
+	if f.Unavailable != nil {
+		return nil, f.Unavailable
+	}
+	switch {
+	case req.Method == http.MethodPost && strings.HasSuffix(req.URL.Path, "/config"):
+		config, err := ioutil.ReadAll(req.Body)
+		if err != nil {
+			return nil, err
+		}
+		status := f.ConfigStatus
+		if status == 0 {
+			status = http.StatusCreated
+		}
+		resp := response(req, status)
+		if status >= http.StatusBadRequest {
+			return resp, fmt.Errorf("HTTP status %d", status)
+		}
+		f.configs = append(f.configs, config)
+		return resp, nil
+	case req.Method == http.MethodGet && (req.URL.Path == "" || req.URL.Path == "/"):
+		if v != nil {
+			if err := roundTrip(f.RootInfo, v); err != nil {
+				return nil, err
+			}
+		}
+		return response(req, http.StatusOK), nil
+	}
+	return response(req, http.StatusNotFound), fmt.Errorf("HTTP status %d", http.StatusNotFound)
+}
+
+// Root returns RootInfo.
+func (f *FakeAdminAPI) Root(ctx context.Context) (map[string]interface{}, error) {
+	req, err := f.NewRequest(http.MethodGet, "/", nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	var root map[string]interface{}
+	if _, err := f.Do(ctx, req, &root); err != nil {
+		return nil, err
+	}
+	return root, nil
+}
+
+// Requests returns the method and path of every request made, in order.
+func (f *FakeAdminAPI) Requests() []string {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	return append([]string(nil), f.requests...)
+}
+
+// Configs returns the configurations Kong accepted, in the order they were pushed.
+func (f *FakeAdminAPI) Configs() [][]byte {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	return append([][]byte(nil), f.configs...)
+}
+
+// response returns an empty response to req with status.
+func response(req *http.Request, status int) *kong.Response {
+	return &kong.Response{Response: &http.Response{
+		Status:     fmt.Sprintf("%d %s", status, http.StatusText(status)),
+		StatusCode: status,
+		Header:     http.Header{},
+		Body:       http.NoBody,
+		Request:    req,
+	}}
+}
+
+// roundTrip copies in to out through JSON, as a response body is decoded.
+func roundTrip(in, out interface{}) error {
+	b, err := json.Marshal(in)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, out)
+}
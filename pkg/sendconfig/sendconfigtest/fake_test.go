@@ -0,0 +1,81 @@
+package sendconfigtest
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"testing"
+
+	"github.com/kong/deck/file"
+	"github.com/kong/go-kong/kong"
+	"github.com/kong/kubernetes-ingress-controller/pkg/sendconfig"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFakeAdminAPIPerformUpdate(t *testing.T) {
+	content := &file.Content{
+		FormatVersion: "1.1",
+		Services: []file.FService{{
+			Service: kong.Service{Name: kong.String("default.foo.80"), Host: kong.String("foo.default.80.svc")},
+		}},
+	}
+	log := logrus.New()
+	log.Out = ioutil.Discard
+
+	for _, tt := range []struct {
+		name         string
+		setup        func(*FakeAdminAPI)
+		inMemory     bool
+		wantErr      bool
+		wantRejected bool
+		wantConfigs  int
+	}{
+		{
+			name:        "accepted",
+			inMemory:    true,
+			wantConfigs: 1,
+		},
+		{
+			name:         "rejected",
+			setup:        func(f *FakeAdminAPI) { f.ConfigStatus = http.StatusBadRequest },
+			inMemory:     true,
+			wantErr:      true,
+			wantRejected: true,
+		},
+		{
+			name:     "unavailable",
+			setup:    func(f *FakeAdminAPI) { f.Unavailable = errors.New("connection refused") },
+			inMemory: true,
+			wantErr:  true,
+		},
+		{
+			name:    "database mode requires a kong client",
+			wantErr: true,
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := NewFakeAdminAPI("2.4.1")
+			if tt.setup != nil {
+				tt.setup(fake)
+			}
+			kongConfig := &sendconfig.Kong{URL: fakeURL, Client: fake, InMemory: tt.inMemory}
+
+			_, err := sendconfig.PerformUpdate(context.Background(), log, kongConfig, tt.inMemory, false,
+				content, nil, nil, nil)
+			assert.Equal(t, tt.wantErr, err != nil)
+			var rejected *sendconfig.ConfigRejectedError
+			assert.Equal(t, tt.wantRejected, errors.As(err, &rejected))
+			assert.Len(t, fake.Configs(), tt.wantConfigs)
+		})
+	}
+}
+
+func TestFakeAdminAPIRoot(t *testing.T) {
+	fake := NewFakeAdminAPI("2.4.1")
+	root, err := fake.Root(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, "2.4.1", root["version"])
+	assert.Equal(t, []string{"GET /"}, fake.Requests())
+}
@@ -0,0 +1,63 @@
+package sendconfig
+
+import (
+	"fmt"
+
+	"github.com/blang/semver"
+	"github.com/kong/kubernetes-ingress-controller/pkg/util"
+)
+
+// unknownVersion is the value of the version label of the Kong version metric
+// when the reported version can't be parsed.
+const unknownVersion = "unknown"
+
+// deckVersionFloor is the version of Kong decK renders the configuration for when
+// the version of Kong is unknown. Below 1.4.0, decK doesn't tag credentials, which
+// would then never be seen as owned by the controller.
+var deckVersionFloor = semver.MustParse("1.4.0")
+
+// KongVersionInfo is the version of Kong, as served by the debug endpoint.
+type KongVersionInfo struct {
+	// Reported is the version reported by the root endpoint of the Admin API.
+	Reported string `json:"reported"`
+	// Version is the semantic version features are gated on, empty if Reported
+	// can't be parsed.
+	Version string `json:"version"`
+}
+
+// SetVersion sets the version of Kong from reported, the version returned by the
+// root endpoint of the Admin API, and records it in the Kong version metric. If
+// reported can't be parsed, the version is set to the zero version, with which
+// no version-gated feature is used, and an error is returned for the caller to
+// warn about. decK still renders the configuration for deckVersionFloor then.
+func (k *Kong) SetVersion(reported string) error {
+	k.ReportedVersion = reported
+	v, err := util.ParseKongVersion(reported)
+	if err != nil {
+		k.Version = semver.Version{}
+		observeKongVersion(reported, unknownVersion)
+		return fmt.Errorf("parsing kong version %q: %w", reported, err)
+	}
+	k.Version = v
+	observeKongVersion(reported, v.String())
+	return nil
+}
+
+// deckVersion returns the version of Kong decK renders the configuration for:
+// the version set with SetVersion, or deckVersionFloor if it is unknown. Features
+// of the controller stay gated on the version set with SetVersion.
+func (k *Kong) deckVersion() semver.Version {
+	if k.Version.Equals(semver.Version{}) {
+		return deckVersionFloor
+	}
+	return k.Version
+}
+
+// VersionInfo returns the version of Kong set with SetVersion.
+func (k *Kong) VersionInfo() KongVersionInfo {
+	info := KongVersionInfo{Reported: k.ReportedVersion}
+	if !k.Version.Equals(semver.Version{}) {
+		info.Version = k.Version.String()
+	}
+	return info
+}
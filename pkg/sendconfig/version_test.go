@@ -0,0 +1,119 @@
+package sendconfig
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/blang/semver"
+	"github.com/kong/deck/file"
+	"github.com/kong/go-kong/kong"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestKongSetVersion(t *testing.T) {
+	for _, tt := range []struct {
+		reported    string
+		wantVersion semver.Version
+		wantErr     bool
+		wantInfo    KongVersionInfo
+	}{
+		{
+			reported:    "2.4.1",
+			wantVersion: semver.MustParse("2.4.1"),
+			wantInfo:    KongVersionInfo{Reported: "2.4.1", Version: "2.4.1"},
+		},
+		{
+			reported:    "2.5.0-rc.1",
+			wantVersion: semver.MustParse("2.5.0-rc1"),
+			wantInfo:    KongVersionInfo{Reported: "2.5.0-rc.1", Version: "2.5.0-rc1"},
+		},
+		{
+			reported:    "2.3.3.0-enterprise-edition",
+			wantVersion: semver.MustParse("2.3.3-0-enterprise"),
+			wantInfo:    KongVersionInfo{Reported: "2.3.3.0-enterprise-edition", Version: "2.3.3-0-enterprise"},
+		},
+		{
+			reported: "next",
+			wantErr:  true,
+			wantInfo: KongVersionInfo{Reported: "next"},
+		},
+		{
+			reported: "",
+			wantErr:  true,
+			wantInfo: KongVersionInfo{},
+		},
+	} {
+		t.Run(tt.reported, func(t *testing.T) {
+			// a previously parsed version must not survive a version which can't be parsed
+			k := Kong{Version: semver.MustParse("1.0.0")}
+			err := k.SetVersion(tt.reported)
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, tt.wantVersion, k.Version)
+			assert.Equal(t, tt.wantInfo, k.VersionInfo())
+
+			version := tt.wantInfo.Version
+			if version == "" {
+				version = unknownVersion
+			}
+			assert.Equal(t, 1, testutil.CollectAndCount(kongVersionInfo))
+			assert.Equal(t, float64(1), testutil.ToFloat64(kongVersionInfo.WithLabelValues(tt.reported, version)))
+		})
+	}
+}
+
+func TestPerformUpdateUnknownVersionTagsCredentials(t *testing.T) {
+	var lock sync.Mutex
+	var credentialTags [][]string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "GET" {
+			_, _ = w.Write([]byte(`{"data":[],"next":null}`))
+			return
+		}
+		body, _ := ioutil.ReadAll(r.Body)
+		if strings.Contains(r.URL.Path, "/key-auth") {
+			var credential struct {
+				Tags []string `json:"tags"`
+			}
+			require.NoError(t, json.Unmarshal(body, &credential))
+			lock.Lock()
+			credentialTags = append(credentialTags, credential.Tags)
+			lock.Unlock()
+		}
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write(body)
+	}))
+	defer server.Close()
+	client, err := kong.NewClient(kong.String(server.URL), server.Client())
+	require.NoError(t, err)
+
+	kongConfig := &Kong{URL: server.URL, Client: client, Concurrency: 1}
+	require.Error(t, kongConfig.SetVersion("next"))
+	content := &file.Content{
+		FormatVersion: "1.1",
+		Info:          &file.Info{SelectorTags: []string{"managed-by-ingress-controller"}},
+		Consumers: []file.FConsumer{{
+			Consumer: kong.Consumer{Username: kong.String("alice")},
+			KeyAuths: []*kong.KeyAuth{{Key: kong.String("secret")}},
+		}},
+	}
+
+	_, err = PerformUpdate(context.Background(), logrus.New(), kongConfig, false, false, content,
+		[]string{"managed-by-ingress-controller"}, nil, nil)
+	require.NoError(t, err)
+	assert.Equal(t, semver.Version{}, kongConfig.Version, "features stay gated on the unknown version")
+	assert.Equal(t, [][]string{{"managed-by-ingress-controller"}}, credentialTags,
+		"credentials are tagged as owned by the controller")
+}
@@ -0,0 +1,57 @@
+package sendconfig
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/kong/go-kong/kong"
+)
+
+// ValidateWorkspaceName returns an error if name is not a valid name for a
+// workspace in Kong. Kong accepts ASCII letters and digits and the characters
+// '.', '-', '_' and '~' in workspace names.
+func ValidateWorkspaceName(name string) error {
+	if name == "" {
+		return fmt.Errorf("workspace name must not be empty")
+	}
+	for _, r := range name {
+		if ('a' <= r && r <= 'z') || ('A' <= r && r <= 'Z') || ('0' <= r && r <= '9') ||
+			strings.ContainsRune(".-_~", r) {
+			continue
+		}
+		return fmt.Errorf("invalid workspace name '%s': character '%c' is not allowed; "+
+			"only ASCII letters, digits and '.', '-', '_', '~' may be used", name, r)
+	}
+	return nil
+}
+
+// EnsureWorkspace makes sure the workspace exists in the Kong behind client,
+// creating it if it does not.
+func EnsureWorkspace(ctx context.Context, client AdminAPIClient, workspace string) error {
+	req, err := client.NewRequest("GET", "/workspaces/"+workspace, nil, nil)
+	if err != nil {
+		return err
+	}
+	_, err = client.Do(ctx, req, nil)
+	if err != nil {
+		if kong.IsNotFoundErr(err) {
+			if err := createWorkspace(ctx, client, workspace); err != nil {
+				return fmt.Errorf("creating workspace '%v': %w", workspace, err)
+			}
+			return nil
+		}
+		return fmt.Errorf("looking up workspace '%v': %w", workspace, err)
+	}
+	return nil
+}
+
+func createWorkspace(ctx context.Context, client AdminAPIClient, workspace string) error {
+	body := map[string]string{"name": workspace}
+	req, err := client.NewRequest("POST", "/workspaces", nil, body)
+	if err != nil {
+		return err
+	}
+	_, err = client.Do(ctx, req, nil)
+	return err
+}
@@ -0,0 +1,73 @@
+package sendconfig
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/kong/go-kong/kong"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateWorkspaceName(t *testing.T) {
+	for _, name := range []string{"team-a", "team_a.v2~", "Team1"} {
+		assert.NoError(t, ValidateWorkspaceName(name), name)
+	}
+	for _, name := range []string{"", "team a", "team/a", "team:a", "Équipe1", "team٣"} {
+		assert.Error(t, ValidateWorkspaceName(name), name)
+	}
+}
+
+func TestEnsureWorkspace(t *testing.T) {
+	tests := []struct {
+		name       string
+		lookup     int
+		wantCreate bool
+		wantErr    bool
+	}{
+		{
+			name:   "workspace exists",
+			lookup: http.StatusOK,
+		},
+		{
+			name:       "workspace is created when missing",
+			lookup:     http.StatusNotFound,
+			wantCreate: true,
+		},
+		{
+			name:    "lookup fails",
+			lookup:  http.StatusForbidden,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			created := false
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				switch {
+				case r.Method == "GET" && r.URL.Path == "/workspaces/team-a":
+					w.WriteHeader(tt.lookup)
+					_, _ = w.Write([]byte(`{}`))
+				case r.Method == "POST" && r.URL.Path == "/workspaces":
+					created = true
+					w.WriteHeader(http.StatusCreated)
+					_, _ = w.Write([]byte(`{}`))
+				default:
+					w.WriteHeader(http.StatusNotFound)
+				}
+			}))
+			defer server.Close()
+			client, err := kong.NewClient(kong.String(server.URL), server.Client())
+			assert.NoError(t, err)
+
+			err = EnsureWorkspace(context.Background(), client, "team-a")
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, tt.wantCreate, created)
+		})
+	}
+}
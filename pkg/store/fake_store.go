@@ -12,6 +12,7 @@ import (
 	networkingv1beta1 "k8s.io/api/networking/v1beta1"
 	"k8s.io/client-go/tools/cache"
 	knative "knative.dev/networking/pkg/apis/networking/v1alpha1"
+	gatewayv1alpha1 "sigs.k8s.io/gateway-api/apis/v1alpha1"
 )
 
 func keyFunc(obj interface{}) (string, error) {
@@ -41,6 +42,8 @@ type FakeObjects struct {
 	KongConsumers      []*configurationv1.KongConsumer
 
 	KnativeIngresses []*knative.Ingress
+
+	HTTPRoutes []*gatewayv1alpha1.HTTPRoute
 }
 
 // NewFakeStore creates a store backed by the objects passed in as arguments.
@@ -132,6 +135,12 @@ func NewFakeStore(
 			return nil, err
 		}
 	}
+	httpRouteStore := cache.NewStore(keyFunc)
+	for _, route := range objects.HTTPRoutes {
+		if err := httpRouteStore.Add(route); err != nil {
+			return nil, err
+		}
+	}
 	s = Store{
 		stores: CacheStores{
 			IngressV1beta1: ingressV1beta1Store,
@@ -148,6 +157,8 @@ func NewFakeStore(
 			Configuration: kongIngressStore,
 
 			KnativeIngress: knativeIngressStore,
+
+			HTTPRoute: httpRouteStore,
 		},
 		ingressClass:                annotations.DefaultIngressClass,
 		isValidIngressClass:         annotations.IngressClassValidatorFuncFromObjectMeta(annotations.DefaultIngressClass),
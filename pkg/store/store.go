@@ -35,6 +35,7 @@ import (
 	"k8s.io/apimachinery/pkg/selection"
 	"k8s.io/client-go/tools/cache"
 	knative "knative.dev/networking/pkg/apis/networking/v1alpha1"
+	gatewayv1alpha1 "sigs.k8s.io/gateway-api/apis/v1alpha1"
 )
 
 const (
@@ -59,6 +60,7 @@ func (e ErrNotFound) Error() string {
 // about ingresses, services, secrets and ingress annotations.
 type Storer interface {
 	GetSecret(namespace, name string) (*apiv1.Secret, error)
+	ListSecretsNamed(name string) ([]*apiv1.Secret, error)
 	GetService(namespace, name string) (*apiv1.Service, error)
 	GetEndpointsForService(namespace, name string) (*apiv1.Endpoints, error)
 	GetKongIngress(namespace, name string) (*configurationv1.KongIngress, error)
@@ -71,6 +73,7 @@ type Storer interface {
 	ListTCPIngresses() ([]*configurationv1beta1.TCPIngress, error)
 	ListUDPIngresses() ([]*v1alpha1.UDPIngress, error)
 	ListKnativeIngresses() ([]*knative.Ingress, error)
+	ListHTTPRoutes() ([]*gatewayv1alpha1.HTTPRoute, error)
 	ListGlobalKongPlugins() ([]*configurationv1.KongPlugin, error)
 	ListGlobalKongClusterPlugins() ([]*configurationv1.KongClusterPlugin, error)
 	ListKongConsumers() []*configurationv1.KongConsumer
@@ -114,6 +117,8 @@ type CacheStores struct {
 	Configuration cache.Store
 
 	KnativeIngress cache.Store
+
+	HTTPRoute cache.Store
 }
 
 // New creates a new object store to be used in the ingress controller
@@ -162,6 +167,18 @@ func (s Store) GetSecret(namespace, name string) (*apiv1.Secret, error) {
 	return secret.(*apiv1.Secret), nil
 }
 
+// ListSecretsNamed returns the Secrets of the given name in any namespace
+func (s Store) ListSecretsNamed(name string) ([]*apiv1.Secret, error) {
+	var secrets []*apiv1.Secret
+	for _, item := range s.stores.Secret.List() {
+		secret, ok := item.(*apiv1.Secret)
+		if ok && secret.Name == name {
+			secrets = append(secrets, secret)
+		}
+	}
+	return secrets, nil
+}
+
 // GetService returns a Service using the namespace and name as key
 func (s Store) GetService(namespace, name string) (*apiv1.Service, error) {
 	key := fmt.Sprintf("%v/%v", namespace, name)
@@ -250,6 +267,22 @@ func (s Store) ListUDPIngresses() ([]*v1alpha1.UDPIngress, error) {
 	return ingresses, err
 }
 
+// ListHTTPRoutes returns the list of Gateway API HTTPRoutes. They are not filtered
+// by ingress class, as the store is only fed with the routes attached to Gateways of Kong.
+func (s Store) ListHTTPRoutes() ([]*gatewayv1alpha1.HTTPRoute, error) {
+	var routes []*gatewayv1alpha1.HTTPRoute
+	if s.stores.HTTPRoute == nil {
+		return routes, nil
+	}
+	err := cache.ListAll(s.stores.HTTPRoute, labels.NewSelector(),
+		func(ob interface{}) {
+			if route, ok := ob.(*gatewayv1alpha1.HTTPRoute); ok {
+				routes = append(routes, route)
+			}
+		})
+	return routes, err
+}
+
 func (s Store) validKnativeIngressClass(objectMeta *metav1.ObjectMeta) bool {
 	ingressAnnotationValue := objectMeta.GetAnnotations()[knativeIngressClassKey]
 	return ingressAnnotationValue == s.ingressClass
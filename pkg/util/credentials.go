@@ -0,0 +1,40 @@
+package util
+
+import (
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+const (
+	// DefaultCredentialTypeKey is the data field of a Secret holding the type
+	// of the Kong credential it contains, unless configured otherwise.
+	DefaultCredentialTypeKey = "kongCredType"
+
+	// CredentialTypeLabel is a label which can hold the type of the Kong
+	// credential a Secret contains, as an alternative to its data field.
+	CredentialTypeLabel = "konghq.com/credential"
+)
+
+// CredentialType returns the type of the Kong credential held by secret, read
+// from its CredentialTypeLabel label or its data field key, which defaults to
+// DefaultCredentialTypeKey if empty. ok is false if secret holds no credential.
+// An error is returned if both the label and the data field are set but disagree.
+func CredentialType(secret *corev1.Secret, key string) (credType string, ok bool, err error) {
+	if key == "" {
+		key = DefaultCredentialTypeKey
+	}
+	labelType, labelOK := secret.Labels[CredentialTypeLabel]
+	dataType, dataOK := secret.Data[key]
+	switch {
+	case labelOK && dataOK && labelType != string(dataType):
+		return "", false, fmt.Errorf("credential type %q of label %s conflicts with credential type %q of field %s",
+			labelType, CredentialTypeLabel, string(dataType), key)
+	case labelOK:
+		return labelType, true, nil
+	case dataOK:
+		return string(dataType), true, nil
+	default:
+		return "", false, nil
+	}
+}
@@ -0,0 +1,69 @@
+package util
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestCredentialType(t *testing.T) {
+	for _, tt := range []struct {
+		name     string
+		labels   map[string]string
+		data     map[string][]byte
+		key      string
+		wantType string
+		wantOK   bool
+		wantErr  bool
+	}{
+		{
+			name: "no credential",
+			data: map[string][]byte{"key": []byte("foo")},
+		},
+		{
+			name:     "default data field",
+			data:     map[string][]byte{"kongCredType": []byte("key-auth")},
+			wantType: "key-auth",
+			wantOK:   true,
+		},
+		{
+			name:     "custom data field",
+			data:     map[string][]byte{"kongCredType": []byte("basic-auth"), "type": []byte("key-auth")},
+			key:      "type",
+			wantType: "key-auth",
+			wantOK:   true,
+		},
+		{
+			name:     "label",
+			labels:   map[string]string{CredentialTypeLabel: "key-auth"},
+			wantType: "key-auth",
+			wantOK:   true,
+		},
+		{
+			name:     "label and data field agreeing",
+			labels:   map[string]string{CredentialTypeLabel: "key-auth"},
+			data:     map[string][]byte{"kongCredType": []byte("key-auth")},
+			wantType: "key-auth",
+			wantOK:   true,
+		},
+		{
+			name:    "label and data field disagreeing",
+			labels:  map[string]string{CredentialTypeLabel: "key-auth"},
+			data:    map[string][]byte{"kongCredType": []byte("basic-auth")},
+			wantErr: true,
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			secret := &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Labels: tt.labels},
+				Data:       tt.data,
+			}
+			credType, ok, err := CredentialType(secret, tt.key)
+			assert.Equal(t, tt.wantErr, err != nil, "error: %v", err)
+			assert.Equal(t, tt.wantOK, ok)
+			assert.Equal(t, tt.wantType, credType)
+		})
+	}
+}
@@ -18,12 +18,15 @@ package util
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"os"
 	"strings"
 
 	apiv1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/version"
+	"k8s.io/client-go/discovery"
 	clientset "k8s.io/client-go/kubernetes"
 )
 
@@ -37,6 +40,20 @@ func ParseNameNS(input string) (string, string, error) {
 	return nsName[0], nsName[1], nil
 }
 
+// ServerVersion retrieves the version of the Kubernetes API server. Unlike
+// the ServerVersion method of discovery clients, it gives up once ctx is done.
+func ServerVersion(ctx context.Context, client discovery.DiscoveryInterface) (*version.Info, error) {
+	body, err := client.RESTClient().Get().AbsPath("/version").Do(ctx).Raw()
+	if err != nil {
+		return nil, err
+	}
+	var info version.Info
+	if err := json.Unmarshal(body, &info); err != nil {
+		return nil, fmt.Errorf("unable to parse the server version: %w", err)
+	}
+	return &info, nil
+}
+
 // GetNodeIPOrName returns the IP address or the name of a node in the cluster
 func GetNodeIPOrName(ctx context.Context, kubeClient clientset.Interface, name string) string {
 	node, err := kubeClient.CoreV1().Nodes().Get(ctx, name, metav1.GetOptions{})
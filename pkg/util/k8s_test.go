@@ -18,12 +18,18 @@ package util
 
 import (
 	"context"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"testing"
+	"time"
 
+	"github.com/stretchr/testify/assert"
 	apiv1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
 	testclient "k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/rest"
 )
 
 func TestParseNameNS(t *testing.T) {
@@ -243,3 +249,34 @@ func TestGetPodDetails(t *testing.T) {
 		t.Errorf("expected a PodInfo but returned nil")
 	}
 }
+
+func TestServerVersion(t *testing.T) {
+	stall := false
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !stall {
+			_, _ = w.Write([]byte(`{"major":"1","minor":"20","gitVersion":"v1.20.2"}`))
+			return
+		}
+		select {
+		case <-r.Context().Done():
+		case <-release:
+		}
+	}))
+	defer server.Close()
+	defer close(release)
+	client, err := kubernetes.NewForConfig(&rest.Config{Host: server.URL})
+	assert.NoError(t, err)
+
+	info, err := ServerVersion(context.Background(), client.Discovery())
+	assert.NoError(t, err)
+	assert.Equal(t, "v1.20.2", info.GitVersion)
+
+	stall = true
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, err = ServerVersion(ctx, client.Discovery())
+	assert.Error(t, err)
+	assert.Less(t, int64(time.Since(start)), int64(time.Second))
+}
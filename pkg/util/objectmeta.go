@@ -9,6 +9,8 @@ type K8sObjectInfo struct {
 	Name        string
 	Namespace   string
 	Annotations map[string]string
+	// Kind is the kind of the object, empty if it isn't recorded.
+	Kind string
 }
 
 func deepCopy(m map[string]string) map[string]string {
@@ -26,3 +28,10 @@ func FromK8sObject(obj metav1.Object) K8sObjectInfo {
 		Annotations: deepCopy(obj.GetAnnotations()),
 	}
 }
+
+// FromK8sObjectOfKind describes obj, an object of kind.
+func FromK8sObjectOfKind(obj metav1.Object, kind string) K8sObjectInfo {
+	info := FromK8sObject(obj)
+	info.Kind = kind
+	return info
+}
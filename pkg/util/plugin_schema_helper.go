@@ -3,6 +3,7 @@ package util
 import (
 	"context"
 	"fmt"
+	"net/http"
 
 	"github.com/kong/go-kong/kong"
 )
@@ -10,14 +11,20 @@ import (
 // FIXME
 // decK will release this official API soon, use that and remove this code.
 
+// AdminAPIRequester sends requests to Kong's Admin API, as *kong.Client does.
+type AdminAPIRequester interface {
+	NewRequest(method, endpoint string, qs interface{}, body interface{}) (*http.Request, error)
+	Do(ctx context.Context, req *http.Request, v interface{}) (*kong.Response, error)
+}
+
 // PluginSchemaStore retrives a schema of a Plugin from Kong.
 type PluginSchemaStore struct {
-	client  *kong.Client
+	client  AdminAPIRequester
 	schemas map[string]map[string]interface{}
 }
 
 // NewPluginSchemaStore creates a PluginSchemaStore.
-func NewPluginSchemaStore(client *kong.Client) *PluginSchemaStore {
+func NewPluginSchemaStore(client AdminAPIRequester) *PluginSchemaStore {
 	return &PluginSchemaStore{
 		client:  client,
 		schemas: make(map[string]map[string]interface{}),
@@ -1,21 +1,37 @@
 package util
 
 import (
+	"context"
+	"fmt"
 	"net"
+	"regexp"
+	"sort"
 	"strconv"
+	"strings"
 	"time"
 
+	"github.com/hashicorp/go-uuid"
 	"github.com/sirupsen/logrus"
+	apiv1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clientset "k8s.io/client-go/kubernetes"
 )
 
 var (
 	reportsHost  = "kong-hf.konghq.com"
 	reportsPort  = 61829
 	pingInterval = 3600
+	// callTimeout bounds each network call made by the reporter, so that an
+	// unresponsive dependency can't stall it.
+	callTimeout = 5 * time.Second
 )
 
 const (
 	prd = "kic"
+
+	// installationIDKey is the key of the ConfigMap data holding the installation ID.
+	installationIDKey = "id"
 )
 
 // Info holds the metadata to be sent as part of a report.
@@ -26,20 +42,58 @@ type Info struct {
 	Hostname          string
 	KongDB            string
 	ID                string
+
+	// Controllers are the kinds of resources the controller watches, e.g.
+	// "ingress" or "knativeingress".
+	Controllers []string
+}
+
+// Usage holds aggregate counts of the features in use. It never holds the
+// names or namespaces of the objects counted.
+type Usage struct {
+	// Ingresses is the number of Ingresses managed by the controller.
+	Ingresses int
+	// KongPlugins is the number of KongPlugins by plugin name, e.g. "key-auth".
+	KongPlugins map[string]int
 }
 
 // Reporter sends anonymous reports of runtime properties and
 // errors in Kong.
+//
+// Every report holds, as semicolon-separated key=value pairs:
+// the signal (kic-start or kic-ping) and the uptime in seconds; the versions
+// of the controller (v), Kubernetes (k8sv) and Kong (kv); the database
+// of Kong (db); a random ID generated at startup (id); the hostname (hn); the
+// kinds of resources watched (ctrls) and, if Usage is set, the number of
+// managed Ingresses (ings) and of KongPlugins by plugin name (plugins).
 type Reporter struct {
 	Info Info
 
+	// Usage returns the counts sent with every report, if set.
+	Usage func() Usage
+
+	// KubernetesVersion, if set, is called when the reporter starts to fill
+	// in Info.KubernetesVersion. ctx is done once the call times out.
+	KubernetesVersion func(ctx context.Context) (string, error)
+
 	serializedInfo string
 	conn           *net.UDPConn
 
 	Logger logrus.FieldLogger
 }
 
-func (r *Reporter) once() error {
+func (r *Reporter) once(ctx context.Context) error {
+	if r.KubernetesVersion != nil {
+		callCtx, cancel := context.WithTimeout(ctx, callTimeout)
+		k8sVersion, err := r.KubernetesVersion(callCtx)
+		cancel()
+		if err != nil {
+			r.Logger.Warnf("failed to fetch k8s api-server version: %v", err)
+		} else {
+			r.Info.KubernetesVersion = k8sVersion
+		}
+	}
+
 	var serializedInfo string
 	serializedInfo = serializedInfo + "v=" + r.Info.KICVersion + ";"
 	serializedInfo = serializedInfo + "k8sv=" + r.Info.KubernetesVersion + ";"
@@ -47,10 +101,12 @@ func (r *Reporter) once() error {
 	serializedInfo = serializedInfo + "db=" + r.Info.KongDB + ";"
 	serializedInfo = serializedInfo + "id=" + r.Info.ID + ";"
 	serializedInfo = serializedInfo + "hn=" + r.Info.Hostname + ";"
+	if len(r.Info.Controllers) > 0 {
+		serializedInfo = serializedInfo + "ctrls=" + strings.Join(r.Info.Controllers, ",") + ";"
+	}
 	r.serializedInfo = serializedInfo
 
-	addr, err := net.ResolveUDPAddr("udp", reportsHost+":"+
-		strconv.Itoa(reportsPort))
+	addr, err := resolveUDPAddr(ctx, reportsHost, reportsPort)
 	if err != nil {
 		return err
 	}
@@ -62,9 +118,41 @@ func (r *Reporter) once() error {
 	return nil
 }
 
+// resolveUDPAddr resolves host like net.ResolveUDPAddr does, preferring IPv4
+// addresses, but within callTimeout and until ctx is done.
+func resolveUDPAddr(ctx context.Context, host string, port int) (*net.UDPAddr, error) {
+	ctx, cancel := context.WithTimeout(ctx, callTimeout)
+	defer cancel()
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+	if len(addrs) == 0 {
+		return nil, fmt.Errorf("no address found for %s", host)
+	}
+	addr := addrs[0]
+	for _, a := range addrs {
+		if a.IP.To4() != nil {
+			addr = a
+			break
+		}
+	}
+	return &net.UDPAddr{IP: addr.IP, Zone: addr.Zone, Port: port}, nil
+}
+
 // Run starts the reporter. It will send reports until done is closed.
 func (r Reporter) Run(done <-chan struct{}) {
-	err := r.once()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		select {
+		case <-done:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	err := r.once(ctx)
 	if err != nil {
 		r.Logger.Errorf("failed to initialize reporter: %s", err)
 		return
@@ -73,12 +161,17 @@ func (r Reporter) Run(done <-chan struct{}) {
 
 	r.sendStart()
 	ticker := time.NewTicker(time.Duration(pingInterval) * time.Second)
+	defer ticker.Stop()
 	i := 1
 	for {
 		select {
-		case <-done:
+		case <-ctx.Done():
 			return
 		case <-ticker.C:
+			// both cases may be ready at once, and select picks either
+			if ctx.Err() != nil {
+				return
+			}
 			r.sendPing(i * pingInterval)
 			i++
 		}
@@ -98,8 +191,80 @@ func (r Reporter) sendPing(uptime int) {
 func (r Reporter) send(signal string, uptime int) {
 	message := "<14>signal=" + signal + ";uptime=" +
 		strconv.Itoa(uptime) + ";" + r.serializedInfo
+	if r.Usage != nil {
+		message = message + serializeUsage(r.Usage())
+	}
 	_, err := r.conn.Write([]byte(message))
 	if err != nil {
 		r.Logger.Errorf("failed to send report: %s", err)
 	}
 }
+
+// pluginNameRegex matches the names of the plugins reported as is; the others
+// are counted as "other", so that the name can't break the report format.
+var pluginNameRegex = regexp.MustCompile(`^[a-zA-Z0-9_-]+$`)
+
+func serializeUsage(usage Usage) string {
+	pluginCounts := map[string]int{}
+	for name, count := range usage.KongPlugins {
+		if !pluginNameRegex.MatchString(name) {
+			name = "other"
+		}
+		pluginCounts[name] += count
+	}
+	plugins := make([]string, 0, len(pluginCounts))
+	for name, count := range pluginCounts {
+		plugins = append(plugins, name+":"+strconv.Itoa(count))
+	}
+	sort.Strings(plugins)
+
+	return "ings=" + strconv.Itoa(usage.Ingresses) + ";" +
+		"plugins=" + strings.Join(plugins, ",") + ";"
+}
+
+// InstallationID returns the ID identifying this installation in reports. It is
+// persisted in the ConfigMap namespace/name, which is created with a random ID
+// if needed, so that it stays the same across restarts of the controller.
+func InstallationID(ctx context.Context, kubeClient clientset.Interface, namespace, name string) (string, error) {
+	configMaps := kubeClient.CoreV1().ConfigMaps(namespace)
+	configMap, err := configMaps.Get(ctx, name, metav1.GetOptions{})
+	found := err == nil
+	if err != nil && !apierrors.IsNotFound(err) {
+		return "", fmt.Errorf("fetching ConfigMap %s/%s: %w", namespace, name, err)
+	}
+	if found && configMap.Data[installationIDKey] != "" {
+		return configMap.Data[installationIDKey], nil
+	}
+
+	id, err := uuid.GenerateUUID()
+	if err != nil {
+		return "", err
+	}
+	if found {
+		if configMap.Data == nil {
+			configMap.Data = map[string]string{}
+		}
+		configMap.Data[installationIDKey] = id
+		_, err = configMaps.Update(ctx, configMap, metav1.UpdateOptions{})
+	} else {
+		_, err = configMaps.Create(ctx, &apiv1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name},
+			Data:       map[string]string{installationIDKey: id},
+		}, metav1.CreateOptions{})
+	}
+	if apierrors.IsAlreadyExists(err) || apierrors.IsConflict(err) {
+		// another replica persisted an ID first, use that one
+		configMap, err = configMaps.Get(ctx, name, metav1.GetOptions{})
+		if err == nil && configMap.Data[installationIDKey] == "" {
+			err = fmt.Errorf("no %q key", installationIDKey)
+		}
+		if err != nil {
+			return "", fmt.Errorf("fetching ConfigMap %s/%s: %w", namespace, name, err)
+		}
+		return configMap.Data[installationIDKey], nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("persisting installation ID in ConfigMap %s/%s: %w", namespace, name, err)
+	}
+	return id, nil
+}
@@ -2,14 +2,23 @@ package util
 
 import (
 	"bytes"
+	"context"
 	"net"
 	"os"
 	"strconv"
 	"sync"
 	"testing"
+	"time"
 
 	"github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	apiv1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	testclient "k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
 )
 
 func TestMain(m *testing.M) {
@@ -32,7 +41,7 @@ func TestReporterOnce(t *testing.T) {
 		Info:   info,
 		Logger: logrus.New(),
 	}
-	assert.Nil(reporter.once())
+	assert.Nil(reporter.once(context.Background()))
 	want := "v=kic.version;k8sv=k8s.version;kv=kong.version;db=off;" +
 		"id=6acb7447-eedf-4815-a193-d714c5108f7b;hn=example.local;"
 	assert.Equal(want, reporter.serializedInfo)
@@ -52,7 +61,7 @@ func TestReporterSendStart(t *testing.T) {
 		Info:   info,
 		Logger: logrus.New(),
 	}
-	assert.Nil(reporter.once())
+	assert.Nil(reporter.once(context.Background()))
 	addr, err := net.ResolveUDPAddr("udp", reportsHost+
 		":"+strconv.Itoa(reportsPort))
 	assert.Nil(err)
@@ -86,7 +95,7 @@ func TestReporterSendPing(t *testing.T) {
 		Info:   info,
 		Logger: logrus.New(),
 	}
-	assert.Nil(reporter.once())
+	assert.Nil(reporter.once(context.Background()))
 	addr, err := net.ResolveUDPAddr("udp", reportsHost+
 		":"+strconv.Itoa(reportsPort))
 	assert.Nil(err)
@@ -120,7 +129,7 @@ func TestReporterRun(t *testing.T) {
 		Info:   info,
 		Logger: logrus.New(),
 	}
-	assert.Nil(reporter.once())
+	assert.Nil(reporter.once(context.Background()))
 	addr, err := net.ResolveUDPAddr("udp", reportsHost+
 		":"+strconv.Itoa(reportsPort))
 	assert.Nil(err)
@@ -163,3 +172,143 @@ func TestReporterRun(t *testing.T) {
 	}()
 	wg.Wait()
 }
+
+func TestReporterSendUsage(t *testing.T) {
+	assert := assert.New(t)
+	info := Info{
+		KubernetesVersion: "k8s.version",
+		KongVersion:       "kong.version",
+		KICVersion:        "kic.version",
+		Hostname:          "example.local",
+		KongDB:            "off",
+		ID:                "6acb7447-eedf-4815-a193-d714c5108f7b",
+		Controllers:       []string{"ingress", "tcpingress"},
+	}
+	reporter := Reporter{
+		Info: info,
+		Usage: func() Usage {
+			return Usage{
+				Ingresses: 3,
+				KongPlugins: map[string]int{
+					"rate-limiting": 1,
+					"key-auth":      2,
+					"bad;name=":     1,
+				},
+			}
+		},
+		Logger: logrus.New(),
+	}
+	assert.Nil(reporter.once(context.Background()))
+	addr, err := net.ResolveUDPAddr("udp", reportsHost+
+		":"+strconv.Itoa(reportsPort))
+	assert.Nil(err)
+	conn, err := net.ListenUDP("udp", addr)
+	assert.Nil(err)
+	defer conn.Close()
+
+	reporter.sendPing(42)
+
+	buffer := make([]byte, 1024)
+	n, _, err := conn.ReadFromUDP(buffer)
+	serialized := "<14>signal=kic-ping;uptime=42;v=kic.version;" +
+		"k8sv=k8s.version;kv=kong.version;db=off;" +
+		"id=6acb7447-eedf-4815-a193-d714c5108f7b;hn=example.local;" +
+		"ctrls=ingress,tcpingress;" +
+		"ings=3;plugins=key-auth:2,other:1,rate-limiting:1;"
+	assert.Equal(len(serialized), n)
+	assert.Nil(err)
+	assert.Equal(serialized, string(bytes.Trim(buffer, "\x00")))
+}
+
+func TestReporterBoundsStalledCalls(t *testing.T) {
+	defer func(timeout time.Duration) { callTimeout = timeout }(callTimeout)
+	callTimeout = 100 * time.Millisecond
+
+	reporter := Reporter{
+		Info: Info{KICVersion: "kic.version"},
+		// stalls like an unresponsive API server
+		KubernetesVersion: func(ctx context.Context) (string, error) {
+			<-ctx.Done()
+			return "", ctx.Err()
+		},
+		Logger: logrus.New(),
+	}
+	start := time.Now()
+	assert.Nil(t, reporter.once(context.Background()))
+	defer reporter.conn.Close()
+	assert.Less(t, int64(time.Since(start)), int64(time.Second))
+	assert.Equal(t, "v=kic.version;k8sv=;kv=;db=;id=;hn=;", reporter.serializedInfo)
+}
+
+func TestReporterRunStopsWhenDone(t *testing.T) {
+	reporter := Reporter{
+		KubernetesVersion: func(ctx context.Context) (string, error) {
+			return "k8s.version", nil
+		},
+		Logger: logrus.New(),
+	}
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		reporter.Run(done)
+		close(stopped)
+	}()
+	close(done)
+
+	select {
+	case <-stopped:
+	case <-time.After(time.Second):
+		t.Fatal("reporter didn't stop once done was closed")
+	}
+}
+
+func TestInstallationID(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("persisted across calls", func(t *testing.T) {
+		kubeClient := testclient.NewSimpleClientset()
+		id, err := InstallationID(ctx, kubeClient, "kong", "reports")
+		require.NoError(t, err)
+		assert.NotEmpty(t, id)
+
+		configMap, err := kubeClient.CoreV1().ConfigMaps("kong").Get(ctx, "reports", metav1.GetOptions{})
+		require.NoError(t, err)
+		assert.Equal(t, id, configMap.Data["id"])
+
+		again, err := InstallationID(ctx, kubeClient, "kong", "reports")
+		require.NoError(t, err)
+		assert.Equal(t, id, again)
+	})
+
+	t.Run("added to an existing ConfigMap", func(t *testing.T) {
+		kubeClient := testclient.NewSimpleClientset(&apiv1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "kong", Name: "reports"},
+			Data:       map[string]string{"other": "kept"},
+		})
+		id, err := InstallationID(ctx, kubeClient, "kong", "reports")
+		require.NoError(t, err)
+
+		configMap, err := kubeClient.CoreV1().ConfigMaps("kong").Get(ctx, "reports", metav1.GetOptions{})
+		require.NoError(t, err)
+		assert.Equal(t, map[string]string{"id": id, "other": "kept"}, configMap.Data)
+	})
+
+	t.Run("ID persisted concurrently by another replica", func(t *testing.T) {
+		kubeClient := testclient.NewSimpleClientset()
+		kubeClient.PrependReactor("create", "configmaps",
+			func(action k8stesting.Action) (bool, runtime.Object, error) {
+				// the other replica wins the race between our get and create
+				other := &apiv1.ConfigMap{
+					ObjectMeta: metav1.ObjectMeta{Namespace: "kong", Name: "reports"},
+					Data:       map[string]string{"id": "other-replica"},
+				}
+				if err := kubeClient.Tracker().Add(other); err != nil {
+					return true, nil, err
+				}
+				return true, nil, apierrors.NewAlreadyExists(apiv1.Resource("configmaps"), "reports")
+			})
+		id, err := InstallationID(ctx, kubeClient, "kong", "reports")
+		require.NoError(t, err)
+		assert.Equal(t, "other-replica", id)
+	})
+}
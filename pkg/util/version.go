@@ -0,0 +1,41 @@
+package util
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/blang/semver"
+)
+
+var kongVersionFormat = regexp.MustCompile(`(\d+\.\d+)(?:[\.-](\d+))?(?:\-?(.+)$|$)`)
+
+// ParseKongVersion parses v, the version reported by the root endpoint of Kong's Admin API, into a
+// semantic version. The versions of enterprise editions and of some pre-releases, which aren't
+// semantic versions, are fixed.
+func ParseKongVersion(v string) (semver.Version, error) {
+	// fix enterprise edition semver adding patch number
+	// fix enterprise edition version with dash
+	// fix bad version formats like 0.13.0preview1
+	m := kongVersionFormat.FindStringSubmatch(v)
+	if len(m) != 4 {
+		return semver.Version{}, fmt.Errorf("Unknown Kong version : '%v'", v)
+	}
+	if m[2] == "" {
+		m[2] = "0"
+	}
+	if m[3] != "" {
+		m[3] = "-" + strings.Replace(m[3], "enterprise-edition", "enterprise", 1)
+		m[3] = strings.Replace(m[3], ".", "", -1)
+	}
+	v = fmt.Sprintf("%s.%s%s", m[1], m[2], m[3])
+	return semver.Make(v)
+}
+
+// KongVersionAtLeast tells whether a Kong of version v has the features introduced in version min.
+// The pre-release part of v is ignored, as enterprise editions and release candidates of a version
+// have its features. The zero version, used when the version of Kong is unknown, has none of them.
+func KongVersionAtLeast(v, min semver.Version) bool {
+	release := semver.Version{Major: v.Major, Minor: v.Minor, Patch: v.Patch}
+	return !release.Equals(semver.Version{}) && release.GTE(min)
+}
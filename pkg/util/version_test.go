@@ -0,0 +1,72 @@
+package util
+
+import (
+	"testing"
+
+	"github.com/blang/semver"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseKongVersion(t *testing.T) {
+	validVersions := map[string]string{
+		"0.14.1":                          "0.14.1",
+		"0.14.2rc":                        "0.14.2-rc",
+		"0.14.2rc1":                       "0.14.2-rc1",
+		"0.14.2preview":                   "0.14.2-preview",
+		"0.14.2preview1":                  "0.14.2-preview1",
+		"0.33-enterprise-edition":         "0.33.0-enterprise",
+		"0.33-1-enterprise-edition":       "0.33.1-enterprise",
+		"1.3.0.0-enterprise-edition-lite": "1.3.0-0-enterprise-lite",
+		"1.3.0.0-enterprise-lite":         "1.3.0-0-enterprise-lite",
+		"2.3.2":                           "2.3.2",
+		"2.4.0-rc.1":                      "2.4.0-rc1",
+		"2.4.0rc2":                        "2.4.0-rc2",
+		"2.5.0-beta.1":                    "2.5.0-beta1",
+		"2.3.3.0-enterprise-edition":      "2.3.3-0-enterprise",
+	}
+	for inputVersion, expectedVersion := range validVersions {
+		v, err := ParseKongVersion(inputVersion)
+		if err != nil {
+			t.Errorf("error converting %s: %v", inputVersion, err)
+		} else if v.String() != expectedVersion {
+			t.Errorf("converting %s, expecting %s, getting %s", inputVersion, expectedVersion, v.String())
+		}
+	}
+
+	invalidVersions := []string{
+		"",
+		"0-1-1",
+		"next",
+	}
+	for _, inputVersion := range invalidVersions {
+		_, err := ParseKongVersion(inputVersion)
+		if err == nil {
+			t.Errorf("expecting error converting %s, getting no errors", inputVersion)
+		}
+	}
+}
+
+func TestKongVersionAtLeast(t *testing.T) {
+	min := semver.MustParse("2.3.2")
+	for _, tt := range []struct {
+		version string
+		want    bool
+	}{
+		{"2.3.2", true},
+		{"2.4.0", true},
+		{"2.3.1", false},
+		{"1.5.0", false},
+		{"2.3.2-rc1", true},
+		{"2.3.2.0-enterprise-edition", true},
+		{"2.3.1.2-enterprise-edition", false},
+		{"2.4.0-beta.1", true},
+	} {
+		t.Run(tt.version, func(t *testing.T) {
+			v, err := ParseKongVersion(tt.version)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, KongVersionAtLeast(v, min))
+		})
+	}
+	assert.False(t, KongVersionAtLeast(semver.Version{}, semver.Version{}),
+		"an unknown version must have no version-gated feature")
+}
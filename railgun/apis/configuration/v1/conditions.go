@@ -0,0 +1,33 @@
+/*
+Copyright 2021 Kong, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+// Conditions reported in the status of the Kong configuration objects.
+const (
+	// ProgrammedConditionType tells whether the configuration of the object was applied to Kong.
+	ProgrammedConditionType = "Programmed"
+
+	// ProgrammedReasonSynced is the reason of the Programmed condition when the configuration holding
+	// the object was successfully synced to Kong.
+	ProgrammedReasonSynced = "Synced"
+	// ProgrammedReasonSyncFailed is the reason of the Programmed condition when the configuration could
+	// not be synced to Kong since the object changed.
+	ProgrammedReasonSyncFailed = "SyncFailed"
+	// ProgrammedReasonRejected is the reason of the Programmed condition when Kong rejected an entity
+	// generated from the object.
+	ProgrammedReasonRejected = "Rejected"
+)
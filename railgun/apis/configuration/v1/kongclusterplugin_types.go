@@ -52,6 +52,9 @@ type KongClusterPlugin struct {
 	// Protocols configures plugin to run on requests received on specific
 	// protocols.
 	Protocols []string `json:"protocols,omitempty"`
+
+	// Status reports whether the configuration of the KongClusterPlugin was applied to Kong.
+	Status KongClusterPluginStatus `json:"status,omitempty"`
 }
 
 //+kubebuilder:object:root=true
@@ -63,6 +66,16 @@ type KongClusterPluginList struct {
 	Items           []KongClusterPlugin `json:"items"`
 }
 
+// KongClusterPluginStatus is the observed state of a KongClusterPlugin.
+type KongClusterPluginStatus struct {
+	// Conditions describe the current state of the KongClusterPlugin, such as whether its configuration
+	// was applied to Kong.
+	// +listType=map
+	// +listMapKey=type
+	// +optional
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+}
+
 func init() {
 	SchemeBuilder.Register(&KongClusterPlugin{}, &KongClusterPluginList{})
 }
@@ -38,6 +38,9 @@ type KongConsumer struct {
 	// Credentials are references to secrets containing a credential to be
 	// provisioned in Kong.
 	Credentials []string `json:"credentials,omitempty"`
+
+	// Status reports whether the configuration of the KongConsumer was applied to Kong.
+	Status KongConsumerStatus `json:"status,omitempty"`
 }
 
 //+kubebuilder:object:root=true
@@ -49,6 +52,16 @@ type KongConsumerList struct {
 	Items           []KongConsumer `json:"items"`
 }
 
+// KongConsumerStatus is the observed state of a KongConsumer.
+type KongConsumerStatus struct {
+	// Conditions describe the current state of the KongConsumer, such as whether its configuration
+	// was applied to Kong.
+	// +listType=map
+	// +listMapKey=type
+	// +optional
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+}
+
 func init() {
 	SchemeBuilder.Register(&KongConsumer{}, &KongConsumerList{})
 }
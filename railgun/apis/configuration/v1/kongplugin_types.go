@@ -45,6 +45,10 @@ type KongPlugin struct {
 	// ConfigFrom references a secret containing the plugin configuration.
 	ConfigFrom kicv1.ConfigSource `json:"configFrom,omitempty"`
 
+	// ConfigPatches set individual fields of the plugin configuration to
+	// values read from secrets, on top of Config or ConfigFrom.
+	ConfigPatches []kicv1.ConfigPatch `json:"configPatches,omitempty"`
+
 	// PluginName is the name of the plugin to which to apply the config
 	PluginName string `json:"plugin,omitempty"`
 
@@ -55,6 +59,9 @@ type KongPlugin struct {
 	// Protocols configures plugin to run on requests received on specific
 	// protocols.
 	Protocols []string `json:"protocols,omitempty"`
+
+	// Status reports whether the configuration of the KongPlugin was applied to Kong.
+	Status KongPluginStatus `json:"status,omitempty"`
 }
 
 //+kubebuilder:object:root=true
@@ -66,6 +73,16 @@ type KongPluginList struct {
 	Items           []KongPlugin `json:"items"`
 }
 
+// KongPluginStatus is the observed state of a KongPlugin.
+type KongPluginStatus struct {
+	// Conditions describe the current state of the KongPlugin, such as whether its configuration
+	// was applied to Kong.
+	// +listType=map
+	// +listMapKey=type
+	// +optional
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+}
+
 func init() {
 	SchemeBuilder.Register(&KongPlugin{}, &KongPluginList{})
 }
@@ -169,17 +169,15 @@ package configuration
 
 import (
 	"context"
-	"time"
 
-	"github.com/go-logr/logr"
 	kongv1 "github.com/kong/kubernetes-ingress-controller/railgun/apis/configuration/v1"
 	kongv1alpha1 "github.com/kong/kubernetes-ingress-controller/railgun/apis/configuration/v1alpha1"
 	extv1beta1 "k8s.io/api/extensions/v1beta1"
 	netv1 "k8s.io/api/networking/v1"
 	netv1beta1 "k8s.io/api/networking/v1beta1"
-	"k8s.io/apimachinery/pkg/runtime"
 	ctrl "sigs.k8s.io/controller-runtime"
-	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 )
 `
 
@@ -188,16 +186,19 @@ var controllerTemplate = `
 // {{.PackageAlias}} {{.Type}}
 // -----------------------------------------------------------------------------
 
-// {{.PackageAlias}}{{.Type}} reconciles a Ingress object
+// {{.PackageAlias}}{{.Type}}Reconciler reconciles a {{.Type}} object
 type {{.PackageAlias}}{{.Type}}Reconciler struct {
-	client.Client
-	Log    logr.Logger
-	Scheme *runtime.Scheme
+	ConfigSecretReconciler
 }
 
+var _ reconcile.Reconciler = &{{.PackageAlias}}{{.Type}}Reconciler{}
+
 // SetupWithManager sets up the controller with the Manager.
 func (r *{{.PackageAlias}}{{.Type}}Reconciler) SetupWithManager(mgr ctrl.Manager) error {
-	return ctrl.NewControllerManagedBy(mgr).For(&{{.PackageImportAlias}}.{{.Type}}{}).Complete(r)
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&{{.PackageImportAlias}}.{{.Type}}{}, builder.WithPredicates(r.relevantUpdates())).
+		WithOptions(r.controllerOptions()).
+		Complete(r)
 }
 
 //+kubebuilder:rbac:groups={{.URL}},resources={{.Plural}},verbs=get;list;watch;create;update;patch;delete
@@ -206,18 +207,6 @@ func (r *{{.PackageAlias}}{{.Type}}Reconciler) SetupWithManager(mgr ctrl.Manager
 
 // Reconcile processes the watched objects
 func (r *{{.PackageAlias}}{{.Type}}Reconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
-	log := r.Log.WithValues("{{.PackageAlias}}{{.Type}}", req.NamespacedName)
-
-	obj := new({{.PackageImportAlias}}.{{.Type}})
-	if err := r.Get(ctx, req.NamespacedName, obj); err != nil {
-		return ctrl.Result{}, client.IgnoreNotFound(err)
-	}
-
-	if !obj.DeletionTimestamp.IsZero() && time.Now().After(obj.DeletionTimestamp.Time) {
-		log.Info("resource is being deleted, its configuration will be removed", "type", "{{.Type}}", "namespace", req.Namespace, "name", req.Name)
-		return cleanupObj(ctx, r.Client, log, req.NamespacedName, obj)
-	}
-
-	return storeIngressObj(ctx, r.Client, log, req.NamespacedName, obj)
+	return r.reconcileObj(ctx, req, "{{.PackageAlias}}{{.Type}}", new({{.PackageImportAlias}}.{{.Type}}), nil)
 }
 `
@@ -2,22 +2,32 @@ package configuration
 
 import (
 	"context"
+	"fmt"
 
-	"github.com/kong/kubernetes-ingress-controller/railgun/controllers"
+	"github.com/kong/kubernetes-ingress-controller/railgun/pkg/configsecret"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/retry"
+	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
-// getOrCreateConfigSecret finds or creates the secret which houses the combined configurations of the cluster
-// for eventual parsing and emitting to the Kong Admin API on the proxy instances.
-func getOrCreateConfigSecret(ctx context.Context, c client.Client, ns string) (*corev1.Secret, bool, error) {
+// ConfigSecretSizeWarningRatio is the fraction of corev1.MaxSecretSize above which the size of the
+// configuration secret is warned about, so that it can be addressed before updates get rejected.
+var ConfigSecretSizeWarningRatio = 0.75
+
+// getOrCreateConfigSecret finds or creates the secret nsn which houses the combined configurations of the cluster
+// for eventual parsing and emitting to the Kong Admin API on the proxy instances. If owner is set, the secret is
+// made a dependent of owner, so that it is garbage-collected once owner is deleted.
+func getOrCreateConfigSecret(ctx context.Context, c client.Client, nsn types.NamespacedName, owner *metav1.OwnerReference) (*corev1.Secret, bool, error) {
 	secret := new(corev1.Secret)
-	if err := c.Get(ctx, types.NamespacedName{Namespace: ns, Name: controllers.ConfigSecretName}, secret); err != nil {
+	if err := c.Get(ctx, nsn, secret); err != nil {
 		if errors.IsNotFound(err) {
-			secret.SetName(controllers.ConfigSecretName)
-			secret.SetNamespace(ns)
+			secret.SetName(nsn.Name)
+			secret.SetNamespace(nsn.Namespace)
+			setConfigSecretOwner(secret, owner)
 			if err := c.Create(ctx, secret); err != nil {
 				return nil, false, err
 			}
@@ -26,8 +36,104 @@ func getOrCreateConfigSecret(ctx context.Context, c client.Client, ns string) (*
 			return nil, false, err
 		}
 	}
+	// the secret may have been created before its owner was set
+	if setConfigSecretOwner(secret, owner) {
+		if err := c.Update(ctx, secret); err != nil {
+			return nil, false, err
+		}
+	}
 	if secret.Data == nil {
 		secret.Data = make(map[string][]byte)
 	}
 	return secret, false, nil
 }
+
+// setConfigSecretOwner adds owner, if set, to the owner references of secret, returning whether it was missing.
+func setConfigSecretOwner(secret *corev1.Secret, owner *metav1.OwnerReference) bool {
+	if owner == nil {
+		return false
+	}
+	for _, ref := range secret.OwnerReferences {
+		if ref.UID == owner.UID {
+			return false
+		}
+	}
+	secret.OwnerReferences = append(secret.OwnerReferences, *owner)
+	return true
+}
+
+// applyConfigSecret is the server-side apply variant of getOrCreateConfigSecret: it ensures the secret nsn
+// exists with a patch owned by fieldManager. The API server then creates the secret if needed, so concurrent
+// reconcilers don't race to do it, and the fields managed by other actors are left untouched. If owner is set,
+// the patch makes the secret a dependent of owner as well.
+func applyConfigSecret(ctx context.Context, c client.Client, nsn types.NamespacedName, fieldManager string,
+	owner *metav1.OwnerReference) (*corev1.Secret, error) {
+	secret := &corev1.Secret{
+		// apply patches are sent as is, so they must identify the kind of the object themselves
+		TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "Secret"},
+		ObjectMeta: metav1.ObjectMeta{Namespace: nsn.Namespace, Name: nsn.Name},
+		Type:       corev1.SecretTypeOpaque,
+	}
+	setConfigSecretOwner(secret, owner)
+	if err := c.Patch(ctx, secret, client.Apply, client.FieldOwner(fieldManager)); err != nil {
+		return nil, err
+	}
+	if secret.Data == nil {
+		secret.Data = make(map[string][]byte)
+	}
+	return secret, nil
+}
+
+// updateConfigSecret applies mutate to the configuration secret nsn and updates it. As several reconcilers
+// write the same secret concurrently, an update rejected with a conflict is retried on a freshly retrieved
+// copy of the secret, so that no reconciler clobbers the changes of another. The persisted secret is returned.
+func updateConfigSecret(ctx context.Context, c client.Client, nsn types.NamespacedName, mutate func(*corev1.Secret) error) (*corev1.Secret, error) {
+	var secret *corev1.Secret
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		// decoding into a previously used object would retain the keys removed since, so start afresh
+		secret = new(corev1.Secret)
+		if err := c.Get(ctx, nsn, secret); err != nil {
+			return err
+		}
+		if secret.Data == nil {
+			secret.Data = make(map[string][]byte)
+		}
+		if err := mutate(secret); err != nil {
+			return err
+		}
+		if err := checkConfigSecretSize(ctx, secret); err != nil {
+			return err
+		}
+		return c.Update(ctx, secret)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return secret, nil
+}
+
+// checkConfigSecretSize records the size of the configuration secret, counted as the API server does for
+// its limit of corev1.MaxSecretSize, and warns when it exceeds ConfigSecretSizeWarningRatio of the limit.
+// An error is returned when the limit is exceeded, as the update would be rejected.
+func checkConfigSecretSize(ctx context.Context, secret *corev1.Secret) error {
+	size := 0
+	for key, value := range secret.Data {
+		size += len(key) + len(value)
+	}
+	configSecretSize.Set(float64(size))
+
+	if size > corev1.MaxSecretSize {
+		remedy := "reduce the objects managed by this controller, e.g. with --watch-namespace"
+		if _, compressed := secret.Data[configsecret.CompressionKey]; !compressed {
+			remedy = "compress it with --compress-config-secret, or " + remedy
+		}
+		return fmt.Errorf("the configuration secret %s/%s would be %d bytes, which exceeds the limit of %d bytes "+
+			"of Secrets; %s", secret.Namespace, secret.Name, size, corev1.MaxSecretSize, remedy)
+	}
+	if float64(size) > ConfigSecretSizeWarningRatio*corev1.MaxSecretSize {
+		configSecretSizeWarnings.Inc()
+		ctrl.LoggerFrom(ctx).Info("the configuration secret is approaching the size limit of Secrets",
+			"namespace", secret.Namespace, "name", secret.Name, "size", size, "limit", corev1.MaxSecretSize)
+	}
+	return nil
+}
@@ -0,0 +1,242 @@
+package configuration
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/kong/kubernetes-ingress-controller/railgun/pkg/configsecret"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+// racingClient lets another writer update the configuration secret right before the first Update
+// goes through, which then fails with a conflict as it was based on an outdated version.
+type racingClient struct {
+	client.Client
+	raced   bool
+	updates int
+}
+
+func (c *racingClient) Update(ctx context.Context, obj client.Object, opts ...client.UpdateOption) error {
+	c.updates++
+	if !c.raced {
+		c.raced = true
+		concurrent := new(corev1.Secret)
+		if err := c.Client.Get(ctx, client.ObjectKeyFromObject(obj), concurrent); err != nil {
+			return err
+		}
+		concurrent.Data = map[string][]byte{"concurrent": []byte("value")}
+		if err := c.Client.Update(ctx, concurrent); err != nil {
+			return err
+		}
+	}
+	return c.Client.Update(ctx, obj, opts...)
+}
+
+func TestUpdateConfigSecretRetriesOnConflict(t *testing.T) {
+	nsn := types.NamespacedName{Namespace: "kong", Name: "kong-config"}
+	c := &racingClient{
+		Client: fake.NewClientBuilder().WithScheme(clientgoscheme.Scheme).
+			WithObjects(&corev1.Secret{ObjectMeta: metav1.ObjectMeta{Namespace: nsn.Namespace, Name: nsn.Name}}).Build(),
+	}
+
+	secret, err := updateConfigSecret(context.Background(), c, nsn, func(secret *corev1.Secret) error {
+		secret.Data["mine"] = []byte("value")
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, c.updates)
+	want := map[string][]byte{"concurrent": []byte("value"), "mine": []byte("value")}
+	assert.Equal(t, want, secret.Data)
+
+	persisted := new(corev1.Secret)
+	assert.NoError(t, c.Get(context.Background(), nsn, persisted))
+	assert.Equal(t, want, persisted.Data)
+	assert.Equal(t, secret.ResourceVersion, persisted.ResourceVersion)
+}
+
+// applyClient records the apply patches, which the fake client doesn't support, and answers them with
+// the persisted object as the API server would.
+type applyClient struct {
+	client.Client
+	patchType types.PatchType
+	patch     []byte
+	opts      client.PatchOptions
+}
+
+func (c *applyClient) Patch(ctx context.Context, obj client.Object, patch client.Patch, opts ...client.PatchOption) error {
+	c.patchType = patch.Type()
+	data, err := patch.Data(obj)
+	if err != nil {
+		return err
+	}
+	c.patch = data
+	c.opts.ApplyOptions(opts)
+	return c.Client.Get(ctx, client.ObjectKeyFromObject(obj), obj)
+}
+
+func TestApplyConfigSecret(t *testing.T) {
+	nsn := types.NamespacedName{Namespace: "kong", Name: "kong-config"}
+	c := &applyClient{
+		Client: fake.NewClientBuilder().WithScheme(clientgoscheme.Scheme).
+			WithObjects(&corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Namespace: nsn.Namespace, Name: nsn.Name},
+				Data:       map[string][]byte{"stored": []byte("value")},
+			}).Build(),
+	}
+
+	secret, err := applyConfigSecret(context.Background(), c, nsn, "kong-ingress-controller", nil)
+	assert.NoError(t, err)
+	assert.Equal(t, types.ApplyPatchType, c.patchType)
+	assert.Equal(t, "kong-ingress-controller", c.opts.FieldManager)
+	assert.JSONEq(t, `{"apiVersion":"v1","kind":"Secret","metadata":{"name":"kong-config","namespace":"kong","creationTimestamp":null},"type":"Opaque"}`,
+		string(c.patch), "the patch must not claim the data of the secret")
+	assert.Equal(t, map[string][]byte{"stored": []byte("value")}, secret.Data)
+}
+
+func TestConfigSecretOwner(t *testing.T) {
+	ctx := context.Background()
+	nsn := types.NamespacedName{Namespace: "kong", Name: "kong-config"}
+	owner := &metav1.OwnerReference{APIVersion: "apps/v1", Kind: "Deployment", Name: "ingress-kong", UID: "b2df61dd"}
+	other := metav1.OwnerReference{APIVersion: "v1", Kind: "ConfigMap", Name: "other", UID: "a1b2c3d4"}
+
+	t.Run("created secret", func(t *testing.T) {
+		c := fake.NewClientBuilder().WithScheme(clientgoscheme.Scheme).Build()
+		_, created, err := getOrCreateConfigSecret(ctx, c, nsn, owner)
+		assert.NoError(t, err)
+		assert.True(t, created)
+
+		persisted := new(corev1.Secret)
+		assert.NoError(t, c.Get(ctx, nsn, persisted))
+		assert.Equal(t, []metav1.OwnerReference{*owner}, persisted.OwnerReferences)
+	})
+
+	t.Run("existing secret", func(t *testing.T) {
+		c := fake.NewClientBuilder().WithScheme(clientgoscheme.Scheme).
+			WithObjects(&corev1.Secret{ObjectMeta: metav1.ObjectMeta{
+				Namespace:       nsn.Namespace,
+				Name:            nsn.Name,
+				OwnerReferences: []metav1.OwnerReference{other},
+			}}).Build()
+		for i := 0; i < 2; i++ {
+			_, created, err := getOrCreateConfigSecret(ctx, c, nsn, owner)
+			assert.NoError(t, err)
+			assert.False(t, created)
+		}
+
+		persisted := new(corev1.Secret)
+		assert.NoError(t, c.Get(ctx, nsn, persisted))
+		assert.Equal(t, []metav1.OwnerReference{other, *owner}, persisted.OwnerReferences,
+			"the owner is added once, along with the existing ones")
+	})
+
+	t.Run("no owner", func(t *testing.T) {
+		c := fake.NewClientBuilder().WithScheme(clientgoscheme.Scheme).Build()
+		_, _, err := getOrCreateConfigSecret(ctx, c, nsn, nil)
+		assert.NoError(t, err)
+
+		persisted := new(corev1.Secret)
+		assert.NoError(t, c.Get(ctx, nsn, persisted))
+		assert.Empty(t, persisted.OwnerReferences)
+	})
+
+	t.Run("server-side apply", func(t *testing.T) {
+		c := &applyClient{
+			Client: fake.NewClientBuilder().WithScheme(clientgoscheme.Scheme).
+				WithObjects(&corev1.Secret{ObjectMeta: metav1.ObjectMeta{Namespace: nsn.Namespace, Name: nsn.Name}}).Build(),
+		}
+		_, err := applyConfigSecret(ctx, c, nsn, "kong-ingress-controller", owner)
+		assert.NoError(t, err)
+		assert.JSONEq(t, `{"apiVersion":"v1","kind":"Secret","metadata":{"name":"kong-config","namespace":"kong",`+
+			`"creationTimestamp":null,"ownerReferences":[{"apiVersion":"apps/v1","kind":"Deployment",`+
+			`"name":"ingress-kong","uid":"b2df61dd"}]},"type":"Opaque"}`, string(c.patch))
+	})
+}
+
+func TestUpdateConfigSecretChecksSize(t *testing.T) {
+	nsn := types.NamespacedName{Namespace: "kong", Name: "kong-config"}
+	const key = "object"
+	// the size of a secret counts its keys along with their values
+	limit := corev1.MaxSecretSize - len(key)
+	warningAt := int(ConfigSecretSizeWarningRatio*corev1.MaxSecretSize) - len(key)
+
+	tests := []struct {
+		name        string
+		valueSize   int
+		wantWarning bool
+		wantErr     bool
+	}{
+		{
+			name:      "below the warning threshold",
+			valueSize: warningAt,
+		},
+		{
+			name:        "above the warning threshold",
+			valueSize:   warningAt + 1,
+			wantWarning: true,
+		},
+		{
+			name:        "at the limit",
+			valueSize:   limit,
+			wantWarning: true,
+		},
+		{
+			name:      "above the limit",
+			valueSize: limit + 1,
+			wantErr:   true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := fake.NewClientBuilder().WithScheme(clientgoscheme.Scheme).
+				WithObjects(&corev1.Secret{ObjectMeta: metav1.ObjectMeta{Namespace: nsn.Namespace, Name: nsn.Name}}).Build()
+			warnings := testutil.ToFloat64(configSecretSizeWarnings)
+
+			_, err := updateConfigSecret(context.Background(), c, nsn, func(secret *corev1.Secret) error {
+				secret.Data[key] = bytes.Repeat([]byte("x"), tt.valueSize)
+				return nil
+			})
+			persisted := new(corev1.Secret)
+			assert.NoError(t, c.Get(context.Background(), nsn, persisted))
+			if tt.wantErr {
+				assert.Error(t, err)
+				assert.Empty(t, persisted.Data, "the secret must not be updated")
+			} else {
+				assert.NoError(t, err)
+				assert.Len(t, persisted.Data[key], tt.valueSize)
+			}
+			assert.Equal(t, float64(len(key)+tt.valueSize), testutil.ToFloat64(configSecretSize))
+			if tt.wantWarning {
+				assert.Equal(t, warnings+1, testutil.ToFloat64(configSecretSizeWarnings))
+			} else {
+				assert.Equal(t, warnings, testutil.ToFloat64(configSecretSizeWarnings))
+			}
+		})
+	}
+}
+
+func TestCheckConfigSecretSizeSuggestsCompression(t *testing.T) {
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "kong", Name: "kong-config"},
+		Data:       map[string][]byte{"object": bytes.Repeat([]byte("x"), corev1.MaxSecretSize)},
+	}
+	err := checkConfigSecretSize(context.Background(), secret)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "--compress-config-secret")
+	assert.Contains(t, err.Error(), "--watch-namespace")
+
+	// once compressed, only fewer objects make the configuration smaller
+	secret.Data[configsecret.CompressionKey] = []byte("gzip")
+	err = checkConfigSecretSize(context.Background(), secret)
+	require.Error(t, err)
+	assert.NotContains(t, err.Error(), "--compress-config-secret")
+	assert.Contains(t, err.Error(), "--watch-namespace")
+}
@@ -0,0 +1,23 @@
+package configuration
+
+// -----------------------------------------------------------------------------
+// Event Reasons
+// -----------------------------------------------------------------------------
+
+const (
+	// KongConfigurationSyncedReason is the reason of the Normal event recorded on an object
+	// the first time its configuration is successfully pushed to Kong.
+	KongConfigurationSyncedReason = "KongConfigurationSynced"
+
+	// KongConfigurationSyncFailedReason is the reason of the Warning event recorded on an object
+	// when its configuration could not be synced to Kong.
+	KongConfigurationSyncFailedReason = "KongConfigurationSyncFailed"
+
+	// KongConfigurationRejectedReason is the reason of the Warning event recorded on an object
+	// when Kong rejected the configuration because of an invalid entity generated from it.
+	KongConfigurationRejectedReason = "KongConfigurationRejected"
+
+	// KongBackendServiceUnresolvedReason is the reason of the Warning event recorded on an object
+	// referencing a backend Service, or a port of it, which does not exist.
+	KongBackendServiceUnresolvedReason = "KongBackendServiceUnresolved"
+)
@@ -0,0 +1,66 @@
+package configuration
+
+import (
+	"context"
+
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+	gatewayv1alpha1 "sigs.k8s.io/gateway-api/apis/v1alpha1"
+
+	"github.com/kong/kubernetes-ingress-controller/railgun/pkg/gateway"
+)
+
+// HTTPRouteReconciler reconciles the Gateway API HTTPRoutes attached to Gateways of a GatewayClass
+// controlled by Kong, which are translated along with Ingresses.
+type HTTPRouteReconciler struct {
+	ConfigSecretReconciler
+}
+
+var _ reconcile.Reconciler = &HTTPRouteReconciler{}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *HTTPRouteReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	// which routes are attached depends on the Gateways and their classes
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&gatewayv1alpha1.HTTPRoute{}, builder.WithPredicates(r.relevantUpdates())).
+		Watches(&source.Kind{Type: &gatewayv1alpha1.Gateway{}}, handler.EnqueueRequestsFromMapFunc(r.allHTTPRoutes)).
+		Watches(&source.Kind{Type: &gatewayv1alpha1.GatewayClass{}}, handler.EnqueueRequestsFromMapFunc(r.allHTTPRoutes)).
+		WithOptions(r.controllerOptions()).
+		Complete(r)
+}
+
+// allHTTPRoutes maps any object to requests to reconcile every HTTPRoute.
+func (r *HTTPRouteReconciler) allHTTPRoutes(client.Object) []reconcile.Request {
+	routes := new(gatewayv1alpha1.HTTPRouteList)
+	if err := r.List(context.Background(), routes); err != nil {
+		r.Log.Error(err, "failed to list HTTPRoutes")
+		return nil
+	}
+	requests := make([]reconcile.Request, 0, len(routes.Items))
+	for _, route := range routes.Items {
+		requests = append(requests, reconcile.Request{NamespacedName: types.NamespacedName{
+			Namespace: route.Namespace,
+			Name:      route.Name,
+		}})
+	}
+	return requests
+}
+
+//+kubebuilder:rbac:groups=networking.x-k8s.io,resources=httproutes,verbs=get;list;watch;update;patch
+//+kubebuilder:rbac:groups=networking.x-k8s.io,resources=httproutes/finalizers,verbs=update
+
+// Reconcile stores the HTTPRoutes attached to Gateways of Kong in the configuration secret,
+// and removes those which are deleted or no longer attached.
+func (r *HTTPRouteReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	return r.reconcileObj(ctx, req, "HTTPRoute", new(gatewayv1alpha1.HTTPRoute), r.isAttached)
+}
+
+// isAttached tells whether route, an HTTPRoute, is attached to a Gateway of Kong.
+func (r *HTTPRouteReconciler) isAttached(ctx context.Context, route client.Object) (bool, error) {
+	return gateway.IsHTTPRouteAttached(ctx, r.Client, route.(*gatewayv1alpha1.HTTPRoute))
+}
@@ -0,0 +1,193 @@
+package configuration
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	extv1beta1 "k8s.io/api/extensions/v1beta1"
+	netv1 "k8s.io/api/networking/v1"
+	netv1beta1 "k8s.io/api/networking/v1beta1"
+	apiequality "k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+)
+
+// IngressStatusReconciler publishes the addresses of the Kong proxy Service in the status of the Ingresses
+// managed by Kong, for tools such as external-dns to find them. Every change to the Service or to an Ingress
+// reconciles the Service, which updates the Ingresses whose status is outdated.
+type IngressStatusReconciler struct {
+	client.Client
+	Log    logr.Logger
+	Scheme *runtime.Scheme
+
+	// PublishService is the Kong proxy Service whose addresses are published.
+	PublishService types.NamespacedName
+	// IngressAPI is the version of the Ingress API the Ingresses are updated with.
+	IngressAPI schema.GroupVersion
+	// CacheSyncTimeout bounds the wait for the caches of the controller to sync at startup, defaulting to
+	// the one of controller-runtime if zero.
+	CacheSyncTimeout time.Duration
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *IngressStatusReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	ingress, err := newIngress(r.IngressAPI)
+	if err != nil {
+		return err
+	}
+	return ctrl.NewControllerManagedBy(mgr).
+		Named("IngressStatus").
+		For(&corev1.Service{}, builder.WithPredicates(predicate.NewPredicateFuncs(r.isPublishService))).
+		Watches(&source.Kind{Type: ingress}, handler.EnqueueRequestsFromMapFunc(r.publishServiceRequest)).
+		WithOptions(controller.Options{CacheSyncTimeout: r.CacheSyncTimeout}).
+		Complete(r)
+}
+
+func (r *IngressStatusReconciler) isPublishService(obj client.Object) bool {
+	return obj.GetNamespace() == r.PublishService.Namespace && obj.GetName() == r.PublishService.Name
+}
+
+// publishServiceRequest maps any object to a request to reconcile the Kong proxy Service.
+func (r *IngressStatusReconciler) publishServiceRequest(client.Object) []reconcile.Request {
+	return []reconcile.Request{{NamespacedName: r.PublishService}}
+}
+
+//+kubebuilder:rbac:groups=core,resources=services,verbs=get;list;watch
+//+kubebuilder:rbac:groups=networking.k8s.io,resources=ingresses/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups=extensions,resources=ingresses/status,verbs=get;update;patch
+
+// Reconcile writes the addresses of the Kong proxy Service in the status of the Ingresses managed by Kong.
+func (r *IngressStatusReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	log := r.Log.WithValues("service", req.NamespacedName)
+
+	service := new(corev1.Service)
+	if err := r.Get(ctx, req.NamespacedName, service); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+	addresses := publishedAddresses(service)
+
+	ingresses, err := r.listIngresses(ctx)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	for _, ingress := range ingresses {
+		managed, err := isIngressManaged(ctx, r.Client, ingress)
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+		if !managed {
+			continue
+		}
+
+		status := ingressLoadBalancerStatus(ingress)
+		if apiequality.Semantic.DeepEqual(status.Ingress, addresses) {
+			continue
+		}
+		status.Ingress = addresses
+		if err := r.Status().Update(ctx, ingress); err != nil {
+			if errors.IsConflict(err) {
+				log.Info("ingress updated while its status was, retrying", "namespace", ingress.GetNamespace(), "name", ingress.GetName())
+				return ctrl.Result{Requeue: true}, nil
+			}
+			return ctrl.Result{}, err
+		}
+		log.Info("ingress status updated", "namespace", ingress.GetNamespace(), "name", ingress.GetName())
+	}
+	return ctrl.Result{}, nil
+}
+
+// listIngresses lists the Ingresses in the version of IngressAPI.
+func (r *IngressStatusReconciler) listIngresses(ctx context.Context) ([]client.Object, error) {
+	var ingresses []client.Object
+	switch r.IngressAPI {
+	case netv1.SchemeGroupVersion:
+		list := new(netv1.IngressList)
+		if err := r.List(ctx, list); err != nil {
+			return nil, err
+		}
+		for i := range list.Items {
+			ingresses = append(ingresses, &list.Items[i])
+		}
+	case netv1beta1.SchemeGroupVersion:
+		list := new(netv1beta1.IngressList)
+		if err := r.List(ctx, list); err != nil {
+			return nil, err
+		}
+		for i := range list.Items {
+			ingresses = append(ingresses, &list.Items[i])
+		}
+	case extv1beta1.SchemeGroupVersion:
+		list := new(extv1beta1.IngressList)
+		if err := r.List(ctx, list); err != nil {
+			return nil, err
+		}
+		for i := range list.Items {
+			ingresses = append(ingresses, &list.Items[i])
+		}
+	default:
+		return nil, fmt.Errorf("unsupported Ingress API %s", r.IngressAPI)
+	}
+	return ingresses, nil
+}
+
+// newIngress returns an empty Ingress of the given API version.
+func newIngress(ingressAPI schema.GroupVersion) (client.Object, error) {
+	switch ingressAPI {
+	case netv1.SchemeGroupVersion:
+		return &netv1.Ingress{}, nil
+	case netv1beta1.SchemeGroupVersion:
+		return &netv1beta1.Ingress{}, nil
+	case extv1beta1.SchemeGroupVersion:
+		return &extv1beta1.Ingress{}, nil
+	}
+	return nil, fmt.Errorf("unsupported Ingress API %s", ingressAPI)
+}
+
+// ingressLoadBalancerStatus returns the status.loadBalancer of any supported version of Ingress.
+func ingressLoadBalancerStatus(obj client.Object) *corev1.LoadBalancerStatus {
+	switch ingress := obj.(type) {
+	case *netv1.Ingress:
+		return &ingress.Status.LoadBalancer
+	case *netv1beta1.Ingress:
+		return &ingress.Status.LoadBalancer
+	case *extv1beta1.Ingress:
+		return &ingress.Status.LoadBalancer
+	}
+	return &corev1.LoadBalancerStatus{}
+}
+
+// publishedAddresses returns the load balancer addresses and external IPs of service, sorted for
+// the status of Ingresses to only change along with them.
+func publishedAddresses(service *corev1.Service) []corev1.LoadBalancerIngress {
+	var addresses []corev1.LoadBalancerIngress
+	if service.Spec.Type == corev1.ServiceTypeLoadBalancer {
+		for _, ingress := range service.Status.LoadBalancer.Ingress {
+			addresses = append(addresses, corev1.LoadBalancerIngress{IP: ingress.IP, Hostname: ingress.Hostname})
+		}
+	}
+	for _, ip := range service.Spec.ExternalIPs {
+		addresses = append(addresses, corev1.LoadBalancerIngress{IP: ip})
+	}
+	sort.SliceStable(addresses, func(i, j int) bool {
+		if c := strings.Compare(addresses[i].Hostname, addresses[j].Hostname); c != 0 {
+			return c < 0
+		}
+		return addresses[i].IP < addresses[j].IP
+	})
+	return addresses
+}
@@ -0,0 +1,95 @@
+package configuration
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-logr/logr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	netv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/kong/kubernetes-ingress-controller/pkg/annotations"
+)
+
+func TestIngressStatusReconciler(t *testing.T) {
+	ctx := context.Background()
+	proxy := types.NamespacedName{Namespace: "kong", Name: "kong-proxy"}
+	ingress := func(name, class string, addresses ...corev1.LoadBalancerIngress) *netv1.Ingress {
+		return &netv1.Ingress{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace:   "default",
+				Name:        name,
+				Annotations: map[string]string{annotations.IngressClassKey: class},
+			},
+			Status: netv1.IngressStatus{LoadBalancer: corev1.LoadBalancerStatus{Ingress: addresses}},
+		}
+	}
+	stale := corev1.LoadBalancerIngress{IP: "10.0.0.1"}
+	c := fake.NewClientBuilder().WithScheme(clientgoscheme.Scheme).WithObjects(
+		&corev1.Service{
+			ObjectMeta: metav1.ObjectMeta{Namespace: proxy.Namespace, Name: proxy.Name},
+			Spec: corev1.ServiceSpec{
+				Type:        corev1.ServiceTypeLoadBalancer,
+				ExternalIPs: []string{"192.0.2.10"},
+			},
+			Status: corev1.ServiceStatus{LoadBalancer: corev1.LoadBalancerStatus{Ingress: []corev1.LoadBalancerIngress{
+				{Hostname: "proxy.example.com"},
+				{IP: "192.0.2.1"},
+			}}},
+		},
+		ingress("managed", annotations.DefaultIngressClass),
+		ingress("outdated", annotations.DefaultIngressClass, stale),
+		ingress("unmanaged", "nginx", stale),
+	).Build()
+	r := &IngressStatusReconciler{
+		Client:         c,
+		Log:            logr.Discard(),
+		PublishService: proxy,
+		IngressAPI:     netv1.SchemeGroupVersion,
+	}
+
+	statusOf := func(name string) []corev1.LoadBalancerIngress {
+		ingress := new(netv1.Ingress)
+		require.NoError(t, c.Get(ctx, types.NamespacedName{Namespace: "default", Name: name}, ingress))
+		return ingress.Status.LoadBalancer.Ingress
+	}
+
+	_, err := r.Reconcile(ctx, ctrl.Request{NamespacedName: proxy})
+	require.NoError(t, err)
+	want := []corev1.LoadBalancerIngress{{IP: "192.0.2.1"}, {IP: "192.0.2.10"}, {Hostname: "proxy.example.com"}}
+	assert.Equal(t, want, statusOf("managed"))
+	assert.Equal(t, want, statusOf("outdated"))
+	assert.Equal(t, []corev1.LoadBalancerIngress{stale}, statusOf("unmanaged"), "unmanaged Ingresses must be left alone")
+
+	t.Run("the proxy service address changes", func(t *testing.T) {
+		service := new(corev1.Service)
+		require.NoError(t, c.Get(ctx, proxy, service))
+		service.Spec.ExternalIPs = nil
+		service.Status.LoadBalancer.Ingress = []corev1.LoadBalancerIngress{{IP: "192.0.2.2"}}
+		require.NoError(t, c.Update(ctx, service))
+
+		_, err := r.Reconcile(ctx, ctrl.Request{NamespacedName: proxy})
+		require.NoError(t, err)
+		want := []corev1.LoadBalancerIngress{{IP: "192.0.2.2"}}
+		assert.Equal(t, want, statusOf("managed"))
+		assert.Equal(t, want, statusOf("outdated"))
+	})
+
+	t.Run("the proxy service is not a load balancer", func(t *testing.T) {
+		service := new(corev1.Service)
+		require.NoError(t, c.Get(ctx, proxy, service))
+		service.Spec.Type = corev1.ServiceTypeClusterIP
+		require.NoError(t, c.Update(ctx, service))
+
+		_, err := r.Reconcile(ctx, ctrl.Request{NamespacedName: proxy})
+		require.NoError(t, err)
+		assert.Empty(t, statusOf("managed"))
+	})
+}
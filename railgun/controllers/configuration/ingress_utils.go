@@ -1,10 +1,20 @@
 package configuration
 
 import (
+	"context"
+	"fmt"
+
 	extv1beta1 "k8s.io/api/extensions/v1beta1"
 	netv1 "k8s.io/api/networking/v1"
 	netv1beta1 "k8s.io/api/networking/v1beta1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/discovery"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/kong/kubernetes-ingress-controller/pkg/annotations"
+	mgrutils "github.com/kong/kubernetes-ingress-controller/railgun/manager/utils"
 )
 
 // -----------------------------------------------------------------------------
@@ -14,96 +24,107 @@ import (
 // KongIngressFinalizer is the finalizer used to ensure Kong configuration cleanup for deleted Ingress resources.
 const KongIngressFinalizer = "configuration.konghq.com/ingress"
 
-// SetupIngressControllers validates which ingress controllers need to be configured and sets them up with the
-// provided controller manager.
-func SetupIngressControllers(mgr ctrl.Manager) error {
-	netV1Ing := new(netv1.Ingress)
-	apiAvailable, err := IsAPIAvailable(mgr, netV1Ing)
+// IngressAPIs are the versions of the Ingress API supported by the controller, from the most to the least preferred.
+var IngressAPIs = []schema.GroupVersion{
+	netv1.SchemeGroupVersion,
+	netv1beta1.SchemeGroupVersion,
+	extv1beta1.SchemeGroupVersion,
+}
+
+// ServedIngressAPIs returns which of IngressAPIs the cluster serves, from the most to the least preferred.
+func ServedIngressAPIs(d discovery.DiscoveryInterface) ([]schema.GroupVersion, error) {
+	groups, err := d.ServerGroups()
 	if err != nil {
-		return err
+		return nil, err
 	}
-	if apiAvailable {
-		if err = (&NetV1IngressReconciler{
-			Client: mgr.GetClient(),
-			Log:    ctrl.Log.WithName("controllers").WithName("Ingress"),
-			Scheme: mgr.GetScheme(),
-		}).SetupWithManager(mgr); err != nil {
+	served := make(map[string]bool)
+	for _, group := range groups.Groups {
+		for _, version := range group.Versions {
+			served[version.GroupVersion] = true
 		}
-	} else {
-		mgr.GetLogger().Info("API networking.k8s.io/v1/Ingress is not available, skipping controller for this API")
 	}
 
-	if !apiAvailable {
-		return setupLegacyIngressControllers(mgr)
+	var result []schema.GroupVersion
+	for _, gv := range IngressAPIs {
+		if !served[gv.String()] {
+			continue
+		}
+		resources, err := d.ServerResourcesForGroupVersion(gv.String())
+		if err != nil {
+			return nil, err
+		}
+		for _, resource := range resources.APIResources {
+			if resource.Name == "ingresses" {
+				result = append(result, gv)
+				break
+			}
+		}
 	}
+	return result, nil
+}
 
-	return nil
+// SetupIngressControllers sets up the controller of the given Ingress API version with the provided
+// controller manager and the settings of base. As the cluster converts Ingresses between the versions it
+// serves, a single version covers all Ingresses.
+func SetupIngressControllers(mgr ctrl.Manager, ingressAPI schema.GroupVersion, base ConfigSecretReconciler) error {
+	switch ingressAPI {
+	case netv1.SchemeGroupVersion:
+		return (&NetV1IngressReconciler{base}).SetupWithManager(mgr)
+	case netv1beta1.SchemeGroupVersion:
+		return (&NetV1Beta1IngressReconciler{base}).SetupWithManager(mgr)
+	case extv1beta1.SchemeGroupVersion:
+		return (&ExtV1Beta1IngressReconciler{base}).SetupWithManager(mgr)
+	}
+	return fmt.Errorf("unsupported Ingress API %s", ingressAPI)
 }
 
 // -----------------------------------------------------------------------------
 // Private Types & Functions
 // -----------------------------------------------------------------------------
 
-// isManaged verifies whether an Ingress resource is managed by Kong controllers by verifying the
-// annotations of the object.
+// isIngressManaged verifies whether an Ingress resource is managed by Kong controllers.
+// spec.ingressClassName takes precedence over the kubernetes.io/ingress.class annotation: when set,
+// the Ingress is managed if the IngressClass it references is controlled by Kong, otherwise it is
+// managed if its annotation matches any of the configured ingress classes.
 // TODO: add these filters to watch options instead!
-func isManaged(annotations map[string]string) bool {
-	class, ok := annotations["kubernetes.io/ingress.class"]
-	if !ok {
-		return false
+func isIngressManaged(ctx context.Context, c client.Client, obj client.Object) (bool, error) {
+	className := ingressClassName(obj)
+	if className == nil {
+		return mgrutils.IsIngressClassName(obj.GetAnnotations()[annotations.IngressClassKey]), nil
 	}
-	if class == "kong" {
-		return true
+	return isKongIngressClass(ctx, c, *className)
+}
+
+// ingressClassName returns the spec.ingressClassName of any supported version of Ingress.
+func ingressClassName(obj client.Object) *string {
+	switch ingress := obj.(type) {
+	case *netv1.Ingress:
+		return ingress.Spec.IngressClassName
+	case *netv1beta1.Ingress:
+		return ingress.Spec.IngressClassName
+	case *extv1beta1.Ingress:
+		return ingress.Spec.IngressClassName
 	}
-	return false
+	return nil
 }
 
-// setupLegacyIngressControllers automates controller setup for Ingress resources, but since we
-// support some older Ingress versions some decisions need to be made about which controllers run.
-// For instance, if networking.k8s.io/v1beta1/Ingress is available, no controller is needed for
-// apiextensions.k8s.io/v1beta1/Ingress as the latter will be converted to the former.
-func setupLegacyIngressControllers(mgr ctrl.Manager) error {
-	// start the networking.k8s.io/v1beta1/Ingress controller (if the API is available)
-	netV1Beta1IngAvailable := false
-	netV1Beta1Ing := new(netv1beta1.Ingress)
-	apiAvailable, err := IsAPIAvailable(mgr, netV1Beta1Ing)
-	if err != nil {
-		return err
+//+kubebuilder:rbac:groups=networking.k8s.io,resources=ingressclasses,verbs=get;list;watch
+
+// isKongIngressClass verifies whether the named IngressClass is controlled by Kong.
+// The networking.k8s.io/v1beta1 API is used on clusters which don't serve IngressClasses in v1 yet.
+func isKongIngressClass(ctx context.Context, c client.Client, name string) (bool, error) {
+	class := new(netv1.IngressClass)
+	err := c.Get(ctx, client.ObjectKey{Name: name}, class)
+	if err == nil {
+		return class.Spec.Controller == mgrutils.IngressClassController, nil
 	}
-	if apiAvailable {
-		if err = (&NetV1Beta1IngressReconciler{
-			Client: mgr.GetClient(),
-			Log:    ctrl.Log.WithName("controllers").WithName("V1Beta1Ingress"),
-			Scheme: mgr.GetScheme(),
-		}).SetupWithManager(mgr); err != nil {
-			return err
-		}
-		netV1Beta1IngAvailable = true
-	} else {
-		mgr.GetLogger().Info("API networking.k8s.io/v1beta1/Ingress is not available, skipping controller for this API")
+	if !meta.IsNoMatchError(err) {
+		return false, client.IgnoreNotFound(err)
 	}
 
-	// start the apiextensions.k8s.io/v1beta1/Ingress controller (if the API is available)
-	if netV1Beta1IngAvailable {
-		mgr.GetLogger().Info("No need to start a controller for apiextensions.k8s.io/v1beta1/Ingress as networking.k8s.io/v1beta1/Ingress covers for this through conversions")
-	} else {
-		extV1Beta1Ing := new(extv1beta1.Ingress)
-		apiAvailable, err = IsAPIAvailable(mgr, extV1Beta1Ing)
-		if err != nil {
-			return err
-		}
-		if apiAvailable {
-			if err = (&ExtV1Beta1IngressReconciler{
-				Client: mgr.GetClient(),
-				Log:    ctrl.Log.WithName("controllers").WithName("ExtensionsV1Beta1Ingress"),
-				Scheme: mgr.GetScheme(),
-			}).SetupWithManager(mgr); err != nil {
-				return err
-			}
-		} else {
-			mgr.GetLogger().Info("API apiextensions.k8s.io/v1beta1/Ingress is not available, skipping controller for this API")
-		}
+	legacyClass := new(netv1beta1.IngressClass)
+	if err := c.Get(ctx, client.ObjectKey{Name: name}, legacyClass); err != nil {
+		return false, client.IgnoreNotFound(err)
 	}
-
-	return nil
+	return legacyClass.Spec.Controller == mgrutils.IngressClassController, nil
 }
@@ -0,0 +1,166 @@
+package configuration
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	extv1beta1 "k8s.io/api/extensions/v1beta1"
+	netv1 "k8s.io/api/networking/v1"
+	netv1beta1 "k8s.io/api/networking/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	fakediscovery "k8s.io/client-go/discovery/fake"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	clienttesting "k8s.io/client-go/testing"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/kong/kubernetes-ingress-controller/pkg/annotations"
+	mgrutils "github.com/kong/kubernetes-ingress-controller/railgun/manager/utils"
+)
+
+func TestIsIngressManaged(t *testing.T) {
+	kongClass := &netv1.IngressClass{
+		ObjectMeta: metav1.ObjectMeta{Name: "kong-class"},
+		Spec:       netv1.IngressClassSpec{Controller: mgrutils.IngressClassController},
+	}
+	otherClass := &netv1.IngressClass{
+		ObjectMeta: metav1.ObjectMeta{Name: "other-class"},
+		Spec:       netv1.IngressClassSpec{Controller: "example.com/other"},
+	}
+	c := fake.NewClientBuilder().WithScheme(clientgoscheme.Scheme).WithObjects(kongClass, otherClass).Build()
+
+	tests := []struct {
+		name       string
+		annotation string
+		className  string
+		want       bool
+	}{
+		{
+			name: "neither annotation nor field",
+		},
+		{
+			name:       "annotation matches",
+			annotation: annotations.DefaultIngressClass,
+			want:       true,
+		},
+		{
+			name:       "annotation does not match",
+			annotation: "nginx",
+		},
+		{
+			name:      "field references a Kong IngressClass",
+			className: "kong-class",
+			want:      true,
+		},
+		{
+			name:      "field references another controller's IngressClass",
+			className: "other-class",
+		},
+		{
+			name:      "field references a missing IngressClass",
+			className: "missing",
+		},
+		{
+			name:       "field wins over a matching annotation",
+			annotation: annotations.DefaultIngressClass,
+			className:  "other-class",
+		},
+		{
+			name:       "field wins over a mismatching annotation",
+			annotation: "nginx",
+			className:  "kong-class",
+			want:       true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ingress := &netv1.Ingress{ObjectMeta: metav1.ObjectMeta{
+				Namespace:   "default",
+				Name:        "foo",
+				Annotations: map[string]string{},
+			}}
+			if tt.annotation != "" {
+				ingress.Annotations[annotations.IngressClassKey] = tt.annotation
+			}
+			if tt.className != "" {
+				className := tt.className
+				ingress.Spec.IngressClassName = &className
+			}
+
+			got, err := isIngressManaged(context.Background(), c, ingress)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestIsIngressManagedWithSeveralClasses(t *testing.T) {
+	defer func(names []string) { mgrutils.IngressClassNames = names }(mgrutils.IngressClassNames)
+	mgrutils.IngressClassNames = []string{"kong-public", "kong-internal"}
+	c := fake.NewClientBuilder().WithScheme(clientgoscheme.Scheme).Build()
+
+	for annotation, want := range map[string]bool{
+		"kong-public":                   true,
+		"kong-internal":                 true,
+		annotations.DefaultIngressClass: false,
+		"nginx":                         false,
+		"":                              false,
+	} {
+		ingress := &netv1.Ingress{ObjectMeta: metav1.ObjectMeta{
+			Namespace:   "default",
+			Name:        "foo",
+			Annotations: map[string]string{annotations.IngressClassKey: annotation},
+		}}
+		got, err := isIngressManaged(context.Background(), c, ingress)
+		assert.NoError(t, err)
+		assert.Equal(t, want, got, "class %q", annotation)
+	}
+}
+
+func TestServedIngressAPIs(t *testing.T) {
+	resources := func(groupVersion string, names ...string) *metav1.APIResourceList {
+		list := &metav1.APIResourceList{GroupVersion: groupVersion}
+		for _, name := range names {
+			list.APIResources = append(list.APIResources, metav1.APIResource{Name: name})
+		}
+		return list
+	}
+	tests := []struct {
+		name      string
+		resources []*metav1.APIResourceList
+		want      []schema.GroupVersion
+	}{
+		{
+			name: "all versions served",
+			resources: []*metav1.APIResourceList{
+				resources("extensions/v1beta1", "ingresses"),
+				resources("networking.k8s.io/v1beta1", "ingresses", "ingressclasses"),
+				resources("networking.k8s.io/v1", "ingresses", "ingressclasses"),
+			},
+			want: []schema.GroupVersion{netv1.SchemeGroupVersion, netv1beta1.SchemeGroupVersion, extv1beta1.SchemeGroupVersion},
+		},
+		{
+			name: "networking.k8s.io/v1 serving no Ingresses yet",
+			resources: []*metav1.APIResourceList{
+				resources("networking.k8s.io/v1beta1", "ingresses"),
+				resources("networking.k8s.io/v1", "networkpolicies"),
+			},
+			want: []schema.GroupVersion{netv1beta1.SchemeGroupVersion},
+		},
+		{
+			name: "no Ingress API served",
+			resources: []*metav1.APIResourceList{
+				resources("v1", "services"),
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := &fakediscovery.FakeDiscovery{Fake: &clienttesting.Fake{Resources: tt.resources}}
+			got, err := ServedIngressAPIs(d)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
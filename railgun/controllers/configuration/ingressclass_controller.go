@@ -0,0 +1,170 @@
+package configuration
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	netv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+
+	"github.com/kong/kubernetes-ingress-controller/railgun/controllers"
+	mgrutils "github.com/kong/kubernetes-ingress-controller/railgun/manager/utils"
+)
+
+// IngressClassDefaultPluginsKey is the key of the ConfigMap referenced as IngressClass parameters
+// holding the comma-separated KongPlugins applied by default to the Ingresses of the class.
+const IngressClassDefaultPluginsKey = "default-plugins"
+
+// IngressClassReconciler tracks the IngressClasses controlled by Kong, along with their parameters,
+// in mgrutils.IngressClasses.
+//
+// The parameters of an IngressClass may reference a ConfigMap in the controller's namespace; see
+// IngressClassDefaultPluginsKey for the settings it can hold. Changes to the ConfigMap are watched.
+type IngressClassReconciler struct {
+	client.Client
+	Log    logr.Logger
+	Scheme *runtime.Scheme
+
+	// ConfigChanged, if set, is notified whenever the IngressClasses or their parameters change, so that
+	// the configuration built from them is synced to Kong again. A pending notification covers the
+	// changes made before it is received.
+	ConfigChanged chan<- event.GenericEvent
+
+	// MaxConcurrentReconciles is the number of objects reconciled in parallel.
+	MaxConcurrentReconciles int
+	// CacheSyncTimeout bounds the wait for the caches of the controller to sync at startup, defaulting to
+	// the one of controller-runtime if zero.
+	CacheSyncTimeout time.Duration
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *IngressClassReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&netv1.IngressClass{}, builder.WithPredicates(predicate.NewPredicateFuncs(isKongIngressClassObj))).
+		Watches(&source.Kind{Type: &corev1.ConfigMap{}}, handler.EnqueueRequestsFromMapFunc(r.referringClasses),
+			builder.WithPredicates(predicate.NewPredicateFuncs(isParametersNamespaceObj))).
+		WithOptions(controller.Options{
+			MaxConcurrentReconciles: r.MaxConcurrentReconciles,
+			CacheSyncTimeout:        r.CacheSyncTimeout,
+		}).
+		Complete(r)
+}
+
+//+kubebuilder:rbac:groups=networking.k8s.io,resources=ingressclasses,verbs=get;list;watch
+//+kubebuilder:rbac:groups=core,resources=configmaps,verbs=get;list;watch
+
+// Reconcile records the parameters of an IngressClass controlled by Kong.
+func (r *IngressClassReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	log := r.Log.WithValues("IngressClass", req.Name)
+
+	class := new(netv1.IngressClass)
+	if err := r.Get(ctx, req.NamespacedName, class); err != nil {
+		if client.IgnoreNotFound(err) == nil {
+			log.Info("ingress class deleted")
+			r.notifyIf(mgrutils.IngressClasses.Delete(req.Name), class)
+		}
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+	if !class.DeletionTimestamp.IsZero() || class.Spec.Controller != mgrutils.IngressClassController {
+		r.notifyIf(mgrutils.IngressClasses.Delete(class.Name), class)
+		return ctrl.Result{}, nil
+	}
+
+	params, err := r.getParameters(ctx, class)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	if mgrutils.IngressClasses.Set(class.Name, params) {
+		log.Info("ingress class configured", "default plugins", params.DefaultPlugins)
+		r.notifyIf(true, class)
+	}
+	return ctrl.Result{}, nil
+}
+
+// notifyIf notifies ConfigChanged of a change to class if changed is set.
+func (r *IngressClassReconciler) notifyIf(changed bool, class *netv1.IngressClass) {
+	if !changed || r.ConfigChanged == nil {
+		return
+	}
+	select {
+	case r.ConfigChanged <- event.GenericEvent{Object: class}:
+	default:
+		// a notification is already pending
+	}
+}
+
+// referringClasses maps a ConfigMap to the IngressClasses controlled by Kong which reference it as their
+// parameters.
+func (r *IngressClassReconciler) referringClasses(obj client.Object) []reconcile.Request {
+	classes := new(netv1.IngressClassList)
+	if err := r.List(context.Background(), classes); err != nil {
+		r.Log.Error(err, "could not list ingress classes referencing ConfigMap", "name", obj.GetName())
+		return nil
+	}
+	var requests []reconcile.Request
+	for i := range classes.Items {
+		class := &classes.Items[i]
+		if isKongIngressClassObj(class) && isConfigMapRef(class.Spec.Parameters) &&
+			class.Spec.Parameters.Name == obj.GetName() {
+			requests = append(requests, reconcile.Request{NamespacedName: types.NamespacedName{Name: class.Name}})
+		}
+	}
+	return requests
+}
+
+// getParameters reads the parameters referenced by the IngressClass. A missing ConfigMap results in
+// empty parameters; the Ingresses of the class are still handled.
+func (r *IngressClassReconciler) getParameters(ctx context.Context, class *netv1.IngressClass) (mgrutils.IngressClassParameters, error) {
+	var params mgrutils.IngressClassParameters
+	ref := class.Spec.Parameters
+	if ref == nil {
+		return params, nil
+	}
+	if !isConfigMapRef(ref) {
+		r.Log.Info("unsupported ingress class parameters, ignoring them", "IngressClass", class.Name, "kind", ref.Kind)
+		return params, nil
+	}
+
+	namespace := os.Getenv(controllers.CtrlNamespaceEnv)
+	configMap := new(corev1.ConfigMap)
+	if err := r.Get(ctx, client.ObjectKey{Namespace: namespace, Name: ref.Name}, configMap); err != nil {
+		if client.IgnoreNotFound(err) == nil {
+			r.Log.Info("ingress class parameters not found", "IngressClass", class.Name, "namespace", namespace, "name", ref.Name)
+			return params, nil
+		}
+		return params, fmt.Errorf("fetching parameters of ingress class %s: %w", class.Name, err)
+	}
+	params.DefaultPlugins = configMap.Data[IngressClassDefaultPluginsKey]
+	return params, nil
+}
+
+// isConfigMapRef tells whether the parameters ref is a ConfigMap.
+func isConfigMapRef(ref *corev1.TypedLocalObjectReference) bool {
+	return ref != nil && (ref.APIGroup == nil || *ref.APIGroup == "") && ref.Kind == "ConfigMap"
+}
+
+// isParametersNamespaceObj filters the objects in the controller's namespace, where the parameters of
+// IngressClasses are read from.
+func isParametersNamespaceObj(obj client.Object) bool {
+	return obj.GetNamespace() == os.Getenv(controllers.CtrlNamespaceEnv)
+}
+
+// isKongIngressClassObj filters the IngressClasses controlled by Kong.
+func isKongIngressClassObj(obj client.Object) bool {
+	class, ok := obj.(*netv1.IngressClass)
+	return ok && class.Spec.Controller == mgrutils.IngressClassController
+}
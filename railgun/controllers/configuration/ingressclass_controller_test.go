@@ -0,0 +1,107 @@
+package configuration
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/go-logr/logr"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	netv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+
+	"github.com/kong/kubernetes-ingress-controller/railgun/controllers"
+	mgrutils "github.com/kong/kubernetes-ingress-controller/railgun/manager/utils"
+)
+
+func TestIngressClassReconciler(t *testing.T) {
+	os.Setenv(controllers.CtrlNamespaceEnv, "kong")
+	defer os.Unsetenv(controllers.CtrlNamespaceEnv)
+	defer func(classes *mgrutils.IngressClassSet) { mgrutils.IngressClasses = classes }(mgrutils.IngressClasses)
+	mgrutils.IngressClasses = mgrutils.NewIngressClassSet()
+
+	withParams := &netv1.IngressClass{
+		ObjectMeta: metav1.ObjectMeta{Name: "with-params"},
+		Spec: netv1.IngressClassSpec{
+			Controller: mgrutils.IngressClassController,
+			Parameters: &corev1.TypedLocalObjectReference{Kind: "ConfigMap", Name: "class-params"},
+		},
+	}
+	withoutParams := &netv1.IngressClass{
+		ObjectMeta: metav1.ObjectMeta{Name: "without-params"},
+		Spec:       netv1.IngressClassSpec{Controller: mgrutils.IngressClassController},
+	}
+	other := &netv1.IngressClass{
+		ObjectMeta: metav1.ObjectMeta{Name: "other"},
+		Spec:       netv1.IngressClassSpec{Controller: "example.com/other"},
+	}
+	configMap := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "kong", Name: "class-params"},
+		Data:       map[string]string{IngressClassDefaultPluginsKey: "rate-limit,auth"},
+	}
+	configChanged := make(chan event.GenericEvent, 1)
+	r := &IngressClassReconciler{
+		Client: fake.NewClientBuilder().WithScheme(clientgoscheme.Scheme).
+			WithObjects(withParams, withoutParams, other, configMap).Build(),
+		Log:           logr.Discard(),
+		ConfigChanged: configChanged,
+	}
+	reconcile := func(name string) {
+		res, err := r.Reconcile(context.Background(), ctrl.Request{NamespacedName: types.NamespacedName{Name: name}})
+		assert.NoError(t, err)
+		assert.Zero(t, res, "the parameters are watched, not polled")
+	}
+	notified := func() bool {
+		select {
+		case <-configChanged:
+			return true
+		default:
+			return false
+		}
+	}
+
+	reconcile("with-params")
+	params, ok := mgrutils.IngressClasses.Get("with-params")
+	assert.True(t, ok)
+	assert.Equal(t, "rate-limit,auth", params.DefaultPlugins)
+	assert.True(t, notified())
+
+	reconcile("without-params")
+	params, ok = mgrutils.IngressClasses.Get("without-params")
+	assert.True(t, ok)
+	assert.Empty(t, params.DefaultPlugins)
+	assert.True(t, notified())
+
+	reconcile("other")
+	_, ok = mgrutils.IngressClasses.Get("other")
+	assert.False(t, ok)
+	assert.False(t, notified())
+
+	// unchanged parameters don't sync the configuration again
+	reconcile("with-params")
+	assert.False(t, notified())
+
+	// a change to the parameters ConfigMap reconciles the classes referencing it
+	assert.Equal(t, []ctrl.Request{{NamespacedName: types.NamespacedName{Name: "with-params"}}},
+		r.referringClasses(configMap))
+	assert.True(t, isParametersNamespaceObj(configMap))
+	assert.False(t, isParametersNamespaceObj(&corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Namespace: "default"}}))
+	configMap.Data[IngressClassDefaultPluginsKey] = "auth"
+	assert.NoError(t, r.Update(context.Background(), configMap))
+	reconcile("with-params")
+	params, _ = mgrutils.IngressClasses.Get("with-params")
+	assert.Equal(t, "auth", params.DefaultPlugins)
+	assert.True(t, notified())
+
+	assert.NoError(t, r.Delete(context.Background(), withParams))
+	reconcile("with-params")
+	_, ok = mgrutils.IngressClasses.Get("with-params")
+	assert.False(t, ok)
+	assert.True(t, notified())
+}
@@ -18,57 +18,34 @@ package configuration
 
 import (
 	"context"
-	"time"
 
-	"github.com/go-logr/logr"
-	"k8s.io/apimachinery/pkg/runtime"
 	ctrl "sigs.k8s.io/controller-runtime"
-	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
 	konghqcomv1 "github.com/kong/kubernetes-ingress-controller/railgun/apis/configuration/v1"
 )
 
 // KongIngressReconciler reconciles a KongIngress object
 type KongIngressReconciler struct {
-	client.Client
-	Log    logr.Logger
-	Scheme *runtime.Scheme
+	ConfigSecretReconciler
 }
 
+var _ reconcile.Reconciler = &KongIngressReconciler{}
+
 // SetupWithManager sets up the controller with the Manager.
 func (r *KongIngressReconciler) SetupWithManager(mgr ctrl.Manager) error {
-	return ctrl.NewControllerManagedBy(mgr).For(&konghqcomv1.KongIngress{}).Complete(r)
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&konghqcomv1.KongIngress{}, builder.WithPredicates(r.relevantUpdates())).
+		WithOptions(r.controllerOptions()).
+		Complete(r)
 }
 
 //+kubebuilder:rbac:groups=configuration.konghq.com,resources=kongingresses,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups=configuration.konghq.com,resources=kongingresses/status,verbs=get;update;patch
 //+kubebuilder:rbac:groups=configuration.konghq.com,resources=kongingresses/finalizers,verbs=update
 
-// Reconcile is part of the main kubernetes reconciliation loop which aims to
-// move the current state of the cluster closer to the desired state.
-// TODO(user): Modify the Reconcile function to compare the state specified by
-// the KongIngress object against the actual cluster state, and then
-// perform operations to make the cluster state reflect the state specified by
-// the user.
-//
-// For more details, check Reconcile and its Result here:
-// - https://pkg.go.dev/sigs.k8s.io/controller-runtime@v0.7.0/pkg/reconcile
+// Reconcile stores the KongIngresses in the configuration secret, and removes those which are deleted.
 func (r *KongIngressReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
-	_ = r.Log.WithValues("kongingress", req.NamespacedName)
-
-	log := r.Log.WithValues("ingress", req.NamespacedName)
-
-	ing := new(konghqcomv1.KongIngress)
-	if err := r.Get(ctx, req.NamespacedName, ing); err != nil {
-		return ctrl.Result{}, client.IgnoreNotFound(err)
-	}
-
-	if !ing.DeletionTimestamp.IsZero() && time.Now().After(ing.DeletionTimestamp.Time) {
-		log.Info("resource being deleted, its configuration will be removed", "namespace", req.Namespace, "name", req.Name)
-		return cleanupObj(ctx, r.Client, log, req.NamespacedName, ing)
-	}
-
-	return storeIngressObj(ctx, r.Client, log, req.NamespacedName, ing)
-
-	//return ctrl.Result{}, nil
+	return r.reconcileObj(ctx, req, "KongIngress", new(konghqcomv1.KongIngress), nil)
 }
@@ -0,0 +1,39 @@
+package configuration
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	metricsNamespace = "kong_ingress_controller"
+	metricsSubsystem = "configuration_secret"
+)
+
+var (
+	configSecretSize = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Subsystem: metricsSubsystem,
+		Name:      "size_bytes",
+		Help:      "Size of the data of the configuration secret, which Kubernetes limits to 1 MiB.",
+	})
+
+	configSecretSizeWarnings = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Subsystem: metricsSubsystem,
+		Name:      "size_warnings_total",
+		Help:      "Number of updates of the configuration secret leaving its size above the warning threshold.",
+	})
+)
+
+// RegisterMetrics registers the collectors of the configuration secret metrics with registerer.
+func RegisterMetrics(registerer prometheus.Registerer) error {
+	for _, collector := range []prometheus.Collector{
+		configSecretSize,
+		configSecretSizeWarnings,
+	} {
+		if err := registerer.Register(collector); err != nil {
+			return err
+		}
+	}
+	return nil
+}
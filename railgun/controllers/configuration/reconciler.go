@@ -0,0 +1,150 @@
+package configuration
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+)
+
+// DefaultRelevantAnnotationPrefixes are the prefixes of the annotations which affect the configuration of Kong,
+// including the ingress.kubernetes.io/force-ssl-redirect and ingress.kubernetes.io/service-upstream ones.
+var DefaultRelevantAnnotationPrefixes = []string{"konghq.com/", "kubernetes.io/ingress.class", "ingress.kubernetes.io/"}
+
+// ConfigSecretReconciler is embedded by the reconcilers storing the objects they watch in the configuration
+// secret, which the SecretReconciler translates and pushes to Kong. It holds their common settings and
+// reconciles their objects the same way, so that they only differ by the objects they watch.
+type ConfigSecretReconciler struct {
+	client.Client
+	Log      logr.Logger
+	Scheme   *runtime.Scheme
+	Recorder record.EventRecorder
+
+	// ConfigSecret is the Secret the configuration for Kong is stored in.
+	ConfigSecret types.NamespacedName
+	// ConfigSecretFieldManager, if set, ensures ConfigSecret exists with server-side apply under this field
+	// manager rather than a get or create.
+	ConfigSecretFieldManager string
+	// ConfigSecretOwner, if set, is made the owner of ConfigSecret, so that it is garbage-collected along with it.
+	ConfigSecretOwner *metav1.OwnerReference
+	// ConfigSecretCompression compresses the contents stored in ConfigSecret.
+	ConfigSecretCompression bool
+
+	// MaxConcurrentReconciles is the number of objects reconciled in parallel.
+	MaxConcurrentReconciles int
+	// CacheSyncTimeout bounds the wait for the caches of the controller to sync at startup, defaulting to
+	// the one of controller-runtime if zero.
+	CacheSyncTimeout time.Duration
+	// RelevantAnnotationPrefixes are the prefixes of the annotations whose changes are reconciled: updates
+	// changing neither the generation of an object, nor its labels, nor any of these annotations, e.g. of its
+	// status, are ignored. Defaults to DefaultRelevantAnnotationPrefixes if nil.
+	RelevantAnnotationPrefixes []string
+}
+
+// controllerOptions returns the options of the controller of the reconciler.
+func (r *ConfigSecretReconciler) controllerOptions() controller.Options {
+	return controller.Options{
+		MaxConcurrentReconciles: r.MaxConcurrentReconciles,
+		CacheSyncTimeout:        r.CacheSyncTimeout,
+	}
+}
+
+// relevantUpdates returns the predicate ignoring the updates of objects which cannot affect the
+// configuration of Kong.
+func (r *ConfigSecretReconciler) relevantUpdates() predicate.Predicate {
+	prefixes := r.RelevantAnnotationPrefixes
+	if prefixes == nil {
+		prefixes = DefaultRelevantAnnotationPrefixes
+	}
+	return predicate.Funcs{
+		UpdateFunc: func(e event.UpdateEvent) bool {
+			if e.ObjectOld == nil || e.ObjectNew == nil {
+				return true
+			}
+			// objects whose generation isn't tracked are always reconciled
+			if e.ObjectNew.GetGeneration() == 0 || e.ObjectOld.GetGeneration() != e.ObjectNew.GetGeneration() {
+				return true
+			}
+			if !e.ObjectOld.GetDeletionTimestamp().Equal(e.ObjectNew.GetDeletionTimestamp()) {
+				return true
+			}
+			// labels select objects, e.g. the global label makes a plugin apply to every entity
+			if !equalAnnotations(e.ObjectOld.GetLabels(), e.ObjectNew.GetLabels(), []string{""}) {
+				return true
+			}
+			return !equalAnnotations(e.ObjectOld.GetAnnotations(), e.ObjectNew.GetAnnotations(), prefixes)
+		},
+	}
+}
+
+// equalAnnotations returns whether the annotations matching any of prefixes are the same in a and b.
+// The empty prefix matches every key, comparing maps such as labels as a whole.
+func equalAnnotations(a, b map[string]string, prefixes []string) bool {
+	relevant := func(key string) bool {
+		for _, prefix := range prefixes {
+			if strings.HasPrefix(key, prefix) {
+				return true
+			}
+		}
+		return false
+	}
+	for key, value := range a {
+		if other, ok := b[key]; relevant(key) && (!ok || other != value) {
+			return false
+		}
+	}
+	for key := range b {
+		if _, ok := a[key]; relevant(key) && !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// reconcileObj stores obj, the object of kind requested by req, in the configuration secret, or removes it
+// from there if it is being deleted or, if selected is set, no longer selected by it. A Warning event is
+// recorded on obj if it could not be stored.
+func (r *ConfigSecretReconciler) reconcileObj(ctx context.Context, req ctrl.Request, kind string, obj client.Object,
+	selected func(context.Context, client.Object) (bool, error)) (ctrl.Result, error) {
+	log := r.Log.WithValues(kind, req.NamespacedName)
+
+	if err := r.Get(ctx, req.NamespacedName, obj); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+
+	if deletion := obj.GetDeletionTimestamp(); !deletion.IsZero() && time.Now().After(deletion.Time) {
+		log.Info("resource is being deleted, its configuration will be removed", "type", kind, "namespace", req.Namespace, "name", req.Name)
+		return cleanupObj(ctx, r.Client, log, r.ConfigSecret, req.NamespacedName, obj)
+	}
+
+	if selected != nil {
+		ok, err := selected(ctx, obj)
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+		if !ok {
+			// the object may have been deselected since its configuration was stored
+			return cleanupObj(ctx, r.Client, log, r.ConfigSecret, req.NamespacedName, obj)
+		}
+	}
+
+	result, err := storeIngressObj(ctx, r.Client, log, r.ConfigSecret, r.ConfigSecretFieldManager, r.ConfigSecretOwner,
+		r.ConfigSecretCompression, req.NamespacedName, obj)
+	if err != nil && r.Recorder != nil {
+		r.Recorder.Event(obj, corev1.EventTypeWarning, KongConfigurationSyncFailedReason,
+			fmt.Sprintf("failed to store the configuration for Kong: %v", err))
+	}
+	return result, err
+}
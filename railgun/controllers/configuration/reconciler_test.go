@@ -0,0 +1,401 @@
+package configuration
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	extv1beta1 "k8s.io/api/extensions/v1beta1"
+	netv1 "k8s.io/api/networking/v1"
+	netv1beta1 "k8s.io/api/networking/v1beta1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	gatewayv1alpha1 "sigs.k8s.io/gateway-api/apis/v1alpha1"
+
+	"github.com/kong/kubernetes-ingress-controller/pkg/annotations"
+	konghqcomv1 "github.com/kong/kubernetes-ingress-controller/railgun/apis/configuration/v1"
+	"github.com/kong/kubernetes-ingress-controller/railgun/apis/configuration/v1alpha1"
+	"github.com/kong/kubernetes-ingress-controller/railgun/pkg/configsecret"
+)
+
+// setupReconciler is a reconciler which sets up its controller with the manager.
+type setupReconciler interface {
+	reconcile.Reconciler
+	SetupWithManager(ctrl.Manager) error
+}
+
+// configSecretReconcilerCase is a reconciler storing objects in the configuration secret, along with an
+// object it stores.
+type configSecretReconcilerCase struct {
+	name       string
+	reconciler func(ConfigSecretReconciler) setupReconciler
+	obj        client.Object
+}
+
+func configSecretReconcilerCases() []configSecretReconcilerCase {
+	meta := func() metav1.ObjectMeta {
+		return metav1.ObjectMeta{
+			Namespace:   "default",
+			Name:        "test",
+			Annotations: map[string]string{annotations.IngressClassKey: "kong"},
+		}
+	}
+	return []configSecretReconcilerCase{
+		{
+			name: "NetV1Ingress",
+			reconciler: func(base ConfigSecretReconciler) setupReconciler {
+				return &NetV1IngressReconciler{base}
+			},
+			obj: &netv1.Ingress{ObjectMeta: meta()},
+		},
+		{
+			name: "NetV1Beta1Ingress",
+			reconciler: func(base ConfigSecretReconciler) setupReconciler {
+				return &NetV1Beta1IngressReconciler{base}
+			},
+			obj: &netv1beta1.Ingress{ObjectMeta: meta()},
+		},
+		{
+			name: "ExtV1Beta1Ingress",
+			reconciler: func(base ConfigSecretReconciler) setupReconciler {
+				return &ExtV1Beta1IngressReconciler{base}
+			},
+			obj: &extv1beta1.Ingress{ObjectMeta: meta()},
+		},
+		{
+			name: "KongV1KongIngress",
+			reconciler: func(base ConfigSecretReconciler) setupReconciler {
+				return &KongV1KongIngressReconciler{base}
+			},
+			obj: &konghqcomv1.KongIngress{ObjectMeta: meta()},
+		},
+		{
+			name: "KongV1KongPlugin",
+			reconciler: func(base ConfigSecretReconciler) setupReconciler {
+				return &KongV1KongPluginReconciler{base}
+			},
+			obj: &konghqcomv1.KongPlugin{ObjectMeta: meta(), PluginName: "key-auth"},
+		},
+		{
+			name: "KongV1KongClusterPlugin",
+			reconciler: func(base ConfigSecretReconciler) setupReconciler {
+				return &KongV1KongClusterPluginReconciler{base}
+			},
+			obj: &konghqcomv1.KongClusterPlugin{ObjectMeta: meta(), PluginName: "key-auth"},
+		},
+		{
+			name: "KongV1KongConsumer",
+			reconciler: func(base ConfigSecretReconciler) setupReconciler {
+				return &KongV1KongConsumerReconciler{base}
+			},
+			obj: &konghqcomv1.KongConsumer{ObjectMeta: meta(), Username: "test"},
+		},
+		{
+			name: "KongV1UDPIngress",
+			reconciler: func(base ConfigSecretReconciler) setupReconciler {
+				return &KongV1UDPIngressReconciler{base}
+			},
+			obj: &v1alpha1.UDPIngress{ObjectMeta: meta()},
+		},
+		{
+			name: "KongIngress",
+			reconciler: func(base ConfigSecretReconciler) setupReconciler {
+				return &KongIngressReconciler{base}
+			},
+			obj: &konghqcomv1.KongIngress{ObjectMeta: meta()},
+		},
+		{
+			name: "HTTPRoute",
+			reconciler: func(base ConfigSecretReconciler) setupReconciler {
+				return &HTTPRouteReconciler{base}
+			},
+			obj: &gatewayv1alpha1.HTTPRoute{ObjectMeta: meta()},
+		},
+	}
+}
+
+func configSecretReconcilerScheme(t *testing.T) *runtime.Scheme {
+	scheme := runtime.NewScheme()
+	require.NoError(t, clientgoscheme.AddToScheme(scheme))
+	require.NoError(t, konghqcomv1.AddToScheme(scheme))
+	require.NoError(t, v1alpha1.AddToScheme(scheme))
+	require.NoError(t, gatewayv1alpha1.AddToScheme(scheme))
+	return scheme
+}
+
+// reconcileUntilDone reconciles the object requested by req until it is no longer requeued.
+func reconcileUntilDone(t *testing.T, r reconcile.Reconciler, req ctrl.Request) {
+	for i := 0; i < 5; i++ {
+		result, err := r.Reconcile(context.Background(), req)
+		require.NoError(t, err)
+		if !result.Requeue {
+			return
+		}
+	}
+	t.Fatalf("%s still requeued after 5 reconciliations", req)
+}
+
+func TestConfigSecretReconcilersSetupWithManager(t *testing.T) {
+	scheme := configSecretReconcilerScheme(t)
+	mgr, err := ctrl.NewManager(&rest.Config{Host: "http://127.0.0.1:1"}, ctrl.Options{
+		Scheme:             scheme,
+		MetricsBindAddress: "0",
+		MapperProvider: func(*rest.Config) (meta.RESTMapper, error) {
+			return meta.NewDefaultRESTMapper(scheme.PrioritizedVersionsAllGroups()), nil
+		},
+	})
+	require.NoError(t, err)
+
+	for _, tt := range configSecretReconcilerCases() {
+		t.Run(tt.name, func(t *testing.T) {
+			r := tt.reconciler(ConfigSecretReconciler{
+				Client: mgr.GetClient(),
+				Log:    logr.Discard(),
+				Scheme: mgr.GetScheme(),
+			})
+			assert.NoError(t, r.SetupWithManager(mgr))
+		})
+	}
+}
+
+func TestConfigSecretReconcilersReconcile(t *testing.T) {
+	ctx := context.Background()
+	configSecret := types.NamespacedName{Namespace: "kong", Name: "kong-config"}
+	for _, tt := range configSecretReconcilerCases() {
+		t.Run(tt.name, func(t *testing.T) {
+			scheme := configSecretReconcilerScheme(t)
+			nsn := types.NamespacedName{Namespace: tt.obj.GetNamespace(), Name: tt.obj.GetName()}
+			req := ctrl.Request{NamespacedName: nsn}
+			gvk, err := apiutil.GVKForObject(tt.obj, scheme)
+			require.NoError(t, err)
+			obj := tt.obj.DeepCopyObject().(client.Object)
+			obj.GetObjectKind().SetGroupVersionKind(gvk)
+			key := configsecret.KeyFor(obj, nsn)
+			reconciler := func(objs ...client.Object) (client.Client, reconcile.Reconciler, *record.FakeRecorder) {
+				c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(objs...).Build()
+				recorder := record.NewFakeRecorder(10)
+				return c, tt.reconciler(ConfigSecretReconciler{
+					Client:       c,
+					Log:          logr.Discard(),
+					Scheme:       scheme,
+					Recorder:     recorder,
+					ConfigSecret: configSecret,
+				}), recorder
+			}
+
+			if _, ok := tt.obj.(*gatewayv1alpha1.HTTPRoute); ok {
+				// the route is not attached to any Gateway, its stored configuration is removed
+				stored := &corev1.Secret{
+					ObjectMeta: metav1.ObjectMeta{Namespace: configSecret.Namespace, Name: configSecret.Name},
+					Data:       map[string][]byte{key: []byte("stale")},
+				}
+				c, r, _ := reconciler(tt.obj, stored)
+				reconcileUntilDone(t, r, req)
+				secret := new(corev1.Secret)
+				require.NoError(t, c.Get(ctx, configSecret, secret))
+				assert.NotContains(t, secret.Data, key)
+				return
+			}
+
+			c, r, recorder := reconciler(tt.obj)
+			reconcileUntilDone(t, r, req)
+			secret := new(corev1.Secret)
+			require.NoError(t, c.Get(ctx, configSecret, secret))
+			assert.Contains(t, secret.Data, key)
+			require.NoError(t, c.Get(ctx, nsn, obj))
+			assert.Contains(t, obj.GetFinalizers(), KongIngressFinalizer)
+			assert.Empty(t, drainEvents(recorder))
+
+			// once deleted, the object is removed from the configuration secret and its finalizer released
+			deleted := metav1.NewTime(time.Now().Add(-time.Second))
+			obj.SetDeletionTimestamp(&deleted)
+			c, r, _ = reconciler(obj, secret)
+			reconcileUntilDone(t, r, req)
+			secret = new(corev1.Secret)
+			require.NoError(t, c.Get(ctx, configSecret, secret))
+			assert.NotContains(t, secret.Data, key)
+			got, err := scheme.New(gvk)
+			require.NoError(t, err)
+			require.NoError(t, c.Get(ctx, nsn, got.(client.Object)))
+			assert.NotContains(t, got.(client.Object).GetFinalizers(), KongIngressFinalizer)
+		})
+	}
+}
+
+func TestConfigSecretReconcilersProgrammedConditions(t *testing.T) {
+	ctx := context.Background()
+	configSecret := types.NamespacedName{Namespace: "kong", Name: "kong-config"}
+	scheme := configSecretReconcilerScheme(t)
+	for _, tt := range configSecretReconcilerCases() {
+		gvk, err := apiutil.GVKForObject(tt.obj, scheme)
+		require.NoError(t, err)
+		obj, conditions := programmedObject(gvk.Kind)
+		if obj == nil {
+			continue
+		}
+		t.Run(tt.name, func(t *testing.T) {
+			// the object is stored in the configuration secret by its controller, and marked as programmed
+			// once the secret is synced
+			nsn := types.NamespacedName{Namespace: tt.obj.GetNamespace(), Name: tt.obj.GetName()}
+			c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(tt.obj.DeepCopyObject().(client.Object)).Build()
+			reconcileUntilDone(t, tt.reconciler(ConfigSecretReconciler{
+				Client:       c,
+				Log:          logr.Discard(),
+				Scheme:       scheme,
+				ConfigSecret: configSecret,
+			}), ctrl.Request{NamespacedName: nsn})
+			secret := new(corev1.Secret)
+			require.NoError(t, c.Get(ctx, configSecret, secret))
+
+			(&SecretReconciler{Client: c, Log: logr.Discard()}).updateProgrammedConditions(ctx, secret, nil, nil)
+			require.NoError(t, c.Get(ctx, nsn, obj))
+			condition := meta.FindStatusCondition(*conditions, konghqcomv1.ProgrammedConditionType)
+			if assert.NotNil(t, condition) {
+				assert.Equal(t, metav1.ConditionTrue, condition.Status)
+				assert.Equal(t, konghqcomv1.ProgrammedReasonSynced, condition.Reason)
+			}
+		})
+	}
+}
+
+func TestConfigSecretReconcilerRelevantUpdates(t *testing.T) {
+	ingress := func(generation int64, annotations map[string]string) *netv1.Ingress {
+		return &netv1.Ingress{ObjectMeta: metav1.ObjectMeta{
+			Namespace:   "default",
+			Name:        "test",
+			Generation:  generation,
+			Annotations: annotations,
+		}}
+	}
+	deleted := metav1.Now()
+	deleting := ingress(1, nil)
+	deleting.DeletionTimestamp = &deleted
+	withStatus := ingress(1, nil)
+	withStatus.Status.LoadBalancer.Ingress = []corev1.LoadBalancerIngress{{IP: "10.0.0.1"}}
+	plugin := func(labels map[string]string) *konghqcomv1.KongPlugin {
+		return &konghqcomv1.KongPlugin{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "test", Generation: 1, Labels: labels},
+			PluginName: "key-auth",
+		}
+	}
+
+	for _, tt := range []struct {
+		name     string
+		prefixes []string
+		old, new client.Object
+		want     bool
+	}{
+		{
+			name: "status",
+			old:  ingress(1, nil),
+			new:  withStatus,
+			want: false,
+		},
+		{
+			name: "unrelated annotation",
+			old:  ingress(1, map[string]string{"example.com/owner": "a"}),
+			new:  ingress(1, map[string]string{"example.com/owner": "b", "example.com/team": "c"}),
+			want: false,
+		},
+		{
+			name: "generation",
+			old:  ingress(1, nil),
+			new:  ingress(2, nil),
+			want: true,
+		},
+		{
+			name: "changed kong annotation",
+			old:  ingress(1, map[string]string{"konghq.com/strip-path": "true"}),
+			new:  ingress(1, map[string]string{"konghq.com/strip-path": "false"}),
+			want: true,
+		},
+		{
+			name: "added class annotation",
+			old:  ingress(1, nil),
+			new:  ingress(1, map[string]string{annotations.IngressClassKey: "kong"}),
+			want: true,
+		},
+		{
+			name: "removed kong annotation",
+			old:  ingress(1, map[string]string{"konghq.com/plugins": "auth"}),
+			new:  ingress(1, nil),
+			want: true,
+		},
+		{
+			name: "changed ingress.kubernetes.io annotation",
+			old:  ingress(1, map[string]string{"ingress.kubernetes.io/force-ssl-redirect": "false"}),
+			new:  ingress(1, map[string]string{"ingress.kubernetes.io/force-ssl-redirect": "true"}),
+			want: true,
+		},
+		{
+			name: "added service-upstream annotation",
+			old:  ingress(1, nil),
+			new:  ingress(1, map[string]string{"ingress.kubernetes.io/service-upstream": "true"}),
+			want: true,
+		},
+		{
+			name: "added global label",
+			old:  plugin(nil),
+			new:  plugin(map[string]string{"global": "true"}),
+			want: true,
+		},
+		{
+			name: "removed global label",
+			old:  plugin(map[string]string{"global": "true", "team": "a"}),
+			new:  plugin(map[string]string{"team": "a"}),
+			want: true,
+		},
+		{
+			name: "same labels",
+			old:  plugin(map[string]string{"global": "true"}),
+			new:  plugin(map[string]string{"global": "true"}),
+			want: false,
+		},
+		{
+			name: "deletion",
+			old:  ingress(1, nil),
+			new:  deleting,
+			want: true,
+		},
+		{
+			name: "untracked generation",
+			old:  ingress(0, nil),
+			new:  ingress(0, nil),
+			want: true,
+		},
+		{
+			name:     "configured prefix",
+			prefixes: []string{"example.com/"},
+			old:      ingress(1, map[string]string{"example.com/owner": "a"}),
+			new:      ingress(1, map[string]string{"example.com/owner": "b"}),
+			want:     true,
+		},
+		{
+			name:     "annotation outside configured prefixes",
+			prefixes: []string{"example.com/"},
+			old:      ingress(1, map[string]string{"konghq.com/strip-path": "true"}),
+			new:      ingress(1, map[string]string{"konghq.com/strip-path": "false"}),
+			want:     false,
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			r := ConfigSecretReconciler{RelevantAnnotationPrefixes: tt.prefixes}
+			assert.Equal(t, tt.want, r.relevantUpdates().Update(event.UpdateEvent{ObjectOld: tt.old, ObjectNew: tt.new}))
+		})
+	}
+}
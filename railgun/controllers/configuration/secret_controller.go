@@ -17,20 +17,40 @@ limitations under the License.
 package configuration
 
 import (
+	"bytes"
 	"context"
+	"fmt"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/go-logr/logr"
 	"github.com/kong/kubernetes-ingress-controller/pkg/deckgen"
+	"github.com/kong/kubernetes-ingress-controller/pkg/kongstate"
 	"github.com/kong/kubernetes-ingress-controller/pkg/parser"
 	"github.com/kong/kubernetes-ingress-controller/pkg/sendconfig"
+	konghqcomv1 "github.com/kong/kubernetes-ingress-controller/railgun/apis/configuration/v1"
+	"github.com/kong/kubernetes-ingress-controller/railgun/pkg/configdump"
+	"github.com/kong/kubernetes-ingress-controller/railgun/pkg/configsecret"
 	"github.com/kong/kubernetes-ingress-controller/railgun/pkg/store"
 	"github.com/sirupsen/logrus"
 	corev1 "k8s.io/api/core/v1"
+	discoveryv1beta1 "k8s.io/api/discovery/v1beta1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
 )
 
 type SecretReconcilerParams struct {
@@ -38,15 +58,74 @@ type SecretReconcilerParams struct {
 	WatchNamespace string
 
 	KongConfig sendconfig.Kong
+
+	// Debouncer coalesces the pushes of changes made in quick succession.
+	// If nil, every reconcile pushes the configuration.
+	Debouncer *sendconfig.Debouncer
+
+	// SyncFailureJitter delays the retry of a failed sync, so that retries don't all reach Kong
+	// together once it recovers. If nil or without delay, the retry follows the controller's backoff.
+	SyncFailureJitter *sendconfig.RetryJitter
+
+	// ConfigDump receives every configuration generated for Kong, if set.
+	ConfigDump *configdump.Store
+
+	// UseEndpointSlices makes the targets of upstreams come from the EndpointSlices
+	// of services rather than their Endpoints, and changes to them trigger a sync.
+	UseEndpointSlices bool
+
+	// UseHTTPRoutes translates the Gateway API HTTPRoutes attached to Gateways of Kong along with Ingresses.
+	UseHTTPRoutes bool
+
+	// ServiceDefaults are applied to every generated Kong service unless overridden by a KongIngress.
+	ServiceDefaults parser.ServiceDefaults
+
+	// RouteDefaults are applied to every generated Kong route unless overridden by an annotation or a KongIngress.
+	RouteDefaults parser.RouteDefaults
+
+	// CredentialTypeKey is the data field of Secrets holding the type of their credential.
+	CredentialTypeKey string
+
+	// Resync, if set, receives the requests for a manual resync, which push the whole configuration to Kong
+	// even if none of the watched objects changed.
+	Resync <-chan event.GenericEvent
+
+	// ConfigChanges, if set, receives notifications that settings the configuration is built from changed
+	// outside of the watched objects, e.g. the parameters of an IngressClass, which sync the configuration.
+	ConfigChanges <-chan event.GenericEvent
 }
 
 // SecretReconciler reconciles a Secret object
 type SecretReconciler struct {
 	client.Client
-	Log    logr.Logger
-	Scheme *runtime.Scheme
+	Log      logr.Logger
+	Scheme   *runtime.Scheme
+	Recorder record.EventRecorder
 
 	Params SecretReconcilerParams
+
+	// MaxConcurrentReconciles is the number of workers of the controller. Whatever
+	// their number, the configuration is built and pushed to Kong by one of them at
+	// a time, so that pushes never race each other.
+	MaxConcurrentReconciles int
+	// CacheSyncTimeout bounds the wait for the caches of the controller to sync at startup, defaulting to
+	// the one of controller-runtime if zero.
+	CacheSyncTimeout time.Duration
+
+	syncLock sync.Mutex
+
+	// synced holds the contents of the configuration secret which were last
+	// successfully pushed to Kong, used to find the objects triggering a sync.
+	synced     map[string][]byte
+	syncedLock sync.Mutex
+
+	// pathValidator validates the regular expression paths of routes with Kong before they are synced.
+	// It is set up with the controller, if a Kong client is configured.
+	pathValidator *sendconfig.RoutePathValidator
+
+	// resyncRequested is set once a manual resync is requested, until the configuration is pushed.
+	resyncRequested bool
+	resyncLock      sync.Mutex
 }
 
 func (r *SecretReconciler) matchNsName(object client.Object) bool {
@@ -56,16 +135,72 @@ func (r *SecretReconciler) matchNsName(object client.Object) bool {
 
 // SetupWithManager sets up the controller with the Manager.
 func (r *SecretReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	if r.Params.KongConfig.Client != nil {
+		r.pathValidator = sendconfig.NewRoutePathValidator(r.Params.KongConfig.Client)
+	}
 	// TODO: something to keep in mind: long term we're still considering use a custom API instead of a secret for the Configuration.
-	return ctrl.NewControllerManagedBy(mgr).
-		For(&corev1.Secret{}).
-		WithEventFilter(predicate.NewPredicateFuncs(r.matchNsName)).
-		Complete(r)
+	b := ctrl.NewControllerManagedBy(mgr).
+		For(&corev1.Secret{}, builder.WithPredicates(predicate.NewPredicateFuncs(r.matchNsName))).
+		WithOptions(controller.Options{
+			MaxConcurrentReconciles: r.MaxConcurrentReconciles,
+			CacheSyncTimeout:        r.CacheSyncTimeout,
+		})
+	if r.Params.UseEndpointSlices {
+		// the targets of every upstream are assembled from the slices when
+		// syncing, so any change to them is a change to the configuration
+		b = b.Watches(&source.Kind{Type: &discoveryv1beta1.EndpointSlice{}},
+			handler.EnqueueRequestsFromMapFunc(r.configSecretRequest))
+	}
+	if r.Params.Resync != nil {
+		b = b.Watches(&source.Channel{Source: r.Params.Resync}, handler.EnqueueRequestsFromMapFunc(r.resyncRequest))
+	}
+	if r.Params.ConfigChanges != nil {
+		b = b.Watches(&source.Channel{Source: r.Params.ConfigChanges},
+			handler.EnqueueRequestsFromMapFunc(r.configSecretRequest))
+	}
+	return b.Complete(r)
+}
+
+// resyncRequest records that a manual resync was requested, and maps it to a request to reconcile the
+// configuration secret.
+func (r *SecretReconciler) resyncRequest(obj client.Object) []reconcile.Request {
+	r.resyncLock.Lock()
+	defer r.resyncLock.Unlock()
+	r.resyncRequested = true
+	return r.configSecretRequest(obj)
+}
+
+// takeResyncRequest returns whether a manual resync was requested since the last push, clearing the request.
+func (r *SecretReconciler) takeResyncRequest() bool {
+	r.resyncLock.Lock()
+	defer r.resyncLock.Unlock()
+	requested := r.resyncRequested
+	r.resyncRequested = false
+	return requested
+}
+
+// logResync logs the outcome of a manual resync, which failed with err unless it is nil.
+func (r *SecretReconciler) logResync(err error) {
+	if err != nil {
+		r.Log.Error(err, "manual resync failed")
+		return
+	}
+	r.Log.Info("manual resync completed")
+}
+
+// configSecretRequest maps any object to a request to reconcile the configuration secret.
+func (r *SecretReconciler) configSecretRequest(client.Object) []reconcile.Request {
+	return []reconcile.Request{{NamespacedName: types.NamespacedName{
+		Namespace: r.Params.WatchNamespace,
+		Name:      r.Params.WatchName,
+	}}}
 }
 
 //+kubebuilder:rbac:groups=core,resources=secrets,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups=core,resources=secrets/status,verbs=get;update;patch
 //+kubebuilder:rbac:groups=core,resources=secrets/finalizers,verbs=update
+//+kubebuilder:rbac:groups=core,resources=events,verbs=create;patch
+//+kubebuilder:rbac:groups=discovery.k8s.io,resources=endpointslices,verbs=get;list;watch
 
 // Reconcile manages the configuration secret for ingresses and parses that into a Kong configuration
 // which is posted to all available Proxy APIs.
@@ -78,20 +213,313 @@ func (r *SecretReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctr
 		return ctrl.Result{}, client.IgnoreNotFound(err)
 	}
 
-	storer := store.New(r.Client)
-	kongstate, err := parser.Build(logruslogger, storer)
+	r.syncLock.Lock()
+	defer r.syncLock.Unlock()
+
+	// changes arriving too soon after the last push are batched: the secret
+	// is reconciled again, with its latest contents, once the period is over
+	if wait := r.Params.Debouncer.Reserve(); wait > 0 {
+		return ctrl.Result{RequeueAfter: wait}, nil
+	}
+	// every reconcile pushes the whole configuration, a manual resync only needs to be reported
+	manualResync := r.takeResyncRequest()
+	if manualResync {
+		r.Log.Info("performing a manual resync of the configuration to Kong")
+	}
+
+	storer := store.NewWithOptions(r.Client, store.Options{
+		UseEndpointSlices: r.Params.UseEndpointSlices,
+		HTTPRoutes:        r.Params.UseHTTPRoutes,
+	})
+	var unresolved []parser.UnresolvedBackend
+	var validatePath func(string) error
+	if r.pathValidator != nil {
+		validatePath = r.pathValidator.Validate
+	}
+	kongstate, err := parser.BuildWithOptions(logruslogger, storer, parser.Options{
+		ServiceDefaults:   r.Params.ServiceDefaults,
+		RouteDefaults:     r.Params.RouteDefaults,
+		CredentialTypeKey: r.Params.CredentialTypeKey,
+		OnUnresolvedBackend: func(backend parser.UnresolvedBackend) {
+			unresolved = append(unresolved, backend)
+		},
+		ValidatePath: validatePath,
+		KongVersion:  r.Params.KongConfig.Version,
+	})
 	if err != nil {
-		return ctrl.Result{}, err
+		if manualResync {
+			r.logResync(err)
+		}
+		r.updateProgrammedConditions(ctx, configSecret, nil, err)
+		return r.syncFailed(configSecret, err)
 	}
+	r.recordUnresolvedBackends(configSecret, unresolved)
 
-	targetConfig := deckgen.ToDeckContent(ctx, logruslogger, kongstate, nil, nil)
+	selectorTags := r.Params.KongConfig.FilterTags
+	targetConfig := deckgen.ToDeckContent(ctx, logruslogger, kongstate, nil, selectorTags)
+	r.Params.ConfigDump.Set(targetConfig)
 
 	timedCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
-	_, err = sendconfig.PerformUpdate(timedCtx, logruslogger, &r.Params.KongConfig, true, false, targetConfig, nil, nil, nil)
+	_, err = sendconfig.PerformUpdate(timedCtx, logruslogger, &r.Params.KongConfig, r.Params.KongConfig.InMemory, false, targetConfig, selectorTags, nil, nil)
 	if err != nil {
-		return ctrl.Result{}, err
+		r.recordRejectedEntities(configSecret, kongstate, sendconfig.RejectedEntities(err))
+		if manualResync {
+			r.logResync(err)
+		}
+		r.updateProgrammedConditions(ctx, configSecret, kongstate, err)
+		return r.syncFailed(configSecret, err)
+	}
+	if manualResync {
+		r.logResync(nil)
+	}
+	if !r.Params.KongConfig.DryRun {
+		r.updateProgrammedConditions(ctx, configSecret, kongstate, nil)
+		r.recordSyncSuccess(configSecret)
 	}
 
 	return ctrl.Result{}, nil
 }
+
+// syncFailed records the failure of a sync and requeues the configuration secret after the delay of
+// SyncFailureJitter, or returns err for the controller to retry with its backoff if there is none. If Kong
+// asked to retry later with a Retry-After header, the secret is requeued no sooner than that.
+func (r *SecretReconciler) syncFailed(configSecret *corev1.Secret, err error) (ctrl.Result, error) {
+	r.recordSyncFailure(configSecret, err)
+	delay := r.Params.SyncFailureJitter.Delay()
+	if retryAfter, ok := sendconfig.RetryAfter(err); ok && retryAfter > delay {
+		delay = retryAfter
+	}
+	if delay <= 0 {
+		return ctrl.Result{}, err
+	}
+	// a result is only honored without an error, so the error is logged here
+	r.Log.Error(err, "failed to sync the configuration to Kong, retrying", "after", delay.String())
+	return ctrl.Result{RequeueAfter: delay}, nil
+}
+
+// recordSyncFailure records a Warning event on every object whose configuration
+// changed since the last successful sync, as one of them caused the failure.
+func (r *SecretReconciler) recordSyncFailure(configSecret *corev1.Secret, syncErr error) {
+	r.syncedLock.Lock()
+	defer r.syncedLock.Unlock()
+
+	for key, value := range r.configObjects(configSecret) {
+		if synced, ok := r.synced[key]; ok && bytes.Equal(synced, value) {
+			continue
+		}
+		r.recordEvent(key, value, corev1.EventTypeWarning, KongConfigurationSyncFailedReason,
+			fmt.Sprintf("failed to sync the configuration to Kong: %v", syncErr))
+	}
+}
+
+// recordRejectedEntities logs the entities Kong reported as invalid when rejecting the configuration,
+// and records a Warning event on the objects they were generated from.
+func (r *SecretReconciler) recordRejectedEntities(configSecret *corev1.Secret, state *kongstate.KongState,
+	entities []sendconfig.RejectedEntity) {
+	for _, entity := range entities {
+		log := r.Log.WithValues("entity", entity.Path, "name", entity.Name, "errors", entity.Errors)
+		source, ok := state.EntitySource(entity.Type, entity.Name)
+		if !ok {
+			log.Info("kong rejected an entity of the configuration")
+			continue
+		}
+		log.Info("kong rejected an entity of the configuration", "kind", source.Kind,
+			"namespace", source.Namespace, "object", source.Name)
+
+		message := fmt.Sprintf("kong rejected the %s generated from this object: %s", entity.Path, entity.Errors)
+		if r.Recorder == nil {
+			continue
+		}
+		if obj, ok := source.Object.(runtime.Object); ok {
+			r.Recorder.Event(obj, corev1.EventTypeWarning, KongConfigurationRejectedReason, message)
+			continue
+		}
+		// routes only record the namespace and name of the object they come from
+		for key, value := range r.configObjects(configSecret) {
+			if isRouteSource(key, source) {
+				r.recordEvent(key, value, corev1.EventTypeWarning, KongConfigurationRejectedReason, message)
+			}
+		}
+	}
+}
+
+// recordUnresolvedBackends logs the backend Services which could not be resolved, and records a Warning
+// event on the objects referencing them.
+func (r *SecretReconciler) recordUnresolvedBackends(configSecret *corev1.Secret, backends []parser.UnresolvedBackend) {
+	if len(backends) == 0 {
+		return
+	}
+	objects := r.configObjects(configSecret)
+	for _, backend := range backends {
+		r.Log.Info("skipping rules referencing a backend service which could not be resolved",
+			"namespace", backend.Source.Namespace, "object", backend.Source.Name,
+			"service", backend.ServiceNamespace+"/"+backend.ServiceName, "port", backend.ServicePort,
+			"error", backend.Err.Error())
+
+		message := fmt.Sprintf("no configuration was generated for the rules using backend service %s port %s: %v",
+			backend.ServiceName, backend.ServicePort, backend.Err)
+		source := kongstate.EntitySource{K8sObjectInfo: backend.Source}
+		for key, value := range objects {
+			if isRouteSource(key, source) {
+				r.recordEvent(key, value, corev1.EventTypeWarning, KongBackendServiceUnresolvedReason, message)
+			}
+		}
+	}
+}
+
+// isRouteSource tells whether the object stored in the configuration secret under key is source, the
+// object a route was generated from.
+func isRouteSource(key string, source kongstate.EntitySource) bool {
+	elems := strings.SplitN(key, configsecret.KeyDelimiter, 5)
+	if len(elems) != 5 {
+		return false
+	}
+	kind, namespace, name := elems[2], elems[3], elems[4]
+	switch kind {
+	case "Ingress", "TCPIngress", "UDPIngress", "HTTPRoute":
+		return namespace == source.Namespace && name == source.Name
+	}
+	return false
+}
+
+// recordSyncSuccess records a Normal event on every object whose configuration
+// got synced to Kong for the first time.
+func (r *SecretReconciler) recordSyncSuccess(configSecret *corev1.Secret) {
+	r.syncedLock.Lock()
+	defer r.syncedLock.Unlock()
+
+	objects := r.configObjects(configSecret)
+	synced := make(map[string][]byte, len(objects))
+	for key, value := range objects {
+		if _, ok := r.synced[key]; !ok {
+			r.recordEvent(key, value, corev1.EventTypeNormal, KongConfigurationSyncedReason,
+				"successfully synced the configuration to Kong")
+		}
+		synced[key] = value
+	}
+	r.synced = synced
+}
+
+// configObjects returns the objects stored in the configuration secret, or none if they can't be read.
+func (r *SecretReconciler) configObjects(configSecret *corev1.Secret) map[string][]byte {
+	objects, err := configsecret.Objects(configSecret.Data)
+	if err != nil {
+		r.Log.Error(err, "could not read objects from configuration secret, skipping events")
+		return nil
+	}
+	return objects
+}
+
+// recordEvent records an event on the object stored in the configuration secret under key.
+func (r *SecretReconciler) recordEvent(key string, value []byte, eventType, reason, message string) {
+	if r.Recorder == nil {
+		return
+	}
+	obj, err := configsecret.DecodeObject(key, value)
+	if err != nil {
+		r.Log.Error(err, "could not decode object from configuration secret, skipping event", "key", key)
+		return
+	}
+	r.Recorder.Event(obj, eventType, reason, message)
+}
+
+// updateProgrammedConditions sets the Programmed condition of the objects stored in the configuration secret
+// which report it, after an attempt to sync the configuration built into state failed with syncErr, or
+// succeeded if it is nil. On a failure, the objects Kong rejected an entity of, and the objects whose
+// configuration changed since the last successful sync, are reported as not programmed.
+func (r *SecretReconciler) updateProgrammedConditions(ctx context.Context, configSecret *corev1.Secret,
+	state *kongstate.KongState, syncErr error) {
+	objects := r.configObjects(configSecret)
+	if syncErr == nil {
+		for key := range objects {
+			r.setProgrammed(ctx, key, metav1.ConditionTrue, konghqcomv1.ProgrammedReasonSynced,
+				"the configuration was synced to Kong")
+		}
+		return
+	}
+
+	// the rejected objects are keyed by kind, namespace and name
+	rejected := make(map[string]string)
+	for _, entity := range sendconfig.RejectedEntities(syncErr) {
+		if state == nil {
+			break
+		}
+		if source, ok := state.EntitySource(entity.Type, entity.Name); ok {
+			rejected[source.Kind+"/"+source.Namespace+"/"+source.Name] =
+				fmt.Sprintf("kong rejected the %s generated from this object: %s", entity.Path, entity.Errors)
+		}
+	}
+
+	r.syncedLock.Lock()
+	defer r.syncedLock.Unlock()
+	for key, value := range objects {
+		elems := strings.SplitN(key, configsecret.KeyDelimiter, 5)
+		if len(elems) != 5 {
+			continue
+		}
+		if message, ok := rejected[elems[2]+"/"+elems[3]+"/"+elems[4]]; ok {
+			r.setProgrammed(ctx, key, metav1.ConditionFalse, konghqcomv1.ProgrammedReasonRejected, message)
+			continue
+		}
+		if synced, ok := r.synced[key]; ok && bytes.Equal(synced, value) {
+			continue
+		}
+		r.setProgrammed(ctx, key, metav1.ConditionFalse, konghqcomv1.ProgrammedReasonSyncFailed,
+			fmt.Sprintf("failed to sync the configuration to Kong: %v", syncErr))
+	}
+}
+
+// programmedObject returns an empty object of kind along with its conditions, or nil if the status of kind
+// doesn't report the Programmed condition.
+func programmedObject(kind string) (client.Object, *[]metav1.Condition) {
+	switch kind {
+	case "KongPlugin":
+		obj := new(konghqcomv1.KongPlugin)
+		return obj, &obj.Status.Conditions
+	case "KongClusterPlugin":
+		obj := new(konghqcomv1.KongClusterPlugin)
+		return obj, &obj.Status.Conditions
+	case "KongConsumer":
+		obj := new(konghqcomv1.KongConsumer)
+		return obj, &obj.Status.Conditions
+	}
+	return nil, nil
+}
+
+// setProgrammed sets the Programmed condition of the object stored in the configuration secret under key, if
+// its kind reports it. Failures are logged only, as the status of the object doesn't affect the configuration.
+func (r *SecretReconciler) setProgrammed(ctx context.Context, key string, status metav1.ConditionStatus,
+	reason, message string) {
+	elems := strings.SplitN(key, configsecret.KeyDelimiter, 5)
+	if len(elems) != 5 {
+		return
+	}
+	obj, conditions := programmedObject(elems[2])
+	if obj == nil {
+		return
+	}
+	if err := r.Get(ctx, types.NamespacedName{Namespace: elems[3], Name: elems[4]}, obj); err != nil {
+		if !apierrors.IsNotFound(err) {
+			r.Log.Error(err, "could not fetch object to update its status", "key", key)
+		}
+		return
+	}
+
+	condition := metav1.Condition{
+		Type:               konghqcomv1.ProgrammedConditionType,
+		Status:             status,
+		ObservedGeneration: obj.GetGeneration(),
+		Reason:             reason,
+		Message:            message,
+	}
+	if current := meta.FindStatusCondition(*conditions, condition.Type); current != nil &&
+		current.Status == condition.Status && current.ObservedGeneration == condition.ObservedGeneration &&
+		current.Reason == condition.Reason && current.Message == condition.Message {
+		return
+	}
+	meta.SetStatusCondition(conditions, condition)
+	if err := r.Status().Update(ctx, obj); err != nil {
+		r.Log.Error(err, "could not update the status of object", "key", key)
+	}
+}
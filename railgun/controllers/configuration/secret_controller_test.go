@@ -0,0 +1,450 @@
+package configuration
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/kong/go-kong/kong"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	netv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/yaml"
+
+	"github.com/kong/kubernetes-ingress-controller/pkg/deckgen"
+	"github.com/kong/kubernetes-ingress-controller/pkg/kongstate"
+	"github.com/kong/kubernetes-ingress-controller/pkg/parser"
+	"github.com/kong/kubernetes-ingress-controller/pkg/sendconfig"
+	"github.com/kong/kubernetes-ingress-controller/pkg/util"
+	konghqcomv1 "github.com/kong/kubernetes-ingress-controller/railgun/apis/configuration/v1"
+	"github.com/kong/kubernetes-ingress-controller/railgun/apis/configuration/v1alpha1"
+	"github.com/kong/kubernetes-ingress-controller/railgun/pkg/configsecret"
+)
+
+func configSecretWith(t *testing.T, ingresses ...*netv1.Ingress) *corev1.Secret {
+	secret := &corev1.Secret{Data: map[string][]byte{}}
+	for _, ingress := range ingresses {
+		ingress.SetGroupVersionKind(netv1.SchemeGroupVersion.WithKind("Ingress"))
+		cfg, err := yaml.Marshal(ingress)
+		assert.NoError(t, err)
+		nsn := types.NamespacedName{Namespace: ingress.Namespace, Name: ingress.Name}
+		secret.Data[configsecret.KeyFor(ingress, nsn)] = cfg
+	}
+	return secret
+}
+
+func drainEvents(recorder *record.FakeRecorder) []string {
+	var events []string
+	for {
+		select {
+		case event := <-recorder.Events:
+			events = append(events, event)
+		default:
+			return events
+		}
+	}
+}
+
+func TestSecretReconcilerEvents(t *testing.T) {
+	recorder := record.NewFakeRecorder(10)
+	r := &SecretReconciler{Log: logr.Discard(), Recorder: recorder}
+
+	foo := &netv1.Ingress{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "foo"}}
+	bar := &netv1.Ingress{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "bar"}}
+
+	r.recordSyncSuccess(configSecretWith(t, foo))
+	assert.Equal(t, []string{"Normal KongConfigurationSynced successfully synced the configuration to Kong"},
+		drainEvents(recorder))
+
+	// syncing the same objects again is not reported
+	r.recordSyncSuccess(configSecretWith(t, foo))
+	assert.Empty(t, drainEvents(recorder))
+
+	// only the object which changed is blamed for a failure
+	r.recordSyncFailure(configSecretWith(t, foo, bar), errors.New("boom"))
+	assert.Equal(t, []string{"Warning KongConfigurationSyncFailed failed to sync the configuration to Kong: boom"},
+		drainEvents(recorder))
+}
+
+func TestSecretReconcilerProgrammedConditions(t *testing.T) {
+	ctx := context.Background()
+	scheme := runtime.NewScheme()
+	assert.NoError(t, clientgoscheme.AddToScheme(scheme))
+	assert.NoError(t, konghqcomv1.AddToScheme(scheme))
+
+	consumer := func(name, username string) *konghqcomv1.KongConsumer {
+		return &konghqcomv1.KongConsumer{
+			TypeMeta:   metav1.TypeMeta{APIVersion: konghqcomv1.GroupVersion.String(), Kind: "KongConsumer"},
+			ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: name, Generation: 1},
+			Username:   username,
+		}
+	}
+	configSecretOf := func(consumers ...*konghqcomv1.KongConsumer) *corev1.Secret {
+		secret := &corev1.Secret{Data: map[string][]byte{}}
+		for _, c := range consumers {
+			cfg, err := yaml.Marshal(c)
+			assert.NoError(t, err)
+			secret.Data[configsecret.KeyFor(c, types.NamespacedName{Namespace: c.Namespace, Name: c.Name})] = cfg
+		}
+		return secret
+	}
+	foo, bar := consumer("foo", "foo"), consumer("bar", "bar")
+	r := &SecretReconciler{
+		Client: fake.NewClientBuilder().WithScheme(scheme).WithObjects(foo.DeepCopy(), bar.DeepCopy()).Build(),
+		Log:    logr.Discard(),
+	}
+	programmed := func(name string) *metav1.Condition {
+		obj := new(konghqcomv1.KongConsumer)
+		assert.NoError(t, r.Get(ctx, types.NamespacedName{Namespace: "default", Name: name}, obj))
+		return meta.FindStatusCondition(obj.Status.Conditions, konghqcomv1.ProgrammedConditionType)
+	}
+
+	synced := configSecretOf(foo, bar)
+	r.updateProgrammedConditions(ctx, synced, nil, nil)
+	r.recordSyncSuccess(synced)
+	for _, name := range []string{"foo", "bar"} {
+		condition := programmed(name)
+		if assert.NotNil(t, condition) {
+			assert.Equal(t, metav1.ConditionTrue, condition.Status)
+			assert.Equal(t, konghqcomv1.ProgrammedReasonSynced, condition.Reason)
+			assert.Equal(t, int64(1), condition.ObservedGeneration)
+		}
+	}
+
+	// only the consumer which changed since the last sync is reported as not programmed
+	r.updateProgrammedConditions(ctx, configSecretOf(foo, consumer("bar", "baz")), nil, errors.New("boom"))
+	condition := programmed("bar")
+	if assert.NotNil(t, condition) {
+		assert.Equal(t, metav1.ConditionFalse, condition.Status)
+		assert.Equal(t, konghqcomv1.ProgrammedReasonSyncFailed, condition.Reason)
+		assert.Equal(t, "failed to sync the configuration to Kong: boom", condition.Message)
+	}
+	condition = programmed("foo")
+	if assert.NotNil(t, condition) {
+		assert.Equal(t, metav1.ConditionTrue, condition.Status)
+	}
+}
+
+func TestSecretReconcilerRejectedEntities(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte(`{"name":"invalid declarative configuration","message":"declarative config is invalid",` +
+			`"fields":{"services":[null,{"routes":[{"hosts":["invalid hostname: -bar"]}]}]}}`))
+	}))
+	defer server.Close()
+	client, err := kong.NewClient(kong.String(server.URL), sendconfig.KeepErrorBodies(server.Client()))
+	assert.NoError(t, err)
+
+	state := &kongstate.KongState{Services: []kongstate.Service{
+		{
+			Service: kong.Service{Name: kong.String("default.foo.80")},
+			Routes: []kongstate.Route{{
+				Route:   kong.Route{Name: kong.String("default.foo.00")},
+				Ingress: util.K8sObjectInfo{Namespace: "default", Name: "foo"},
+			}},
+		},
+		{
+			Service: kong.Service{Name: kong.String("default.bar.80")},
+			Routes: []kongstate.Route{{
+				Route:   kong.Route{Name: kong.String("default.bar.00"), Hosts: kong.StringSlice("-bar")},
+				Ingress: util.K8sObjectInfo{Namespace: "default", Name: "bar"},
+			}},
+		},
+	}}
+	_, err = sendconfig.PerformUpdate(context.Background(), logrus.New(), &sendconfig.Kong{URL: server.URL, Client: client},
+		true, false, deckgen.ToDeckContent(context.Background(), logrus.New(), state, nil, nil), nil, nil, nil)
+	assert.Error(t, err)
+
+	recorder := record.NewFakeRecorder(10)
+	r := &SecretReconciler{Log: logr.Discard(), Recorder: recorder}
+	foo := &netv1.Ingress{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "foo"}}
+	bar := &netv1.Ingress{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "bar"}}
+	r.recordRejectedEntities(configSecretWith(t, foo, bar), state, sendconfig.RejectedEntities(err))
+
+	// only the Ingress the invalid route comes from is blamed
+	assert.Equal(t, []string{"Warning KongConfigurationRejected kong rejected the services[1].routes[0] " +
+		`generated from this object: {"hosts":["invalid hostname: -bar"]}`}, drainEvents(recorder))
+}
+
+func TestSecretReconcilerUnresolvedBackends(t *testing.T) {
+	recorder := record.NewFakeRecorder(10)
+	r := &SecretReconciler{Log: logr.Discard(), Recorder: recorder}
+	foo := &netv1.Ingress{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "foo"}}
+	bar := &netv1.Ingress{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "bar"}}
+	r.recordUnresolvedBackends(configSecretWith(t, foo, bar), []parser.UnresolvedBackend{{
+		Source:           util.K8sObjectInfo{Namespace: "default", Name: "bar"},
+		ServiceNamespace: "default",
+		ServiceName:      "bar-svc",
+		ServicePort:      "80",
+		Err:              errors.New("Service default/bar-svc not found"),
+	}})
+
+	// only the Ingress referencing the missing Service is warned
+	assert.Equal(t, []string{"Warning KongBackendServiceUnresolved no configuration was generated for the rules " +
+		"using backend service bar-svc port 80: Service default/bar-svc not found"}, drainEvents(recorder))
+}
+
+func TestSecretReconcilerSerializesSyncs(t *testing.T) {
+	var lock sync.Mutex
+	var inFlight, maxInFlight, pushes int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lock.Lock()
+		inFlight++
+		pushes++
+		if inFlight > maxInFlight {
+			maxInFlight = inFlight
+		}
+		lock.Unlock()
+		time.Sleep(20 * time.Millisecond)
+		lock.Lock()
+		inFlight--
+		lock.Unlock()
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer server.Close()
+	kongClient, err := kong.NewClient(kong.String(server.URL), server.Client())
+	assert.NoError(t, err)
+
+	scheme := runtime.NewScheme()
+	assert.NoError(t, clientgoscheme.AddToScheme(scheme))
+	assert.NoError(t, konghqcomv1.AddToScheme(scheme))
+	assert.NoError(t, v1alpha1.AddToScheme(scheme))
+	configSecret := configSecretWith(t)
+	configSecret.ObjectMeta = metav1.ObjectMeta{Namespace: "kong-system", Name: "kong-config"}
+	r := &SecretReconciler{
+		Client:   fake.NewClientBuilder().WithScheme(scheme).WithObjects(configSecret).Build(),
+		Log:      logr.Discard(),
+		Recorder: record.NewFakeRecorder(100),
+		Params: SecretReconcilerParams{
+			KongConfig: sendconfig.Kong{URL: server.URL, Client: kongClient, InMemory: true},
+		},
+		// many workers may process the secret, but pushes must not overlap
+		MaxConcurrentReconciles: 8,
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < r.MaxConcurrentReconciles; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := r.Reconcile(context.Background(), ctrl.Request{NamespacedName: types.NamespacedName{
+				Namespace: configSecret.Namespace,
+				Name:      configSecret.Name,
+			}})
+			assert.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, r.MaxConcurrentReconciles, pushes)
+	assert.Equal(t, 1, maxInFlight)
+}
+
+func TestSecretReconcilerManualResync(t *testing.T) {
+	var lock sync.Mutex
+	var pushes int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lock.Lock()
+		pushes++
+		lock.Unlock()
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer server.Close()
+	kongClient, err := kong.NewClient(kong.String(server.URL), server.Client())
+	assert.NoError(t, err)
+
+	scheme := runtime.NewScheme()
+	assert.NoError(t, clientgoscheme.AddToScheme(scheme))
+	assert.NoError(t, konghqcomv1.AddToScheme(scheme))
+	assert.NoError(t, v1alpha1.AddToScheme(scheme))
+	configSecret := configSecretWith(t)
+	configSecret.ObjectMeta = metav1.ObjectMeta{Namespace: "kong-system", Name: "kong-config"}
+	r := &SecretReconciler{
+		Client: fake.NewClientBuilder().WithScheme(scheme).WithObjects(configSecret).Build(),
+		Log:    logr.Discard(),
+		Params: SecretReconcilerParams{
+			WatchName:      configSecret.Name,
+			WatchNamespace: configSecret.Namespace,
+			KongConfig:     sendconfig.Kong{URL: server.URL, Client: kongClient, InMemory: true},
+			Resync:         make(chan event.GenericEvent),
+		},
+	}
+
+	// a manual resync reconciles the configuration secret, pushing the configuration although nothing changed
+	requests := r.resyncRequest(&corev1.Secret{ObjectMeta: configSecret.ObjectMeta})
+	assert.Equal(t, []reconcile.Request{{NamespacedName: types.NamespacedName{
+		Namespace: configSecret.Namespace,
+		Name:      configSecret.Name,
+	}}}, requests)
+	for _, req := range requests {
+		_, err := r.Reconcile(context.Background(), req)
+		assert.NoError(t, err)
+	}
+	assert.Equal(t, 1, pushes)
+	assert.False(t, r.takeResyncRequest(), "the resync request must be cleared once pushed")
+}
+
+func TestSecretReconcilerBatchesPushes(t *testing.T) {
+	var lock sync.Mutex
+	var pushes int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lock.Lock()
+		pushes++
+		lock.Unlock()
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer server.Close()
+	kongClient, err := kong.NewClient(kong.String(server.URL), server.Client())
+	assert.NoError(t, err)
+
+	scheme := runtime.NewScheme()
+	assert.NoError(t, clientgoscheme.AddToScheme(scheme))
+	assert.NoError(t, konghqcomv1.AddToScheme(scheme))
+	assert.NoError(t, v1alpha1.AddToScheme(scheme))
+	configSecret := configSecretWith(t)
+	configSecret.ObjectMeta = metav1.ObjectMeta{Namespace: "kong-system", Name: "kong-config"}
+	r := &SecretReconciler{
+		Client:   fake.NewClientBuilder().WithScheme(scheme).WithObjects(configSecret).Build(),
+		Log:      logr.Discard(),
+		Recorder: record.NewFakeRecorder(100),
+		Params: SecretReconcilerParams{
+			KongConfig: sendconfig.Kong{URL: server.URL, Client: kongClient, InMemory: true},
+			Debouncer:  sendconfig.NewDebouncer(time.Minute),
+		},
+	}
+	req := ctrl.Request{NamespacedName: types.NamespacedName{
+		Namespace: configSecret.Namespace,
+		Name:      configSecret.Name,
+	}}
+
+	// every change to the configuration triggers a reconcile of the secret
+	var requeued int
+	for i := 0; i < 50; i++ {
+		result, err := r.Reconcile(context.Background(), req)
+		assert.NoError(t, err)
+		if result.RequeueAfter > 0 {
+			assert.LessOrEqual(t, int64(result.RequeueAfter), int64(time.Minute))
+			requeued++
+		}
+	}
+
+	// the first change is pushed right away, the others wait for the trailing push
+	assert.Equal(t, 1, pushes)
+	assert.Equal(t, 49, requeued)
+}
+
+func TestSecretReconcilerJittersRetries(t *testing.T) {
+	var lock sync.Mutex
+	kongDown := true
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lock.Lock()
+		defer lock.Unlock()
+		if kongDown {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer server.Close()
+	kongClient, err := kong.NewClient(kong.String(server.URL), server.Client())
+	assert.NoError(t, err)
+
+	scheme := runtime.NewScheme()
+	assert.NoError(t, clientgoscheme.AddToScheme(scheme))
+	assert.NoError(t, konghqcomv1.AddToScheme(scheme))
+	assert.NoError(t, v1alpha1.AddToScheme(scheme))
+	configSecret := configSecretWith(t)
+	configSecret.ObjectMeta = metav1.ObjectMeta{Namespace: "kong-system", Name: "kong-config"}
+	r := &SecretReconciler{
+		Client:   fake.NewClientBuilder().WithScheme(scheme).WithObjects(configSecret).Build(),
+		Log:      logr.Discard(),
+		Recorder: record.NewFakeRecorder(100),
+		Params: SecretReconcilerParams{
+			KongConfig:        sendconfig.Kong{URL: server.URL, Client: kongClient, InMemory: true},
+			SyncFailureJitter: sendconfig.NewRetryJitter(time.Second, 10*time.Second),
+		},
+	}
+	req := ctrl.Request{NamespacedName: types.NamespacedName{
+		Namespace: configSecret.Namespace,
+		Name:      configSecret.Name,
+	}}
+
+	// pushes failing together are retried at different times
+	delays := make(map[time.Duration]bool)
+	for i := 0; i < 10; i++ {
+		result, err := r.Reconcile(context.Background(), req)
+		assert.NoError(t, err)
+		assert.GreaterOrEqual(t, int64(result.RequeueAfter), int64(time.Second))
+		assert.LessOrEqual(t, int64(result.RequeueAfter), int64(11*time.Second))
+		delays[result.RequeueAfter] = true
+	}
+	assert.Greater(t, len(delays), 1)
+
+	// a successful push isn't requeued
+	lock.Lock()
+	kongDown = false
+	lock.Unlock()
+	result, err := r.Reconcile(context.Background(), req)
+	assert.NoError(t, err)
+	assert.Equal(t, ctrl.Result{}, result)
+
+	// without a delay, the failure is returned for the controller's backoff
+	lock.Lock()
+	kongDown = true
+	lock.Unlock()
+	r.Params.SyncFailureJitter = nil
+	_, err = r.Reconcile(context.Background(), req)
+	assert.Error(t, err)
+}
+
+func TestSecretReconcilerHonorsRetryAfter(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "120")
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer server.Close()
+	httpClient := sendconfig.RetryRequests(server.Client(), sendconfig.RetryOpts{MaxRetries: 2, MaxDelay: time.Second})
+	kongClient, err := kong.NewClient(kong.String(server.URL), httpClient)
+	assert.NoError(t, err)
+
+	scheme := runtime.NewScheme()
+	assert.NoError(t, clientgoscheme.AddToScheme(scheme))
+	assert.NoError(t, konghqcomv1.AddToScheme(scheme))
+	assert.NoError(t, v1alpha1.AddToScheme(scheme))
+	configSecret := configSecretWith(t)
+	configSecret.ObjectMeta = metav1.ObjectMeta{Namespace: "kong-system", Name: "kong-config"}
+	r := &SecretReconciler{
+		Client:   fake.NewClientBuilder().WithScheme(scheme).WithObjects(configSecret).Build(),
+		Log:      logr.Discard(),
+		Recorder: record.NewFakeRecorder(100),
+		Params: SecretReconcilerParams{
+			KongConfig:        sendconfig.Kong{URL: server.URL, Client: kongClient, InMemory: true},
+			SyncFailureJitter: sendconfig.NewRetryJitter(time.Second, 10*time.Second),
+		},
+	}
+	req := ctrl.Request{NamespacedName: types.NamespacedName{
+		Namespace: configSecret.Namespace,
+		Name:      configSecret.Name,
+	}}
+
+	// the push is retried no sooner than kong asked, rather than after the jittered delay
+	result, err := r.Reconcile(context.Background(), req)
+	assert.NoError(t, err)
+	assert.Equal(t, 2*time.Minute, result.RequeueAfter)
+}
@@ -3,19 +3,17 @@ package configuration
 import (
 	"bytes"
 	"context"
-	"fmt"
-	"os"
 
 	"github.com/go-logr/logr"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/yaml"
 
-	"github.com/kong/kubernetes-ingress-controller/railgun/controllers"
 	"github.com/kong/kubernetes-ingress-controller/railgun/pkg/configsecret"
 )
 
@@ -24,41 +22,48 @@ import (
 // -----------------------------------------------------------------------------
 
 // storeIngressObj reconciles storing the YAML contents of Ingress resources (which are managed by Kong)
-// from multiple versions which remain supported.
-func storeIngressObj(ctx context.Context, c client.Client, log logr.Logger, nsn types.NamespacedName, obj client.Object) (ctrl.Result, error) {
+// from multiple versions which remain supported, in the configuration secret configSecret. If fieldManager
+// is set, the configuration secret is ensured with server-side apply under this field manager rather than
+// retrieved or created. If owner is set, the configuration secret is made a dependent of owner. If compress is
+// set, the contents stored in the configuration secret are compressed.
+func storeIngressObj(ctx context.Context, c client.Client, log logr.Logger, configSecret types.NamespacedName, fieldManager string,
+	owner *metav1.OwnerReference, compress bool, nsn types.NamespacedName, obj client.Object) (ctrl.Result, error) {
 	// TODO need EVENTS here
 	// TODO need more status updates
 	// TODO: (shane) I want to refactor this into several smaller functions
 	// TODO: collapse nsn + obj, this is redudant as obj includes nsn
-	// TODO: pass in secret namespace as part of function sig instead of env
 	// ^ follow up for these items is in: https://github.com/Kong/kubernetes-ingress-controller/issues/1094
 
 	// if this is an Ingress resource make sure it's managed by KIC
 	// BUG: this takes only the kind into account, not the API group.
 	if obj.GetObjectKind().GroupVersionKind().Kind == "Ingress" {
-		if !isManaged(obj.GetAnnotations()) {
+		managed, err := isIngressManaged(ctx, c, obj)
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+		if !managed {
 			return ctrl.Result{}, nil
 		}
 	}
 
-	// get the configuration secret namespace
-	secretNamespace := os.Getenv(controllers.CtrlNamespaceEnv)
-	if secretNamespace == "" {
-		return ctrl.Result{}, fmt.Errorf("kong can not be configured because the required %s env var is not present", controllers.CtrlNamespaceEnv)
-	}
-
 	// get the configuration secret
-	secret, created, err := getOrCreateConfigSecret(ctx, c, secretNamespace)
-	if err != nil {
-		if errors.IsAlreadyExists(err) {
-			log.Info("kong configuration secret was created elsewhere retrying", "namespace", nsn.Namespace, "ingress", nsn.Name)
+	if fieldManager != "" {
+		if _, err := applyConfigSecret(ctx, c, configSecret, fieldManager, owner); err != nil {
+			return ctrl.Result{}, err
+		}
+	} else {
+		_, created, err := getOrCreateConfigSecret(ctx, c, configSecret, owner)
+		if err != nil {
+			if errors.IsAlreadyExists(err) {
+				log.Info("kong configuration secret was created elsewhere retrying", "namespace", nsn.Namespace, "ingress", nsn.Name)
+				return ctrl.Result{Requeue: true}, nil
+			}
+			return ctrl.Result{}, err
+		}
+		if created {
+			log.Info("kong configuration did not exist, was created successfully", "namespace", nsn.Namespace, "ingress", nsn.Name)
 			return ctrl.Result{Requeue: true}, nil
 		}
-		return ctrl.Result{}, err
-	}
-	if created {
-		log.Info("kong configuration did not exist, was created successfully", "namespace", nsn.Namespace, "ingress", nsn.Name)
-		return ctrl.Result{Requeue: true}, nil
 	}
 
 	// before we store configuration data for this Ingress object, ensure that it has our finalizer set
@@ -73,7 +78,7 @@ func storeIngressObj(ctx context.Context, c client.Client, log logr.Logger, nsn
 	}
 
 	// store the ingress record
-	if err := storeRuntimeObject(ctx, c, secret, obj, nsn); err != nil {
+	if err := storeRuntimeObject(ctx, c, configSecret, compress, obj, nsn); err != nil {
 		if errors.IsConflict(err) {
 			log.Error(err, "object updated while reconcilation was running, retrying", nsn.Namespace, nsn.Name)
 			return ctrl.Result{Requeue: true}, nil
@@ -81,7 +86,7 @@ func storeIngressObj(ctx context.Context, c client.Client, log logr.Logger, nsn
 		return ctrl.Result{}, err
 	}
 
-	log.Info("kong secret configuration successfully patched patched", "namespace", nsn.Namespace, "name", controllers.ConfigSecretName)
+	log.Info("kong secret configuration successfully patched patched", "namespace", configSecret.Namespace, "name", configSecret.Name)
 	return ctrl.Result{}, nil
 }
 
@@ -95,13 +100,18 @@ func isRuntimeObjectSame(secret *corev1.Secret, obj runtime.Object, nsn types.Na
 	}
 
 	// check if there's any existing object
+	objects, err := configsecret.Objects(secret.Data)
+	if err != nil {
+		return false, err
+	}
 	key := configsecret.KeyFor(obj, nsn)
-	foundCFG, ok := secret.Data[key]
+	foundCFG, ok := objects[key]
 	return ok && bytes.Equal(foundCFG, cfg), nil
 }
 
-// storeRuntimeObject stores a runtime.Object in the configuration secret. Callers should re-queue after this completes successfully.
-func storeRuntimeObject(ctx context.Context, c client.Client, secret *corev1.Secret, obj runtime.Object, nsn types.NamespacedName) error {
+// storeRuntimeObject stores a runtime.Object in the configuration secret configSecret, compressed if compress is set.
+// Callers should re-queue after this completes successfully.
+func storeRuntimeObject(ctx context.Context, c client.Client, configSecret types.NamespacedName, compress bool, obj runtime.Object, nsn types.NamespacedName) error {
 	// marshal to YAML for storage
 	cfg, err := yaml.Marshal(obj)
 	if err != nil {
@@ -110,35 +120,33 @@ func storeRuntimeObject(ctx context.Context, c client.Client, secret *corev1.Sec
 
 	// patch the secret with the runtime.Object contents
 	key := configsecret.KeyFor(obj, nsn)
-	secret.Data[key] = cfg
-
-	return c.Update(ctx, secret) // TODO: patch here instead of update for perf
+	_, err = updateConfigSecret(ctx, c, configSecret, func(secret *corev1.Secret) error {
+		return configsecret.SetObject(secret.Data, key, cfg, compress)
+	})
+	return err // TODO: patch here instead of update for perf
 }
 
-// cleanupObj ensures that a deleted ingress resource is no longer present in the kong configuration secret.
-func cleanupObj(ctx context.Context, c client.Client, log logr.Logger, nsn types.NamespacedName, obj client.Object) (ctrl.Result, error) {
+// cleanupObj ensures that a deleted ingress resource is no longer present in the kong configuration secret configSecret.
+func cleanupObj(ctx context.Context, c client.Client, log logr.Logger, configSecret, nsn types.NamespacedName, obj client.Object) (ctrl.Result, error) {
 	// TODO need EVENTS here
 	// TODO need more status updates
 	// TODO: (shane) I want to refactor this into several smaller functions
 	// ^ follow up for these items is in: https://github.com/Kong/kubernetes-ingress-controller/issues/1094
 
-	// get the configuration secret namespace
-	secretNamespace := os.Getenv(controllers.CtrlNamespaceEnv)
-	if secretNamespace == "" {
-		return ctrl.Result{}, fmt.Errorf("kong can not be configured because the required %s env var is not present", controllers.CtrlNamespaceEnv)
-	}
-
 	// grab the configuration secret from the API
 	secret := new(corev1.Secret)
-	if err := c.Get(ctx, types.NamespacedName{Namespace: secretNamespace, Name: controllers.ConfigSecretName}, secret); err != nil {
+	if err := c.Get(ctx, configSecret, secret); err != nil {
 		return ctrl.Result{}, client.IgnoreNotFound(err)
 	}
 
 	key := configsecret.KeyFor(obj, nsn)
 	if _, ok := secret.Data[key]; ok {
-		delete(secret.Data, key)
-		if err := c.Update(ctx, secret); err != nil { // TODO: patch here instead of update
-			return ctrl.Result{}, err
+		secret, err := updateConfigSecret(ctx, c, configSecret, func(secret *corev1.Secret) error {
+			delete(secret.Data, key)
+			return nil
+		})
+		if err != nil { // TODO: patch here instead of update
+			return ctrl.Result{}, client.IgnoreNotFound(err)
 		}
 		log.Info("kong ingress record removed from kong configuration", "ingress", obj.GetName(), "config", secret.GetName())
 		return ctrl.Result{Requeue: true}, nil
@@ -0,0 +1,69 @@
+package configuration
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/kong/kubernetes-ingress-controller/railgun/pkg/configsecret"
+)
+
+func TestStoreRuntimeObjectRoundTrip(t *testing.T) {
+	configSecret := types.NamespacedName{Namespace: "kong", Name: "kong-config"}
+	service := func(name string) *corev1.Service {
+		return &corev1.Service{
+			TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "Service"},
+			ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: name},
+			Spec:       corev1.ServiceSpec{Ports: []corev1.ServicePort{{Name: "http", Port: 80}}},
+		}
+	}
+
+	tests := []struct {
+		name string
+		// compression of the object stored first, then of the second one
+		compressFirst, compressSecond bool
+	}{
+		{name: "uncompressed"},
+		{name: "compressed", compressFirst: true, compressSecond: true},
+		{name: "compression enabled", compressSecond: true},
+		{name: "compression disabled", compressFirst: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			c := fake.NewClientBuilder().WithScheme(clientgoscheme.Scheme).
+				WithObjects(&corev1.Secret{ObjectMeta: metav1.ObjectMeta{Namespace: configSecret.Namespace, Name: configSecret.Name}}).Build()
+			first, second := service("first"), service("second")
+
+			require.NoError(t, storeRuntimeObject(ctx, c, configSecret, tt.compressFirst, first, types.NamespacedName{Namespace: "default", Name: "first"}))
+			require.NoError(t, storeRuntimeObject(ctx, c, configSecret, tt.compressSecond, second, types.NamespacedName{Namespace: "default", Name: "second"}))
+
+			secret := new(corev1.Secret)
+			require.NoError(t, c.Get(ctx, configSecret, secret))
+			_, compressed := secret.Data[configsecret.CompressionKey]
+			assert.Equal(t, tt.compressSecond, compressed)
+
+			objects, err := configsecret.Objects(secret.Data)
+			require.NoError(t, err)
+			require.Len(t, objects, 2)
+			for _, want := range []*corev1.Service{first, second} {
+				nsn := types.NamespacedName{Namespace: want.Namespace, Name: want.Name}
+				same, err := isRuntimeObjectSame(secret, want, nsn)
+				require.NoError(t, err)
+				assert.True(t, same)
+
+				key := configsecret.KeyFor(want, nsn)
+				obj, err := configsecret.DecodeObject(key, objects[key])
+				require.NoError(t, err)
+				assert.Equal(t, want.Spec, obj.(*corev1.Service).Spec)
+			}
+		})
+	}
+}
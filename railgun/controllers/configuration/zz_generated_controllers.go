@@ -20,33 +20,34 @@ package configuration
 
 import (
 	"context"
-	"time"
 
-	"github.com/go-logr/logr"
 	kongv1 "github.com/kong/kubernetes-ingress-controller/railgun/apis/configuration/v1"
 	kongv1alpha1 "github.com/kong/kubernetes-ingress-controller/railgun/apis/configuration/v1alpha1"
 	extv1beta1 "k8s.io/api/extensions/v1beta1"
 	netv1 "k8s.io/api/networking/v1"
 	netv1beta1 "k8s.io/api/networking/v1beta1"
-	"k8s.io/apimachinery/pkg/runtime"
 	ctrl "sigs.k8s.io/controller-runtime"
-	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 )
 
 // -----------------------------------------------------------------------------
 // NetV1 Ingress
 // -----------------------------------------------------------------------------
 
-// NetV1Ingress reconciles a Ingress object
+// NetV1IngressReconciler reconciles a Ingress object
 type NetV1IngressReconciler struct {
-	client.Client
-	Log    logr.Logger
-	Scheme *runtime.Scheme
+	ConfigSecretReconciler
 }
 
+var _ reconcile.Reconciler = &NetV1IngressReconciler{}
+
 // SetupWithManager sets up the controller with the Manager.
 func (r *NetV1IngressReconciler) SetupWithManager(mgr ctrl.Manager) error {
-	return ctrl.NewControllerManagedBy(mgr).For(&netv1.Ingress{}).Complete(r)
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&netv1.Ingress{}, builder.WithPredicates(r.relevantUpdates())).
+		WithOptions(r.controllerOptions()).
+		Complete(r)
 }
 
 //+kubebuilder:rbac:groups=networking.k8s.io,resources=ingresses,verbs=get;list;watch;create;update;patch;delete
@@ -55,35 +56,26 @@ func (r *NetV1IngressReconciler) SetupWithManager(mgr ctrl.Manager) error {
 
 // Reconcile processes the watched objects
 func (r *NetV1IngressReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
-	log := r.Log.WithValues("NetV1Ingress", req.NamespacedName)
-
-	obj := new(netv1.Ingress)
-	if err := r.Get(ctx, req.NamespacedName, obj); err != nil {
-		return ctrl.Result{}, client.IgnoreNotFound(err)
-	}
-
-	if !obj.DeletionTimestamp.IsZero() && time.Now().After(obj.DeletionTimestamp.Time) {
-		log.Info("resource is being deleted, its configuration will be removed", "type", "Ingress", "namespace", req.Namespace, "name", req.Name)
-		return cleanupObj(ctx, r.Client, log, req.NamespacedName, obj)
-	}
-
-	return storeIngressObj(ctx, r.Client, log, req.NamespacedName, obj)
+	return r.reconcileObj(ctx, req, "NetV1Ingress", new(netv1.Ingress), nil)
 }
 
 // -----------------------------------------------------------------------------
 // NetV1Beta1 Ingress
 // -----------------------------------------------------------------------------
 
-// NetV1Beta1Ingress reconciles a Ingress object
+// NetV1Beta1IngressReconciler reconciles a Ingress object
 type NetV1Beta1IngressReconciler struct {
-	client.Client
-	Log    logr.Logger
-	Scheme *runtime.Scheme
+	ConfigSecretReconciler
 }
 
+var _ reconcile.Reconciler = &NetV1Beta1IngressReconciler{}
+
 // SetupWithManager sets up the controller with the Manager.
 func (r *NetV1Beta1IngressReconciler) SetupWithManager(mgr ctrl.Manager) error {
-	return ctrl.NewControllerManagedBy(mgr).For(&netv1beta1.Ingress{}).Complete(r)
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&netv1beta1.Ingress{}, builder.WithPredicates(r.relevantUpdates())).
+		WithOptions(r.controllerOptions()).
+		Complete(r)
 }
 
 //+kubebuilder:rbac:groups=networking.k8s.io,resources=ingresses,verbs=get;list;watch;create;update;patch;delete
@@ -92,35 +84,26 @@ func (r *NetV1Beta1IngressReconciler) SetupWithManager(mgr ctrl.Manager) error {
 
 // Reconcile processes the watched objects
 func (r *NetV1Beta1IngressReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
-	log := r.Log.WithValues("NetV1Beta1Ingress", req.NamespacedName)
-
-	obj := new(netv1beta1.Ingress)
-	if err := r.Get(ctx, req.NamespacedName, obj); err != nil {
-		return ctrl.Result{}, client.IgnoreNotFound(err)
-	}
-
-	if !obj.DeletionTimestamp.IsZero() && time.Now().After(obj.DeletionTimestamp.Time) {
-		log.Info("resource is being deleted, its configuration will be removed", "type", "Ingress", "namespace", req.Namespace, "name", req.Name)
-		return cleanupObj(ctx, r.Client, log, req.NamespacedName, obj)
-	}
-
-	return storeIngressObj(ctx, r.Client, log, req.NamespacedName, obj)
+	return r.reconcileObj(ctx, req, "NetV1Beta1Ingress", new(netv1beta1.Ingress), nil)
 }
 
 // -----------------------------------------------------------------------------
 // ExtV1Beta1 Ingress
 // -----------------------------------------------------------------------------
 
-// ExtV1Beta1Ingress reconciles a Ingress object
+// ExtV1Beta1IngressReconciler reconciles a Ingress object
 type ExtV1Beta1IngressReconciler struct {
-	client.Client
-	Log    logr.Logger
-	Scheme *runtime.Scheme
+	ConfigSecretReconciler
 }
 
+var _ reconcile.Reconciler = &ExtV1Beta1IngressReconciler{}
+
 // SetupWithManager sets up the controller with the Manager.
 func (r *ExtV1Beta1IngressReconciler) SetupWithManager(mgr ctrl.Manager) error {
-	return ctrl.NewControllerManagedBy(mgr).For(&extv1beta1.Ingress{}).Complete(r)
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&extv1beta1.Ingress{}, builder.WithPredicates(r.relevantUpdates())).
+		WithOptions(r.controllerOptions()).
+		Complete(r)
 }
 
 //+kubebuilder:rbac:groups=apiextensions.k8s.io,resources=ingresses,verbs=get;list;watch;create;update;patch;delete
@@ -129,35 +112,26 @@ func (r *ExtV1Beta1IngressReconciler) SetupWithManager(mgr ctrl.Manager) error {
 
 // Reconcile processes the watched objects
 func (r *ExtV1Beta1IngressReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
-	log := r.Log.WithValues("ExtV1Beta1Ingress", req.NamespacedName)
-
-	obj := new(extv1beta1.Ingress)
-	if err := r.Get(ctx, req.NamespacedName, obj); err != nil {
-		return ctrl.Result{}, client.IgnoreNotFound(err)
-	}
-
-	if !obj.DeletionTimestamp.IsZero() && time.Now().After(obj.DeletionTimestamp.Time) {
-		log.Info("resource is being deleted, its configuration will be removed", "type", "Ingress", "namespace", req.Namespace, "name", req.Name)
-		return cleanupObj(ctx, r.Client, log, req.NamespacedName, obj)
-	}
-
-	return storeIngressObj(ctx, r.Client, log, req.NamespacedName, obj)
+	return r.reconcileObj(ctx, req, "ExtV1Beta1Ingress", new(extv1beta1.Ingress), nil)
 }
 
 // -----------------------------------------------------------------------------
 // KongV1 KongIngress
 // -----------------------------------------------------------------------------
 
-// KongV1KongIngress reconciles a Ingress object
+// KongV1KongIngressReconciler reconciles a KongIngress object
 type KongV1KongIngressReconciler struct {
-	client.Client
-	Log    logr.Logger
-	Scheme *runtime.Scheme
+	ConfigSecretReconciler
 }
 
+var _ reconcile.Reconciler = &KongV1KongIngressReconciler{}
+
 // SetupWithManager sets up the controller with the Manager.
 func (r *KongV1KongIngressReconciler) SetupWithManager(mgr ctrl.Manager) error {
-	return ctrl.NewControllerManagedBy(mgr).For(&kongv1.KongIngress{}).Complete(r)
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&kongv1.KongIngress{}, builder.WithPredicates(r.relevantUpdates())).
+		WithOptions(r.controllerOptions()).
+		Complete(r)
 }
 
 //+kubebuilder:rbac:groups=configuration.konghq.com,resources=kongingresses,verbs=get;list;watch;create;update;patch;delete
@@ -166,35 +140,26 @@ func (r *KongV1KongIngressReconciler) SetupWithManager(mgr ctrl.Manager) error {
 
 // Reconcile processes the watched objects
 func (r *KongV1KongIngressReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
-	log := r.Log.WithValues("KongV1KongIngress", req.NamespacedName)
-
-	obj := new(kongv1.KongIngress)
-	if err := r.Get(ctx, req.NamespacedName, obj); err != nil {
-		return ctrl.Result{}, client.IgnoreNotFound(err)
-	}
-
-	if !obj.DeletionTimestamp.IsZero() && time.Now().After(obj.DeletionTimestamp.Time) {
-		log.Info("resource is being deleted, its configuration will be removed", "type", "KongIngress", "namespace", req.Namespace, "name", req.Name)
-		return cleanupObj(ctx, r.Client, log, req.NamespacedName, obj)
-	}
-
-	return storeIngressObj(ctx, r.Client, log, req.NamespacedName, obj)
+	return r.reconcileObj(ctx, req, "KongV1KongIngress", new(kongv1.KongIngress), nil)
 }
 
 // -----------------------------------------------------------------------------
 // KongV1 KongPlugin
 // -----------------------------------------------------------------------------
 
-// KongV1KongPlugin reconciles a Ingress object
+// KongV1KongPluginReconciler reconciles a KongPlugin object
 type KongV1KongPluginReconciler struct {
-	client.Client
-	Log    logr.Logger
-	Scheme *runtime.Scheme
+	ConfigSecretReconciler
 }
 
+var _ reconcile.Reconciler = &KongV1KongPluginReconciler{}
+
 // SetupWithManager sets up the controller with the Manager.
 func (r *KongV1KongPluginReconciler) SetupWithManager(mgr ctrl.Manager) error {
-	return ctrl.NewControllerManagedBy(mgr).For(&kongv1.KongPlugin{}).Complete(r)
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&kongv1.KongPlugin{}, builder.WithPredicates(r.relevantUpdates())).
+		WithOptions(r.controllerOptions()).
+		Complete(r)
 }
 
 //+kubebuilder:rbac:groups=configuration.konghq.com,resources=kongplugins,verbs=get;list;watch;create;update;patch;delete
@@ -203,35 +168,26 @@ func (r *KongV1KongPluginReconciler) SetupWithManager(mgr ctrl.Manager) error {
 
 // Reconcile processes the watched objects
 func (r *KongV1KongPluginReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
-	log := r.Log.WithValues("KongV1KongPlugin", req.NamespacedName)
-
-	obj := new(kongv1.KongPlugin)
-	if err := r.Get(ctx, req.NamespacedName, obj); err != nil {
-		return ctrl.Result{}, client.IgnoreNotFound(err)
-	}
-
-	if !obj.DeletionTimestamp.IsZero() && time.Now().After(obj.DeletionTimestamp.Time) {
-		log.Info("resource is being deleted, its configuration will be removed", "type", "KongPlugin", "namespace", req.Namespace, "name", req.Name)
-		return cleanupObj(ctx, r.Client, log, req.NamespacedName, obj)
-	}
-
-	return storeIngressObj(ctx, r.Client, log, req.NamespacedName, obj)
+	return r.reconcileObj(ctx, req, "KongV1KongPlugin", new(kongv1.KongPlugin), nil)
 }
 
 // -----------------------------------------------------------------------------
 // KongV1 KongClusterPlugin
 // -----------------------------------------------------------------------------
 
-// KongV1KongClusterPlugin reconciles a Ingress object
+// KongV1KongClusterPluginReconciler reconciles a KongClusterPlugin object
 type KongV1KongClusterPluginReconciler struct {
-	client.Client
-	Log    logr.Logger
-	Scheme *runtime.Scheme
+	ConfigSecretReconciler
 }
 
+var _ reconcile.Reconciler = &KongV1KongClusterPluginReconciler{}
+
 // SetupWithManager sets up the controller with the Manager.
 func (r *KongV1KongClusterPluginReconciler) SetupWithManager(mgr ctrl.Manager) error {
-	return ctrl.NewControllerManagedBy(mgr).For(&kongv1.KongClusterPlugin{}).Complete(r)
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&kongv1.KongClusterPlugin{}, builder.WithPredicates(r.relevantUpdates())).
+		WithOptions(r.controllerOptions()).
+		Complete(r)
 }
 
 //+kubebuilder:rbac:groups=configuration.konghq.com,resources=kongclusterplugins,verbs=get;list;watch;create;update;patch;delete
@@ -240,35 +196,26 @@ func (r *KongV1KongClusterPluginReconciler) SetupWithManager(mgr ctrl.Manager) e
 
 // Reconcile processes the watched objects
 func (r *KongV1KongClusterPluginReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
-	log := r.Log.WithValues("KongV1KongClusterPlugin", req.NamespacedName)
-
-	obj := new(kongv1.KongClusterPlugin)
-	if err := r.Get(ctx, req.NamespacedName, obj); err != nil {
-		return ctrl.Result{}, client.IgnoreNotFound(err)
-	}
-
-	if !obj.DeletionTimestamp.IsZero() && time.Now().After(obj.DeletionTimestamp.Time) {
-		log.Info("resource is being deleted, its configuration will be removed", "type", "KongClusterPlugin", "namespace", req.Namespace, "name", req.Name)
-		return cleanupObj(ctx, r.Client, log, req.NamespacedName, obj)
-	}
-
-	return storeIngressObj(ctx, r.Client, log, req.NamespacedName, obj)
+	return r.reconcileObj(ctx, req, "KongV1KongClusterPlugin", new(kongv1.KongClusterPlugin), nil)
 }
 
 // -----------------------------------------------------------------------------
 // KongV1 KongConsumer
 // -----------------------------------------------------------------------------
 
-// KongV1KongConsumer reconciles a Ingress object
+// KongV1KongConsumerReconciler reconciles a KongConsumer object
 type KongV1KongConsumerReconciler struct {
-	client.Client
-	Log    logr.Logger
-	Scheme *runtime.Scheme
+	ConfigSecretReconciler
 }
 
+var _ reconcile.Reconciler = &KongV1KongConsumerReconciler{}
+
 // SetupWithManager sets up the controller with the Manager.
 func (r *KongV1KongConsumerReconciler) SetupWithManager(mgr ctrl.Manager) error {
-	return ctrl.NewControllerManagedBy(mgr).For(&kongv1.KongConsumer{}).Complete(r)
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&kongv1.KongConsumer{}, builder.WithPredicates(r.relevantUpdates())).
+		WithOptions(r.controllerOptions()).
+		Complete(r)
 }
 
 //+kubebuilder:rbac:groups=configuration.konghq.com,resources=kongconsumers,verbs=get;list;watch;create;update;patch;delete
@@ -277,35 +224,26 @@ func (r *KongV1KongConsumerReconciler) SetupWithManager(mgr ctrl.Manager) error
 
 // Reconcile processes the watched objects
 func (r *KongV1KongConsumerReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
-	log := r.Log.WithValues("KongV1KongConsumer", req.NamespacedName)
-
-	obj := new(kongv1.KongConsumer)
-	if err := r.Get(ctx, req.NamespacedName, obj); err != nil {
-		return ctrl.Result{}, client.IgnoreNotFound(err)
-	}
-
-	if !obj.DeletionTimestamp.IsZero() && time.Now().After(obj.DeletionTimestamp.Time) {
-		log.Info("resource is being deleted, its configuration will be removed", "type", "KongConsumer", "namespace", req.Namespace, "name", req.Name)
-		return cleanupObj(ctx, r.Client, log, req.NamespacedName, obj)
-	}
-
-	return storeIngressObj(ctx, r.Client, log, req.NamespacedName, obj)
+	return r.reconcileObj(ctx, req, "KongV1KongConsumer", new(kongv1.KongConsumer), nil)
 }
 
 // -----------------------------------------------------------------------------
 // KongV1 UDPIngress
 // -----------------------------------------------------------------------------
 
-// KongV1UDPIngress reconciles a Ingress object
+// KongV1UDPIngressReconciler reconciles a UDPIngress object
 type KongV1UDPIngressReconciler struct {
-	client.Client
-	Log    logr.Logger
-	Scheme *runtime.Scheme
+	ConfigSecretReconciler
 }
 
+var _ reconcile.Reconciler = &KongV1UDPIngressReconciler{}
+
 // SetupWithManager sets up the controller with the Manager.
 func (r *KongV1UDPIngressReconciler) SetupWithManager(mgr ctrl.Manager) error {
-	return ctrl.NewControllerManagedBy(mgr).For(&kongv1alpha1.UDPIngress{}).Complete(r)
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&kongv1alpha1.UDPIngress{}, builder.WithPredicates(r.relevantUpdates())).
+		WithOptions(r.controllerOptions()).
+		Complete(r)
 }
 
 //+kubebuilder:rbac:groups=configuration.konghq.com,resources=udpingresses,verbs=get;list;watch;create;update;patch;delete
@@ -314,17 +252,5 @@ func (r *KongV1UDPIngressReconciler) SetupWithManager(mgr ctrl.Manager) error {
 
 // Reconcile processes the watched objects
 func (r *KongV1UDPIngressReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
-	log := r.Log.WithValues("KongV1UDPIngress", req.NamespacedName)
-
-	obj := new(kongv1alpha1.UDPIngress)
-	if err := r.Get(ctx, req.NamespacedName, obj); err != nil {
-		return ctrl.Result{}, client.IgnoreNotFound(err)
-	}
-
-	if !obj.DeletionTimestamp.IsZero() && time.Now().After(obj.DeletionTimestamp.Time) {
-		log.Info("resource is being deleted, its configuration will be removed", "type", "UDPIngress", "namespace", req.Namespace, "name", req.Name)
-		return cleanupObj(ctx, r.Client, log, req.NamespacedName, obj)
-	}
-
-	return storeIngressObj(ctx, r.Client, log, req.NamespacedName, obj)
+	return r.reconcileObj(ctx, req, "KongV1UDPIngress", new(kongv1alpha1.UDPIngress), nil)
 }
@@ -0,0 +1,61 @@
+package controllers
+
+import (
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+// DefaultControllerName is the name the controller identifies itself with unless --controller-name is set.
+const DefaultControllerName = "kong-ingress-controller"
+
+// The filter tag and leader election ID of the controller named DefaultControllerName, which predate
+// --controller-name. They are kept so that upgraded instances still own the Kong entities they created
+// and still elect their leader along with the replicas being replaced.
+const (
+	defaultFilterTag        = "managed-by-railgun"
+	defaultLeaderElectionID = "5b374a9e.konghq.com"
+)
+
+// Identity is how an instance of the controller identifies itself in the objects it owns and writes, in
+// Kubernetes and in Kong. Everything derives from its name, so that instances given different names keep
+// out of each other's way.
+type Identity struct {
+	// Name is the name of the controller, set by --controller-name.
+	Name string
+}
+
+// Validate returns an error if the name of the identity cannot name a controller: it must be a valid DNS-1123
+// label, so that every value derived from it is valid where it is used.
+func (i Identity) Validate() error {
+	if errs := validation.IsDNS1123Label(i.Name); len(errs) > 0 {
+		return fmt.Errorf("invalid controller name %q: %s", i.Name, errs[0])
+	}
+	return nil
+}
+
+// FilterTag returns the tag marking the Kong entities owned by the controller, unless --kong-filter-tag is set.
+func (i Identity) FilterTag() string {
+	if i.Name == DefaultControllerName {
+		return defaultFilterTag
+	}
+	return "managed-by-" + i.Name
+}
+
+// EventSource returns the component name of the Kubernetes events the controller records.
+func (i Identity) EventSource() string {
+	return i.Name
+}
+
+// FieldManager returns the field manager owning the fields the controller sets with server-side apply.
+func (i Identity) FieldManager() string {
+	return i.Name
+}
+
+// LeaderElectionID returns the name of the resource the replicas of the controller elect their leader with.
+func (i Identity) LeaderElectionID() string {
+	if i.Name == DefaultControllerName {
+		return defaultLeaderElectionID
+	}
+	return i.Name + ".konghq.com"
+}
@@ -0,0 +1,28 @@
+package controllers
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIdentity(t *testing.T) {
+	identity := Identity{Name: "kong-canary"}
+	assert.NoError(t, identity.Validate())
+	assert.Equal(t, "managed-by-kong-canary", identity.FilterTag())
+	assert.Equal(t, "kong-canary", identity.EventSource())
+	assert.Equal(t, "kong-canary", identity.FieldManager())
+	assert.Equal(t, "kong-canary.konghq.com", identity.LeaderElectionID())
+
+	// the default identity keeps the values predating --controller-name
+	identity = Identity{Name: DefaultControllerName}
+	assert.NoError(t, identity.Validate())
+	assert.Equal(t, "managed-by-railgun", identity.FilterTag())
+	assert.Equal(t, "kong-ingress-controller", identity.EventSource())
+	assert.Equal(t, "kong-ingress-controller", identity.FieldManager())
+	assert.Equal(t, "5b374a9e.konghq.com", identity.LeaderElectionID())
+
+	for _, name := range []string{"", "Kong", "kong.canary", "-kong"} {
+		assert.Error(t, Identity{Name: name}.Validate(), name)
+	}
+}
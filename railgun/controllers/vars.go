@@ -5,8 +5,8 @@ package controllers
 // -----------------------------------------------------------------------------
 
 var (
-	// CtrlNamespaceEnv provides the name of the environment variable where controllers which
-	// manage the Kong configuration should use to find (or create) the configuration secret.
+	// CtrlNamespaceEnv provides the name of the environment variable holding the namespace of the
+	// controller, where the ConfigMaps referenced as parameters by IngressClasses are looked up.
 	CtrlNamespaceEnv = "KONG_CONFIGURATION_NAMESPACE"
 
 	// ExternalCtrlEnv is an environment variable used to indicate whether the controller is running
@@ -24,8 +24,8 @@ var (
 	// when no other is provided for the deployment or management of resources.
 	DefaultNamespace = "kong-system"
 
-	// ConfigSecretName indicates the name of the Secret object where Ingress controllers will upload
-	// ingress objects for eventual parsing and configuration in the Kong Proxy APIs.
+	// ConfigSecretName indicates the default name of the Secret object where Ingress controllers will
+	// upload ingress objects for eventual parsing and configuration in the Kong Proxy APIs.
 	ConfigSecretName = "kong-config"
 
 	// ProxyInstanceLabel is a label used for controllers (such as the secret configuration
@@ -0,0 +1,172 @@
+package manager
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/rest"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+)
+
+// watchNamespacesCacheBuilder returns a function creating a cache which only
+// watches namespaced objects in the given namespaces, while still watching
+// cluster-scoped objects such as KongClusterPlugins. The objects of the kinds in
+// extraNamespaces are also watched in the namespaces listed for their kind, which
+// doesn't widen the watches of the other kinds.
+func watchNamespacesCacheBuilder(namespaces []string, extraNamespaces map[schema.GroupKind][]string) cache.NewCacheFunc {
+	return func(config *rest.Config, opts cache.Options) (cache.Cache, error) {
+		namespacedCache, err := cache.MultiNamespacedCacheBuilder(namespaces)(config, opts)
+		if err != nil {
+			return nil, err
+		}
+		clusterCache, err := cache.New(config, opts)
+		if err != nil {
+			return nil, err
+		}
+		kindCaches := make(map[schema.GroupKind]cache.Cache, len(extraNamespaces))
+		for kind, extra := range extraNamespaces {
+			kindNamespaces := appendMissing(namespaces, extra...)
+			if len(kindNamespaces) == len(namespaces) {
+				continue
+			}
+			kindCaches[kind], err = cache.MultiNamespacedCacheBuilder(kindNamespaces)(config, opts)
+			if err != nil {
+				return nil, err
+			}
+		}
+		return &watchNamespacesCache{
+			Cache:        namespacedCache,
+			clusterCache: clusterCache,
+			kindCaches:   kindCaches,
+			scheme:       opts.Scheme,
+			mapper:       opts.Mapper,
+		}, nil
+	}
+}
+
+// watchNamespacesCache delegates namespaced objects to a cache watching a
+// set of namespaces, and cluster-scoped objects to one watching the whole
+// cluster, as the former can not serve cluster-scoped objects. The objects of
+// the kinds in kindCaches are delegated to the cache of their kind instead.
+type watchNamespacesCache struct {
+	cache.Cache
+	clusterCache cache.Cache
+	kindCaches   map[schema.GroupKind]cache.Cache
+
+	scheme *runtime.Scheme
+	mapper meta.RESTMapper
+}
+
+var _ cache.Cache = &watchNamespacesCache{}
+
+// cacheForKind returns the cache responsible for objects of the given kind.
+func (c *watchNamespacesCache) cacheForKind(gvk schema.GroupVersionKind) (cache.Cache, error) {
+	mapping, err := c.mapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+	if err != nil {
+		return nil, err
+	}
+	if mapping.Scope.Name() == meta.RESTScopeNameRoot {
+		return c.clusterCache, nil
+	}
+	if kindCache, ok := c.kindCaches[gvk.GroupKind()]; ok {
+		return kindCache, nil
+	}
+	return c.Cache, nil
+}
+
+// caches returns every cache objects are delegated to, starting with the one of namespaced objects.
+func (c *watchNamespacesCache) caches() []cache.Cache {
+	caches := []cache.Cache{c.Cache, c.clusterCache}
+	for _, kindCache := range c.kindCaches {
+		caches = append(caches, kindCache)
+	}
+	return caches
+}
+
+func (c *watchNamespacesCache) cacheFor(obj runtime.Object) (cache.Cache, error) {
+	gvk, err := apiutil.GVKForObject(obj, c.scheme)
+	if err != nil {
+		return nil, err
+	}
+	if _, isList := obj.(client.ObjectList); isList {
+		if !strings.HasSuffix(gvk.Kind, "List") {
+			return nil, fmt.Errorf("non-list type %T (kind %q) passed as output", obj, gvk)
+		}
+		gvk.Kind = strings.TrimSuffix(gvk.Kind, "List")
+	}
+	return c.cacheForKind(gvk)
+}
+
+func (c *watchNamespacesCache) Get(ctx context.Context, key client.ObjectKey, obj client.Object) error {
+	objCache, err := c.cacheFor(obj)
+	if err != nil {
+		return err
+	}
+	return objCache.Get(ctx, key, obj)
+}
+
+func (c *watchNamespacesCache) List(ctx context.Context, list client.ObjectList, opts ...client.ListOption) error {
+	objCache, err := c.cacheFor(list)
+	if err != nil {
+		return err
+	}
+	return objCache.List(ctx, list, opts...)
+}
+
+func (c *watchNamespacesCache) GetInformer(ctx context.Context, obj client.Object) (cache.Informer, error) {
+	objCache, err := c.cacheFor(obj)
+	if err != nil {
+		return nil, err
+	}
+	return objCache.GetInformer(ctx, obj)
+}
+
+func (c *watchNamespacesCache) GetInformerForKind(ctx context.Context, gvk schema.GroupVersionKind) (cache.Informer, error) {
+	objCache, err := c.cacheForKind(gvk)
+	if err != nil {
+		return nil, err
+	}
+	return objCache.GetInformerForKind(ctx, gvk)
+}
+
+func (c *watchNamespacesCache) IndexField(ctx context.Context, obj client.Object, field string, extractValue client.IndexerFunc) error {
+	objCache, err := c.cacheFor(obj)
+	if err != nil {
+		return err
+	}
+	return objCache.IndexField(ctx, obj, field, extractValue)
+}
+
+func (c *watchNamespacesCache) Start(ctx context.Context) error {
+	caches := c.caches()
+	errs := make(chan error, len(caches)-1)
+	for _, other := range caches[1:] {
+		go func(other cache.Cache) {
+			errs <- other.Start(ctx)
+		}(other)
+	}
+	if err := c.Cache.Start(ctx); err != nil {
+		return err
+	}
+	for range caches[1:] {
+		if err := <-errs; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *watchNamespacesCache) WaitForCacheSync(ctx context.Context) bool {
+	for _, each := range c.caches() {
+		if !each.WaitForCacheSync(ctx) {
+			return false
+		}
+	}
+	return true
+}
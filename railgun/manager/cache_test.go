@@ -0,0 +1,91 @@
+package manager
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/rest"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	konghqcomv1 "github.com/kong/kubernetes-ingress-controller/railgun/apis/configuration/v1"
+)
+
+// recordingCache records the calls it serves; its other methods are not implemented.
+type recordingCache struct {
+	cache.Cache
+	name  string
+	calls *[]string
+}
+
+func (c *recordingCache) Get(_ context.Context, _ client.ObjectKey, _ client.Object) error {
+	*c.calls = append(*c.calls, c.name)
+	return nil
+}
+
+func (c *recordingCache) List(_ context.Context, _ client.ObjectList, _ ...client.ListOption) error {
+	*c.calls = append(*c.calls, c.name)
+	return nil
+}
+
+func TestWatchNamespacesCache(t *testing.T) {
+	testScheme := runtime.NewScheme()
+	assert.NoError(t, clientgoscheme.AddToScheme(testScheme))
+	assert.NoError(t, konghqcomv1.AddToScheme(testScheme))
+
+	mapper := meta.NewDefaultRESTMapper(nil)
+	mapper.Add(corev1.SchemeGroupVersion.WithKind("Service"), meta.RESTScopeNamespace)
+	mapper.Add(corev1.SchemeGroupVersion.WithKind("Secret"), meta.RESTScopeNamespace)
+	mapper.Add(konghqcomv1.GroupVersion.WithKind("KongPlugin"), meta.RESTScopeNamespace)
+	mapper.Add(konghqcomv1.GroupVersion.WithKind("KongClusterPlugin"), meta.RESTScopeRoot)
+
+	var calls []string
+	c := &watchNamespacesCache{
+		Cache:        &recordingCache{name: "namespaced", calls: &calls},
+		clusterCache: &recordingCache{name: "cluster", calls: &calls},
+		kindCaches: map[schema.GroupKind]cache.Cache{
+			{Kind: "Secret"}: &recordingCache{name: "secret", calls: &calls},
+		},
+		scheme: testScheme,
+		mapper: mapper,
+	}
+
+	ctx := context.Background()
+	assert.NoError(t, c.Get(ctx, client.ObjectKey{Namespace: "default", Name: "foo"}, &corev1.Service{}))
+	assert.NoError(t, c.List(ctx, &konghqcomv1.KongPluginList{}))
+	assert.NoError(t, c.Get(ctx, client.ObjectKey{Name: "foo"}, &konghqcomv1.KongClusterPlugin{}))
+	assert.NoError(t, c.List(ctx, &konghqcomv1.KongClusterPluginList{}))
+	assert.NoError(t, c.Get(ctx, client.ObjectKey{Namespace: "kong-system", Name: "kong-config"}, &corev1.Secret{}))
+	assert.NoError(t, c.List(ctx, &corev1.SecretList{}))
+	assert.Equal(t, []string{"namespaced", "namespaced", "cluster", "cluster", "secret", "secret"}, calls)
+}
+
+func TestWatchNamespacesCacheBuilder(t *testing.T) {
+	testScheme := runtime.NewScheme()
+	assert.NoError(t, clientgoscheme.AddToScheme(testScheme))
+	newCache := watchNamespacesCacheBuilder([]string{"default"}, map[schema.GroupKind][]string{
+		{Kind: "Secret"}:    {"kong-system"},
+		{Kind: "ConfigMap"}: {"default"},
+	})
+	c, err := newCache(&rest.Config{Host: "http://127.0.0.1:1"}, cache.Options{
+		Scheme: testScheme,
+		Mapper: meta.NewDefaultRESTMapper(nil),
+	})
+	assert.NoError(t, err)
+
+	// only the kinds watched in more namespaces have a cache of their own
+	kindCaches := c.(*watchNamespacesCache).kindCaches
+	assert.Len(t, kindCaches, 1)
+	assert.Contains(t, kindCaches, schema.GroupKind{Kind: "Secret"})
+}
+
+func TestAppendMissing(t *testing.T) {
+	assert.Equal(t, []string{"a", "b", "kong-system"},
+		appendMissing([]string{"a", "b"}, "kong-system", "a", "kong-system"))
+}
@@ -0,0 +1,184 @@
+package manager
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-logr/logr"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/rest"
+	toolscache "k8s.io/client-go/tools/cache"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+)
+
+// cacheSyncProgressInterval is how often the progress of the initial sync of the caches is logged.
+const cacheSyncProgressInterval = 10 * time.Second
+
+// cacheSyncMonitor follows the initial sync of the informers the controllers start, logging which kinds
+// synced and how many objects were listed so far, so that a long sync on a large cluster doesn't look like
+// a hang. It is a manager.Runnable failing the manager with the kinds still syncing if they don't sync
+// within the timeout, which the controllers are given as well.
+type cacheSyncMonitor struct {
+	timeout  time.Duration
+	interval time.Duration
+	log      logr.Logger
+
+	lock      sync.Mutex
+	informers map[string]*informerProgress
+	// started is closed when the first informer is tracked, at startedAt, as the controllers start their
+	// informers only once they are started themselves, e.g. after winning the leader election.
+	started   chan struct{}
+	startedAt time.Time
+}
+
+// informerProgress is the progress of the initial sync of an informer.
+type informerProgress struct {
+	informer cache.Informer
+	objects  int64
+}
+
+func newCacheSyncMonitor(timeout time.Duration, log logr.Logger) *cacheSyncMonitor {
+	return &cacheSyncMonitor{
+		timeout:   timeout,
+		interval:  cacheSyncProgressInterval,
+		log:       log,
+		informers: map[string]*informerProgress{},
+		started:   make(chan struct{}),
+	}
+}
+
+// newCache wraps the caches created by newCache, or the default cache if nil, so that the informers
+// requested from them are tracked by the monitor.
+func (m *cacheSyncMonitor) newCache(newCache cache.NewCacheFunc) cache.NewCacheFunc {
+	if newCache == nil {
+		newCache = cache.New
+	}
+	return func(config *rest.Config, opts cache.Options) (cache.Cache, error) {
+		c, err := newCache(config, opts)
+		if err != nil {
+			return nil, err
+		}
+		return &syncProgressCache{Cache: c, monitor: m, scheme: opts.Scheme}, nil
+	}
+}
+
+// track follows the initial sync of informer, the informer of kind, if it isn't already.
+func (m *cacheSyncMonitor) track(kind schema.GroupKind, informer cache.Informer) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	key := kind.String()
+	if _, ok := m.informers[key]; ok {
+		return
+	}
+	progress := &informerProgress{informer: informer}
+	informer.AddEventHandler(toolscache.ResourceEventHandlerFuncs{
+		AddFunc: func(interface{}) { atomic.AddInt64(&progress.objects, 1) },
+	})
+	m.informers[key] = progress
+	if m.startedAt.IsZero() {
+		m.startedAt = time.Now()
+		close(m.started)
+	}
+}
+
+// progress returns the kinds whose informers synced and those still syncing, along with the number of
+// objects listed so far.
+func (m *cacheSyncMonitor) progress() (synced, syncing []string) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	for kind, progress := range m.informers {
+		status := fmt.Sprintf("%s (%d)", kind, atomic.LoadInt64(&progress.objects))
+		if progress.informer.HasSynced() {
+			synced = append(synced, status)
+		} else {
+			syncing = append(syncing, status)
+		}
+	}
+	sort.Strings(synced)
+	sort.Strings(syncing)
+	return synced, syncing
+}
+
+// Start implements manager.Runnable. It returns once the informers tracked synced, or with an error once
+// the timeout elapsed since the first one was tracked.
+func (m *cacheSyncMonitor) Start(ctx context.Context) error {
+	select {
+	case <-m.started:
+	case <-ctx.Done():
+		return nil
+	}
+	m.lock.Lock()
+	deadline := time.NewTimer(m.timeout - time.Since(m.startedAt))
+	m.lock.Unlock()
+	defer deadline.Stop()
+	ticker := time.NewTicker(m.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-deadline.C:
+			if _, syncing := m.progress(); len(syncing) > 0 {
+				err := fmt.Errorf("caches did not sync within --cache-sync-timeout (%s), still syncing: %s",
+					m.timeout, strings.Join(syncing, ", "))
+				m.log.Error(err, "giving up waiting for caches to sync")
+				return err
+			}
+			return nil
+		case <-ticker.C:
+			synced, syncing := m.progress()
+			if len(syncing) == 0 {
+				m.log.Info("caches synced", "synced", synced)
+				return nil
+			}
+			m.log.Info("waiting for caches to sync", "synced", synced, "syncing", syncing)
+		}
+	}
+}
+
+// NeedLeaderElection implements manager.LeaderElectionRunnable.
+func (m *cacheSyncMonitor) NeedLeaderElection() bool {
+	return false
+}
+
+// syncProgressCache is a cache whose informers are tracked by a cacheSyncMonitor.
+type syncProgressCache struct {
+	cache.Cache
+	monitor *cacheSyncMonitor
+	scheme  *runtime.Scheme
+}
+
+var _ cache.Cache = &syncProgressCache{}
+
+// GetInformer implements cache.Informers.
+func (c *syncProgressCache) GetInformer(ctx context.Context, obj client.Object) (cache.Informer, error) {
+	informer, err := c.Cache.GetInformer(ctx, obj)
+	if err != nil {
+		return nil, err
+	}
+	if gvk, err := apiutil.GVKForObject(obj, c.scheme); err == nil {
+		c.monitor.track(gvk.GroupKind(), informer)
+	}
+	return informer, nil
+}
+
+// GetInformerForKind implements cache.Informers.
+func (c *syncProgressCache) GetInformerForKind(ctx context.Context, gvk schema.GroupVersionKind) (cache.Informer, error) {
+	informer, err := c.Cache.GetInformerForKind(ctx, gvk)
+	if err != nil {
+		return nil, err
+	}
+	c.monitor.track(gvk.GroupKind(), informer)
+	return informer, nil
+}
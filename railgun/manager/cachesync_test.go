@@ -0,0 +1,93 @@
+package manager
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/rest"
+	toolscache "k8s.io/client-go/tools/cache"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/cache/informertest"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllertest"
+)
+
+func TestCacheSyncMonitor(t *testing.T) {
+	tests := []struct {
+		name    string
+		synced  bool
+		wantErr string
+	}{
+		{
+			name:   "synced caches stop the monitor",
+			synced: true,
+		},
+		{
+			name:    "slow caches fail the manager once the timeout elapses",
+			synced:  false,
+			wantErr: "caches did not sync within --cache-sync-timeout (100ms), still syncing: Service (2)",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fakeCache := &informertest.FakeInformers{
+				Scheme: clientgoscheme.Scheme,
+				InformersByGVK: map[schema.GroupVersionKind]toolscache.SharedIndexInformer{
+					corev1.SchemeGroupVersion.WithKind("Service"): &controllertest.FakeInformer{Synced: tt.synced},
+				},
+			}
+			monitor := newCacheSyncMonitor(100*time.Millisecond, logr.Discard())
+			monitor.interval = 10 * time.Millisecond
+			newCache := monitor.newCache(func(*rest.Config, cache.Options) (cache.Cache, error) {
+				return fakeCache, nil
+			})
+			c, err := newCache(nil, cache.Options{Scheme: clientgoscheme.Scheme})
+			require.NoError(t, err)
+
+			errs := make(chan error)
+			go func() { errs <- monitor.Start(context.Background()) }()
+
+			// the timeout starts once the controllers request their informers
+			time.Sleep(200 * time.Millisecond)
+			informer, err := c.GetInformer(context.Background(), &corev1.Service{})
+			require.NoError(t, err)
+			for _, name := range []string{"foo", "bar"} {
+				informer.(*controllertest.FakeInformer).Add(&corev1.Service{ObjectMeta: metav1.ObjectMeta{Name: name}})
+			}
+			start := time.Now()
+
+			select {
+			case err := <-errs:
+				if tt.wantErr == "" {
+					assert.NoError(t, err)
+				} else {
+					assert.EqualError(t, err, tt.wantErr)
+					assert.GreaterOrEqual(t, int64(time.Since(start)), int64(50*time.Millisecond))
+				}
+			case <-time.After(5 * time.Second):
+				t.Fatal("the monitor didn't return")
+			}
+			syncedKinds, syncing := monitor.progress()
+			if tt.synced {
+				assert.Equal(t, []string{"Service (2)"}, syncedKinds)
+				assert.Empty(t, syncing)
+			} else {
+				assert.Empty(t, syncedKinds)
+			}
+		})
+	}
+
+	t.Run("the monitor stops with the manager", func(t *testing.T) {
+		monitor := newCacheSyncMonitor(time.Hour, logr.Discard())
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		assert.NoError(t, monitor.Start(ctx))
+	})
+}
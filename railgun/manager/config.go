@@ -0,0 +1,362 @@
+package manager
+
+import (
+	"flag"
+	"strings"
+	"time"
+
+	"github.com/spf13/pflag"
+	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+
+	"github.com/kong/kubernetes-ingress-controller/pkg/annotations"
+	"github.com/kong/kubernetes-ingress-controller/pkg/sendconfig"
+	"github.com/kong/kubernetes-ingress-controller/pkg/util"
+	"github.com/kong/kubernetes-ingress-controller/railgun/controllers"
+	kongctrl "github.com/kong/kubernetes-ingress-controller/railgun/controllers/configuration"
+	"github.com/kong/kubernetes-ingress-controller/railgun/pkg/adminapi"
+)
+
+// Config collects all configuration that the controller manager takes from the environment.
+type Config struct {
+	// See flag definitions in MakeFlagSetFor(...) for documentation of the fields defined here.
+
+	// Controller identity
+	ControllerName string
+
+	// Kubernetes API configurations
+	KubeconfigPath string
+	KubeconfigFrom string
+
+	// controller-runtime manager configurations
+	MetricsAddr             string
+	EnableLeaderElection    bool
+	LeaderElectionNamespace string
+	LeaseDuration           time.Duration
+	RenewDeadline           time.Duration
+	RetryPeriod             time.Duration
+	ProbeAddr               string
+	WatchNamespaces         []string
+	IngressClassNames       []string
+	ShutdownGracePeriod     time.Duration
+	CacheSyncTimeout        time.Duration
+	UseEndpointSlices       string
+	IngressAPI              string
+	PublishService          string
+
+	ReconcileConcurrency          int
+	ReconcileConcurrencyOverrides map[string]int
+	FeatureGates                  map[string]string
+	DisableControllersWithoutCRDs bool
+	RelevantAnnotationPrefixes    []string
+
+	// Kong Admin API configurations
+	KongURLs           []string
+	FilterTags         []string
+	Concurrency        int
+	KongAdminToken     string
+	KongAdminTokenPath string
+	KongAdminAPIConfig adminapi.HTTPClientOpts
+	KongAdminAPITrace  bool
+	KongAdminRetries   sendconfig.RetryOpts
+	KongAdminInitRetry time.Duration
+	KongWorkspace      string
+	KongDBMode         string
+	DryRun             bool
+	NoDelete           bool
+	ManagedEntityTypes []string
+	SyncPeriod         time.Duration
+	SyncRetryDelay     time.Duration
+	SyncRetryJitter    time.Duration
+
+	// Kong service defaults
+	DefaultServiceConnectTimeout time.Duration
+	DefaultServiceWriteTimeout   time.Duration
+	DefaultServiceReadTimeout    time.Duration
+
+	// Kong route defaults
+	DefaultRouteStripPath    bool
+	DefaultRoutePreserveHost bool
+
+	CredentialTypeKey string
+
+	// Debug endpoint configurations
+	DebugAddr        string
+	DebugBearerToken string
+
+	// Kong configuration secret
+	SecretName             string
+	SecretNamespace        string
+	UseServerSideApply     bool
+	CompressConfigSecret   bool
+	SecretSizeWarningRatio float64
+	ConfigSecretOwner      string
+
+	// Logging configurations
+	LogLevel              string
+	LogSamplingInitial    int
+	LogSamplingThereafter int
+	LogStderrThreshold    string
+	ZapOptions            zap.Options
+
+	// Termination log configurations
+	TerminationLogPath string
+}
+
+// MakeFlagSetFor binds the provided Config to commandline flags.
+func MakeFlagSetFor(c *Config) *pflag.FlagSet {
+	flagSet := pflag.NewFlagSet("", pflag.ExitOnError)
+
+	flagSet.StringVar(&c.ControllerName, "controller-name", controllers.DefaultControllerName,
+		`Name the controller identifies itself with: it tags the Kong entities it owns as managed-by-<name>
+unless --kong-filter-tag is set, records Kubernetes events as <name>, applies the configuration Secret
+under the field manager <name> and elects its leader with the lock <name>.konghq.com. Instances
+managing separate configurations side by side must be given different names. Must be a DNS-1123 label.
+The default name keeps the filter tag managed-by-railgun and the lock 5b374a9e.konghq.com of earlier releases.`)
+
+	flagSet.StringVar(&c.KubeconfigPath, "kubeconfig", "", "Path to the kubeconfig file.")
+	flagSet.StringVar(&c.KubeconfigFrom, "kubeconfig-from", KubeconfigFromAuto,
+		`How to obtain the configuration to connect to Kubernetes: 'incluster' uses the Pod's
+service account, 'file' uses a kubeconfig file and 'auto' uses the service account when
+one is mounted and no --kubeconfig was given, and a kubeconfig file otherwise.`)
+
+	flagSet.StringVar(&c.MetricsAddr, "metrics-bind-address", ":8080", "The address the metric endpoint binds to.")
+	flagSet.StringVar(&c.ProbeAddr, "health-probe-bind-address", ":8081", "The address the probe endpoint binds to.")
+	flagSet.BoolVar(&c.EnableLeaderElection, "leader-elect", false,
+		"Enable leader election for controller manager. "+
+			"Enabling this will ensure there is only one active controller manager.")
+	flagSet.StringVar(&c.LeaderElectionNamespace, "leader-election-namespace", "",
+		`Namespace of the leader election lock, independently of --watch-namespace. It must exist, and the
+controller must be allowed to manage ConfigMaps and Leases there. Defaults to the namespace the controller
+runs in.`)
+	flagSet.DurationVar(&c.LeaseDuration, "leader-elect-lease-duration", 15*time.Second,
+		"How long non-leader candidates wait before trying to acquire the leadership.")
+	flagSet.DurationVar(&c.RenewDeadline, "leader-elect-renew-deadline", 10*time.Second,
+		"How long the leader keeps trying to renew its leadership before giving it up. "+
+			"Must be less than the lease duration.")
+	flagSet.DurationVar(&c.RetryPeriod, "leader-elect-retry-period", 2*time.Second,
+		"How long leader election clients wait between attempts.")
+	flagSet.StringSliceVar(&c.WatchNamespaces, "watch-namespace", nil,
+		`Namespace(s) to watch for Kubernetes resources. Defaults to all namespaces. This flag accepts
+a comma-separated list and can be specified multiple times; cluster-scoped resources are always watched, and so
+are the configuration secret, the --publish-service and the IngressClass parameters in their own namespaces.`)
+	flagSet.StringSliceVar(&c.IngressClassNames, "ingress-class", []string{annotations.DefaultIngressClass},
+		`Name of an ingress class to route through this controller, matched against the
+kubernetes.io/ingress.class annotation of Ingresses without spec.ingressClassName. This flag accepts
+a comma-separated list and can be specified multiple times; Ingresses of any listed class are managed.
+The first class is used wherever the controller must name a single class itself.`)
+	flagSet.DurationVar(&c.ShutdownGracePeriod, "shutdown-grace-period", 10*time.Second,
+		`How long to wait, once the manager stops, for configuration pushes to Kong
+which are in progress to complete before exiting.`)
+
+	flagSet.DurationVar(&c.CacheSyncTimeout, "cache-sync-timeout", 2*time.Minute,
+		`How long the controllers wait at startup for the initial list of the Kubernetes resources they watch,
+logging the progress every `+cacheSyncProgressInterval.String()+`, before failing. Raise it for clusters holding
+many resources.`)
+
+	flagSet.StringSliceVar(&c.RelevantAnnotationPrefixes, "reconcile-annotation-prefix", kongctrl.DefaultRelevantAnnotationPrefixes,
+		`Prefix(es) of the annotations which affect the configuration of Kong. Updates of Ingresses and Kong
+resources changing neither their generation nor any annotation matching these prefixes, e.g. of their
+status, are not reconciled. This flag accepts a comma-separated list and can be specified multiple times.`)
+
+	flagSet.StringVar(&c.UseEndpointSlices, "use-endpointslices", "false",
+		`Whether the targets of upstreams are assembled from the EndpointSlices of services
+rather than their Endpoints: 'true', 'false', or 'auto' to use them when the cluster serves
+the discovery.k8s.io/v1beta1 API. Terminating endpoints are left out of the targets.`)
+
+	flagSet.StringVar(&c.IngressAPI, "ingress-api", "auto",
+		`Version of the Ingress API to reconcile: networking.k8s.io/v1, networking.k8s.io/v1beta1,
+extensions/v1beta1, or 'auto' to pick the newest one the cluster serves. Ingresses of the other
+served versions are reconciled too, as they are converted by the cluster.`)
+	flagSet.StringVar(&c.PublishService, "publish-service", "",
+		`Kong proxy Service, as namespace/name, whose load balancer addresses and external IPs are written
+in the status of the Ingresses managed by this controller, e.g. for external-dns. Its namespace is
+watched in addition to --watch-namespace. Ingress statuses are left alone if unset.`)
+
+	flagSet.IntVar(&c.ReconcileConcurrency, "reconcile-concurrency", 1,
+		`How many objects of each kind are reconciled in parallel. Raising it speeds up the processing
+of many objects, but configuration is still pushed to Kong one sync at a time.`)
+	flagSet.StringToIntVar(&c.ReconcileConcurrencyOverrides, "reconcile-concurrency-override", nil,
+		`Per-kind overrides of --reconcile-concurrency, e.g. Ingress=8,Secret=1. Supported kinds are
+HTTPRoute, Ingress, IngressClass, KongClusterPlugin, KongConsumer, KongIngress, KongPlugin, Secret and UDPIngress.`)
+
+	flagSet.StringToStringVar(&c.FeatureGates, "feature-gates", nil,
+		`Toggles controllers which are not stable yet, e.g. UDPIngress=false. Gates are named after the
+kind of their controller; AllAlpha and AllBeta toggle all alpha or beta controllers at once, and are
+overridden by the gates of single controllers. The effective enablement is logged at startup.`)
+	flagSet.BoolVar(&c.DisableControllersWithoutCRDs, "disable-controllers-without-crds", false,
+		`Disable, with a warning, the enabled controllers whose CRD is not installed in the cluster, e.g.
+UDPIngress, instead of failing startup.`)
+
+	flagSet.StringSliceVar(&c.KongURLs, "kong-url", []string{"http://localhost:8001"},
+		`The Admin API URL(s) of the Kong instance(s) to configure. This flag accepts a comma-separated list
+and can be specified multiple times; configuration is pushed to all of them concurrently. A URL of the form
+svc://namespace/name:port (svcs:// for HTTPS) names the port of a Service instead, whose calls are routed to
+one of its ready pods, following them as they are replaced.`)
+	flagSet.StringSliceVar(&c.FilterTags, "kong-filter-tag", nil,
+		`Tag(s) marking the Kong entities owned by this controller; entities lacking any of them
+are left untouched. This flag accepts a comma-separated list and can be specified multiple times.
+Defaults to managed-by-<name>, after --controller-name, or managed-by-railgun for the default name.`)
+	flagSet.IntVar(&c.Concurrency, "kong-concurrency", 10,
+		`Maximum number of Admin API requests in flight at once, across all Kong instances;
+further requests are queued until one completes. Must be at least 1.`)
+
+	// the token flags intentionally have no default so that a token can never end up in the usage output.
+	flagSet.StringVar(&c.KongAdminToken, "kong-admin-token", "",
+		`Sets the value of the 'kong-admin-token' header on every Admin API call; useful for
+authentication/authorization in Kong Enterprise (RBAC) environments.`)
+	flagSet.StringVar(&c.KongAdminTokenPath, "kong-admin-token-file", "",
+		`Path to a file containing the value of the 'kong-admin-token' header. The file is
+read once at startup; changes to it require a restart of the controller.
+Cannot be combined with --kong-admin-token.`)
+	flagSet.StringSliceVar(&c.KongAdminAPIConfig.Headers, "kong-admin-header", nil,
+		`add a header (key:value) to every Admin API call,
+this flag can be used multiple times to specify multiple headers`)
+	flagSet.StringVar(&c.KongAdminAPIConfig.UserAgent, "kong-admin-user-agent", adminapi.UserAgent(Release, Commit),
+		`User-Agent header of every Admin API call, identifying the controller in the logs of Kong.`)
+	flagSet.StringVar(&c.KongAdminAPIConfig.ProxyURL, "kong-admin-proxy-url", "",
+		`URL of the proxy (http, https or socks5) Admin API calls go through. If empty, the proxy
+is taken from the HTTP_PROXY, HTTPS_PROXY and NO_PROXY environment variables.`)
+	flagSet.BoolVar(&c.KongAdminAPIConfig.TLSSkipVerify, "kong-admin-tls-skip-verify", false,
+		"Disable verification of TLS certificate of Kong's Admin endpoint.")
+	flagSet.StringVar(&c.KongAdminAPIConfig.TLSServerName, "kong-admin-tls-server-name", "",
+		"SNI name to use to verify the certificate presented by Kong in TLS.")
+	flagSet.StringVar(&c.KongAdminAPIConfig.CACertPath, "kong-admin-ca-cert-file", "",
+		`Path to PEM-encoded CA certificate file to verify
+Kong's Admin SSL certificate.`)
+	flagSet.DurationVar(&c.KongAdminAPIConfig.CACertReloadInterval, "kong-admin-ca-cert-reload-interval", 0,
+		`Interval at which --kong-admin-ca-cert-file is read again, so that a rotated CA certificate is
+trusted for the connections opened afterwards without a restart. Disabled if 0.`)
+	flagSet.StringVar(&c.KongAdminAPIConfig.CACert, "kong-admin-ca-cert", "",
+		`PEM-encoded CA certificate to verify Kong's Admin SSL certificate.`)
+	flagSet.StringVar(&c.KongAdminAPIConfig.TLSClientCertPath, "kong-admin-tls-client-cert-file", "",
+		`Path to the PEM-encoded client certificate presented to Kong's Admin API (mutual TLS).
+Requires --kong-admin-tls-client-key-file or --kong-admin-tls-client-key.`)
+	flagSet.StringVar(&c.KongAdminAPIConfig.TLSClientCert, "kong-admin-tls-client-cert", "",
+		`PEM-encoded client certificate presented to Kong's Admin API (mutual TLS).`)
+	flagSet.StringVar(&c.KongAdminAPIConfig.TLSClientKeyPath, "kong-admin-tls-client-key-file", "",
+		`Path to the PEM-encoded private key of the client certificate presented to Kong's Admin API.`)
+	flagSet.StringVar(&c.KongAdminAPIConfig.TLSClientKey, "kong-admin-tls-client-key", "",
+		`PEM-encoded private key of the client certificate presented to Kong's Admin API.`)
+
+	flagSet.BoolVar(&c.KongAdminAPITrace, "kong-admin-api-trace", false,
+		`Log every Admin API call (method, path, status code, duration and body sizes) as JSON.
+The values of the Authorization and Kong-Admin-Token headers are redacted.`)
+	flagSet.IntVar(&c.KongAdminRetries.MaxRetries, "kong-admin-max-retries", 5,
+		`How many times an idempotent Admin API call failing with a 5xx status or a connection
+error is retried, with exponential backoff, before the failure is reported. 0 disables retries.`)
+	flagSet.DurationVar(&c.KongAdminRetries.MaxDelay, "kong-admin-retry-max-delay", 10*time.Second,
+		"The maximum delay between two attempts of an Admin API call.")
+	flagSet.DurationVar(&c.KongAdminInitRetry, "kong-admin-init-retry", 0,
+		`How long to wait at startup for the Kong Admin API to answer, retrying with exponential backoff,
+before giving up; useful when Kong starts along with the controller. 0 disables waiting.`)
+	flagSet.StringVar(&c.KongWorkspace, "kong-workspace", "",
+		`Workspace in Kong Enterprise to be configured. The workspace is created
+if it doesn't exist yet.`)
+	flagSet.StringVar(&c.KongDBMode, "kong-db-mode", "auto",
+		`How the configuration is pushed to Kong: 'db' with per-entity Admin API calls, for a Kong backed
+by a database, 'dbless' as a whole declarative configuration posted to /config, for a DB-less Kong, or
+'auto' to pick the mode matching the database Kong reports at startup.`)
+
+	flagSet.DurationVar(&c.SyncPeriod, "sync-period", 3*time.Second,
+		`Minimum time between two configuration pushes to Kong. The first change after a quiet period
+is pushed right away; further changes within the period are batched into a single push at its end.
+0 pushes every change as it is reconciled.`)
+	flagSet.DurationVar(&c.SyncRetryDelay, "sync-retry-delay", time.Second,
+		`Base delay before a failed configuration push to Kong is retried.`)
+	flagSet.DurationVar(&c.SyncRetryJitter, "sync-retry-jitter", 5*time.Second,
+		`Maximum random delay added to --sync-retry-delay, so that retries don't all reach Kong at once
+when it recovers. If both are 0, failed pushes are retried with the exponential backoff of the controller.`)
+
+	flagSet.DurationVar(&c.DefaultServiceConnectTimeout, "default-service-connect-timeout", 60*time.Second,
+		"Connect timeout of the Kong services generated for Kubernetes services, unless overridden by a KongIngress.")
+	flagSet.DurationVar(&c.DefaultServiceWriteTimeout, "default-service-write-timeout", 60*time.Second,
+		"Write timeout of the Kong services generated for Kubernetes services, unless overridden by a KongIngress.")
+	flagSet.DurationVar(&c.DefaultServiceReadTimeout, "default-service-read-timeout", 60*time.Second,
+		"Read timeout of the Kong services generated for Kubernetes services, unless overridden by a KongIngress.")
+	flagSet.BoolVar(&c.DefaultRouteStripPath, "default-route-strip-path", false,
+		`Strip the matched path from requests on the Kong routes generated for HTTP, unless overridden by the
+konghq.com/strip-path annotation or a KongIngress. For Ingress paths of type Prefix the prefix is stripped;
+for type Exact the whole path is, so the upstream receives /.`)
+	flagSet.BoolVar(&c.DefaultRoutePreserveHost, "default-route-preserve-host", true,
+		`Send the Host header of requests to upstreams on the Kong routes generated for HTTP, unless overridden
+by the konghq.com/preserve-host annotation or a KongIngress.`)
+
+	flagSet.StringVar(&c.CredentialTypeKey, "credential-type-key", util.DefaultCredentialTypeKey,
+		`Data field of Secrets holding the type of the Kong credential they contain. The type can
+also be set with the `+util.CredentialTypeLabel+` label, which must then agree with the data field if both are present.`)
+
+	flagSet.BoolVar(&c.DryRun, "dry-run", false,
+		`Only log the changes the controller would make to Kong, without applying them.
+The controller otherwise runs as usual, so the changes reflect the live cluster state.`)
+	flagSet.BoolVar(&c.NoDelete, "no-delete", false,
+		`Only create and update Kong entities, keeping those which no longer correspond to a Kubernetes object
+instead of deleting them, e.g. during risky migrations. The number of entities kept is logged with every push.
+Requires Kong to be backed by a database, as a DB-less Kong replaces its configuration as a whole.`)
+	flagSet.StringSliceVar(&c.ManagedEntityTypes, "managed-entity-types", nil,
+		`Types of Kong entities the controller creates, updates and deletes, among `+
+			strings.Join(sendconfig.EntityTypes, ", ")+`; all of them if unset.
+The entities of other types are left untouched, e.g. upstreams managed out of band: the targets of upstreams,
+the SNIs of certificates and the credentials of consumers go along with them, and plugins also require the type
+of the entities they are attached to. Requires Kong to be backed by a database, as a DB-less Kong replaces its
+configuration as a whole.`)
+
+	flagSet.StringVar(&c.DebugAddr, "debug-bind-address", "",
+		`The address the debug endpoint binds to. It serves the last configuration generated for Kong
+at /config, masking credentials and plugin configurations with ?redact=true, and pushes the whole configuration
+to Kong again on a POST to /resync, as a SIGHUP does. The version of Kong detected at startup is served at
+/kong-version. Disabled if empty.`)
+	// no default, so that the token can never end up in the usage output.
+	flagSet.StringVar(&c.DebugBearerToken, "debug-bearer-token", "",
+		`Bearer token requests to the debug endpoint must present. As the configuration may hold
+credentials sourced from Secrets, setting it is recommended unless access to the address is restricted.`)
+
+	flagSet.StringVar(&c.SecretName, "config-secret-name", controllers.ConfigSecretName,
+		`Name of the Secret the configuration for Kong is assembled in. It is created if it doesn't exist.`)
+	flagSet.StringVar(&c.SecretNamespace, "config-secret-namespace", controllers.DefaultNamespace,
+		`Namespace of the Secret the configuration for Kong is assembled in, which must exist at startup.`)
+	flagSet.BoolVar(&c.UseServerSideApply, "use-server-side-apply", false,
+		`Ensure the configuration Secret exists with server-side apply, under the field manager named after
+--controller-name, rather than retrieving it and creating it if missing. Requires Kubernetes 1.18 or newer.`)
+	flagSet.BoolVar(&c.CompressConfigSecret, "compress-config-secret", false,
+		`Compress the objects stored in the configuration Secret with gzip, so that more fit within the size limit
+of Secrets. Existing contents are converted on the next update, in either direction.`)
+	flagSet.Float64Var(&c.SecretSizeWarningRatio, "config-secret-size-warning-ratio", 0.75,
+		`Fraction of the 1 MiB size limit of Secrets above which a warning is logged and the
+kong_ingress_controller_configuration_secret_size_warnings_total metric is incremented, as the
+configuration Secret can't grow past the limit.`)
+	flagSet.StringVar(&c.ConfigSecretOwner, "config-secret-owner", "",
+		`Object owning the configuration Secret, as resource[.group]/name in the namespace of the Secret, e.g.
+deployments.apps/ingress-kong or configmaps/kong-config-owner, so that Kubernetes garbage-collects the Secret
+once the object is deleted. The object must exist at startup and the controller must be allowed to get it.`)
+	// the former names of the flags above
+	flagSet.StringVar(&c.SecretName, "secret-name", controllers.ConfigSecretName, "")
+	flagSet.StringVar(&c.SecretNamespace, "secret-namespace", controllers.DefaultNamespace, "")
+	_ = flagSet.MarkDeprecated("secret-name", "use --config-secret-name instead")
+	_ = flagSet.MarkDeprecated("secret-namespace", "use --config-secret-namespace instead")
+
+	flagSet.StringVar(&c.LogLevel, "log-level", "",
+		`Level of the logs, one of debug, info, warn or error. Defaults to the level of the zap mode
+(debug with --zap-devel, info otherwise). --zap-log-level takes precedence when both are given.`)
+	flagSet.IntVar(&c.LogSamplingInitial, "log-sampling-initial", 0,
+		`Log the first N identical messages each second, then only every Mth as set with
+--log-sampling-thereafter, to keep hot loops from flooding the logs. 0 disables sampling.
+The production zap mode (--zap-devel=false) already samples with N=M=100.`)
+	flagSet.IntVar(&c.LogSamplingThereafter, "log-sampling-thereafter", 100,
+		"Once --log-sampling-initial identical messages were logged in a second, log only every Mth of them.")
+	flagSet.StringVar(&c.LogStderrThreshold, "log-stderr-threshold", "",
+		`Level from which logs are written to stderr, one of debug, info, warn or error; the less severe
+logs are then written to stdout. By default, all logs are written to stderr.`)
+
+	flagSet.StringVar(&c.TerminationLogPath, "termination-log-path", DefaultTerminationLogPath,
+		`File the error the controller exits with is written to, for Kubernetes to report it in the status of the
+terminated container. It must match the terminationMessagePath of the container. Disabled if empty.`)
+
+	c.ZapOptions = zap.Options{
+		Development: true,
+	}
+	zapFlagSet := flag.NewFlagSet("", flag.ExitOnError)
+	c.ZapOptions.BindFlags(zapFlagSet)
+	flagSet.AddGoFlagSet(zapFlagSet)
+
+	return flagSet
+}
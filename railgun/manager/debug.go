@@ -0,0 +1,81 @@
+package manager
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/kong/kubernetes-ingress-controller/pkg/sendconfig"
+	"github.com/kong/kubernetes-ingress-controller/railgun/pkg/configdump"
+)
+
+// debugServerShutdownTimeout bounds how long the debug server waits for
+// requests in progress when the manager stops.
+const debugServerShutdownTimeout = 5 * time.Second
+
+// debugServer serves the debug endpoints. It is a manager.Runnable running on
+// every replica, leader or not.
+type debugServer struct {
+	addr    string
+	handler http.Handler
+}
+
+func newDebugServer(addr string, configDump *configdump.Store, resync *resyncTrigger,
+	kongVersion sendconfig.KongVersionInfo, token string) *debugServer {
+	mux := http.NewServeMux()
+	mux.Handle("/config", configdump.Handler(configDump, token))
+	mux.Handle("/resync", resync.handler(token))
+	mux.Handle("/kong-version", kongVersionHandler(kongVersion, token))
+	return &debugServer{addr: addr, handler: mux}
+}
+
+// kongVersionHandler returns the http.Handler of the debug endpoint serving the version of Kong detected at
+// startup as JSON. If token isn't empty, requests must present it as a bearer token.
+func kongVersionHandler(info sendconfig.KongVersionInfo, token string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if token != "" && !configdump.HasBearerToken(r, token) {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			w.Header().Set("Allow", "GET, HEAD")
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		data, err := json.Marshal(info)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write(data)
+	})
+}
+
+// Start implements manager.Runnable.
+func (s *debugServer) Start(ctx context.Context) error {
+	listener, err := net.Listen("tcp", s.addr)
+	if err != nil {
+		return err
+	}
+	server := &http.Server{Handler: s.handler}
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), debugServerShutdownTimeout)
+		defer cancel()
+		_ = server.Shutdown(shutdownCtx)
+	}()
+	if err := server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// NeedLeaderElection implements manager.LeaderElectionRunnable.
+func (s *debugServer) NeedLeaderElection() bool {
+	return false
+}
@@ -0,0 +1,46 @@
+package manager
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/kong/kubernetes-ingress-controller/pkg/sendconfig"
+)
+
+func TestKongVersionHandler(t *testing.T) {
+	handler := kongVersionHandler(sendconfig.KongVersionInfo{Reported: "2.5.0-rc.1", Version: "2.5.0-rc1"}, "s3cr3t")
+
+	for _, tt := range []struct {
+		name       string
+		method     string
+		token      string
+		wantStatus int
+		wantBody   string
+	}{
+		{name: "without token", method: http.MethodGet, wantStatus: http.StatusUnauthorized},
+		{name: "post", method: http.MethodPost, token: "s3cr3t", wantStatus: http.StatusMethodNotAllowed},
+		{
+			name:       "get",
+			method:     http.MethodGet,
+			token:      "s3cr3t",
+			wantStatus: http.StatusOK,
+			wantBody:   `{"reported":"2.5.0-rc.1","version":"2.5.0-rc1"}`,
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, "/kong-version", nil)
+			if tt.token != "" {
+				req.Header.Set("Authorization", "Bearer "+tt.token)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+			assert.Equal(t, tt.wantStatus, rec.Code)
+			if tt.wantBody != "" {
+				assert.JSONEq(t, tt.wantBody, rec.Body.String())
+			}
+		})
+	}
+}
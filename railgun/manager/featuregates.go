@@ -0,0 +1,173 @@
+package manager
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// featureStage is the maturity of a gated feature.
+type featureStage string
+
+const (
+	alpha  featureStage = "Alpha"
+	beta   featureStage = "Beta"
+	stable featureStage = "GA"
+)
+
+// allAlphaGate and allBetaGate toggle all the alpha and beta controllers at once,
+// as the gates of the same names do for Kubernetes components.
+const (
+	allAlphaGate = "AllAlpha"
+	allBetaGate  = "AllBeta"
+)
+
+// controllerGate describes a controller which can be toggled with --feature-gates.
+type controllerGate struct {
+	stage     featureStage
+	byDefault bool
+}
+
+// controllerGates are the controllers which can be toggled with --feature-gates, keyed by kind.
+// The controllers of the other kinds in reconcileConcurrencyKinds are stable and always enabled.
+var controllerGates = map[string]controllerGate{
+	// the UDPIngress API is still v1alpha1
+	"UDPIngress":   {stage: alpha, byDefault: true},
+	"IngressClass": {stage: beta, byDefault: true},
+	// the Gateway API is still v1alpha1, and its CRDs aren't installed on most clusters
+	"HTTPRoute": {stage: alpha, byDefault: false},
+	// the controllers of the Kong custom resources were disabled for an unrecorded problem; the
+	// Programmed condition of KongPlugins, KongClusterPlugins and KongConsumers needs them, so they
+	// can be opted into until they are known to be fixed
+	"KongClusterPlugin": {stage: alpha, byDefault: false},
+	"KongConsumer":      {stage: alpha, byDefault: false},
+	"KongIngress":       {stage: alpha, byDefault: false},
+	"KongPlugin":        {stage: alpha, byDefault: false},
+}
+
+// controllerState tells whether a controller is enabled, and why.
+type controllerState string
+
+const (
+	// stateEnabled is the state of stable controllers, and of those enabled by a gate.
+	stateEnabled controllerState = "enabled"
+	// stateAutoEnabled is the state of the controllers enabled by default, with no gate set.
+	stateAutoEnabled controllerState = "auto-enabled"
+	// stateDisabled is the state of the controllers disabled by a gate, or by default.
+	stateDisabled controllerState = "disabled"
+	// stateAutoSkipped is the state of the controllers skipped as the cluster doesn't serve their API.
+	stateAutoSkipped controllerState = "auto-skipped"
+)
+
+// controllerEnablement tells whether the controller of a kind is enabled, and at which stage it is.
+type controllerEnablement struct {
+	kind  string
+	stage featureStage
+	state controllerState
+}
+
+// enabled tells whether the controller is to be set up.
+func (e controllerEnablement) enabled() bool {
+	return e.state == stateEnabled || e.state == stateAutoEnabled
+}
+
+// resolveControllerEnablement computes which controllers are enabled given the values of --feature-gates.
+// The gate named after a controller takes precedence over AllAlpha or AllBeta, which in turn
+// take precedence over the default of the controller. Entries are sorted by kind.
+func resolveControllerEnablement(featureGates map[string]string) ([]controllerEnablement, error) {
+	gates := make(map[string]bool, len(featureGates))
+	for name, value := range featureGates {
+		_, isController := controllerGates[name]
+		if !isController && name != allAlphaGate && name != allBetaGate {
+			return nil, fmt.Errorf("--feature-gates: unknown feature gate %q, must be one of %s",
+				name, strings.Join(featureGateNames(), ", "))
+		}
+		enabled, err := strconv.ParseBool(value)
+		if err != nil {
+			return nil, fmt.Errorf("--feature-gates: invalid value %q of %s, must be true or false", value, name)
+		}
+		gates[name] = enabled
+	}
+
+	var result []controllerEnablement
+	for _, kind := range reconcileConcurrencyKinds {
+		gate, ok := controllerGates[kind]
+		if !ok {
+			result = append(result, controllerEnablement{kind: kind, stage: stable, state: stateEnabled})
+			continue
+		}
+		enabled, gated := gate.byDefault, false
+		if group, ok := gates[groupGate(gate.stage)]; ok {
+			enabled, gated = group, true
+		}
+		if specific, ok := gates[kind]; ok {
+			enabled, gated = specific, true
+		}
+		state := stateDisabled
+		if enabled && gated {
+			state = stateEnabled
+		} else if enabled {
+			state = stateAutoEnabled
+		}
+		result = append(result, controllerEnablement{kind: kind, stage: gate.stage, state: state})
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].kind < result[j].kind })
+	return result, nil
+}
+
+// groupGate returns the gate toggling all the controllers at stage.
+func groupGate(stage featureStage) string {
+	if stage == alpha {
+		return allAlphaGate
+	}
+	return allBetaGate
+}
+
+// featureGateNames returns the sorted names of the gates accepted by --feature-gates.
+func featureGateNames() []string {
+	names := []string{allAlphaGate, allBetaGate}
+	for kind := range controllerGates {
+		names = append(names, kind)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// controllerEnabled tells whether the controller of kind is enabled in enablement.
+func controllerEnabled(enablement []controllerEnablement, kind string) bool {
+	for _, e := range enablement {
+		if e.kind == kind {
+			return e.enabled()
+		}
+	}
+	return false
+}
+
+// optionController returns the enablement of the stable controller of kind which only runs when the option it
+// serves is set, e.g. IngressStatus with --publish-service.
+func optionController(kind string, optionSet bool) controllerEnablement {
+	if optionSet {
+		return controllerEnablement{kind: kind, stage: stable, state: stateEnabled}
+	}
+	return controllerEnablement{kind: kind, stage: stable, state: stateDisabled}
+}
+
+// skipController marks the controller of kind as auto-skipped in enablement, as the cluster
+// doesn't serve its API.
+func skipController(enablement []controllerEnablement, kind string) {
+	for i := range enablement {
+		if enablement[i].kind == kind {
+			enablement[i].state = stateAutoSkipped
+		}
+	}
+}
+
+// controllerStates returns the final state of every controller in enablement, keyed by kind.
+func controllerStates(enablement []controllerEnablement) map[string]controllerState {
+	states := make(map[string]controllerState, len(enablement))
+	for _, e := range enablement {
+		states[e.kind] = e.state
+	}
+	return states
+}
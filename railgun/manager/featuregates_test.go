@@ -0,0 +1,106 @@
+package manager
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResolveControllerEnablement(t *testing.T) {
+	enablement := func(udpIngress, ingressClass, httpRoute controllerState) []controllerEnablement {
+		return []controllerEnablement{
+			{kind: "HTTPRoute", stage: alpha, state: httpRoute},
+			{kind: "Ingress", stage: stable, state: stateEnabled},
+			{kind: "IngressClass", stage: beta, state: ingressClass},
+			{kind: "KongClusterPlugin", stage: alpha, state: stateDisabled},
+			{kind: "KongConsumer", stage: alpha, state: stateDisabled},
+			{kind: "KongIngress", stage: alpha, state: stateDisabled},
+			{kind: "KongPlugin", stage: alpha, state: stateDisabled},
+			{kind: "Secret", stage: stable, state: stateEnabled},
+			{kind: "UDPIngress", stage: alpha, state: udpIngress},
+		}
+	}
+	tests := []struct {
+		name         string
+		featureGates map[string]string
+		want         []controllerEnablement
+		wantErr      string
+	}{
+		{
+			name: "defaults",
+			want: enablement(stateAutoEnabled, stateAutoEnabled, stateDisabled),
+		},
+		{
+			name:         "controller gate",
+			featureGates: map[string]string{"UDPIngress": "false"},
+			want:         enablement(stateDisabled, stateAutoEnabled, stateDisabled),
+		},
+		{
+			name:         "group gate",
+			featureGates: map[string]string{"AllAlpha": "false"},
+			want:         enablement(stateDisabled, stateAutoEnabled, stateDisabled),
+		},
+		{
+			name:         "controller disabled by default",
+			featureGates: map[string]string{"HTTPRoute": "true"},
+			want:         enablement(stateAutoEnabled, stateAutoEnabled, stateEnabled),
+		},
+		{
+			name:         "Kong controller gate",
+			featureGates: map[string]string{"KongPlugin": "true"},
+			want:         withState(enablement(stateAutoEnabled, stateAutoEnabled, stateDisabled), stateEnabled, "KongPlugin"),
+		},
+		{
+			name:         "group gate enabling a controller disabled by default",
+			featureGates: map[string]string{"AllAlpha": "true"},
+			want: withState(enablement(stateEnabled, stateAutoEnabled, stateEnabled),
+				stateEnabled, "KongClusterPlugin", "KongConsumer", "KongIngress", "KongPlugin"),
+		},
+		{
+			name:         "controller gate takes precedence over group gate",
+			featureGates: map[string]string{"AllAlpha": "false", "AllBeta": "false", "UDPIngress": "true"},
+			want:         enablement(stateEnabled, stateDisabled, stateDisabled),
+		},
+		{
+			name:         "unknown gate",
+			featureGates: map[string]string{"Secret": "false"},
+			wantErr: `--feature-gates: unknown feature gate "Secret", must be one of AllAlpha, AllBeta, HTTPRoute, IngressClass, ` +
+				`KongClusterPlugin, KongConsumer, KongIngress, KongPlugin, UDPIngress`,
+		},
+		{
+			name:         "invalid value",
+			featureGates: map[string]string{"AllBeta": "maybe"},
+			wantErr:      `--feature-gates: invalid value "maybe" of AllBeta, must be true or false`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := resolveControllerEnablement(tt.featureGates)
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+// withState sets the state of the controllers of kinds in enablement.
+func withState(enablement []controllerEnablement, state controllerState, kinds ...string) []controllerEnablement {
+	for _, kind := range kinds {
+		for i := range enablement {
+			if enablement[i].kind == kind {
+				enablement[i].state = state
+			}
+		}
+	}
+	return enablement
+}
+
+func TestOptionController(t *testing.T) {
+	assert.Equal(t, controllerEnablement{kind: "IngressStatus", stage: stable, state: stateEnabled},
+		optionController("IngressStatus", true))
+	assert.Equal(t, controllerEnablement{kind: "IngressStatus", stage: stable, state: stateDisabled},
+		optionController("IngressStatus", false))
+}
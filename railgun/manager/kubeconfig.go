@@ -0,0 +1,64 @@
+package manager
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/go-logr/logr"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+// Mechanisms which can be used to obtain the configuration to connect to the Kubernetes API.
+const (
+	// KubeconfigFromAuto uses the in-cluster configuration when running in a Pod with a
+	// service account token mounted, and a kubeconfig file otherwise.
+	KubeconfigFromAuto = "auto"
+	// KubeconfigFromInCluster always uses the in-cluster configuration.
+	KubeconfigFromInCluster = "incluster"
+	// KubeconfigFromFile always uses a kubeconfig file.
+	KubeconfigFromFile = "file"
+)
+
+// serviceAccountTokenPath is where Kubernetes mounts the token of the Pod's service account.
+var serviceAccountTokenPath = "/var/run/secrets/kubernetes.io/serviceaccount/token"
+
+// kubeconfigSource resolves which mechanism must be used to obtain the Kubernetes API configuration.
+func kubeconfigSource(c *Config) (string, error) {
+	switch c.KubeconfigFrom {
+	case KubeconfigFromInCluster, KubeconfigFromFile:
+		return c.KubeconfigFrom, nil
+	case KubeconfigFromAuto:
+		if c.KubeconfigPath != "" {
+			return KubeconfigFromFile, nil
+		}
+		if _, err := os.Stat(serviceAccountTokenPath); err == nil {
+			return KubeconfigFromInCluster, nil
+		}
+		return KubeconfigFromFile, nil
+	default:
+		return "", fmt.Errorf("invalid --kubeconfig-from '%s': must be one of %s, %s or %s",
+			c.KubeconfigFrom, KubeconfigFromAuto, KubeconfigFromInCluster, KubeconfigFromFile)
+	}
+}
+
+// getKubeconfig returns the configuration to connect to the Kubernetes API and
+// logs which mechanism was used to obtain it.
+func getKubeconfig(c *Config, log logr.Logger) (*rest.Config, error) {
+	source, err := kubeconfigSource(c)
+	if err != nil {
+		return nil, err
+	}
+
+	if source == KubeconfigFromInCluster {
+		log.Info("using the in-cluster configuration to connect to Kubernetes")
+		return rest.InClusterConfig()
+	}
+
+	// without an explicit path, the KUBECONFIG env var and the user's home
+	// directory are looked up
+	loadingRules := clientcmd.NewDefaultClientConfigLoadingRules()
+	loadingRules.ExplicitPath = c.KubeconfigPath
+	log.Info("using a kubeconfig file to connect to Kubernetes", "path", c.KubeconfigPath)
+	return clientcmd.NewNonInteractiveDeferredLoadingClientConfig(loadingRules, &clientcmd.ConfigOverrides{}).ClientConfig()
+}
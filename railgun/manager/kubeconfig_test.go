@@ -0,0 +1,80 @@
+package manager
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKubeconfigSource(t *testing.T) {
+	dir, err := ioutil.TempDir("", "serviceaccount")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	mountedToken := filepath.Join(dir, "token")
+	assert.NoError(t, ioutil.WriteFile(mountedToken, []byte("token"), 0600))
+
+	defaultTokenPath := serviceAccountTokenPath
+	defer func() { serviceAccountTokenPath = defaultTokenPath }()
+
+	tests := []struct {
+		name      string
+		from      string
+		path      string
+		tokenPath string
+		want      string
+		wantErr   bool
+	}{
+		{
+			name:      "auto with a mounted token uses the in-cluster config",
+			from:      KubeconfigFromAuto,
+			tokenPath: mountedToken,
+			want:      KubeconfigFromInCluster,
+		},
+		{
+			name:      "auto with an explicit path uses the file",
+			from:      KubeconfigFromAuto,
+			path:      "/tmp/kubeconfig",
+			tokenPath: mountedToken,
+			want:      KubeconfigFromFile,
+		},
+		{
+			name:      "auto outside of a cluster uses the file",
+			from:      KubeconfigFromAuto,
+			tokenPath: filepath.Join(dir, "missing"),
+			want:      KubeconfigFromFile,
+		},
+		{
+			name:      "incluster is forced",
+			from:      KubeconfigFromInCluster,
+			path:      "/tmp/kubeconfig",
+			tokenPath: filepath.Join(dir, "missing"),
+			want:      KubeconfigFromInCluster,
+		},
+		{
+			name:      "file is forced",
+			from:      KubeconfigFromFile,
+			tokenPath: mountedToken,
+			want:      KubeconfigFromFile,
+		},
+		{
+			name:    "unknown mechanism",
+			from:    "magic",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			serviceAccountTokenPath = tt.tokenPath
+			got, err := kubeconfigSource(&Config{KubeconfigFrom: tt.from, KubeconfigPath: tt.path})
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
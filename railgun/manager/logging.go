@@ -0,0 +1,101 @@
+package manager
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	uberzap "go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+)
+
+// logLevels are the levels accepted by --log-level.
+var logLevels = map[string]zapcore.Level{
+	"debug": zapcore.DebugLevel,
+	"info":  zapcore.InfoLevel,
+	"warn":  zapcore.WarnLevel,
+	"error": zapcore.ErrorLevel,
+}
+
+// zapOptions returns the options of the loggers of the manager: the ones set
+// with the zap flags, with the level set with --log-level unless --zap-log-level
+// was given too, log sampling if enabled, and the entries split between stdout
+// and stderr if --log-stderr-threshold is set.
+func (c *Config) zapOptions() (zap.Options, error) {
+	opts := c.ZapOptions
+	if c.LogLevel != "" {
+		level, ok := logLevels[c.LogLevel]
+		if !ok {
+			return opts, fmt.Errorf("--log-level: unknown level %q, must be one of debug, info, warn or error", c.LogLevel)
+		}
+		// the zap flags only set the level when --zap-log-level is given
+		if opts.Level == nil {
+			atomicLevel := uberzap.NewAtomicLevelAt(level)
+			opts.Level = &atomicLevel
+		}
+	}
+
+	if c.LogSamplingInitial < 0 || c.LogSamplingThereafter < 0 {
+		return opts, fmt.Errorf("--log-sampling-initial (%d) and --log-sampling-thereafter (%d) cannot be negative",
+			c.LogSamplingInitial, c.LogSamplingThereafter)
+	}
+	// zap's sampler can't handle the levels more verbose than debug
+	// which --zap-log-level accepts
+	verbose := opts.Level != nil && opts.Level.Enabled(zapcore.DebugLevel-1)
+	if c.LogSamplingInitial > 0 && !verbose {
+		opts.ZapOpts = append(append([]uberzap.Option{}, opts.ZapOpts...),
+			uberzap.WrapCore(func(core zapcore.Core) zapcore.Core {
+				return zapcore.NewSamplerWithOptions(core, time.Second, c.LogSamplingInitial, c.LogSamplingThereafter)
+			}))
+	}
+	if c.LogStderrThreshold != "" {
+		threshold, ok := logLevels[c.LogStderrThreshold]
+		if !ok {
+			return opts, fmt.Errorf("--log-stderr-threshold: unknown level %q, must be one of debug, info, warn or error",
+				c.LogStderrThreshold)
+		}
+		opts = splitLogsByLevel(opts, threshold, os.Stdout, os.Stderr)
+	}
+	return opts, nil
+}
+
+// splitLogsByLevel returns opts writing the entries at or above threshold to errOut, and the others to out.
+func splitLogsByLevel(opts zap.Options, threshold zapcore.Level, out, errOut io.Writer) zap.Options {
+	errOpts := opts
+	errOpts.DestWriter = errOut
+	errCore := zap.NewRaw(zap.UseFlagOptions(&errOpts)).Core()
+
+	opts.DestWriter = out
+	opts.ZapOpts = append(append([]uberzap.Option{}, opts.ZapOpts...),
+		uberzap.WrapCore(func(core zapcore.Core) zapcore.Core {
+			return zapcore.NewTee(
+				levelFilterCore{Core: core, enabled: func(level zapcore.Level) bool { return level < threshold }},
+				levelFilterCore{Core: errCore, enabled: func(level zapcore.Level) bool { return level >= threshold }},
+			)
+		}))
+	return opts
+}
+
+// levelFilterCore is a zapcore.Core only writing the entries of the levels enabled
+// by both its enabled function and the core it wraps.
+type levelFilterCore struct {
+	zapcore.Core
+	enabled func(zapcore.Level) bool
+}
+
+func (c levelFilterCore) Enabled(level zapcore.Level) bool {
+	return c.enabled(level) && c.Core.Enabled(level)
+}
+
+func (c levelFilterCore) With(fields []zapcore.Field) zapcore.Core {
+	return levelFilterCore{Core: c.Core.With(fields), enabled: c.enabled}
+}
+
+func (c levelFilterCore) Check(entry zapcore.Entry, checked *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if !c.enabled(entry.Level) {
+		return checked
+	}
+	return c.Core.Check(entry, checked)
+}
@@ -0,0 +1,105 @@
+package manager
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap/zapcore"
+	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+)
+
+func TestZapOptionsLevel(t *testing.T) {
+	tests := []struct {
+		name      string
+		args      []string
+		wantDebug bool
+		wantErr   bool
+	}{
+		{
+			name:      "development preset",
+			wantDebug: true,
+		},
+		{
+			name: "info level",
+			args: []string{"--log-level=info"},
+		},
+		{
+			name:      "debug level",
+			args:      []string{"--log-level=debug", "--zap-devel=false"},
+			wantDebug: true,
+		},
+		{
+			name:      "zap level takes precedence",
+			args:      []string{"--log-level=info", "--zap-log-level=debug"},
+			wantDebug: true,
+		},
+		{
+			name:    "unknown level",
+			args:    []string{"--log-level=verbose"},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &Config{}
+			assert.NoError(t, MakeFlagSetFor(c).Parse(tt.args))
+			opts, err := c.zapOptions()
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+
+			var out bytes.Buffer
+			opts.DestWriter = &out
+			logger := zap.New(zap.UseFlagOptions(&opts))
+			logger.V(1).Info("debug line")
+			logger.Info("info line")
+
+			assert.Contains(t, out.String(), "info line")
+			if tt.wantDebug {
+				assert.Contains(t, out.String(), "debug line")
+			} else {
+				assert.NotContains(t, out.String(), "debug line")
+			}
+		})
+	}
+}
+
+func TestZapOptionsSampling(t *testing.T) {
+	c := &Config{}
+	assert.NoError(t, MakeFlagSetFor(c).Parse([]string{
+		"--log-sampling-initial=2",
+		"--log-sampling-thereafter=5",
+	}))
+	opts, err := c.zapOptions()
+	assert.NoError(t, err)
+
+	var out bytes.Buffer
+	opts.DestWriter = &out
+	logger := zap.New(zap.UseFlagOptions(&opts))
+	for i := 0; i < 12; i++ {
+		logger.Info("hot loop")
+	}
+	// the 1st, 2nd, 7th and 12th messages
+	assert.Equal(t, 4, strings.Count(out.String(), "hot loop"))
+}
+
+func TestSplitLogsByLevel(t *testing.T) {
+	var out, errOut bytes.Buffer
+	opts := splitLogsByLevel(zap.Options{Development: true}, zapcore.ErrorLevel, &out, &errOut)
+	logger := zap.New(zap.UseFlagOptions(&opts)).WithName("test").WithValues("key", "value")
+	logger.V(1).Info("debug line")
+	logger.Info("info line")
+	logger.Error(errors.New("boom"), "error line")
+
+	assert.Contains(t, out.String(), "debug line")
+	assert.Contains(t, out.String(), "info line")
+	assert.NotContains(t, out.String(), "error line")
+	assert.NotContains(t, errOut.String(), "info line")
+	assert.Contains(t, errOut.String(), "error line")
+	assert.Contains(t, errOut.String(), `"key": "value"`)
+}
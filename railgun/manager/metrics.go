@@ -0,0 +1,24 @@
+package manager
+
+import (
+	"github.com/go-logr/logr"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// controllerInfo has a series of value 1 per controller, labeled with its stage and final state, so that
+// dashboards can show which controllers the manager runs.
+var controllerInfo = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+	Namespace: "kong_ingress_controller",
+	Name:      "controller_info",
+	Help:      "Controllers of the manager, with their stage and whether they were set up.",
+}, []string{"controller", "stage", "state"})
+
+// reportControllerStates logs the final state of every controller in enablement, once they are set
+// up, and exposes them as controllerInfo.
+func reportControllerStates(enablement []controllerEnablement, log logr.Logger) {
+	controllerInfo.Reset()
+	for _, e := range enablement {
+		controllerInfo.WithLabelValues(e.kind, string(e.stage), string(e.state)).Set(1)
+	}
+	log.Info("controllers set up", "controllers", controllerStates(enablement))
+}
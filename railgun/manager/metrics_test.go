@@ -0,0 +1,59 @@
+package manager
+
+import (
+	"testing"
+
+	"github.com/go-logr/logr"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	fakediscovery "k8s.io/client-go/discovery/fake"
+	clienttesting "k8s.io/client-go/testing"
+)
+
+func TestReportControllerStates(t *testing.T) {
+	// UDPIngress is disabled, HTTPRoute enabled but its CRD is missing, the cluster doesn't serve IngressClass and
+	// no publish service is set for IngressStatus
+	enablement, err := resolveControllerEnablement(map[string]string{"UDPIngress": "false", "HTTPRoute": "true"})
+	require.NoError(t, err)
+	d := &fakediscovery.FakeDiscovery{Fake: &clienttesting.Fake{Resources: []*metav1.APIResourceList{{
+		GroupVersion: "networking.x-k8s.io/v1alpha1",
+		APIResources: []metav1.APIResource{{Name: "gateways", Kind: "Gateway"}},
+	}}}}
+	enablement, err = checkControllerCRDs(d, enablement, true, logr.Discard())
+	require.NoError(t, err)
+	skipController(enablement, "IngressClass")
+	enablement = append(enablement, optionController("IngressStatus", false))
+
+	assert.Equal(t, map[string]controllerState{
+		"HTTPRoute":         stateAutoSkipped,
+		"Ingress":           stateEnabled,
+		"IngressClass":      stateAutoSkipped,
+		"IngressStatus":     stateDisabled,
+		"KongClusterPlugin": stateDisabled,
+		"KongConsumer":      stateDisabled,
+		"KongIngress":       stateDisabled,
+		"KongPlugin":        stateDisabled,
+		"Secret":            stateEnabled,
+		"UDPIngress":        stateDisabled,
+	}, controllerStates(enablement))
+
+	reportControllerStates(enablement, logr.Discard())
+	assert.Equal(t, 10, testutil.CollectAndCount(controllerInfo))
+	for _, labels := range [][]string{
+		{"HTTPRoute", "Alpha", "auto-skipped"},
+		{"Ingress", "GA", "enabled"},
+		{"IngressClass", "Beta", "auto-skipped"},
+		{"IngressStatus", "GA", "disabled"},
+		{"KongPlugin", "Alpha", "disabled"},
+		{"Secret", "GA", "enabled"},
+		{"UDPIngress", "Alpha", "disabled"},
+	} {
+		assert.Equal(t, float64(1), testutil.ToFloat64(controllerInfo.WithLabelValues(labels...)), labels)
+	}
+
+	// a previous report doesn't linger
+	reportControllerStates(enablement[:1], logr.Discard())
+	assert.Equal(t, 1, testutil.CollectAndCount(controllerInfo))
+}
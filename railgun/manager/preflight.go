@@ -0,0 +1,74 @@
+package manager
+
+import (
+	"fmt"
+
+	"github.com/go-logr/logr"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/discovery"
+	gatewayv1alpha1 "sigs.k8s.io/gateway-api/apis/v1alpha1"
+
+	konghqcomv1 "github.com/kong/kubernetes-ingress-controller/railgun/apis/configuration/v1"
+	"github.com/kong/kubernetes-ingress-controller/railgun/apis/configuration/v1alpha1"
+)
+
+// crdControllers maps the kinds of the controllers reconciling custom resources to the API of their
+// resource, which the cluster only serves once its CRD is installed.
+var crdControllers = map[string]schema.GroupVersionResource{
+	"UDPIngress": v1alpha1.GroupVersion.WithResource("udpingresses"),
+	"HTTPRoute":  gatewayv1alpha1.SchemeGroupVersion.WithResource("httproutes"),
+
+	"KongClusterPlugin": konghqcomv1.GroupVersion.WithResource("kongclusterplugins"),
+	"KongConsumer":      konghqcomv1.GroupVersion.WithResource("kongconsumers"),
+	"KongIngress":       konghqcomv1.GroupVersion.WithResource("kongingresses"),
+	"KongPlugin":        konghqcomv1.GroupVersion.WithResource("kongplugins"),
+}
+
+// checkControllerCRDs verifies, with discovery, that the CRD of every enabled controller in enablement
+// is installed, as their watches would otherwise fail once the manager runs. A missing CRD fails startup,
+// unless disableMissing is set, in which case the controller is auto-skipped in the returned enablement
+// and a warning logged.
+func checkControllerCRDs(d discovery.DiscoveryInterface, enablement []controllerEnablement, disableMissing bool,
+	log logr.Logger) ([]controllerEnablement, error) {
+	groups, err := d.ServerGroups()
+	if err != nil {
+		return nil, fmt.Errorf("unable to check whether the CRDs of the controllers are installed: %w", err)
+	}
+	served := make(map[string]bool)
+	for _, group := range groups.Groups {
+		for _, version := range group.Versions {
+			served[version.GroupVersion] = true
+		}
+	}
+
+	result := make([]controllerEnablement, 0, len(enablement))
+	for _, e := range enablement {
+		gvr, ok := crdControllers[e.kind]
+		if !e.enabled() || !ok {
+			result = append(result, e)
+			continue
+		}
+		installed := false
+		if served[gvr.GroupVersion().String()] {
+			resources, err := d.ServerResourcesForGroupVersion(gvr.GroupVersion().String())
+			if err != nil {
+				return nil, fmt.Errorf("unable to check whether the CRD of controller %s is installed: %w", e.kind, err)
+			}
+			for _, resource := range resources.APIResources {
+				installed = installed || resource.Name == gvr.Resource
+			}
+		}
+		if !installed {
+			crd := gvr.GroupResource().String()
+			if !disableMissing {
+				return nil, fmt.Errorf("CRD %s (%s) not installed, disable controller %s with --feature-gates=%s=false "+
+					"or install the CRD", crd, gvr.GroupVersion(), e.kind, e.kind)
+			}
+			log.Error(nil, "CRD not installed, disabling its controller", "crd", crd,
+				"version", gvr.GroupVersion().String(), "controller", e.kind)
+			e.state = stateAutoSkipped
+		}
+		result = append(result, e)
+	}
+	return result, nil
+}
@@ -0,0 +1,96 @@
+package manager
+
+import (
+	"testing"
+
+	"github.com/go-logr/logr"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	fakediscovery "k8s.io/client-go/discovery/fake"
+	clienttesting "k8s.io/client-go/testing"
+)
+
+func TestCheckControllerCRDs(t *testing.T) {
+	enablement := func(udpIngress, httpRoute controllerState) []controllerEnablement {
+		return []controllerEnablement{
+			{kind: "HTTPRoute", stage: alpha, state: httpRoute},
+			{kind: "Ingress", stage: stable, state: stateEnabled},
+			{kind: "IngressClass", stage: beta, state: stateAutoEnabled},
+			{kind: "KongClusterPlugin", stage: alpha, state: stateDisabled},
+			{kind: "KongConsumer", stage: alpha, state: stateDisabled},
+			{kind: "KongIngress", stage: alpha, state: stateDisabled},
+			{kind: "KongPlugin", stage: alpha, state: stateDisabled},
+			{kind: "Secret", stage: stable, state: stateEnabled},
+			{kind: "UDPIngress", stage: alpha, state: udpIngress},
+		}
+	}
+	kongCRDs := &metav1.APIResourceList{
+		GroupVersion: "configuration.konghq.com/v1alpha1",
+		APIResources: []metav1.APIResource{{Name: "udpingresses", Kind: "UDPIngress"}},
+	}
+	// the group is served, for other CRDs, without the one of the controller
+	gatewayCRDs := &metav1.APIResourceList{
+		GroupVersion: "networking.x-k8s.io/v1alpha1",
+		APIResources: []metav1.APIResource{{Name: "gateways", Kind: "Gateway"}},
+	}
+
+	tests := []struct {
+		name           string
+		resources      []*metav1.APIResourceList
+		enablement     []controllerEnablement
+		disableMissing bool
+		want           []controllerEnablement
+		wantErr        string
+	}{
+		{
+			name:       "every CRD is installed",
+			resources:  []*metav1.APIResourceList{kongCRDs},
+			enablement: enablement(stateAutoEnabled, stateDisabled),
+			want:       enablement(stateAutoEnabled, stateDisabled),
+		},
+		{
+			name:       "the CRD of a disabled controller is not required",
+			enablement: enablement(stateDisabled, stateDisabled),
+			want:       enablement(stateDisabled, stateDisabled),
+		},
+		{
+			name:       "group not served",
+			enablement: enablement(stateAutoEnabled, stateDisabled),
+			wantErr: "CRD udpingresses.configuration.konghq.com (configuration.konghq.com/v1alpha1) not installed, " +
+				"disable controller UDPIngress with --feature-gates=UDPIngress=false or install the CRD",
+		},
+		{
+			name:       "resource not served",
+			resources:  []*metav1.APIResourceList{kongCRDs, gatewayCRDs},
+			enablement: enablement(stateAutoEnabled, stateEnabled),
+			wantErr: "CRD httproutes.networking.x-k8s.io (networking.x-k8s.io/v1alpha1) not installed, " +
+				"disable controller HTTPRoute with --feature-gates=HTTPRoute=false or install the CRD",
+		},
+		{
+			name:       "CRD of an enabled Kong controller not installed",
+			resources:  []*metav1.APIResourceList{kongCRDs},
+			enablement: withState(enablement(stateAutoEnabled, stateDisabled), stateEnabled, "KongPlugin"),
+			wantErr: "CRD kongplugins.configuration.konghq.com (configuration.konghq.com/v1) not installed, " +
+				"disable controller KongPlugin with --feature-gates=KongPlugin=false or install the CRD",
+		},
+		{
+			name:           "controllers without CRD are disabled",
+			resources:      []*metav1.APIResourceList{gatewayCRDs},
+			enablement:     enablement(stateAutoEnabled, stateEnabled),
+			disableMissing: true,
+			want:           enablement(stateAutoSkipped, stateAutoSkipped),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := &fakediscovery.FakeDiscovery{Fake: &clienttesting.Fake{Resources: tt.resources}}
+			got, err := checkControllerCRDs(d, tt.enablement, tt.disableMissing, logr.Discard())
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
@@ -0,0 +1,69 @@
+package manager
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	deckutils "github.com/kong/deck/utils"
+	"github.com/kong/kubernetes-ingress-controller/pkg/sendconfig"
+)
+
+const (
+	// kongReadinessTimeout bounds the Admin API call made by the readiness check.
+	kongReadinessTimeout = 2 * time.Second
+	// kongReadinessCacheTTL is how long the result of the readiness check is reused,
+	// so that frequent probes don't hammer the Admin API.
+	kongReadinessCacheTTL = 5 * time.Second
+)
+
+// kongReadinessCheck reports the manager ready only while at least one of the
+// Kong Admin APIs it pushes configuration to can be reached.
+type kongReadinessCheck struct {
+	clients  []sendconfig.AdminAPIClient
+	timeout  time.Duration
+	cacheTTL time.Duration
+
+	lock      sync.Mutex
+	checkedAt time.Time
+	lastErr   error
+}
+
+func newKongReadinessCheck(clients []sendconfig.AdminAPIClient) *kongReadinessCheck {
+	return &kongReadinessCheck{
+		clients:  clients,
+		timeout:  kongReadinessTimeout,
+		cacheTTL: kongReadinessCacheTTL,
+	}
+}
+
+// Check implements healthz.Checker.
+func (k *kongReadinessCheck) Check(_ *http.Request) error {
+	k.lock.Lock()
+	defer k.lock.Unlock()
+
+	if !k.checkedAt.IsZero() && time.Since(k.checkedAt) < k.cacheTTL {
+		return k.lastErr
+	}
+	k.lastErr = k.reachKong()
+	k.checkedAt = time.Now()
+	return k.lastErr
+}
+
+// reachKong calls the root endpoint of every Admin API and succeeds as soon
+// as one of them responds.
+func (k *kongReadinessCheck) reachKong() error {
+	var errs []error
+	for _, client := range k.clients {
+		ctx, cancel := context.WithTimeout(context.Background(), k.timeout)
+		_, err := client.Root(ctx)
+		cancel()
+		if err == nil {
+			return nil
+		}
+		errs = append(errs, err)
+	}
+	return fmt.Errorf("kong admin API is unreachable: %w", deckutils.ErrArray{Errors: errs})
+}
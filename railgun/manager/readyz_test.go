@@ -0,0 +1,43 @@
+package manager
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/kong/go-kong/kong"
+	"github.com/kong/kubernetes-ingress-controller/pkg/sendconfig"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKongReadinessCheck(t *testing.T) {
+	status := http.StatusOK
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.WriteHeader(status)
+		_, _ = w.Write([]byte(`{"version":"2.4.0"}`))
+	}))
+	defer server.Close()
+	client, err := kong.NewClient(kong.String(server.URL), server.Client())
+	assert.NoError(t, err)
+
+	check := newKongReadinessCheck([]sendconfig.AdminAPIClient{client})
+	check.cacheTTL = time.Hour
+
+	// the result is cached
+	assert.NoError(t, check.Check(nil))
+	assert.NoError(t, check.Check(nil))
+	assert.Equal(t, 1, calls)
+
+	// once expired, Kong is checked again
+	status = http.StatusInternalServerError
+	check.cacheTTL = 0
+	assert.Error(t, check.Check(nil))
+	assert.Equal(t, 2, calls)
+
+	// Kong is down
+	server.Close()
+	assert.Error(t, check.Check(nil))
+}
@@ -0,0 +1,109 @@
+package manager
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+
+	"github.com/kong/kubernetes-ingress-controller/railgun/pkg/configdump"
+)
+
+// errNotLeader is returned when a manual resync is requested from a replica which is not the leader, and
+// doesn't push the configuration to Kong.
+var errNotLeader = errors.New("this replica is not the leader, only the leader pushes the configuration to Kong")
+
+// resyncTrigger requests manual resyncs of the configuration to Kong, on SIGHUP or from the debug endpoint.
+// The requests are sent to the controller of the configuration secret, which only runs on the leader: the
+// other replicas turn them down.
+type resyncTrigger struct {
+	log          logr.Logger
+	elected      <-chan struct{}
+	configSecret types.NamespacedName
+
+	// events holds a single pending request, as requests made before it is handled are satisfied by it.
+	events chan event.GenericEvent
+}
+
+func newResyncTrigger(log logr.Logger, elected <-chan struct{}, configSecret types.NamespacedName) *resyncTrigger {
+	return &resyncTrigger{
+		log:          log,
+		elected:      elected,
+		configSecret: configSecret,
+		events:       make(chan event.GenericEvent, 1),
+	}
+}
+
+// request requests a manual resync on behalf of origin, failing with errNotLeader if this replica is not the
+// leader.
+func (t *resyncTrigger) request(origin string) error {
+	select {
+	case <-t.elected:
+	default:
+		t.log.Info("ignoring the manual resync request, this replica is not the leader", "origin", origin)
+		return errNotLeader
+	}
+
+	secret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{
+		Namespace: t.configSecret.Namespace,
+		Name:      t.configSecret.Name,
+	}}
+	select {
+	case t.events <- event.GenericEvent{Object: secret}:
+		t.log.Info("manual resync requested", "origin", origin)
+	default:
+		t.log.Info("manual resync requested, one is already pending", "origin", origin)
+	}
+	return nil
+}
+
+// Start implements manager.Runnable, requesting a manual resync on every SIGHUP.
+func (t *resyncTrigger) Start(ctx context.Context) error {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGHUP)
+	defer signal.Stop(signals)
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-signals:
+			_ = t.request("SIGHUP")
+		}
+	}
+}
+
+// NeedLeaderElection implements manager.LeaderElectionRunnable. SIGHUP is handled by every replica, as it
+// would terminate them otherwise.
+func (t *resyncTrigger) NeedLeaderElection() bool {
+	return false
+}
+
+// handler returns the http.Handler of the debug endpoint requesting a manual resync with a POST. If token
+// isn't empty, requests must present it as a bearer token.
+func (t *resyncTrigger) handler(token string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if token != "" && !configdump.HasBearerToken(r, token) {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", "POST")
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		if err := t.request("debug endpoint"); err != nil {
+			http.Error(w, err.Error(), http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusAccepted)
+	})
+}
@@ -0,0 +1,62 @@
+package manager
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-logr/logr"
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func TestResyncTrigger(t *testing.T) {
+	configSecret := types.NamespacedName{Namespace: "kong", Name: "kong-config"}
+	elected := make(chan struct{})
+	trigger := newResyncTrigger(logr.Discard(), elected, configSecret)
+
+	// replicas which are not the leader turn requests down
+	assert.Equal(t, errNotLeader, trigger.request("test"))
+	assert.Len(t, trigger.events, 0)
+
+	close(elected)
+	assert.NoError(t, trigger.request("test"))
+	// a request made while one is pending is satisfied by it
+	assert.NoError(t, trigger.request("test"))
+	if assert.Len(t, trigger.events, 1) {
+		event := <-trigger.events
+		assert.Equal(t, configSecret.Namespace, event.Object.GetNamespace())
+		assert.Equal(t, configSecret.Name, event.Object.GetName())
+	}
+}
+
+func TestResyncTriggerHandler(t *testing.T) {
+	elected := make(chan struct{})
+	close(elected)
+	trigger := newResyncTrigger(logr.Discard(), elected, types.NamespacedName{Namespace: "kong", Name: "kong-config"})
+	handler := trigger.handler("s3cr3t")
+
+	for _, tt := range []struct {
+		name       string
+		method     string
+		token      string
+		wantStatus int
+		wantEvents int
+	}{
+		{name: "without token", method: http.MethodPost, wantStatus: http.StatusUnauthorized},
+		{name: "wrong token", method: http.MethodPost, token: "guess", wantStatus: http.StatusUnauthorized},
+		{name: "get", method: http.MethodGet, token: "s3cr3t", wantStatus: http.StatusMethodNotAllowed},
+		{name: "post", method: http.MethodPost, token: "s3cr3t", wantStatus: http.StatusAccepted, wantEvents: 1},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, "/resync", nil)
+			if tt.token != "" {
+				req.Header.Set("Authorization", "Bearer "+tt.token)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+			assert.Equal(t, tt.wantStatus, rec.Code)
+			assert.Len(t, trigger.events, tt.wantEvents)
+		})
+	}
+}
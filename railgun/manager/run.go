@@ -0,0 +1,835 @@
+package manager
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/kong/go-kong/kong"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	corev1 "k8s.io/api/core/v1"
+	discoveryv1beta1 "k8s.io/api/discovery/v1beta1"
+	netv1 "k8s.io/api/networking/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/client-go/discovery"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/healthz"
+	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+	ctrlmetrics "sigs.k8s.io/controller-runtime/pkg/metrics"
+	gatewayv1alpha1 "sigs.k8s.io/gateway-api/apis/v1alpha1"
+
+	"github.com/kong/kubernetes-ingress-controller/pkg/kongstate"
+	"github.com/kong/kubernetes-ingress-controller/pkg/parser"
+	"github.com/kong/kubernetes-ingress-controller/pkg/sendconfig"
+	"github.com/kong/kubernetes-ingress-controller/pkg/util"
+	konghqcomv1 "github.com/kong/kubernetes-ingress-controller/railgun/apis/configuration/v1"
+	"github.com/kong/kubernetes-ingress-controller/railgun/apis/configuration/v1alpha1"
+	"github.com/kong/kubernetes-ingress-controller/railgun/controllers"
+	kongctrl "github.com/kong/kubernetes-ingress-controller/railgun/controllers/configuration"
+	mgrutils "github.com/kong/kubernetes-ingress-controller/railgun/manager/utils"
+	"github.com/kong/kubernetes-ingress-controller/railgun/pkg/adminapi"
+	"github.com/kong/kubernetes-ingress-controller/railgun/pkg/configdump"
+	//+kubebuilder:scaffold:imports
+)
+
+var scheme = runtime.NewScheme()
+
+func init() {
+	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
+
+	utilruntime.Must(konghqcomv1.AddToScheme(scheme))
+	utilruntime.Must(v1alpha1.AddToScheme(scheme))
+	utilruntime.Must(gatewayv1alpha1.AddToScheme(scheme))
+	//+kubebuilder:scaffold:scheme
+}
+
+// Run starts the controller manager and blocks until it exits.
+func Run(ctx context.Context, c *Config) error {
+	if err := c.Validate(); err != nil {
+		return err
+	}
+	zapOptions, err := c.zapOptions()
+	if err != nil {
+		return err
+	}
+	ctrl.SetLogger(zap.New(zap.UseFlagOptions(&zapOptions)))
+	setupLog := ctrl.Log.WithName("setup")
+	for _, warning := range c.Warnings() {
+		setupLog.Info("WARNING: " + warning)
+	}
+
+	mgrutils.IngressClassNames = c.IngressClassNames
+	publishService, err := c.publishService()
+	if err != nil {
+		return err
+	}
+
+	// TODO: we might want to change how this works in the future, rather than just assuming the default ns
+	if v := os.Getenv(controllers.CtrlNamespaceEnv); v == "" {
+		os.Setenv(controllers.CtrlNamespaceEnv, controllers.DefaultNamespace)
+	}
+
+	enablement, err := resolveControllerEnablement(c.FeatureGates)
+	if err != nil {
+		return err
+	}
+	kongctrl.ConfigSecretSizeWarningRatio = c.SecretSizeWarningRatio
+	serviceDefaults, err := c.serviceDefaults()
+	if err != nil {
+		return err
+	}
+
+	kubeconfig, err := getKubeconfig(c, setupLog)
+	if err != nil {
+		return fmt.Errorf("unable to get the Kubernetes API configuration: %w", err)
+	}
+
+	// controllers whose CRD is missing would only fail once their watch starts
+	discoveryClient, err := discovery.NewDiscoveryClientForConfig(kubeconfig)
+	if err != nil {
+		return fmt.Errorf("unable to create the discovery client: %w", err)
+	}
+	enablement, err = checkControllerCRDs(discoveryClient, enablement, c.DisableControllersWithoutCRDs, setupLog)
+	if err != nil {
+		return err
+	}
+
+	mgrOpts := c.managerOptions()
+	if len(c.WatchNamespaces) > 0 {
+		// the configuration secret, the proxy service and the parameters of IngressClasses must be watched no
+		// matter which namespaces were requested, which only widens the watches of their kinds
+		extraNamespaces := map[schema.GroupKind][]string{
+			{Kind: "Secret"}:    {c.SecretNamespace},
+			{Kind: "ConfigMap"}: {os.Getenv(controllers.CtrlNamespaceEnv)},
+		}
+		if publishService != nil {
+			extraNamespaces[schema.GroupKind{Kind: "Service"}] = []string{publishService.Namespace}
+		}
+		setupLog.Info("watching a subset of the namespaces", "namespaces", c.WatchNamespaces)
+		mgrOpts.NewCache = watchNamespacesCacheBuilder(c.WatchNamespaces, extraNamespaces)
+	}
+	cacheSync := newCacheSyncMonitor(c.CacheSyncTimeout, ctrl.Log.WithName("cache"))
+	mgrOpts.NewCache = cacheSync.newCache(mgrOpts.NewCache)
+
+	mgr, err := ctrl.NewManager(kubeconfig, mgrOpts)
+	if err != nil {
+		return fmt.Errorf("unable to start manager: %w", err)
+	}
+	if err := mgr.Add(cacheSync); err != nil {
+		return fmt.Errorf("unable to set up the cache sync monitor: %w", err)
+	}
+	recorder := mgr.GetEventRecorderFor(c.identity().EventSource())
+
+	configSecret := types.NamespacedName{Namespace: c.SecretNamespace, Name: c.SecretName}
+	var configSecretOwner *metav1.OwnerReference
+	// configSecretReconciler returns the settings shared by the controllers storing their objects in the
+	// configuration secret, for the controller of kind.
+	configSecretReconciler := func(kind string) kongctrl.ConfigSecretReconciler {
+		return kongctrl.ConfigSecretReconciler{
+			Client:   mgr.GetClient(),
+			Log:      ctrl.Log.WithName("controllers").WithName(kind),
+			Scheme:   mgr.GetScheme(),
+			Recorder: recorder,
+
+			ConfigSecret:             configSecret,
+			ConfigSecretFieldManager: c.configSecretFieldManager(),
+			ConfigSecretOwner:        configSecretOwner,
+			ConfigSecretCompression:  c.CompressConfigSecret,
+			MaxConcurrentReconciles:  c.reconcileConcurrency(kind),
+			CacheSyncTimeout:         c.CacheSyncTimeout,
+
+			RelevantAnnotationPrefixes: c.RelevantAnnotationPrefixes,
+		}
+	}
+	if err := validateConfigSecretNamespace(ctx, mgr.GetAPIReader(), configSecret.Namespace); err != nil {
+		return err
+	}
+	if c.ConfigSecretOwner != "" {
+		configSecretOwner, err = resolveConfigSecretOwner(ctx, mgr.GetAPIReader(), mgr.GetRESTMapper(),
+			configSecret.Namespace, c.ConfigSecretOwner)
+		if err != nil {
+			return err
+		}
+		setupLog.Info("the configuration secret is owned by another object", "owner", c.ConfigSecretOwner)
+	}
+	if c.EnableLeaderElection && c.LeaderElectionNamespace != "" {
+		if err := validateLeaderElectionNamespace(ctx, mgr.GetAPIReader(), mgr.GetClient(), c.LeaderElectionNamespace); err != nil {
+			return err
+		}
+	}
+
+	for _, kongController := range []struct {
+		kind       string
+		reconciler interface{ SetupWithManager(ctrl.Manager) error }
+	}{
+		{"KongIngress", &kongctrl.KongV1KongIngressReconciler{ConfigSecretReconciler: configSecretReconciler("KongIngress")}},
+		{"KongClusterPlugin", &kongctrl.KongV1KongClusterPluginReconciler{ConfigSecretReconciler: configSecretReconciler("KongClusterPlugin")}},
+		{"KongPlugin", &kongctrl.KongV1KongPluginReconciler{ConfigSecretReconciler: configSecretReconciler("KongPlugin")}},
+		{"KongConsumer", &kongctrl.KongV1KongConsumerReconciler{ConfigSecretReconciler: configSecretReconciler("KongConsumer")}},
+	} {
+		if !controllerEnabled(enablement, kongController.kind) {
+			setupLog.Info("controller is disabled", "controller", kongController.kind)
+			continue
+		}
+		if err := kongController.reconciler.SetupWithManager(mgr); err != nil {
+			return fmt.Errorf("unable to create controller %s: %w", kongController.kind, err)
+		}
+	}
+
+	kongAdminToken, err := getKongAdminToken(c)
+	if err != nil {
+		return err
+	}
+	if kongAdminToken != "" {
+		c.KongAdminAPIConfig.Headers = append(c.KongAdminAPIConfig.Headers, "kong-admin-token:"+kongAdminToken)
+	}
+
+	if c.KongAdminAPITrace {
+		c.KongAdminAPIConfig.TraceLogger = zap.New(zap.UseFlagOptions(&zapOptions), zap.JSONEncoder()).
+			WithName("kong-admin-api")
+	}
+
+	httpClient, err := adminapi.MakeHTTPClient(&c.KongAdminAPIConfig)
+	if err != nil {
+		return fmt.Errorf("unable to create the Kong Admin API HTTP client: %w", err)
+	}
+
+	kongConfig, err := makeKongConfig(ctx, c, httpClient, mgr.GetAPIReader(), setupLog)
+	if err != nil {
+		return err
+	}
+	setupLog.Info("configuration push strategy selected", "dbless", kongConfig.InMemory, "requested", c.KongDBMode)
+	if err := detectKongVersion(ctx, &kongConfig); err != nil {
+		setupLog.Error(err, "unable to determine the version of Kong, version-gated features are disabled")
+	} else {
+		setupLog.Info("kong version detected", "version", kongConfig.Version.String())
+	}
+
+	if err := sendconfig.RegisterMetrics(ctrlmetrics.Registry); err != nil {
+		return fmt.Errorf("unable to register configuration push metrics: %w", err)
+	}
+	if err := kongctrl.RegisterMetrics(ctrlmetrics.Registry); err != nil {
+		return fmt.Errorf("unable to register configuration secret metrics: %w", err)
+	}
+	if err := kongstate.RegisterMetrics(ctrlmetrics.Registry); err != nil {
+		return fmt.Errorf("unable to register translation metrics: %w", err)
+	}
+	if err := ctrlmetrics.Registry.Register(controllerInfo); err != nil {
+		return fmt.Errorf("unable to register controller metrics: %w", err)
+	}
+
+	configDump := &configdump.Store{}
+	resync := newResyncTrigger(ctrl.Log.WithName("resync"), mgr.Elected(), configSecret)
+	if err := mgr.Add(resync); err != nil {
+		return fmt.Errorf("unable to set up the manual resync trigger: %w", err)
+	}
+	if c.DebugAddr != "" {
+		if err := mgr.Add(newDebugServer(c.DebugAddr, configDump, resync, kongConfig.VersionInfo(), c.DebugBearerToken)); err != nil {
+			return fmt.Errorf("unable to set up the debug server: %w", err)
+		}
+	}
+
+	useEndpointSlices, err := endpointSlicesEnabled(c, mgr, setupLog)
+	if err != nil {
+		return err
+	}
+
+	// the CRDs of enabled controllers were checked to be installed
+	useHTTPRoutes := controllerEnabled(enablement, "HTTPRoute")
+	// configChanges holds a single pending notification of a change to the IngressClasses
+	configChanges := make(chan event.GenericEvent, 1)
+
+	if err = (&kongctrl.SecretReconciler{
+		Client:   mgr.GetClient(),
+		Log:      ctrl.Log.WithName("controllers").WithName("Secret"),
+		Scheme:   mgr.GetScheme(),
+		Recorder: recorder,
+		Params: kongctrl.SecretReconcilerParams{
+			WatchName:         configSecret.Name,
+			WatchNamespace:    configSecret.Namespace,
+			KongConfig:        kongConfig,
+			Debouncer:         sendconfig.NewDebouncer(c.SyncPeriod),
+			SyncFailureJitter: sendconfig.NewRetryJitter(c.SyncRetryDelay, c.SyncRetryJitter),
+			ConfigDump:        configDump,
+
+			UseEndpointSlices: useEndpointSlices,
+			UseHTTPRoutes:     useHTTPRoutes,
+			ServiceDefaults:   serviceDefaults,
+			RouteDefaults: parser.RouteDefaults{
+				StripPath:    kong.Bool(c.DefaultRouteStripPath),
+				PreserveHost: kong.Bool(c.DefaultRoutePreserveHost),
+			},
+			CredentialTypeKey: c.CredentialTypeKey,
+			Resync:            resync.events,
+			ConfigChanges:     configChanges,
+		},
+		MaxConcurrentReconciles: c.reconcileConcurrency("Secret"),
+		CacheSyncTimeout:        c.CacheSyncTimeout,
+	}).SetupWithManager(mgr); err != nil {
+		return fmt.Errorf("unable to create controller Secret: %w", err)
+	}
+
+	ingressAPI, err := selectIngressAPI(c.IngressAPI, discoveryClient, setupLog)
+	if err != nil {
+		return err
+	}
+	if err := kongctrl.SetupIngressControllers(mgr, ingressAPI, configSecretReconciler("Ingress")); err != nil {
+		return fmt.Errorf("unable to create Ingress controllers: %w", err)
+	}
+	enablement = append(enablement, optionController("IngressStatus", publishService != nil))
+	if publishService != nil {
+		if err := (&kongctrl.IngressStatusReconciler{
+			Client: mgr.GetClient(),
+			Log:    ctrl.Log.WithName("controllers").WithName("IngressStatus"),
+			Scheme: mgr.GetScheme(),
+
+			PublishService:   *publishService,
+			IngressAPI:       ingressAPI,
+			CacheSyncTimeout: c.CacheSyncTimeout,
+		}).SetupWithManager(mgr); err != nil {
+			return fmt.Errorf("unable to create controller IngressStatus: %w", err)
+		}
+	}
+
+	if !controllerEnabled(enablement, "UDPIngress") {
+		setupLog.Info("UDPIngress controller is disabled")
+	} else {
+		if err = (&kongctrl.KongV1UDPIngressReconciler{
+			ConfigSecretReconciler: configSecretReconciler("UDPIngress"),
+		}).SetupWithManager(mgr); err != nil {
+			return fmt.Errorf("unable to create controller UDPIngress: %w", err)
+		}
+	}
+
+	// IngressClass is a built-in API, which clusters older than Kubernetes 1.19 don't serve
+	// TODO - reconsider short circuiting this controller before we release KIC 2.0.
+	// SEE: https://github.com/Kong/kubernetes-ingress-controller/issues/1101
+	ingressClassAvailable, err := kongctrl.IsAPIAvailable(mgr, &netv1.IngressClass{})
+	if !controllerEnabled(enablement, "IngressClass") {
+		setupLog.Info("IngressClass controller is disabled by --feature-gates")
+	} else if !ingressClassAvailable {
+		setupLog.Error(err, "API networking.k8s.io/v1/IngressClass is not available, skipping controller")
+		skipController(enablement, "IngressClass")
+	} else {
+		if err = (&kongctrl.IngressClassReconciler{
+			Client:        mgr.GetClient(),
+			Log:           ctrl.Log.WithName("controllers").WithName("IngressClass"),
+			Scheme:        mgr.GetScheme(),
+			ConfigChanged: configChanges,
+
+			MaxConcurrentReconciles: c.reconcileConcurrency("IngressClass"),
+			CacheSyncTimeout:        c.CacheSyncTimeout,
+		}).SetupWithManager(mgr); err != nil {
+			return fmt.Errorf("unable to create controller IngressClass: %w", err)
+		}
+	}
+
+	if !useHTTPRoutes {
+		setupLog.Info("HTTPRoute controller is disabled")
+	} else {
+		if err = (&kongctrl.HTTPRouteReconciler{
+			ConfigSecretReconciler: configSecretReconciler("HTTPRoute"),
+		}).SetupWithManager(mgr); err != nil {
+			return fmt.Errorf("unable to create controller HTTPRoute: %w", err)
+		}
+	}
+
+	//+kubebuilder:scaffold:builder
+
+	reportControllerStates(enablement, setupLog)
+
+	// liveness stays a ping so the manager isn't restarted during transient Kong outages,
+	// while readiness reflects whether configuration can be pushed to Kong
+	if err := mgr.AddHealthzCheck("health", healthz.Ping); err != nil {
+		return fmt.Errorf("unable to set up health check: %w", err)
+	}
+	kongClients := []sendconfig.AdminAPIClient{kongConfig.Client}
+	for _, endpoint := range kongConfig.AdditionalEndpoints {
+		kongClients = append(kongClients, endpoint.Client)
+	}
+	if err := mgr.AddReadyzCheck("kong", newKongReadinessCheck(kongClients).Check); err != nil {
+		return fmt.Errorf("unable to set up ready check: %w", err)
+	}
+
+	setupLog.Info("starting manager")
+	startErr := mgr.Start(ctx)
+
+	// don't leave Kong partially updated: wait for pushes still in progress, also when the manager failed
+	setupLog.Info("waiting for in-flight configuration pushes", "grace period", c.ShutdownGracePeriod)
+	drainCtx, cancel := context.WithTimeout(context.Background(), c.ShutdownGracePeriod)
+	defer cancel()
+	drainErr := kongConfig.InFlight.Drain(drainCtx)
+	if startErr != nil {
+		if drainErr != nil {
+			setupLog.Error(drainErr, "configuration pushes still in progress at shutdown")
+		}
+		return startErr
+	}
+	return drainErr
+}
+
+// reconcileConcurrencyKinds are the kinds of the controllers whose concurrency
+// can be overridden with --reconcile-concurrency-override.
+var reconcileConcurrencyKinds = []string{"HTTPRoute", "Ingress", "IngressClass", "KongClusterPlugin", "KongConsumer",
+	"KongIngress", "KongPlugin", "Secret", "UDPIngress"}
+
+// reconcileConcurrency returns how many objects of the given kind are reconciled in parallel.
+func (c *Config) reconcileConcurrency(kind string) int {
+	if n, ok := c.ReconcileConcurrencyOverrides[kind]; ok {
+		return n
+	}
+	return c.ReconcileConcurrency
+}
+
+// validateReconcileConcurrency checks the reconcile concurrency and its overrides.
+func validateReconcileConcurrency(c *Config) error {
+	if c.ReconcileConcurrency < 1 {
+		return fmt.Errorf("--reconcile-concurrency (%d) cannot be less than 1", c.ReconcileConcurrency)
+	}
+	for kind, n := range c.ReconcileConcurrencyOverrides {
+		known := false
+		for _, k := range reconcileConcurrencyKinds {
+			known = known || k == kind
+		}
+		if !known {
+			return fmt.Errorf("--reconcile-concurrency-override: unknown kind %q, must be one of %s",
+				kind, strings.Join(reconcileConcurrencyKinds, ", "))
+		}
+		if n < 1 {
+			return fmt.Errorf("--reconcile-concurrency-override: concurrency of %s (%d) cannot be less than 1", kind, n)
+		}
+	}
+	return nil
+}
+
+// identity returns the identity of the controller, named by --controller-name.
+func (c *Config) identity() controllers.Identity {
+	return controllers.Identity{Name: c.ControllerName}
+}
+
+// filterTags returns the tags marking the Kong entities owned by the controller: those set by --kong-filter-tag,
+// or else the filter tag of its identity.
+func (c *Config) filterTags() []string {
+	if len(c.FilterTags) > 0 {
+		return c.FilterTags
+	}
+	return []string{c.identity().FilterTag()}
+}
+
+// configSecretFieldManager returns the field manager the configuration secret is applied under, or an empty
+// string if it is not to be ensured with server-side apply.
+func (c *Config) configSecretFieldManager() string {
+	if !c.UseServerSideApply {
+		return ""
+	}
+	return c.identity().FieldManager()
+}
+
+// managerOptions returns the options of the controller manager set by the flags, but for the cache.
+func (c *Config) managerOptions() ctrl.Options {
+	return ctrl.Options{
+		Scheme:                  scheme,
+		MetricsBindAddress:      c.MetricsAddr,
+		Port:                    9443,
+		HealthProbeBindAddress:  c.ProbeAddr,
+		LeaderElection:          c.EnableLeaderElection,
+		LeaderElectionID:        c.identity().LeaderElectionID(),
+		LeaderElectionNamespace: c.LeaderElectionNamespace,
+		LeaseDuration:           &c.LeaseDuration,
+		RenewDeadline:           &c.RenewDeadline,
+		RetryPeriod:             &c.RetryPeriod,
+	}
+}
+
+// validateKongAdmin checks the settings of the Admin API client the configuration is pushed with.
+func validateKongAdmin(c *Config) error {
+	if c.Concurrency < 1 {
+		return fmt.Errorf("--kong-concurrency (%d) cannot be less than 1", c.Concurrency)
+	}
+	if err := sendconfig.ValidateFilterTags(c.filterTags()); err != nil {
+		return fmt.Errorf("invalid --kong-filter-tag: %w", err)
+	}
+	return nil
+}
+
+// validateLeaderElection checks the leader election durations are consistent.
+func validateLeaderElection(c *Config) error {
+	if c.RenewDeadline >= c.LeaseDuration {
+		return fmt.Errorf("--leader-elect-renew-deadline (%s) must be less than --leader-elect-lease-duration (%s)",
+			c.RenewDeadline, c.LeaseDuration)
+	}
+	return nil
+}
+
+// appendMissing appends to list the values it does not contain yet.
+func appendMissing(list []string, values ...string) []string {
+	result := append([]string{}, list...)
+	for _, value := range values {
+		found := false
+		for _, existing := range result {
+			if existing == value {
+				found = true
+				break
+			}
+		}
+		if !found {
+			result = append(result, value)
+		}
+	}
+	return result
+}
+
+// getKongAdminToken returns the Kong Admin API token configured either inline or through a file.
+// The token file is read only once, when the manager starts.
+func getKongAdminToken(c *Config) (string, error) {
+	if c.KongAdminToken != "" && c.KongAdminTokenPath != "" {
+		return "", fmt.Errorf("both --kong-admin-token and --kong-admin-token-file are set; please remove one or the other")
+	}
+	if c.KongAdminTokenPath != "" {
+		token, err := ioutil.ReadFile(c.KongAdminTokenPath)
+		if err != nil {
+			return "", fmt.Errorf("failed to read kong-admin-token from path '%s': %w", c.KongAdminTokenPath, err)
+		}
+		return strings.TrimSpace(string(token)), nil
+	}
+	return c.KongAdminToken, nil
+}
+
+//+kubebuilder:rbac:groups=core,resources=endpoints,verbs=get
+
+// makeKongConfig builds the configuration used to push to Kong, with one
+// Admin API client per URL the manager was configured with, all of them sharing
+// the --kong-concurrency limit on in-flight requests. When a workspace
+// is configured, it is ensured to exist and the clients are scoped to it.
+// With --kong-db-mode=auto, the configuration is pushed in the DB mode of the
+// Kong at the first URL. The calls to a URL naming a Service are routed to one
+// of its ready pods, looked up with reader. With --kong-admin-init-retry, every
+// endpoint is waited for, within a single deadline, before it is used.
+func makeKongConfig(ctx context.Context, c *Config, httpClient *http.Client, reader client.Reader,
+	log logr.Logger) (sendconfig.Kong, error) {
+	if len(c.KongURLs) == 0 {
+		return sendconfig.Kong{}, fmt.Errorf("at least one Kong Admin API URL is required")
+	}
+
+	// a single limit is shared by the clients of every endpoint; calls waiting
+	// for a retry don't hold on to their slot
+	httpClient = sendconfig.LimitConcurrency(httpClient, c.Concurrency)
+	httpClient = sendconfig.RetryRequests(httpClient, c.KongAdminRetries)
+	httpClient = sendconfig.KeepErrorBodies(httpClient)
+
+	var waitCtx context.Context
+	if c.KongAdminInitRetry > 0 {
+		var cancel context.CancelFunc
+		waitCtx, cancel = context.WithTimeout(ctx, c.KongAdminInitRetry)
+		defer cancel()
+	}
+
+	var endpoints []sendconfig.Endpoint
+	var dbMode string
+	for i, url := range c.KongURLs {
+		url := url
+		endpointClient := httpClient
+		service, ok, err := adminapi.ParseServiceRef(url)
+		if err != nil {
+			return sendconfig.Kong{}, err
+		}
+		if ok {
+			url = service.URL()
+			endpointClient = (&adminapi.ServiceResolver{Reader: reader, Service: service}).HTTPClient(httpClient)
+		}
+		kongClient, err := kong.NewClient(&url, endpointClient)
+		if err != nil {
+			return sendconfig.Kong{}, fmt.Errorf("unable to create kongClient for %s: %w", url, err)
+		}
+		if waitCtx != nil {
+			if err := adminapi.WaitForKong(waitCtx, kongClient, log.WithValues("url", url)); err != nil {
+				log.Error(err, "giving up waiting for kong", "url", url, "initRetry", c.KongAdminInitRetry.String())
+				return sendconfig.Kong{}, fmt.Errorf("kong at %s not reachable within %s: %w", url, c.KongAdminInitRetry, err)
+			}
+		}
+		if i == 0 {
+			// every endpoint is expected to run in the same mode as the first one
+			dbMode, err = adminapi.ResolveDBMode(ctx, kongClient, c.KongDBMode)
+			if err != nil {
+				return sendconfig.Kong{}, fmt.Errorf("unable to detect the DB mode of kong at %s: %w", url, err)
+			}
+		}
+		if c.KongWorkspace != "" {
+			if err := sendconfig.EnsureWorkspace(ctx, kongClient, c.KongWorkspace); err != nil {
+				return sendconfig.Kong{}, fmt.Errorf("unable to ensure workspace in kong at %s: %w", url, err)
+			}
+			kongClient, err = kong.NewClient(kong.String(url+"/"+c.KongWorkspace), endpointClient)
+			if err != nil {
+				return sendconfig.Kong{}, fmt.Errorf("unable to create kongClient for %s: %w", url, err)
+			}
+		}
+		endpoints = append(endpoints, sendconfig.Endpoint{URL: url, Client: kongClient})
+	}
+
+	if c.NoDelete && dbMode == adminapi.DBModeDBLess {
+		return sendconfig.Kong{}, fmt.Errorf("--no-delete requires kong to be backed by a database, "+
+			"kong at %s runs DB-less", endpoints[0].URL)
+	}
+	if c.ManagedEntityTypes != nil && dbMode == adminapi.DBModeDBLess {
+		return sendconfig.Kong{}, fmt.Errorf("--managed-entity-types requires kong to be backed by a database, "+
+			"kong at %s runs DB-less", endpoints[0].URL)
+	}
+
+	return sendconfig.Kong{
+		URL:                 endpoints[0].URL,
+		Client:              endpoints[0].Client,
+		AdditionalEndpoints: endpoints[1:],
+		InMemory:            dbMode == adminapi.DBModeDBLess,
+		DryRun:              c.DryRun,
+		NoDelete:            c.NoDelete,
+		ManagedEntityTypes:  c.ManagedEntityTypes,
+		FilterTags:          c.filterTags(),
+		Concurrency:         c.Concurrency,
+		InFlight:            &sendconfig.InFlight{},
+	}, nil
+}
+
+// detectKongVersion sets the version of the Kong at the first URL on kongConfig. If it can't be fetched
+// or parsed, the zero version is set, with which no version-gated feature is used, and an error returned.
+func detectKongVersion(ctx context.Context, kongConfig *sendconfig.Kong) error {
+	var reported string
+	root, err := kongConfig.Client.Root(ctx)
+	if err != nil {
+		err = fmt.Errorf("fetching the version of kong: %w", err)
+	} else {
+		reported, _ = root["version"].(string)
+	}
+	if parseErr := kongConfig.SetVersion(reported); err == nil {
+		err = parseErr
+	}
+	return err
+}
+
+// endpointSlicesEnabled tells whether upstream targets are to be assembled from EndpointSlices,
+// as set with --use-endpointslices, detecting whether the cluster serves them when set to auto.
+func endpointSlicesEnabled(c *Config, mgr ctrl.Manager, log logr.Logger) (bool, error) {
+	if c.UseEndpointSlices == "false" {
+		return false, nil
+	}
+	available, err := kongctrl.IsAPIAvailable(mgr, &discoveryv1beta1.EndpointSlice{})
+	if err != nil {
+		return false, fmt.Errorf("unable to detect whether EndpointSlices are available: %w", err)
+	}
+	if !available {
+		if c.UseEndpointSlices == "true" {
+			return false, fmt.Errorf("--use-endpointslices is set but API discovery.k8s.io/v1beta1/EndpointSlice is not available")
+		}
+		log.Info("API discovery.k8s.io/v1beta1/EndpointSlice is not available, using Endpoints")
+		return false, nil
+	}
+	log.Info("assembling upstream targets from EndpointSlices")
+	return true, nil
+}
+
+// serviceDefaults converts the --default-service-* flags into the defaults of the parser.
+func (c *Config) serviceDefaults() (parser.ServiceDefaults, error) {
+	var (
+		defaults parser.ServiceDefaults
+		err      error
+	)
+	if defaults.ConnectTimeout, err = timeoutMillis("--default-service-connect-timeout", c.DefaultServiceConnectTimeout); err != nil {
+		return parser.ServiceDefaults{}, err
+	}
+	if defaults.WriteTimeout, err = timeoutMillis("--default-service-write-timeout", c.DefaultServiceWriteTimeout); err != nil {
+		return parser.ServiceDefaults{}, err
+	}
+	if defaults.ReadTimeout, err = timeoutMillis("--default-service-read-timeout", c.DefaultServiceReadTimeout); err != nil {
+		return parser.ServiceDefaults{}, err
+	}
+	return defaults, nil
+}
+
+// publishService parses --publish-service, returning nil if it is unset.
+func (c *Config) publishService() (*types.NamespacedName, error) {
+	if c.PublishService == "" {
+		return nil, nil
+	}
+	namespace, name, err := util.ParseNameNS(c.PublishService)
+	if err != nil || namespace == "" || name == "" {
+		return nil, fmt.Errorf("--publish-service (%q) must be of the form namespace/name", c.PublishService)
+	}
+	return &types.NamespacedName{Namespace: namespace, Name: name}, nil
+}
+
+// timeoutMillis converts the timeout given with flag into the milliseconds Kong expects.
+func timeoutMillis(flag string, timeout time.Duration) (int, error) {
+	if timeout < time.Millisecond {
+		return 0, fmt.Errorf("%s (%s) cannot be less than 1ms", flag, timeout)
+	}
+	return int(timeout / time.Millisecond), nil
+}
+
+//+kubebuilder:rbac:groups=core,resources=namespaces,verbs=get
+
+// validateConfigSecretNamespace checks that the namespace of the configuration secret exists,
+// as the secret can't be created otherwise and no configuration would ever be pushed to Kong.
+func validateConfigSecretNamespace(ctx context.Context, reader client.Reader, namespace string) error {
+	if err := reader.Get(ctx, client.ObjectKey{Name: namespace}, &corev1.Namespace{}); err != nil {
+		if apierrors.IsNotFound(err) {
+			return fmt.Errorf("--config-secret-namespace %q does not exist", namespace)
+		}
+		return fmt.Errorf("unable to check that --config-secret-namespace %q exists: %w", namespace, err)
+	}
+	return nil
+}
+
+// parseConfigSecretOwner parses the value of --config-secret-owner, resource[.group]/name, into the resource and
+// the name of the owner of the configuration secret.
+func parseConfigSecretOwner(owner string) (schema.GroupResource, string, error) {
+	split := strings.Split(owner, "/")
+	if len(split) != 2 || split[0] == "" || split[1] == "" {
+		return schema.GroupResource{}, "", fmt.Errorf("--config-secret-owner (%q) must be of the form resource[.group]/name", owner)
+	}
+	return schema.ParseGroupResource(split[0]), split[1], nil
+}
+
+// resolveConfigSecretOwner returns the reference to the object named by --config-secret-owner in namespace, the
+// namespace of the configuration secret, as owners of namespaced objects must be in their namespace.
+func resolveConfigSecretOwner(ctx context.Context, reader client.Reader, mapper meta.RESTMapper, namespace, owner string) (*metav1.OwnerReference, error) {
+	resource, name, err := parseConfigSecretOwner(owner)
+	if err != nil {
+		return nil, err
+	}
+	gvk, err := mapper.KindFor(resource.WithVersion(""))
+	if err != nil {
+		return nil, fmt.Errorf("unknown resource %q of --config-secret-owner: %w", resource, err)
+	}
+	mapping, err := mapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+	if err != nil {
+		return nil, fmt.Errorf("unknown resource %q of --config-secret-owner: %w", resource, err)
+	}
+	if mapping.Scope.Name() != meta.RESTScopeNameNamespace {
+		return nil, fmt.Errorf("--config-secret-owner %s must be a namespaced object", owner)
+	}
+
+	obj := new(unstructured.Unstructured)
+	obj.SetGroupVersionKind(gvk)
+	if err := reader.Get(ctx, types.NamespacedName{Namespace: namespace, Name: name}, obj); err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil, fmt.Errorf("--config-secret-owner %s does not exist in namespace %q", owner, namespace)
+		}
+		return nil, fmt.Errorf("unable to get --config-secret-owner %s: %w", owner, err)
+	}
+	return &metav1.OwnerReference{
+		APIVersion: gvk.GroupVersion().String(),
+		Kind:       gvk.Kind,
+		Name:       obj.GetName(),
+		UID:        obj.GetUID(),
+	}, nil
+}
+
+// leaderElectionResources are the resources the leader election lock of the manager is stored in.
+var leaderElectionResources = []schema.GroupResource{
+	{Resource: "configmaps"},
+	{Group: "coordination.k8s.io", Resource: "leases"},
+}
+
+// validateLeaderElectionNamespace checks that the namespace of the leader election lock exists and that
+// the controller may manage the lock there, as the manager would otherwise only fail once started.
+func validateLeaderElectionNamespace(ctx context.Context, reader client.Reader, writer client.Writer, namespace string) error {
+	if err := reader.Get(ctx, client.ObjectKey{Name: namespace}, &corev1.Namespace{}); err != nil {
+		if apierrors.IsNotFound(err) {
+			return fmt.Errorf("--leader-election-namespace %q does not exist", namespace)
+		}
+		return fmt.Errorf("unable to check that --leader-election-namespace %q exists: %w", namespace, err)
+	}
+	for _, resource := range leaderElectionResources {
+		for _, verb := range []string{"get", "create", "update"} {
+			review := &authorizationv1.SelfSubjectAccessReview{
+				Spec: authorizationv1.SelfSubjectAccessReviewSpec{
+					ResourceAttributes: &authorizationv1.ResourceAttributes{
+						Namespace: namespace,
+						Verb:      verb,
+						Group:     resource.Group,
+						Resource:  resource.Resource,
+					},
+				},
+			}
+			if err := writer.Create(ctx, review); err != nil {
+				return fmt.Errorf("unable to check the permissions in --leader-election-namespace %q: %w", namespace, err)
+			}
+			if !review.Status.Allowed {
+				return fmt.Errorf("the controller is not allowed to %s %s in --leader-election-namespace %q", verb, resource, namespace)
+			}
+		}
+	}
+	return nil
+}
+
+// validateIngressAPI checks the value of --ingress-api.
+func validateIngressAPI(ingressAPI string) error {
+	values := []string{"auto"}
+	for _, gv := range kongctrl.IngressAPIs {
+		values = append(values, gv.String())
+	}
+	for _, value := range values {
+		if ingressAPI == value {
+			return nil
+		}
+	}
+	return fmt.Errorf("--ingress-api (%q) must be one of %s", ingressAPI, strings.Join(values, ", "))
+}
+
+// validateIngressClassNames checks the values of --ingress-class.
+func validateIngressClassNames(names []string) error {
+	if len(names) == 0 {
+		return fmt.Errorf("--ingress-class must name at least one ingress class")
+	}
+	for _, name := range names {
+		if name == "" {
+			return fmt.Errorf("--ingress-class must not contain an empty ingress class")
+		}
+	}
+	return nil
+}
+
+// selectIngressAPI returns the version of the Ingress API to reconcile as requested with --ingress-api,
+// which must be served by the cluster. With "auto", the newest version served is picked.
+func selectIngressAPI(ingressAPI string, d discovery.DiscoveryInterface, log logr.Logger) (schema.GroupVersion, error) {
+	served, err := kongctrl.ServedIngressAPIs(d)
+	if err != nil {
+		return schema.GroupVersion{}, fmt.Errorf("unable to detect the Ingress APIs served by the cluster: %w", err)
+	}
+	if len(served) == 0 {
+		return schema.GroupVersion{}, fmt.Errorf("the cluster serves none of the supported Ingress APIs")
+	}
+	selected := served[0]
+	if ingressAPI != "auto" {
+		found := false
+		for _, gv := range served {
+			if gv.String() == ingressAPI {
+				selected, found = gv, true
+			}
+		}
+		if !found {
+			return schema.GroupVersion{}, fmt.Errorf("--ingress-api is set to %s, which the cluster does not serve", ingressAPI)
+		}
+	}
+	servedNames := make([]string, 0, len(served))
+	for _, gv := range served {
+		servedNames = append(servedNames, gv.String())
+	}
+	log.Info("selected the Ingress API to reconcile, Ingresses of the other served versions are converted to it",
+		"api", selected.String(), "requested", ingressAPI, "served", servedNames)
+	return selected, nil
+}
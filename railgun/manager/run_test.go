@@ -0,0 +1,471 @@
+package manager
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/kong/deck/file"
+	"github.com/kong/go-kong/kong"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	appsv1 "k8s.io/api/apps/v1"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	fakediscovery "k8s.io/client-go/discovery/fake"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	clienttesting "k8s.io/client-go/testing"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/kong/kubernetes-ingress-controller/pkg/sendconfig"
+)
+
+func TestGetKongAdminToken(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kong-admin-token")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	tokenPath := filepath.Join(dir, "token")
+	assert.NoError(t, ioutil.WriteFile(tokenPath, []byte("file-token\n"), 0600))
+
+	tests := []struct {
+		name    string
+		config  Config
+		want    string
+		wantErr bool
+	}{
+		{
+			name:   "no token configured",
+			config: Config{},
+			want:   "",
+		},
+		{
+			name:   "inline token",
+			config: Config{KongAdminToken: "inline-token"},
+			want:   "inline-token",
+		},
+		{
+			name:   "token read from file",
+			config: Config{KongAdminTokenPath: tokenPath},
+			want:   "file-token",
+		},
+		{
+			name:    "token file does not exist",
+			config:  Config{KongAdminTokenPath: filepath.Join(dir, "missing")},
+			wantErr: true,
+		},
+		{
+			name:    "both inline token and token file",
+			config:  Config{KongAdminToken: "inline-token", KongAdminTokenPath: tokenPath},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := getKongAdminToken(&tt.config)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestValidateLeaderElection(t *testing.T) {
+	assert.NoError(t, validateLeaderElection(&Config{LeaseDuration: 15 * time.Second, RenewDeadline: 10 * time.Second}))
+	assert.Error(t, validateLeaderElection(&Config{LeaseDuration: 10 * time.Second, RenewDeadline: 10 * time.Second}))
+	assert.Error(t, validateLeaderElection(&Config{LeaseDuration: 10 * time.Second, RenewDeadline: 15 * time.Second}))
+}
+
+func TestManagerOptions(t *testing.T) {
+	c := &Config{}
+	flagSet := MakeFlagSetFor(c)
+	assert.NoError(t, flagSet.Parse([]string{
+		"--leader-elect",
+		"--leader-election-namespace=kong-leases",
+		"--watch-namespace=default",
+	}))
+	opts := c.managerOptions()
+	assert.True(t, opts.LeaderElection)
+	assert.Equal(t, "kong-leases", opts.LeaderElectionNamespace)
+	assert.Equal(t, 15*time.Second, *opts.LeaseDuration)
+}
+
+func TestControllerIdentity(t *testing.T) {
+	tests := []struct {
+		name                 string
+		args                 []string
+		wantFilterTags       []string
+		wantLeaderElectionID string
+		wantFieldManager     string
+	}{
+		{
+			name:                 "defaults",
+			wantFilterTags:       []string{"managed-by-railgun"},
+			wantLeaderElectionID: "5b374a9e.konghq.com",
+		},
+		{
+			name:                 "custom name",
+			args:                 []string{"--controller-name=kong-canary", "--use-server-side-apply"},
+			wantFilterTags:       []string{"managed-by-kong-canary"},
+			wantLeaderElectionID: "kong-canary.konghq.com",
+			wantFieldManager:     "kong-canary",
+		},
+		{
+			name:                 "custom name and tags",
+			args:                 []string{"--controller-name=kong-canary", "--kong-filter-tag=team-a,team-b"},
+			wantFilterTags:       []string{"team-a", "team-b"},
+			wantLeaderElectionID: "kong-canary.konghq.com",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &Config{}
+			assert.NoError(t, MakeFlagSetFor(c).Parse(tt.args))
+			assert.NoError(t, c.identity().Validate())
+			assert.Equal(t, tt.wantFilterTags, c.filterTags())
+			assert.Equal(t, tt.wantLeaderElectionID, c.managerOptions().LeaderElectionID)
+			assert.Equal(t, tt.wantFieldManager, c.configSecretFieldManager())
+		})
+	}
+}
+
+// reviewClient allows the access reviewed by SelfSubjectAccessReviews to the resources it lists.
+type reviewClient struct {
+	client.Client
+	allowed map[string]bool
+}
+
+func (c *reviewClient) Create(ctx context.Context, obj client.Object, opts ...client.CreateOption) error {
+	review, ok := obj.(*authorizationv1.SelfSubjectAccessReview)
+	if !ok {
+		return c.Client.Create(ctx, obj, opts...)
+	}
+	review.Status.Allowed = c.allowed[review.Spec.ResourceAttributes.Resource]
+	return nil
+}
+
+func TestValidateLeaderElectionNamespace(t *testing.T) {
+	c := fake.NewClientBuilder().WithScheme(clientgoscheme.Scheme).
+		WithObjects(&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "kong-leases"}}).Build()
+	allowed := &reviewClient{Client: c, allowed: map[string]bool{"configmaps": true, "leases": true}}
+	assert.NoError(t, validateLeaderElectionNamespace(context.Background(), c, allowed, "kong-leases"))
+	assert.EqualError(t, validateLeaderElectionNamespace(context.Background(), c, allowed, "missing"),
+		`--leader-election-namespace "missing" does not exist`)
+
+	denied := &reviewClient{Client: c, allowed: map[string]bool{"configmaps": true}}
+	assert.EqualError(t, validateLeaderElectionNamespace(context.Background(), c, denied, "kong-leases"),
+		`the controller is not allowed to get leases.coordination.k8s.io in --leader-election-namespace "kong-leases"`)
+}
+
+func TestValidateKongAdmin(t *testing.T) {
+	tests := []struct {
+		name        string
+		concurrency int
+		filterTags  []string
+		wantErr     string
+	}{
+		{name: "minimal concurrency", concurrency: 1, filterTags: []string{"managed-by-railgun"}},
+		{name: "several tags", concurrency: 10, filterTags: []string{"managed-by-railgun", "team-a"}},
+		{name: "zero concurrency", concurrency: 0, filterTags: []string{"managed-by-railgun"},
+			wantErr: "--kong-concurrency (0) cannot be less than 1"},
+		{name: "negative concurrency", concurrency: -1, filterTags: []string{"managed-by-railgun"},
+			wantErr: "--kong-concurrency (-1) cannot be less than 1"},
+		{name: "no tags, named after the controller", concurrency: 1},
+		{name: "empty tag", concurrency: 1, filterTags: []string{"managed-by-railgun", ""},
+			wantErr: "invalid --kong-filter-tag: tags cannot be empty"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateKongAdmin(&Config{Concurrency: tt.concurrency, FilterTags: tt.filterTags})
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestMakeKongConfigDBMode(t *testing.T) {
+	content := &file.Content{
+		FormatVersion: "1.1",
+		Services: []file.FService{{
+			Service: kong.Service{Name: kong.String("default.foo.80"), Host: kong.String("foo.default.80.svc")},
+		}},
+	}
+
+	tests := []struct {
+		name         string
+		mode         string
+		database     string
+		wantInMemory bool
+	}{
+		{name: "auto with a DB-less kong", mode: "auto", database: "off", wantInMemory: true},
+		{name: "auto with a database", mode: "auto", database: "postgres"},
+		{name: "dbless override", mode: "dbless", database: "postgres", wantInMemory: true},
+		{name: "db override", mode: "db", database: "off"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var lock sync.Mutex
+			var pushes []string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				switch {
+				case r.Method == http.MethodGet && r.URL.Path == "/":
+					_, _ = w.Write([]byte(`{"version":"2.4.1","configuration":{"database":"` + tt.database + `"}}`))
+				case r.Method == http.MethodGet:
+					_, _ = w.Write([]byte(`{"data":[],"next":null}`))
+				default:
+					lock.Lock()
+					pushes = append(pushes, r.Method+" "+r.URL.Path)
+					lock.Unlock()
+					// the entities created are echoed back, as Kong does
+					body, _ := ioutil.ReadAll(r.Body)
+					w.WriteHeader(http.StatusCreated)
+					_, _ = w.Write(body)
+				}
+			}))
+			defer server.Close()
+
+			kongConfig, err := makeKongConfig(context.Background(), &Config{
+				KongURLs:    []string{server.URL},
+				KongDBMode:  tt.mode,
+				Concurrency: 1,
+				FilterTags:  []string{"managed-by-railgun"},
+			}, server.Client(), nil, logr.Discard())
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantInMemory, kongConfig.InMemory)
+
+			_, err = sendconfig.PerformUpdate(context.Background(), logrus.New(), &kongConfig, kongConfig.InMemory, false,
+				content, kongConfig.FilterTags, nil, nil)
+			assert.NoError(t, err)
+			if tt.wantInMemory {
+				assert.Equal(t, []string{"POST /config"}, pushes)
+			} else {
+				// the service is created on its own
+				assert.Len(t, pushes, 1)
+				assert.Contains(t, pushes[0], " /services")
+			}
+		})
+	}
+}
+
+func TestDetectKongVersion(t *testing.T) {
+	tests := []struct {
+		name        string
+		root        string
+		status      int
+		wantVersion string
+		wantErr     bool
+	}{
+		{name: "release", root: `{"version":"2.4.1"}`, wantVersion: "2.4.1"},
+		{name: "pre-release", root: `{"version":"2.5.0-rc.1"}`, wantVersion: "2.5.0-rc1"},
+		{name: "enterprise", root: `{"version":"2.3.3.0-enterprise-edition"}`, wantVersion: "2.3.3-0-enterprise"},
+		{name: "unparseable", root: `{"version":"next"}`, wantVersion: "0.0.0", wantErr: true},
+		{name: "missing", root: `{}`, wantVersion: "0.0.0", wantErr: true},
+		{name: "unreachable", status: http.StatusInternalServerError, wantVersion: "0.0.0", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if tt.status != 0 {
+					w.WriteHeader(tt.status)
+					return
+				}
+				w.Header().Set("Content-Type", "application/json")
+				_, _ = w.Write([]byte(tt.root))
+			}))
+			defer server.Close()
+			client, err := kong.NewClient(kong.String(server.URL), server.Client())
+			assert.NoError(t, err)
+
+			kongConfig := sendconfig.Kong{URL: server.URL, Client: client}
+			err = detectKongVersion(context.Background(), &kongConfig)
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, tt.wantVersion, kongConfig.Version.String())
+		})
+	}
+}
+
+func TestReconcileConcurrency(t *testing.T) {
+	c := &Config{}
+	flagSet := MakeFlagSetFor(c)
+	assert.NoError(t, flagSet.Parse([]string{
+		"--reconcile-concurrency=4",
+		"--reconcile-concurrency-override=Ingress=16,Secret=1",
+	}))
+	assert.NoError(t, validateReconcileConcurrency(c))
+	assert.Equal(t, 16, c.reconcileConcurrency("Ingress"))
+	assert.Equal(t, 1, c.reconcileConcurrency("Secret"))
+	assert.Equal(t, 4, c.reconcileConcurrency("UDPIngress"))
+
+	assert.Error(t, validateReconcileConcurrency(&Config{ReconcileConcurrency: 0}))
+	assert.Error(t, validateReconcileConcurrency(&Config{
+		ReconcileConcurrency:          1,
+		ReconcileConcurrencyOverrides: map[string]int{"Ingres": 2},
+	}))
+	assert.Error(t, validateReconcileConcurrency(&Config{
+		ReconcileConcurrency:          1,
+		ReconcileConcurrencyOverrides: map[string]int{"Ingress": 0},
+	}))
+}
+
+func TestValidateConfigSecretNamespace(t *testing.T) {
+	reader := fake.NewClientBuilder().WithScheme(clientgoscheme.Scheme).
+		WithObjects(&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "kong"}}).Build()
+	assert.NoError(t, validateConfigSecretNamespace(context.Background(), reader, "kong"))
+	assert.EqualError(t, validateConfigSecretNamespace(context.Background(), reader, "missing"),
+		`--config-secret-namespace "missing" does not exist`)
+}
+
+func TestResolveConfigSecretOwner(t *testing.T) {
+	ctx := context.Background()
+	reader := fake.NewClientBuilder().WithScheme(clientgoscheme.Scheme).
+		WithObjects(
+			&appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Namespace: "kong", Name: "ingress-kong", UID: "b2df61dd"}},
+			&corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Namespace: "kong", Name: "kong-config-owner", UID: "a1b2c3d4"}},
+		).Build()
+	mapper := meta.NewDefaultRESTMapper(nil)
+	mapper.Add(appsv1.SchemeGroupVersion.WithKind("Deployment"), meta.RESTScopeNamespace)
+	mapper.Add(corev1.SchemeGroupVersion.WithKind("ConfigMap"), meta.RESTScopeNamespace)
+	mapper.Add(corev1.SchemeGroupVersion.WithKind("Namespace"), meta.RESTScopeRoot)
+
+	owner, err := resolveConfigSecretOwner(ctx, reader, mapper, "kong", "deployments.apps/ingress-kong")
+	assert.NoError(t, err)
+	assert.Equal(t, &metav1.OwnerReference{APIVersion: "apps/v1", Kind: "Deployment", Name: "ingress-kong", UID: "b2df61dd"}, owner)
+
+	owner, err = resolveConfigSecretOwner(ctx, reader, mapper, "kong", "configmaps/kong-config-owner")
+	assert.NoError(t, err)
+	assert.Equal(t, &metav1.OwnerReference{APIVersion: "v1", Kind: "ConfigMap", Name: "kong-config-owner", UID: "a1b2c3d4"}, owner)
+
+	_, err = resolveConfigSecretOwner(ctx, reader, mapper, "default", "configmaps/kong-config-owner")
+	assert.EqualError(t, err, `--config-secret-owner configmaps/kong-config-owner does not exist in namespace "default"`)
+	_, err = resolveConfigSecretOwner(ctx, reader, mapper, "kong", "namespaces/kong")
+	assert.EqualError(t, err, "--config-secret-owner namespaces/kong must be a namespaced object")
+	_, err = resolveConfigSecretOwner(ctx, reader, mapper, "kong", "widgets/kong")
+	assert.Error(t, err)
+	_, err = resolveConfigSecretOwner(ctx, reader, mapper, "kong", "ingress-kong")
+	assert.EqualError(t, err, `--config-secret-owner ("ingress-kong") must be of the form resource[.group]/name`)
+}
+
+func TestSelectIngressAPI(t *testing.T) {
+	d := &fakediscovery.FakeDiscovery{Fake: &clienttesting.Fake{Resources: []*metav1.APIResourceList{
+		{GroupVersion: "networking.k8s.io/v1beta1", APIResources: []metav1.APIResource{{Name: "ingresses"}}},
+		{GroupVersion: "extensions/v1beta1", APIResources: []metav1.APIResource{{Name: "ingresses"}}},
+	}}}
+	tests := []struct {
+		name       string
+		ingressAPI string
+		want       string
+		wantErr    string
+	}{
+		{
+			name:       "auto picks the newest served version",
+			ingressAPI: "auto",
+			want:       "networking.k8s.io/v1beta1",
+		},
+		{
+			name:       "served version",
+			ingressAPI: "extensions/v1beta1",
+			want:       "extensions/v1beta1",
+		},
+		{
+			name:       "version not served",
+			ingressAPI: "networking.k8s.io/v1",
+			wantErr:    "--ingress-api is set to networking.k8s.io/v1, which the cluster does not serve",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.NoError(t, validateIngressAPI(tt.ingressAPI))
+			got, err := selectIngressAPI(tt.ingressAPI, d, logr.Discard())
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got.String())
+		})
+	}
+
+	assert.EqualError(t, validateIngressAPI("v1"),
+		`--ingress-api ("v1") must be one of auto, networking.k8s.io/v1, networking.k8s.io/v1beta1, extensions/v1beta1`)
+}
+
+func TestValidateIngressClassNames(t *testing.T) {
+	assert.NoError(t, validateIngressClassNames([]string{"kong"}))
+	assert.NoError(t, validateIngressClassNames([]string{"kong", "kong-internal"}))
+	assert.Error(t, validateIngressClassNames(nil))
+	assert.Error(t, validateIngressClassNames([]string{"kong", ""}))
+}
+
+func TestPublishService(t *testing.T) {
+	publishService, err := (&Config{}).publishService()
+	assert.NoError(t, err)
+	assert.Nil(t, publishService)
+
+	publishService, err = (&Config{PublishService: "kong/kong-proxy"}).publishService()
+	assert.NoError(t, err)
+	assert.Equal(t, &types.NamespacedName{Namespace: "kong", Name: "kong-proxy"}, publishService)
+
+	for _, invalid := range []string{"kong-proxy", "kong/", "/kong-proxy", "kong/kong-proxy/extra"} {
+		_, err := (&Config{PublishService: invalid}).publishService()
+		assert.Error(t, err, invalid)
+	}
+}
+
+func TestSelectIngressAPIAuto(t *testing.T) {
+	ingresses := []metav1.APIResource{{Name: "ingresses"}}
+	extV1beta1 := &metav1.APIResourceList{GroupVersion: "extensions/v1beta1", APIResources: ingresses}
+	netV1beta1 := &metav1.APIResourceList{GroupVersion: "networking.k8s.io/v1beta1", APIResources: ingresses}
+	netV1 := &metav1.APIResourceList{GroupVersion: "networking.k8s.io/v1", APIResources: ingresses}
+
+	tests := []struct {
+		resources []*metav1.APIResourceList
+		want      string
+	}{
+		{resources: []*metav1.APIResourceList{extV1beta1, netV1beta1, netV1}, want: "networking.k8s.io/v1"},
+		{resources: []*metav1.APIResourceList{netV1beta1, netV1}, want: "networking.k8s.io/v1"},
+		{resources: []*metav1.APIResourceList{extV1beta1, netV1}, want: "networking.k8s.io/v1"},
+		{resources: []*metav1.APIResourceList{netV1}, want: "networking.k8s.io/v1"},
+		{resources: []*metav1.APIResourceList{extV1beta1, netV1beta1}, want: "networking.k8s.io/v1beta1"},
+		{resources: []*metav1.APIResourceList{netV1beta1}, want: "networking.k8s.io/v1beta1"},
+		{resources: []*metav1.APIResourceList{extV1beta1}, want: "extensions/v1beta1"},
+		{resources: nil, want: ""},
+	}
+	for _, tt := range tests {
+		var served []string
+		for _, list := range tt.resources {
+			served = append(served, list.GroupVersion)
+		}
+		t.Run(strings.Join(served, ","), func(t *testing.T) {
+			d := &fakediscovery.FakeDiscovery{Fake: &clienttesting.Fake{Resources: tt.resources}}
+			got, err := selectIngressAPI("auto", d, logr.Discard())
+			if tt.want == "" {
+				assert.EqualError(t, err, "the cluster serves none of the supported Ingress APIs")
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got.String())
+		})
+	}
+}
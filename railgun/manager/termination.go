@@ -0,0 +1,36 @@
+package manager
+
+import (
+	"io/ioutil"
+	"unicode/utf8"
+)
+
+// DefaultTerminationLogPath is the path Kubernetes reads the termination message of containers from,
+// unless their terminationMessagePath says otherwise.
+const DefaultTerminationLogPath = "/dev/termination-log"
+
+// maxTerminationMessageSize is the size Kubernetes truncates termination messages to.
+const maxTerminationMessageSize = 4096
+
+// WriteTerminationMessage writes the error the manager exited with to the termination log at path, for
+// Kubernetes to report it in the status of the terminated container, truncated to the size Kubernetes
+// keeps. Nothing is written if path is empty.
+func WriteTerminationMessage(path string, err error) error {
+	if path == "" || err == nil {
+		return nil
+	}
+	return ioutil.WriteFile(path, []byte(truncateTerminationMessage(err.Error())), 0644)
+}
+
+// truncateTerminationMessage truncates msg to maxTerminationMessageSize bytes, without splitting a
+// character.
+func truncateTerminationMessage(msg string) string {
+	if len(msg) <= maxTerminationMessageSize {
+		return msg
+	}
+	cut := maxTerminationMessageSize
+	for cut > 0 && !utf8.RuneStart(msg[cut]) {
+		cut--
+	}
+	return msg[:cut]
+}
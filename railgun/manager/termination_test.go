@@ -0,0 +1,48 @@
+package manager
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteTerminationMessage(t *testing.T) {
+	dir, err := ioutil.TempDir("", "termination-log")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	t.Run("the error Run exits with is written", func(t *testing.T) {
+		path := filepath.Join(dir, "fatal")
+		var c Config
+		MakeFlagSetFor(&c)
+		c.KongURLs = nil
+
+		runErr := Run(context.Background(), &c)
+		require.Error(t, runErr)
+		require.NoError(t, WriteTerminationMessage(path, runErr))
+		content, err := ioutil.ReadFile(path)
+		require.NoError(t, err)
+		assert.Equal(t, "--kong-url must name at least one Kong Admin API URL", string(content))
+	})
+
+	t.Run("long errors are truncated to the size kubernetes keeps", func(t *testing.T) {
+		path := filepath.Join(dir, "long")
+		require.NoError(t, WriteTerminationMessage(path, errors.New(strings.Repeat("é", maxTerminationMessageSize))))
+		content, err := ioutil.ReadFile(path)
+		require.NoError(t, err)
+		assert.Len(t, content, maxTerminationMessageSize)
+		assert.True(t, utf8.Valid(content))
+	})
+
+	t.Run("nothing is written without a path", func(t *testing.T) {
+		assert.NoError(t, WriteTerminationMessage("", errors.New("boom")))
+	})
+}
@@ -0,0 +1,81 @@
+package utils
+
+import (
+	"sync"
+
+	"github.com/kong/kubernetes-ingress-controller/pkg/annotations"
+)
+
+// IngressClassController is the value of spec.controller in the IngressClasses handled by Kong.
+const IngressClassController = "ingress-controllers.konghq.com/kong"
+
+// IngressClassNames are the ingress classes the controllers watch for in the
+// kubernetes.io/ingress.class annotation of Ingresses; an Ingress matching any of
+// them is managed. The first one is the class of the controller wherever it must
+// name a single class itself.
+var IngressClassNames = []string{annotations.DefaultIngressClass}
+
+// IsIngressClassName reports whether class is one of IngressClassNames.
+func IsIngressClassName(class string) bool {
+	for _, name := range IngressClassNames {
+		if class == name {
+			return true
+		}
+	}
+	return false
+}
+
+// IngressClasses holds the IngressClasses controlled by Kong which exist in the cluster.
+var IngressClasses = NewIngressClassSet()
+
+// IngressClassParameters are the per-class settings read from the parameters
+// an IngressClass references.
+type IngressClassParameters struct {
+	// DefaultPlugins are the KongPlugins applied to the Ingresses of the class
+	// which don't set the konghq.com/plugins annotation themselves.
+	DefaultPlugins string
+}
+
+// IngressClassSet tracks the IngressClasses controlled by Kong along with their parameters.
+//
+// Any number of IngressClasses can name Kong as their controller. An Ingress uses the
+// parameters of the class named by its spec.ingressClassName or, lacking one, of the class
+// named by its kubernetes.io/ingress.class annotation; parameters of distinct classes are
+// never merged, and settings made on the Ingress itself always win over those of its class.
+type IngressClassSet struct {
+	lock    sync.RWMutex
+	classes map[string]IngressClassParameters
+}
+
+// NewIngressClassSet creates an empty IngressClassSet.
+func NewIngressClassSet() *IngressClassSet {
+	return &IngressClassSet{classes: make(map[string]IngressClassParameters)}
+}
+
+// Set records the IngressClass name with its parameters, returning whether they changed.
+func (s *IngressClassSet) Set(name string, params IngressClassParameters) bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if current, ok := s.classes[name]; ok && current == params {
+		return false
+	}
+	s.classes[name] = params
+	return true
+}
+
+// Delete forgets the IngressClass name, returning whether it was recorded.
+func (s *IngressClassSet) Delete(name string) bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	_, ok := s.classes[name]
+	delete(s.classes, name)
+	return ok
+}
+
+// Get returns the parameters of the IngressClass name, if it is controlled by Kong.
+func (s *IngressClassSet) Get(name string) (IngressClassParameters, bool) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	params, ok := s.classes[name]
+	return params, ok
+}
@@ -0,0 +1,186 @@
+package manager
+
+import (
+	"fmt"
+	"net/url"
+
+	"github.com/kong/kubernetes-ingress-controller/pkg/sendconfig"
+	"github.com/kong/kubernetes-ingress-controller/railgun/pkg/adminapi"
+)
+
+// Validate checks the configuration as a whole, before the manager starts, so that invalid values
+// and combinations of flags are reported with the flags to fix rather than midway through startup.
+func (c *Config) Validate() error {
+	if _, err := c.zapOptions(); err != nil {
+		return err
+	}
+
+	// Kubernetes and controllers
+	if err := c.identity().Validate(); err != nil {
+		return fmt.Errorf("invalid --controller-name: %w", err)
+	}
+	if err := validateLeaderElection(c); err != nil {
+		return err
+	}
+	if c.CacheSyncTimeout <= 0 {
+		return fmt.Errorf("--cache-sync-timeout (%s) must be positive", c.CacheSyncTimeout)
+	}
+	if err := validateIngressClassNames(c.IngressClassNames); err != nil {
+		return err
+	}
+	if _, err := c.publishService(); err != nil {
+		return err
+	}
+	if err := validateIngressAPI(c.IngressAPI); err != nil {
+		return err
+	}
+	switch c.UseEndpointSlices {
+	case "auto", "true", "false":
+	default:
+		return fmt.Errorf("--use-endpointslices (%q) must be one of auto, true or false", c.UseEndpointSlices)
+	}
+	if err := validateReconcileConcurrency(c); err != nil {
+		return err
+	}
+	if _, err := resolveControllerEnablement(c.FeatureGates); err != nil {
+		return err
+	}
+	if c.SecretSizeWarningRatio <= 0 || c.SecretSizeWarningRatio > 1 {
+		return fmt.Errorf("--config-secret-size-warning-ratio (%v) must be greater than 0 and at most 1", c.SecretSizeWarningRatio)
+	}
+	if c.ConfigSecretOwner != "" {
+		if _, _, err := parseConfigSecretOwner(c.ConfigSecretOwner); err != nil {
+			return err
+		}
+	}
+
+	// Kong Admin API
+	if err := validateKongURLs(c.KongURLs); err != nil {
+		return err
+	}
+	if err := validateKongAdmin(c); err != nil {
+		return err
+	}
+	if err := validateKongAdminTLS(&c.KongAdminAPIConfig); err != nil {
+		return err
+	}
+	if _, err := adminapi.ParseHeaders(c.KongAdminAPIConfig.Headers); err != nil {
+		return err
+	}
+	if c.KongAdminToken != "" && c.KongAdminTokenPath != "" {
+		return fmt.Errorf("both --kong-admin-token and --kong-admin-token-file are set; please remove one or the other")
+	}
+	if c.KongAdminRetries.MaxRetries < 0 {
+		return fmt.Errorf("--kong-admin-max-retries (%d) cannot be negative", c.KongAdminRetries.MaxRetries)
+	}
+	if c.KongAdminInitRetry < 0 {
+		return fmt.Errorf("--kong-admin-init-retry (%s) cannot be negative", c.KongAdminInitRetry)
+	}
+	if c.KongWorkspace != "" {
+		if err := sendconfig.ValidateWorkspaceName(c.KongWorkspace); err != nil {
+			return fmt.Errorf("invalid --kong-workspace: %w", err)
+		}
+	}
+	if err := adminapi.ValidateDBMode(c.KongDBMode); err != nil {
+		return fmt.Errorf("invalid --kong-db-mode: %w", err)
+	}
+	if c.NoDelete && c.KongDBMode == adminapi.DBModeDBLess {
+		return fmt.Errorf("--no-delete requires kong to be backed by a database; it cannot be used with --kong-db-mode=%s",
+			adminapi.DBModeDBLess)
+	}
+	if c.ManagedEntityTypes != nil {
+		if err := sendconfig.ValidateManagedEntityTypes(c.ManagedEntityTypes); err != nil {
+			return fmt.Errorf("invalid --managed-entity-types: %w", err)
+		}
+		if c.KongDBMode == adminapi.DBModeDBLess {
+			return fmt.Errorf("--managed-entity-types requires kong to be backed by a database; "+
+				"it cannot be used with --kong-db-mode=%s", adminapi.DBModeDBLess)
+		}
+	}
+
+	// configuration pushes
+	if c.SyncPeriod < 0 {
+		return fmt.Errorf("--sync-period (%s) cannot be negative", c.SyncPeriod)
+	}
+	if c.SyncRetryDelay < 0 {
+		return fmt.Errorf("--sync-retry-delay (%s) cannot be negative", c.SyncRetryDelay)
+	}
+	if c.SyncRetryJitter < 0 {
+		return fmt.Errorf("--sync-retry-jitter (%s) cannot be negative", c.SyncRetryJitter)
+	}
+	if c.CredentialTypeKey == "" {
+		return fmt.Errorf("--credential-type-key cannot be empty")
+	}
+	if _, err := c.serviceDefaults(); err != nil {
+		return err
+	}
+	return nil
+}
+
+// Warnings returns the problems of the configuration which don't prevent the manager from starting:
+// combinations of flags where one of them has no effect, which are accepted for compatibility.
+func (c *Config) Warnings() []string {
+	var warnings []string
+	if c.LeaderElectionNamespace != "" && !c.EnableLeaderElection {
+		warnings = append(warnings, "--leader-election-namespace is ignored as leader election is disabled; "+
+			"please set --leader-elect")
+	}
+	opts := &c.KongAdminAPIConfig
+	if opts.TLSSkipVerify && (opts.CACertPath != "" || opts.CACert != "") {
+		warnings = append(warnings, "--kong-admin-ca-cert-file and --kong-admin-ca-cert are ignored as "+
+			"--kong-admin-tls-skip-verify disables the verification of the certificate of Kong")
+	}
+	if opts.CACertReloadInterval < 0 {
+		warnings = append(warnings, fmt.Sprintf("--kong-admin-ca-cert-reload-interval (%s) is negative; "+
+			"the CA certificate is not reloaded", opts.CACertReloadInterval))
+	}
+	if opts.CACertReloadInterval > 0 && opts.CACertPath == "" {
+		warnings = append(warnings, "--kong-admin-ca-cert-reload-interval is ignored without --kong-admin-ca-cert-file")
+	}
+	return warnings
+}
+
+// validateKongURLs checks the values of --kong-url are absolute HTTP(S) URLs or name a Service.
+func validateKongURLs(urls []string) error {
+	if len(urls) == 0 {
+		return fmt.Errorf("--kong-url must name at least one Kong Admin API URL")
+	}
+	for _, kongURL := range urls {
+		if _, ok, err := adminapi.ParseServiceRef(kongURL); ok {
+			if err != nil {
+				return fmt.Errorf("invalid --kong-url: %w", err)
+			}
+			continue
+		}
+		u, err := url.Parse(kongURL)
+		if err != nil {
+			return fmt.Errorf("invalid --kong-url %q: %w", kongURL, err)
+		}
+		if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("invalid --kong-url %q: must be an http or https URL, e.g. http://localhost:8001", kongURL)
+		}
+	}
+	return nil
+}
+
+// validateKongAdminTLS checks the TLS settings of the Admin API client don't conflict.
+func validateKongAdminTLS(opts *adminapi.HTTPClientOpts) error {
+	if opts.CACertPath != "" && opts.CACert != "" {
+		return fmt.Errorf("both --kong-admin-ca-cert-file and --kong-admin-ca-cert are set; please remove one or the other")
+	}
+	if opts.TLSClientCertPath != "" && opts.TLSClientCert != "" {
+		return fmt.Errorf("both --kong-admin-tls-client-cert-file and --kong-admin-tls-client-cert are set; " +
+			"please remove one or the other")
+	}
+	if opts.TLSClientKeyPath != "" && opts.TLSClientKey != "" {
+		return fmt.Errorf("both --kong-admin-tls-client-key-file and --kong-admin-tls-client-key are set; " +
+			"please remove one or the other")
+	}
+	hasCert := opts.TLSClientCertPath != "" || opts.TLSClientCert != ""
+	hasKey := opts.TLSClientKeyPath != "" || opts.TLSClientKey != ""
+	if hasCert != hasKey {
+		return fmt.Errorf("the client certificate and its private key must be set together: " +
+			"--kong-admin-tls-client-cert(-file) requires --kong-admin-tls-client-key(-file) and vice versa")
+	}
+	return nil
+}
@@ -0,0 +1,10 @@
+package manager
+
+// Release and Commit identify the build of the manager. They are set at build time with
+// -ldflags "-X github.com/kong/kubernetes-ingress-controller/railgun/manager.Release=...".
+var (
+	// Release is the release version.
+	Release = "UNKNOWN"
+	// Commit is the short sha from git.
+	Commit = "UNKNOWN"
+)
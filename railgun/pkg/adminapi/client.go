@@ -0,0 +1,346 @@
+package adminapi
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+)
+
+// HTTPClientOpts defines parameters that configure an HTTP client.
+type HTTPClientOpts struct {
+	// Disable verification of TLS certificate of Kong's Admin endpoint.
+	TLSSkipVerify bool
+	// SNI name to use to verify the certificate presented by Kong in TLS.
+	TLSServerName string
+	// Path to PEM-encoded CA certificate file to verify Kong's Admin SSL certificate.
+	CACertPath string
+	// Interval at which CACertPath is read again, to pick up a rotated CA. Disabled if zero.
+	CACertReloadInterval time.Duration
+	// PEM-encoded CA certificate to verify Kong's Admin SSL certificate.
+	CACert string
+	// Path to the PEM-encoded client certificate presented to Kong's Admin API.
+	TLSClientCertPath string
+	// PEM-encoded client certificate presented to Kong's Admin API.
+	TLSClientCert string
+	// Path to the PEM-encoded private key of the client certificate.
+	TLSClientKeyPath string
+	// PEM-encoded private key of the client certificate.
+	TLSClientKey string
+	// Array of headers added to every Admin API call, each in the key:value form
+	// parsed by ParseHeaders.
+	Headers []string
+	// User-Agent header of every Admin API call, unless set by Headers.
+	UserAgent string
+	// URL of the proxy Admin API calls go through. If empty, the proxy is taken from the
+	// HTTP_PROXY, HTTPS_PROXY and NO_PROXY environment variables.
+	ProxyURL string
+	// TraceLogger, when set, logs every Admin API call.
+	TraceLogger logr.Logger
+}
+
+// MakeHTTPClient returns an HTTP client with the specified mTLS/headers configuration.
+func MakeHTTPClient(opts *HTTPClientOpts) (*http.Client, error) {
+	headers, err := ParseHeaders(opts.Headers)
+	if err != nil {
+		return nil, err
+	}
+
+	var tlsConfig tls.Config
+
+	if opts.TLSSkipVerify {
+		tlsConfig.InsecureSkipVerify = true //nolint:gosec
+	}
+
+	if opts.TLSServerName != "" {
+		tlsConfig.ServerName = opts.TLSServerName
+	}
+
+	if opts.CACertPath != "" && opts.CACert != "" {
+		return nil, fmt.Errorf("both --kong-admin-ca-cert-file and --kong-admin-ca-cert " +
+			"are set; please remove one or the other")
+	}
+	if opts.CACert != "" {
+		certPool := x509.NewCertPool()
+		ok := certPool.AppendCertsFromPEM([]byte(opts.CACert))
+		if !ok {
+			// TODO give user an error to make this actionable
+			return nil, fmt.Errorf("failed to load kong-admin-ca-cert")
+		}
+		tlsConfig.RootCAs = certPool
+	}
+	var caCert []byte
+	if opts.CACertPath != "" {
+		certPath := opts.CACertPath
+		certPool := x509.NewCertPool()
+		cert, err := ioutil.ReadFile(certPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read kong-admin-ca-cert from path '%s': %w", certPath, err)
+		}
+		ok := certPool.AppendCertsFromPEM(cert)
+		if !ok {
+			// TODO give user an error to make this actionable
+			return nil, fmt.Errorf("failed to load kong-admin-ca-cert from path '%s'", certPath)
+		}
+		tlsConfig.RootCAs = certPool
+		caCert = cert
+	}
+
+	clientCert, err := loadTLSClientCert(opts)
+	if err != nil {
+		return nil, err
+	}
+	if clientCert != nil {
+		tlsConfig.Certificates = []tls.Certificate{*clientCert}
+	}
+
+	var proxy func(*http.Request) (*url.URL, error)
+	if opts.ProxyURL != "" {
+		proxyURL, err := url.Parse(opts.ProxyURL)
+		if err != nil {
+			return nil, fmt.Errorf("invalid --kong-admin-proxy-url: %w", err)
+		}
+		switch proxyURL.Scheme {
+		case "http", "https", "socks5":
+		default:
+			return nil, fmt.Errorf("invalid --kong-admin-proxy-url %q: the scheme must be http, https or socks5", opts.ProxyURL)
+		}
+		proxy = http.ProxyURL(proxyURL)
+	}
+	// newTransport returns a transport verifying the certificate of servers against serverName, if set, rather
+	// than against the host of the URLs called
+	newTransport := func(serverName string) http.RoundTripper {
+		// the default transport honors the proxy environment variables
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		transport.TLSClientConfig = tlsConfig.Clone()
+		if serverName != "" {
+			transport.TLSClientConfig.ServerName = serverName
+		}
+		if proxy != nil {
+			transport.Proxy = proxy
+		}
+		if opts.CACertPath != "" && opts.CACertReloadInterval > 0 {
+			return &caReloadingRoundTripper{
+				path:     opts.CACertPath,
+				interval: opts.CACertReloadInterval,
+				current:  transport,
+				cert:     caCert,
+				checked:  time.Now(),
+			}
+		}
+		return transport
+	}
+	rt := newTransport("")
+	if opts.TLSServerName == "" {
+		// the server name set for the Admin API takes precedence over the one of a request
+		rt = &serverNameRoundTripper{rt: rt, newTransport: newTransport}
+	}
+	if opts.TraceLogger != nil {
+		// traced requests include the injected headers, to be redacted
+		rt = &traceRoundTripper{log: opts.TraceLogger, rt: rt}
+	}
+	return &http.Client{
+		Transport: &headerRoundTripper{
+			userAgent: opts.UserAgent,
+			headers:   headers,
+			rt:        rt,
+		},
+	}, nil
+}
+
+// serverNameKey is the context key of the name the certificate of the server called by a request is verified
+// against, when the host of its URL is not the name the server is known by, e.g. the address of the pod
+// a ServiceResolver routes the request to.
+type serverNameKey struct{}
+
+// withServerName returns ctx, for a request whose server certificate is verified against serverName.
+func withServerName(ctx context.Context, serverName string) context.Context {
+	return context.WithValue(ctx, serverNameKey{}, serverName)
+}
+
+// serverNameRoundTripper makes the requests whose context holds a server name with a transport dedicated to
+// it, made with newTransport, and the other requests with rt. Each transport keeps its own connections.
+type serverNameRoundTripper struct {
+	rt           http.RoundTripper
+	newTransport func(serverName string) http.RoundTripper
+
+	lock       sync.Mutex
+	transports map[string]http.RoundTripper
+}
+
+// RoundTrip satisfies the RoundTripper interface.
+func (t *serverNameRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	serverName, _ := req.Context().Value(serverNameKey{}).(string)
+	if serverName == "" {
+		return t.rt.RoundTrip(req)
+	}
+	t.lock.Lock()
+	rt, ok := t.transports[serverName]
+	if !ok {
+		if t.transports == nil {
+			t.transports = make(map[string]http.RoundTripper)
+		}
+		rt = t.newTransport(serverName)
+		t.transports[serverName] = rt
+	}
+	t.lock.Unlock()
+	return rt.RoundTrip(req)
+}
+
+// caReloadingRoundTripper reads the CA certificate file at path again every interval. When its content
+// changed, the requests that follow are made with a new transport trusting the new CA, while those in flight
+// complete on the previous one, whose idle connections are closed. A file which can't be read or holds no
+// certificate is ignored, so that a rotation in progress doesn't break the calls made with the current CA.
+type caReloadingRoundTripper struct {
+	path     string
+	interval time.Duration
+
+	lock    sync.Mutex
+	current *http.Transport
+	cert    []byte
+	checked time.Time
+}
+
+// RoundTrip satisfies the RoundTripper interface.
+func (t *caReloadingRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	return t.transport().RoundTrip(req)
+}
+
+// CloseIdleConnections closes the idle connections of the current transport.
+func (t *caReloadingRoundTripper) CloseIdleConnections() {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	t.current.CloseIdleConnections()
+}
+
+// transport returns the transport to make the next request with, reloading the CA certificate if due.
+func (t *caReloadingRoundTripper) transport() *http.Transport {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	now := time.Now()
+	if now.Sub(t.checked) < t.interval {
+		return t.current
+	}
+	t.checked = now
+
+	cert, err := ioutil.ReadFile(t.path)
+	if err != nil || bytes.Equal(cert, t.cert) {
+		return t.current
+	}
+	certPool := x509.NewCertPool()
+	if !certPool.AppendCertsFromPEM(cert) {
+		return t.current
+	}
+	// the clone has its own copy of the TLS configuration, so the previous transport is left unchanged
+	next := t.current.Clone()
+	next.TLSClientConfig.RootCAs = certPool
+	t.current.CloseIdleConnections()
+	t.current, t.cert = next, cert
+	return next
+}
+
+// loadTLSClientCert loads the client certificate configured in opts, if any,
+// from either its file or its inline PEM variant.
+func loadTLSClientCert(opts *HTTPClientOpts) (*tls.Certificate, error) {
+	if opts.TLSClientCertPath != "" && opts.TLSClientCert != "" {
+		return nil, fmt.Errorf("both --kong-admin-tls-client-cert-file and --kong-admin-tls-client-cert " +
+			"are set; please remove one or the other")
+	}
+	if opts.TLSClientKeyPath != "" && opts.TLSClientKey != "" {
+		return nil, fmt.Errorf("both --kong-admin-tls-client-key-file and --kong-admin-tls-client-key " +
+			"are set; please remove one or the other")
+	}
+
+	cert := []byte(opts.TLSClientCert)
+	if opts.TLSClientCertPath != "" {
+		var err error
+		if cert, err = ioutil.ReadFile(opts.TLSClientCertPath); err != nil {
+			return nil, fmt.Errorf("failed to read kong-admin-tls-client-cert from path '%s': %w",
+				opts.TLSClientCertPath, err)
+		}
+	}
+	key := []byte(opts.TLSClientKey)
+	if opts.TLSClientKeyPath != "" {
+		var err error
+		if key, err = ioutil.ReadFile(opts.TLSClientKeyPath); err != nil {
+			return nil, fmt.Errorf("failed to read kong-admin-tls-client-key from path '%s': %w",
+				opts.TLSClientKeyPath, err)
+		}
+	}
+
+	switch {
+	case len(cert) == 0 && len(key) == 0:
+		return nil, nil
+	case len(cert) == 0:
+		return nil, fmt.Errorf("a client key is set without a client certificate; " +
+			"both --kong-admin-tls-client-cert(-file) and --kong-admin-tls-client-key(-file) are required")
+	case len(key) == 0:
+		return nil, fmt.Errorf("a client certificate is set without a client key; " +
+			"both --kong-admin-tls-client-cert(-file) and --kong-admin-tls-client-key(-file) are required")
+	}
+	pair, err := tls.X509KeyPair(cert, key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load the kong-admin-tls-client certificate and key: %w", err)
+	}
+	return &pair, nil
+}
+
+// ParseHeaders parses headers given as key:value pairs, as in --kong-admin-header. Each pair is split on its
+// first colon only, so that the value may itself contain colons (e.g. a URL), and the key and value are trimmed
+// of surrounding whitespace. A pair without a colon or with an empty key is rejected. Keys are kept as given
+// rather than canonicalized; when a key is repeated, the last value wins.
+func ParseHeaders(pairs []string) (http.Header, error) {
+	headers := make(http.Header, len(pairs))
+	for _, pair := range pairs {
+		split := strings.SplitN(pair, ":", 2)
+		if len(split) != 2 {
+			return nil, fmt.Errorf("invalid --kong-admin-header %q: must be key:value", pair)
+		}
+		key := strings.TrimSpace(split[0])
+		if key == "" {
+			return nil, fmt.Errorf("invalid --kong-admin-header %q: the header name is empty", pair)
+		}
+		headers[key] = []string{strings.TrimSpace(split[1])}
+	}
+	return headers, nil
+}
+
+// UserAgent returns the default User-Agent of the Admin API calls of the given build of the controller.
+func UserAgent(release, commit string) string {
+	return fmt.Sprintf("kong-ingress-controller/%s (%s)", release, commit)
+}
+
+// headerRoundTripper injects the User-Agent and Headers into requests
+// made via RT.
+type headerRoundTripper struct {
+	userAgent string
+	headers   http.Header
+	rt        http.RoundTripper
+}
+
+// RoundTrip satisfies the RoundTripper interface.
+func (t *headerRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	newRequest := new(http.Request)
+	*newRequest = *req
+	newRequest.Header = make(http.Header, len(req.Header))
+	for k, s := range req.Header {
+		newRequest.Header[k] = append([]string(nil), s...)
+	}
+	if t.userAgent != "" {
+		newRequest.Header.Set("User-Agent", t.userAgent)
+	}
+	for k, s := range t.headers {
+		newRequest.Header[k] = append([]string(nil), s...)
+	}
+	return t.rt.RoundTrip(newRequest)
+}
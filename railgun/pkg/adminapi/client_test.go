@@ -0,0 +1,362 @@
+package adminapi
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMakeHTTPClientInjectsHeaders(t *testing.T) {
+	var got http.Header
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header.Clone()
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	client, err := MakeHTTPClient(&HTTPClientOpts{
+		Headers: []string{"kong-admin-token:my-token", "X-Foo:bar", "X-Url: http://example.com:8080/"},
+	})
+	assert.NoError(t, err)
+
+	resp, err := client.Get(server.URL)
+	assert.NoError(t, err)
+	resp.Body.Close()
+
+	assert.Equal(t, "my-token", got.Get("Kong-Admin-Token"))
+	assert.Equal(t, "bar", got.Get("X-Foo"))
+	assert.Equal(t, "http://example.com:8080/", got.Get("X-Url"))
+}
+
+func TestParseHeaders(t *testing.T) {
+	tests := []struct {
+		name    string
+		pairs   []string
+		want    http.Header
+		wantErr string
+	}{
+		{
+			name: "none",
+			want: http.Header{},
+		},
+		{
+			name:  "value with colons",
+			pairs: []string{"X-Forwarded-Url:https://example.com:8443/admin", "X-Timestamp: 2021-04-01T12:30:00Z"},
+			want: http.Header{
+				"X-Forwarded-Url": {"https://example.com:8443/admin"},
+				"X-Timestamp":     {"2021-04-01T12:30:00Z"},
+			},
+		},
+		{
+			name:  "surrounding whitespace",
+			pairs: []string{"  kong-admin-token :  my-token  "},
+			want:  http.Header{"kong-admin-token": {"my-token"}},
+		},
+		{
+			name:  "empty value",
+			pairs: []string{"X-Empty:"},
+			want:  http.Header{"X-Empty": {""}},
+		},
+		{
+			name:  "repeated key",
+			pairs: []string{"X-Foo:bar", "X-Foo:baz"},
+			want:  http.Header{"X-Foo": {"baz"}},
+		},
+		{
+			name:    "no colon",
+			pairs:   []string{"X-Foo:bar", "X-Foo=bar"},
+			wantErr: `invalid --kong-admin-header "X-Foo=bar": must be key:value`,
+		},
+		{
+			name:    "empty key",
+			pairs:   []string{" :bar"},
+			wantErr: `invalid --kong-admin-header " :bar": the header name is empty`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseHeaders(tt.pairs)
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestMakeHTTPClientRejectsMalformedHeaders(t *testing.T) {
+	_, err := MakeHTTPClient(&HTTPClientOpts{Headers: []string{"kong-admin-token"}})
+	assert.EqualError(t, err, `invalid --kong-admin-header "kong-admin-token": must be key:value`)
+}
+
+func TestMakeHTTPClientSetsUserAgent(t *testing.T) {
+	var got http.Header
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header.Clone()
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name string
+		opts HTTPClientOpts
+		want string
+	}{
+		{
+			name: "default user agent",
+			opts: HTTPClientOpts{UserAgent: UserAgent("1.3.0", "abc1234")},
+			want: "kong-ingress-controller/1.3.0 (abc1234)",
+		},
+		{
+			name: "header takes precedence",
+			opts: HTTPClientOpts{UserAgent: UserAgent("1.3.0", "abc1234"), Headers: []string{"User-Agent:custom"}},
+			want: "custom",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, err := MakeHTTPClient(&tt.opts)
+			assert.NoError(t, err)
+
+			resp, err := client.Get(server.URL)
+			assert.NoError(t, err)
+			resp.Body.Close()
+
+			assert.Equal(t, tt.want, got.Get("User-Agent"))
+		})
+	}
+}
+
+func TestMakeHTTPClientUsesProxy(t *testing.T) {
+	var proxied []string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		proxied = append(proxied, r.Method+" "+r.Host)
+		if r.Method != http.MethodConnect {
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+		// tunnel to the requested host
+		upstream, err := net.Dial("tcp", r.Host)
+		if err != nil {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		conn, _, err := w.(http.Hijacker).Hijack()
+		if err != nil {
+			upstream.Close()
+			return
+		}
+		go func() {
+			defer upstream.Close()
+			defer conn.Close()
+			go func() { _, _ = io.Copy(upstream, conn) }()
+			_, _ = io.Copy(conn, upstream)
+		}()
+	}))
+	defer proxy.Close()
+
+	t.Run("plain HTTP", func(t *testing.T) {
+		proxied = nil
+		client, err := MakeHTTPClient(&HTTPClientOpts{ProxyURL: proxy.URL})
+		assert.NoError(t, err)
+
+		resp, err := client.Get("http://kong-admin.example:8001/status")
+		assert.NoError(t, err)
+		resp.Body.Close()
+
+		assert.Equal(t, []string{"GET kong-admin.example:8001"}, proxied)
+	})
+
+	t.Run("TLS is verified through the proxy", func(t *testing.T) {
+		proxied = nil
+		server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusOK)
+		}))
+		defer server.Close()
+		serverCA := string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw}))
+
+		client, err := MakeHTTPClient(&HTTPClientOpts{ProxyURL: proxy.URL, CACert: serverCA})
+		assert.NoError(t, err)
+		resp, err := client.Get(server.URL)
+		assert.NoError(t, err)
+		resp.Body.Close()
+		assert.Equal(t, []string{"CONNECT " + server.Listener.Addr().String()}, proxied)
+
+		// without the CA of the server, the TLS handshake through the tunnel fails
+		client, err = MakeHTTPClient(&HTTPClientOpts{ProxyURL: proxy.URL})
+		assert.NoError(t, err)
+		_, err = client.Get(server.URL)
+		assert.Error(t, err)
+	})
+
+	t.Run("invalid proxy URL", func(t *testing.T) {
+		_, err := MakeHTTPClient(&HTTPClientOpts{ProxyURL: "ftp://proxy.example"})
+		assert.Error(t, err)
+	})
+}
+
+func TestMakeHTTPClientRejectsConflictingCACerts(t *testing.T) {
+	_, err := MakeHTTPClient(&HTTPClientOpts{
+		CACert:     "cert",
+		CACertPath: "/path/to/cert",
+	})
+	assert.Error(t, err)
+}
+
+// makeClientCert returns a self-signed client certificate and its key, PEM-encoded.
+func makeClientCert(t *testing.T) (*x509.Certificate, []byte, []byte) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "kong-ingress-controller"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	assert.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	assert.NoError(t, err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	assert.NoError(t, err)
+	return cert,
+		pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+}
+
+func TestMakeHTTPClientPresentsClientCert(t *testing.T) {
+	clientCert, certPEM, keyPEM := makeClientCert(t)
+	clientCAs := x509.NewCertPool()
+	clientCAs.AddCert(clientCert)
+
+	var gotSubject string
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotSubject = r.TLS.PeerCertificates[0].Subject.CommonName
+		w.WriteHeader(http.StatusOK)
+	}))
+	server.TLS = &tls.Config{ClientAuth: tls.RequireAndVerifyClientCert, ClientCAs: clientCAs}
+	server.StartTLS()
+	defer server.Close()
+	serverCA := string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw}))
+
+	dir, err := ioutil.TempDir("", "kong-admin-tls")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	certPath, keyPath := filepath.Join(dir, "tls.crt"), filepath.Join(dir, "tls.key")
+	assert.NoError(t, ioutil.WriteFile(certPath, certPEM, 0600))
+	assert.NoError(t, ioutil.WriteFile(keyPath, keyPEM, 0600))
+
+	tests := []struct {
+		name    string
+		opts    HTTPClientOpts
+		wantErr bool
+	}{
+		{
+			name:    "without a client certificate",
+			opts:    HTTPClientOpts{},
+			wantErr: true,
+		},
+		{
+			name: "client certificate from files",
+			opts: HTTPClientOpts{TLSClientCertPath: certPath, TLSClientKeyPath: keyPath},
+		},
+		{
+			name: "inline client certificate",
+			opts: HTTPClientOpts{TLSClientCert: string(certPEM), TLSClientKey: string(keyPEM)},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotSubject = ""
+			tt.opts.CACert = serverCA
+			client, err := MakeHTTPClient(&tt.opts)
+			assert.NoError(t, err)
+
+			resp, err := client.Get(server.URL)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			resp.Body.Close()
+			assert.Equal(t, "kong-ingress-controller", gotSubject)
+		})
+	}
+}
+
+func TestMakeHTTPClientRejectsIncompleteClientCert(t *testing.T) {
+	_, certPEM, keyPEM := makeClientCert(t)
+	for _, opts := range []HTTPClientOpts{
+		{TLSClientCert: string(certPEM)},
+		{TLSClientKey: string(keyPEM)},
+		{TLSClientCertPath: "/path/to/cert"},
+		{TLSClientCert: string(certPEM), TLSClientCertPath: "/path/to/cert", TLSClientKey: string(keyPEM)},
+	} {
+		opts := opts
+		_, err := MakeHTTPClient(&opts)
+		assert.Error(t, err)
+	}
+}
+
+func TestMakeHTTPClientReloadsCACert(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+	serverCA := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	_, otherCA, _ := makeClientCert(t)
+
+	dir, err := ioutil.TempDir("", "kong-admin-ca")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	caPath := filepath.Join(dir, "ca.crt")
+	assert.NoError(t, ioutil.WriteFile(caPath, otherCA, 0600))
+
+	client, err := MakeHTTPClient(&HTTPClientOpts{CACertPath: caPath, CACertReloadInterval: time.Nanosecond})
+	assert.NoError(t, err)
+	_, err = client.Get(server.URL)
+	assert.Error(t, err, "the server certificate is not signed by the initial CA")
+
+	assert.NoError(t, ioutil.WriteFile(caPath, serverCA, 0600))
+	resp, err := client.Get(server.URL)
+	assert.NoError(t, err)
+	resp.Body.Close()
+
+	// the connection established with the previous CA isn't reused
+	assert.NoError(t, ioutil.WriteFile(caPath, otherCA, 0600))
+	_, err = client.Get(server.URL)
+	assert.Error(t, err)
+
+	// a file without any certificate leaves the current CA in place
+	assert.NoError(t, ioutil.WriteFile(caPath, serverCA, 0600))
+	resp, err = client.Get(server.URL)
+	assert.NoError(t, err)
+	resp.Body.Close()
+	assert.NoError(t, ioutil.WriteFile(caPath, []byte("rotating"), 0600))
+	resp, err = client.Get(server.URL)
+	assert.NoError(t, err)
+	resp.Body.Close()
+}
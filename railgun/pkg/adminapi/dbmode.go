@@ -0,0 +1,51 @@
+package adminapi
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/kong/go-kong/kong"
+)
+
+// DB modes of Kong, deciding how the configuration is pushed to it.
+const (
+	// DBModeAuto detects the DB mode from the database setting Kong reports.
+	DBModeAuto = "auto"
+	// DBModeDB pushes the configuration to a Kong backed by a database with
+	// per-entity Admin API calls.
+	DBModeDB = "db"
+	// DBModeDBLess pushes the configuration to a DB-less Kong as a whole
+	// declarative configuration, posted to /config.
+	DBModeDBLess = "dbless"
+)
+
+// ValidateDBMode returns an error if mode is not one of the DB modes.
+func ValidateDBMode(mode string) error {
+	switch mode {
+	case DBModeAuto, DBModeDB, DBModeDBLess:
+		return nil
+	}
+	return fmt.Errorf("invalid DB mode '%s': must be one of %s, %s or %s", mode, DBModeAuto, DBModeDB, DBModeDBLess)
+}
+
+// ResolveDBMode returns mode, unless it is DBModeAuto, in which case the DB
+// mode of the Kong behind client is detected: a Kong whose database is "off"
+// runs DB-less.
+func ResolveDBMode(ctx context.Context, client *kong.Client, mode string) (string, error) {
+	if mode != DBModeAuto {
+		return mode, nil
+	}
+	root, err := client.Root(ctx)
+	if err != nil {
+		return "", fmt.Errorf("fetching the configuration of kong: %w", err)
+	}
+	configuration, _ := root["configuration"].(map[string]interface{})
+	database, ok := configuration["database"].(string)
+	if !ok {
+		return "", fmt.Errorf("kong did not report its database")
+	}
+	if database == "off" {
+		return DBModeDBLess, nil
+	}
+	return DBModeDB, nil
+}
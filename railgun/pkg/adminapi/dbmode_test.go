@@ -0,0 +1,84 @@
+package adminapi
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/kong/go-kong/kong"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateDBMode(t *testing.T) {
+	for _, mode := range []string{DBModeAuto, DBModeDB, DBModeDBLess} {
+		assert.NoError(t, ValidateDBMode(mode), mode)
+	}
+	for _, mode := range []string{"", "off", "postgres", "DB"} {
+		assert.Error(t, ValidateDBMode(mode), mode)
+	}
+}
+
+func TestResolveDBMode(t *testing.T) {
+	tests := []struct {
+		name      string
+		mode      string
+		root      string
+		want      string
+		wantErr   bool
+		wantFetch bool
+	}{
+		{
+			name:      "auto detects DB-less",
+			mode:      DBModeAuto,
+			root:      `{"configuration":{"database":"off"}}`,
+			want:      DBModeDBLess,
+			wantFetch: true,
+		},
+		{
+			name:      "auto detects a database",
+			mode:      DBModeAuto,
+			root:      `{"configuration":{"database":"postgres"}}`,
+			want:      DBModeDB,
+			wantFetch: true,
+		},
+		{
+			name:      "auto fails without database",
+			mode:      DBModeAuto,
+			root:      `{"configuration":{}}`,
+			wantErr:   true,
+			wantFetch: true,
+		},
+		{
+			name: "db override",
+			mode: DBModeDB,
+			want: DBModeDB,
+		},
+		{
+			name: "dbless override",
+			mode: DBModeDBLess,
+			want: DBModeDBLess,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fetched := false
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				fetched = true
+				_, _ = w.Write([]byte(tt.root))
+			}))
+			defer server.Close()
+			client, err := kong.NewClient(kong.String(server.URL), server.Client())
+			assert.NoError(t, err)
+
+			mode, err := ResolveDBMode(context.Background(), client, tt.mode)
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.want, mode)
+			}
+			assert.Equal(t, tt.wantFetch, fetched)
+		})
+	}
+}
@@ -0,0 +1,226 @@
+package adminapi
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Schemes of the Admin API URLs naming a Kubernetes Service rather than a host.
+const (
+	// ServiceScheme reaches the Admin API of the pods behind a Service over HTTP.
+	ServiceScheme = "svc"
+	// ServiceTLSScheme reaches the Admin API of the pods behind a Service over HTTPS.
+	ServiceTLSScheme = "svcs"
+)
+
+// DefaultServiceRefreshInterval is how long the address of a pod resolved for a Service is used before
+// the endpoints of the Service are checked again.
+const DefaultServiceRefreshInterval = 5 * time.Second
+
+// ServiceRef is a port of a Kubernetes Service whose pods serve the Admin API, as named by an URL of the
+// form svc://namespace/name:port or svcs://namespace/name:port.
+type ServiceRef struct {
+	types.NamespacedName
+	// Port is the number or the name of the port of the Service.
+	Port string
+	// TLS tells whether the Admin API is served over HTTPS.
+	TLS bool
+}
+
+// ParseServiceRef parses rawURL into the Service it names, returning false if it is a plain URL.
+func ParseServiceRef(rawURL string) (ServiceRef, bool, error) {
+	var ref ServiceRef
+	var rest string
+	switch {
+	case strings.HasPrefix(rawURL, ServiceScheme+"://"):
+		rest = strings.TrimPrefix(rawURL, ServiceScheme+"://")
+	case strings.HasPrefix(rawURL, ServiceTLSScheme+"://"):
+		rest = strings.TrimPrefix(rawURL, ServiceTLSScheme+"://")
+		ref.TLS = true
+	default:
+		return ServiceRef{}, false, nil
+	}
+
+	split := strings.Split(rest, "/")
+	if len(split) != 2 {
+		return ServiceRef{}, true, fmt.Errorf("%q must be of the form %s://namespace/name:port", rawURL, ServiceScheme)
+	}
+	ref.Namespace = split[0]
+	colon := strings.LastIndex(split[1], ":")
+	if colon < 0 {
+		return ServiceRef{}, true, fmt.Errorf("%q must be of the form %s://namespace/name:port", rawURL, ServiceScheme)
+	}
+	ref.Name, ref.Port = split[1][:colon], split[1][colon+1:]
+	if ref.Namespace == "" || ref.Name == "" || ref.Port == "" {
+		return ServiceRef{}, true, fmt.Errorf("%q must be of the form %s://namespace/name:port", rawURL, ServiceScheme)
+	}
+	return ref, true, nil
+}
+
+// URL returns the cluster DNS URL of the Service, which identifies it in the configuration of the
+// controller. The calls made to it are routed to one of its pods by ServiceResolver.
+func (ref ServiceRef) URL() string {
+	scheme := "http"
+	if ref.TLS {
+		scheme = "https"
+	}
+	host := ref.Name + "." + ref.Namespace + ".svc"
+	if _, err := strconv.Atoi(ref.Port); err == nil {
+		host = net.JoinHostPort(host, ref.Port)
+	}
+	return scheme + "://" + host
+}
+
+// String returns the URL the Service was named with.
+func (ref ServiceRef) String() string {
+	scheme := ServiceScheme
+	if ref.TLS {
+		scheme = ServiceTLSScheme
+	}
+	return scheme + "://" + ref.Namespace + "/" + ref.Name + ":" + ref.Port
+}
+
+// ServiceResolver routes the Admin API calls made to the URL of a Service to the address of one of its ready
+// pods, looked up from the Endpoints of the Service. The same pod is called as long as it stays ready, its
+// readiness being checked again every RefreshInterval and after any failed call, so that calls follow the
+// pods of the Service as they are replaced.
+type ServiceResolver struct {
+	Reader  client.Reader
+	Service ServiceRef
+	// RefreshInterval defaults to DefaultServiceRefreshInterval if zero.
+	RefreshInterval time.Duration
+
+	lock     sync.Mutex
+	address  string
+	resolved time.Time
+}
+
+// Resolve returns the host:port of the ready pod the calls to the Service are routed to. It fails if the
+// Service has no ready endpoints.
+func (r *ServiceResolver) Resolve(ctx context.Context) (string, error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	interval := r.RefreshInterval
+	if interval == 0 {
+		interval = DefaultServiceRefreshInterval
+	}
+	if r.address != "" && time.Since(r.resolved) < interval {
+		return r.address, nil
+	}
+
+	addresses, err := r.readyAddresses(ctx)
+	if err != nil {
+		r.address = ""
+		return "", err
+	}
+	if len(addresses) == 0 {
+		r.address = ""
+		return "", fmt.Errorf("service %s has no ready endpoints for port %s", r.Service.NamespacedName, r.Service.Port)
+	}
+	// the current pod keeps being called while it is ready
+	address := addresses[0]
+	for _, ready := range addresses {
+		if ready == r.address {
+			address = ready
+			break
+		}
+	}
+	r.address, r.resolved = address, time.Now()
+	return address, nil
+}
+
+// invalidate forgets the resolved address, so that the next call checks the endpoints of the Service again.
+func (r *ServiceResolver) invalidate() {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.address = ""
+}
+
+// readyAddresses returns the sorted host:port addresses of the ready endpoints of the port of the Service.
+func (r *ServiceResolver) readyAddresses(ctx context.Context) ([]string, error) {
+	service := new(corev1.Service)
+	if err := r.Reader.Get(ctx, r.Service.NamespacedName, service); err != nil {
+		return nil, fmt.Errorf("fetching service %s: %w", r.Service.NamespacedName, err)
+	}
+	var portName string
+	found := false
+	for _, port := range service.Spec.Ports {
+		if port.Name == r.Service.Port || strconv.Itoa(int(port.Port)) == r.Service.Port {
+			portName, found = port.Name, true
+			break
+		}
+	}
+	if !found {
+		return nil, fmt.Errorf("service %s has no port %s", r.Service.NamespacedName, r.Service.Port)
+	}
+
+	endpoints := new(corev1.Endpoints)
+	if err := r.Reader.Get(ctx, r.Service.NamespacedName, endpoints); err != nil {
+		return nil, fmt.Errorf("fetching the endpoints of service %s: %w", r.Service.NamespacedName, err)
+	}
+	var addresses []string
+	for _, subset := range endpoints.Subsets {
+		for _, port := range subset.Ports {
+			// the ports of endpoints are named after the ports of their service
+			if port.Name != portName {
+				continue
+			}
+			for _, address := range subset.Addresses {
+				addresses = append(addresses, net.JoinHostPort(address.IP, strconv.Itoa(int(port.Port))))
+			}
+		}
+	}
+	sort.Strings(addresses)
+	return addresses, nil
+}
+
+// HTTPClient returns a client making the calls of httpClient to the pod the Service resolves to.
+func (r *ServiceResolver) HTTPClient(httpClient *http.Client) *http.Client {
+	routed := *httpClient
+	rt := httpClient.Transport
+	if rt == nil {
+		rt = http.DefaultTransport
+	}
+	routed.Transport = &serviceRoundTripper{resolver: r, rt: rt}
+	return &routed
+}
+
+// serviceRoundTripper sends requests to the pod resolver resolves to, via rt.
+type serviceRoundTripper struct {
+	resolver *ServiceResolver
+	rt       http.RoundTripper
+}
+
+// RoundTrip satisfies the RoundTripper interface.
+func (t *serviceRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	address, err := t.resolver.Resolve(req.Context())
+	if err != nil {
+		return nil, fmt.Errorf("unable to route the call to %s: %w", t.resolver.Service, err)
+	}
+	ctx := req.Context()
+	if t.resolver.Service.TLS {
+		// the certificate of the pod is issued for the Service, not for the address it is called at
+		ctx = withServerName(ctx, req.URL.Hostname())
+	}
+	newRequest := req.Clone(ctx)
+	newRequest.URL.Host = address
+	newRequest.Host = ""
+	resp, err := t.rt.RoundTrip(newRequest)
+	if err != nil {
+		// the pod may be gone, another one is picked for the next call
+		t.resolver.invalidate()
+	}
+	return resp, err
+}
@@ -0,0 +1,233 @@
+package adminapi
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestParseServiceRef(t *testing.T) {
+	for _, tt := range []struct {
+		url     string
+		want    ServiceRef
+		wantOK  bool
+		wantURL string
+		wantErr bool
+	}{
+		{url: "http://localhost:8001"},
+		{
+			url:     "svc://kong/kong-admin:8001",
+			want:    ServiceRef{NamespacedName: types.NamespacedName{Namespace: "kong", Name: "kong-admin"}, Port: "8001"},
+			wantOK:  true,
+			wantURL: "http://kong-admin.kong.svc:8001",
+		},
+		{
+			url:     "svcs://kong/kong-admin:admin-tls",
+			want:    ServiceRef{NamespacedName: types.NamespacedName{Namespace: "kong", Name: "kong-admin"}, Port: "admin-tls", TLS: true},
+			wantOK:  true,
+			wantURL: "https://kong-admin.kong.svc",
+		},
+		{url: "svc://kong/kong-admin", wantOK: true, wantErr: true},
+		{url: "svc://kong-admin:8001", wantOK: true, wantErr: true},
+		{url: "svc:///kong-admin:8001", wantOK: true, wantErr: true},
+		{url: "svc://kong/kong-admin:", wantOK: true, wantErr: true},
+	} {
+		t.Run(tt.url, func(t *testing.T) {
+			got, ok, err := ParseServiceRef(tt.url)
+			assert.Equal(t, tt.wantOK, ok)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+			if ok {
+				assert.Equal(t, tt.wantURL, got.URL())
+				assert.Equal(t, tt.url, got.String())
+			}
+		})
+	}
+}
+
+// adminEndpoints returns the Endpoints of the kong/kong-admin Service, with the addresses of the admin port.
+func adminEndpoints(t *testing.T, ready, notReady []string) *corev1.Endpoints {
+	endpoints := &corev1.Endpoints{ObjectMeta: metav1.ObjectMeta{Namespace: "kong", Name: "kong-admin"}}
+	addresses := func(hostPorts []string) ([]corev1.EndpointAddress, int32) {
+		var result []corev1.EndpointAddress
+		var port int
+		for _, hostPort := range hostPorts {
+			host, p, err := net.SplitHostPort(hostPort)
+			require.NoError(t, err)
+			port, err = strconv.Atoi(p)
+			require.NoError(t, err)
+			result = append(result, corev1.EndpointAddress{IP: host})
+		}
+		return result, int32(port)
+	}
+	if len(ready) > 0 {
+		readyAddresses, port := addresses(ready)
+		endpoints.Subsets = append(endpoints.Subsets, corev1.EndpointSubset{
+			Addresses: readyAddresses,
+			Ports:     []corev1.EndpointPort{{Name: "admin", Port: port}, {Name: "proxy", Port: 1}},
+		})
+	}
+	if len(notReady) > 0 {
+		notReadyAddresses, port := addresses(notReady)
+		endpoints.Subsets = append(endpoints.Subsets, corev1.EndpointSubset{
+			NotReadyAddresses: notReadyAddresses,
+			Ports:             []corev1.EndpointPort{{Name: "admin", Port: port}},
+		})
+	}
+	return endpoints
+}
+
+func TestServiceResolver(t *testing.T) {
+	ctx := context.Background()
+	serve := func(name string) *httptest.Server {
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write([]byte(name))
+		}))
+	}
+	first, second := serve("first"), serve("second")
+	defer first.Close()
+	defer second.Close()
+	hostPort := func(server *httptest.Server) string {
+		return server.Listener.Addr().String()
+	}
+
+	service := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "kong", Name: "kong-admin"},
+		Spec: corev1.ServiceSpec{Ports: []corev1.ServicePort{
+			{Name: "proxy", Port: 80},
+			{Name: "admin", Port: 8001},
+		}},
+	}
+	c := fake.NewClientBuilder().WithScheme(clientgoscheme.Scheme).
+		WithObjects(service, adminEndpoints(t, []string{hostPort(first)}, []string{hostPort(second)})).Build()
+	ref, _, err := ParseServiceRef("svc://kong/kong-admin:8001")
+	require.NoError(t, err)
+	resolver := &ServiceResolver{Reader: c, Service: ref}
+	httpClient := resolver.HTTPClient(http.DefaultClient)
+
+	get := func() (string, error) {
+		resp, err := httpClient.Get(ref.URL() + "/status")
+		if err != nil {
+			return "", err
+		}
+		defer resp.Body.Close()
+		var body [16]byte
+		n, _ := resp.Body.Read(body[:])
+		return string(body[:n]), nil
+	}
+
+	// the calls are routed to the ready pod only
+	body, err := get()
+	require.NoError(t, err)
+	assert.Equal(t, "first", body)
+
+	// once the pod is replaced, the calls follow the endpoints of the service
+	existing := new(corev1.Endpoints)
+	require.NoError(t, c.Get(ctx, ref.NamespacedName, existing))
+	existing.Subsets = adminEndpoints(t, []string{hostPort(second)}, nil).Subsets
+	require.NoError(t, c.Update(ctx, existing))
+	resolver.RefreshInterval = 1
+	body, err = get()
+	require.NoError(t, err)
+	assert.Equal(t, "second", body)
+
+	// calls fail clearly while the service has no ready endpoints
+	existing.Subsets = adminEndpoints(t, nil, []string{hostPort(first)}).Subsets
+	require.NoError(t, c.Update(ctx, existing))
+	_, err = get()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "service kong/kong-admin has no ready endpoints for port 8001")
+}
+
+func TestServiceResolverVerifiesServiceCert(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "kong-admin.kong.svc"},
+		DNSNames:              []string{"kong-admin.kong.svc"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+
+	var gotServerName string
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotServerName = r.TLS.ServerName
+		w.WriteHeader(http.StatusOK)
+	}))
+	server.TLS = &tls.Config{Certificates: []tls.Certificate{{Certificate: [][]byte{der}, PrivateKey: key}}}
+	server.StartTLS()
+	defer server.Close()
+
+	service := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "kong", Name: "kong-admin"},
+		Spec:       corev1.ServiceSpec{Ports: []corev1.ServicePort{{Name: "admin", Port: 8444}}},
+	}
+	c := fake.NewClientBuilder().WithScheme(clientgoscheme.Scheme).
+		WithObjects(service, adminEndpoints(t, []string{server.Listener.Addr().String()}, nil)).Build()
+	ref, _, err := ParseServiceRef("svcs://kong/kong-admin:8444")
+	require.NoError(t, err)
+	httpClient, err := MakeHTTPClient(&HTTPClientOpts{
+		CACert: string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})),
+	})
+	require.NoError(t, err)
+	httpClient = (&ServiceResolver{Reader: c, Service: ref}).HTTPClient(httpClient)
+
+	// the certificate is issued for the service, not for the address of the pod
+	resp, err := httpClient.Get(ref.URL() + "/status")
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, "kong-admin.kong.svc", gotServerName)
+
+	// the server name configured for the Admin API takes precedence
+	httpClient, err = MakeHTTPClient(&HTTPClientOpts{
+		CACert:        string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})),
+		TLSServerName: "kong.example.com",
+	})
+	require.NoError(t, err)
+	httpClient = (&ServiceResolver{Reader: c, Service: ref}).HTTPClient(httpClient)
+	_, err = httpClient.Get(ref.URL() + "/status")
+	assert.Error(t, err)
+}
+
+func TestServiceResolverUnknownPort(t *testing.T) {
+	service := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "kong", Name: "kong-admin"},
+		Spec:       corev1.ServiceSpec{Ports: []corev1.ServicePort{{Name: "admin", Port: 8001}}},
+	}
+	c := fake.NewClientBuilder().WithScheme(clientgoscheme.Scheme).WithObjects(service).Build()
+	ref, _, err := ParseServiceRef("svc://kong/kong-admin:admin-tls")
+	require.NoError(t, err)
+	_, err = (&ServiceResolver{Reader: c, Service: ref}).Resolve(context.Background())
+	assert.EqualError(t, err, "service kong/kong-admin has no port admin-tls")
+}
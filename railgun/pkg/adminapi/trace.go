@@ -0,0 +1,87 @@
+package adminapi
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+)
+
+// redactedHeaders are the headers whose values never show up in the trace.
+var redactedHeaders = []string{"Authorization", "Kong-Admin-Token"}
+
+// traceRoundTripper logs every request made via rt along with its outcome.
+// Bodies are never buffered, only their sizes are logged.
+type traceRoundTripper struct {
+	log logr.Logger
+	rt  http.RoundTripper
+}
+
+// RoundTrip satisfies the RoundTripper interface.
+func (t *traceRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	start := time.Now()
+	keysAndValues := []interface{}{
+		"method", req.Method,
+		"path", req.URL.Path,
+		"headers", redactHeaders(req.Header),
+		"request_size", req.ContentLength,
+	}
+
+	resp, err := t.rt.RoundTrip(req)
+	if err != nil {
+		t.log.Error(err, "admin API call failed", append(keysAndValues, "duration", time.Since(start).String())...)
+		return nil, err
+	}
+
+	// the call is only logged once its response was read, so that the size
+	// of the response can be counted as it streams through
+	keysAndValues = append(keysAndValues, "status", resp.StatusCode)
+	resp.Body = &countingBody{
+		ReadCloser: resp.Body,
+		onClose: func(size int64) {
+			t.log.Info("admin API call", append(keysAndValues,
+				"response_size", size,
+				"duration", time.Since(start).String(),
+			)...)
+		},
+	}
+	return resp, nil
+}
+
+// redactHeaders returns a copy of headers with sensitive values replaced.
+func redactHeaders(headers http.Header) http.Header {
+	redacted := headers.Clone()
+	// injected headers are not necessarily in canonical form
+	for key := range redacted {
+		for _, header := range redactedHeaders {
+			if strings.EqualFold(key, header) {
+				redacted[key] = []string{"REDACTED"}
+			}
+		}
+	}
+	return redacted
+}
+
+// countingBody counts the bytes read from a response body and reports the
+// count once the body is closed.
+type countingBody struct {
+	io.ReadCloser
+	size    int64
+	onClose func(size int64)
+	once    sync.Once
+}
+
+func (b *countingBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.size += int64(n)
+	return n, err
+}
+
+func (b *countingBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.once.Do(func() { b.onClose(b.size) })
+	return err
+}
@@ -0,0 +1,61 @@
+package adminapi
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-logr/logr"
+	"github.com/stretchr/testify/assert"
+)
+
+// recordingLogger keeps the key/value pairs of every message logged.
+type recordingLogger struct {
+	logr.Logger
+	entries []map[string]interface{}
+}
+
+func (l *recordingLogger) Info(_ string, keysAndValues ...interface{}) {
+	entry := map[string]interface{}{}
+	for i := 0; i+1 < len(keysAndValues); i += 2 {
+		entry[keysAndValues[i].(string)] = keysAndValues[i+1]
+	}
+	l.entries = append(l.entries, entry)
+}
+
+func TestMakeHTTPClientTracesCalls(t *testing.T) {
+	body := strings.Repeat("x", 4096)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte(body))
+	}))
+	defer server.Close()
+
+	log := &recordingLogger{Logger: logr.Discard()}
+	client, err := MakeHTTPClient(&HTTPClientOpts{
+		Headers:     []string{"kong-admin-token:my-token", "X-Foo:bar"},
+		TraceLogger: log,
+	})
+	assert.NoError(t, err)
+
+	resp, err := client.Post(server.URL+"/config", "application/json", strings.NewReader(`{}`))
+	assert.NoError(t, err)
+	got, err := ioutil.ReadAll(resp.Body)
+	assert.NoError(t, err)
+	assert.NoError(t, resp.Body.Close())
+	assert.Equal(t, body, string(got))
+
+	if assert.Len(t, log.entries, 1) {
+		entry := log.entries[0]
+		assert.Equal(t, "POST", entry["method"])
+		assert.Equal(t, "/config", entry["path"])
+		assert.Equal(t, http.StatusCreated, entry["status"])
+		assert.Equal(t, int64(2), entry["request_size"])
+		assert.Equal(t, int64(len(body)), entry["response_size"])
+		headers := entry["headers"].(http.Header)
+		assert.Equal(t, []string{"REDACTED"}, headers["kong-admin-token"])
+		assert.Equal(t, "bar", headers.Get("X-Foo"))
+	}
+}
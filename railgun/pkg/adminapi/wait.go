@@ -0,0 +1,40 @@
+package adminapi
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/kong/go-kong/kong"
+)
+
+// The delay between two attempts of WaitForKong starts at waitInitialDelay and
+// doubles with every attempt, up to waitMaxDelay.
+var (
+	waitInitialDelay = 500 * time.Millisecond
+	waitMaxDelay     = 10 * time.Second
+)
+
+// WaitForKong calls the root endpoint of the Kong behind client until it answers, retrying with
+// exponential backoff until ctx is done; the deadline of ctx bounds how long Kong is waited for.
+// Failed attempts are logged at debug level, the error of the last one is returned.
+func WaitForKong(ctx context.Context, client *kong.Client, log logr.Logger) error {
+	delay := waitInitialDelay
+	for attempt := 1; ; attempt++ {
+		_, err := client.Root(ctx)
+		if err == nil {
+			return nil
+		}
+		log.V(1).Info("kong admin API not reachable yet, retrying", "attempt", attempt, "retryIn", delay.String(), "error", err.Error())
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("kong admin API not reachable after %d attempts: %w", attempt, err)
+		case <-time.After(delay):
+		}
+		delay *= 2
+		if delay > waitMaxDelay {
+			delay = waitMaxDelay
+		}
+	}
+}
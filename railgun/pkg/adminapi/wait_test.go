@@ -0,0 +1,59 @@
+package adminapi
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/kong/go-kong/kong"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWaitForKong(t *testing.T) {
+	defer func(initial, max time.Duration) { waitInitialDelay, waitMaxDelay = initial, max }(waitInitialDelay, waitMaxDelay)
+	waitInitialDelay, waitMaxDelay = 10*time.Millisecond, 50*time.Millisecond
+
+	// reserve an address which refuses connections until kong starts listening on it
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	addr := listener.Addr().String()
+	require.NoError(t, listener.Close())
+	client, err := kong.NewClient(kong.String("http://"+addr), &http.Client{})
+	require.NoError(t, err)
+
+	t.Run("gives up once the context is done", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		defer cancel()
+		err := WaitForKong(ctx, client, logr.Discard())
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "connection refused")
+	})
+
+	t.Run("succeeds once kong accepts connections", func(t *testing.T) {
+		server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write([]byte(`{"version":"2.4.1"}`))
+		}))
+		started := make(chan struct{})
+		go func() {
+			defer close(started)
+			time.Sleep(100 * time.Millisecond)
+			listener, err := net.Listen("tcp", addr)
+			if !assert.NoError(t, err) {
+				return
+			}
+			server.Listener = listener
+			server.Start()
+		}()
+		defer server.Close()
+		defer func() { <-started }()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		assert.NoError(t, WaitForKong(ctx, client, logr.Discard()))
+	})
+}
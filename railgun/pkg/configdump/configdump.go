@@ -0,0 +1,139 @@
+// Package configdump keeps the last Kong configuration generated by the
+// controller and serves it over HTTP, to help troubleshoot a Kong state which
+// diverges from expectations.
+package configdump
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/kong/deck/file"
+)
+
+// RedactedValue replaces the masked values of a redacted configuration.
+const RedactedValue = "<redacted>"
+
+// redactedFields are the fields of the declarative configuration which may
+// hold credential material: the secrets of consumer credentials, the private
+// keys of certificates and the configuration of plugins, which can be sourced
+// from Secrets.
+var redactedFields = map[string]bool{
+	"client_secret": true,
+	"config":        true,
+	"key":           true,
+	"password":      true,
+	"secret":        true,
+}
+
+// Store holds the last Kong configuration generated by the controller.
+// It is safe for concurrent use.
+type Store struct {
+	lock   sync.RWMutex
+	config *file.Content
+}
+
+// Set records config as the last generated configuration.
+func (s *Store) Set(config *file.Content) {
+	if s == nil {
+		return
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.config = config
+}
+
+// Get returns the last generated configuration, or nil if none was generated yet.
+func (s *Store) Get() *file.Content {
+	if s == nil {
+		return nil
+	}
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	return s.config
+}
+
+// Handler returns an http.Handler serving the configuration held by store as
+// JSON, or a 503 until one is generated. The values of the fields which may
+// hold credential material are masked when the redact query parameter is true.
+// If token isn't empty, requests must present it as a bearer token.
+func Handler(store *Store, token string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if token != "" && !HasBearerToken(r, token) {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			w.Header().Set("Allow", "GET, HEAD")
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		config := store.Get()
+		if config == nil {
+			http.Error(w, "no configuration was generated yet", http.StatusServiceUnavailable)
+			return
+		}
+
+		var body interface{} = config
+		if r.URL.Query().Get("redact") == "true" {
+			var err error
+			if body, err = redact(config); err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+		}
+		data, err := json.MarshalIndent(body, "", "  ")
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write(data)
+	})
+}
+
+// HasBearerToken tells whether r presents token as a bearer token, as the requests to the debug
+// endpoints must.
+func HasBearerToken(r *http.Request, token string) bool {
+	const prefix = "Bearer "
+	auth := r.Header.Get("Authorization")
+	if !strings.HasPrefix(auth, prefix) {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(strings.TrimPrefix(auth, prefix)), []byte(token)) == 1
+}
+
+// redact returns the JSON representation of config with the values of
+// redactedFields masked, wherever they are nested.
+func redact(config *file.Content) (interface{}, error) {
+	data, err := json.Marshal(config)
+	if err != nil {
+		return nil, err
+	}
+	var document interface{}
+	if err := json.Unmarshal(data, &document); err != nil {
+		return nil, err
+	}
+	return redactValue(document), nil
+}
+
+func redactValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for field, fieldValue := range v {
+			if redactedFields[field] && fieldValue != nil {
+				v[field] = RedactedValue
+				continue
+			}
+			v[field] = redactValue(fieldValue)
+		}
+	case []interface{}:
+		for i := range v {
+			v[i] = redactValue(v[i])
+		}
+	}
+	return value
+}
@@ -0,0 +1,97 @@
+package configsecret
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io/ioutil"
+)
+
+// CompressionKey is the key of the configuration secret which, when present, holds the compression
+// of all its values. Readers must therefore get the objects of the secret with Objects.
+const CompressionKey = "compression"
+
+// gzipCompression is the value of CompressionKey for values compressed with gzip.
+const gzipCompression = "gzip"
+
+// Objects returns the objects stored in the data of a configuration secret, keyed as with KeyFor,
+// with their values decompressed if needed.
+func Objects(data map[string][]byte) (map[string][]byte, error) {
+	compression, compressed := data[CompressionKey]
+	if compressed && string(compression) != gzipCompression {
+		return nil, fmt.Errorf("unsupported compression %q of the configuration secret", compression)
+	}
+	objects := make(map[string][]byte, len(data))
+	for key, value := range data {
+		if key == CompressionKey {
+			continue
+		}
+		if compressed {
+			var err error
+			if value, err = decompress(value); err != nil {
+				return nil, fmt.Errorf("failed to decompress %s: %w", key, err)
+			}
+		}
+		objects[key] = value
+	}
+	return objects, nil
+}
+
+// SetObject stores value under key in the data of a configuration secret, compressed with gzip if
+// compress is set. When the compression of the data differs, the other values are converted, so that
+// all values are stored alike.
+func SetObject(data map[string][]byte, key string, value []byte, compress bool) error {
+	_, compressed := data[CompressionKey]
+	if compressed != compress {
+		objects, err := Objects(data)
+		if err != nil {
+			return err
+		}
+		for k := range data {
+			delete(data, k)
+		}
+		for k, v := range objects {
+			if err := setValue(data, k, v, compress); err != nil {
+				return err
+			}
+		}
+		if compress {
+			data[CompressionKey] = []byte(gzipCompression)
+		}
+	}
+	return setValue(data, key, value, compress)
+}
+
+func setValue(data map[string][]byte, key string, value []byte, compress bool) error {
+	if compress {
+		var err error
+		if value, err = compressValue(value); err != nil {
+			return fmt.Errorf("failed to compress %s: %w", key, err)
+		}
+	}
+	data[key] = value
+	return nil
+}
+
+// compressValue compresses value with gzip. The output only depends on the value, as no
+// modification time is set, so that unchanged objects leave the secret unchanged.
+func compressValue(value []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	if _, err := w.Write(value); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func decompress(value []byte) ([]byte, error) {
+	r, err := gzip.NewReader(bytes.NewReader(value))
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return ioutil.ReadAll(r)
+}
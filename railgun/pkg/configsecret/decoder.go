@@ -14,6 +14,7 @@ import (
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	knative "knative.dev/networking/pkg/apis/networking/v1alpha1"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	gatewayv1alpha1 "sigs.k8s.io/gateway-api/apis/v1alpha1"
 	"sigs.k8s.io/yaml"
 )
 
@@ -57,6 +58,8 @@ func DecodeObject(key string, value []byte) (client.Object, error) {
 		result = new(configurationv1.KongConsumer)
 	case isGV(knative.SchemeGroupVersion, group, version) && kind == "Ingress":
 		result = new(knative.Ingress)
+	case isGV(gatewayv1alpha1.SchemeGroupVersion, group, version) && kind == "HTTPRoute":
+		result = new(gatewayv1alpha1.HTTPRoute)
 	}
 
 	if err := yaml.Unmarshal(value, result); err != nil {
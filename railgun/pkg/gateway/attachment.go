@@ -0,0 +1,124 @@
+// Package gateway decides which Gateway API objects the controller is responsible for.
+package gateway
+
+import (
+	"context"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	gatewayv1alpha1 "sigs.k8s.io/gateway-api/apis/v1alpha1"
+)
+
+// GatewayClassController is the value of spec.controller in the GatewayClasses handled by Kong.
+const GatewayClassController = "konghq.com/kic-gateway-controller"
+
+// httpRouteKind is the kind listeners select to bind HTTPRoutes.
+const httpRouteKind = "HTTPRoute"
+
+//+kubebuilder:rbac:groups=networking.x-k8s.io,resources=gateways;gatewayclasses,verbs=get;list;watch
+//+kubebuilder:rbac:groups=core,resources=namespaces,verbs=get;list;watch
+
+// IsHTTPRouteAttached reports whether the HTTPRoute is bound to a listener of a Gateway whose GatewayClass
+// is controlled by Kong. The route must allow the Gateway with its spec.gateways, and a HTTP or HTTPS
+// listener of the Gateway must select the route by kind, labels and namespace.
+func IsHTTPRouteAttached(ctx context.Context, c client.Reader, route *gatewayv1alpha1.HTTPRoute) (bool, error) {
+	gateways := new(gatewayv1alpha1.GatewayList)
+	if err := c.List(ctx, gateways); err != nil {
+		return false, err
+	}
+	kongClasses := make(map[string]bool)
+	for i := range gateways.Items {
+		gw := &gateways.Items[i]
+		if !routeAllowsGateway(route, gw) {
+			continue
+		}
+		isKong, ok := kongClasses[gw.Spec.GatewayClassName]
+		if !ok {
+			var err error
+			if isKong, err = isKongGatewayClass(ctx, c, gw.Spec.GatewayClassName); err != nil {
+				return false, err
+			}
+			kongClasses[gw.Spec.GatewayClassName] = isKong
+		}
+		if !isKong {
+			continue
+		}
+		for _, listener := range gw.Spec.Listeners {
+			selected, err := listenerSelectsRoute(ctx, c, gw, listener, route)
+			if err != nil {
+				return false, err
+			}
+			if selected {
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
+
+// isKongGatewayClass verifies whether the named GatewayClass is controlled by Kong.
+func isKongGatewayClass(ctx context.Context, c client.Reader, name string) (bool, error) {
+	class := new(gatewayv1alpha1.GatewayClass)
+	if err := c.Get(ctx, client.ObjectKey{Name: name}, class); err != nil {
+		return false, client.IgnoreNotFound(err)
+	}
+	return class.Spec.Controller == GatewayClassController, nil
+}
+
+// routeAllowsGateway checks the spec.gateways of the route, which defaults to Gateways of its namespace.
+func routeAllowsGateway(route *gatewayv1alpha1.HTTPRoute, gw *gatewayv1alpha1.Gateway) bool {
+	switch route.Spec.Gateways.Allow {
+	case gatewayv1alpha1.GatewayAllowAll:
+		return true
+	case gatewayv1alpha1.GatewayAllowFromList:
+		for _, ref := range route.Spec.Gateways.GatewayRefs {
+			if ref.Namespace == gw.Namespace && ref.Name == gw.Name {
+				return true
+			}
+		}
+		return false
+	default:
+		return route.Namespace == gw.Namespace
+	}
+}
+
+// listenerSelectsRoute checks whether the listener binds the route. Its namespaces default to the
+// namespace of the Gateway, and an empty label selector selects every route.
+func listenerSelectsRoute(ctx context.Context, c client.Reader, gw *gatewayv1alpha1.Gateway,
+	listener gatewayv1alpha1.Listener, route *gatewayv1alpha1.HTTPRoute) (bool, error) {
+	if listener.Protocol != gatewayv1alpha1.HTTPProtocolType && listener.Protocol != gatewayv1alpha1.HTTPSProtocolType {
+		return false, nil
+	}
+	routes := listener.Routes
+	if routes.Kind != httpRouteKind || (routes.Group != "" && routes.Group != gatewayv1alpha1.GroupName) {
+		return false, nil
+	}
+	selected, err := matchesLabelSelector(routes.Selector, route.Labels)
+	if err != nil || !selected {
+		return false, err
+	}
+
+	switch routes.Namespaces.From {
+	case gatewayv1alpha1.RouteSelectAll:
+		return true, nil
+	case gatewayv1alpha1.RouteSelectSelector:
+		namespace := new(corev1.Namespace)
+		if err := c.Get(ctx, client.ObjectKey{Name: route.Namespace}, namespace); err != nil {
+			return false, client.IgnoreNotFound(err)
+		}
+		return matchesLabelSelector(routes.Namespaces.Selector, namespace.Labels)
+	default:
+		return route.Namespace == gw.Namespace, nil
+	}
+}
+
+// matchesLabelSelector reports whether the labels match the label selector.
+func matchesLabelSelector(selector metav1.LabelSelector, objLabels map[string]string) (bool, error) {
+	s, err := metav1.LabelSelectorAsSelector(&selector)
+	if err != nil {
+		return false, err
+	}
+	return s.Matches(labels.Set(objLabels)), nil
+}
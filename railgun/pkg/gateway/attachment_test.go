@@ -0,0 +1,139 @@
+package gateway
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	gatewayv1alpha1 "sigs.k8s.io/gateway-api/apis/v1alpha1"
+)
+
+func TestIsHTTPRouteAttached(t *testing.T) {
+	scheme := runtime.NewScheme()
+	assert.NoError(t, clientgoscheme.AddToScheme(scheme))
+	assert.NoError(t, gatewayv1alpha1.AddToScheme(scheme))
+
+	kongClass := &gatewayv1alpha1.GatewayClass{
+		ObjectMeta: metav1.ObjectMeta{Name: "kong"},
+		Spec:       gatewayv1alpha1.GatewayClassSpec{Controller: GatewayClassController},
+	}
+	otherClass := &gatewayv1alpha1.GatewayClass{
+		ObjectMeta: metav1.ObjectMeta{Name: "other"},
+		Spec:       gatewayv1alpha1.GatewayClassSpec{Controller: "example.com/other"},
+	}
+	teamNamespace := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "team", Labels: map[string]string{"expose": "true"}}}
+	gatewayWith := func(namespace, class string, routes gatewayv1alpha1.RouteBindingSelector) *gatewayv1alpha1.Gateway {
+		return &gatewayv1alpha1.Gateway{
+			ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: "gateway"},
+			Spec: gatewayv1alpha1.GatewaySpec{
+				GatewayClassName: class,
+				Listeners: []gatewayv1alpha1.Listener{{
+					Port:     80,
+					Protocol: gatewayv1alpha1.HTTPProtocolType,
+					Routes:   routes,
+				}},
+			},
+		}
+	}
+	httpRoutes := gatewayv1alpha1.RouteBindingSelector{Kind: "HTTPRoute"}
+
+	tests := []struct {
+		name    string
+		gateway *gatewayv1alpha1.Gateway
+		route   gatewayv1alpha1.HTTPRoute
+		want    bool
+	}{
+		{
+			name:    "same namespace as a Kong gateway",
+			gateway: gatewayWith("team", "kong", httpRoutes),
+			route:   gatewayv1alpha1.HTTPRoute{ObjectMeta: metav1.ObjectMeta{Namespace: "team", Name: "route"}},
+			want:    true,
+		},
+		{
+			name:    "gateway of another controller",
+			gateway: gatewayWith("team", "other", httpRoutes),
+			route:   gatewayv1alpha1.HTTPRoute{ObjectMeta: metav1.ObjectMeta{Namespace: "team", Name: "route"}},
+		},
+		{
+			name:    "gateway of a missing class",
+			gateway: gatewayWith("team", "missing", httpRoutes),
+			route:   gatewayv1alpha1.HTTPRoute{ObjectMeta: metav1.ObjectMeta{Namespace: "team", Name: "route"}},
+		},
+		{
+			name:    "listener selecting another kind",
+			gateway: gatewayWith("team", "kong", gatewayv1alpha1.RouteBindingSelector{Kind: "TCPRoute"}),
+			route:   gatewayv1alpha1.HTTPRoute{ObjectMeta: metav1.ObjectMeta{Namespace: "team", Name: "route"}},
+		},
+		{
+			name: "listener selecting other labels",
+			gateway: gatewayWith("team", "kong", gatewayv1alpha1.RouteBindingSelector{
+				Kind:     "HTTPRoute",
+				Selector: metav1.LabelSelector{MatchLabels: map[string]string{"app": "web"}},
+			}),
+			route: gatewayv1alpha1.HTTPRoute{ObjectMeta: metav1.ObjectMeta{Namespace: "team", Name: "route", Labels: map[string]string{"app": "api"}}},
+		},
+		{
+			name:    "route in another namespace than the gateway",
+			gateway: gatewayWith("kong", "kong", httpRoutes),
+			route:   gatewayv1alpha1.HTTPRoute{ObjectMeta: metav1.ObjectMeta{Namespace: "team", Name: "route"}},
+		},
+		{
+			name: "gateway and route both allowing all namespaces",
+			gateway: gatewayWith("kong", "kong", gatewayv1alpha1.RouteBindingSelector{
+				Kind:       "HTTPRoute",
+				Namespaces: gatewayv1alpha1.RouteNamespaces{From: gatewayv1alpha1.RouteSelectAll},
+			}),
+			route: gatewayv1alpha1.HTTPRoute{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "team", Name: "route"},
+				Spec:       gatewayv1alpha1.HTTPRouteSpec{Gateways: gatewayv1alpha1.RouteGateways{Allow: gatewayv1alpha1.GatewayAllowAll}},
+			},
+			want: true,
+		},
+		{
+			name: "gateway selecting the namespace of a route listing it",
+			gateway: gatewayWith("kong", "kong", gatewayv1alpha1.RouteBindingSelector{
+				Kind: "HTTPRoute",
+				Namespaces: gatewayv1alpha1.RouteNamespaces{
+					From:     gatewayv1alpha1.RouteSelectSelector,
+					Selector: metav1.LabelSelector{MatchLabels: map[string]string{"expose": "true"}},
+				},
+			}),
+			route: gatewayv1alpha1.HTTPRoute{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "team", Name: "route"},
+				Spec: gatewayv1alpha1.HTTPRouteSpec{Gateways: gatewayv1alpha1.RouteGateways{
+					Allow:       gatewayv1alpha1.GatewayAllowFromList,
+					GatewayRefs: []gatewayv1alpha1.GatewayReference{{Namespace: "kong", Name: "gateway"}},
+				}},
+			},
+			want: true,
+		},
+		{
+			name: "route not listing the gateway",
+			gateway: gatewayWith("kong", "kong", gatewayv1alpha1.RouteBindingSelector{
+				Kind:       "HTTPRoute",
+				Namespaces: gatewayv1alpha1.RouteNamespaces{From: gatewayv1alpha1.RouteSelectAll},
+			}),
+			route: gatewayv1alpha1.HTTPRoute{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "team", Name: "route"},
+				Spec: gatewayv1alpha1.HTTPRouteSpec{Gateways: gatewayv1alpha1.RouteGateways{
+					Allow:       gatewayv1alpha1.GatewayAllowFromList,
+					GatewayRefs: []gatewayv1alpha1.GatewayReference{{Namespace: "kong", Name: "other-gateway"}},
+				}},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := fake.NewClientBuilder().WithScheme(scheme).
+				WithObjects(kongClass, otherClass, teamNamespace, tt.gateway).Build()
+			got, err := IsHTTPRouteAttached(context.Background(), c, &tt.route)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
@@ -5,18 +5,24 @@ import (
 	"fmt"
 	"os"
 
+	"github.com/kong/kubernetes-ingress-controller/pkg/annotations"
 	configurationv1 "github.com/kong/kubernetes-ingress-controller/pkg/apis/configuration/v1"
 	configurationv1beta1 "github.com/kong/kubernetes-ingress-controller/pkg/apis/configuration/v1beta1"
 	oldstr "github.com/kong/kubernetes-ingress-controller/pkg/store"
 	"github.com/kong/kubernetes-ingress-controller/railgun/apis/configuration/v1alpha1"
 	configurationv1alpha1 "github.com/kong/kubernetes-ingress-controller/railgun/apis/configuration/v1alpha1"
+	mgrutils "github.com/kong/kubernetes-ingress-controller/railgun/manager/utils"
+	"github.com/kong/kubernetes-ingress-controller/railgun/pkg/gateway"
 	apiv1 "k8s.io/api/core/v1"
+	discoveryv1beta1 "k8s.io/api/discovery/v1beta1"
 	networkingv1 "k8s.io/api/networking/v1"
 	networkingv1beta1 "k8s.io/api/networking/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/selection"
 	knative "knative.dev/networking/pkg/apis/networking/v1alpha1"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	gatewayv1alpha1 "sigs.k8s.io/gateway-api/apis/v1alpha1"
 )
 
 // -----------------------------------------------------------------------------
@@ -37,7 +43,29 @@ import (
 // If we continue to use storer, we should consider expanding the interface to include
 // contexts, as all the relevant API calls made underneath the hood here use contexts.
 func New(c client.Client) oldstr.Storer {
-	return &store{c}
+	return &store{c: c}
+}
+
+// NewWithEndpointSlices produces a new oldstr.Storer like New, which assembles the
+// endpoints of services from their EndpointSlices rather than from their Endpoints.
+func NewWithEndpointSlices(c client.Client) oldstr.Storer {
+	return &store{c: c, useEndpointSlices: true}
+}
+
+// Options select optional sources of the objects of a store.
+type Options struct {
+	// UseEndpointSlices assembles the endpoints of services from their EndpointSlices
+	// rather than from their Endpoints.
+	UseEndpointSlices bool
+
+	// HTTPRoutes lists the Gateway API HTTPRoutes attached to Gateways of Kong. Otherwise,
+	// no HTTPRoute is listed, so that the store works on clusters without the Gateway API.
+	HTTPRoutes bool
+}
+
+// NewWithOptions produces a new oldstr.Storer like New, with the given options.
+func NewWithOptions(c client.Client, opts Options) oldstr.Storer {
+	return &store{c: c, useEndpointSlices: opts.UseEndpointSlices, httpRoutes: opts.HTTPRoutes}
 }
 
 // -----------------------------------------------------------------------------
@@ -46,6 +74,9 @@ func New(c client.Client) oldstr.Storer {
 
 type store struct {
 	c client.Client
+
+	useEndpointSlices bool
+	httpRoutes        bool
 }
 
 // -----------------------------------------------------------------------------
@@ -69,6 +100,15 @@ func (s *store) GetService(namespace, name string) (*apiv1.Service, error) {
 }
 
 func (s *store) GetEndpointsForService(namespace, name string) (*apiv1.Endpoints, error) {
+	if s.useEndpointSlices {
+		slices := new(discoveryv1beta1.EndpointSliceList)
+		if err := s.c.List(context.Background(), slices, client.InNamespace(namespace),
+			client.MatchingLabels{discoveryv1beta1.LabelServiceName: name}); err != nil {
+			return nil, err
+		}
+		return endpointsFromSlices(namespace, name, slices.Items), nil
+	}
+
 	endpoints := new(apiv1.Endpoints)
 	if err := s.c.Get(context.Background(), client.ObjectKey{Namespace: namespace, Name: name}, endpoints); err != nil {
 		return nil, err
@@ -119,8 +159,10 @@ func (s *store) ListIngressesV1beta1() []*networkingv1beta1.Ingress {
 	}
 
 	ingresses := make([]*networkingv1beta1.Ingress, 0, len(list.Items))
-	for _, ingress := range list.Items {
-		ingresses = append(ingresses, &ingress)
+	for i := range list.Items {
+		ingress := &list.Items[i]
+		applyIngressClassDefaults(ingress, ingress.Spec.IngressClassName)
+		ingresses = append(ingresses, ingress)
 	}
 
 	return ingresses
@@ -133,8 +175,10 @@ func (s *store) ListIngressesV1() []*networkingv1.Ingress {
 	}
 
 	ingresses := make([]*networkingv1.Ingress, 0, len(list.Items))
-	for _, ingress := range list.Items {
-		ingresses = append(ingresses, &ingress)
+	for i := range list.Items {
+		ingress := &list.Items[i]
+		applyIngressClassDefaults(ingress, ingress.Spec.IngressClassName)
+		ingresses = append(ingresses, ingress)
 	}
 
 	return ingresses
@@ -182,6 +226,30 @@ func (s *store) ListKnativeIngresses() ([]*knative.Ingress, error) {
 	return ingresses, nil
 }
 
+func (s *store) ListHTTPRoutes() ([]*gatewayv1alpha1.HTTPRoute, error) {
+	if !s.httpRoutes {
+		return nil, nil
+	}
+	list := new(gatewayv1alpha1.HTTPRouteList)
+	if err := s.c.List(context.Background(), list); err != nil {
+		return nil, err
+	}
+
+	routes := make([]*gatewayv1alpha1.HTTPRoute, 0, len(list.Items))
+	for i := range list.Items {
+		route := &list.Items[i]
+		attached, err := gateway.IsHTTPRouteAttached(context.Background(), s.c, route)
+		if err != nil {
+			return nil, err
+		}
+		if attached {
+			routes = append(routes, route)
+		}
+	}
+
+	return routes, nil
+}
+
 func (s *store) ListGlobalKongPlugins() ([]*configurationv1.KongPlugin, error) {
 	list := new(configurationv1.KongPluginList)
 	if err := s.c.List(context.Background(), list); err != nil {
@@ -226,6 +294,21 @@ func (s *store) ListKongConsumers() []*configurationv1.KongConsumer {
 	return ingresses
 }
 
+func (s *store) ListSecretsNamed(name string) ([]*apiv1.Secret, error) {
+	list := new(apiv1.SecretList)
+	if err := s.c.List(context.Background(), list); err != nil {
+		return nil, err
+	}
+
+	var secrets []*apiv1.Secret
+	for i := range list.Items {
+		if list.Items[i].Name == name {
+			secrets = append(secrets, &list.Items[i])
+		}
+	}
+	return secrets, nil
+}
+
 func (s *store) ListCACerts() ([]*apiv1.Secret, error) {
 	req1, err := labels.NewRequirement("konghq.com/ca-cert", selection.Exists, []string{})
 	if err != nil {
@@ -254,3 +337,72 @@ func (s *store) ListCACerts() ([]*apiv1.Secret, error) {
 
 	return secrets, nil
 }
+
+// -----------------------------------------------------------------------------
+// Secret Controller - Storer - Private Functions
+// -----------------------------------------------------------------------------
+
+// applyIngressClassDefaults sets the default plugins of the IngressClass of an Ingress
+// on that Ingress, unless it already references plugins itself. The class is the one
+// named by className or, lacking it, by the kubernetes.io/ingress.class annotation.
+func applyIngressClassDefaults(obj metav1.Object, className *string) {
+	anns := obj.GetAnnotations()
+	if len(annotations.ExtractKongPluginsFromAnnotations(anns)) > 0 {
+		return
+	}
+
+	class := anns[annotations.IngressClassKey]
+	if className != nil {
+		class = *className
+	}
+	params, ok := mgrutils.IngressClasses.Get(class)
+	if !ok || params.DefaultPlugins == "" {
+		return
+	}
+
+	if anns == nil {
+		anns = make(map[string]string)
+	}
+	anns[annotations.AnnotationPrefix+annotations.PluginsKey] = params.DefaultPlugins
+	obj.SetAnnotations(anns)
+}
+
+// endpointsFromSlices assembles the Endpoints of a service from its EndpointSlices, with
+// a subset per slice. Endpoints which are terminating or known not to be ready are left out.
+func endpointsFromSlices(namespace, name string, slices []discoveryv1beta1.EndpointSlice) *apiv1.Endpoints {
+	endpoints := &apiv1.Endpoints{ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name}}
+	for _, slice := range slices {
+		var subset apiv1.EndpointSubset
+		for _, port := range slice.Ports {
+			if port.Port == nil {
+				// the slice applies to all ports of the service, which Endpoints can't express
+				continue
+			}
+			epPort := apiv1.EndpointPort{Port: *port.Port, Protocol: apiv1.ProtocolTCP}
+			if port.Name != nil {
+				epPort.Name = *port.Name
+			}
+			if port.Protocol != nil {
+				epPort.Protocol = *port.Protocol
+			}
+			subset.Ports = append(subset.Ports, epPort)
+		}
+		for _, endpoint := range slice.Endpoints {
+			conditions := endpoint.Conditions
+			if (conditions.Terminating != nil && *conditions.Terminating) ||
+				(conditions.Ready != nil && !*conditions.Ready) {
+				continue
+			}
+			for _, address := range endpoint.Addresses {
+				subset.Addresses = append(subset.Addresses, apiv1.EndpointAddress{
+					IP:        address,
+					TargetRef: endpoint.TargetRef,
+				})
+			}
+		}
+		if len(subset.Ports) > 0 && len(subset.Addresses) > 0 {
+			endpoints.Subsets = append(endpoints.Subsets, subset)
+		}
+	}
+	return endpoints
+}
@@ -0,0 +1,117 @@
+package store
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	apiv1 "k8s.io/api/core/v1"
+	discoveryv1beta1 "k8s.io/api/discovery/v1beta1"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/kong/kubernetes-ingress-controller/pkg/annotations"
+	mgrutils "github.com/kong/kubernetes-ingress-controller/railgun/manager/utils"
+)
+
+func TestListIngressesV1AppliesIngressClassDefaults(t *testing.T) {
+	defer func(classes *mgrutils.IngressClassSet) { mgrutils.IngressClasses = classes }(mgrutils.IngressClasses)
+	mgrutils.IngressClasses = mgrutils.NewIngressClassSet()
+	mgrutils.IngressClasses.Set("kong-a", mgrutils.IngressClassParameters{DefaultPlugins: "plugin-a"})
+	mgrutils.IngressClasses.Set("kong-b", mgrutils.IngressClassParameters{DefaultPlugins: "plugin-b"})
+
+	classA, classB := "kong-a", "kong-b"
+	pluginsKey := annotations.AnnotationPrefix + annotations.PluginsKey
+	ingresses := []*networkingv1.Ingress{
+		{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "by-field"},
+			Spec:       networkingv1.IngressSpec{IngressClassName: &classA},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "by-annotation", Annotations: map[string]string{
+				annotations.IngressClassKey: "kong-b",
+			}},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "field-wins", Annotations: map[string]string{
+				annotations.IngressClassKey: "kong-a",
+			}},
+			Spec: networkingv1.IngressSpec{IngressClassName: &classB},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "own-plugins", Annotations: map[string]string{
+				pluginsKey: "mine",
+			}},
+			Spec: networkingv1.IngressSpec{IngressClassName: &classA},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "unknown-class"},
+		},
+	}
+	builder := fake.NewClientBuilder().WithScheme(clientgoscheme.Scheme)
+	for _, ingress := range ingresses {
+		builder = builder.WithObjects(ingress)
+	}
+
+	got := map[string]string{}
+	for _, ingress := range New(builder.Build()).ListIngressesV1() {
+		got[ingress.Name] = ingress.Annotations[pluginsKey]
+	}
+	assert.Equal(t, map[string]string{
+		"by-field":      "plugin-a",
+		"by-annotation": "plugin-b",
+		"field-wins":    "plugin-b",
+		"own-plugins":   "mine",
+		"unknown-class": "",
+	}, got)
+}
+
+func TestGetEndpointsForServiceAssemblesSlices(t *testing.T) {
+	yes, no := true, false
+	portName, port, protocol := "http", int32(8080), apiv1.ProtocolTCP
+	ports := []discoveryv1beta1.EndpointPort{{Name: &portName, Port: &port, Protocol: &protocol}}
+	slice := func(name, service string, endpoints ...discoveryv1beta1.Endpoint) *discoveryv1beta1.EndpointSlice {
+		return &discoveryv1beta1.EndpointSlice{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: name, Labels: map[string]string{
+				discoveryv1beta1.LabelServiceName: service,
+			}},
+			AddressType: discoveryv1beta1.AddressTypeIPv4,
+			Ports:       ports,
+			Endpoints:   endpoints,
+		}
+	}
+	endpoint := func(ip string, conditions discoveryv1beta1.EndpointConditions) discoveryv1beta1.Endpoint {
+		return discoveryv1beta1.Endpoint{Addresses: []string{ip}, Conditions: conditions}
+	}
+
+	c := fake.NewClientBuilder().WithScheme(clientgoscheme.Scheme).WithObjects(
+		slice("svc-a", "svc",
+			endpoint("10.0.0.1", discoveryv1beta1.EndpointConditions{Ready: &yes}),
+			endpoint("10.0.0.2", discoveryv1beta1.EndpointConditions{Ready: &no}),
+		),
+		slice("svc-b", "svc",
+			endpoint("10.0.0.3", discoveryv1beta1.EndpointConditions{}),
+			endpoint("10.0.0.4", discoveryv1beta1.EndpointConditions{Ready: &yes, Terminating: &yes}),
+		),
+		slice("svc-c", "svc",
+			endpoint("10.0.0.5", discoveryv1beta1.EndpointConditions{Terminating: &yes}),
+		),
+		slice("other-a", "other",
+			endpoint("10.0.1.1", discoveryv1beta1.EndpointConditions{Ready: &yes}),
+		),
+	).Build()
+
+	endpoints, err := NewWithEndpointSlices(c).GetEndpointsForService("default", "svc")
+	require.NoError(t, err)
+	epPorts := []apiv1.EndpointPort{{Name: portName, Port: port, Protocol: protocol}}
+	assert.ElementsMatch(t, []apiv1.EndpointSubset{
+		{Addresses: []apiv1.EndpointAddress{{IP: "10.0.0.1"}}, Ports: epPorts},
+		{Addresses: []apiv1.EndpointAddress{{IP: "10.0.0.3"}}, Ports: epPorts},
+	}, endpoints.Subsets)
+
+	endpoints, err = NewWithEndpointSlices(c).GetEndpointsForService("default", "missing")
+	require.NoError(t, err)
+	assert.Empty(t, endpoints.Subsets)
+}